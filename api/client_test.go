@@ -0,0 +1,274 @@
+package api_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"atropos/api"
+	"atropos/client"
+	"atropos/history"
+)
+
+const clientPolicy = `
+server:
+  hmac_secret: ` + testSecret + `
+  dedup_window_seconds: 0
+nodes:
+  athena:
+    rate_limit:
+      max_cuts: 10
+      window_minutes: 60
+    strategies:
+      - threshold: 0.5
+        action: test_restart
+  borg:
+    strategies:
+      - threshold: 0.5
+        action: test_restart
+`
+
+// The handlers and the client share the api package's types; these round
+// trips catch a route or field that one side renamed.
+func TestClientCutAndHistory(t *testing.T) {
+	s := newTestServer(t, clientPolicy)
+	c := s.client(t, testSecret)
+	ctx := context.Background()
+
+	var ids []string
+	for i := 0; i < 5; i++ {
+		resp, job, err := c.Cut(ctx, api.CutRequest{Node: "athena", Entropy: 0.8, Source: "client-test", Tags: map[string]string{"run": "rt"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if job != nil || !resp.Success || resp.Action != "test_restart" || resp.CutID == "" {
+			t.Fatalf("cut = %+v, job %+v", resp, job)
+		}
+		ids = append(ids, resp.CutID)
+	}
+
+	cut, err := c.GetCut(ctx, ids[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cut.ID != ids[0] || cut.Source != "client-test" || cut.Tags["run"] != "rt" {
+		t.Fatalf("GetCut = %+v", cut)
+	}
+	if _, err := c.GetCut(ctx, "no-such-cut"); !client.IsStatus(err, http.StatusNotFound) {
+		t.Fatalf("GetCut of an unknown ID: %v, want 404", err)
+	}
+
+	page, err := c.History(ctx, client.HistoryQuery{Node: "athena", Limit: 2, Offset: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Count != 2 || page.Cuts[0].ID != ids[3] || page.Cuts[1].ID != ids[2] {
+		t.Fatalf("second-newest page = %+v, want %s and %s", page.Cuts, ids[3], ids[2])
+	}
+	all, err := c.HistoryAll(ctx, client.HistoryQuery{Node: "athena", Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != len(ids) {
+		t.Fatalf("HistoryAll returned %d cuts, want %d", len(all), len(ids))
+	}
+	for i, cut := range all {
+		if want := ids[len(ids)-1-i]; cut.ID != want {
+			t.Fatalf("HistoryAll[%d] = %s, want %s", i, cut.ID, want)
+		}
+	}
+	borg, err := c.History(ctx, client.HistoryQuery{Node: "borg"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if borg.Count != 0 {
+		t.Fatalf("borg history = %+v, want none", borg.Cuts)
+	}
+
+	stats, err := c.Stats(ctx, client.StatsQuery{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.TotalCuts != len(ids) || stats.Nodes["athena"].TotalCuts != len(ids) {
+		t.Fatalf("stats = %+v, want %d cuts on athena", stats, len(ids))
+	}
+	if _, err := c.Trends(ctx, 7); err != nil {
+		t.Fatalf("Trends: %v", err)
+	}
+	if _, err := c.NodeTrends(ctx, "athena"); err != nil {
+		t.Fatalf("NodeTrends: %v", err)
+	}
+}
+
+func TestClientExport(t *testing.T) {
+	s := newTestServer(t, clientPolicy)
+	c := s.client(t, testSecret)
+	ctx := context.Background()
+	for _, node := range []string{"athena", "borg"} {
+		if _, _, err := c.Cut(ctx, api.CutRequest{Node: node, Entropy: 0.9}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var csv bytes.Buffer
+	if err := c.Export(ctx, client.ExportCSV, 1, &csv); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(csv.String()), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "ID,Node,") || !strings.Contains(lines[1], ",borg,") {
+		t.Fatalf("CSV export limited to the newest cut:\n%s", csv.String())
+	}
+
+	var body bytes.Buffer
+	if err := c.Export(ctx, client.ExportJSON, 0, &body); err != nil {
+		t.Fatal(err)
+	}
+	var export struct {
+		TotalCuts int                  `json:"total_cuts"`
+		Cuts      []*history.CutRecord `json:"cuts"`
+	}
+	if err := json.Unmarshal(body.Bytes(), &export); err != nil {
+		t.Fatal(err)
+	}
+	if export.TotalCuts != 2 || len(export.Cuts) != 2 {
+		t.Fatalf("JSON export = %+v, want both cuts", export)
+	}
+}
+
+func TestClientDryRunAndNodes(t *testing.T) {
+	s := newTestServer(t, clientPolicy)
+	c := s.client(t, testSecret)
+	ctx := context.Background()
+
+	dry, err := c.DryRun(ctx, "athena", 0.7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !dry.WouldExecute || dry.Action != "test_restart" || dry.Threshold != 0.5 {
+		t.Fatalf("dry run = %+v", dry)
+	}
+	if s.cutter.Calls() != 0 {
+		t.Fatal("dry run ran the cutter")
+	}
+	if _, err := c.DryRun(ctx, "nowhere", 0.7); !client.IsStatus(err, http.StatusNotFound) {
+		t.Fatalf("dry run of an unknown node: %v, want 404", err)
+	}
+	if _, err := s.client(t, "").DryRun(ctx, "athena", 0.7); !client.IsStatus(err, http.StatusUnauthorized) {
+		t.Fatalf("unsigned dry run: %v, want 401", err)
+	}
+
+	nodes, err := c.Nodes(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nodes.Count != 2 || len(nodes.Nodes) != 2 {
+		t.Fatalf("nodes = %+v, want athena and borg", nodes)
+	}
+	status, err := c.NodeStatus(ctx, "borg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Node != "borg" || status.Circuit.Open {
+		t.Fatalf("borg status = %+v", status)
+	}
+}
+
+func TestClientRateLimits(t *testing.T) {
+	s := newTestServer(t, clientPolicy)
+	c := s.client(t, testSecret)
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		if _, _, err := c.Cut(ctx, api.CutRequest{Node: "athena", Entropy: 0.9}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	limit, err := c.RateLimit(ctx, "athena")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !limit.Configured || limit.MaxCuts != 10 || limit.Count != 3 || limit.Remaining != 7 {
+		t.Fatalf("athena's rate limit = %+v, want 3 of 10 used", limit)
+	}
+	list, err := c.RateLimits(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list.Count == 0 || list.Exhausted != 0 {
+		t.Fatalf("rate limits = %+v", list)
+	}
+
+	if _, err := s.client(t, "").ResetRateLimit(ctx, "athena", "test"); !client.IsStatus(err, http.StatusUnauthorized) {
+		t.Fatalf("unsigned reset: %v, want 401", err)
+	}
+	reset, err := c.ResetRateLimit(ctx, "athena", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reset.Node != "athena" || reset.Previous.Count != 3 {
+		t.Fatalf("reset = %+v, want the 3 cuts before it", reset)
+	}
+	if limit, err = c.RateLimit(ctx, "athena"); err != nil || limit.Count != 0 {
+		t.Fatalf("after reset: %+v, %v", limit, err)
+	}
+}
+
+func TestClientBatchAndAsyncCut(t *testing.T) {
+	s := newTestServer(t, clientPolicy)
+	c := s.client(t, testSecret)
+	ctx := context.Background()
+
+	batch, err := c.BatchCut(ctx, []api.CutRequest{{Node: "athena", Entropy: 0.9}, {Node: "borg", Entropy: 0.9}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(batch.Results) != 2 || !batch.Results[0].Success || !batch.Results[1].Success {
+		t.Fatalf("batch = %+v", batch)
+	}
+
+	started, job, err := c.CutAsync(ctx, api.CutRequest{Node: "borg", Entropy: 0.9})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job != nil || started.ID == "" || started.Node != "borg" {
+		t.Fatalf("async cut = %+v, job %+v", started, job)
+	}
+	waitFor(t, "the async cut to finish", func() bool {
+		cut, err := c.GetCut(ctx, started.ID)
+		return err == nil && cut.Outcome != history.OutcomeInProgress
+	})
+}
+
+func TestClientKeyID(t *testing.T) {
+	s := newTestServer(t, `
+server:
+  hmac_keys:
+    - key_id: old
+      secret: old-secret
+    - key_id: new
+      secret: new-secret
+nodes:
+  athena:
+    strategies:
+      - threshold: 0.5
+        action: test_restart
+`)
+	ctx := context.Background()
+	for _, tc := range []struct {
+		secret, keyID string
+		want          int
+	}{
+		{"new-secret", "new", http.StatusOK},
+		{"new-secret", "", http.StatusOK},
+		{"old-secret", "old", http.StatusOK},
+		{"wrong", "new", http.StatusForbidden},
+	} {
+		_, err := s.client(t, tc.secret, client.WithKeyID(tc.keyID)).ReloadPolicy(ctx)
+		if status := statusOf(t, err); status != tc.want {
+			t.Errorf("secret %s key %q: status %d, want %d", tc.secret, tc.keyID, status, tc.want)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"atropos/history"
+)
+
+// notModified sets the validators for the current history version and answers
+// 304 when the client's copy is still current.
+func notModified(c *gin.Context, v history.Version) bool {
+	etag := v.ETag()
+	c.Header("ETag", etag)
+	c.Header("Last-Modified", v.Modified.Format(http.TimeFormat))
+
+	if match := c.GetHeader("If-None-Match"); match != "" {
+		if !etagMatches(match, etag) {
+			return false
+		}
+		c.Status(http.StatusNotModified)
+		return true
+	}
+
+	if since := c.GetHeader("If-Modified-Since"); since != "" {
+		t, err := http.ParseTime(since)
+		if err == nil && !v.Modified.Truncate(time.Second).After(t) {
+			c.Status(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
+
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+type exportCache struct {
+	mu    sync.Mutex
+	key   string
+	body  []byte
+	count int
+}
+
+func (e *exportCache) get(key string) ([]byte, int, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.key != key || e.body == nil {
+		return nil, 0, false
+	}
+	return e.body, e.count, true
+}
+
+func (e *exportCache) put(key string, body []byte, count int) {
+	e.mu.Lock()
+	e.key = key
+	e.body = body
+	e.count = count
+	e.mu.Unlock()
+}
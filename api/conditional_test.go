@@ -0,0 +1,209 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"atropos/api"
+)
+
+// get sends a GET with the given headers and returns the response, body
+// read.
+func (s *testServer) get(t *testing.T, path string, header map[string]string) (*http.Response, string) {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, s.URL+path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, value := range header {
+		req.Header.Set(key, value)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp, string(data)
+}
+
+func (s *testServer) cut(t *testing.T, node string) {
+	t.Helper()
+	if _, _, err := s.client(t, testSecret).Cut(context.Background(), api.CutRequest{Node: node, Entropy: 0.9}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+const conditionalPolicy = `
+server:
+  hmac_secret: ` + testSecret + `
+  dedup_window_seconds: 0
+nodes:
+  athena:
+    strategies:
+      - threshold: 0.5
+        action: test_restart
+`
+
+func TestConditionalRequests(t *testing.T) {
+	s := newTestServer(t, conditionalPolicy)
+	s.cut(t, "athena")
+
+	for _, path := range []string{
+		"/api/v1/export/history.json",
+		"/api/v1/export/history.csv",
+		"/api/v1/export/report.html",
+		"/api/v1/cuts/history",
+		"/api/v1/cuts/history/athena",
+	} {
+		t.Run(path, func(t *testing.T) {
+			resp, body := s.get(t, path, nil)
+			etag := resp.Header.Get("ETag")
+			lastModified := resp.Header.Get("Last-Modified")
+			if resp.StatusCode != http.StatusOK || etag == "" || lastModified == "" {
+				t.Fatalf("status %d, ETag %q, Last-Modified %q", resp.StatusCode, etag, lastModified)
+			}
+			if body == "" {
+				t.Fatal("empty body")
+			}
+
+			for _, header := range []map[string]string{
+				{"If-None-Match": etag},
+				{"If-None-Match": strings.TrimPrefix(etag, "W/")},
+				{"If-None-Match": `"other", ` + etag},
+				{"If-None-Match": "*"},
+				{"If-Modified-Since": lastModified},
+				{"If-Modified-Since": time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)},
+			} {
+				resp, body := s.get(t, path, header)
+				if resp.StatusCode != http.StatusNotModified || body != "" {
+					t.Errorf("%v: status %d with %d bytes, want an empty 304", header, resp.StatusCode, len(body))
+				}
+				if resp.Header.Get("ETag") != etag {
+					t.Errorf("%v: 304 carried ETag %q, want %q", header, resp.Header.Get("ETag"), etag)
+				}
+			}
+
+			for _, header := range []map[string]string{
+				{"If-None-Match": `W/"stale-0"`},
+				{"If-Modified-Since": time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat)},
+				{"If-Modified-Since": "not a date"},
+				// If-None-Match wins over If-Modified-Since.
+				{"If-None-Match": `W/"stale-0"`, "If-Modified-Since": lastModified},
+			} {
+				if resp, _ := s.get(t, path, header); resp.StatusCode != http.StatusOK {
+					t.Errorf("%v: status %d, want 200", header, resp.StatusCode)
+				}
+			}
+		})
+	}
+}
+
+func TestNewCutInvalidatesValidators(t *testing.T) {
+	s := newTestServer(t, conditionalPolicy)
+	s.cut(t, "athena")
+
+	const path = "/api/v1/export/history.json"
+	resp, before := s.get(t, path, nil)
+	etag := resp.Header.Get("ETag")
+
+	s.cut(t, "athena")
+	resp, after := s.get(t, path, map[string]string{"If-None-Match": etag})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("after a new cut: status %d, want 200", resp.StatusCode)
+	}
+	if resp.Header.Get("ETag") == etag {
+		t.Fatal("ETag did not change after a new cut")
+	}
+	if strings.Count(before, `"id"`) != 1 || strings.Count(after, `"id"`) != 2 {
+		t.Fatalf("export went from %d to %d cuts, want 1 to 2", strings.Count(before, `"id"`), strings.Count(after, `"id"`))
+	}
+}
+
+// The rendered export is reused until the history changes: a record removed
+// behind the store's back is still exported, until the next cut bumps the
+// version and the export is rendered again.
+func TestExportCacheInvalidation(t *testing.T) {
+	s := newTestServer(t, conditionalPolicy)
+	s.cut(t, "athena")
+	s.cut(t, "athena")
+
+	_, jsonBefore := s.get(t, "/api/v1/export/history.json", nil)
+	_, csvBefore := s.get(t, "/api/v1/export/history.csv", nil)
+
+	records, err := filepath.Glob(filepath.Join(s.dir, "history", "*.json.gz"))
+	if err != nil || len(records) != 2 {
+		t.Fatalf("records on disk: %v, %v", records, err)
+	}
+	if err := os.Remove(records[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	_, csvCached := s.get(t, "/api/v1/export/history.csv", nil)
+	if csvCached != csvBefore {
+		t.Fatal("CSV export was rendered again without a change to the history")
+	}
+	// The cache holds the last rendering only; another limit renders afresh.
+	if _, csvLimited := s.get(t, "/api/v1/export/history.csv?limit=10", nil); strings.Count(csvLimited, "\n") != 2 {
+		t.Fatalf("CSV export with another limit:\n%s", csvLimited)
+	}
+
+	s.cut(t, "athena")
+	_, csvAfter := s.get(t, "/api/v1/export/history.csv", nil)
+	if rows := strings.Count(csvAfter, "\n") - 1; rows != 2 {
+		t.Fatalf("CSV export after a new cut has %d rows, want 2:\n%s", rows, csvAfter)
+	}
+	_, jsonAfter := s.get(t, "/api/v1/export/history.json", nil)
+	if jsonAfter == jsonBefore || strings.Count(jsonAfter, `"id"`) != 2 {
+		t.Fatalf("JSON export after a new cut:\n%s", jsonAfter)
+	}
+}
+
+// A cached JSON export reuses its cuts but is stamped with each response's
+// own exported_at.
+func TestExportStampedPerResponse(t *testing.T) {
+	s := newTestServer(t, conditionalPolicy)
+	s.cut(t, "athena")
+	s.cut(t, "athena")
+
+	type export struct {
+		ExportedAt string            `json:"exported_at"`
+		Cuts       []json.RawMessage `json:"cuts"`
+	}
+	get := func() export {
+		_, body := s.get(t, "/api/v1/export/history.json", nil)
+		var e export
+		if err := json.Unmarshal([]byte(body), &e); err != nil {
+			t.Fatal(err)
+		}
+		return e
+	}
+	first := get()
+
+	records, err := filepath.Glob(filepath.Join(s.dir, "history", "*.json.gz"))
+	if err != nil || len(records) != 2 {
+		t.Fatalf("records on disk: %v, %v", records, err)
+	}
+	if err := os.Remove(records[0]); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Second)
+
+	cached := get()
+	if len(cached.Cuts) != 2 {
+		t.Fatalf("JSON export has %d cuts, want the 2 cached", len(cached.Cuts))
+	}
+	if cached.ExportedAt == first.ExportedAt {
+		t.Fatalf("cached JSON export kept exported_at %s", first.ExportedAt)
+	}
+}
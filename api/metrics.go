@@ -0,0 +1,6 @@
+package api
+
+import "atropos/internal/metrics"
+
+var entropyClamped = metrics.NewCounter("atropos_entropy_clamped_total",
+	"Readings just outside [0, 1] pulled onto the nearest bound, by node.", "node")
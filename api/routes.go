@@ -1,15 +1,32 @@
 package api
 
 import (
+	"bytes"
+	"context"
 	"embed"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
 	"net/http"
+	"path/filepath"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 
 	"atropos/correlation"
+	"atropos/cutter"
 	"atropos/engine"
+	"atropos/export"
+	"atropos/history"
+	"atropos/internal/metrics"
+	"atropos/internal/timefmt"
+	"atropos/policy"
 	"atropos/trends"
 )
 
@@ -19,24 +36,32 @@ type Routes struct {
 	executor *engine.Executor
 	analyzer *trends.Analyzer
 	handler  *WebhookHandler
+	exports  exportCache
+	reports  *correlation.ClothoImporter
 }
 
-func NewRoutes(exec *engine.Executor, hmacSecret string) *Routes {
+func NewRoutes(exec *engine.Executor) *Routes {
+	reports := correlation.NewClothoImporter()
+	analyzer := trends.NewAnalyzer(exec.GetHistory())
+	analyzer.SetCorrelation(reports)
 	return &Routes{
 		executor: exec,
-		analyzer: trends.NewAnalyzer(exec.GetHistory()),
-		handler:  NewWebhookHandler(exec, hmacSecret),
+		analyzer: analyzer,
+		handler:  NewWebhookHandler(exec),
+		reports:  reports,
 	}
 }
 
 func (r *Routes) RegisterRoutes(g *gin.Engine) {
 	g.GET("/", r.serveDashboard)
 	g.GET("/dashboard", r.serveDashboard)
+	g.GET("/metrics", r.serveMetrics)
 	g.Static("/static", "./dashboard/static")
 
 	api := g.Group("/api/v1")
 	{
 		api.POST("/cut", r.handler.hmacMiddleware(), r.handler.handleCut)
+		api.POST("/cut/batch", r.handler.hmacMiddleware(), r.handler.handleBatchCut)
 		api.GET("/health", r.handler.handleHealth)
 
 		history := api.Group("/cuts/history")
@@ -48,6 +73,7 @@ func (r *Routes) RegisterRoutes(g *gin.Engine) {
 		cuts := api.Group("/cuts")
 		{
 			cuts.GET("/:id", r.getCut)
+			cuts.GET("/:id/chain", r.getCutChain)
 		}
 
 		stats := api.Group("/stats")
@@ -56,9 +82,41 @@ func (r *Routes) RegisterRoutes(g *gin.Engine) {
 			stats.GET("/:node", r.getNodeStats)
 		}
 
+		nodes := api.Group("/nodes")
+		{
+			nodes.GET("", r.listNodes)
+			nodes.GET("/:node/status", r.getNodeStatus)
+			nodes.GET("/:node/ratelimit", r.getRateLimit)
+			// Listing snapshots runs VBoxManage, possibly on the node's host.
+			nodes.GET("/:node/snapshots", r.handler.hmacMiddleware(), r.getSnapshots)
+			nodes.POST("/:node/ratelimit/reset", r.handler.hmacMiddleware(), r.resetRateLimit)
+			nodes.POST("/:node/silence", r.handler.hmacMiddleware(), r.silenceNode)
+			nodes.DELETE("/:node/silence", r.handler.hmacMiddleware(), r.unsilenceNode)
+			nodes.POST("/:node/circuit/reset", r.handler.hmacMiddleware(), r.resetCircuit)
+		}
+		api.GET("/ratelimits", r.listRateLimits)
+		api.GET("/silences", r.listSilences)
+		api.GET("/schedules", r.listSchedules)
+		api.GET("/ready", r.ready)
+		api.GET("/cutters", r.listCutters)
+		api.GET("/cutters/health", r.cuttersHealth)
+		api.GET("/jobs/:id", r.handler.getJob)
+		api.GET("/debug/state", r.debugState)
+
+		approvals := api.Group("/approvals")
+		{
+			approvals.GET("", r.listApprovals)
+			approvals.POST("/:id/approve", r.handler.hmacMiddleware(), r.approveCut)
+			approvals.POST("/:id/reject", r.handler.hmacMiddleware(), r.rejectCut)
+		}
+
+		api.GET("/policy/lint", r.lintPolicy)
+		api.POST("/policy/reload", r.handler.hmacMiddleware(), r.reloadPolicy)
+
 		api.GET("/trends", r.getTrends)
 		api.GET("/trends/:node", r.getNodeTrends)
-		api.POST("/cut/dryrun", r.handleDryRun)
+		// A dry run plans the cut, which may call out to the node.
+		api.POST("/cut/dryrun", r.handler.hmacMiddleware(), r.handleDryRun)
 
 		export := api.Group("/export")
 		{
@@ -67,67 +125,174 @@ func (r *Routes) RegisterRoutes(g *gin.Engine) {
 			export.GET("/report.html", r.exportHTMLReport)
 		}
 
+		api.POST("/history/import/external", r.handler.hmacMiddleware(), r.importExternalHistory)
+
 		api.POST("/correlation/import", r.importClothoReport)
 		api.GET("/correlation/:node", r.getCorrelation)
 	}
 }
 
 type StatsResponse struct {
-	TotalCuts     int                        `json:"total_cuts"`
-	SuccessCuts   int                        `json:"success_cuts"`
-	FailedCuts    int                        `json:"failed_cuts"`
-	SuccessRate   float64                    `json:"success_rate"`
-	FirstCut      *string                    `json:"first_cut,omitempty"`
-	LastCut       *string                    `json:"last_cut,omitempty"`
-	TotalDuration int64                      `json:"total_duration_seconds"`
-	ByNode        map[string]int             `json:"by_node"`
-	ByAction      map[string]int             `json:"by_action"`
-	Nodes         map[string]NodeStatsDetail `json:"nodes"`
+	TotalCuts          int                        `json:"total_cuts"`
+	SuccessCuts        int                        `json:"success_cuts"`
+	FailedCuts         int                        `json:"failed_cuts"`
+	DeferredCuts       int                        `json:"deferred_cuts"`
+	DryRunCuts         int                        `json:"dry_run_cuts"`
+	SkippedCuts        int                        `json:"skipped_cuts"`
+	DuplicateCuts      int                        `json:"duplicate_cuts"`
+	SkippedByOutcome   map[string]int             `json:"skipped_by_outcome"`
+	ChainedCuts        int                        `json:"chained_cuts"`
+	SuccessRate        float64                    `json:"success_rate"`
+	FirstCut           *string                    `json:"first_cut,omitempty"`
+	LastCut            *string                    `json:"last_cut,omitempty"`
+	TotalDuration      int64                      `json:"total_duration_seconds"`
+	TotalDurationHuman string                     `json:"total_duration_human,omitempty"`
+	ByNode             map[string]int             `json:"by_node"`
+	ByAction           map[string]int             `json:"by_action"`
+	ByOutcome          map[string]int             `json:"by_outcome,omitempty"`
+	ByLabel            map[string]int             `json:"by_label,omitempty"`
+	PolicyHashes       []history.PolicyHashUsage  `json:"policy_hashes,omitempty"`
+	Nodes              map[string]NodeStatsDetail `json:"nodes"`
 }
 
 type NodeStatsDetail struct {
-	TotalCuts int `json:"total_cuts"`
-	Success   int `json:"success"`
-	Failed    int `json:"failed"`
+	TotalCuts int  `json:"total_cuts"`
+	Success   int  `json:"success"`
+	Failed    int  `json:"failed"`
+	Silenced  bool `json:"silenced,omitempty"`
+}
+
+// CutListResponse is returned by the history listing endpoints. Offset
+// skips that many of the newest matching records.
+type CutListResponse struct {
+	Node   string               `json:"node,omitempty"`
+	Count  int                  `json:"count"`
+	Offset int                  `json:"offset,omitempty"`
+	Cuts   []*history.CutRecord `json:"cuts"`
+}
+
+type SilenceListResponse struct {
+	Count    int                `json:"count"`
+	Silences []*history.Silence `json:"silences"`
+}
+
+type RateLimitListResponse struct {
+	Count     int                       `json:"count"`
+	Exhausted int                       `json:"exhausted"`
+	Nodes     []*engine.RateLimitStatus `json:"nodes"`
+}
+
+type NodeListResponse struct {
+	Count int                  `json:"count"`
+	Nodes []*engine.NodeStatus `json:"nodes"`
+}
+
+type PolicyReloadResponse struct {
+	Reloaded      bool   `json:"reloaded"`
+	PolicyVersion string `json:"policy_version,omitempty"`
+	PolicyHash    string `json:"policy_hash"`
+	NodeCount     int    `json:"node_count"`
+}
+
+// page parses ?limit= (default 100) and ?offset= and returns how many
+// records to fetch so the page can be sliced out afterwards.
+func page(c *gin.Context) (offset, fetch int) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	offset, _ = strconv.Atoi(c.Query("offset"))
+	if offset < 0 {
+		offset = 0
+	}
+	if limit <= 0 {
+		return offset, limit
+	}
+	return offset, limit + offset
+}
+
+func skip(cuts []*history.CutRecord, offset int) []*history.CutRecord {
+	if offset >= len(cuts) {
+		return []*history.CutRecord{}
+	}
+	return cuts[offset:]
 }
 
 func (r *Routes) listCuts(c *gin.Context) {
-	limitStr := c.DefaultQuery("limit", "100")
-	limit, _ := strconv.Atoi(limitStr)
+	offset, limit := page(c)
 
-	cuts, err := r.executor.GetHistory().ListCuts(limit)
+	filter, filtered, err := selectorFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if notModified(c, r.executor.GetHistory().Version()) {
+		return
+	}
+
+	var cuts []*history.CutRecord
+	if filtered {
+		cuts, err = r.executor.GetHistory().ListCuts(0)
+		cuts = history.FilterCuts(cuts, filter, limit)
+	} else {
+		cuts, err = r.executor.GetHistory().ListCuts(limit)
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"count": len(cuts),
-		"cuts":  cuts,
+	cuts = skip(cuts, offset)
+	c.JSON(http.StatusOK, CutListResponse{
+		Count:  len(cuts),
+		Offset: offset,
+		Cuts:   cuts,
 	})
 }
 
 func (r *Routes) listCutsByNode(c *gin.Context) {
 	node := c.Param("node")
-	limitStr := c.DefaultQuery("limit", "100")
-	limit, _ := strconv.Atoi(limitStr)
+	offset, limit := page(c)
 
-	cuts, err := r.executor.GetHistory().ListCutsByNode(node, limit)
+	filter, filtered, err := selectorFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if notModified(c, r.executor.GetHistory().Version()) {
+		return
+	}
+
+	var cuts []*history.CutRecord
+	if filtered {
+		cuts, err = r.executor.GetHistory().ListCutsByNode(node, 0)
+		cuts = history.FilterCuts(cuts, filter, limit)
+	} else {
+		cuts, err = r.executor.GetHistory().ListCutsByNode(node, limit)
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"node":  node,
-		"count": len(cuts),
-		"cuts":  cuts,
+	cuts = skip(cuts, offset)
+	c.JSON(http.StatusOK, CutListResponse{
+		Node:   node,
+		Count:  len(cuts),
+		Offset: offset,
+		Cuts:   cuts,
 	})
 }
 
 func (r *Routes) getCut(c *gin.Context) {
 	id := c.Param("id")
 
+	// A chain saves its first record before later steps finish, so the
+	// placeholder wins while the cut runs.
+	if cut, ok := r.executor.InProgressCut(id); ok {
+		c.JSON(http.StatusOK, cut)
+		return
+	}
+
 	cut, err := r.executor.GetHistory().LoadCut(id)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Cut not found"})
@@ -137,20 +302,51 @@ func (r *Routes) getCut(c *gin.Context) {
 	c.JSON(http.StatusOK, cut)
 }
 
+func (r *Routes) getCutChain(c *gin.Context) {
+	chain, err := r.executor.GetHistory().LoadChain(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Cut not found"})
+		return
+	}
+
+	chainID := chain[0].ChainID
+	if chainID == "" {
+		chainID = chain[0].ID
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"chain_id": chainID,
+		"cuts":     chain,
+	})
+}
+
 func (r *Routes) getStats(c *gin.Context) {
-	stats, err := r.executor.GetHistory().GetStats()
+	filter := recordFilter(c, false)
+	if days, err := strconv.Atoi(c.Query("days")); err == nil && days > 0 {
+		filter.Since = time.Now().AddDate(0, 0, -days)
+	}
+
+	stats, err := r.executor.GetHistory().GetStats(filter)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
 	response := &StatsResponse{
-		TotalCuts:   stats.TotalCuts,
-		SuccessCuts: stats.SuccessCuts,
-		FailedCuts:  stats.FailedCuts,
-		ByNode:      stats.ByNode,
-		ByAction:    stats.ByAction,
-		Nodes:       make(map[string]NodeStatsDetail),
+		TotalCuts:        stats.TotalCuts,
+		SuccessCuts:      stats.SuccessCuts,
+		FailedCuts:       stats.FailedCuts,
+		DeferredCuts:     stats.DeferredCuts,
+		DryRunCuts:       stats.DryRunCuts,
+		SkippedCuts:      stats.SkippedCuts,
+		DuplicateCuts:    stats.DuplicateCuts,
+		SkippedByOutcome: stats.SkippedByOutcome,
+		ChainedCuts:      stats.ChainedCuts,
+		ByNode:           stats.ByNode,
+		ByAction:         stats.ByAction,
+		ByOutcome:        stats.ByOutcome,
+		ByLabel:          stats.ByLabel,
+		PolicyHashes:     stats.PolicyHashes,
+		Nodes:            make(map[string]NodeStatsDetail),
 	}
 
 	if stats.TotalCuts > 0 {
@@ -158,22 +354,26 @@ func (r *Routes) getStats(c *gin.Context) {
 	}
 
 	if stats.FirstCut != nil {
-		firstCut := stats.FirstCut.Format("2006-01-02T15:04:05Z")
+		firstCut := timefmt.RFC3339(*stats.FirstCut)
 		response.FirstCut = &firstCut
 	}
 
 	if stats.LastCut != nil {
-		lastCut := stats.LastCut.Format("2006-01-02T15:04:05Z")
+		lastCut := timefmt.RFC3339(*stats.LastCut)
 		response.LastCut = &lastCut
 	}
 
 	response.TotalDuration = int64(stats.TotalDuration.Seconds())
+	if stats.TotalDuration > 0 {
+		response.TotalDurationHuman = timefmt.Human(stats.TotalDuration)
+	}
 
 	for node, nodeStats := range stats.Nodes {
 		response.Nodes[node] = NodeStatsDetail{
 			TotalCuts: nodeStats.TotalCuts,
 			Success:   nodeStats.Success,
 			Failed:    nodeStats.Failed,
+			Silenced:  r.executor.GetHistory().Silences().IsSilenced(node),
 		}
 	}
 
@@ -183,7 +383,7 @@ func (r *Routes) getStats(c *gin.Context) {
 func (r *Routes) getNodeStats(c *gin.Context) {
 	node := c.Param("node")
 
-	trend, err := r.analyzer.GetNodeTrends(node)
+	trend, err := r.analyzer.GetNodeTrends(node, recordFilter(c, true))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -192,11 +392,303 @@ func (r *Routes) getNodeStats(c *gin.Context) {
 	c.JSON(http.StatusOK, trend)
 }
 
+func (r *Routes) getNodeStatus(c *gin.Context) {
+	node := c.Param("node")
+
+	status, ok := r.executor.NodeStatus(node)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Node not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+func (r *Routes) getRateLimit(c *gin.Context) {
+	status, ok := r.executor.RateLimitStatus(c.Param("node"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Node not found"})
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+func (r *Routes) listRateLimits(c *gin.Context) {
+	resp := RateLimitListResponse{Nodes: r.executor.RateLimitStatuses()}
+	resp.Count = len(resp.Nodes)
+	for _, status := range resp.Nodes {
+		if status.Exhausted {
+			resp.Exhausted++
+		}
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// RateLimitResetRequest is the optional body of a rate-limit reset.
+type RateLimitResetRequest struct {
+	Source string `json:"source,omitempty"`
+	Reason string `json:"reason,omitempty"`
+}
+
+type RateLimitResetResponse struct {
+	Node     string                  `json:"node"`
+	Previous *engine.RateLimitStatus `json:"previous"`
+}
+
+func (r *Routes) resetRateLimit(c *gin.Context) {
+	var req RateLimitResetRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Source == "" {
+		req.Source = history.SourceWebhook
+	}
+
+	node := c.Param("node")
+	previous, ok := r.executor.ResetRateLimit(node, engine.RateLimitReset{
+		KeyID:    c.GetString(hmacKeyIDContextKey),
+		Source:   req.Source,
+		Reason:   req.Reason,
+		RemoteIP: c.ClientIP(),
+	})
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Node not found"})
+		return
+	}
+	c.JSON(http.StatusOK, RateLimitResetResponse{Node: node, Previous: previous})
+}
+
+func (r *Routes) getSnapshots(c *gin.Context) {
+	node := c.Param("node")
+	if _, ok := r.executor.GetPolicy().GetNode(node); !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Node not found"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+	snapshots, err := r.executor.Snapshots(ctx, node)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	if snapshots == nil {
+		snapshots = []string{}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"node":      node,
+		"snapshots": snapshots,
+	})
+}
+
+func (r *Routes) resetCircuit(c *gin.Context) {
+	node := c.Param("node")
+
+	if _, ok := r.executor.GetPolicy().GetNode(node); !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Node not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"node":  node,
+		"reset": r.executor.ResetCircuit(node),
+	})
+}
+
+type SilenceRequest struct {
+	Duration string `json:"duration" binding:"required"`
+	Reason   string `json:"reason" binding:"required"`
+}
+
+func (r *Routes) silenceNode(c *gin.Context) {
+	node := c.Param("node")
+
+	var req SilenceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	duration, err := time.ParseDuration(req.Duration)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid duration: " + err.Error()})
+		return
+	}
+	if duration <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "duration must be positive"})
+		return
+	}
+
+	if _, ok := r.executor.GetPolicy().GetNode(node); !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Node not found"})
+		return
+	}
+
+	silence, err := r.executor.GetHistory().Silences().Silence(node, duration, req.Reason)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, silence)
+}
+
+func (r *Routes) unsilenceNode(c *gin.Context) {
+	node := c.Param("node")
+
+	removed, err := r.executor.GetHistory().Silences().Unsilence(node)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !removed {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Node is not silenced"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"node": node, "silenced": false})
+}
+
+func (r *Routes) listSilences(c *gin.Context) {
+	silences := r.executor.GetHistory().Silences().Active()
+
+	c.JSON(http.StatusOK, SilenceListResponse{
+		Count:    len(silences),
+		Silences: silences,
+	})
+}
+
+func (r *Routes) listNodes(c *gin.Context) {
+	nodes := r.executor.NodeStatuses()
+	c.JSON(http.StatusOK, NodeListResponse{Count: len(nodes), Nodes: nodes})
+}
+
+// listSchedules shows when each node schedule fires next, so operators can
+// check a cron expression parsed as intended.
+func (r *Routes) listSchedules(c *gin.Context) {
+	count, err := strconv.Atoi(c.DefaultQuery("count", "5"))
+	if err != nil || count < 1 || count > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "count must be between 1 and 100"})
+		return
+	}
+
+	schedules := r.executor.Schedules(time.Now(), count)
+	if node := c.Query("node"); node != "" {
+		filtered := schedules[:0]
+		for _, s := range schedules {
+			if s.Node == node {
+				filtered = append(filtered, s)
+			}
+		}
+		schedules = filtered
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"count":     len(schedules),
+		"schedules": schedules,
+	})
+}
+
+func (r *Routes) reloadPolicy(c *gin.Context) {
+	pol, err := r.executor.ReloadPolicy()
+	if errors.Is(err, engine.ErrReloadUnsupported) {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, PolicyReloadResponse{
+		Reloaded:      true,
+		PolicyVersion: pol.Meta.Version,
+		PolicyHash:    pol.Hash(),
+		NodeCount:     len(pol.Nodes),
+	})
+}
+
+type ApprovalDecision struct {
+	By     string `json:"by" binding:"required"`
+	Reason string `json:"reason"`
+}
+
+func (r *Routes) listApprovals(c *gin.Context) {
+	pending := r.executor.PendingApprovals()
+
+	c.JSON(http.StatusOK, gin.H{
+		"count":     len(pending),
+		"approvals": pending,
+	})
+}
+
+func (r *Routes) approveCut(c *gin.Context) {
+	var req ApprovalDecision
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// The approved cut runs to the end even if the approver hangs up.
+	result, err := r.executor.Approve(context.WithoutCancel(c.Request.Context()), c.Param("id"), req.By, req.Reason)
+	if errors.Is(err, engine.ErrShuttingDown) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, engine.ErrExecutorSaturated) {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	resp := newCutResponse(result)
+	if result.Success {
+		c.JSON(http.StatusOK, resp)
+	} else if resp.Skipped || result.Outcome == history.OutcomeBlocked {
+		c.JSON(http.StatusConflict, resp)
+	} else if result.Outcome == history.OutcomeCircuitOpen {
+		c.JSON(http.StatusServiceUnavailable, resp)
+	} else {
+		c.JSON(http.StatusInternalServerError, resp)
+	}
+}
+
+func (r *Routes) rejectCut(c *gin.Context) {
+	var req ApprovalDecision
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := r.executor.Reject(c.Param("id"), req.By, req.Reason); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"id": c.Param("id"), "status": "rejected"})
+}
+
+func (r *Routes) lintPolicy(c *gin.Context) {
+	warnings := r.executor.GetPolicy().Lint(r.executor.HasCutter)
+	if warnings == nil {
+		warnings = []policy.LintWarning{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"count":    len(warnings),
+		"warnings": warnings,
+	})
+}
+
 func (r *Routes) getTrends(c *gin.Context) {
 	daysStr := c.DefaultQuery("days", "30")
 	days, _ := strconv.Atoi(daysStr)
 
-	trends, err := r.analyzer.GetGlobalTrends(days)
+	trends, err := r.analyzer.GetGlobalTrends(days, recordFilter(c, true))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -208,7 +700,7 @@ func (r *Routes) getTrends(c *gin.Context) {
 func (r *Routes) getNodeTrends(c *gin.Context) {
 	node := c.Param("node")
 
-	trend, err := r.analyzer.GetNodeTrends(node)
+	trend, err := r.analyzer.GetNodeTrends(node, recordFilter(c, true))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -219,16 +711,21 @@ func (r *Routes) getNodeTrends(c *gin.Context) {
 
 type DryRunRequest struct {
 	Node    string  `json:"node" binding:"required"`
-	Entropy float64 `json:"entropy" binding:"required,gte=0,lte=1"`
+	Entropy float64 `json:"entropy" binding:"required"`
 }
 
 type DryRunResponse struct {
-	Node         string  `json:"node"`
-	Entropy      float64 `json:"entropy"`
-	Action       string  `json:"action"`
-	WouldExecute bool    `json:"would_execute"`
-	Threshold    float64 `json:"threshold"`
-	Critical     bool    `json:"critical"`
+	Node         string                  `json:"node"`
+	Entropy      float64                 `json:"entropy"`
+	Action       string                  `json:"action"`
+	WouldExecute bool                    `json:"would_execute"`
+	Threshold    float64                 `json:"threshold"`
+	Critical     bool                    `json:"critical"`
+	Description  string                  `json:"description,omitempty"`
+	RunbookURL   string                  `json:"runbook_url,omitempty"`
+	BlockedBy    *engine.DependencyBlock `json:"blocked_by,omitempty"`
+	// Plan is each action's read-only check by its cutter.
+	Plan []engine.StepPlan `json:"plan,omitempty"`
 }
 
 func (r *Routes) handleDryRun(c *gin.Context) {
@@ -250,6 +747,13 @@ func (r *Routes) handleDryRun(c *gin.Context) {
 		return
 	}
 
+	entropy, _, err := policy.NormalizeEntropy(req.Entropy)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	req.Entropy = entropy
+
 	strategy, ok := nodePolicy.SelectStrategy(req.Entropy)
 	if !ok {
 		c.JSON(http.StatusOK, DryRunResponse{
@@ -261,13 +765,20 @@ func (r *Routes) handleDryRun(c *gin.Context) {
 		return
 	}
 
+	block := r.executor.CheckDependencies(nodePolicy)
+	plan := r.executor.Plan(c.Request.Context(), req.Node, nodePolicy, strategy)
+
 	c.JSON(http.StatusOK, DryRunResponse{
 		Node:         req.Node,
 		Entropy:      req.Entropy,
 		Action:       strategy.Action,
-		WouldExecute: true,
+		WouldExecute: block == nil,
+		BlockedBy:    block,
 		Threshold:    strategy.Threshold,
 		Critical:     strategy.Critical,
+		Description:  strategy.Description,
+		RunbookURL:   strategy.RunbookURL,
+		Plan:         plan,
 	})
 }
 
@@ -275,75 +786,166 @@ func (r *Routes) exportCSV(c *gin.Context) {
 	limitStr := c.DefaultQuery("limit", "1000")
 	limit, _ := strconv.Atoi(limitStr)
 
-	cuts, err := r.executor.GetHistory().ListCuts(limit)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	version := r.executor.GetHistory().Version()
+	if notModified(c, version) {
 		return
 	}
 
-	c.Header("Content-Type", "text/csv")
-	c.Header("Content-Disposition", "attachment; filename=cut_history.csv")
+	key := fmt.Sprintf("csv:%d:%d", limit, version.Counter)
+	body, count, ok := r.exports.get(key)
+	if !ok {
+		cuts, err := r.executor.GetHistory().ListCuts(limit)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		body, err = renderCSV(cuts)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		count = len(cuts)
+		r.exports.put(key, body, count)
+	}
 
-	csv := "ID,Node,Entropy,Action,Success,Error,LatencyMs,Timestamp\n"
+	r.writeExport(c, "cut_history.csv", "text/csv", body, count)
+}
+
+// renderCSV writes one row per cut. Every field is quoted as needed, since
+// errors, labels, tags, sources and reasons can hold commas, quotes and
+// line breaks.
+func renderCSV(cuts []*history.CutRecord) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write([]string{"ID", "Node", "Entropy", "Action", "Success", "Outcome", "Error", "LatencyMs", "Timestamp", "Labels", "Tags", "Source", "Reason"})
 	for _, cut := range cuts {
-		csv += cut.ID + ","
-		csv += cut.Node + ","
-		csv += strconv.FormatFloat(cut.Entropy, 'f', 4, 64) + ","
-		csv += cut.Action + ","
-		csv += strconv.FormatBool(cut.Success) + ","
-		csv += cut.Error + ","
-		csv += strconv.FormatInt(cut.LatencyMs, 10) + ","
-		csv += cut.Timestamp.Format("2006-01-02T15:04:05Z") + "\n"
+		w.Write([]string{
+			cut.ID,
+			cut.Node,
+			strconv.FormatFloat(cut.Entropy, 'f', 4, 64),
+			cut.Action,
+			strconv.FormatBool(cut.Success),
+			cut.Outcome,
+			cut.Error,
+			strconv.FormatInt(cut.LatencyMs, 10),
+			timefmt.RFC3339(cut.Timestamp),
+			formatLabels(cut.Strategy.Labels),
+			formatTags(cut.Tags),
+			cut.Source,
+			cut.Reason,
+		})
 	}
-
-	c.String(http.StatusOK, csv)
+	w.Flush()
+	return buf.Bytes(), w.Error()
 }
 
 func (r *Routes) exportJSON(c *gin.Context) {
 	limitStr := c.DefaultQuery("limit", "1000")
 	limit, _ := strconv.Atoi(limitStr)
 
-	cuts, err := r.executor.GetHistory().ListCuts(limit)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	version := r.executor.GetHistory().Version()
+	if notModified(c, version) {
 		return
 	}
 
-	c.Header("Content-Type", "application/json")
-	c.Header("Content-Disposition", "attachment; filename=cut_history.json")
+	// Only the cuts are cached; each response gets its own exported_at.
+	key := fmt.Sprintf("json:%d:%d", limit, version.Counter)
+	cutsJSON, count, ok := r.exports.get(key)
+	if !ok {
+		cuts, err := r.executor.GetHistory().ListCuts(limit)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		cutsJSON, err = json.Marshal(cuts)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		count = len(cuts)
+		r.exports.put(key, cutsJSON, count)
+	}
 
-	c.JSON(http.StatusOK, gin.H{
+	body, err := json.Marshal(gin.H{
 		"exported_at": exportTimestamp(),
-		"total_cuts":  len(cuts),
-		"cuts":        cuts,
+		"total_cuts":  count,
+		"cuts":        json.RawMessage(cutsJSON),
 	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	r.writeExport(c, "cut_history.json", "application/json", body, count)
+}
+
+// writeExport sends the rendered export as-is, or with ?signed=true wrapped
+// in a zip bundle with a signed manifest.
+func (r *Routes) writeExport(c *gin.Context, filename, contentType string, body []byte, count int) {
+	if signed, _ := strconv.ParseBool(c.Query("signed")); !signed {
+		c.Header("Content-Disposition", "attachment; filename="+filename)
+		c.Data(http.StatusOK, contentType, body)
+		return
+	}
+
+	pol := r.executor.GetPolicy()
+	if pol == nil || pol.GetExportSigningKey() == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "export signing key not configured"})
+		return
+	}
+
+	filters := make(map[string]string)
+	for key, values := range c.Request.URL.Query() {
+		if key != "signed" {
+			filters[key] = strings.Join(values, ",")
+		}
+	}
+
+	manifest := export.Manifest{
+		DataFile:      filename,
+		RecordCount:   count,
+		Filters:       filters,
+		ExportedAt:    time.Now(),
+		PolicyVersion: pol.Meta.Version,
+		PolicyHash:    pol.Hash(),
+	}
+
+	var bundle bytes.Buffer
+	if err := export.WriteBundle(&bundle, body, manifest, []byte(pol.GetExportSigningKey())); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	name := strings.TrimSuffix(filename, filepath.Ext(filename)) + "_signed.zip"
+	c.Header("Content-Disposition", "attachment; filename="+name)
+	c.Data(http.StatusOK, "application/zip", bundle.Bytes())
 }
 
 func (r *Routes) exportHTMLReport(c *gin.Context) {
 	limitStr := c.DefaultQuery("limit", "1000")
 	limit, _ := strconv.Atoi(limitStr)
 
+	if notModified(c, r.executor.GetHistory().Version()) {
+		return
+	}
+
 	cuts, err := r.executor.GetHistory().ListCuts(limit)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	stats, err := r.executor.GetHistory().GetStats()
+	stats, err := r.executor.GetHistory().GetStats(recordFilter(c, false))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.Header("Content-Type", "text/html")
-	c.Header("Content-Disposition", "attachment; filename=remediation_report.html")
-
 	successRate := 0.0
 	if stats.TotalCuts > 0 {
 		successRate = float64(stats.SuccessCuts) / float64(stats.TotalCuts) * 100
 	}
 
-	html := `<!DOCTYPE html>
+	report := `<!DOCTYPE html>
 <html lang="en">
 <head>
     <meta charset="UTF-8">
@@ -370,6 +972,7 @@ func (r *Routes) exportHTMLReport(c *gin.Context) {
         .badge { padding: 0.25rem 0.5rem; border-radius: 4px; font-size: 0.85rem; }
         .badge.success { background: #d4edda; color: #155724; }
         .badge.failure { background: #f8d7da; color: #721c24; }
+        .badge.skipped { background: #e2e3e5; color: #383d41; }
     </style>
 </head>
 <body>
@@ -412,6 +1015,7 @@ func (r *Routes) exportHTMLReport(c *gin.Context) {
                         <th>Entropy</th>
                         <th>Status</th>
                         <th>Latency</th>
+                        <th>Source</th>
                     </tr>
                 </thead>
                 <tbody>
@@ -422,19 +1026,33 @@ func (r *Routes) exportHTMLReport(c *gin.Context) {
 		if !cut.Success {
 			statusBadge = `<span class="badge failure">Failed</span>`
 		}
+		if cut.Skipped() {
+			statusBadge = `<span class="badge skipped">` + html.EscapeString(cut.Outcome) + `</span>`
+		}
 
-		html += `
+		action := html.EscapeString(cut.Action)
+		if cut.Strategy.RunbookURL != "" {
+			action = `<a href="` + html.EscapeString(cut.Strategy.RunbookURL) + `">` + action + `</a>`
+		}
+
+		source := html.EscapeString(cut.Source)
+		if cut.Reason != "" {
+			source += `<br><small>` + html.EscapeString(cut.Reason) + `</small>`
+		}
+
+		report += `
                     <tr>
-                        <td>` + cut.Timestamp.Format("2006-01-02 15:04:05") + `</td>
-                        <td>` + cut.Node + `</td>
-                        <td>` + cut.Action + `</td>
+                        <td>` + timefmt.RFC3339(cut.Timestamp) + `</td>
+                        <td>` + html.EscapeString(cut.Node) + `</td>
+                        <td>` + action + `</td>
                         <td>` + strconv.FormatFloat(cut.Entropy, 'f', 4, 64) + `</td>
                         <td>` + statusBadge + `</td>
                         <td>` + strconv.FormatInt(cut.LatencyMs, 10) + `ms</td>
+                        <td>` + source + `</td>
                     </tr>`
 	}
 
-	html += `
+	report += `
                 </tbody>
             </table>
         </div>
@@ -454,16 +1072,16 @@ func (r *Routes) exportHTMLReport(c *gin.Context) {
 `
 
 	for nodeId, nodeStats := range stats.Nodes {
-		html += `
+		report += `
                     <tr>
-                        <td>` + nodeId + `</td>
+                        <td>` + html.EscapeString(nodeId) + `</td>
                         <td>` + strconv.Itoa(nodeStats.TotalCuts) + `</td>
                         <td class="success">` + strconv.Itoa(nodeStats.Success) + `</td>
                         <td class="failure">` + strconv.Itoa(nodeStats.Failed) + `</td>
                     </tr>`
 	}
 
-	html += `
+	report += `
                 </tbody>
             </table>
         </div>
@@ -481,14 +1099,14 @@ func (r *Routes) exportHTMLReport(c *gin.Context) {
 `
 
 	for action, count := range stats.ByAction {
-		html += `
+		report += `
                     <tr>
-                        <td>` + action + `</td>
+                        <td>` + html.EscapeString(action) + `</td>
                         <td>` + strconv.Itoa(count) + `</td>
                     </tr>`
 	}
 
-	html += `
+	report += `
                 </tbody>
             </table>
         </div>
@@ -496,13 +1114,186 @@ func (r *Routes) exportHTMLReport(c *gin.Context) {
 </body>
 </html>`
 
-	c.String(http.StatusOK, html)
+	r.writeExport(c, "remediation_report.html", "text/html", []byte(report), len(cuts))
 }
 
-func (r *Routes) importClothoReport(c *gin.Context) {
-	importer := correlation.NewClothoImporter()
+func (r *Routes) ready(c *gin.Context) {
+	exceeded := r.executor.GetHistory().QuotaExceeded()
+	if len(exceeded) > 0 {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"ready":                  false,
+			"history_quota_exceeded": exceeded,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"ready": true})
+}
+
+// CutterResponse is a registered cutter with the result of its last
+// background health check.
+type CutterResponse struct {
+	cutter.Info
+	Health *engine.CutterHealth `json:"health,omitempty"`
+}
+
+func (r *Routes) listCutters(c *gin.Context) {
+	health := make(map[string]engine.CutterHealth)
+	for _, h := range r.executor.CutterHealth() {
+		health[h.Cutter] = h
+	}
+	infos := r.executor.Cutters()
+	cutters := make([]CutterResponse, len(infos))
+	for i, info := range infos {
+		cutters[i] = CutterResponse{Info: info}
+		if h, ok := health[info.Name]; ok {
+			cutters[i].Health = &h
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"cutters": cutters})
+}
+
+// cuttersHealth runs every cutter's health check now rather than reading
+// the last background result.
+// cuttersHealth serves the results of the last background health check, so
+// a caller can't make the server probe on demand. Before the first check it
+// runs one.
+func (r *Routes) cuttersHealth(c *gin.Context) {
+	results := r.executor.CutterHealth()
+	if results == nil {
+		results = r.executor.CheckCutters(c.Request.Context())
+	}
+	component := engine.CutterComponent(results)
+
+	status := http.StatusOK
+	if component.Status != engine.HealthOperational {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, gin.H{
+		"status":  component.Status,
+		"cutters": results,
+	})
+}
+
+// serveMetrics writes the Prometheus metrics. Like the other read endpoints
+// it needs no signature; server.disable_metrics turns it off.
+func (r *Routes) serveMetrics(c *gin.Context) {
+	if pol := r.executor.GetPolicy(); pol != nil && pol.Server.DisableMetrics {
+		c.JSON(http.StatusNotFound, gin.H{"error": "metrics disabled"})
+		return
+	}
+	c.Header("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	c.Status(http.StatusOK)
+	metrics.Write(c.Writer)
+}
+
+func (r *Routes) debugState(c *gin.Context) {
+	c.JSON(http.StatusOK, r.executor.StateSummary())
+}
+
+func recordFilter(c *gin.Context, includeImported bool) history.Filter {
+	filter := history.Filter{IncludeImported: includeImported}
+	if v, err := strconv.ParseBool(c.Query("include_imported")); err == nil {
+		filter.IncludeImported = v
+	}
+	if v, err := strconv.ParseBool(c.Query("include_dry_run")); err == nil {
+		filter.IncludeDryRun = v
+	}
+	if v, err := strconv.ParseBool(c.Query("exclude_noop")); err == nil {
+		filter.ExcludeNoop = v
+	}
+	if v, err := strconv.ParseBool(c.Query("include_skipped")); err == nil {
+		filter.IncludeSkipped = v
+	}
+	return filter
+}
+
+func formatTags(tags map[string]string) string {
+	pairs := make([]string, 0, len(tags))
+	for key, value := range tags {
+		pairs = append(pairs, history.TagKey(key, value))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ";")
+}
+
+func selectorFilter(c *gin.Context) (history.Filter, bool, error) {
+	filter := history.Filter{IncludeImported: true, IncludeDryRun: true, IncludeSkipped: true}
+	filtered := false
+	if v, err := strconv.ParseBool(c.Query("include_skipped")); err == nil && !v {
+		filter.IncludeSkipped, filtered = false, true
+	}
+	if outcome := c.Query("outcome"); outcome != "" {
+		filter.Outcome, filtered = outcome, true
+	}
 
-	report, err := importer.ImportReport(c.Request.Body)
+	for _, s := range c.QueryArray("label") {
+		key, value, err := history.ParseLabelSelector(s)
+		if err != nil {
+			return filter, false, err
+		}
+		if filter.Labels == nil {
+			filter.Labels = make(map[string]string)
+		}
+		filter.Labels[key] = value
+	}
+
+	for _, s := range c.QueryArray("tag") {
+		key, value, err := history.ParseTagSelector(s)
+		if err != nil {
+			return filter, false, err
+		}
+		if filter.Tags == nil {
+			filter.Tags = make(map[string]string)
+		}
+		filter.Tags[key] = value
+	}
+
+	return filter, filtered || len(filter.Labels) > 0 || len(filter.Tags) > 0, nil
+}
+
+func formatLabels(labels map[string]string) string {
+	pairs := make([]string, 0, len(labels))
+	for key, value := range labels {
+		pairs = append(pairs, history.LabelKey(key, value))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ";")
+}
+
+func (r *Routes) importExternalHistory(c *gin.Context) {
+	records, errs := history.ParseExternalRecords(c.Request.Body)
+	if len(errs) > 0 {
+		if len(errs) > 50 {
+			errs = errs[:50]
+		}
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":  "validation failed, nothing imported",
+			"errors": errs,
+		})
+		return
+	}
+
+	imported := 0
+	for _, record := range records {
+		if err := r.executor.GetHistory().SaveNewCut(record); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":    err.Error(),
+				"imported": imported,
+			})
+			return
+		}
+		imported++
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "External history imported successfully",
+		"imported": imported,
+	})
+}
+
+func (r *Routes) importClothoReport(c *gin.Context) {
+	report, err := r.reports.ImportReport(c.Request.Body)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to parse Clotho report: " + err.Error()})
 		return
@@ -523,8 +1314,6 @@ func (r *Routes) getCorrelation(c *gin.Context) {
 
 	timeWindow := time.Duration(hours) * time.Hour
 
-	importer := correlation.NewClothoImporter()
-
 	cuts, err := r.executor.GetHistory().ListCutsByNode(node, 0)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -541,7 +1330,7 @@ func (r *Routes) getCorrelation(c *gin.Context) {
 		})
 	}
 
-	correlator := correlation.NewCorrelator(importer, cutRefs)
+	correlator := correlation.NewCorrelator(r.reports, cutRefs)
 
 	result, err := correlator.Correlate(node, timeWindow)
 	if err != nil {
@@ -569,7 +1358,7 @@ func (r *Routes) getCorrelation(c *gin.Context) {
 }
 
 func exportTimestamp() string {
-	return time.Now().Format("2006-01-02T15:04:05Z")
+	return timefmt.Now()
 }
 
 func (r *Routes) serveDashboard(c *gin.Context) {
@@ -0,0 +1,389 @@
+package api_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"atropos/api"
+	"atropos/client"
+	"atropos/history"
+)
+
+func TestImportExternalHistoryRequiresSignature(t *testing.T) {
+	s := newTestServer(t, testPolicy)
+	body := []byte(`{"node":"athena","action":"restart_nginx","success":true,"timestamp":"2025-11-02T03:14:00Z"}` + "\n")
+
+	if status, _ := s.do(t, http.MethodPost, "/api/v1/history/import/external", "", body); status != http.StatusUnauthorized {
+		t.Fatalf("unsigned import status = %d, want 401", status)
+	}
+	if status, _ := s.do(t, http.MethodPost, "/api/v1/history/import/external", "wrong", body); status != http.StatusForbidden {
+		t.Fatalf("badly signed import status = %d, want 403", status)
+	}
+	cuts, err := s.executor.GetHistory().ListCutsByNode("athena", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cuts) != 0 {
+		t.Fatalf("rejected imports stored %d records", len(cuts))
+	}
+
+	if status, data := s.do(t, http.MethodPost, "/api/v1/history/import/external", testSecret, body); status != http.StatusOK {
+		t.Fatalf("signed import status = %d: %s", status, data)
+	}
+}
+
+func TestSilenceRequiresSignature(t *testing.T) {
+	s := newTestServer(t, testPolicy)
+	ctx := context.Background()
+
+	unsigned := s.client(t, "")
+	_, err := unsigned.Silence(ctx, "athena", "1h", "INC-1")
+	if status := statusOf(t, err); status != http.StatusUnauthorized {
+		t.Fatalf("unsigned silence status = %d, want 401", status)
+	}
+	if status := statusOf(t, unsigned.Unsilence(ctx, "athena")); status != http.StatusUnauthorized {
+		t.Fatalf("unsigned unsilence status = %d, want 401", status)
+	}
+	if s.executor.GetHistory().Silences().IsSilenced("athena") {
+		t.Fatal("unsigned request silenced the node")
+	}
+
+	signed := s.client(t, testSecret)
+	silence, err := signed.Silence(ctx, "athena", "1h", "INC-1")
+	if err != nil {
+		t.Fatalf("signed silence: %v", err)
+	}
+	if silence.Node != "athena" || silence.Reason != "INC-1" {
+		t.Fatalf("silence = %+v", silence)
+	}
+	if err := signed.Unsilence(ctx, "athena"); err != nil {
+		t.Fatalf("signed unsilence: %v", err)
+	}
+	if s.executor.GetHistory().Silences().IsSilenced("athena") {
+		t.Fatal("node still silenced after unsilence")
+	}
+}
+
+func TestSnapshotsRequireSignature(t *testing.T) {
+	s := newTestServer(t, `
+server:
+  hmac_secret: `+testSecret+`
+nodes:
+  athena:
+    strategies:
+      - threshold: 0.5
+        action: test_restart
+`)
+	if status, _ := s.do(t, http.MethodGet, "/api/v1/nodes/athena/snapshots", "", nil); status != http.StatusUnauthorized {
+		t.Fatalf("unsigned snapshots status = %d, want 401", status)
+	}
+	if status, _ := s.do(t, http.MethodGet, "/api/v1/nodes/athena/snapshots", "wrong", nil); status != http.StatusForbidden {
+		t.Fatalf("badly signed snapshots status = %d, want 403", status)
+	}
+	if status, _ := s.do(t, http.MethodGet, "/api/v1/nodes/nowhere/snapshots", testSecret, nil); status != http.StatusNotFound {
+		t.Fatalf("signed snapshots of an unknown node = %d, want 404", status)
+	}
+}
+
+func TestSilenceStoreFailureIsServerError(t *testing.T) {
+	s := newTestServer(t, testPolicy)
+	historyDir := filepath.Join(s.dir, "history")
+	if err := os.RemoveAll(historyDir); err != nil {
+		t.Fatal(err)
+	}
+	// A file where the history directory was makes persisting fail.
+	if err := os.WriteFile(historyDir, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := s.client(t, testSecret).Silence(context.Background(), "athena", "1h", "INC-1")
+	if status := statusOf(t, err); status != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want 500: %v", status, err)
+	}
+}
+
+// Every free-text field survives the CSV export, whatever it holds.
+func TestCSVExportEscapesFields(t *testing.T) {
+	s := newTestServer(t, testPolicy)
+	cut := &history.CutRecord{
+		Node:      "athena",
+		Action:    "test_restart",
+		Error:     "exit 1, \"boom\"\nstderr: no",
+		Timestamp: time.Now(),
+		Strategy:  history.StrategyInfo{Labels: map[string]string{"team": "db,ops"}},
+		Tags:      map[string]string{"run": `say "hi"`},
+		Source:    "agent,1",
+		Reason:    "first\rsecond",
+	}
+	if err := s.executor.GetHistory().SaveNewCut(cut); err != nil {
+		t.Fatal(err)
+	}
+
+	var body bytes.Buffer
+	if err := s.client(t, testSecret).Export(context.Background(), client.ExportCSV, 0, &body); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(&body).ReadAll()
+	if err != nil {
+		t.Fatalf("export is not valid CSV: %v", err)
+	}
+	if len(rows) != 2 || len(rows[1]) != len(rows[0]) {
+		t.Fatalf("export rows %q, want a header and one cut", rows)
+	}
+	got := make(map[string]string)
+	for i, name := range rows[0] {
+		got[name] = rows[1][i]
+	}
+	want := map[string]string{
+		"Error":  cut.Error,
+		"Labels": "team=db,ops",
+		"Tags":   `run:say "hi"`,
+		"Source": cut.Source,
+		"Reason": cut.Reason,
+	}
+	for name, value := range want {
+		if got[name] != value {
+			t.Errorf("%s = %q, want %q", name, got[name], value)
+		}
+	}
+}
+
+// Names from history are escaped wherever the HTML report shows them.
+func TestHTMLReportEscapes(t *testing.T) {
+	s := newTestServer(t, testPolicy)
+	for _, cut := range []*history.CutRecord{
+		{ID: "cut_1_html", Node: "<b>athena</b>", Action: "test_restart", Success: true, Timestamp: time.Now()},
+		{Node: "athena", Action: "<script>alert(1)</script>", Success: true, Timestamp: time.Now()},
+	} {
+		if err := s.executor.GetHistory().SaveNewCut(cut); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	status, report := s.do(t, http.MethodGet, "/api/v1/export/report.html", "", nil)
+	body := string(report)
+	if status != http.StatusOK {
+		t.Fatalf("status = %d", status)
+	}
+	for _, raw := range []string{"<b>athena</b>", "<script>"} {
+		if strings.Contains(body, raw) {
+			t.Fatalf("report has %q unescaped", raw)
+		}
+	}
+	for _, escaped := range []string{"&lt;b&gt;athena&lt;/b&gt;", "&lt;script&gt;alert(1)&lt;/script&gt;"} {
+		if strings.Count(body, escaped) < 2 {
+			t.Fatalf("report shows %q fewer than twice, want the cut and its stats row", escaped)
+		}
+	}
+}
+
+func TestTimestampsAreUTC(t *testing.T) {
+	s := newTestServer(t, testPolicy)
+	pdt := time.FixedZone("PDT", -7*3600)
+	first := time.Date(2025, 11, 1, 20, 14, 0, 0, pdt)
+	last := time.Date(2025, 11, 2, 12, 44, 0, 0, time.Local)
+	for _, ts := range []time.Time{first, last} {
+		if err := s.executor.GetHistory().SaveNewCut(&history.CutRecord{Node: "athena", Action: "test_restart", Success: true, Timestamp: ts}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c := s.client(t, testSecret)
+	ctx := context.Background()
+
+	stats, err := c.Stats(ctx, client.StatsQuery{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.FirstCut == nil || *stats.FirstCut != "2025-11-02T03:14:00Z" {
+		t.Fatalf("first_cut = %v, want 2025-11-02T03:14:00Z", stats.FirstCut)
+	}
+	if stats.LastCut == nil || *stats.LastCut != "2025-11-02T03:14:00Z" {
+		t.Fatalf("last_cut = %v, want 2025-11-02T03:14:00Z", stats.LastCut)
+	}
+
+	var csv bytes.Buffer
+	if err := c.Export(ctx, client.ExportCSV, 0, &csv); err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(csv.String(), ",2025-11-02T03:14:00Z,"); n != 2 {
+		t.Fatalf("CSV export has %d UTC timestamps, want 2:\n%s", n, csv.String())
+	}
+
+	var body bytes.Buffer
+	if err := c.Export(ctx, client.ExportJSON, 0, &body); err != nil {
+		t.Fatal(err)
+	}
+	var export struct {
+		ExportedAt string `json:"exported_at"`
+		Cuts       []struct {
+			Timestamp string `json:"timestamp"`
+		} `json:"cuts"`
+	}
+	if err := json.Unmarshal(body.Bytes(), &export); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := time.Parse(time.RFC3339, export.ExportedAt); err != nil || !strings.HasSuffix(export.ExportedAt, "Z") {
+		t.Fatalf("exported_at = %q, want UTC RFC3339", export.ExportedAt)
+	}
+	for _, cut := range export.Cuts {
+		if cut.Timestamp != "2025-11-02T03:14:00Z" {
+			t.Fatalf("exported cut timestamp = %q, want UTC", cut.Timestamp)
+		}
+	}
+}
+
+func TestReadyReportsHistoryQuota(t *testing.T) {
+	s := newTestServer(t, `
+server:
+  hmac_secret: `+testSecret+`
+  dedup_window_seconds: 0
+nodes:
+  athena:
+    strategies:
+      - threshold: 0.5
+        action: test_restart
+`)
+	h := s.executor.GetHistory()
+	if err := h.SetQuota(history.Quota{MaxRecordsPerNode: 1}, s.executor.HistoryQuotaExceeded); err != nil {
+		t.Fatal(err)
+	}
+	ready := func() (int, map[string]interface{}) {
+		status, data := s.do(t, http.MethodGet, "/api/v1/ready", "", nil)
+		var body map[string]interface{}
+		if err := json.Unmarshal(data, &body); err != nil {
+			t.Fatal(err)
+		}
+		return status, body
+	}
+
+	c := s.client(t, testSecret)
+	for i := 0; i < 2; i++ {
+		if _, _, err := c.Cut(context.Background(), api.CutRequest{Node: "athena", Entropy: 0.8}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	status, body := ready()
+	if status != http.StatusServiceUnavailable || body["ready"] != false {
+		t.Fatalf("ready over the quota: %d %v, want 503", status, body)
+	}
+	exceeded, _ := body["history_quota_exceeded"].([]interface{})
+	if len(exceeded) != 1 || exceeded[0].(map[string]interface{})["node"] != "athena" {
+		t.Fatalf("history_quota_exceeded = %v, want athena", body["history_quota_exceeded"])
+	}
+
+	if _, err := h.TrimToQuota(); err != nil {
+		t.Fatal(err)
+	}
+	if status, body := ready(); status != http.StatusOK || body["ready"] != true {
+		t.Fatalf("ready after the trim: %d %v, want 200", status, body)
+	}
+}
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// checkGolden compares the JSON body with testdata/name.golden.
+func checkGolden(t *testing.T, name string, body []byte) {
+	t.Helper()
+	var got bytes.Buffer
+	if err := json.Indent(&got, body, "", "  "); err != nil {
+		t.Fatal(err)
+	}
+	got.WriteByte('\n')
+	path := filepath.Join("testdata", name+".golden")
+	if *update {
+		if err := os.WriteFile(path, got.Bytes(), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got.Bytes(), want) {
+		t.Fatalf("%s differs from %s:\n%s", name, path, got.Bytes())
+	}
+}
+
+func TestStatsResponseJSON(t *testing.T) {
+	s := newTestServer(t, testPolicy)
+	base := time.Date(2025, 11, 2, 3, 0, 0, 0, time.UTC)
+	for i, offset := range []time.Duration{0, 45 * time.Minute, 90*time.Minute + 500*time.Millisecond} {
+		record := &history.CutRecord{Node: "athena", Action: "test_restart", Success: i != 1, Timestamp: base.Add(offset)}
+		if err := s.executor.GetHistory().SaveNewCut(record); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	status, body := s.do(t, http.MethodGet, "/api/v1/stats", "", nil)
+	if status != http.StatusOK {
+		t.Fatalf("stats status %d: %s", status, body)
+	}
+	checkGolden(t, "stats_response", body)
+}
+
+// probeCutter counts its health checks.
+type probeCutter struct {
+	mu     sync.Mutex
+	probes int
+}
+
+func (c *probeCutter) Name() string                 { return "probe" }
+func (c *probeCutter) CanHandle(action string) bool { return action == "probe_restart" }
+func (c *probeCutter) Execute(context.Context, string, map[string]string) error {
+	return nil
+}
+
+func (c *probeCutter) HealthCheck(context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.probes++
+	return nil
+}
+
+func (c *probeCutter) Probes() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.probes
+}
+
+// Requests read the last check rather than probing again.
+func TestCuttersHealthServesLastCheck(t *testing.T) {
+	s := newTestServer(t, `
+nodes:
+  athena:
+    strategies:
+      - threshold: 0.5
+        action: probe_restart
+`)
+	probe := &probeCutter{}
+	s.executor.RegisterCutter(probe)
+
+	for i := 0; i < 3; i++ {
+		status, body := s.do(t, http.MethodGet, "/api/v1/cutters/health", "", nil)
+		if status != http.StatusOK || !strings.Contains(string(body), `"cutter":"probe","status":"healthy"`) {
+			t.Fatalf("request %d: %d %s", i+1, status, body)
+		}
+	}
+	if probes := probe.Probes(); probes != 1 {
+		t.Fatalf("cutter probed %d times for 3 requests, want once", probes)
+	}
+
+	s.executor.StartHealthChecks(time.Hour)
+	if probes := probe.Probes(); probes != 2 {
+		t.Fatalf("cutter probed %d times after a background check, want 2", probes)
+	}
+	s.do(t, http.MethodGet, "/api/v1/cutters/health", "", nil)
+	if probes := probe.Probes(); probes != 2 {
+		t.Fatalf("request after the background check probed again: %d", probes)
+	}
+}
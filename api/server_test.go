@@ -0,0 +1,179 @@
+package api_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"atropos/api"
+	"atropos/client"
+	"atropos/engine"
+	"atropos/history"
+	"atropos/notifications"
+	"atropos/policy"
+)
+
+const testSecret = "test-secret"
+
+// The API tests run in a local zone off UTC by a half hour, so a timestamp
+// formatted in local time shows up in any response that carries one.
+func TestMain(m *testing.M) {
+	os.Setenv("TZ", "Australia/Adelaide")
+	time.Local = time.FixedZone("ACST", 9*3600+30*60)
+	os.Exit(m.Run())
+}
+
+// testCutter handles test_ actions. While block is set, each call waits on
+// it so a test can hold cuts in flight.
+type testCutter struct {
+	mu    sync.Mutex
+	calls int
+	block chan struct{}
+}
+
+func (c *testCutter) Name() string { return "test" }
+
+func (c *testCutter) CanHandle(action string) bool {
+	return strings.HasPrefix(action, "test_")
+}
+
+func (c *testCutter) Execute(ctx context.Context, target string, params map[string]string) error {
+	c.mu.Lock()
+	c.calls++
+	block := c.block
+	c.mu.Unlock()
+	if block != nil {
+		select {
+		case <-block:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+func (c *testCutter) Calls() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.calls
+}
+
+type testServer struct {
+	*httptest.Server
+	dir        string
+	policyPath string
+	executor   *engine.Executor
+	cutter     *testCutter
+}
+
+// newTestServer serves policyYAML with a testCutter registered. Reloading
+// re-reads the policy file, as main does.
+func newTestServer(t *testing.T, policyYAML string) *testServer {
+	t.Helper()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "policy.yaml")
+	if err := os.WriteFile(path, []byte(policyYAML), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	pol, err := policy.LoadPolicy(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	historyDir := filepath.Join(dir, "history")
+	notif := notifications.NewNotificationManager(&notifications.NotificationConfig{
+		StateFile: filepath.Join(historyDir, "notification_state.json"),
+	})
+	exec := engine.NewExecutor(pol, history.NewHistoryManager(historyDir), notif)
+	c := &testCutter{}
+	exec.RegisterCutter(c)
+	exec.SetReloader(func() (*policy.RemediationPolicy, error) {
+		pol, err := policy.LoadPolicy(path)
+		if err != nil {
+			return nil, err
+		}
+		exec.SetPolicy(pol)
+		return pol, nil
+	})
+
+	s := &testServer{Server: httptest.NewServer(api.NewServer(exec)), dir: dir, policyPath: path, executor: exec, cutter: c}
+	t.Cleanup(s.Close)
+	return s
+}
+
+// client returns an API client signing with secret, or an unsigned one when
+// secret is empty.
+func (s *testServer) client(t *testing.T, secret string, opts ...client.Option) *client.Client {
+	t.Helper()
+	c, err := client.New(s.URL, append([]client.Option{client.WithHMACSecret(secret)}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// statusOf is the status a client call got: 200 for success, the server's
+// code for an *client.Error.
+func statusOf(t *testing.T, err error) int {
+	t.Helper()
+	if err == nil {
+		return http.StatusOK
+	}
+	var apiErr *client.Error
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("request failed before the server answered: %v", err)
+	}
+	return apiErr.StatusCode
+}
+
+// do sends body, signed with secret unless it is empty, and returns the
+// status and response body. It is for routes the client has no method for.
+func (s *testServer) do(t *testing.T, method, path, secret string, body []byte) (int, []byte) {
+	t.Helper()
+	req, err := http.NewRequest(method, s.URL+path, bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if secret != "" {
+		req.Header.Set(client.SignatureHeader, client.Sign(secret, body))
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, data
+}
+
+func mustJSON(t *testing.T, v interface{}) []byte {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+const testPolicy = `
+server:
+  hmac_secret: ` + testSecret + `
+nodes:
+  athena:
+    strategies:
+      - threshold: 0.5
+        action: test_restart
+`
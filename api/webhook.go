@@ -1,43 +1,101 @@
 package api
 
 import (
+	"context"
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"io"
 	"net/http"
+	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
 
+	"atropos/cutter"
 	"atropos/engine"
+	"atropos/history"
 	"atropos/internal/logger"
+	"atropos/internal/timefmt"
+	"atropos/policy"
 )
 
 type CutRequest struct {
-	Node      string  `json:"node" binding:"required"`
-	Entropy   float64 `json:"entropy" binding:"required,gte=0,lte=1"`
-	Timestamp string  `json:"timestamp"`
+	Node      string            `json:"node" binding:"required"`
+	Entropy   float64           `json:"entropy" binding:"required"`
+	Timestamp string            `json:"timestamp"`
+	Tags      map[string]string `json:"tags,omitempty"`
+	// CallbackURL asks for the outcome report to go here instead of the
+	// configured target; its host must be in callbacks.allowed_hosts.
+	CallbackURL string `json:"callback_url,omitempty"`
+	// Source names the calling system and Reason why it asked; both end up
+	// in the record and notifications. Source defaults to "webhook".
+	Source string `json:"source,omitempty"`
+	Reason string `json:"reason,omitempty"`
+}
+
+func (r CutRequest) source() string {
+	if r.Source == "" {
+		return history.SourceWebhook
+	}
+	return r.Source
 }
 
 type CutResponse struct {
+	CutID   string `json:"cut_id,omitempty"`
+	Node    string `json:"node"`
+	Action  string `json:"action"`
+	Success bool   `json:"success"`
+	DryRun  bool   `json:"dry_run,omitempty"`
+	// Skipped means the cut was refused before it ran; Outcome says why.
+	Skipped bool `json:"skipped,omitempty"`
+	// Duplicate means an identical request within the dedup window already
+	// ran; the rest of the response is its result.
+	Duplicate bool               `json:"duplicate,omitempty"`
+	Outcome   string             `json:"outcome,omitempty"`
+	Error     string             `json:"error,omitempty"`
+	LatencyMs int64              `json:"latency_ms"`
+	Chain     []cutter.ChainLink `json:"chain,omitempty"`
+	// Escalation is set when the selected strategy failed and a fallback
+	// or escalation produced this result.
+	Escalation *cutter.Escalation `json:"escalation,omitempty"`
+	// StatusURL is set on a 202 for a cut still running past the deadline;
+	// poll it until the outcome is no longer in_progress.
+	StatusURL string `json:"status_url,omitempty"`
+}
+
+// AsyncCutResponse is returned with 202 for POST /cut?async=true. The cut's
+// record is already saved with outcome in_progress.
+type AsyncCutResponse struct {
+	ID        string `json:"id"`
 	Node      string `json:"node"`
-	Action    string `json:"action"`
-	Success   bool   `json:"success"`
-	Error     string `json:"error,omitempty"`
-	LatencyMs int64  `json:"latency_ms"`
+	Outcome   string `json:"outcome"`
+	StatusURL string `json:"status_url"`
+}
+
+// JobAcceptedResponse is returned with 202 when the worker pool queues a cut.
+type JobAcceptedResponse struct {
+	JobID     string `json:"job_id"`
+	Status    string `json:"status"`
+	StatusURL string `json:"status_url"`
 }
 
+const hmacKeyIDContextKey = "hmac_key_id"
+
 type WebhookHandler struct {
-	executor   *engine.Executor
-	hmacSecret []byte
+	executor       *engine.Executor
+	entropyClamped atomic.Int64
 }
 
-func NewWebhookHandler(exec *engine.Executor, hmacSecret string) *WebhookHandler {
+// NewWebhookHandler checks signatures against the keys of the executor's
+// current policy, so a reload changes them.
+func NewWebhookHandler(exec *engine.Executor) *WebhookHandler {
 	return &WebhookHandler{
-		executor:   exec,
-		hmacSecret: []byte(hmacSecret),
+		executor: exec,
 	}
 }
 
@@ -52,50 +110,280 @@ func (h *WebhookHandler) RegisterRoutes(r *gin.Engine) {
 }
 
 func (h *WebhookHandler) handleCut(c *gin.Context) {
+	received := time.Now()
 	var req CutRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
+	if err := history.ValidateNodeName(req.Node); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := history.ValidateTags(req.Tags); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.checkCallbackURL(req.CallbackURL); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+
+	entropy, original, err := h.normalizeEntropy(req.Node, req.Entropy)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	req.Entropy = entropy
+
 	logger.WebhookReceived(req.Node, req.Entropy, true)
 
-	resultCh := h.executor.ExecuteCutAsync(c.Request.Context(), req.Node, req.Entropy)
+	opts := engine.CutOptions{
+		Tags:            req.Tags,
+		KeyID:           c.GetString(hmacKeyIDContextKey),
+		CallbackURL:     req.CallbackURL,
+		ReceivedAt:      received,
+		EntropyOriginal: original,
+		Source:          req.source(),
+		Reason:          req.Reason,
+	}
+
+	if h.executor.PoolEnabled() {
+		h.submitCut(c, req, opts)
+		return
+	}
+
+	if async, _ := strconv.ParseBool(c.Query("async")); async {
+		h.startCut(c, req, opts)
+		return
+	}
+
+	// The cut may outlive the request, so it gets the ID to poll up front
+	// and a context the caller hanging up doesn't cancel.
+	opts.CutID = history.NewCutID(req.Node, received)
+	ctx := context.WithoutCancel(c.Request.Context())
+	resultCh := h.executor.ExecuteCutAsync(ctx, req.Node, req.Entropy, opts)
 
 	select {
 	case result := <-resultCh:
-		resp := CutResponse{
-			Node:      result.Target,
-			Action:    result.Action,
-			Success:   result.Success,
-			LatencyMs: result.LatencyMs,
-		}
-		if result.Error != nil {
-			resp.Error = result.Error.Error()
-		}
+		resp := newCutResponse(result)
 
-		if result.Success {
+		if result.Outcome == history.OutcomePendingApproval {
+			c.JSON(http.StatusAccepted, resp)
+		} else if result.Outcome == history.OutcomeBlocked {
+			c.JSON(http.StatusConflict, resp)
+		} else if result.Outcome == history.OutcomeCircuitOpen {
+			c.JSON(http.StatusServiceUnavailable, resp)
+		} else if errors.Is(result.Error, engine.ErrExecutorSaturated) {
+			c.JSON(http.StatusTooManyRequests, resp)
+		} else if errors.Is(result.Error, engine.ErrShuttingDown) {
+			c.JSON(http.StatusServiceUnavailable, resp)
+		} else if result.Success {
 			c.JSON(http.StatusOK, resp)
 		} else {
 			c.JSON(http.StatusInternalServerError, resp)
 		}
 
-	case <-time.After(35 * time.Second):
-		c.JSON(http.StatusGatewayTimeout, gin.H{
-			"error": "cut operation timed out",
-			"node":  req.Node,
+	case <-time.After(h.executor.GetPolicy().GetCutDeadline()):
+		h.executor.PersistInProgress(opts.CutID)
+		c.JSON(http.StatusAccepted, CutResponse{
+			CutID:     opts.CutID,
+			Node:      req.Node,
+			Outcome:   history.OutcomeInProgress,
+			StatusURL: "/api/v1/cuts/" + opts.CutID,
 		})
 	}
 }
 
-func (h *WebhookHandler) handleHealth(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{
-		"status":  "operational",
-		"service": "atropos",
-		"ts":      time.Now().UTC().Format(time.RFC3339),
+// startCut answers POST /cut?async=true: the cut runs in the background
+// and the caller polls its record.
+func (h *WebhookHandler) startCut(c *gin.Context, req CutRequest, opts engine.CutOptions) {
+	if _, ok := h.executor.GetPolicy().GetNode(req.Node); !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "unknown node: " + req.Node})
+		return
+	}
+
+	id := h.executor.StartCut(context.WithoutCancel(c.Request.Context()), req.Node, req.Entropy, opts)
+	c.JSON(http.StatusAccepted, AsyncCutResponse{
+		ID:        id,
+		Node:      req.Node,
+		Outcome:   history.OutcomeInProgress,
+		StatusURL: "/api/v1/cuts/" + id,
+	})
+}
+
+type BatchCutRequest struct {
+	Cuts []CutRequest `json:"cuts" binding:"required,min=1,dive"`
+}
+
+type BatchCutResponse struct {
+	Order   []policy.OrderStep `json:"order"`
+	Results []CutResponse      `json:"results"`
+}
+
+func (h *WebhookHandler) handleBatchCut(c *gin.Context) {
+	received := time.Now()
+	var req BatchCutRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	seen := make(map[string]bool, len(req.Cuts))
+	cuts := make([]engine.BatchCut, 0, len(req.Cuts))
+	for _, cut := range req.Cuts {
+		if err := history.ValidateNodeName(cut.Node); err != nil {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			return
+		}
+		if seen[cut.Node] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "duplicate node in batch: " + cut.Node})
+			return
+		}
+		seen[cut.Node] = true
+
+		if err := history.ValidateTags(cut.Tags); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err := h.checkCallbackURL(cut.CallbackURL); err != nil {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			return
+		}
+		entropy, original, err := h.normalizeEntropy(cut.Node, cut.Entropy)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		cut.Entropy = entropy
+		logger.WebhookReceived(cut.Node, cut.Entropy, true)
+
+		cuts = append(cuts, engine.BatchCut{
+			Node:    cut.Node,
+			Entropy: cut.Entropy,
+			Opts: engine.CutOptions{
+				Tags:            cut.Tags,
+				KeyID:           c.GetString(hmacKeyIDContextKey),
+				CallbackURL:     cut.CallbackURL,
+				ReceivedAt:      received,
+				EntropyOriginal: original,
+				Source:          cut.source(),
+				Reason:          cut.Reason,
+			},
+		})
+	}
+
+	// The cuts outlive a caller that hangs up, as a single cut does.
+	resp := BatchCutResponse{}
+	for _, r := range h.executor.ExecuteBatch(context.WithoutCancel(c.Request.Context()), cuts) {
+		resp.Order = append(resp.Order, r.Step)
+		resp.Results = append(resp.Results, newCutResponse(r.Result))
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// submitCut queues the cut on the worker pool and answers 202 with a job to
+// poll, or 429 when the queue is full.
+func (h *WebhookHandler) submitCut(c *gin.Context, req CutRequest, opts engine.CutOptions) {
+	job, err := h.executor.SubmitCut(req.Node, req.Entropy, opts)
+	if err != nil {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusAccepted, JobAcceptedResponse{
+		JobID:     job.ID,
+		Status:    job.Status,
+		StatusURL: "/api/v1/jobs/" + job.ID,
 	})
 }
 
+func (h *WebhookHandler) getJob(c *gin.Context) {
+	job, ok := h.executor.Job(c.Param("id"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "job not found"})
+		return
+	}
+	c.JSON(http.StatusOK, job)
+}
+
+// normalizeEntropy applies server.entropy_out_of_range to a reading. When the
+// value was clamped, the original is returned alongside it.
+func (h *WebhookHandler) normalizeEntropy(node string, v float64) (float64, *float64, error) {
+	pol := h.executor.GetPolicy()
+	if pol == nil {
+		return v, nil, nil
+	}
+	entropy, clamped, err := pol.NormalizeEntropy(v)
+	if err != nil || !clamped {
+		return entropy, nil, err
+	}
+	h.entropyClamped.Add(1)
+	entropyClamped.Inc(node)
+	logger.Get().Warn("ENTROPY_CLAMPED",
+		zap.String("node", node),
+		zap.Float64("original", v),
+		zap.Float64("entropy", entropy),
+	)
+	return entropy, &v, nil
+}
+
+func (h *WebhookHandler) checkCallbackURL(raw string) error {
+	if raw == "" {
+		return nil
+	}
+	var callbacks *policy.CallbackConfig
+	if pol := h.executor.GetPolicy(); pol != nil {
+		callbacks = pol.Callbacks
+	}
+	return callbacks.AllowRequestURL(raw)
+}
+
+func newCutResponse(result *cutter.CutResult) CutResponse {
+	resp := CutResponse{
+		CutID:      result.CutID,
+		Node:       result.Target,
+		Action:     result.Action,
+		Success:    result.Success,
+		DryRun:     result.DryRun,
+		Outcome:    result.Outcome,
+		Skipped:    history.IsSkipped(result.Outcome),
+		Duplicate:  result.Duplicate,
+		LatencyMs:  result.LatencyMs,
+		Chain:      result.Chain,
+		Escalation: result.Escalation,
+	}
+	if result.Error != nil {
+		resp.Error = result.Error.Error()
+	}
+	return resp
+}
+
+func (h *WebhookHandler) handleHealth(c *gin.Context) {
+	report := h.executor.Health()
+
+	status := http.StatusOK
+	if pol := h.executor.GetPolicy(); pol != nil && engine.HealthRank(report.Status) >= engine.HealthRank(pol.GetHealthFailLevel()) {
+		status = http.StatusServiceUnavailable
+	}
+
+	body := gin.H{
+		"status":          report.Status,
+		"service":         "atropos",
+		"ts":              timefmt.Now(),
+		"cuts":            h.executor.Concurrency(),
+		"callbacks":       h.executor.CallbackStats(),
+		"components":      report.Components,
+		"entropy_clamped": h.entropyClamped.Load(),
+	}
+	if pool := h.executor.Pool(); pool != nil {
+		body["pool"] = pool
+	}
+	c.JSON(status, body)
+}
+
 func (h *WebhookHandler) hmacMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		sig := c.GetHeader("X-Lachesis-Signature")
@@ -111,38 +399,71 @@ func (h *WebhookHandler) hmacMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		if !h.verifySignature(body, sig) {
+		keyID, ok := h.verifySignature(body, sig, c.GetHeader("X-Lachesis-Key-Id"))
+		if !ok {
 			logger.WebhookReceived("unknown", 0, false)
 			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "invalid signature"})
 			return
 		}
+		c.Set(hmacKeyIDContextKey, keyID)
 
 		c.Request.Body = io.NopCloser(strings.NewReader(string(body)))
 		c.Next()
 	}
 }
 
-func (h *WebhookHandler) verifySignature(payload []byte, signature string) bool {
-	if len(h.hmacSecret) == 0 {
-		return true
+// verifySignature checks the payload against every configured key, trying the
+// one named by the sender first, and returns the ID of the key that matched.
+// A match on an expired key is rejected.
+func (h *WebhookHandler) verifySignature(payload []byte, signature, preferredKeyID string) (string, bool) {
+	hmacKeys := h.executor.GetPolicy().GetHMACKeys()
+	if len(hmacKeys) == 0 {
+		return "", true
 	}
 
 	parts := strings.SplitN(signature, "=", 2)
 	if len(parts) != 2 || parts[0] != "sha256" {
-		return false
+		return "", false
 	}
 
 	expectedMAC, err := hex.DecodeString(parts[1])
 	if err != nil {
-		return false
+		return "", false
+	}
+
+	keys := hmacKeys
+	if preferredKeyID != "" {
+		keys = make([]policy.HMACKey, 0, len(hmacKeys))
+		for _, key := range hmacKeys {
+			if key.KeyID == preferredKeyID {
+				keys = append([]policy.HMACKey{key}, keys...)
+			} else {
+				keys = append(keys, key)
+			}
+		}
+	}
+
+	now := time.Now()
+	for _, key := range keys {
+		mac := hmac.New(sha256.New, []byte(key.SecretValue()))
+		mac.Write(payload)
+		if !hmac.Equal(mac.Sum(nil), expectedMAC) {
+			continue
+		}
+		if key.Expired(now) {
+			logger.Get().Warn("WEBHOOK_KEY_EXPIRED",
+				zap.String("key_id", key.KeyID),
+				zap.Time("expired_at", *key.ExpiresAt),
+			)
+			return key.KeyID, false
+		}
+		return key.KeyID, true
 	}
 
-	mac := hmac.New(sha256.New, h.hmacSecret)
-	mac.Write(payload)
-	return hmac.Equal(mac.Sum(nil), expectedMAC)
+	return "", false
 }
 
-func NewServer(exec *engine.Executor, hmacSecret string) *gin.Engine {
+func NewServer(exec *engine.Executor) *gin.Engine {
 	gin.SetMode(gin.ReleaseMode)
 
 	r := gin.New()
@@ -150,7 +471,7 @@ func NewServer(exec *engine.Executor, hmacSecret string) *gin.Engine {
 		SkipPaths: []string{"/api/v1/health"},
 	}))
 
-	routes := NewRoutes(exec, hmacSecret)
+	routes := NewRoutes(exec)
 	routes.RegisterRoutes(r)
 
 	return r
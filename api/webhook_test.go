@@ -0,0 +1,265 @@
+package api_test
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"atropos/api"
+	"atropos/history"
+)
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestCutSaturatedReturns429(t *testing.T) {
+	s := newTestServer(t, `
+server:
+  hmac_secret: `+testSecret+`
+  max_concurrent_cuts: 1
+  cut_queue_timeout_seconds: 1
+nodes:
+  athena:
+    strategies:
+      - threshold: 0.5
+        action: test_restart
+  borg:
+    strategies:
+      - threshold: 0.5
+        action: test_restart
+`)
+	block := make(chan struct{})
+	s.cutter.block = block
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.executor.ExecuteCut(context.Background(), "athena", 0.8)
+	}()
+	waitFor(t, "the first cut to reach the cutter", func() bool { return s.cutter.Calls() == 1 })
+
+	c := s.client(t, testSecret)
+	result := make(chan error, 1)
+	go func() {
+		_, _, err := c.Cut(context.Background(), api.CutRequest{Node: "borg", Entropy: 0.8})
+		result <- err
+	}()
+	waitFor(t, "the second cut to queue", func() bool { return s.executor.Concurrency().Queued == 1 })
+
+	select {
+	case err := <-result:
+		if status := statusOf(t, err); status != http.StatusTooManyRequests {
+			t.Fatalf("saturated cut status = %d, want 429", status)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("saturated cut did not give up after the queue timeout")
+	}
+	if calls := s.cutter.Calls(); calls != 1 {
+		t.Fatalf("cutter ran %d times, want 1", calls)
+	}
+
+	close(block)
+	<-done
+	resp, _, err := c.Cut(context.Background(), api.CutRequest{Node: "borg", Entropy: 0.9})
+	if err != nil {
+		t.Fatalf("cut after the slot freed: %v", err)
+	}
+	if !resp.Success {
+		t.Fatalf("cut after the slot freed = %+v", resp)
+	}
+}
+
+// A synchronous cut past the deadline is answered 202, and only then is its
+// placeholder saved for the caller to poll.
+func TestCutPastDeadlineSavesPlaceholder(t *testing.T) {
+	s := newTestServer(t, `
+server:
+  hmac_secret: `+testSecret+`
+  cut_deadline_seconds: 1
+nodes:
+  athena:
+    strategies:
+      - threshold: 0.5
+        action: test_restart
+`)
+	block := make(chan struct{})
+	s.cutter.block = block
+
+	resp, _, err := s.client(t, testSecret).Cut(context.Background(), api.CutRequest{Node: "athena", Entropy: 0.8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Outcome != history.OutcomeInProgress || resp.CutID == "" {
+		t.Fatalf("response %+v, want in_progress with the ID to poll", resp)
+	}
+	if cut, err := s.executor.GetHistory().LoadCut(resp.CutID); err != nil || cut.Outcome != history.OutcomeInProgress {
+		t.Fatalf("history has %+v, %v; want the placeholder", cut, err)
+	}
+
+	close(block)
+	waitFor(t, "the cut's record", func() bool {
+		cut, err := s.executor.GetHistory().LoadCut(resp.CutID)
+		return err == nil && cut.Outcome == history.OutcomeExecuted
+	})
+}
+
+const batchPolicy = `
+server:
+  hmac_secret: ` + testSecret + `
+  cut_deadline_seconds: 1
+nodes:
+  athena:
+    strategies:
+      - threshold: 0.5
+        action: test_restart
+  borg:
+    strategies:
+      - threshold: 0.5
+        action: test_restart
+`
+
+// A batch keeps running when its caller hangs up.
+func TestBatchCutOutlivesCaller(t *testing.T) {
+	s := newTestServer(t, batchPolicy)
+	block := make(chan struct{})
+	s.cutter.block = block
+
+	ctx, cancel := context.WithCancel(context.Background())
+	result := make(chan error, 1)
+	go func() {
+		_, err := s.client(t, testSecret).BatchCut(ctx, []api.CutRequest{{Node: "athena", Entropy: 0.9}, {Node: "borg", Entropy: 0.9}})
+		result <- err
+	}()
+	waitFor(t, "the first cut to reach the cutter", func() bool { return s.cutter.Calls() == 1 })
+	cancel()
+	if err := <-result; err == nil {
+		t.Fatal("batch answered a caller that hung up")
+	}
+
+	close(block)
+	for _, node := range []string{"athena", "borg"} {
+		waitFor(t, node+"'s cut", func() bool {
+			cut, err := s.executor.GetHistory().GetLatestCutByNode(node)
+			return err == nil && cut != nil && cut.Outcome == history.OutcomeExecuted
+		})
+	}
+}
+
+// Each cut of a batch is bounded by the cut deadline.
+func TestBatchCutDeadline(t *testing.T) {
+	s := newTestServer(t, batchPolicy)
+	s.cutter.block = make(chan struct{})
+	defer close(s.cutter.block)
+
+	start := time.Now()
+	batch, err := s.client(t, testSecret).BatchCut(context.Background(), []api.CutRequest{{Node: "athena", Entropy: 0.9}, {Node: "borg", Entropy: 0.9}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Fatalf("batch of two took %s with a 1s cut deadline", elapsed)
+	}
+	for _, r := range batch.Results {
+		if r.Success {
+			t.Fatalf("%s's cut succeeded past the deadline", r.Node)
+		}
+	}
+}
+
+func writeFile(t *testing.T, path, data string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHMACKeyRotationOnReload(t *testing.T) {
+	dir := t.TempDir()
+	q4 := filepath.Join(dir, "q4")
+	q1 := filepath.Join(dir, "q1")
+	writeFile(t, q4, "secret-q4")
+	writeFile(t, q1, "secret-q1")
+	policyYAML := func(keys string) string {
+		return "server:\n  hmac_keys:\n" + keys + `
+nodes:
+  athena:
+    strategies:
+      - threshold: 0.5
+        action: test_restart
+`
+	}
+	q4Key := "    - key_id: q4\n      secret_file: " + q4 + "\n"
+	q1Key := "    - key_id: q1\n      secret_file: " + q1 + "\n"
+
+	s := newTestServer(t, policyYAML(q4Key))
+	reload := func(secret string) int {
+		_, err := s.client(t, secret).ReloadPolicy(context.Background())
+		return statusOf(t, err)
+	}
+	if status := reload("secret-q1"); status != http.StatusForbidden {
+		t.Fatalf("q1 before it was added: status %d, want 403", status)
+	}
+
+	// Overlap: both keys are accepted once the new one is loaded.
+	writeFile(t, s.policyPath, policyYAML(q4Key+q1Key))
+	if status := reload("secret-q4"); status != http.StatusOK {
+		t.Fatalf("reload adding q1: status %d", status)
+	}
+	for _, secret := range []string{"secret-q4", "secret-q1"} {
+		if status := reload(secret); status != http.StatusOK {
+			t.Fatalf("%s during the overlap: status %d, want 200", secret, status)
+		}
+	}
+
+	// Retire the old key.
+	writeFile(t, s.policyPath, policyYAML(q1Key))
+	if status := reload("secret-q1"); status != http.StatusOK {
+		t.Fatalf("reload dropping q4: status %d", status)
+	}
+	if status := reload("secret-q4"); status != http.StatusForbidden {
+		t.Fatalf("q4 after it was dropped: status %d, want 403", status)
+	}
+	if status := reload("secret-q1"); status != http.StatusOK {
+		t.Fatalf("q1 after the rotation: status %d, want 200", status)
+	}
+}
+
+func TestHMACSecretFileReread(t *testing.T) {
+	secretFile := filepath.Join(t.TempDir(), "hmac")
+	writeFile(t, secretFile, "first")
+	s := newTestServer(t, `
+server:
+  hmac_secret_file: `+secretFile+`
+nodes:
+  athena:
+    strategies:
+      - threshold: 0.5
+        action: test_restart
+`)
+
+	reload := func(secret string) int {
+		_, err := s.client(t, secret).ReloadPolicy(context.Background())
+		return statusOf(t, err)
+	}
+	writeFile(t, secretFile, "second")
+	if status := reload("first"); status != http.StatusOK {
+		t.Fatalf("reload signed with the loaded secret: status %d", status)
+	}
+	if status := reload("first"); status != http.StatusForbidden {
+		t.Fatalf("old secret after reload: status %d, want 403", status)
+	}
+	if status := reload("second"); status != http.StatusOK {
+		t.Fatalf("new secret after reload: status %d, want 200", status)
+	}
+}
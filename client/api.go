@@ -0,0 +1,258 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"atropos/api"
+	"atropos/engine"
+	"atropos/history"
+	"atropos/trends"
+)
+
+// Cut asks for a cut. When the server runs a worker pool it answers with a
+// queued job instead of the result; poll it with Job. A cut still running at
+// the server's deadline comes back with outcome in_progress; poll it with
+// GetCut.
+func (c *Client) Cut(ctx context.Context, req api.CutRequest) (*api.CutResponse, *api.JobAcceptedResponse, error) {
+	resp, err := c.do(ctx, http.MethodPost, "/api/v1/cut", nil, req)
+	if err != nil {
+		return nil, nil, err
+	}
+	if resp.status == http.StatusAccepted {
+		var job api.JobAcceptedResponse
+		if err := decode(resp, &job); err != nil {
+			return nil, nil, err
+		}
+		if job.JobID != "" {
+			return nil, &job, nil
+		}
+	}
+	var result api.CutResponse
+	return &result, nil, decode(resp, &result)
+}
+
+// CutAsync asks for a cut without waiting for it. The answer carries the
+// ID of the cut's in_progress record; poll it with GetCut. A server running
+// a worker pool queues a job instead.
+func (c *Client) CutAsync(ctx context.Context, req api.CutRequest) (*api.AsyncCutResponse, *api.JobAcceptedResponse, error) {
+	resp, err := c.do(ctx, http.MethodPost, "/api/v1/cut", url.Values{"async": {"true"}}, req)
+	if err != nil {
+		return nil, nil, err
+	}
+	var job api.JobAcceptedResponse
+	if err := decode(resp, &job); err != nil {
+		return nil, nil, err
+	}
+	if job.JobID != "" {
+		return nil, &job, nil
+	}
+	var out api.AsyncCutResponse
+	return &out, nil, decode(resp, &out)
+}
+
+func (c *Client) BatchCut(ctx context.Context, cuts []api.CutRequest) (*api.BatchCutResponse, error) {
+	var out api.BatchCutResponse
+	_, err := c.sendJSON(ctx, http.MethodPost, "/api/v1/cut/batch", api.BatchCutRequest{Cuts: cuts}, &out)
+	return &out, err
+}
+
+func (c *Client) Job(ctx context.Context, id string) (*engine.CutJob, error) {
+	var out engine.CutJob
+	return &out, c.getJSON(ctx, "/api/v1/jobs/"+url.PathEscape(id), nil, &out)
+}
+
+func (c *Client) DryRun(ctx context.Context, node string, entropy float64) (*api.DryRunResponse, error) {
+	var out api.DryRunResponse
+	_, err := c.sendJSON(ctx, http.MethodPost, "/api/v1/cut/dryrun", api.DryRunRequest{Node: node, Entropy: entropy}, &out)
+	return &out, err
+}
+
+// HistoryQuery selects cut records, newest first. Labels are "key=value"
+// and Tags "key:value" selectors.
+type HistoryQuery struct {
+	Node           string
+	Limit          int
+	Offset         int
+	Labels         []string
+	Tags           []string
+	Outcome        string
+	ExcludeSkipped bool
+}
+
+func (q HistoryQuery) values() url.Values {
+	v := url.Values{}
+	if q.Limit > 0 {
+		v.Set("limit", strconv.Itoa(q.Limit))
+	}
+	if q.Offset > 0 {
+		v.Set("offset", strconv.Itoa(q.Offset))
+	}
+	for _, l := range q.Labels {
+		v.Add("label", l)
+	}
+	for _, t := range q.Tags {
+		v.Add("tag", t)
+	}
+	if q.Outcome != "" {
+		v.Set("outcome", q.Outcome)
+	}
+	if q.ExcludeSkipped {
+		v.Set("include_skipped", "false")
+	}
+	return v
+}
+
+func (c *Client) History(ctx context.Context, q HistoryQuery) (*api.CutListResponse, error) {
+	path := "/api/v1/cuts/history"
+	if q.Node != "" {
+		path += "/" + url.PathEscape(q.Node)
+	}
+	var out api.CutListResponse
+	return &out, c.getJSON(ctx, path, q.values(), &out)
+}
+
+// HistoryAll follows pages of q.Limit records (default 100) from q.Offset
+// until the history is exhausted.
+func (c *Client) HistoryAll(ctx context.Context, q HistoryQuery) ([]*history.CutRecord, error) {
+	if q.Limit <= 0 {
+		q.Limit = 100
+	}
+	var cuts []*history.CutRecord
+	for {
+		page, err := c.History(ctx, q)
+		if err != nil {
+			return cuts, err
+		}
+		cuts = append(cuts, page.Cuts...)
+		if len(page.Cuts) < q.Limit {
+			return cuts, nil
+		}
+		q.Offset += q.Limit
+	}
+}
+
+func (c *Client) GetCut(ctx context.Context, id string) (*history.CutRecord, error) {
+	var out history.CutRecord
+	return &out, c.getJSON(ctx, "/api/v1/cuts/"+url.PathEscape(id), nil, &out)
+}
+
+// StatsQuery limits stats to the last Days days; zero means all history.
+type StatsQuery struct {
+	Days            int
+	IncludeImported bool
+	IncludeDryRun   bool
+	IncludeSkipped  bool
+}
+
+func (c *Client) Stats(ctx context.Context, q StatsQuery) (*api.StatsResponse, error) {
+	v := url.Values{}
+	if q.Days > 0 {
+		v.Set("days", strconv.Itoa(q.Days))
+	}
+	if q.IncludeImported {
+		v.Set("include_imported", "true")
+	}
+	if q.IncludeDryRun {
+		v.Set("include_dry_run", "true")
+	}
+	if q.IncludeSkipped {
+		v.Set("include_skipped", "true")
+	}
+	var out api.StatsResponse
+	return &out, c.getJSON(ctx, "/api/v1/stats", v, &out)
+}
+
+// Trends returns global trends over the last days days (the server default
+// of 30 when zero).
+func (c *Client) Trends(ctx context.Context, days int) (*trends.GlobalTrend, error) {
+	v := url.Values{}
+	if days > 0 {
+		v.Set("days", strconv.Itoa(days))
+	}
+	var out trends.GlobalTrend
+	return &out, c.getJSON(ctx, "/api/v1/trends", v, &out)
+}
+
+func (c *Client) NodeTrends(ctx context.Context, node string) (*trends.NodeTrend, error) {
+	var out trends.NodeTrend
+	return &out, c.getJSON(ctx, "/api/v1/trends/"+url.PathEscape(node), nil, &out)
+}
+
+const (
+	ExportCSV  = "csv"
+	ExportJSON = "json"
+)
+
+// Export writes the newest limit records (the server default of 1000 when
+// zero) in format to w.
+func (c *Client) Export(ctx context.Context, format string, limit int, w io.Writer) error {
+	if format != ExportCSV && format != ExportJSON {
+		return fmt.Errorf("unknown export format %q", format)
+	}
+	v := url.Values{}
+	if limit > 0 {
+		v.Set("limit", strconv.Itoa(limit))
+	}
+	resp, err := c.do(ctx, http.MethodGet, "/api/v1/export/history."+format, v, nil)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(resp.body)
+	return err
+}
+
+func (c *Client) Nodes(ctx context.Context) (*api.NodeListResponse, error) {
+	var out api.NodeListResponse
+	return &out, c.getJSON(ctx, "/api/v1/nodes", nil, &out)
+}
+
+func (c *Client) NodeStatus(ctx context.Context, node string) (*engine.NodeStatus, error) {
+	var out engine.NodeStatus
+	return &out, c.getJSON(ctx, "/api/v1/nodes/"+url.PathEscape(node)+"/status", nil, &out)
+}
+
+func (c *Client) RateLimit(ctx context.Context, node string) (*engine.RateLimitStatus, error) {
+	var out engine.RateLimitStatus
+	return &out, c.getJSON(ctx, "/api/v1/nodes/"+url.PathEscape(node)+"/ratelimit", nil, &out)
+}
+
+// ResetRateLimit clears node's rate limit and returns its state before the
+// reset.
+func (c *Client) ResetRateLimit(ctx context.Context, node, reason string) (*api.RateLimitResetResponse, error) {
+	var out api.RateLimitResetResponse
+	_, err := c.sendJSON(ctx, http.MethodPost, "/api/v1/nodes/"+url.PathEscape(node)+"/ratelimit/reset", api.RateLimitResetRequest{Reason: reason}, &out)
+	return &out, err
+}
+
+func (c *Client) RateLimits(ctx context.Context) (*api.RateLimitListResponse, error) {
+	var out api.RateLimitListResponse
+	return &out, c.getJSON(ctx, "/api/v1/ratelimits", nil, &out)
+}
+
+func (c *Client) Silences(ctx context.Context) (*api.SilenceListResponse, error) {
+	var out api.SilenceListResponse
+	return &out, c.getJSON(ctx, "/api/v1/silences", nil, &out)
+}
+
+// Silence puts node into maintenance for duration, e.g. "4h".
+func (c *Client) Silence(ctx context.Context, node, duration, reason string) (*history.Silence, error) {
+	var out history.Silence
+	_, err := c.sendJSON(ctx, http.MethodPost, "/api/v1/nodes/"+url.PathEscape(node)+"/silence", api.SilenceRequest{Duration: duration, Reason: reason}, &out)
+	return &out, err
+}
+
+func (c *Client) Unsilence(ctx context.Context, node string) error {
+	_, err := c.do(ctx, http.MethodDelete, "/api/v1/nodes/"+url.PathEscape(node)+"/silence", nil, nil)
+	return err
+}
+
+func (c *Client) ReloadPolicy(ctx context.Context) (*api.PolicyReloadResponse, error) {
+	var out api.PolicyReloadResponse
+	_, err := c.sendJSON(ctx, http.MethodPost, "/api/v1/policy/reload", struct{}{}, &out)
+	return &out, err
+}
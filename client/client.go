@@ -0,0 +1,233 @@
+// Package client is a typed Go client for the Atropos API. It signs requests
+// the way the server verifies them and decodes responses into the api
+// package's own types.
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	SignatureHeader = "X-Lachesis-Signature"
+	KeyIDHeader     = "X-Lachesis-Key-Id"
+)
+
+type Client struct {
+	baseURL string
+	secret  string
+	keyID   string
+	http    *http.Client
+	retries int
+	backoff time.Duration
+}
+
+type Option func(*Client)
+
+// WithHMACSecret signs every request body with secret.
+func WithHMACSecret(secret string) Option {
+	return func(c *Client) { c.secret = secret }
+}
+
+// WithKeyID names the server's hmac_keys entry the secret belongs to, so
+// the server checks it first.
+func WithKeyID(keyID string) Option {
+	return func(c *Client) { c.keyID = keyID }
+}
+
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) { c.http = hc }
+}
+
+// WithTimeout bounds each HTTP attempt; the default is 60s.
+func WithTimeout(d time.Duration) Option {
+	return func(c *Client) { c.http.Timeout = d }
+}
+
+// WithRetries retries up to n more times, waiting backoff and doubling it
+// before each retry. GETs are retried on network errors, 429 and 5xx. Cut
+// requests are only retried on 429, since any other failure may have
+// happened after the cut ran.
+func WithRetries(n int, backoff time.Duration) Option {
+	return func(c *Client) {
+		c.retries = n
+		c.backoff = backoff
+	}
+}
+
+// New returns a client for the server at baseURL, e.g.
+// "https://atropos.internal:8443".
+func New(baseURL string, opts ...Option) (*Client, error) {
+	baseURL = strings.TrimRight(baseURL, "/")
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("parse base url: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return nil, fmt.Errorf("base url must be an absolute http(s) URL")
+	}
+	c := &Client{
+		baseURL: baseURL,
+		http:    &http.Client{Timeout: 60 * time.Second},
+		backoff: time.Second,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
+}
+
+// Sign returns the signature header value for body.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Error is a non-2xx answer from the server.
+type Error struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("atropos: %d: %s", e.StatusCode, e.Message)
+}
+
+// IsStatus reports whether err is an *Error with the given status code.
+func IsStatus(err error, status int) bool {
+	var apiErr *Error
+	return errors.As(err, &apiErr) && apiErr.StatusCode == status
+}
+
+type response struct {
+	status int
+	header http.Header
+	body   []byte
+}
+
+func (c *Client) url(path string, query url.Values) string {
+	if len(query) > 0 {
+		return c.baseURL + path + "?" + query.Encode()
+	}
+	return c.baseURL + path
+}
+
+// do sends the request, retrying as configured, and returns the first
+// response that is not retried. Non-2xx responses come back as *Error.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body interface{}) (*response, error) {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return nil, fmt.Errorf("encode request: %w", err)
+		}
+	}
+
+	wait := c.backoff
+	for attempt := 0; ; attempt++ {
+		resp, err := c.send(ctx, method, c.url(path, query), payload, body != nil)
+		if attempt >= c.retries || !c.retryable(method, resp, err) {
+			if err != nil {
+				return nil, err
+			}
+			if resp.status >= 300 {
+				return resp, decodeError(resp)
+			}
+			return resp, nil
+		}
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		wait *= 2
+	}
+}
+
+func (c *Client) retryable(method string, resp *response, err error) bool {
+	if method != http.MethodGet {
+		return err == nil && resp.status == http.StatusTooManyRequests
+	}
+	if err != nil {
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+	return resp.status == http.StatusTooManyRequests || resp.status >= 500
+}
+
+func (c *Client) send(ctx context.Context, method, target string, payload []byte, hasBody bool) (*response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	if hasBody {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.secret != "" && method != http.MethodGet {
+		req.Header.Set(SignatureHeader, Sign(c.secret, payload))
+		if c.keyID != "" {
+			req.Header.Set(KeyIDHeader, c.keyID)
+		}
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read response: %w", err)
+	}
+	return &response{status: resp.StatusCode, header: resp.Header, body: data}, nil
+}
+
+func decodeError(resp *response) error {
+	var body struct {
+		Error string `json:"error"`
+	}
+	if json.Unmarshal(resp.body, &body) != nil || body.Error == "" {
+		body.Error = strings.TrimSpace(string(resp.body))
+	}
+	if body.Error == "" {
+		body.Error = http.StatusText(resp.status)
+	}
+	return &Error{StatusCode: resp.status, Message: body.Error}
+}
+
+func (c *Client) getJSON(ctx context.Context, path string, query url.Values, out interface{}) error {
+	resp, err := c.do(ctx, http.MethodGet, path, query, nil)
+	if err != nil {
+		return err
+	}
+	return decode(resp, out)
+}
+
+func (c *Client) sendJSON(ctx context.Context, method, path string, body, out interface{}) (*response, error) {
+	resp, err := c.do(ctx, method, path, nil, body)
+	if err != nil {
+		return nil, err
+	}
+	return resp, decode(resp, out)
+}
+
+func decode(resp *response, out interface{}) error {
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(resp.body, out); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}
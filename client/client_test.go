@@ -0,0 +1,438 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"atropos/api"
+	"atropos/history"
+)
+
+// fakeServer answers each request with the next of responses and records
+// what it was sent. The last response repeats.
+type fakeServer struct {
+	*httptest.Server
+	mu        sync.Mutex
+	requests  []*http.Request
+	bodies    [][]byte
+	responses []fakeResponse
+}
+
+type fakeResponse struct {
+	status int
+	body   string
+}
+
+func newFakeServer(t *testing.T, responses ...fakeResponse) *fakeServer {
+	t.Helper()
+	f := &fakeServer{responses: responses}
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		f.mu.Lock()
+		n := len(f.requests)
+		f.requests = append(f.requests, r)
+		f.bodies = append(f.bodies, body)
+		resp := f.responses[min(n, len(f.responses)-1)]
+		f.mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(resp.status)
+		io.WriteString(w, resp.body)
+	}))
+	t.Cleanup(f.Close)
+	return f
+}
+
+func (f *fakeServer) count() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.requests)
+}
+
+func (f *fakeServer) request(i int) (*http.Request, []byte) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.requests[i], f.bodies[i]
+}
+
+func newTestClient(t *testing.T, url string, opts ...Option) *Client {
+	t.Helper()
+	c, err := New(url, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestSign(t *testing.T) {
+	for _, tc := range []struct {
+		body, want string
+	}{
+		{`{"node":"athena","entropy":0.8}`, "sha256=6c2f1bf0fb9785b3e18ccca94aae0ce98ebe8d9096817f78aebb901b2a29653f"},
+		{``, "sha256=a41bc6d81d6413576ae0994995e0ad89a416ec97389515c3604f47722122eeeb"},
+	} {
+		if got := Sign("test-secret", []byte(tc.body)); got != tc.want {
+			t.Errorf("Sign(%q) = %s, want %s", tc.body, got, tc.want)
+		}
+	}
+}
+
+func TestRequestsAreSigned(t *testing.T) {
+	f := newFakeServer(t, fakeResponse{http.StatusOK, `{"success":true}`})
+	c := newTestClient(t, f.URL+"/", WithHMACSecret("test-secret"), WithKeyID("q1"))
+	ctx := context.Background()
+
+	if _, _, err := c.Cut(ctx, api.CutRequest{Node: "athena", Entropy: 0.8}); err != nil {
+		t.Fatal(err)
+	}
+	req, body := f.request(0)
+	if req.URL.Path != "/api/v1/cut" || req.Method != http.MethodPost {
+		t.Fatalf("cut went to %s %s", req.Method, req.URL.Path)
+	}
+	if got, want := req.Header.Get(SignatureHeader), Sign("test-secret", body); got != want {
+		t.Fatalf("signature = %q, want %q over the body sent", got, want)
+	}
+	if got := req.Header.Get(KeyIDHeader); got != "q1" {
+		t.Fatalf("key ID = %q, want q1", got)
+	}
+	if got := req.Header.Get("Content-Type"); got != "application/json" {
+		t.Fatalf("content type = %q", got)
+	}
+
+	if _, err := c.Stats(ctx, StatsQuery{}); err != nil {
+		t.Fatal(err)
+	}
+	req, _ = f.request(1)
+	if req.Header.Get(SignatureHeader) != "" || req.Header.Get(KeyIDHeader) != "" {
+		t.Fatal("GET was signed")
+	}
+
+	if err := c.Unsilence(ctx, "athena"); err != nil {
+		t.Fatal(err)
+	}
+	req, _ = f.request(2)
+	if got, want := req.Header.Get(SignatureHeader), Sign("test-secret", nil); got != want {
+		t.Fatalf("bodiless DELETE signature = %q, want the empty body's %q", got, want)
+	}
+
+	unsigned := newTestClient(t, f.URL)
+	if _, _, err := unsigned.Cut(ctx, api.CutRequest{Node: "athena", Entropy: 0.8}); err != nil {
+		t.Fatal(err)
+	}
+	req, _ = f.request(3)
+	if req.Header.Get(SignatureHeader) != "" {
+		t.Fatal("client without a secret signed its request")
+	}
+}
+
+func TestRetries(t *testing.T) {
+	ctx := context.Background()
+	for _, tc := range []struct {
+		name      string
+		responses []fakeResponse
+		call      func(*Client) error
+		wantCalls int
+		wantErr   int
+	}{
+		{
+			name:      "GET retried on 503",
+			responses: []fakeResponse{{503, `{"error":"busy"}`}, {503, `{"error":"busy"}`}, {200, `{}`}},
+			call:      func(c *Client) error { _, err := c.Stats(ctx, StatsQuery{}); return err },
+			wantCalls: 3,
+		},
+		{
+			name:      "GET retried on 429",
+			responses: []fakeResponse{{429, `{"error":"slow down"}`}, {200, `{}`}},
+			call:      func(c *Client) error { _, err := c.Nodes(ctx); return err },
+			wantCalls: 2,
+		},
+		{
+			name:      "GET gives up after the retries",
+			responses: []fakeResponse{{502, `{"error":"bad gateway"}`}},
+			call:      func(c *Client) error { _, err := c.Stats(ctx, StatsQuery{}); return err },
+			wantCalls: 4,
+			wantErr:   502,
+		},
+		{
+			name:      "GET not retried on 404",
+			responses: []fakeResponse{{404, `{"error":"cut not found"}`}},
+			call:      func(c *Client) error { _, err := c.GetCut(ctx, "x"); return err },
+			wantCalls: 1,
+			wantErr:   404,
+		},
+		{
+			name:      "cut not retried on 500",
+			responses: []fakeResponse{{500, `{"error":"boom"}`}, {200, `{}`}},
+			call: func(c *Client) error {
+				_, _, err := c.Cut(ctx, api.CutRequest{Node: "athena", Entropy: 0.8})
+				return err
+			},
+			wantCalls: 1,
+			wantErr:   500,
+		},
+		{
+			name:      "cut retried on 429",
+			responses: []fakeResponse{{429, `{"error":"saturated"}`}, {200, `{"success":true}`}},
+			call: func(c *Client) error {
+				_, _, err := c.Cut(ctx, api.CutRequest{Node: "athena", Entropy: 0.8})
+				return err
+			},
+			wantCalls: 2,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			f := newFakeServer(t, tc.responses...)
+			c := newTestClient(t, f.URL, WithRetries(3, time.Millisecond))
+			err := tc.call(c)
+			if tc.wantErr == 0 && err != nil {
+				t.Fatalf("error: %v", err)
+			}
+			if tc.wantErr != 0 && !IsStatus(err, tc.wantErr) {
+				t.Fatalf("error = %v, want status %d", err, tc.wantErr)
+			}
+			if got := f.count(); got != tc.wantCalls {
+				t.Fatalf("%d requests, want %d", got, tc.wantCalls)
+			}
+		})
+	}
+}
+
+func TestRetriesSameBodyAndSignature(t *testing.T) {
+	f := newFakeServer(t, fakeResponse{429, `{"error":"saturated"}`}, fakeResponse{200, `{"success":true}`})
+	c := newTestClient(t, f.URL, WithHMACSecret("test-secret"), WithRetries(1, time.Millisecond))
+	if _, _, err := c.Cut(context.Background(), api.CutRequest{Node: "athena", Entropy: 0.8}); err != nil {
+		t.Fatal(err)
+	}
+	first, firstBody := f.request(0)
+	second, secondBody := f.request(1)
+	if !bytes.Equal(firstBody, secondBody) || len(secondBody) == 0 {
+		t.Fatalf("retry sent %q after %q", secondBody, firstBody)
+	}
+	if first.Header.Get(SignatureHeader) != second.Header.Get(SignatureHeader) {
+		t.Fatal("retry was signed differently")
+	}
+}
+
+func TestRetryStopsOnCancel(t *testing.T) {
+	f := newFakeServer(t, fakeResponse{503, `{"error":"busy"}`})
+	c := newTestClient(t, f.URL, WithRetries(5, time.Hour))
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := c.Stats(ctx, StatsQuery{})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("error = %v, want the context's", err)
+	}
+	if got := f.count(); got != 1 {
+		t.Fatalf("%d requests, want 1 before the backoff was cut short", got)
+	}
+}
+
+func TestGETRetriedOnNetworkError(t *testing.T) {
+	f := newFakeServer(t, fakeResponse{200, `{"count":0}`})
+	var failed bool
+	transport := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		if !failed {
+			failed = true
+			return nil, errors.New("connection reset")
+		}
+		return http.DefaultTransport.RoundTrip(r)
+	})
+	c := newTestClient(t, f.URL, WithHTTPClient(&http.Client{Transport: transport}), WithRetries(1, time.Millisecond))
+	if _, err := c.Nodes(context.Background()); err != nil {
+		t.Fatalf("GET after a network error: %v", err)
+	}
+
+	failed = false
+	if _, _, err := c.Cut(context.Background(), api.CutRequest{Node: "athena", Entropy: 0.8}); err == nil {
+		t.Fatal("cut was retried after a network error")
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+func TestErrorDecoding(t *testing.T) {
+	for _, tc := range []struct {
+		resp fakeResponse
+		want string
+	}{
+		{fakeResponse{403, `{"error":"invalid signature"}`}, "atropos: 403: invalid signature"},
+		{fakeResponse{502, "upstream went away\n"}, "atropos: 502: upstream went away"},
+		{fakeResponse{503, ``}, "atropos: 503: Service Unavailable"},
+	} {
+		f := newFakeServer(t, tc.resp)
+		_, err := newTestClient(t, f.URL).Stats(context.Background(), StatsQuery{})
+		if err == nil || err.Error() != tc.want {
+			t.Errorf("error = %v, want %q", err, tc.want)
+		}
+		if !IsStatus(err, tc.resp.status) || IsStatus(err, 200) {
+			t.Errorf("IsStatus(%v, %d) is wrong", err, tc.resp.status)
+		}
+	}
+	if IsStatus(errors.New("plain"), 500) {
+		t.Error("IsStatus matched an error that is not an *Error")
+	}
+}
+
+func TestCutQueuedAsJob(t *testing.T) {
+	f := newFakeServer(t, fakeResponse{http.StatusAccepted, `{"job_id":"job-1","status":"queued","status_url":"/api/v1/jobs/job-1"}`})
+	c := newTestClient(t, f.URL)
+	resp, job, err := c.Cut(context.Background(), api.CutRequest{Node: "athena", Entropy: 0.8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp != nil || job == nil || job.JobID != "job-1" || job.Status != "queued" {
+		t.Fatalf("cut = %+v, job %+v, want the queued job", resp, job)
+	}
+
+	// A 202 without a job is a cut still running at the server's deadline.
+	f = newFakeServer(t, fakeResponse{http.StatusAccepted, `{"cut_id":"c1","node":"athena","outcome":"in_progress"}`})
+	resp, job, err = newTestClient(t, f.URL).Cut(context.Background(), api.CutRequest{Node: "athena", Entropy: 0.8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job != nil || resp.CutID != "c1" || resp.Outcome != history.OutcomeInProgress {
+		t.Fatalf("cut = %+v, job %+v, want the in-progress cut", resp, job)
+	}
+}
+
+func TestCutAsyncQuery(t *testing.T) {
+	f := newFakeServer(t, fakeResponse{http.StatusAccepted, `{"id":"c1","node":"athena","outcome":"in_progress","status_url":"/api/v1/cuts/c1"}`})
+	started, job, err := newTestClient(t, f.URL).CutAsync(context.Background(), api.CutRequest{Node: "athena", Entropy: 0.8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job != nil || started.ID != "c1" || started.StatusURL != "/api/v1/cuts/c1" {
+		t.Fatalf("async cut = %+v, job %+v", started, job)
+	}
+	if req, _ := f.request(0); req.URL.Query().Get("async") != "true" {
+		t.Fatalf("async cut query = %q", req.URL.RawQuery)
+	}
+}
+
+func TestHistoryQuery(t *testing.T) {
+	f := newFakeServer(t, fakeResponse{200, `{"count":0,"cuts":[]}`})
+	c := newTestClient(t, f.URL)
+	_, err := c.History(context.Background(), HistoryQuery{
+		Node:           "web/01",
+		Limit:          5,
+		Offset:         10,
+		Labels:         []string{"tier=web", "env=prod"},
+		Tags:           []string{"ticket:INC-1"},
+		Outcome:        "failed",
+		ExcludeSkipped: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ := f.request(0)
+	if req.URL.EscapedPath() != "/api/v1/cuts/history/web%2F01" {
+		t.Fatalf("path = %s, want the node escaped", req.URL.EscapedPath())
+	}
+	q := req.URL.Query()
+	if q.Get("limit") != "5" || q.Get("offset") != "10" || q.Get("outcome") != "failed" || q.Get("include_skipped") != "false" {
+		t.Fatalf("query = %s", req.URL.RawQuery)
+	}
+	if got := strings.Join(q["label"], ","); got != "tier=web,env=prod" {
+		t.Fatalf("labels = %s", got)
+	}
+	if got := strings.Join(q["tag"], ","); got != "ticket:INC-1" {
+		t.Fatalf("tags = %s", got)
+	}
+}
+
+func TestHistoryAllPages(t *testing.T) {
+	page := func(ids ...string) fakeResponse {
+		resp := api.CutListResponse{Count: len(ids)}
+		for _, id := range ids {
+			resp.Cuts = append(resp.Cuts, &history.CutRecord{ID: id})
+		}
+		data, _ := json.Marshal(resp)
+		return fakeResponse{200, string(data)}
+	}
+	f := newFakeServer(t, page("a", "b"), page("c", "d"), page("e"))
+	cuts, err := newTestClient(t, f.URL).HistoryAll(context.Background(), HistoryQuery{Limit: 2, Offset: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, cut := range cuts {
+		ids = append(ids, cut.ID)
+	}
+	if strings.Join(ids, "") != "abcde" {
+		t.Fatalf("cuts = %v, want a through e", ids)
+	}
+	for i, want := range []string{"4", "6", "8"} {
+		if req, _ := f.request(i); req.URL.Query().Get("offset") != want || req.URL.Query().Get("limit") != "2" {
+			t.Fatalf("page %d query = %s, want offset %s", i, req.URL.RawQuery, want)
+		}
+	}
+
+	// A full last page costs one more, empty, request.
+	f = newFakeServer(t, page("a", "b"), page())
+	if cuts, err = newTestClient(t, f.URL).HistoryAll(context.Background(), HistoryQuery{Limit: 2}); err != nil || len(cuts) != 2 {
+		t.Fatalf("cuts = %d, %v", len(cuts), err)
+	}
+	if f.count() != 2 {
+		t.Fatalf("%d requests, want 2", f.count())
+	}
+}
+
+func TestHistoryAllKeepsPagesBeforeAnError(t *testing.T) {
+	f := newFakeServer(t, fakeResponse{200, `{"count":1,"cuts":[{"id":"a"}]}`}, fakeResponse{500, `{"error":"disk"}`})
+	cuts, err := newTestClient(t, f.URL).HistoryAll(context.Background(), HistoryQuery{Limit: 1})
+	if !IsStatus(err, 500) {
+		t.Fatalf("error = %v, want 500", err)
+	}
+	if len(cuts) != 1 || cuts[0].ID != "a" {
+		t.Fatalf("cuts = %v, want the first page", cuts)
+	}
+}
+
+func TestExport(t *testing.T) {
+	f := newFakeServer(t, fakeResponse{200, "ID,Node\nc1,athena\n"})
+	c := newTestClient(t, f.URL)
+	var out bytes.Buffer
+	if err := c.Export(context.Background(), ExportCSV, 50, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "ID,Node\nc1,athena\n" {
+		t.Fatalf("export wrote %q", out.String())
+	}
+	req, _ := f.request(0)
+	if req.URL.Path != "/api/v1/export/history.csv" || req.URL.Query().Get("limit") != "50" {
+		t.Fatalf("export requested %s", req.URL)
+	}
+
+	if err := c.Export(context.Background(), "xml", 0, &out); err == nil {
+		t.Fatal("export accepted format xml")
+	}
+	if f.count() != 1 {
+		t.Fatal("an unknown format reached the server")
+	}
+}
+
+func TestNew(t *testing.T) {
+	for _, url := range []string{"", "localhost:8443", "ftp://atropos", "http://", "://bad"} {
+		if _, err := New(url); err == nil {
+			t.Errorf("New(%q) succeeded", url)
+		}
+	}
+	c, err := New("https://atropos.internal:8443/", WithTimeout(5*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.baseURL != "https://atropos.internal:8443" || c.http.Timeout != 5*time.Second {
+		t.Fatalf("client = %+v", c)
+	}
+}
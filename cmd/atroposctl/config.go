@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+
+	"atropos/internal/secretfile"
+)
+
+const defaultServer = "http://localhost:8443"
+
+// config is read from ~/.atroposctl.yaml (or -config / ATROPOSCTL_CONFIG),
+// then overridden by environment variables and finally by flags.
+type config struct {
+	Server     string `yaml:"server"`
+	Secret     string `yaml:"secret"`
+	SecretFile string `yaml:"secret_file"`
+	KeyID      string `yaml:"key_id"`
+	Output     string `yaml:"output"`
+}
+
+func defaultConfigPath() string {
+	if path := os.Getenv("ATROPOSCTL_CONFIG"); path != "" {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".atroposctl.yaml")
+}
+
+// loadConfig reads path; a missing file is only an error when the path was
+// given explicitly.
+func loadConfig(path string, explicit bool) (config, error) {
+	var cfg config
+	if path != "" {
+		data, err := os.ReadFile(path)
+		switch {
+		case err == nil:
+			if err := yaml.Unmarshal(data, &cfg); err != nil {
+				return cfg, fmt.Errorf("parse %s: %w", path, err)
+			}
+		case !os.IsNotExist(err) || explicit:
+			return cfg, fmt.Errorf("read config: %w", err)
+		}
+	}
+
+	if v := os.Getenv("ATROPOS_SERVER"); v != "" {
+		cfg.Server = v
+	}
+	if v := os.Getenv("ATROPOS_HMAC_SECRET"); v != "" {
+		cfg.Secret = v
+	}
+	if v := os.Getenv("ATROPOS_KEY_ID"); v != "" {
+		cfg.KeyID = v
+	}
+	return cfg, nil
+}
+
+func (c *config) resolve() error {
+	if c.Server == "" {
+		c.Server = defaultServer
+	}
+	if c.Output == "" {
+		c.Output = "table"
+	}
+	if c.Output != "table" && c.Output != "json" {
+		return fmt.Errorf("output must be table or json, got %q", c.Output)
+	}
+	if c.Secret == "" && c.SecretFile != "" {
+		secret, err := secretfile.Read(c.SecretFile)
+		if err != nil {
+			return err
+		}
+		c.Secret = secret
+	}
+	return nil
+}
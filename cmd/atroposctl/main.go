@@ -0,0 +1,338 @@
+// Command atroposctl is an operator client for the Atropos API.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"atropos/api"
+	"atropos/client"
+	"atropos/history"
+)
+
+const usage = `usage: atroposctl [flags] <command> [args]
+
+commands:
+  cut NODE ENTROPY [-tag k=v] [-callback-url URL] [-source NAME] [-reason TEXT] [-async]
+  dryrun NODE ENTROPY
+  history list [-node NODE] [-limit N] [-offset N] [-all]
+  history show CUT_ID
+  stats
+  nodes [NODE]
+  maintenance list
+  maintenance start NODE DURATION REASON
+  maintenance end NODE
+  policy reload
+
+flags:
+`
+
+type cli struct {
+	client *client.Client
+	output string
+	out    io.Writer
+	ctx    context.Context
+}
+
+func main() {
+	flags := flag.NewFlagSet("atroposctl", flag.ExitOnError)
+	configPath := flags.String("config", "", "config file (default ~/.atroposctl.yaml)")
+	server := flags.String("server", "", "Atropos base URL (env ATROPOS_SERVER)")
+	secret := flags.String("secret", "", "HMAC secret (env ATROPOS_HMAC_SECRET)")
+	keyID := flags.String("key-id", "", "HMAC key ID (env ATROPOS_KEY_ID)")
+	output := flags.String("o", "", "output format: table or json")
+	flags.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		flags.PrintDefaults()
+	}
+	flags.Parse(os.Args[1:])
+
+	path, explicit := *configPath, *configPath != ""
+	if !explicit {
+		path = defaultConfigPath()
+	}
+	cfg, err := loadConfig(path, explicit)
+	if err != nil {
+		fatal(err)
+	}
+	if *server != "" {
+		cfg.Server = *server
+	}
+	if *secret != "" {
+		cfg.Secret = *secret
+	}
+	if *keyID != "" {
+		cfg.KeyID = *keyID
+	}
+	if *output != "" {
+		cfg.Output = *output
+	}
+	if err := cfg.resolve(); err != nil {
+		fatal(err)
+	}
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	apiClient, err := client.New(cfg.Server, client.WithHMACSecret(cfg.Secret), client.WithKeyID(cfg.KeyID))
+	if err != nil {
+		fatal(err)
+	}
+	c := &cli{client: apiClient, output: cfg.Output, out: os.Stdout, ctx: context.Background()}
+	if err := c.run(flags.Arg(0), flags.Args()[1:]); err != nil {
+		fatal(err)
+	}
+}
+
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, "atroposctl:", err)
+	os.Exit(1)
+}
+
+var errUsage = errors.New("invalid arguments, see atroposctl -h")
+
+func (c *cli) run(cmd string, args []string) error {
+	switch cmd {
+	case "cut":
+		return c.cut(args)
+	case "dryrun":
+		return c.dryRun(args)
+	case "history":
+		if len(args) == 0 {
+			return errUsage
+		}
+		switch args[0] {
+		case "list":
+			return c.historyList(args[1:])
+		case "show":
+			return c.historyShow(args[1:])
+		}
+	case "stats":
+		return c.stats()
+	case "nodes":
+		return c.nodes(args)
+	case "maintenance":
+		return c.maintenance(args)
+	case "policy":
+		if len(args) == 1 && args[0] == "reload" {
+			return c.reloadPolicy()
+		}
+	}
+	return errUsage
+}
+
+// parseNodeEntropy accepts flags before or after the two positional
+// arguments.
+func parseNodeEntropy(fs *flag.FlagSet, args []string) (string, float64, error) {
+	if err := fs.Parse(args); err != nil {
+		return "", 0, err
+	}
+	rest := fs.Args()
+	if len(rest) < 2 {
+		return "", 0, errUsage
+	}
+	if err := fs.Parse(rest[2:]); err != nil {
+		return "", 0, err
+	}
+	entropy, err := strconv.ParseFloat(rest[1], 64)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid entropy %q", rest[1])
+	}
+	return rest[0], entropy, nil
+}
+
+type tagFlags map[string]string
+
+func (t tagFlags) String() string { return "" }
+
+func (t tagFlags) Set(v string) error {
+	key, value, ok := strings.Cut(v, "=")
+	if !ok {
+		return fmt.Errorf("tag must be key=value, got %q", v)
+	}
+	t[key] = value
+	return nil
+}
+
+func (c *cli) cut(args []string) error {
+	fs := flag.NewFlagSet("cut", flag.ContinueOnError)
+	tags := tagFlags{}
+	fs.Var(tags, "tag", "tag as key=value (repeatable)")
+	callbackURL := fs.String("callback-url", "", "outcome callback URL")
+	source := fs.String("source", "atroposctl", "who is asking for the cut")
+	reason := fs.String("reason", "", "why the cut is needed")
+	async := fs.Bool("async", false, "return the cut's ID at once instead of waiting")
+	node, entropy, err := parseNodeEntropy(fs, args)
+	if err != nil {
+		return err
+	}
+
+	req := api.CutRequest{Node: node, Entropy: entropy, CallbackURL: *callbackURL, Source: *source, Reason: *reason}
+	if len(tags) > 0 {
+		req.Tags = tags
+	}
+
+	if *async {
+		started, job, err := c.client.CutAsync(c.ctx, req)
+		if err != nil {
+			return err
+		}
+		if job == nil {
+			return c.print(started, func(t *table) {
+				t.kv("Cut", started.ID)
+				t.kv("Outcome", started.Outcome)
+				t.kv("Status URL", started.StatusURL)
+			})
+		}
+		return c.printJob(job)
+	}
+
+	resp, job, err := c.client.Cut(c.ctx, req)
+	if err != nil {
+		return err
+	}
+	if job != nil {
+		return c.printJob(job)
+	}
+	return c.print(resp, func(t *table) { cutResponseTable(t, *resp) })
+}
+
+func (c *cli) printJob(job *api.JobAcceptedResponse) error {
+	return c.print(job, func(t *table) {
+		t.kv("Job", job.JobID)
+		t.kv("Status", job.Status)
+		t.kv("Status URL", job.StatusURL)
+	})
+}
+
+func (c *cli) dryRun(args []string) error {
+	fs := flag.NewFlagSet("dryrun", flag.ContinueOnError)
+	node, entropy, err := parseNodeEntropy(fs, args)
+	if err != nil {
+		return err
+	}
+	resp, err := c.client.DryRun(c.ctx, node, entropy)
+	if err != nil {
+		return err
+	}
+	return c.print(resp, func(t *table) { dryRunTable(t, *resp) })
+}
+
+func (c *cli) historyList(args []string) error {
+	fs := flag.NewFlagSet("history list", flag.ContinueOnError)
+	node := fs.String("node", "", "only cuts for this node")
+	limit := fs.Int("limit", 20, "records per page")
+	offset := fs.Int("offset", 0, "skip this many of the newest records")
+	all := fs.Bool("all", false, "follow pages until the history is exhausted")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *limit <= 0 {
+		return fmt.Errorf("limit must be positive")
+	}
+
+	q := client.HistoryQuery{Node: *node, Limit: *limit, Offset: *offset}
+	var cuts []*history.CutRecord
+	if *all {
+		var err error
+		if cuts, err = c.client.HistoryAll(c.ctx, q); err != nil {
+			return err
+		}
+	} else {
+		page, err := c.client.History(c.ctx, q)
+		if err != nil {
+			return err
+		}
+		cuts = page.Cuts
+	}
+
+	resp := api.CutListResponse{Node: *node, Count: len(cuts), Offset: *offset, Cuts: cuts}
+	return c.print(resp, func(t *table) { cutListTable(t, cuts) })
+}
+
+func (c *cli) historyShow(args []string) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+	cut, err := c.client.GetCut(c.ctx, args[0])
+	if err != nil {
+		return err
+	}
+	return c.print(cut, func(t *table) { cutRecordTable(t, cut) })
+}
+
+func (c *cli) stats() error {
+	resp, err := c.client.Stats(c.ctx, client.StatsQuery{})
+	if err != nil {
+		return err
+	}
+	return c.print(resp, func(t *table) { statsTable(t, *resp) })
+}
+
+func (c *cli) nodes(args []string) error {
+	switch len(args) {
+	case 0:
+		resp, err := c.client.Nodes(c.ctx)
+		if err != nil {
+			return err
+		}
+		return c.print(resp, func(t *table) { nodeListTable(t, resp.Nodes) })
+	case 1:
+		status, err := c.client.NodeStatus(c.ctx, args[0])
+		if err != nil {
+			return err
+		}
+		return c.print(status, func(t *table) { nodeStatusTable(t, status) })
+	}
+	return errUsage
+}
+
+func (c *cli) maintenance(args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+	switch {
+	case args[0] == "list" && len(args) == 1:
+		resp, err := c.client.Silences(c.ctx)
+		if err != nil {
+			return err
+		}
+		return c.print(resp, func(t *table) { silenceListTable(t, resp.Silences) })
+	case args[0] == "start" && len(args) >= 4:
+		silence, err := c.client.Silence(c.ctx, args[1], args[2], strings.Join(args[3:], " "))
+		if err != nil {
+			return err
+		}
+		return c.print(silence, func(t *table) { silenceListTable(t, []*history.Silence{silence}) })
+	case args[0] == "end" && len(args) == 2:
+		if err := c.client.Unsilence(c.ctx, args[1]); err != nil {
+			return err
+		}
+		resp := map[string]interface{}{"node": args[1], "silenced": false}
+		return c.print(resp, func(t *table) {
+			t.kv("Node", args[1])
+			t.kv("Silenced", "false")
+		})
+	}
+	return errUsage
+}
+
+func (c *cli) reloadPolicy() error {
+	resp, err := c.client.ReloadPolicy(c.ctx)
+	if err != nil {
+		return err
+	}
+	return c.print(resp, func(t *table) {
+		t.kv("Reloaded", strconv.FormatBool(resp.Reloaded))
+		t.kv("Version", resp.PolicyVersion)
+		t.kv("Hash", resp.PolicyHash)
+		t.kv("Nodes", strconv.Itoa(resp.NodeCount))
+	})
+}
@@ -0,0 +1,394 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"atropos/api"
+	"atropos/client"
+	"atropos/engine"
+	"atropos/history"
+	"atropos/notifications"
+	"atropos/policy"
+)
+
+const testSecret = "test-secret"
+
+const testPolicy = `
+server:
+  hmac_secret: ` + testSecret + `
+  dedup_window_seconds: 0
+nodes:
+  athena:
+    strategies:
+      - threshold: 0.5
+        action: test_restart
+`
+
+type testCutter struct{}
+
+func (testCutter) Name() string { return "test" }
+
+func (testCutter) CanHandle(action string) bool { return strings.HasPrefix(action, "test_") }
+
+func (testCutter) Execute(ctx context.Context, target string, params map[string]string) error {
+	return nil
+}
+
+type testAPI struct {
+	*httptest.Server
+	policyPath  string
+	historyGETs atomic.Int32
+}
+
+// newTestAPI serves policyYAML through the real API handlers, with the
+// policy re-read from disk on reload as the server's main does.
+func newTestAPI(t *testing.T, policyYAML string) *testAPI {
+	t.Helper()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "policy.yaml")
+	writeFile(t, path, policyYAML)
+	pol, err := policy.LoadPolicy(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	historyDir := filepath.Join(dir, "history")
+	notif := notifications.NewNotificationManager(&notifications.NotificationConfig{
+		StateFile: filepath.Join(historyDir, "notification_state.json"),
+	})
+	exec := engine.NewExecutor(pol, history.NewHistoryManager(historyDir), notif)
+	exec.RegisterCutter(testCutter{})
+	exec.SetReloader(func() (*policy.RemediationPolicy, error) {
+		pol, err := policy.LoadPolicy(path)
+		if err != nil {
+			return nil, err
+		}
+		exec.SetPolicy(pol)
+		return pol, nil
+	})
+
+	s := &testAPI{policyPath: path}
+	handler := api.NewServer(exec)
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet && r.URL.Path == "/api/v1/cuts/history" {
+			s.historyGETs.Add(1)
+		}
+		handler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func writeFile(t *testing.T, path, data string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// newTestCLI is the cli main builds, writing to out.
+func newTestCLI(t *testing.T, server, secret, keyID, output string) (*cli, *bytes.Buffer) {
+	t.Helper()
+	c, err := client.New(server, client.WithHMACSecret(secret), client.WithKeyID(keyID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := &bytes.Buffer{}
+	return &cli{client: c, output: output, out: out, ctx: context.Background()}, out
+}
+
+func run(t *testing.T, c *cli, args ...string) string {
+	t.Helper()
+	out := c.out.(*bytes.Buffer)
+	out.Reset()
+	if err := c.run(args[0], args[1:]); err != nil {
+		t.Fatalf("atroposctl %s: %v", strings.Join(args, " "), err)
+	}
+	return out.String()
+}
+
+func runJSON(t *testing.T, c *cli, v interface{}, args ...string) {
+	t.Helper()
+	c.output = "json"
+	defer func() { c.output = "table" }()
+	if err := json.Unmarshal([]byte(run(t, c, args...)), v); err != nil {
+		t.Fatalf("atroposctl %s: %v", strings.Join(args, " "), err)
+	}
+}
+
+func TestCutAndHistory(t *testing.T) {
+	s := newTestAPI(t, testPolicy)
+	c, _ := newTestCLI(t, s.URL, testSecret, "", "table")
+
+	out := run(t, c, "cut", "athena", "0.8", "-tag", "ticket=INC-7", "-reason", "disk full")
+	for _, want := range []string{"Node:", "athena", "Action:", "test_restart", "Status:", "ok"} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("cut output is missing %q:\n%s", want, out)
+		}
+	}
+
+	var list api.CutListResponse
+	runJSON(t, c, &list, "history", "list", "-node", "athena")
+	if list.Count != 1 || len(list.Cuts) != 1 {
+		t.Fatalf("history list = %+v, want one cut", list)
+	}
+	cut := list.Cuts[0]
+	if cut.Source != "atroposctl" || cut.Reason != "disk full" || cut.Tags["ticket"] != "INC-7" {
+		t.Fatalf("cut record = %+v, want the CLI's source, reason and tag", cut)
+	}
+
+	var shown history.CutRecord
+	runJSON(t, c, &shown, "history", "show", cut.ID)
+	if shown.ID != cut.ID || shown.Action != "test_restart" {
+		t.Fatalf("history show = %+v, want %s", shown, cut.ID)
+	}
+	out = run(t, c, "history", "show", cut.ID)
+	if !strings.Contains(out, "Tag ticket:") || !strings.Contains(out, "INC-7") {
+		t.Fatalf("history show table is missing the tag:\n%s", out)
+	}
+}
+
+func TestHistoryListAllFollowsPages(t *testing.T) {
+	s := newTestAPI(t, testPolicy)
+	c, _ := newTestCLI(t, s.URL, testSecret, "", "table")
+	for i := 0; i < 5; i++ {
+		run(t, c, "cut", "athena", "0.9")
+	}
+
+	s.historyGETs.Store(0)
+	var page api.CutListResponse
+	runJSON(t, c, &page, "history", "list", "-limit", "2")
+	if page.Count != 2 || s.historyGETs.Load() != 1 {
+		t.Fatalf("one page: %d cuts in %d requests, want 2 in 1", page.Count, s.historyGETs.Load())
+	}
+
+	s.historyGETs.Store(0)
+	var all api.CutListResponse
+	runJSON(t, c, &all, "history", "list", "-limit", "2", "-all")
+	if all.Count != 5 {
+		t.Fatalf("-all returned %d cuts, want 5", all.Count)
+	}
+	if got := s.historyGETs.Load(); got != 3 {
+		t.Fatalf("-all made %d requests for 5 cuts at 2 per page, want 3", got)
+	}
+	seen := map[string]bool{}
+	for _, cut := range all.Cuts {
+		if seen[cut.ID] {
+			t.Fatalf("cut %s listed twice across pages", cut.ID)
+		}
+		seen[cut.ID] = true
+	}
+
+	out := run(t, c, "history", "list", "-limit", "2", "-offset", "4")
+	if lines := strings.Count(strings.TrimSpace(out), "\n"); lines != 1 {
+		t.Fatalf("table for the last page has %d rows, want a header and 1:\n%s", lines+1, out)
+	}
+}
+
+func TestDryRunStatsAndNodes(t *testing.T) {
+	s := newTestAPI(t, testPolicy)
+	c, _ := newTestCLI(t, s.URL, testSecret, "", "table")
+
+	var dry api.DryRunResponse
+	runJSON(t, c, &dry, "dryrun", "athena", "0.7")
+	if dry.Node != "athena" || dry.Action != "test_restart" {
+		t.Fatalf("dryrun = %+v, want test_restart on athena", dry)
+	}
+	run(t, c, "cut", "athena", "0.9")
+
+	var stats api.StatsResponse
+	runJSON(t, c, &stats, "stats")
+	if stats.TotalCuts != 1 || stats.SuccessCuts != 1 {
+		t.Fatalf("stats = %+v, want one successful cut; the dry run doesn't count", stats)
+	}
+	if out := run(t, c, "stats"); !strings.Contains(out, "Total cuts:") {
+		t.Fatalf("stats table:\n%s", out)
+	}
+
+	out := run(t, c, "nodes")
+	if !strings.HasPrefix(out, "NODE") || !strings.Contains(out, "athena") || !strings.Contains(out, "closed") {
+		t.Fatalf("nodes table:\n%s", out)
+	}
+	var status engine.NodeStatus
+	runJSON(t, c, &status, "nodes", "athena")
+	if status.Node != "athena" || status.Circuit.Open {
+		t.Fatalf("node status = %+v", status)
+	}
+}
+
+func TestMaintenance(t *testing.T) {
+	s := newTestAPI(t, testPolicy)
+	c, _ := newTestCLI(t, s.URL, testSecret, "", "table")
+
+	run(t, c, "maintenance", "start", "athena", "1h", "patching", "the", "kernel")
+	var list api.SilenceListResponse
+	runJSON(t, c, &list, "maintenance", "list")
+	if len(list.Silences) != 1 || list.Silences[0].Node != "athena" || list.Silences[0].Reason != "patching the kernel" {
+		t.Fatalf("silences = %+v, want athena with the joined reason", list.Silences)
+	}
+
+	run(t, c, "maintenance", "end", "athena")
+	runJSON(t, c, &list, "maintenance", "list")
+	if len(list.Silences) != 0 {
+		t.Fatalf("silences after end = %+v, want none", list.Silences)
+	}
+}
+
+func TestWrongSecretIsRejected(t *testing.T) {
+	s := newTestAPI(t, testPolicy)
+	c, out := newTestCLI(t, s.URL, "wrong", "", "table")
+
+	err := c.run("cut", []string{"athena", "0.8"})
+	if !client.IsStatus(err, http.StatusForbidden) {
+		t.Fatalf("cut with the wrong secret: %v, want 403", err)
+	}
+	if out.Len() != 0 {
+		t.Fatalf("rejected cut printed %q", out.String())
+	}
+}
+
+// The CLI's reload is signed with the keys loaded before it; the keys it
+// loads apply from the next request on.
+func TestPolicyReloadRotatesKeys(t *testing.T) {
+	dir := t.TempDir()
+	q4 := filepath.Join(dir, "q4")
+	q1 := filepath.Join(dir, "q1")
+	writeFile(t, q4, "secret-q4")
+	writeFile(t, q1, "secret-q1")
+	policyYAML := func(keyID, file string) string {
+		return "server:\n  hmac_keys:\n    - key_id: " + keyID + "\n      secret_file: " + file + `
+nodes:
+  athena:
+    strategies:
+      - threshold: 0.5
+        action: test_restart
+`
+	}
+	s := newTestAPI(t, policyYAML("q4", q4))
+	old, _ := newTestCLI(t, s.URL, "secret-q4", "q4", "table")
+	rotated, _ := newTestCLI(t, s.URL, "secret-q1", "q1", "table")
+
+	writeFile(t, s.policyPath, policyYAML("q1", q1))
+	var resp api.PolicyReloadResponse
+	runJSON(t, old, &resp, "policy", "reload")
+	if !resp.Reloaded || resp.NodeCount != 1 {
+		t.Fatalf("reload = %+v", resp)
+	}
+
+	if err := old.run("policy", []string{"reload"}); !client.IsStatus(err, http.StatusForbidden) {
+		t.Fatalf("retired key after the reload: %v, want 403", err)
+	}
+	if out := run(t, rotated, "policy", "reload"); !strings.Contains(out, "Reloaded:") || !strings.Contains(out, "true") {
+		t.Fatalf("reload with the new key:\n%s", out)
+	}
+}
+
+func TestUsageErrors(t *testing.T) {
+	c, _ := newTestCLI(t, "http://127.0.0.1:0", testSecret, "", "table")
+	for _, args := range [][]string{
+		{"bogus"},
+		{"history"},
+		{"history", "show"},
+		{"cut", "athena"},
+		{"nodes", "a", "b"},
+		{"maintenance", "start", "athena", "1h"},
+		{"policy"},
+	} {
+		if err := c.run(args[0], args[1:]); !errors.Is(err, errUsage) {
+			t.Errorf("atroposctl %s: %v, want the usage error", strings.Join(args, " "), err)
+		}
+	}
+	if err := c.run("cut", []string{"athena", "high"}); err == nil || !strings.Contains(err.Error(), "invalid entropy") {
+		t.Errorf("non-numeric entropy: %v", err)
+	}
+}
+
+func TestParseNodeEntropyFlagsEitherSide(t *testing.T) {
+	for _, args := range [][]string{
+		{"-tag", "a=1", "athena", "0.8", "-tag", "b=2"},
+		{"athena", "0.8", "-tag", "a=1", "-tag", "b=2"},
+	} {
+		fs := flag.NewFlagSet("cut", flag.ContinueOnError)
+		tags := tagFlags{}
+		fs.Var(tags, "tag", "")
+		node, entropy, err := parseNodeEntropy(fs, args)
+		if err != nil {
+			t.Fatalf("%v: %v", args, err)
+		}
+		if node != "athena" || entropy != 0.8 || tags["a"] != "1" || tags["b"] != "2" {
+			t.Fatalf("%v: node %q entropy %v tags %v", args, node, entropy, tags)
+		}
+	}
+}
+
+func TestConfigPrecedence(t *testing.T) {
+	dir := t.TempDir()
+	secretFile := filepath.Join(dir, "secret")
+	writeFile(t, secretFile, "from-file\n")
+	path := filepath.Join(dir, "atroposctl.yaml")
+	writeFile(t, path, "server: http://config:8443\nsecret_file: "+secretFile+"\nkey_id: config-key\noutput: json\n")
+
+	t.Setenv("ATROPOS_SERVER", "")
+	t.Setenv("ATROPOS_HMAC_SECRET", "")
+	t.Setenv("ATROPOS_KEY_ID", "env-key")
+	cfg, err := loadConfig(path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.resolve(); err != nil {
+		t.Fatal(err)
+	}
+	want := config{Server: "http://config:8443", Secret: "from-file", SecretFile: secretFile, KeyID: "env-key", Output: "json"}
+	if cfg != want {
+		t.Fatalf("config = %+v, want %+v", cfg, want)
+	}
+
+	// The environment's secret wins over the file's.
+	t.Setenv("ATROPOS_HMAC_SECRET", "from-env")
+	cfg, err = loadConfig(path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.resolve(); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Secret != "from-env" {
+		t.Fatalf("secret = %q, want the environment's", cfg.Secret)
+	}
+}
+
+func TestConfigMissingFile(t *testing.T) {
+	t.Setenv("ATROPOS_SERVER", "")
+	t.Setenv("ATROPOS_HMAC_SECRET", "")
+	t.Setenv("ATROPOS_KEY_ID", "")
+	missing := filepath.Join(t.TempDir(), "missing.yaml")
+
+	cfg, err := loadConfig(missing, false)
+	if err != nil {
+		t.Fatalf("missing default config: %v", err)
+	}
+	if err := cfg.resolve(); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Server != defaultServer || cfg.Output != "table" {
+		t.Fatalf("defaults = %+v", cfg)
+	}
+	if _, err := loadConfig(missing, true); err == nil {
+		t.Fatal("missing -config file was not an error")
+	}
+
+	cfg = config{Output: "yaml"}
+	if err := cfg.resolve(); err == nil {
+		t.Fatal("output yaml was accepted")
+	}
+}
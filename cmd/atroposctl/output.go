@@ -0,0 +1,225 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"atropos/api"
+	"atropos/engine"
+	"atropos/history"
+)
+
+type table struct {
+	w *tabwriter.Writer
+}
+
+func (t *table) row(cols ...string) {
+	fmt.Fprintln(t.w, strings.Join(cols, "\t"))
+}
+
+// kv prints a key/value line, skipping empty values.
+func (t *table) kv(key, value string) {
+	if value != "" {
+		t.row(key+":", value)
+	}
+}
+
+// print writes v as indented JSON with -o json, or renders it with fn.
+func (c *cli) print(v interface{}, fn func(*table)) error {
+	if c.output == "json" {
+		enc := json.NewEncoder(c.out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	}
+	t := &table{w: tabwriter.NewWriter(c.out, 0, 4, 2, ' ', 0)}
+	fn(t)
+	return t.w.Flush()
+}
+
+func formatTime(ts time.Time) string {
+	if ts.IsZero() {
+		return ""
+	}
+	return ts.Local().Format("2006-01-02 15:04:05")
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+func status(success, dryRun bool) string {
+	switch {
+	case dryRun:
+		return "dry-run"
+	case success:
+		return "ok"
+	default:
+		return "failed"
+	}
+}
+
+func cutResponseTable(t *table, resp api.CutResponse) {
+	t.kv("Cut", resp.CutID)
+	t.kv("Node", resp.Node)
+	t.kv("Action", resp.Action)
+	t.kv("Status", status(resp.Success, resp.DryRun))
+	t.kv("Outcome", resp.Outcome)
+	t.kv("Error", resp.Error)
+	t.kv("Latency", fmt.Sprintf("%dms", resp.LatencyMs))
+	if esc := resp.Escalation; esc != nil {
+		t.kv("Selected", fmt.Sprintf("%s (threshold %.2f)", esc.OriginalAction, esc.OriginalThreshold))
+		t.kv("Moved on by", esc.Type)
+		t.kv("Selected error", esc.OriginalError)
+	}
+	// A single link is the cut itself.
+	if len(resp.Chain) < 2 {
+		return
+	}
+	for i, link := range resp.Chain {
+		step := link.Action + " " + status(link.Success, false)
+		if link.Via != "" {
+			step = fmt.Sprintf("%s (%s) %s", link.Action, link.Via, status(link.Success, false))
+		}
+		if link.Error != "" {
+			step += ": " + link.Error
+		}
+		t.kv(fmt.Sprintf("Chain %d", i), step)
+	}
+}
+
+func dryRunTable(t *table, resp api.DryRunResponse) {
+	t.kv("Node", resp.Node)
+	t.kv("Entropy", formatFloat(resp.Entropy))
+	t.kv("Action", resp.Action)
+	t.kv("Would execute", strconv.FormatBool(resp.WouldExecute))
+	t.kv("Threshold", formatFloat(resp.Threshold))
+	t.kv("Critical", strconv.FormatBool(resp.Critical))
+	t.kv("Description", resp.Description)
+	t.kv("Runbook", resp.RunbookURL)
+	if resp.BlockedBy != nil {
+		t.kv("Blocked by", fmt.Sprintf("%+v", *resp.BlockedBy))
+	}
+	for i, step := range resp.Plan {
+		line := step.Action + ": " + step.Summary
+		switch {
+		case step.Error != "":
+			line = step.Action + ": " + step.Error
+		case step.Supported:
+			line += " (ok)"
+		}
+		t.kv(fmt.Sprintf("Plan %d", i), line)
+	}
+}
+
+func cutListTable(t *table, cuts []*history.CutRecord) {
+	t.row("ID", "TIME", "NODE", "ENTROPY", "ACTION", "STATUS", "OUTCOME", "LATENCY")
+	for _, cut := range cuts {
+		t.row(cut.ID, formatTime(cut.Timestamp), cut.Node, formatFloat(cut.Entropy),
+			cut.Action, status(cut.Success, cut.DryRun), cut.Outcome, fmt.Sprintf("%dms", cut.LatencyMs))
+	}
+}
+
+func cutRecordTable(t *table, cut *history.CutRecord) {
+	t.kv("ID", cut.ID)
+	t.kv("Time", formatTime(cut.Timestamp))
+	t.kv("Node", cut.Node)
+	t.kv("Entropy", formatFloat(cut.Entropy))
+	t.kv("Action", cut.Action)
+	t.kv("Status", status(cut.Success, cut.DryRun))
+	t.kv("Outcome", cut.Outcome)
+	t.kv("Error", cut.Error)
+	t.kv("Latency", fmt.Sprintf("%dms", cut.LatencyMs))
+	t.kv("Trigger", cut.Trigger)
+	t.kv("Source", cut.Source)
+	t.kv("Reason", cut.Reason)
+	t.kv("Parent", cut.ParentCutID)
+	t.kv("Chain", cut.ChainID)
+	t.kv("Key", cut.KeyID)
+	t.kv("Policy", strings.TrimSpace(cut.PolicyVersion+" "+cut.PolicyHash))
+	for _, key := range sortedKeys(cut.Tags) {
+		t.kv("Tag "+key, cut.Tags[key])
+	}
+}
+
+func statsTable(t *table, resp api.StatsResponse) {
+	t.kv("Total cuts", strconv.Itoa(resp.TotalCuts))
+	t.kv("Success", strconv.Itoa(resp.SuccessCuts))
+	t.kv("Failed", strconv.Itoa(resp.FailedCuts))
+	t.kv("Deferred", strconv.Itoa(resp.DeferredCuts))
+	t.kv("Dry run", strconv.Itoa(resp.DryRunCuts))
+	t.kv("Skipped", strconv.Itoa(resp.SkippedCuts))
+	t.kv("Duplicates", strconv.Itoa(resp.DuplicateCuts))
+	t.kv("Chained", strconv.Itoa(resp.ChainedCuts))
+	t.kv("Success rate", fmt.Sprintf("%.1f%%", resp.SuccessRate))
+	if len(resp.Nodes) == 0 {
+		return
+	}
+	t.row("")
+	t.row("NODE", "CUTS", "SUCCESS", "FAILED", "SILENCED")
+	names := make([]string, 0, len(resp.Nodes))
+	for name := range resp.Nodes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		n := resp.Nodes[name]
+		t.row(name, strconv.Itoa(n.TotalCuts), strconv.Itoa(n.Success), strconv.Itoa(n.Failed), strconv.FormatBool(n.Silenced))
+	}
+}
+
+func circuitState(c engine.CircuitState) string {
+	if c.Open {
+		return "open"
+	}
+	return "closed"
+}
+
+func nodeListTable(t *table, nodes []*engine.NodeStatus) {
+	t.row("NODE", "CIRCUIT", "FAILURES", "TRIGGERS")
+	for _, n := range nodes {
+		triggers := make([]string, 0, len(n.Triggers))
+		for _, tr := range n.Triggers {
+			triggers = append(triggers, fmt.Sprintf("%s %d/%d", tr.Action, tr.Count, tr.Required))
+		}
+		t.row(n.Node, circuitState(n.Circuit), strconv.Itoa(n.Circuit.ConsecutiveFailures), strings.Join(triggers, ", "))
+	}
+}
+
+func nodeStatusTable(t *table, n *engine.NodeStatus) {
+	t.kv("Node", n.Node)
+	t.kv("Circuit", circuitState(n.Circuit))
+	t.kv("Failures", strconv.Itoa(n.Circuit.ConsecutiveFailures))
+	if n.Circuit.OpenUntil != nil {
+		t.kv("Open until", formatTime(*n.Circuit.OpenUntil))
+	}
+	t.kv("Last error", n.Circuit.LastError)
+	if len(n.Triggers) == 0 {
+		return
+	}
+	t.row("")
+	t.row("ACTION", "THRESHOLD", "COUNT", "REQUIRED")
+	for _, tr := range n.Triggers {
+		t.row(tr.Action, formatFloat(tr.Threshold), strconv.Itoa(tr.Count), strconv.Itoa(tr.Required))
+	}
+}
+
+func silenceListTable(t *table, silences []*history.Silence) {
+	t.row("NODE", "EXPIRES", "REASON")
+	for _, s := range silences {
+		t.row(s.Node, formatTime(s.ExpiresAt), s.Reason)
+	}
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"sync"
 	"time"
 )
 
@@ -44,6 +45,21 @@ type CorrelationResult struct {
 	Effectiveness float64         `json:"effectiveness"`
 }
 
+// ResolutionRate is the percentage of failed findings followed by a
+// successful cut.
+func (r *CorrelationResult) ResolutionRate() float64 {
+	if len(r.Findings) == 0 {
+		return 0
+	}
+	var confirmed int
+	for _, corr := range r.Remediated {
+		if corr.Resolved {
+			confirmed++
+		}
+	}
+	return float64(confirmed) / float64(len(r.Findings)) * 100
+}
+
 type CutReference struct {
 	ID        string    `json:"id"`
 	Timestamp time.Time `json:"timestamp"`
@@ -60,6 +76,7 @@ type Correlation struct {
 
 type ClothoImporter struct {
 	reports map[string]ClothoReport
+	mu      sync.RWMutex
 }
 
 func NewClothoImporter() *ClothoImporter {
@@ -74,16 +91,28 @@ func (ci *ClothoImporter) ImportReport(r io.Reader) (*ClothoReport, error) {
 		return nil, fmt.Errorf("decode report: %w", err)
 	}
 
+	ci.mu.Lock()
 	ci.reports[report.AuditID] = report
+	ci.mu.Unlock()
 	return &report, nil
 }
 
 func (ci *ClothoImporter) GetReport(auditID string) (*ClothoReport, bool) {
+	ci.mu.RLock()
+	defer ci.mu.RUnlock()
 	report, ok := ci.reports[auditID]
 	return &report, ok
 }
 
+func (ci *ClothoImporter) HasReports() bool {
+	ci.mu.RLock()
+	defer ci.mu.RUnlock()
+	return len(ci.reports) > 0
+}
+
 func (ci *ClothoImporter) ListReports() []ClothoReport {
+	ci.mu.RLock()
+	defer ci.mu.RUnlock()
 	var reports []ClothoReport
 	for _, report := range ci.reports {
 		reports = append(reports, report)
@@ -0,0 +1,88 @@
+package cutter
+
+import (
+	"sort"
+	"strings"
+)
+
+// ActionLister is implemented by cutters that can list the actions they
+// run. An entry ending in "*" stands for every action with that prefix.
+type ActionLister interface {
+	SupportedActions() []string
+}
+
+// ActionSpec is an action and the params it can't run without.
+type ActionSpec struct {
+	Action         string   `json:"action"`
+	RequiredParams []string `json:"required_params,omitempty"`
+}
+
+// Info describes a registered cutter. Prefix is set for cutters that take
+// any action starting with it; Actions is empty for cutters that don't
+// list theirs.
+type Info struct {
+	Name    string       `json:"name"`
+	Prefix  string       `json:"prefix,omitempty"`
+	Actions []ActionSpec `json:"actions,omitempty"`
+}
+
+// requiredParams are the params each built-in action fails without.
+// "host" comes from the node's host.
+var requiredParams = map[string][]string{
+	"vbox_revert_snapshot":  {"snapshot_name"},
+	"vmw_revert_snapshot":   {"snapshot_name"},
+	"winrm_exec":            {"host", "command"},
+	"winrm_restart_service": {"host", "service"},
+	"winrm_shutdown":        {"host"},
+	"ssh_*":                 {"host", "command"},
+	"net_isolate":           {"host"},
+	"net_unisolate":         {"host"},
+	"systemd_restart":       {"unit"},
+	"systemd_stop":          {"unit"},
+	"systemd_mask":          {"unit"},
+	"dns_remove_record":     {"dns_zone"},
+	"dns_restore_record":    {"dns_zone"},
+	"ssm_run_command":       {"command"},
+	"http_call":             {"http_url"},
+	"local_exec":            {"command"},
+}
+
+// Describe returns what the registry knows about each cutter, in the order
+// they are consulted.
+func (r *Registry) Describe() []Info {
+	infos := make([]Info, 0, len(r.cutters))
+	for _, c := range r.cutters {
+		info := Info{Name: c.Name()}
+		if lister, ok := c.(ActionLister); ok {
+			actions := lister.SupportedActions()
+			sort.Strings(actions)
+			for _, action := range actions {
+				if prefix, ok := strings.CutSuffix(action, "*"); ok {
+					info.Prefix = prefix
+				}
+				info.Actions = append(info.Actions, ActionSpec{Action: action, RequiredParams: requiredParams[action]})
+			}
+		}
+		infos = append(infos, info)
+	}
+	return infos
+}
+
+// Supports reports whether a registered cutter runs action. Cutters that
+// list their actions must list it; the others are taken at CanHandle.
+func (r *Registry) Supports(action string) bool {
+	c, ok := r.FindCutter(action)
+	if !ok {
+		return false
+	}
+	lister, ok := c.(ActionLister)
+	if !ok {
+		return true
+	}
+	for _, supported := range lister.SupportedActions() {
+		if prefix, ok := strings.CutSuffix(supported, "*"); (ok && strings.HasPrefix(action, prefix)) || supported == action {
+			return true
+		}
+	}
+	return false
+}
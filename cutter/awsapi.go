@@ -0,0 +1,213 @@
+package cutter
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// The SSM cutter calls the AWS JSON API directly, signing requests with
+// Signature Version 4.
+
+const (
+	awsRetryBase = 200 * time.Millisecond
+	awsRetryMax  = 5 * time.Second
+)
+
+type awsCredentials struct {
+	accessKey    string
+	secretKey    string
+	sessionToken string
+}
+
+// awsCredentialsFromParams reads aws_access_key_id, aws_secret_access_key
+// and aws_session_token, falling back to the standard environment
+// variables.
+func awsCredentialsFromParams(params map[string]string) (awsCredentials, error) {
+	creds := awsCredentials{
+		accessKey:    paramOrEnv(params, "aws_access_key_id", "AWS_ACCESS_KEY_ID"),
+		secretKey:    paramOrEnv(params, "aws_secret_access_key", "AWS_SECRET_ACCESS_KEY"),
+		sessionToken: paramOrEnv(params, "aws_session_token", "AWS_SESSION_TOKEN"),
+	}
+	if creds.accessKey == "" || creds.secretKey == "" {
+		return creds, fmt.Errorf("aws credentials missing; set AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY")
+	}
+	return creds, nil
+}
+
+// awsError is an error the service returned, e.g. ThrottlingException.
+type awsError struct {
+	Type    string
+	Message string
+	Status  int
+}
+
+func (e *awsError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Type, e.Message)
+}
+
+func (e *awsError) throttled() bool {
+	return e.Status == http.StatusTooManyRequests || strings.Contains(e.Type, "Throttling") ||
+		e.Type == "TooManyRequestsException" || e.Type == "RequestLimitExceeded"
+}
+
+// awsJSONClient calls one service's JSON 1.1 API, e.g. AmazonSSM.
+type awsJSONClient struct {
+	endpoint string
+	region   string
+	service  string
+	target   string
+	creds    awsCredentials
+	http     *http.Client
+}
+
+// call sends operation with in as its body and decodes the response into
+// out. Throttled and server-side failures are retried with backoff until
+// ctx ends.
+func (c *awsJSONClient) call(ctx context.Context, operation string, in, out interface{}) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	backoff := awsRetryBase
+	for {
+		err := c.do(ctx, operation, body, out)
+		var apiErr *awsError
+		if err == nil || !errors.As(err, &apiErr) || !(apiErr.throttled() || apiErr.Status >= 500) {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%s: %w (last error %v)", operation, ctx.Err(), err)
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > awsRetryMax {
+			backoff = awsRetryMax
+		}
+	}
+}
+
+func (c *awsJSONClient) do(ctx context.Context, operation string, body []byte, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", c.target+"."+operation)
+	signAWSRequest(req, body, c.creds, c.region, c.service, time.Now().UTC())
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		var e struct {
+			Type     string `json:"__type"`
+			Message  string `json:"message"`
+			MessageU string `json:"Message"`
+		}
+		_ = json.Unmarshal(data, &e)
+		if e.Message == "" {
+			e.Message = e.MessageU
+		}
+		if e.Type == "" {
+			e.Type = fmt.Sprintf("HTTP %d", resp.StatusCode)
+		}
+		// e.g. com.amazonaws.ssm#InvalidInstanceId
+		if i := strings.LastIndexByte(e.Type, '#'); i >= 0 {
+			e.Type = e.Type[i+1:]
+		}
+		return &awsError{Type: e.Type, Message: e.Message, Status: resp.StatusCode}
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(data, out)
+}
+
+// signAWSRequest adds Signature Version 4 headers to req, whose body is
+// body.
+func signAWSRequest(req *http.Request, body []byte, creds awsCredentials, region, service string, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := sha256Hex(body)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	if creds.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.sessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name := range req.Header {
+		lower := strings.ToLower(name)
+		if lower == "content-type" || strings.HasPrefix(lower, "x-amz-") {
+			headers[lower] = strings.TrimSpace(req.Header.Get(name))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	canonical := strings.Join([]string{
+		req.Method,
+		path,
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + region + "/" + service + "/aws4_request"
+	toSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonical))
+	key := hmacSHA256([]byte("AWS4"+creds.secretKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, toSign))
+
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+creds.accessKey+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+func canonicalQuery(q url.Values) string {
+	// Encode sorts by key; SigV4 wants %20 rather than +.
+	return strings.ReplaceAll(q.Encode(), "+", "%20")
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
@@ -0,0 +1,115 @@
+package cutter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// The cases below come from AWS's Signature Version 4 test suite, which
+// signs for service "service" in us-east-1 at 20150830T123600Z.
+var sigV4Creds = awsCredentials{accessKey: "AKIDEXAMPLE", secretKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"}
+
+func TestSignAWSRequestSuite(t *testing.T) {
+	now := time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC)
+	tests := []struct {
+		name          string
+		method, url   string
+		contentType   string
+		body          string
+		signedHeaders string
+		signature     string
+	}{
+		{"get-vanilla", "GET", "https://example.amazonaws.com/", "", "",
+			"host;x-amz-date", "5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"},
+		{"post-vanilla", "POST", "https://example.amazonaws.com/", "", "",
+			"host;x-amz-date", "5da7c1a2acd57cee7505fc6676e4e544621c30862966e37dddb68e92efbe5d6b"},
+		{"get-vanilla-query-order-key-case", "GET", "https://example.amazonaws.com/?Param2=value2&Param1=value1", "", "",
+			"host;x-amz-date", "b97d918cfa904a5beff61c982a1b6f458b799221646efd99d3219ec94cdf2500"},
+		{"post-x-www-form-urlencoded", "POST", "https://example.amazonaws.com/", "application/x-www-form-urlencoded", "Param1=value1",
+			"content-type;host;x-amz-date", "ff11897932ad3f4e8b18135d722051e5ac45fc38421b1da7b9d196a0fe09473a"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, tt.url, strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			signAWSRequest(req, []byte(tt.body), sigV4Creds, "us-east-1", "service", now)
+
+			want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, " +
+				"SignedHeaders=" + tt.signedHeaders + ", Signature=" + tt.signature
+			if got := req.Header.Get("Authorization"); got != want {
+				t.Errorf("Authorization =\n%s\nwant\n%s", got, want)
+			}
+			if got := req.Header.Get("X-Amz-Date"); got != "20150830T123600Z" {
+				t.Errorf("X-Amz-Date = %s", got)
+			}
+		})
+	}
+}
+
+func TestSignAWSRequestSessionToken(t *testing.T) {
+	req, _ := http.NewRequest("POST", "https://ssm.us-east-1.amazonaws.com/", nil)
+	creds := sigV4Creds
+	creds.sessionToken = "session"
+	signAWSRequest(req, nil, creds, "us-east-1", "ssm", time.Now().UTC())
+	if req.Header.Get("X-Amz-Security-Token") != "session" {
+		t.Fatal("session token not sent")
+	}
+	if auth := req.Header.Get("Authorization"); !strings.Contains(auth, "SignedHeaders=host;x-amz-date;x-amz-security-token,") {
+		t.Fatalf("session token not signed: %s", auth)
+	}
+}
+
+func TestAWSJSONClientRetriesThrottling(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Amz-Target") != "AmazonSSM.DescribeInstanceInformation" || r.Header.Get("Authorization") == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"__type": "com.amazonaws.ssm#ThrottlingException", "message": "Rate exceeded"}`)
+			return
+		}
+		fmt.Fprint(w, `{"InstanceInformationList": [{"InstanceId": "i-1"}]}`)
+	}))
+	defer srv.Close()
+
+	c := &awsJSONClient{endpoint: srv.URL, region: "us-east-1", service: "ssm", target: "AmazonSSM", creds: sigV4Creds, http: srv.Client()}
+	var out struct {
+		InstanceInformationList []struct{ InstanceId string }
+	}
+	if err := c.call(context.Background(), "DescribeInstanceInformation", map[string]string{}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if calls.Load() != 2 || len(out.InstanceInformationList) != 1 {
+		t.Fatalf("calls = %d, out = %+v", calls.Load(), out)
+	}
+}
+
+func TestAWSJSONClientError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"__type": "com.amazonaws.ssm#InvalidInstanceId", "Message": "no such instance"}`)
+	}))
+	defer srv.Close()
+
+	c := &awsJSONClient{endpoint: srv.URL, region: "us-east-1", service: "ssm", target: "AmazonSSM", creds: sigV4Creds, http: srv.Client()}
+	err := c.call(context.Background(), "SendCommand", map[string]string{}, nil)
+	var apiErr *awsError
+	if !errors.As(err, &apiErr) || apiErr.Type != "InvalidInstanceId" || apiErr.Message != "no such instance" {
+		t.Fatalf("err = %v, want InvalidInstanceId", err)
+	}
+}
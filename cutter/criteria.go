@@ -0,0 +1,74 @@
+package cutter
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var patternCache sync.Map
+
+func compiledPattern(pattern string) (*regexp.Regexp, error) {
+	if re, ok := patternCache.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	patternCache.Store(pattern, re)
+	return re, nil
+}
+
+// evaluateCommand applies the strategy's success criteria to a finished
+// command. It returns the rule that decided the outcome alongside the error,
+// so the rule can be recorded whether the command passed or not.
+func evaluateCommand(params map[string]string, exitCode int, output string) (string, error) {
+	if pattern := params["failure_output_regex"]; pattern != "" {
+		re, err := compiledPattern(pattern)
+		if err != nil {
+			return "", fmt.Errorf("failure_output_regex: %w", err)
+		}
+		if re.MatchString(output) {
+			return "failure_output_regex", fmt.Errorf("output matched failure_output_regex %q", pattern)
+		}
+	}
+
+	successCodes := []int{0}
+	if codes := params["success_exit_codes"]; codes != "" {
+		successCodes = successCodes[:0]
+		for _, field := range strings.Split(codes, ",") {
+			code, err := strconv.Atoi(strings.TrimSpace(field))
+			if err != nil {
+				return "", fmt.Errorf("success_exit_codes: %w", err)
+			}
+			successCodes = append(successCodes, code)
+		}
+	}
+
+	exitOK := false
+	for _, code := range successCodes {
+		if code == exitCode {
+			exitOK = true
+			break
+		}
+	}
+	if !exitOK {
+		return "exit_code", fmt.Errorf("exit status %d not in success_exit_codes %v", exitCode, successCodes)
+	}
+
+	if pattern := params["success_output_regex"]; pattern != "" {
+		re, err := compiledPattern(pattern)
+		if err != nil {
+			return "", fmt.Errorf("success_output_regex: %w", err)
+		}
+		if !re.MatchString(output) {
+			return "success_output_regex", fmt.Errorf("output did not match success_output_regex %q", pattern)
+		}
+		return "success_output_regex", nil
+	}
+
+	return "exit_code", nil
+}
@@ -0,0 +1,555 @@
+package cutter
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// dbusConn speaks enough of the D-Bus wire protocol to call methods on the
+// system bus and read their replies. Signals and calls to us are skipped.
+
+const (
+	dbusDefaultSystemBus = "unix:path=/var/run/dbus/system_bus_socket"
+	dbusMaxMessage       = 1 << 20
+
+	dbusMethodCall   = 1
+	dbusMethodReturn = 2
+	dbusError        = 3
+
+	dbusFieldPath        = 1
+	dbusFieldInterface   = 2
+	dbusFieldMember      = 3
+	dbusFieldErrorName   = 4
+	dbusFieldReplySerial = 5
+	dbusFieldDestination = 6
+	dbusFieldSignature   = 8
+)
+
+// dbusVariant is a value of type v.
+type dbusVariant struct {
+	sig   string
+	value interface{}
+}
+
+// dbusCallError is an error reply, e.g. org.freedesktop.DBus.Error.UnknownObject.
+type dbusCallError struct {
+	Name    string
+	Message string
+}
+
+func (e *dbusCallError) Error() string {
+	if e.Message == "" {
+		return e.Name
+	}
+	return e.Name + ": " + e.Message
+}
+
+type dbusConn struct {
+	conn   net.Conn
+	r      *bufio.Reader
+	serial uint32
+}
+
+// dialSystemBus connects to DBUS_SYSTEM_BUS_ADDRESS, or the default system
+// bus socket, and authenticates as this process's user.
+func dialSystemBus(ctx context.Context) (*dbusConn, error) {
+	addr := os.Getenv("DBUS_SYSTEM_BUS_ADDRESS")
+	if addr == "" {
+		addr = dbusDefaultSystemBus
+	}
+	path, err := dbusSocketPath(addr)
+	if err != nil {
+		return nil, err
+	}
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("system bus: %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	c := &dbusConn{conn: conn, r: bufio.NewReader(conn)}
+	if err := c.auth(); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("system bus auth: %w", err)
+	}
+	if _, err := c.call("org.freedesktop.DBus", "/org/freedesktop/DBus", "org.freedesktop.DBus", "Hello", ""); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("system bus hello: %w", err)
+	}
+	return c, nil
+}
+
+// dbusSocketPath takes the first unix address of a bus address list.
+func dbusSocketPath(addr string) (string, error) {
+	for _, entry := range strings.Split(addr, ";") {
+		transport, rest, ok := strings.Cut(entry, ":")
+		if !ok || transport != "unix" {
+			continue
+		}
+		for _, kv := range strings.Split(rest, ",") {
+			key, value, _ := strings.Cut(kv, "=")
+			switch key {
+			case "path":
+				return value, nil
+			case "abstract":
+				return "@" + value, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("no unix socket in bus address %q", addr)
+}
+
+func (c *dbusConn) Close() error {
+	return c.conn.Close()
+}
+
+func (c *dbusConn) auth() error {
+	uid := hex.EncodeToString([]byte(strconv.Itoa(os.Getuid())))
+	if _, err := c.conn.Write([]byte("\x00AUTH EXTERNAL " + uid + "\r\n")); err != nil {
+		return err
+	}
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(line, "OK ") {
+		return fmt.Errorf("rejected: %s", strings.TrimSpace(line))
+	}
+	_, err = c.conn.Write([]byte("BEGIN\r\n"))
+	return err
+}
+
+// call sends a method call and waits for its reply's body.
+func (c *dbusConn) call(dest, path, iface, member, sig string, args ...interface{}) ([]interface{}, error) {
+	c.serial++
+	serial := c.serial
+	fields := []interface{}{
+		[]interface{}{byte(dbusFieldPath), dbusVariant{"o", path}},
+		[]interface{}{byte(dbusFieldInterface), dbusVariant{"s", iface}},
+		[]interface{}{byte(dbusFieldMember), dbusVariant{"s", member}},
+		[]interface{}{byte(dbusFieldDestination), dbusVariant{"s", dest}},
+	}
+	if sig != "" {
+		fields = append(fields, []interface{}{byte(dbusFieldSignature), dbusVariant{"g", sig}})
+	}
+
+	var body dbusEncoder
+	types, err := dbusSplitSignature(sig)
+	if err != nil {
+		return nil, err
+	}
+	if len(types) != len(args) {
+		return nil, fmt.Errorf("%s takes %d args, got %d", member, len(types), len(args))
+	}
+	for i, t := range types {
+		if err := body.value(t, args[i]); err != nil {
+			return nil, fmt.Errorf("%s arg %d: %w", member, i, err)
+		}
+	}
+
+	var msg dbusEncoder
+	msg.buf = append(msg.buf, 'l', dbusMethodCall, 0, 1)
+	msg.uint32(uint32(len(body.buf)))
+	msg.uint32(serial)
+	if err := msg.value("a(yv)", fields); err != nil {
+		return nil, err
+	}
+	msg.align(8)
+	if _, err := c.conn.Write(append(msg.buf, body.buf...)); err != nil {
+		return nil, err
+	}
+
+	for {
+		typ, headers, reply, err := c.read()
+		if err != nil {
+			return nil, err
+		}
+		if typ != dbusMethodReturn && typ != dbusError {
+			continue
+		}
+		if replySerial, _ := headers[dbusFieldReplySerial].(uint32); replySerial != serial {
+			continue
+		}
+		if typ == dbusError {
+			callErr := &dbusCallError{}
+			callErr.Name, _ = headers[dbusFieldErrorName].(string)
+			if len(reply) > 0 {
+				callErr.Message, _ = reply[0].(string)
+			}
+			return nil, callErr
+		}
+		return reply, nil
+	}
+}
+
+// read reads one message and returns its type, header fields and body.
+func (c *dbusConn) read() (byte, map[byte]interface{}, []interface{}, error) {
+	fixed := make([]byte, 16)
+	if _, err := io.ReadFull(c.r, fixed); err != nil {
+		return 0, nil, nil, err
+	}
+	var order binary.ByteOrder
+	switch fixed[0] {
+	case 'l':
+		order = binary.LittleEndian
+	case 'B':
+		order = binary.BigEndian
+	default:
+		return 0, nil, nil, fmt.Errorf("bad endianness byte %q", fixed[0])
+	}
+	bodyLen := order.Uint32(fixed[4:])
+	fieldsLen := order.Uint32(fixed[12:])
+	if bodyLen > dbusMaxMessage || fieldsLen > dbusMaxMessage {
+		return 0, nil, nil, errors.New("message too large")
+	}
+	headerLen := (16 + int(fieldsLen) + 7) &^ 7
+	msg := make([]byte, headerLen+int(bodyLen))
+	copy(msg, fixed)
+	if _, err := io.ReadFull(c.r, msg[16:]); err != nil {
+		return 0, nil, nil, err
+	}
+
+	d := &dbusDecoder{buf: msg, off: 12, order: order}
+	raw, err := d.value("a(yv)")
+	if err != nil {
+		return 0, nil, nil, fmt.Errorf("header: %w", err)
+	}
+	headers := make(map[byte]interface{})
+	for _, field := range raw.([]interface{}) {
+		if f, ok := field.([]interface{}); ok {
+			if code, ok := f[0].(byte); ok {
+				headers[code] = f[1]
+			}
+		}
+	}
+
+	sig, _ := headers[dbusFieldSignature].(string)
+	types, err := dbusSplitSignature(sig)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	d.off = headerLen
+	body := make([]interface{}, len(types))
+	for i, t := range types {
+		if body[i], err = d.value(t); err != nil {
+			return 0, nil, nil, fmt.Errorf("body: %w", err)
+		}
+	}
+	return fixed[1], headers, body, nil
+}
+
+// dbusSplitSignature splits a signature into its complete types.
+func dbusSplitSignature(sig string) ([]string, error) {
+	var types []string
+	for sig != "" {
+		n, err := dbusTypeLen(sig)
+		if err != nil {
+			return nil, err
+		}
+		types = append(types, sig[:n])
+		sig = sig[n:]
+	}
+	return types, nil
+}
+
+// dbusTypeLen is the length of the complete type sig starts with.
+func dbusTypeLen(sig string) (int, error) {
+	if sig == "" {
+		return 0, errors.New("truncated signature")
+	}
+	switch sig[0] {
+	case 'a':
+		n, err := dbusTypeLen(sig[1:])
+		return n + 1, err
+	case '(', '{':
+		depth := 0
+		for i := 0; i < len(sig); i++ {
+			switch sig[i] {
+			case '(', '{':
+				depth++
+			case ')', '}':
+				depth--
+				if depth == 0 {
+					return i + 1, nil
+				}
+			}
+		}
+		return 0, fmt.Errorf("unbalanced signature %q", sig)
+	case 'y', 'b', 'n', 'q', 'i', 'u', 'x', 't', 'd', 's', 'o', 'g', 'v', 'h':
+		return 1, nil
+	}
+	return 0, fmt.Errorf("unsupported type %q", sig[0])
+}
+
+func dbusAlignment(t byte) int {
+	switch t {
+	case 'y', 'g', 'v':
+		return 1
+	case 'n', 'q':
+		return 2
+	case 'x', 't', 'd', '(', '{':
+		return 8
+	}
+	return 4
+}
+
+// dbusEncoder writes little-endian values. Alignment is relative to the
+// start of buf, which is always 8-aligned in the message.
+type dbusEncoder struct {
+	buf []byte
+}
+
+func (e *dbusEncoder) align(n int) {
+	for len(e.buf)%n != 0 {
+		e.buf = append(e.buf, 0)
+	}
+}
+
+func (e *dbusEncoder) uint32(v uint32) {
+	e.align(4)
+	e.buf = binary.LittleEndian.AppendUint32(e.buf, v)
+}
+
+func (e *dbusEncoder) value(sig string, v interface{}) error {
+	switch sig[0] {
+	case 'y':
+		b, ok := v.(byte)
+		if !ok {
+			return fmt.Errorf("want byte for y, got %T", v)
+		}
+		e.buf = append(e.buf, b)
+	case 'b':
+		b, ok := v.(bool)
+		if !ok {
+			return fmt.Errorf("want bool for b, got %T", v)
+		}
+		var u uint32
+		if b {
+			u = 1
+		}
+		e.uint32(u)
+	case 'u':
+		u, ok := v.(uint32)
+		if !ok {
+			return fmt.Errorf("want uint32 for u, got %T", v)
+		}
+		e.uint32(u)
+	case 's', 'o':
+		s, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("want string for %c, got %T", sig[0], v)
+		}
+		e.uint32(uint32(len(s)))
+		e.buf = append(e.buf, s...)
+		e.buf = append(e.buf, 0)
+	case 'g':
+		s, ok := v.(string)
+		if !ok || len(s) > 255 {
+			return fmt.Errorf("want a signature for g, got %v", v)
+		}
+		e.buf = append(e.buf, byte(len(s)))
+		e.buf = append(e.buf, s...)
+		e.buf = append(e.buf, 0)
+	case 'v':
+		dv, ok := v.(dbusVariant)
+		if !ok {
+			return fmt.Errorf("want variant for v, got %T", v)
+		}
+		if err := e.value("g", dv.sig); err != nil {
+			return err
+		}
+		return e.value(dv.sig, dv.value)
+	case 'a':
+		items, ok := v.([]interface{})
+		if !ok {
+			return fmt.Errorf("want []interface{} for %s, got %T", sig, v)
+		}
+		e.uint32(0)
+		lenAt := len(e.buf) - 4
+		e.align(dbusAlignment(sig[1]))
+		start := len(e.buf)
+		for _, item := range items {
+			if err := e.value(sig[1:], item); err != nil {
+				return err
+			}
+		}
+		binary.LittleEndian.PutUint32(e.buf[lenAt:], uint32(len(e.buf)-start))
+	case '(':
+		fields, ok := v.([]interface{})
+		if !ok {
+			return fmt.Errorf("want []interface{} for %s, got %T", sig, v)
+		}
+		types, err := dbusSplitSignature(sig[1 : len(sig)-1])
+		if err != nil {
+			return err
+		}
+		if len(types) != len(fields) {
+			return fmt.Errorf("%s has %d fields, got %d", sig, len(types), len(fields))
+		}
+		e.align(8)
+		for i, t := range types {
+			if err := e.value(t, fields[i]); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("cannot encode %s", sig)
+	}
+	return nil
+}
+
+// dbusDecoder reads values from a whole message, so offsets are aligned
+// from its start. Arrays, structs and dict entries come back as
+// []interface{}.
+type dbusDecoder struct {
+	buf   []byte
+	off   int
+	order binary.ByteOrder
+}
+
+func (d *dbusDecoder) align(n int) error {
+	d.off = (d.off + n - 1) &^ (n - 1)
+	if d.off > len(d.buf) {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+
+func (d *dbusDecoder) take(n int) ([]byte, error) {
+	if n < 0 || d.off+n > len(d.buf) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	b := d.buf[d.off : d.off+n]
+	d.off += n
+	return b, nil
+}
+
+func (d *dbusDecoder) fixed(n int) ([]byte, error) {
+	if err := d.align(n); err != nil {
+		return nil, err
+	}
+	return d.take(n)
+}
+
+func (d *dbusDecoder) value(sig string) (interface{}, error) {
+	switch sig[0] {
+	case 'y':
+		b, err := d.take(1)
+		if err != nil {
+			return nil, err
+		}
+		return b[0], nil
+	case 'n', 'q':
+		b, err := d.fixed(2)
+		if err != nil {
+			return nil, err
+		}
+		if sig[0] == 'n' {
+			return int16(d.order.Uint16(b)), nil
+		}
+		return d.order.Uint16(b), nil
+	case 'b', 'i', 'u', 'h':
+		b, err := d.fixed(4)
+		if err != nil {
+			return nil, err
+		}
+		switch sig[0] {
+		case 'b':
+			return d.order.Uint32(b) != 0, nil
+		case 'i':
+			return int32(d.order.Uint32(b)), nil
+		}
+		return d.order.Uint32(b), nil
+	case 'x', 't', 'd':
+		b, err := d.fixed(8)
+		if err != nil {
+			return nil, err
+		}
+		switch sig[0] {
+		case 'x':
+			return int64(d.order.Uint64(b)), nil
+		case 'd':
+			return math.Float64frombits(d.order.Uint64(b)), nil
+		}
+		return d.order.Uint64(b), nil
+	case 's', 'o':
+		b, err := d.fixed(4)
+		if err != nil {
+			return nil, err
+		}
+		s, err := d.take(int(d.order.Uint32(b)) + 1)
+		if err != nil {
+			return nil, err
+		}
+		return string(s[:len(s)-1]), nil
+	case 'g':
+		n, err := d.take(1)
+		if err != nil {
+			return nil, err
+		}
+		s, err := d.take(int(n[0]) + 1)
+		if err != nil {
+			return nil, err
+		}
+		return string(s[:len(s)-1]), nil
+	case 'v':
+		inner, err := d.value("g")
+		if err != nil {
+			return nil, err
+		}
+		if n, err := dbusTypeLen(inner.(string)); err != nil || n != len(inner.(string)) {
+			return nil, fmt.Errorf("bad variant signature %q", inner)
+		}
+		return d.value(inner.(string))
+	case 'a':
+		b, err := d.fixed(4)
+		if err != nil {
+			return nil, err
+		}
+		size := int(d.order.Uint32(b))
+		if err := d.align(dbusAlignment(sig[1])); err != nil {
+			return nil, err
+		}
+		end := d.off + size
+		if size > dbusMaxMessage || end > len(d.buf) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		items := []interface{}{}
+		for d.off < end {
+			item, err := d.value(sig[1:])
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		return items, nil
+	case '(', '{':
+		types, err := dbusSplitSignature(sig[1 : len(sig)-1])
+		if err != nil {
+			return nil, err
+		}
+		if err := d.align(8); err != nil {
+			return nil, err
+		}
+		fields := make([]interface{}, len(types))
+		for i, t := range types {
+			if fields[i], err = d.value(t); err != nil {
+				return nil, err
+			}
+		}
+		return fields, nil
+	}
+	return nil, fmt.Errorf("cannot decode %s", sig)
+}
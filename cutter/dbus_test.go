@@ -0,0 +1,298 @@
+package cutter
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"net"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDBusRoundTrip(t *testing.T) {
+	tests := []struct {
+		sig   string
+		value interface{}
+		want  interface{}
+	}{
+		{"y", byte(7), byte(7)},
+		{"b", true, true},
+		{"b", false, false},
+		{"u", uint32(0xdeadbeef), uint32(0xdeadbeef)},
+		{"s", "sshd.service", "sshd.service"},
+		{"s", "", ""},
+		{"o", "/org/freedesktop/systemd1/job/42", "/org/freedesktop/systemd1/job/42"},
+		{"g", "a(yv)", "a(yv)"},
+		{"v", dbusVariant{"s", "active"}, "active"},
+		{"as", []interface{}{"a.service", "b.service"}, []interface{}{"a.service", "b.service"}},
+		{"as", []interface{}{}, []interface{}{}},
+		{"(ysu)", []interface{}{byte(1), "x", uint32(2)}, []interface{}{byte(1), "x", uint32(2)}},
+		{"a(yv)",
+			[]interface{}{[]interface{}{byte(1), dbusVariant{"o", "/a"}}, []interface{}{byte(5), dbusVariant{"u", uint32(9)}}},
+			[]interface{}{[]interface{}{byte(1), "/a"}, []interface{}{byte(5), uint32(9)}}},
+	}
+	for _, tt := range tests {
+		// A leading byte checks values are aligned from the buffer's start.
+		e := dbusEncoder{buf: []byte{0xff}}
+		if err := e.value(tt.sig, tt.value); err != nil {
+			t.Fatalf("encode %s %v: %v", tt.sig, tt.value, err)
+		}
+		d := &dbusDecoder{buf: e.buf, off: 1, order: binary.LittleEndian}
+		got, err := d.value(tt.sig)
+		if err != nil {
+			t.Fatalf("decode %s: %v", tt.sig, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %#v, want %#v", tt.sig, got, tt.want)
+		}
+		if d.off != len(e.buf) {
+			t.Errorf("%s: decoded %d of %d bytes", tt.sig, d.off, len(e.buf))
+		}
+	}
+}
+
+func TestDBusEncoding(t *testing.T) {
+	tests := []struct {
+		sig   string
+		value interface{}
+		want  []byte
+	}{
+		{"s", "ab", []byte{2, 0, 0, 0, 'a', 'b', 0}},
+		{"g", "s", []byte{1, 's', 0}},
+		{"b", true, []byte{1, 0, 0, 0}},
+		{"v", dbusVariant{"u", uint32(1)}, []byte{1, 'u', 0, 0, 1, 0, 0, 0}},
+		// The array length leaves out the padding before its first struct.
+		{"a(y)", []interface{}{[]interface{}{byte(3)}}, []byte{1, 0, 0, 0, 0, 0, 0, 0, 3}},
+	}
+	for _, tt := range tests {
+		var e dbusEncoder
+		if err := e.value(tt.sig, tt.value); err != nil {
+			t.Fatalf("encode %s: %v", tt.sig, err)
+		}
+		if !reflect.DeepEqual(e.buf, tt.want) {
+			t.Errorf("%s: got % x, want % x", tt.sig, e.buf, tt.want)
+		}
+	}
+}
+
+func TestDBusDecodeBigEndian(t *testing.T) {
+	buf := []byte{0, 0, 0, 3, 'a', 'b', 'c', 0, 0, 0, 0, 0, 0, 0, 0, 9}
+	d := &dbusDecoder{buf: buf, order: binary.BigEndian}
+	if s, err := d.value("s"); err != nil || s != "abc" {
+		t.Fatalf("s = %v, %v", s, err)
+	}
+	if v, err := d.value("t"); err != nil || v != uint64(9) {
+		t.Fatalf("t = %v, %v", v, err)
+	}
+}
+
+func TestDBusDecodeTruncated(t *testing.T) {
+	var e dbusEncoder
+	e.value("as", []interface{}{"a.service", "b.service"})
+	for n := 0; n < len(e.buf); n++ {
+		d := &dbusDecoder{buf: e.buf[:n], order: binary.LittleEndian}
+		if _, err := d.value("as"); err == nil {
+			t.Fatalf("decoding %d of %d bytes succeeded", n, len(e.buf))
+		}
+	}
+}
+
+func TestDBusEncodeTypeMismatch(t *testing.T) {
+	var e dbusEncoder
+	for sig, value := range map[string]interface{}{
+		"s":    42,
+		"u":    "42",
+		"(su)": []interface{}{"only one"},
+		"v":    "not a variant",
+	} {
+		if err := e.value(sig, value); err == nil {
+			t.Errorf("encoding %#v as %s succeeded", value, sig)
+		}
+	}
+}
+
+func TestDBusSplitSignature(t *testing.T) {
+	types, err := dbusSplitSignature("sa{sv}(ub)as")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"s", "a{sv}", "(ub)", "as"}; !reflect.DeepEqual(types, want) {
+		t.Fatalf("got %q, want %q", types, want)
+	}
+	for _, bad := range []string{"a", "(s", "z"} {
+		if _, err := dbusSplitSignature(bad); err == nil {
+			t.Errorf("%q split without error", bad)
+		}
+	}
+}
+
+func TestDBusSocketPath(t *testing.T) {
+	tests := map[string]string{
+		"unix:path=/run/dbus/system_bus_socket":                "/run/dbus/system_bus_socket",
+		"tcp:host=bus;unix:abstract=/tmp/dbus-x,guid=0123abcd": "@/tmp/dbus-x",
+	}
+	for addr, want := range tests {
+		if got, err := dbusSocketPath(addr); err != nil || got != want {
+			t.Errorf("%s: got %q, %v; want %q", addr, got, err, want)
+		}
+	}
+	if _, err := dbusSocketPath("tcp:host=bus,port=1"); err == nil {
+		t.Error("tcp-only address accepted")
+	}
+}
+
+// dbusReply is what a fakeBus handler answers a call with. Name makes it
+// an error reply.
+type dbusReply struct {
+	sig  string
+	body []interface{}
+	name string
+}
+
+// dbusCall is a method call as the fake bus received it.
+type dbusCall struct {
+	path, iface, member string
+	args                []interface{}
+}
+
+// fakeBus serves handle on a unix socket and returns the bus address.
+// Each reply is preceded by a signal, which the client must skip.
+func fakeBus(t *testing.T, handle func(dbusCall) dbusReply) string {
+	path := filepath.Join(t.TempDir(), "bus")
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go serveFakeBus(conn, handle)
+		}
+	}()
+	return "unix:path=" + path
+}
+
+func serveFakeBus(conn net.Conn, handle func(dbusCall) dbusReply) {
+	defer conn.Close()
+	c := &dbusConn{conn: conn, r: bufio.NewReader(conn)}
+	line, err := c.r.ReadString('\n')
+	if err != nil || !strings.HasPrefix(line, "\x00AUTH EXTERNAL ") {
+		return
+	}
+	conn.Write([]byte("OK 0123456789abcdef\r\n"))
+	if line, err := c.r.ReadString('\n'); err != nil || line != "BEGIN\r\n" {
+		return
+	}
+	// The client numbers its calls from 1.
+	for serial := uint32(1); ; serial++ {
+		_, headers, body, err := c.read()
+		if err != nil {
+			return
+		}
+		call := dbusCall{args: body}
+		call.path, _ = headers[dbusFieldPath].(string)
+		call.iface, _ = headers[dbusFieldInterface].(string)
+		call.member, _ = headers[dbusFieldMember].(string)
+		reply := handle(call)
+
+		conn.Write(dbusMessage(4, 1000+serial, []interface{}{
+			[]interface{}{byte(dbusFieldPath), dbusVariant{"o", "/org/freedesktop/DBus"}},
+			[]interface{}{byte(dbusFieldInterface), dbusVariant{"s", "org.freedesktop.DBus"}},
+			[]interface{}{byte(dbusFieldMember), dbusVariant{"s", "NameAcquired"}},
+		}, "s", []interface{}{":1.1"}))
+
+		typ := byte(dbusMethodReturn)
+		fields := []interface{}{[]interface{}{byte(dbusFieldReplySerial), dbusVariant{"u", serial}}}
+		if reply.name != "" {
+			typ = dbusError
+			fields = append(fields, []interface{}{byte(dbusFieldErrorName), dbusVariant{"s", reply.name}})
+		}
+		if reply.sig != "" {
+			fields = append(fields, []interface{}{byte(dbusFieldSignature), dbusVariant{"g", reply.sig}})
+		}
+		conn.Write(dbusMessage(typ, 2000+serial, fields, reply.sig, reply.body))
+	}
+}
+
+func dbusMessage(typ byte, serial uint32, fields []interface{}, sig string, args []interface{}) []byte {
+	var body dbusEncoder
+	types, _ := dbusSplitSignature(sig)
+	for i, t := range types {
+		body.value(t, args[i])
+	}
+	var msg dbusEncoder
+	msg.buf = append(msg.buf, 'l', typ, 0, 1)
+	msg.uint32(uint32(len(body.buf)))
+	msg.uint32(serial)
+	msg.value("a(yv)", fields)
+	msg.align(8)
+	return append(msg.buf, body.buf...)
+}
+
+func TestDBusCall(t *testing.T) {
+	addr := fakeBus(t, func(call dbusCall) dbusReply {
+		switch call.member {
+		case "Hello":
+			return dbusReply{sig: "s", body: []interface{}{":1.1"}}
+		case "LoadUnit":
+			if call.args[0] == "missing.service" {
+				return dbusReply{name: "org.freedesktop.systemd1.NoSuchUnit", sig: "s", body: []interface{}{"Unit missing.service not found."}}
+			}
+			return dbusReply{sig: "o", body: []interface{}{"/org/freedesktop/systemd1/unit/sshd_2eservice"}}
+		}
+		return dbusReply{name: "org.freedesktop.DBus.Error.UnknownMethod"}
+	})
+	t.Setenv("DBUS_SYSTEM_BUS_ADDRESS", addr)
+
+	bus, err := dialSystemBus(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bus.Close()
+
+	reply, err := bus.call(systemdBusName, systemdBusPath, systemdManager, "LoadUnit", "s", "sshd.service")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []interface{}{"/org/freedesktop/systemd1/unit/sshd_2eservice"}; !reflect.DeepEqual(reply, want) {
+		t.Fatalf("reply = %#v, want %#v", reply, want)
+	}
+
+	_, err = bus.call(systemdBusName, systemdBusPath, systemdManager, "LoadUnit", "s", "missing.service")
+	var callErr *dbusCallError
+	if !errors.As(err, &callErr) || callErr.Name != "org.freedesktop.systemd1.NoSuchUnit" || callErr.Message != "Unit missing.service not found." {
+		t.Fatalf("err = %#v, want NoSuchUnit", err)
+	}
+
+	if _, err := bus.call(systemdBusName, systemdBusPath, systemdManager, "LoadUnit", "ss", "sshd.service"); err == nil {
+		t.Fatal("call with a missing arg succeeded")
+	}
+}
+
+func TestDBusAuthRejected(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bus")
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		bufio.NewReader(conn).ReadString('\n')
+		conn.Write([]byte("REJECTED EXTERNAL\r\n"))
+	}()
+	t.Setenv("DBUS_SYSTEM_BUS_ADDRESS", "unix:path="+path)
+	if _, err := dialSystemBus(t.Context()); err == nil || !strings.Contains(err.Error(), "REJECTED") {
+		t.Fatalf("err = %v, want the rejection", err)
+	}
+}
@@ -0,0 +1,29 @@
+package cutter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// StepContext gives the next step an even share of whatever is left of the
+// parent deadline, so one slow step cannot starve the ones after it.
+func StepContext(ctx context.Context, stepsLeft int) (context.Context, context.CancelFunc) {
+	deadline, ok := ctx.Deadline()
+	if !ok || stepsLeft <= 1 {
+		return context.WithCancel(ctx)
+	}
+	share := time.Until(deadline) / time.Duration(stepsLeft)
+	return context.WithTimeout(ctx, share)
+}
+
+func stepError(ctx context.Context, step string, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		if errors.Is(ctxErr, context.DeadlineExceeded) {
+			return fmt.Errorf("deadline exceeded during step %s: %w", step, ctxErr)
+		}
+		return fmt.Errorf("cancelled during step %s: %w", step, ctxErr)
+	}
+	return err
+}
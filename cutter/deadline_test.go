@@ -0,0 +1,252 @@
+package cutter
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/pem"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/goleak"
+	"golang.org/x/crypto/ssh"
+)
+
+// closeSSHPool drops every pooled client, as shutting down would.
+func closeSSHPool(p *sshPool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for key, hp := range p.hosts {
+		for _, pc := range append([]*pooledSSH(nil), hp.clients...) {
+			p.drop(key, pc)
+		}
+	}
+}
+
+// fakeSSHServer accepts any key and hands each exec to its exec func.
+type fakeSSHServer struct {
+	net.Listener
+	// exec runs command on ch. It closes ch when done, or leaves it open
+	// to hang.
+	exec func(command string, ch ssh.Channel)
+
+	mu      sync.Mutex
+	conns   []net.Conn
+	signals []string
+}
+
+func newFakeSSHServer(t *testing.T, exec func(command string, ch ssh.Channel)) *fakeSSHServer {
+	_, hostKey, _ := ed25519.GenerateKey(rand.Reader)
+	signer, err := ssh.NewSignerFromKey(hostKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := &ssh.ServerConfig{
+		PublicKeyCallback: func(ssh.ConnMetadata, ssh.PublicKey) (*ssh.Permissions, error) { return nil, nil },
+	}
+	config.AddHostKey(signer)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeSSHServer{Listener: l, exec: exec}
+	t.Cleanup(s.close)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.conns = append(s.conns, conn)
+			s.mu.Unlock()
+			go s.serve(conn, config)
+		}
+	}()
+	return s
+}
+
+func (s *fakeSSHServer) close() {
+	s.Listener.Close()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, conn := range s.conns {
+		conn.Close()
+	}
+}
+
+func (s *fakeSSHServer) serve(conn net.Conn, config *ssh.ServerConfig) {
+	_, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+	for newCh := range chans {
+		ch, requests, err := newCh.Accept()
+		if err != nil {
+			continue
+		}
+		go func() {
+			for req := range requests {
+				switch req.Type {
+				case "exec":
+					command := string(req.Payload[4:])
+					req.Reply(true, nil)
+					go s.exec(command, ch)
+				case "signal":
+					s.mu.Lock()
+					s.signals = append(s.signals, string(req.Payload[4:]))
+					s.mu.Unlock()
+				default:
+					req.Reply(false, nil)
+				}
+			}
+			ch.Close()
+		}()
+	}
+}
+
+func (s *fakeSSHServer) Signals() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.signals...)
+}
+
+// params points the network cutter at the server with a fresh client key.
+func (s *fakeSSHServer) params(t *testing.T, command string) map[string]string {
+	_, key, _ := ed25519.GenerateKey(rand.Reader)
+	block, err := ssh.MarshalPrivateKey(key, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyFile := filepath.Join(t.TempDir(), "id_ed25519")
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(block), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("SSH_AUTH_SOCK", "")
+	host, port, _ := net.SplitHostPort(s.Addr().String())
+	return map[string]string{
+		"action":            "ssh_exec",
+		"command":           command,
+		"host":              host,
+		"port":              port,
+		"ssh_key_file":      keyFile,
+		"host_key_checking": HostKeyInsecure,
+	}
+}
+
+// exitWith writes output and ends the command with status.
+func exitWith(ch ssh.Channel, output string, status uint32) {
+	ch.Write([]byte(output))
+	ch.SendRequest("exit-status", false, binary.BigEndian.AppendUint32(nil, status))
+	ch.Close()
+}
+
+func TestSSHCommandKilledAtDeadline(t *testing.T) {
+	// Registered first, so it runs after the servers' cleanups.
+	running := goleak.IgnoreCurrent()
+	t.Cleanup(func() { goleak.VerifyNone(t, running) })
+	server := newFakeSSHServer(t, func(command string, ch ssh.Channel) {
+		if command == "true" {
+			exitWith(ch, "", 0)
+		}
+		// Anything else hangs until the client gives up.
+	})
+	n := NewNetworkCutter()
+	defer closeSSHPool(n.pool)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := n.Execute(ctx, "web-01", server.params(t, "sleep 3600"))
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("returned after %s, long past the deadline", elapsed)
+	}
+	if err == nil || !strings.Contains(err.Error(), "deadline exceeded during step command") {
+		t.Fatalf("err = %v, want the command step's deadline", err)
+	}
+	if signals := server.Signals(); len(signals) != 1 || signals[0] != "KILL" {
+		t.Fatalf("signals = %v, want KILL", signals)
+	}
+
+	// The connection survives the killed command and is reused.
+	if err := n.Execute(context.Background(), "web-01", server.params(t, "true")); err != nil {
+		t.Fatalf("command after the killed one: %v", err)
+	}
+}
+
+func TestDockerContainerDeadline(t *testing.T) {
+	// Registered first, so it runs after the servers' cleanups.
+	running := goleak.IgnoreCurrent()
+	t.Cleanup(func() { goleak.VerifyNone(t, running) })
+	daemon := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Api-Version", "1.43")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/_ping"):
+			fmt.Fprint(w, "OK")
+		case strings.HasSuffix(r.URL.Path, "/containers/json"):
+			fmt.Fprint(w, `[{"Id": "0123456789abcdef0123", "Names": ["/api"], "State": "running", "Labels": {"atropos.node": "web-01"}}]`)
+		default:
+			// Stopping hangs.
+			<-r.Context().Done()
+		}
+	}))
+	defer daemon.Close()
+	d := NewDockerCutter()
+	defer func() {
+		for _, cli := range d.clients {
+			cli.Close()
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := d.Execute(ctx, "web-01", map[string]string{"action": "docker_stop_all", "docker_host": "tcp://" + daemon.Listener.Addr().String()})
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("returned after %s, long past the deadline", elapsed)
+	}
+	want := "docker_stop_all failed for 1 of 1 containers: api: deadline exceeded during step docker_stop_all: context deadline exceeded"
+	if err == nil || err.Error() != want {
+		t.Fatalf("err = %v\nwant %s", err, want)
+	}
+}
+
+func TestStepContext(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 900*time.Millisecond)
+	defer cancel()
+	step, stepCancel := StepContext(ctx, 3)
+	defer stepCancel()
+	deadline, _ := step.Deadline()
+	if share := time.Until(deadline); share > 310*time.Millisecond || share < 250*time.Millisecond {
+		t.Fatalf("step gets %s of 900ms over 3 steps", share)
+	}
+
+	last, lastCancel := StepContext(ctx, 1)
+	defer lastCancel()
+	if d, _ := last.Deadline(); !d.Equal(func() time.Time { d, _ := ctx.Deadline(); return d }()) {
+		t.Fatal("the last step does not get the rest of the deadline")
+	}
+}
+
+func TestStepError(t *testing.T) {
+	cause := fmt.Errorf("boom")
+	if err := stepError(context.Background(), "list", cause); err != cause {
+		t.Fatalf("err = %v, want the cause as is", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := stepError(ctx, "list", cause); err.Error() != "cancelled during step list: context canceled" {
+		t.Fatalf("err = %v", err)
+	}
+}
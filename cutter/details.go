@@ -0,0 +1,60 @@
+package cutter
+
+import (
+	"context"
+	"sync"
+)
+
+type detailsKey struct{}
+
+type Details struct {
+	values map[string]interface{}
+	output string
+	mu     sync.Mutex
+}
+
+func WithDetails(ctx context.Context) (context.Context, *Details) {
+	d := &Details{values: make(map[string]interface{})}
+	return context.WithValue(ctx, detailsKey{}, d), d
+}
+
+func RecordDetail(ctx context.Context, key string, value interface{}) {
+	d, ok := ctx.Value(detailsKey{}).(*Details)
+	if !ok {
+		return
+	}
+	d.mu.Lock()
+	d.values[key] = value
+	d.mu.Unlock()
+}
+
+// RecordOutput keeps a command's output for the cut's record.
+func RecordOutput(ctx context.Context, output string) {
+	d, ok := ctx.Value(detailsKey{}).(*Details)
+	if !ok {
+		return
+	}
+	d.mu.Lock()
+	d.output = output
+	d.mu.Unlock()
+}
+
+func (d *Details) Output() string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.output
+}
+
+func (d *Details) Map() map[string]interface{} {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if len(d.values) == 0 {
+		return nil
+	}
+	out := make(map[string]interface{}, len(d.values))
+	for k, v := range d.values {
+		out[k] = v
+	}
+	return out
+}
@@ -0,0 +1,174 @@
+package cutter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"atropos/internal/logger"
+)
+
+const (
+	DNSProviderRFC2136    = "rfc2136"
+	DNSProviderCloudflare = "cloudflare"
+
+	defaultDNSRestoreTTL = 60
+)
+
+// dnsRecord is one resource record as removed and restored. It is stored
+// in the cut's details under dns_removed, which dns_restore_record reads
+// back as its dns_records param.
+type dnsRecord struct {
+	Name    string `json:"name"`
+	Type    string `json:"type"`
+	Content string `json:"content"`
+	TTL     int    `json:"ttl"`
+	// Proxied is Cloudflare's orange cloud.
+	Proxied *bool `json:"proxied,omitempty"`
+}
+
+// dnsProvider is a DNS service records can be removed from and added to.
+type dnsProvider interface {
+	records(ctx context.Context, name, rtype string) ([]dnsRecord, error)
+	remove(ctx context.Context, records []dnsRecord) error
+	add(ctx context.Context, records []dnsRecord) error
+}
+
+// DNSCutter takes a node out of rotation by deleting its DNS records, and
+// puts back exactly what it deleted.
+type DNSCutter struct{}
+
+func NewDNSCutter() *DNSCutter {
+	return &DNSCutter{}
+}
+
+func (d *DNSCutter) Name() string {
+	return "dns"
+}
+
+func (d *DNSCutter) CanHandle(action string) bool {
+	return action == "dns_remove_record" || action == "dns_restore_record"
+}
+
+func (d *DNSCutter) SupportedActions() []string {
+	return []string{"dns_remove_record", "dns_restore_record"}
+}
+
+func (d *DNSCutter) Execute(ctx context.Context, target string, params map[string]string) error {
+	action := params["action"]
+	if !d.CanHandle(action) {
+		return fmt.Errorf("unsupported action: %s", action)
+	}
+	zone := strings.TrimSuffix(params["dns_zone"], ".")
+	if zone == "" {
+		return fmt.Errorf("dns cutter requires dns_zone")
+	}
+	name := params["dns_record_name"]
+	if name == "" {
+		name = target
+	}
+	name = dnsFQDN(name, zone)
+	rtype := strings.ToUpper(params["dns_record_type"])
+	if rtype == "" {
+		rtype = "A"
+	}
+	if rtype != "A" && rtype != "AAAA" {
+		return fmt.Errorf("dns_record_type must be A or AAAA")
+	}
+
+	provider, err := newDNSProvider(zone, params)
+	if err != nil {
+		return err
+	}
+
+	logger.Get().Info("dns_cut",
+		zap.String("target", target),
+		zap.String("action", action),
+		zap.String("provider", params["dns_provider"]),
+		zap.String("record", name),
+		zap.String("type", rtype),
+	)
+
+	existing, err := provider.records(ctx, name, rtype)
+	if err != nil {
+		return stepError(ctx, "lookup", fmt.Errorf("look up %s %s: %w", name, rtype, err))
+	}
+
+	if action == "dns_remove_record" {
+		var remove []dnsRecord
+		for _, r := range existing {
+			if content := params["dns_record_content"]; content == "" || r.Content == content {
+				remove = append(remove, r)
+			}
+		}
+		if len(remove) == 0 {
+			return fmt.Errorf("no %s record for %s to remove", rtype, name)
+		}
+		if err := provider.remove(ctx, remove); err != nil {
+			return stepError(ctx, "remove", fmt.Errorf("remove %s: %w", name, err))
+		}
+		RecordDetail(ctx, "dns_removed", remove)
+		return nil
+	}
+
+	var saved []dnsRecord
+	if err := json.Unmarshal([]byte(params["dns_records"]), &saved); err != nil || len(saved) == 0 {
+		return fmt.Errorf("dns_restore_record found no records removed from %s; set dns_records to restore by hand", name)
+	}
+	ttl := defaultDNSRestoreTTL
+	if s := params["dns_restore_ttl"]; s != "" {
+		if ttl, err = strconv.Atoi(s); err != nil || ttl <= 0 {
+			return fmt.Errorf("dns_restore_ttl must be a whole number > 0")
+		}
+	}
+	var restore []dnsRecord
+	for _, r := range saved {
+		if dnsHasRecord(existing, r) || !strings.EqualFold(r.Name, name) || r.Type != rtype {
+			continue
+		}
+		if r.TTL == 0 || r.TTL > ttl {
+			r.TTL = ttl
+		}
+		restore = append(restore, r)
+	}
+	if len(restore) > 0 {
+		if err := provider.add(ctx, restore); err != nil {
+			return stepError(ctx, "restore", fmt.Errorf("restore %s: %w", name, err))
+		}
+	}
+	RecordDetail(ctx, "dns_restored", restore)
+	return nil
+}
+
+func newDNSProvider(zone string, params map[string]string) (dnsProvider, error) {
+	switch params["dns_provider"] {
+	case DNSProviderRFC2136:
+		return newRFC2136Provider(zone, params)
+	case DNSProviderCloudflare:
+		return newCloudflareProvider(zone, params)
+	default:
+		return nil, fmt.Errorf("dns_provider must be %q or %q", DNSProviderRFC2136, DNSProviderCloudflare)
+	}
+}
+
+// dnsFQDN places a relative name in zone, e.g. web-01 in example.com.
+func dnsFQDN(name, zone string) string {
+	name = strings.TrimSuffix(name, ".")
+	if strings.EqualFold(name, zone) || strings.HasSuffix(strings.ToLower(name), "."+strings.ToLower(zone)) {
+		return name
+	}
+	return name + "." + zone
+}
+
+func dnsHasRecord(records []dnsRecord, r dnsRecord) bool {
+	for _, e := range records {
+		if strings.EqualFold(e.Name, r.Name) && e.Type == r.Type && e.Content == r.Content {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,177 @@
+package cutter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const cloudflareAPI = "https://api.cloudflare.com/client/v4"
+
+// cloudflareProvider edits records through Cloudflare's v4 API with an API
+// token allowed to edit the zone's DNS.
+type cloudflareProvider struct {
+	api    string
+	token  string
+	zone   string
+	zoneID string
+	http   *http.Client
+}
+
+func newCloudflareProvider(zone string, params map[string]string) (*cloudflareProvider, error) {
+	p := &cloudflareProvider{
+		api:    strings.TrimSuffix(params["cloudflare_api_url"], "/"),
+		token:  paramOrEnv(params, "cloudflare_api_token", "CLOUDFLARE_API_TOKEN"),
+		zone:   zone,
+		zoneID: params["cloudflare_zone_id"],
+	}
+	if p.api == "" {
+		p.api = cloudflareAPI
+	}
+	if p.token == "" {
+		return nil, fmt.Errorf("cloudflare requires cloudflare_api_token or CLOUDFLARE_API_TOKEN")
+	}
+	client, err := outboundClient(params, false)
+	if err != nil {
+		return nil, err
+	}
+	p.http = client
+	return p, nil
+}
+
+type cloudflareRecord struct {
+	ID      string `json:"id,omitempty"`
+	Type    string `json:"type"`
+	Name    string `json:"name"`
+	Content string `json:"content"`
+	TTL     int    `json:"ttl"`
+	Proxied *bool  `json:"proxied,omitempty"`
+}
+
+func (p *cloudflareProvider) records(ctx context.Context, name, rtype string) ([]dnsRecord, error) {
+	found, err := p.list(ctx, name, rtype)
+	if err != nil {
+		return nil, err
+	}
+	records := make([]dnsRecord, len(found))
+	for i, r := range found {
+		records[i] = dnsRecord{Name: r.Name, Type: r.Type, Content: r.Content, TTL: r.TTL, Proxied: r.Proxied}
+	}
+	return records, nil
+}
+
+func (p *cloudflareProvider) remove(ctx context.Context, records []dnsRecord) error {
+	for _, r := range records {
+		found, err := p.list(ctx, r.Name, r.Type)
+		if err != nil {
+			return err
+		}
+		for _, f := range found {
+			if f.Content != r.Content {
+				continue
+			}
+			if err := p.call(ctx, http.MethodDelete, "/zones/"+p.zoneID+"/dns_records/"+f.ID, nil, nil); err != nil {
+				return fmt.Errorf("delete %s %s: %w", r.Type, r.Content, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (p *cloudflareProvider) add(ctx context.Context, records []dnsRecord) error {
+	for _, r := range records {
+		body := cloudflareRecord{Type: r.Type, Name: r.Name, Content: r.Content, TTL: r.TTL, Proxied: r.Proxied}
+		if err := p.call(ctx, http.MethodPost, "/zones/"+p.zoneID+"/dns_records", body, nil); err != nil {
+			return fmt.Errorf("create %s %s: %w", r.Type, r.Content, err)
+		}
+	}
+	return nil
+}
+
+func (p *cloudflareProvider) list(ctx context.Context, name, rtype string) ([]cloudflareRecord, error) {
+	if err := p.resolveZone(ctx); err != nil {
+		return nil, err
+	}
+	var found []cloudflareRecord
+	q := url.Values{"name": {name}, "type": {rtype}, "per_page": {"100"}}
+	if err := p.call(ctx, http.MethodGet, "/zones/"+p.zoneID+"/dns_records?"+q.Encode(), nil, &found); err != nil {
+		return nil, err
+	}
+	return found, nil
+}
+
+// resolveZone looks up the zone's ID by name, unless cloudflare_zone_id
+// gave it.
+func (p *cloudflareProvider) resolveZone(ctx context.Context) error {
+	if p.zoneID != "" {
+		return nil
+	}
+	var zones []struct {
+		ID string `json:"id"`
+	}
+	if err := p.call(ctx, http.MethodGet, "/zones?"+url.Values{"name": {p.zone}}.Encode(), nil, &zones); err != nil {
+		return fmt.Errorf("zone %s: %w", p.zone, err)
+	}
+	if len(zones) != 1 {
+		return fmt.Errorf("zone %s not found for this token", p.zone)
+	}
+	p.zoneID = zones[0].ID
+	return nil
+}
+
+// call sends body as JSON and decodes the envelope's result into out,
+// returning Cloudflare's own errors when it reports any.
+func (p *cloudflareProvider) call(ctx context.Context, method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, p.api+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+p.token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := p.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var env struct {
+		Success bool            `json:"success"`
+		Result  json.RawMessage `json:"result"`
+		Errors  []struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 4<<20)).Decode(&env); err != nil {
+		return fmt.Errorf("HTTP %d: unreadable response: %w", resp.StatusCode, err)
+	}
+	if !env.Success {
+		msgs := make([]string, len(env.Errors))
+		for i, e := range env.Errors {
+			msgs[i] = fmt.Sprintf("%d %s", e.Code, e.Message)
+		}
+		if len(msgs) == 0 {
+			msgs = append(msgs, fmt.Sprintf("HTTP %d", resp.StatusCode))
+		}
+		return fmt.Errorf("cloudflare: %s", strings.Join(msgs, "; "))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(env.Result, out)
+}
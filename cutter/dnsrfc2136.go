@@ -0,0 +1,353 @@
+package cutter
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+// rfc2136Provider sends DNS UPDATE messages (RFC 2136), signed with a TSIG
+// key (RFC 8945), to the zone's primary server over TCP.
+
+const (
+	dnsTypeA    = 1
+	dnsTypeSOA  = 6
+	dnsTypeAAAA = 28
+	dnsTypeTSIG = 250
+
+	dnsClassIN   = 1
+	dnsClassNone = 254
+	dnsClassAny  = 255
+
+	dnsOpcodeUpdate = 5
+	tsigFudge       = 300
+)
+
+var dnsRcodes = map[int]string{
+	1: "FORMERR", 2: "SERVFAIL", 3: "NXDOMAIN", 4: "NOTIMP", 5: "REFUSED",
+	6: "YXDOMAIN", 7: "YXRRSET", 8: "NXRRSET", 9: "NOTAUTH", 10: "NOTZONE",
+}
+
+var tsigAlgorithms = map[string]func() hash.Hash{
+	"hmac-sha1":   sha1.New,
+	"hmac-sha256": sha256.New,
+	"hmac-sha512": sha512.New,
+}
+
+type rfc2136Provider struct {
+	server    string
+	zone      string
+	keyName   string
+	secret    []byte
+	algorithm string
+}
+
+func newRFC2136Provider(zone string, params map[string]string) (*rfc2136Provider, error) {
+	server := params["dns_server"]
+	if server == "" {
+		return nil, fmt.Errorf("rfc2136 requires dns_server")
+	}
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, "53")
+	}
+	p := &rfc2136Provider{
+		server:    server,
+		zone:      zone,
+		keyName:   paramOrEnv(params, "dns_tsig_key_name", "DNS_TSIG_KEY_NAME"),
+		algorithm: strings.ToLower(strings.TrimSuffix(params["dns_tsig_algorithm"], ".")),
+	}
+	if p.algorithm == "" {
+		p.algorithm = "hmac-sha256"
+	}
+	if _, ok := tsigAlgorithms[p.algorithm]; !ok {
+		return nil, fmt.Errorf("dns_tsig_algorithm must be hmac-sha1, hmac-sha256 or hmac-sha512")
+	}
+	if p.keyName == "" {
+		return nil, fmt.Errorf("rfc2136 requires dns_tsig_key_name or DNS_TSIG_KEY_NAME")
+	}
+	secret, err := base64.StdEncoding.DecodeString(paramOrEnv(params, "dns_tsig_secret", "DNS_TSIG_SECRET"))
+	if err != nil || len(secret) == 0 {
+		return nil, fmt.Errorf("rfc2136 requires a base64 dns_tsig_secret or DNS_TSIG_SECRET")
+	}
+	p.secret = secret
+	return p, nil
+}
+
+func (p *rfc2136Provider) records(ctx context.Context, name, rtype string) ([]dnsRecord, error) {
+	qtype := dnsTypeCode(rtype)
+	var msg dnsMessage
+	msg.header(0, 1, 0, 0)
+	msg.name(name)
+	msg.uint16(qtype)
+	msg.uint16(dnsClassIN)
+	resp, err := p.exchange(ctx, msg.bytes())
+	if err != nil {
+		return nil, err
+	}
+	rcode := int(resp[3] & 0x0f)
+	if rcode == 3 {
+		return nil, nil
+	}
+	if rcode != 0 {
+		return nil, dnsRcodeError(rcode)
+	}
+
+	qd := int(binary.BigEndian.Uint16(resp[4:]))
+	an := int(binary.BigEndian.Uint16(resp[6:]))
+	off := 12
+	for i := 0; i < qd; i++ {
+		if _, off, err = readDNSName(resp, off); err != nil {
+			return nil, err
+		}
+		off += 4
+	}
+	var records []dnsRecord
+	for i := 0; i < an; i++ {
+		var rrName string
+		if rrName, off, err = readDNSName(resp, off); err != nil {
+			return nil, err
+		}
+		if off+10 > len(resp) {
+			return nil, errors.New("truncated answer")
+		}
+		typ := int(binary.BigEndian.Uint16(resp[off:]))
+		ttl := int(binary.BigEndian.Uint32(resp[off+4:]))
+		rdlen := int(binary.BigEndian.Uint16(resp[off+8:]))
+		off += 10
+		if off+rdlen > len(resp) {
+			return nil, errors.New("truncated answer")
+		}
+		if typ == qtype && strings.EqualFold(rrName, name) {
+			records = append(records, dnsRecord{Name: name, Type: rtype, Content: net.IP(resp[off : off+rdlen]).String(), TTL: ttl})
+		}
+		off += rdlen
+	}
+	return records, nil
+}
+
+// remove deletes each record by its content, leaving the rest of the set.
+func (p *rfc2136Provider) remove(ctx context.Context, records []dnsRecord) error {
+	return p.update(ctx, records, dnsClassNone)
+}
+
+func (p *rfc2136Provider) add(ctx context.Context, records []dnsRecord) error {
+	return p.update(ctx, records, dnsClassIN)
+}
+
+func (p *rfc2136Provider) update(ctx context.Context, records []dnsRecord, class int) error {
+	var msg dnsMessage
+	msg.header(dnsOpcodeUpdate<<11, 1, 0, len(records))
+	msg.name(p.zone)
+	msg.uint16(dnsTypeSOA)
+	msg.uint16(dnsClassIN)
+	for _, r := range records {
+		ip := net.ParseIP(r.Content)
+		rdata := ip.To4()
+		if r.Type == "AAAA" {
+			rdata = ip.To16()
+		}
+		if ip == nil || rdata == nil {
+			return fmt.Errorf("%s record content %q is not an address", r.Type, r.Content)
+		}
+		ttl := r.TTL
+		if class == dnsClassNone {
+			ttl = 0
+		}
+		msg.name(r.Name)
+		msg.uint16(dnsTypeCode(r.Type))
+		msg.uint16(class)
+		msg.uint32(uint32(ttl))
+		msg.uint16(len(rdata))
+		msg.buf = append(msg.buf, rdata...)
+	}
+	resp, err := p.exchange(ctx, msg.bytes())
+	if err != nil {
+		return err
+	}
+	if rcode := int(resp[3] & 0x0f); rcode != 0 {
+		return dnsRcodeError(rcode)
+	}
+	return nil
+}
+
+// exchange signs msg and sends it over TCP, returning the response.
+func (p *rfc2136Provider) exchange(ctx context.Context, msg []byte) ([]byte, error) {
+	signed, err := p.sign(msg, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", p.server)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	out := make([]byte, 2, 2+len(signed))
+	binary.BigEndian.PutUint16(out, uint16(len(signed)))
+	if _, err := conn.Write(append(out, signed...)); err != nil {
+		return nil, err
+	}
+	var size [2]byte
+	if _, err := io.ReadFull(conn, size[:]); err != nil {
+		return nil, err
+	}
+	resp := make([]byte, binary.BigEndian.Uint16(size[:]))
+	if _, err := io.ReadFull(conn, resp); err != nil {
+		return nil, err
+	}
+	if len(resp) < 12 || resp[0] != signed[0] || resp[1] != signed[1] {
+		return nil, errors.New("response does not match the request")
+	}
+	return resp, nil
+}
+
+// sign appends a TSIG record to msg.
+func (p *rfc2136Provider) sign(msg []byte, now time.Time) ([]byte, error) {
+	var vars dnsMessage
+	vars.name(strings.ToLower(p.keyName))
+	vars.uint16(dnsClassAny)
+	vars.uint32(0)
+	vars.name(p.algorithm)
+	signedAt := uint64(now.Unix())
+	vars.uint16(int(signedAt >> 32))
+	vars.uint32(uint32(signedAt))
+	vars.uint16(tsigFudge)
+	vars.uint16(0) // error
+	vars.uint16(0) // other len
+
+	mac := hmac.New(tsigAlgorithms[p.algorithm], p.secret)
+	mac.Write(msg)
+	mac.Write(vars.buf)
+	sum := mac.Sum(nil)
+
+	var rdata dnsMessage
+	rdata.name(p.algorithm)
+	rdata.uint16(int(signedAt >> 32))
+	rdata.uint32(uint32(signedAt))
+	rdata.uint16(tsigFudge)
+	rdata.uint16(len(sum))
+	rdata.buf = append(rdata.buf, sum...)
+	rdata.buf = append(rdata.buf, msg[0], msg[1]) // original ID
+	rdata.uint16(0)                               // error
+	rdata.uint16(0)                               // other len
+
+	var rr dnsMessage
+	rr.buf = append(rr.buf, msg...)
+	rr.name(strings.ToLower(p.keyName))
+	rr.uint16(dnsTypeTSIG)
+	rr.uint16(dnsClassAny)
+	rr.uint32(0)
+	rr.uint16(len(rdata.buf))
+	rr.buf = append(rr.buf, rdata.buf...)
+	// One more additional record.
+	binary.BigEndian.PutUint16(rr.buf[10:], binary.BigEndian.Uint16(msg[10:])+1)
+	return rr.buf, nil
+}
+
+// dnsMessage builds DNS wire format.
+type dnsMessage struct {
+	buf []byte
+}
+
+// header starts a message with a random ID and the given flags and
+// counts for the question (zone), answer (prerequisite) and authority
+// (update) sections.
+func (m *dnsMessage) header(flags, qd, an, ns int) {
+	var id [2]byte
+	rand.Read(id[:])
+	m.buf = append(m.buf, id[0], id[1])
+	m.uint16(flags)
+	m.uint16(qd)
+	m.uint16(an)
+	m.uint16(ns)
+	m.uint16(0)
+}
+
+func (m *dnsMessage) name(name string) {
+	for _, label := range strings.Split(strings.TrimSuffix(name, "."), ".") {
+		if label == "" {
+			continue
+		}
+		m.buf = append(m.buf, byte(len(label)))
+		m.buf = append(m.buf, label...)
+	}
+	m.buf = append(m.buf, 0)
+}
+
+func (m *dnsMessage) uint16(v int) {
+	m.buf = binary.BigEndian.AppendUint16(m.buf, uint16(v))
+}
+
+func (m *dnsMessage) uint32(v uint32) {
+	m.buf = binary.BigEndian.AppendUint32(m.buf, v)
+}
+
+func (m *dnsMessage) bytes() []byte {
+	return m.buf
+}
+
+// readDNSName reads the possibly compressed name at off and returns it
+// with the offset just past it.
+func readDNSName(msg []byte, off int) (string, int, error) {
+	var labels []string
+	end := -1
+	for jumps := 0; jumps < 64; {
+		if off >= len(msg) {
+			return "", 0, errors.New("truncated name")
+		}
+		n := int(msg[off])
+		switch {
+		case n == 0:
+			if end < 0 {
+				end = off + 1
+			}
+			return strings.Join(labels, "."), end, nil
+		case n&0xc0 == 0xc0:
+			if off+1 >= len(msg) {
+				return "", 0, errors.New("truncated name")
+			}
+			if end < 0 {
+				end = off + 2
+			}
+			off = int(binary.BigEndian.Uint16(msg[off:]) & 0x3fff)
+			jumps++
+		default:
+			if off+1+n > len(msg) {
+				return "", 0, errors.New("truncated name")
+			}
+			labels = append(labels, string(msg[off+1:off+1+n]))
+			off += 1 + n
+		}
+	}
+	return "", 0, errors.New("name compression loop")
+}
+
+func dnsTypeCode(rtype string) int {
+	if rtype == "AAAA" {
+		return dnsTypeAAAA
+	}
+	return dnsTypeA
+}
+
+func dnsRcodeError(rcode int) error {
+	if name, ok := dnsRcodes[rcode]; ok {
+		return fmt.Errorf("server answered %s", name)
+	}
+	return fmt.Errorf("server answered rcode %d", rcode)
+}
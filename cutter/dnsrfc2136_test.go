@@ -0,0 +1,250 @@
+package cutter
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testTSIGSecret = "YXRyb3Bvcy10ZXN0LXNlY3JldC0wMTIzNDU2Nzg5YWI="
+
+func testRFC2136Provider(t *testing.T, server string) *rfc2136Provider {
+	p, err := newRFC2136Provider("example.com", map[string]string{
+		"dns_server":         server,
+		"dns_tsig_key_name":  "Atropos-Key.",
+		"dns_tsig_secret":    testTSIGSecret,
+		"dns_tsig_algorithm": "HMAC-SHA256.",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+// removeWeb01 is the update removing web-01.example.com A 192.0.2.10,
+// with ID 0x1234.
+const (
+	removeWeb01Body = "076578616d706c6503636f6d00 0006 0001" +
+		"067765622d3031076578616d706c6503636f6d00 0001 00fe 00000000 0004 c000020a"
+	removeWeb01 = "1234 2800 0001 0000 0001 0000" + removeWeb01Body
+)
+
+func TestTSIGSign(t *testing.T) {
+	p := testRFC2136Provider(t, "127.0.0.1")
+	msg := unhex(t, removeWeb01)
+	signed, err := p.sign(msg, time.Unix(1700000000, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The MAC was computed separately from RFC 8945 section 4.3.3.
+	want := unhex(t, "1234 2800 0001 0000 0001 0001"+removeWeb01Body+
+		"0b617472 6f706f73 2d6b6579 00"+ // atropos-key.
+		"00fa 00ff 00000000 003d"+
+		"0b686d61632d736861323536 00"+ // hmac-sha256.
+		"0000 6553f100 012c 0020"+
+		"f98102dd433b1eca9c3dce35d66aea1b301a4957b2a66dfc71579d9290ec3e9e"+
+		"1234 0000 0000")
+	if !bytes.Equal(signed, want) {
+		t.Fatalf("signed =\n%x\nwant\n%x", signed, want)
+	}
+}
+
+// verifyTSIG strips the TSIG record from msg and checks its MAC the way a
+// server would, returning the message as it was before signing.
+func verifyTSIG(t *testing.T, msg []byte, secret []byte) []byte {
+	t.Helper()
+	// The TSIG record is the last one; walk the sections to find it.
+	off := 12
+	counts := []int{}
+	for i := 0; i < 4; i++ {
+		counts = append(counts, int(binary.BigEndian.Uint16(msg[4+2*i:])))
+	}
+	var err error
+	for i := 0; i < counts[0]; i++ {
+		if _, off, err = readDNSName(msg, off); err != nil {
+			t.Fatal(err)
+		}
+		off += 4
+	}
+	for i := 0; i < counts[1]+counts[2]+counts[3]-1; i++ {
+		if _, off, err = readDNSName(msg, off); err != nil {
+			t.Fatal(err)
+		}
+		off += 10 + int(binary.BigEndian.Uint16(msg[off+8:]))
+	}
+	start := off
+	keyName, off, err := readDNSName(msg, off)
+	if err != nil || binary.BigEndian.Uint16(msg[off:]) != dnsTypeTSIG {
+		t.Fatalf("last record is not TSIG: %v", err)
+	}
+	rdata := msg[off+10:]
+	algorithm, n, err := readDNSName(rdata, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	timeAndFudge := rdata[n : n+8]
+	macLen := int(binary.BigEndian.Uint16(rdata[n+8:]))
+	mac := rdata[n+10 : n+10+macLen]
+
+	unsigned := append([]byte(nil), msg[:start]...)
+	binary.BigEndian.PutUint16(unsigned[10:], uint16(counts[3]-1))
+	copy(unsigned, rdata[n+10+macLen:n+12+macLen]) // original ID
+
+	var vars dnsMessage
+	vars.name(keyName)
+	vars.uint16(dnsClassAny)
+	vars.uint32(0)
+	vars.name(algorithm)
+	vars.buf = append(vars.buf, timeAndFudge...)
+	vars.uint16(0)
+	vars.uint16(0)
+	h := hmac.New(tsigAlgorithms[algorithm], secret)
+	h.Write(unsigned)
+	h.Write(vars.buf)
+	if !hmac.Equal(h.Sum(nil), mac) {
+		t.Fatalf("TSIG MAC %x does not verify", mac)
+	}
+	return unsigned
+}
+
+// fakeDNSServer answers each TCP query with what answer returns. The
+// exchange finishes before the client returns, so answer may keep the
+// query for the test to check.
+func fakeDNSServer(t *testing.T, answer func(query []byte) []byte) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			var size [2]byte
+			if _, err := io.ReadFull(conn, size[:]); err == nil {
+				query := make([]byte, binary.BigEndian.Uint16(size[:]))
+				if _, err := io.ReadFull(conn, query); err == nil {
+					resp := answer(query)
+					conn.Write(append(binary.BigEndian.AppendUint16(nil, uint16(len(resp))), resp...))
+				}
+			}
+			conn.Close()
+		}
+	}()
+	return l.Addr().String()
+}
+
+// dnsResponse copies the query's header with the QR bit and rcode set and
+// the counts given.
+func dnsResponse(query []byte, rcode int, counts ...int) []byte {
+	resp := append([]byte(nil), query[:12]...)
+	resp[2] |= 0x80
+	resp[3] = resp[3]&0xf0 | byte(rcode)
+	for i := range 4 {
+		binary.BigEndian.PutUint16(resp[4+2*i:], uint16(counts[i]))
+	}
+	return resp
+}
+
+func TestRFC2136Remove(t *testing.T) {
+	secret, _ := base64.StdEncoding.DecodeString(testTSIGSecret)
+	var got []byte
+	addr := fakeDNSServer(t, func(query []byte) []byte {
+		got = query
+		return dnsResponse(query, 0, 1, 0, 0, 0)
+	})
+	p := testRFC2136Provider(t, addr)
+	err := p.remove(context.Background(), []dnsRecord{{Name: "web-01.example.com", Type: "A", Content: "192.0.2.10", TTL: 300}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got = verifyTSIG(t, got, secret)
+	if want := unhex(t, removeWeb01); !bytes.Equal(got[2:], want[2:]) {
+		t.Fatalf("update =\n%x\nwant\n%x", got[2:], want[2:])
+	}
+}
+
+func TestRFC2136AddAAAA(t *testing.T) {
+	secret, _ := base64.StdEncoding.DecodeString(testTSIGSecret)
+	var got []byte
+	addr := fakeDNSServer(t, func(query []byte) []byte {
+		got = query
+		return dnsResponse(query, 0, 1, 0, 0, 0)
+	})
+	p := testRFC2136Provider(t, addr)
+	err := p.add(context.Background(), []dnsRecord{{Name: "web-01.example.com", Type: "AAAA", Content: "2001:db8::1", TTL: 60}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got = verifyTSIG(t, got, secret)
+	want := unhex(t, "067765622d3031076578616d706c6503636f6d00 001c 0001 0000003c 0010 20010db8000000000000000000000001")
+	if !bytes.HasSuffix(got, want) {
+		t.Fatalf("update =\n%x\nwant suffix\n%x", got, want)
+	}
+}
+
+func TestRFC2136Refused(t *testing.T) {
+	addr := fakeDNSServer(t, func(query []byte) []byte {
+		return dnsResponse(query, 5, 1, 0, 0, 0)
+	})
+	p := testRFC2136Provider(t, addr)
+	err := p.remove(context.Background(), []dnsRecord{{Name: "web-01.example.com", Type: "A", Content: "192.0.2.10"}})
+	if err == nil || !strings.Contains(err.Error(), "REFUSED") {
+		t.Fatalf("err = %v, want REFUSED", err)
+	}
+}
+
+func TestRFC2136Records(t *testing.T) {
+	addr := fakeDNSServer(t, func(query []byte) []byte {
+		resp := dnsResponse(query, 0, 1, 3, 0, 0)
+		// The question, as the query asked it.
+		_, end, _ := readDNSName(query, 12)
+		resp = append(resp, query[12:end+4]...)
+		// Two answers pointing back at the question's name, and one
+		// for another name.
+		for _, ip := range []string{"c000020a", "c000020b"} {
+			resp = append(resp, unhex(t, "c00c 0001 0001 0000012c 0004"+ip)...)
+		}
+		resp = append(resp, unhex(t, "03777777 c00c 0001 0001 0000012c 0004 c000020c")...)
+		return resp
+	})
+	p := testRFC2136Provider(t, addr)
+	records, err := p.records(context.Background(), "web-01.example.com", "A")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []dnsRecord{
+		{Name: "web-01.example.com", Type: "A", Content: "192.0.2.10", TTL: 300},
+		{Name: "web-01.example.com", Type: "A", Content: "192.0.2.11", TTL: 300},
+	}
+	if !reflect.DeepEqual(records, want) {
+		t.Fatalf("records = %+v, want %+v", records, want)
+	}
+}
+
+func TestReadDNSName(t *testing.T) {
+	msg := unhex(t, "0000 03777777 076578616d706c6503636f6d00 c002")
+	name, end, err := readDNSName(msg, 2)
+	if err != nil || name != "www.example.com" || end != 19 {
+		t.Fatalf("got %q, %d, %v", name, end, err)
+	}
+	if name, end, err = readDNSName(msg, 19); err != nil || name != "www.example.com" || end != 21 {
+		t.Fatalf("compressed: got %q, %d, %v", name, end, err)
+	}
+	if _, _, err := readDNSName(unhex(t, "c000"), 0); err == nil {
+		t.Fatal("pointer loop read without error")
+	}
+	if _, _, err := readDNSName(unhex(t, "0377"), 0); err == nil {
+		t.Fatal("truncated name read without error")
+	}
+}
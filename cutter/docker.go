@@ -3,8 +3,11 @@ package cutter
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"strings"
+	"sync"
 
+	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/filters"
 	"github.com/docker/docker/client"
@@ -13,12 +16,23 @@ import (
 	"atropos/internal/logger"
 )
 
+// dockerWorkers bounds how many containers one cut acts on at once.
+const dockerWorkers = 8
+
 type DockerCutter struct {
-	cli *client.Client
+	// clients holds a client per Docker host, keyed by dockerHost.cacheKey.
+	clients map[string]*client.Client
+	// disconnected holds, per container ID, the networks
+	// docker_network_disconnect_all took it off.
+	disconnected map[string][]string
+	mu           sync.Mutex
 }
 
 func NewDockerCutter() *DockerCutter {
-	return &DockerCutter{}
+	return &DockerCutter{
+		clients:      make(map[string]*client.Client),
+		disconnected: make(map[string][]string),
+	}
 }
 
 func (d *DockerCutter) Name() string {
@@ -29,59 +43,248 @@ func (d *DockerCutter) CanHandle(action string) bool {
 	return strings.HasPrefix(action, "docker_")
 }
 
-func (d *DockerCutter) Execute(ctx context.Context, target string, params map[string]string) error {
-	if d.cli == nil {
-		cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
-		if err != nil {
-			return fmt.Errorf("docker client: %w", err)
-		}
-		d.cli = cli
-	}
+func (d *DockerCutter) SupportedActions() []string {
+	return []string{"docker_pause_all", "docker_unpause_all", "docker_stop_all", "docker_kill_all", "docker_restart_all", "docker_network_disconnect_all", "docker_network_reconnect"}
+}
 
+func (d *DockerCutter) Execute(ctx context.Context, target string, params map[string]string) error {
 	action := params["action"]
 	logger.Get().Info("docker_cut",
 		zap.String("target", target),
 		zap.String("action", action),
+		zap.String("docker_host", dockerHostFromParams(params).String()),
 	)
 
+	switch action {
+	case "docker_pause_all", "docker_unpause_all", "docker_stop_all", "docker_kill_all", "docker_restart_all",
+		"docker_network_disconnect_all", "docker_network_reconnect":
+	default:
+		return fmt.Errorf("unsupported action: %s", action)
+	}
+	stopOpts := container.StopOptions{}
+	if s := params["stop_timeout_seconds"]; s != "" {
+		timeout, err := strconv.Atoi(s)
+		if err != nil || timeout < 0 {
+			return fmt.Errorf("stop_timeout_seconds must be a whole number >= 0")
+		}
+		stopOpts.Timeout = &timeout
+	}
+
+	cli, err := d.dockerClient(ctx, params)
+	if err != nil {
+		return stepError(ctx, "connect", err)
+	}
+	todo, skipped, err := d.targetContainers(ctx, cli, target, action, params)
+	if err != nil {
+		return err
+	}
+
+	// Every container gets its turn even after one fails, dockerWorkers at a
+	// time; the error lists each container that failed.
+	errs := make([]error, len(todo))
+	sem := make(chan struct{}, dockerWorkers)
+	var wg sync.WaitGroup
+	for i, c := range todo {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(i int, c types.Container) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			// Share what's left of the deadline among the rounds still to go.
+			opCtx, cancel := StepContext(ctx, (len(todo)-i+dockerWorkers-1)/dockerWorkers)
+			defer cancel()
+			if err := d.containerOp(opCtx, cli, action, c, params, stopOpts); err != nil {
+				// The container's name is added once, with the others below.
+				errs[i] = stepError(opCtx, action, err)
+			}
+		}(i, c)
+	}
+	wg.Wait()
+
+	succeeded := []string{}
+	var failed []string
+	var failures containerErrors
+	for i, c := range todo {
+		if errs[i] != nil {
+			failed = append(failed, containerName(c))
+			failures = append(failures, fmt.Errorf("%s: %w", containerName(c), errs[i]))
+			continue
+		}
+		succeeded = append(succeeded, containerName(c))
+	}
+
+	RecordDetail(ctx, "containers", succeeded)
+	if len(failed) > 0 {
+		RecordDetail(ctx, "containers_failed", failed)
+	}
+	if skipped > 0 {
+		RecordDetail(ctx, "containers_skipped", skipped)
+	}
+	if len(failures) > 0 {
+		err := fmt.Errorf("%s failed for %d of %d containers: %w", action, len(failures), len(todo), failures)
+		logger.CutFailed(target, action, err)
+		return err
+	}
+	return nil
+}
+
+// Plan lists the containers the action would act on, without touching them.
+func (d *DockerCutter) Plan(ctx context.Context, target string, params map[string]string) (PlanResult, error) {
+	action := params["action"]
+	if !d.CanHandle(action) {
+		return PlanResult{}, fmt.Errorf("unsupported action: %s", action)
+	}
+	cli, err := d.dockerClient(ctx, params)
+	if err != nil {
+		return PlanResult{}, stepError(ctx, "connect", err)
+	}
+	todo, skipped, err := d.targetContainers(ctx, cli, target, action, params)
+	if err != nil {
+		return PlanResult{}, err
+	}
+	names := make([]string, len(todo))
+	for i, c := range todo {
+		names[i] = containerName(c)
+	}
+	return PlanResult{
+		Summary: fmt.Sprintf("%s on %d containers on %s", action, len(todo), dockerHostFromParams(params)),
+		Details: map[string]interface{}{"containers": names, "containers_skipped": skipped},
+	}, nil
+}
+
+// targetContainers finds the node's containers the action applies to, and
+// how many of them it would skip as already done.
+func (d *DockerCutter) targetContainers(ctx context.Context, cli *client.Client, target, action string, params map[string]string) ([]types.Container, int, error) {
 	filterArgs := filters.NewArgs()
 	filterArgs.Add("label", fmt.Sprintf("atropos.node=%s", target))
 
-	containers, err := d.cli.ContainerList(ctx, container.ListOptions{
+	containers, err := cli.ContainerList(ctx, container.ListOptions{
 		All:     true,
 		Filters: filterArgs,
 	})
 	if err != nil {
-		return fmt.Errorf("list containers: %w", err)
+		return nil, 0, stepError(ctx, "list", fmt.Errorf("list containers on %s: %w", dockerHostFromParams(params), err))
 	}
 
-	if len(containers) == 0 {
-		containers, err = d.cli.ContainerList(ctx, container.ListOptions{All: false})
+	if ref := params["container"]; ref != "" {
+		c, err := d.selectContainer(ctx, cli, target, containers, ref)
+		if err != nil {
+			return nil, 0, stepError(ctx, "list", err)
+		}
+		containers = []types.Container{c}
+	} else if len(containers) == 0 {
+		// Acting on every container is only safe on a host that runs nothing
+		// but the node, so it has to be asked for.
+		if allow, _ := strconv.ParseBool(params["allow_unlabeled"]); !allow {
+			return nil, 0, fmt.Errorf("no containers labeled atropos.node=%s", target)
+		}
+		logger.Get().Warn("docker_unlabeled_fallback",
+			zap.String("target", target),
+			zap.String("action", action),
+		)
+		containers, err = cli.ContainerList(ctx, container.ListOptions{All: false})
 		if err != nil {
-			return fmt.Errorf("list all containers: %w", err)
+			return nil, 0, stepError(ctx, "list", fmt.Errorf("list all containers on %s: %w", dockerHostFromParams(params), err))
 		}
 	}
 
+	var todo []types.Container
+	skipped := 0
 	for _, c := range containers {
-		var opErr error
-		switch action {
-		case "docker_pause_all":
-			if c.State == "running" {
-				opErr = d.cli.ContainerPause(ctx, c.ID)
-			}
-		case "docker_stop_all":
-			opErr = d.cli.ContainerStop(ctx, c.ID, container.StopOptions{})
-		case "docker_kill_all":
-			opErr = d.cli.ContainerKill(ctx, c.ID, "SIGKILL")
-		default:
-			return fmt.Errorf("unsupported action: %s", action)
+		if dockerSkips(action, c.State) {
+			skipped++
+			continue
 		}
+		todo = append(todo, c)
+	}
+	return todo, skipped, nil
+}
+
+func (d *DockerCutter) containerOp(ctx context.Context, cli *client.Client, action string, c types.Container, params map[string]string, stopOpts container.StopOptions) error {
+	switch action {
+	case "docker_pause_all":
+		return cli.ContainerPause(ctx, c.ID)
+	case "docker_unpause_all":
+		return cli.ContainerUnpause(ctx, c.ID)
+	case "docker_stop_all":
+		return cli.ContainerStop(ctx, c.ID, stopOpts)
+	case "docker_kill_all":
+		return cli.ContainerKill(ctx, c.ID, "SIGKILL")
+	case "docker_restart_all":
+		return cli.ContainerRestart(ctx, c.ID, stopOpts)
+	case "docker_network_disconnect_all":
+		return d.disconnectNetworks(ctx, cli, c, params["quarantine_network"])
+	case "docker_network_reconnect":
+		return d.reconnectNetworks(ctx, cli, c, params["quarantine_network"])
+	}
+	return fmt.Errorf("unsupported action: %s", action)
+}
 
-		if opErr != nil {
-			logger.CutFailed(target, action, opErr)
-			return fmt.Errorf("%s container %s: %w", action, c.ID[:12], opErr)
+// containerErrors is the failures of a docker action, one per container.
+type containerErrors []error
+
+func (e containerErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func (e containerErrors) Unwrap() []error {
+	return e
+}
+
+// selectContainer picks the container named or ID-prefixed by ref out of the
+// node's labelled containers.
+func (d *DockerCutter) selectContainer(ctx context.Context, cli *client.Client, target string, labelled []types.Container, ref string) (types.Container, error) {
+	var matches []types.Container
+	for _, c := range labelled {
+		if containerName(c) == ref || strings.HasPrefix(c.ID, ref) {
+			matches = append(matches, c)
 		}
 	}
+	switch len(matches) {
+	case 1:
+		return matches[0], nil
+	case 0:
+		if _, err := cli.ContainerInspect(ctx, ref); err != nil {
+			if client.IsErrNotFound(err) {
+				return types.Container{}, fmt.Errorf("container %s not found", ref)
+			}
+			return types.Container{}, fmt.Errorf("inspect container %s: %w", ref, err)
+		}
+		return types.Container{}, fmt.Errorf("container %s is not labeled atropos.node=%s", ref, target)
+	default:
+		return types.Container{}, fmt.Errorf("container %s matches %d of the node's containers", ref, len(matches))
+	}
+}
 
-	return nil
+// containerName is the container's name without Docker's leading slash, or
+// its short ID if it has none.
+func containerName(c types.Container) string {
+	if len(c.Names) > 0 {
+		return strings.TrimPrefix(c.Names[0], "/")
+	}
+	return c.ID[:12]
+}
+
+// dockerSkips reports whether a container in state is already where action
+// would put it.
+func dockerSkips(action, state string) bool {
+	switch action {
+	case "docker_pause_all":
+		return state != "running"
+	case "docker_unpause_all":
+		return state != "paused"
+	case "docker_restart_all":
+		return state == "restarting"
+	case "docker_kill_all":
+		// Docker refuses to kill a container that isn't running, and the
+		// node's list includes stopped ones. Paused ones can be killed.
+		return state != "running" && state != "paused"
+	}
+	return false
 }
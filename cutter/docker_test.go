@@ -0,0 +1,258 @@
+package cutter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeContainer is a container the fake daemon knows.
+type fakeContainer struct {
+	id, name, state, node string
+}
+
+// fakeDocker serves the Docker Engine API calls the cutter makes. Each
+// container action is recorded as "action name", e.g. "kill web".
+type fakeDocker struct {
+	*httptest.Server
+	containers []fakeContainer
+	// fail maps a container name to the status its actions answer with.
+	fail map[string]int
+
+	mu    sync.Mutex
+	ops   []string
+	lists []string
+}
+
+func newFakeDocker(t *testing.T, containers ...fakeContainer) *fakeDocker {
+	f := &fakeDocker{containers: containers, fail: map[string]int{}}
+	f.Server = httptest.NewServer(http.HandlerFunc(f.serve))
+	t.Cleanup(f.Close)
+	return f
+}
+
+func (f *fakeDocker) serve(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Api-Version", "1.43")
+	path := r.URL.Path
+	if i := strings.Index(path[1:], "/"); strings.HasPrefix(path, "/v1.") && i > 0 {
+		path = path[i+1:]
+	}
+	if path == "/_ping" {
+		fmt.Fprint(w, "OK")
+		return
+	}
+	if path == "/containers/json" {
+		f.list(w, r)
+		return
+	}
+	// /containers/{id}/{op}
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(parts) != 3 || parts[0] != "containers" {
+		http.NotFound(w, r)
+		return
+	}
+	for _, c := range f.containers {
+		if c.id != parts[1] && c.name != parts[1] {
+			continue
+		}
+		if parts[2] == "json" {
+			fmt.Fprintf(w, `{"Id": %q, "Name": "/%s", "State": {"Status": %q}}`, c.id, c.name, c.state)
+			return
+		}
+		f.mu.Lock()
+		f.ops = append(f.ops, parts[2]+" "+c.name)
+		f.mu.Unlock()
+		if status := f.fail[c.name]; status != 0 {
+			w.WriteHeader(status)
+			fmt.Fprintf(w, `{"message": "cannot %s container %s"}`, parts[2], c.name)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	w.WriteHeader(http.StatusNotFound)
+	fmt.Fprintf(w, `{"message": "No such container: %s"}`, parts[1])
+}
+
+// list applies the label filter and all flag the way dockerd does.
+func (f *fakeDocker) list(w http.ResponseWriter, r *http.Request) {
+	var filters map[string]map[string]bool
+	if raw := r.URL.Query().Get("filters"); raw != "" {
+		json.Unmarshal([]byte(raw), &filters)
+	}
+	all := r.URL.Query().Get("all") == "1"
+	f.mu.Lock()
+	f.lists = append(f.lists, fmt.Sprintf("all=%t filters=%v", all, filters["label"]))
+	f.mu.Unlock()
+
+	out := []map[string]interface{}{}
+	for _, c := range f.containers {
+		if !all && c.state != "running" {
+			continue
+		}
+		labels := map[string]string{}
+		if c.node != "" {
+			labels["atropos.node"] = c.node
+		}
+		match := true
+		for label := range filters["label"] {
+			key, value, _ := strings.Cut(label, "=")
+			match = match && labels[key] == value
+		}
+		if match {
+			out = append(out, map[string]interface{}{"Id": c.id, "Names": []string{"/" + c.name}, "State": c.state, "Labels": labels})
+		}
+	}
+	json.NewEncoder(w).Encode(out)
+}
+
+func (f *fakeDocker) Ops() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	ops := append([]string(nil), f.ops...)
+	sort.Strings(ops)
+	return ops
+}
+
+func (f *fakeDocker) run(t *testing.T, action string, params map[string]string) (map[string]interface{}, error) {
+	t.Helper()
+	d := NewDockerCutter()
+	t.Cleanup(func() {
+		for _, cli := range d.clients {
+			cli.Close()
+		}
+	})
+	if params == nil {
+		params = map[string]string{}
+	}
+	params["action"] = action
+	params["docker_host"] = "tcp://" + f.Listener.Addr().String()
+	ctx, details := WithDetails(context.Background())
+	err := d.Execute(ctx, "web-01", params)
+	return details.Map(), err
+}
+
+func containerID(n int) string {
+	return fmt.Sprintf("%064x", n)
+}
+
+func TestDockerRequiresLabeledContainers(t *testing.T) {
+	f := newFakeDocker(t,
+		fakeContainer{containerID(1), "monitoring", "running", ""},
+		fakeContainer{containerID(2), "db", "running", "db-01"},
+	)
+	_, err := f.run(t, "docker_kill_all", nil)
+	if err == nil || err.Error() != "no containers labeled atropos.node=web-01" {
+		t.Fatalf("err = %v", err)
+	}
+	if ops := f.Ops(); len(ops) != 0 {
+		t.Fatalf("acted on %v", ops)
+	}
+	if len(f.lists) != 1 || !strings.Contains(f.lists[0], "atropos.node=web-01") {
+		t.Fatalf("lists = %v, want only the labeled one", f.lists)
+	}
+}
+
+func TestDockerAllowUnlabeled(t *testing.T) {
+	f := newFakeDocker(t,
+		fakeContainer{containerID(1), "app", "running", ""},
+		fakeContainer{containerID(2), "old", "exited", ""},
+	)
+	if _, err := f.run(t, "docker_stop_all", map[string]string{"allow_unlabeled": "true"}); err != nil {
+		t.Fatal(err)
+	}
+	if ops := f.Ops(); !reflect.DeepEqual(ops, []string{"stop app"}) {
+		t.Fatalf("ops = %v", ops)
+	}
+}
+
+func TestDockerKillSkipsStoppedContainers(t *testing.T) {
+	f := newFakeDocker(t,
+		fakeContainer{containerID(1), "api", "running", "web-01"},
+		fakeContainer{containerID(2), "worker", "paused", "web-01"},
+		fakeContainer{containerID(3), "migrate", "exited", "web-01"},
+		fakeContainer{containerID(4), "seed", "created", "web-01"},
+	)
+	details, err := f.run(t, "docker_kill_all", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ops := f.Ops(); !reflect.DeepEqual(ops, []string{"kill api", "kill worker"}) {
+		t.Fatalf("ops = %v", ops)
+	}
+	if details["containers_skipped"] != 2 {
+		t.Fatalf("containers_skipped = %v, want 2", details["containers_skipped"])
+	}
+}
+
+func TestDockerSkips(t *testing.T) {
+	tests := []struct {
+		action string
+		acts   []string
+	}{
+		{"docker_pause_all", []string{"running"}},
+		{"docker_unpause_all", []string{"paused"}},
+		{"docker_kill_all", []string{"running", "paused"}},
+		{"docker_restart_all", []string{"running", "paused", "exited", "created", "dead"}},
+		{"docker_stop_all", []string{"running", "paused", "restarting", "exited", "created", "dead"}},
+	}
+	for _, tt := range tests {
+		for _, state := range []string{"running", "paused", "restarting", "exited", "created", "dead"} {
+			acts := false
+			for _, s := range tt.acts {
+				acts = acts || s == state
+			}
+			if got := !dockerSkips(tt.action, state); got != acts {
+				t.Errorf("%s on a %s container: acts = %t, want %t", tt.action, state, got, acts)
+			}
+		}
+	}
+}
+
+func TestDockerReportsEachFailedContainer(t *testing.T) {
+	f := newFakeDocker(t,
+		fakeContainer{containerID(1), "api", "running", "web-01"},
+		fakeContainer{containerID(2), "worker", "running", "web-01"},
+		fakeContainer{containerID(3), "cache", "running", "web-01"},
+	)
+	f.fail["worker"] = http.StatusInternalServerError
+	details, err := f.run(t, "docker_restart_all", nil)
+	if err == nil || !strings.HasPrefix(err.Error(), "docker_restart_all failed for 1 of 3 containers: worker: ") {
+		t.Fatalf("err = %v", err)
+	}
+	if strings.Contains(err.Error(), containerID(2)[:12]) {
+		t.Fatalf("err names the container twice: %v", err)
+	}
+	if got := details["containers"]; !reflect.DeepEqual(got, []string{"api", "cache"}) {
+		t.Fatalf("containers = %v", got)
+	}
+	if got := details["containers_failed"]; !reflect.DeepEqual(got, []string{"worker"}) {
+		t.Fatalf("containers_failed = %v", got)
+	}
+}
+
+func TestDockerContainerParam(t *testing.T) {
+	f := newFakeDocker(t,
+		fakeContainer{containerID(1), "api", "running", "web-01"},
+		fakeContainer{containerID(2), "worker", "running", "web-01"},
+		fakeContainer{containerID(3), "monitoring", "running", ""},
+	)
+	if _, err := f.run(t, "docker_pause_all", map[string]string{"container": "worker"}); err != nil {
+		t.Fatal(err)
+	}
+	if ops := f.Ops(); !reflect.DeepEqual(ops, []string{"pause worker"}) {
+		t.Fatalf("ops = %v", ops)
+	}
+
+	_, err := f.run(t, "docker_pause_all", map[string]string{"container": "monitoring"})
+	if err == nil || !strings.Contains(err.Error(), "container monitoring is not labeled atropos.node=web-01") {
+		t.Fatalf("err = %v", err)
+	}
+}
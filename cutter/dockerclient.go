@@ -0,0 +1,205 @@
+package cutter
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/client"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/ssh"
+
+	"atropos/internal/logger"
+)
+
+const dockerPingTimeout = 3 * time.Second
+
+// dockerHost is where a node's containers run: docker_host and its TLS
+// files from the params, or the environment's daemon when docker_host is
+// unset.
+type dockerHost struct {
+	host string
+	ca   string
+	cert string
+	key  string
+	// The host key settings of an ssh:// host.
+	knownHosts  string
+	fingerprint string
+	checking    string
+}
+
+func dockerHostFromParams(params map[string]string) dockerHost {
+	return dockerHost{
+		host:        params["docker_host"],
+		ca:          params["docker_tls_ca"],
+		cert:        params["docker_tls_cert"],
+		key:         params["docker_tls_key"],
+		knownHosts:  params["known_hosts"],
+		fingerprint: params["host_key_fingerprint"],
+		checking:    params["host_key_checking"],
+	}
+}
+
+// cacheKey tells clients apart; the same host with other TLS files or host
+// key settings gets its own client.
+func (h dockerHost) cacheKey() string {
+	return strings.Join([]string{h.host, h.ca, h.cert, h.key, h.knownHosts, h.fingerprint, h.checking}, "|")
+}
+
+func (h dockerHost) hostKeyParams() map[string]string {
+	return map[string]string{
+		"known_hosts":          h.knownHosts,
+		"host_key_fingerprint": h.fingerprint,
+		"host_key_checking":    h.checking,
+	}
+}
+
+func (h dockerHost) String() string {
+	if h.host == "" {
+		return "local daemon"
+	}
+	return h.host
+}
+
+// dockerClient returns the cached client for the params' host, after
+// checking it still answers. A client that doesn't is replaced.
+func (d *DockerCutter) dockerClient(ctx context.Context, params map[string]string) (*client.Client, error) {
+	host := dockerHostFromParams(params)
+	key := host.cacheKey()
+
+	d.mu.Lock()
+	cli := d.clients[key]
+	d.mu.Unlock()
+	if cli != nil {
+		err := pingDocker(ctx, cli)
+		if err == nil {
+			return cli, nil
+		}
+		logger.Get().Warn("docker_client_stale",
+			zap.String("docker_host", host.String()),
+			zap.Error(err),
+		)
+		d.mu.Lock()
+		if d.clients[key] == cli {
+			delete(d.clients, key)
+		}
+		d.mu.Unlock()
+		cli.Close()
+	}
+
+	cli, err := newDockerClient(ctx, host)
+	if err != nil {
+		return nil, fmt.Errorf("docker host %s: %w", host, err)
+	}
+	if err := pingDocker(ctx, cli); err != nil {
+		cli.Close()
+		return nil, fmt.Errorf("docker host %s: %w", host, err)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if existing := d.clients[key]; existing != nil {
+		// Another cut connected first.
+		cli.Close()
+		return existing, nil
+	}
+	d.clients[key] = cli
+	return cli, nil
+}
+
+// HealthCheck reports whether the environment's daemon, the one nodes
+// without docker_host use, answers a ping.
+func (d *DockerCutter) HealthCheck(ctx context.Context) error {
+	_, err := d.dockerClient(ctx, nil)
+	return err
+}
+
+// pingDocker gives the daemon dockerPingTimeout to answer, so a dead one
+// fails the cut quickly instead of using up its deadline.
+func pingDocker(ctx context.Context, cli *client.Client) error {
+	pingCtx, cancel := context.WithTimeout(ctx, dockerPingTimeout)
+	defer cancel()
+	_, err := cli.Ping(pingCtx)
+	return err
+}
+
+func newDockerClient(ctx context.Context, host dockerHost) (*client.Client, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	opts := []client.Opt{client.FromEnv, client.WithAPIVersionNegotiation()}
+	if host.host == "" {
+		return client.NewClientWithOpts(opts...)
+	}
+
+	u, err := url.Parse(host.host)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "ssh" {
+		dialer := newDockerSSHDialer(u, host.hostKeyParams())
+		opts = append(opts, client.WithHost("tcp://docker"), client.WithDialContext(dialer.DialContext))
+		return client.NewClientWithOpts(opts...)
+	}
+	opts = append(opts, client.WithHost(host.host))
+	if host.ca != "" || host.cert != "" || host.key != "" {
+		opts = append(opts, client.WithTLSClientConfig(host.ca, host.cert, host.key))
+	}
+	return client.NewClientWithOpts(opts...)
+}
+
+// dockerSSHDialer reaches a remote daemon's socket through an SSH
+// connection, opened on first use and again whenever it drops.
+type dockerSSHDialer struct {
+	user   string
+	host   string
+	port   string
+	socket string
+	// params carry the host key settings.
+	params map[string]string
+	conn   *ssh.Client
+	mu     sync.Mutex
+}
+
+func newDockerSSHDialer(u *url.URL, params map[string]string) *dockerSSHDialer {
+	s := &dockerSSHDialer{user: u.User.Username(), host: u.Hostname(), port: u.Port(), socket: u.Path, params: params}
+	if s.user == "" {
+		s.user = "root"
+	}
+	if s.port == "" {
+		s.port = "22"
+	}
+	if s.socket == "" {
+		s.socket = "/var/run/docker.sock"
+	}
+	return s
+}
+
+func (s *dockerSSHDialer) DialContext(ctx context.Context, _, _ string) (net.Conn, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn != nil {
+		conn, err := s.conn.Dial("unix", s.socket)
+		if err == nil {
+			return conn, nil
+		}
+		s.conn.Close()
+		s.conn = nil
+	}
+	sshConn, err := dialSSH(ctx, s.user, s.host, s.port, s.params)
+	if err != nil {
+		return nil, fmt.Errorf("ssh connect: %w", err)
+	}
+	conn, err := sshConn.Dial("unix", s.socket)
+	if err != nil {
+		sshConn.Close()
+		return nil, fmt.Errorf("dial %s: %w", s.socket, err)
+	}
+	s.conn = sshConn
+	return conn, nil
+}
@@ -0,0 +1,107 @@
+package cutter
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+)
+
+// disconnectNetworks takes c off every network but quarantine, then joins
+// quarantine when it is set, so the container stays reachable for exec but
+// not from its peers. The networks it left are kept for
+// docker_network_reconnect.
+func (d *DockerCutter) disconnectNetworks(ctx context.Context, cli *client.Client, c types.Container, quarantine string) error {
+	if !dockerNetworked(c) {
+		return nil
+	}
+	names := make([]string, 0, len(c.NetworkSettings.Networks))
+	for name := range c.NetworkSettings.Networks {
+		if name != quarantine {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var left []string
+	defer func() { d.rememberNetworks(c.ID, left) }()
+	for _, name := range names {
+		if err := cli.NetworkDisconnect(ctx, name, c.ID, true); err != nil {
+			return fmt.Errorf("disconnect %s: %w", name, err)
+		}
+		left = append(left, name)
+	}
+	if _, joined := c.NetworkSettings.Networks[quarantine]; quarantine != "" && !joined {
+		if err := cli.NetworkConnect(ctx, quarantine, c.ID, nil); err != nil {
+			return fmt.Errorf("connect %s: %w", quarantine, err)
+		}
+	}
+	return nil
+}
+
+// reconnectNetworks undoes disconnectNetworks. Without a record of what c
+// left, e.g. after Atropos restarted, it rejoins the network c was created
+// on.
+func (d *DockerCutter) reconnectNetworks(ctx context.Context, cli *client.Client, c types.Container, quarantine string) error {
+	if !dockerNetworked(c) {
+		return nil
+	}
+	names := d.forgetNetworks(c.ID)
+	if len(names) == 0 {
+		names = []string{dockerHomeNetwork(c)}
+	}
+	for i, name := range names {
+		if _, ok := c.NetworkSettings.Networks[name]; ok {
+			continue
+		}
+		if err := cli.NetworkConnect(ctx, name, c.ID, nil); err != nil {
+			d.rememberNetworks(c.ID, names[i:])
+			return fmt.Errorf("connect %s: %w", name, err)
+		}
+	}
+	if _, joined := c.NetworkSettings.Networks[quarantine]; quarantine != "" && joined {
+		if err := cli.NetworkDisconnect(ctx, quarantine, c.ID, true); err != nil {
+			return fmt.Errorf("disconnect %s: %w", quarantine, err)
+		}
+	}
+	return nil
+}
+
+func (d *DockerCutter) rememberNetworks(id string, names []string) {
+	if len(names) == 0 {
+		return
+	}
+	d.mu.Lock()
+	d.disconnected[id] = append(d.disconnected[id], names...)
+	d.mu.Unlock()
+}
+
+func (d *DockerCutter) forgetNetworks(id string) []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	names := d.disconnected[id]
+	delete(d.disconnected, id)
+	return names
+}
+
+// dockerNetworked reports whether c has networks of its own; host, none and
+// container: modes can't be connected or disconnected.
+func dockerNetworked(c types.Container) bool {
+	mode := c.HostConfig.NetworkMode
+	if mode == "host" || mode == "none" || strings.HasPrefix(mode, "container:") {
+		return false
+	}
+	return c.NetworkSettings != nil
+}
+
+func dockerHomeNetwork(c types.Container) string {
+	switch mode := c.HostConfig.NetworkMode; mode {
+	case "", "default":
+		return "bridge"
+	default:
+		return mode
+	}
+}
@@ -0,0 +1,124 @@
+package cutter
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// haproxyRuntime sends commands to an HAProxy runtime API socket, one
+// command per connection as the non-interactive mode expects.
+type haproxyRuntime struct {
+	network string
+	addr    string
+}
+
+// newHAProxyRuntime reads haproxy_socket: a unix socket path, optionally
+// prefixed unix:, or a tcp:// or host:port address.
+func newHAProxyRuntime(socket string) (*haproxyRuntime, error) {
+	switch {
+	case socket == "":
+		return nil, fmt.Errorf("haproxy requires haproxy_socket")
+	case strings.HasPrefix(socket, "unix:"):
+		return &haproxyRuntime{network: "unix", addr: strings.TrimPrefix(strings.TrimPrefix(socket, "unix:"), "//")}, nil
+	case strings.HasPrefix(socket, "tcp://"):
+		return &haproxyRuntime{network: "tcp", addr: strings.TrimPrefix(socket, "tcp://")}, nil
+	case strings.HasPrefix(socket, "/"):
+		return &haproxyRuntime{network: "unix", addr: socket}, nil
+	default:
+		return &haproxyRuntime{network: "tcp", addr: socket}, nil
+	}
+}
+
+func (h *haproxyRuntime) String() string {
+	return h.network + ":" + h.addr
+}
+
+func (h *haproxyRuntime) command(ctx context.Context, cmd string) (string, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, h.network, h.addr)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	if _, err := io.WriteString(conn, cmd+"\n"); err != nil {
+		return "", err
+	}
+	out, err := io.ReadAll(io.LimitReader(conn, 4<<20))
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+// setServerState sets backend/server to ready, drain or maint. HAProxy
+// answers a good command with nothing and a bad one with why.
+func (h *haproxyRuntime) setServerState(ctx context.Context, backend, server, state string) error {
+	out, err := h.command(ctx, fmt.Sprintf("set server %s/%s state %s", backend, server, state))
+	if err != nil {
+		return err
+	}
+	if msg := strings.TrimSpace(out); msg != "" {
+		return fmt.Errorf("set server %s/%s state %s: %s", backend, server, state, msg)
+	}
+	return nil
+}
+
+// sessions reads backend/server's current session count from show stat.
+func (h *haproxyRuntime) sessions(ctx context.Context, backend, server string) (int, error) {
+	out, err := h.command(ctx, "show stat")
+	if err != nil {
+		return 0, err
+	}
+	r := csv.NewReader(strings.NewReader(strings.TrimPrefix(out, "# ")))
+	r.FieldsPerRecord = -1
+	rows, err := r.ReadAll()
+	if err != nil {
+		return 0, fmt.Errorf("show stat: %w", err)
+	}
+	if len(rows) == 0 {
+		return 0, fmt.Errorf("show stat: empty response")
+	}
+	scur := -1
+	for i, col := range rows[0] {
+		if col == "scur" {
+			scur = i
+		}
+	}
+	if scur < 2 {
+		return 0, fmt.Errorf("show stat: no scur column")
+	}
+	for _, row := range rows[1:] {
+		if len(row) > scur && row[0] == backend && row[1] == server {
+			return strconv.Atoi(row[scur])
+		}
+	}
+	return 0, fmt.Errorf("server %s/%s not in show stat", backend, server)
+}
+
+// waitDrained polls until backend/server has no sessions or wait runs out,
+// returning the last count seen.
+func (h *haproxyRuntime) waitDrained(ctx context.Context, backend, server string, wait time.Duration) (int, error) {
+	deadline := time.Now().Add(wait)
+	ticker := time.NewTicker(lbDrainPolling)
+	defer ticker.Stop()
+	for {
+		n, err := h.sessions(ctx, backend, server)
+		if err != nil || n == 0 || !time.Now().Before(deadline) {
+			return n, err
+		}
+		select {
+		case <-ctx.Done():
+			return n, nil
+		case <-ticker.C:
+		}
+	}
+}
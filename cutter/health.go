@@ -0,0 +1,10 @@
+package cutter
+
+import "context"
+
+// HealthChecker is implemented by cutters that can tell, before any cut,
+// whether what they depend on is there: the Docker daemon answers,
+// VBoxManage runs, ssh-agent is reachable.
+type HealthChecker interface {
+	HealthCheck(ctx context.Context) error
+}
@@ -0,0 +1,135 @@
+package cutter
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+
+	"atropos/internal/logger"
+)
+
+const (
+	HostKeyStrict   = "strict"
+	HostKeyTOFU     = "tofu"
+	HostKeyInsecure = "insecure"
+)
+
+// knownHostsMu serializes the keys tofu appends to known_hosts files.
+var knownHostsMu sync.Mutex
+
+// hostKeyCallback checks SSH host keys as the params ask: against
+// host_key_fingerprint when it is set, otherwise against the known_hosts
+// file (default ~/.ssh/known_hosts) in host_key_checking mode, default
+// strict. tofu records the key of a host the file doesn't know yet.
+func hostKeyCallback(params map[string]string) (ssh.HostKeyCallback, error) {
+	if fp := params["host_key_fingerprint"]; fp != "" {
+		return pinnedHostKey(fp), nil
+	}
+
+	mode := params["host_key_checking"]
+	switch mode {
+	case "":
+		mode = HostKeyStrict
+	case HostKeyStrict, HostKeyTOFU:
+	case HostKeyInsecure:
+		return ssh.InsecureIgnoreHostKey(), nil
+	default:
+		return nil, fmt.Errorf("host_key_checking must be %q, %q or %q", HostKeyStrict, HostKeyTOFU, HostKeyInsecure)
+	}
+
+	path := params["known_hosts"]
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("known_hosts: %w", err)
+		}
+		path = filepath.Join(home, ".ssh", "known_hosts")
+	}
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		return checkKnownHost(path, mode == HostKeyTOFU, hostname, remote, key)
+	}, nil
+}
+
+func checkKnownHost(path string, tofu bool, hostname string, remote net.Addr, key ssh.PublicKey) error {
+	knownHostsMu.Lock()
+	defer knownHostsMu.Unlock()
+
+	// Read the file on every connection, so keys added since are seen.
+	if tofu {
+		if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+			return fmt.Errorf("known_hosts: %w", err)
+		}
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_RDONLY, 0o600)
+		if err != nil {
+			return fmt.Errorf("known_hosts: %w", err)
+		}
+		f.Close()
+	}
+	check, err := knownhosts.New(path)
+	if err != nil {
+		return fmt.Errorf("known_hosts: %w", err)
+	}
+	err = check(hostname, remote, key)
+	if err == nil {
+		return nil
+	}
+
+	fingerprint := ssh.FingerprintSHA256(key)
+	var keyErr *knownhosts.KeyError
+	if !errors.As(err, &keyErr) {
+		return err
+	}
+	if len(keyErr.Want) > 0 {
+		return hostKeyMismatch(hostname, fingerprint, path)
+	}
+	if !tofu {
+		return fmt.Errorf("host key %s for %s is not in %s", fingerprint, hostname, path)
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("known_hosts: %w", err)
+	}
+	defer f.Close()
+	if _, err := fmt.Fprintln(f, knownhosts.Line([]string{knownhosts.Normalize(hostname)}, key)); err != nil {
+		return fmt.Errorf("known_hosts: %w", err)
+	}
+	logger.Get().Warn("ssh_host_key_recorded",
+		zap.String("host", hostname),
+		zap.String("fingerprint", fingerprint),
+		zap.String("known_hosts", path),
+	)
+	return nil
+}
+
+// pinnedHostKey accepts only the key with the given SHA256 fingerprint,
+// with or without its SHA256: prefix.
+func pinnedHostKey(want string) ssh.HostKeyCallback {
+	want = strings.TrimPrefix(want, "SHA256:")
+	return func(hostname string, _ net.Addr, key ssh.PublicKey) error {
+		fingerprint := ssh.FingerprintSHA256(key)
+		if strings.TrimPrefix(fingerprint, "SHA256:") == want {
+			return nil
+		}
+		return hostKeyMismatch(hostname, fingerprint, "host_key_fingerprint")
+	}
+}
+
+// hostKeyMismatch is logged at error level: a changed key may mean someone
+// is in the middle of the connection.
+func hostKeyMismatch(hostname, fingerprint, expected string) error {
+	logger.Get().Error("ssh_host_key_mismatch",
+		zap.String("host", hostname),
+		zap.String("fingerprint", fingerprint),
+		zap.String("expected_from", expected),
+	)
+	return fmt.Errorf("host key mismatch for %s: got %s, which does not match %s", hostname, fingerprint, expected)
+}
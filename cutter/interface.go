@@ -9,25 +9,87 @@ type Cutter interface {
 }
 
 type CutResult struct {
+	CutID     string
 	Target    string
 	Action    string
 	Success   bool
+	DryRun    bool
+	Outcome   string
 	Error     error
 	LatencyMs int64
+	Details   map[string]interface{}
+	// Output is what the cut's command printed, if it ran one.
+	Output string
+	// Chain lists every strategy tried for the cut, ending with this one.
+	Chain []ChainLink
+	// Escalation is set when a later strategy than the selected one
+	// produced this result.
+	Escalation *Escalation
+	// Duplicate marks the result of an earlier identical request, returned
+	// instead of cutting again.
+	Duplicate bool
+}
+
+// ChainLink is one strategy attempt in a fallback/escalation chain. Via is
+// empty for the first attempt, otherwise on_failure, escalate_to, or
+// threshold.
+type ChainLink struct {
+	CutID     string `json:"cut_id,omitempty"`
+	Action    string `json:"action"`
+	Via       string `json:"via,omitempty"`
+	Success   bool   `json:"success"`
+	Error     string `json:"error,omitempty"`
+	LatencyMs int64  `json:"latency_ms"`
+}
+
+// Escalation tells the caller the strategy its reading selected failed and
+// how Atropos moved on: Type is "fallback" (on_failure) or "escalation".
+type Escalation struct {
+	Type              string  `json:"type"`
+	OriginalAction    string  `json:"original_action"`
+	OriginalThreshold float64 `json:"original_threshold"`
+	OriginalCritical  bool    `json:"original_critical,omitempty"`
+	OriginalCutID     string  `json:"original_cut_id,omitempty"`
+	OriginalError     string  `json:"original_error,omitempty"`
+	FinalAction       string  `json:"final_action"`
+	FinalError        string  `json:"final_error,omitempty"`
+	Steps             int     `json:"steps"`
 }
 
 type Registry struct {
 	cutters []Cutter
 }
 
-func NewRegistry() *Registry {
-	return &Registry{
-		cutters: []Cutter{
-			NewDockerCutter(),
-			NewNetworkCutter(),
-			NewVBoxCutter(),
-		},
+// NewRegistry returns the built-in cutters named in enabled, or all of them
+// but local when enabled is empty. Unknown names are ignored; the policy
+// rejects them. The local cutter runs commands on this host, so it is only
+// registered when enabled names it.
+func NewRegistry(enabled ...string) *Registry {
+	network := NewNetworkCutter()
+	builtin := []Cutter{
+		NewDockerCutter(),
+		network,
+		NewLBCutter(network),
+		NewSystemdCutter(network),
+		NewVBoxCutter(),
+		NewVMwareCutter(),
+		NewWinRMCutter(),
+		NewDNSCutter(),
+		NewSSMCutter(),
+		NewWebhookCutter(),
 	}
+	if len(enabled) == 0 {
+		return &Registry{cutters: builtin}
+	}
+	r := &Registry{}
+	for _, c := range append(builtin, NewLocalCutter()) {
+		for _, name := range enabled {
+			if c.Name() == name {
+				r.cutters = append(r.cutters, c)
+			}
+		}
+	}
+	return r
 }
 
 func (r *Registry) FindCutter(action string) (Cutter, bool) {
@@ -39,6 +101,21 @@ func (r *Registry) FindCutter(action string) (Cutter, bool) {
 	return nil, false
 }
 
+// Get returns the cutter with the given name.
+func (r *Registry) Get(name string) (Cutter, bool) {
+	for _, c := range r.cutters {
+		if c.Name() == name {
+			return c, true
+		}
+	}
+	return nil, false
+}
+
+// Cutters returns the registered cutters in the order they are consulted.
+func (r *Registry) Cutters() []Cutter {
+	return append([]Cutter(nil), r.cutters...)
+}
+
 func (r *Registry) Register(c Cutter) {
 	r.cutters = append(r.cutters, c)
 }
@@ -0,0 +1,298 @@
+package cutter
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"atropos/internal/logger"
+)
+
+// net_isolate cuts a host off the network except for the management_cidrs
+// allowlist and loopback, with nftables (the default) or iptables as the
+// firewall param says. Every rule carries isolationMarker, so
+// net_unisolate removes those and nothing else.
+
+const (
+	isolationMarker = "atropos-isolate"
+	// nftables rules live in a table of their own.
+	isolationTable = "inet atropos_isolate"
+	// iptables rules live in chains of their own, jumped to from the
+	// built-in ones.
+	isolationChainIn  = "ATROPOS-ISOLATE-IN"
+	isolationChainOut = "ATROPOS-ISOLATE-OUT"
+
+	FirewallNftables = "nftables"
+	FirewallIptables = "iptables"
+)
+
+func (n *NetworkCutter) isolate(ctx context.Context, target, user, host, port string, params map[string]string) error {
+	firewall, err := isolationFirewall(params)
+	if err != nil {
+		return err
+	}
+	v4, v6, err := parseManagementCIDRs(params["management_cidrs"])
+	if err != nil {
+		return err
+	}
+
+	logger.Get().Info("net_isolate",
+		zap.String("target", target),
+		zap.String("host", host),
+		zap.String("firewall", firewall),
+		zap.String("management_cidrs", params["management_cidrs"]),
+	)
+
+	// Refuse before any rule goes in if this very connection would be cut.
+	output, _, err := n.run(ctx, user, host, port, params, `echo "$SSH_CLIENT"`)
+	if err != nil {
+		return err
+	}
+	if err := checkManagementSource(output, append(v4, v6...)); err != nil {
+		return err
+	}
+
+	var script string
+	if firewall == FirewallNftables {
+		script = nftIsolateScript(v4, v6)
+	} else {
+		script = iptablesIsolateScript(v4, v6)
+	}
+	if err := n.runFirewall(ctx, user, host, port, params, "apply", script); err != nil {
+		return err
+	}
+
+	count, err := n.countIsolationRules(ctx, user, host, port, params, firewall)
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return fmt.Errorf("isolation rules not found after applying them")
+	}
+	RecordDetail(ctx, "firewall", firewall)
+	RecordDetail(ctx, "isolation_rules", count)
+	return nil
+}
+
+func (n *NetworkCutter) unisolate(ctx context.Context, target, user, host, port string, params map[string]string) error {
+	firewall, err := isolationFirewall(params)
+	if err != nil {
+		return err
+	}
+
+	logger.Get().Info("net_unisolate",
+		zap.String("target", target),
+		zap.String("host", host),
+		zap.String("firewall", firewall),
+	)
+
+	var script string
+	if firewall == FirewallNftables {
+		script = nftUnisolateScript()
+	} else {
+		script = iptablesUnisolateScript()
+	}
+	if err := n.runFirewall(ctx, user, host, port, params, "remove", script); err != nil {
+		return err
+	}
+
+	count, err := n.countIsolationRules(ctx, user, host, port, params, firewall)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return fmt.Errorf("%d isolation rules still present after removing them", count)
+	}
+	RecordDetail(ctx, "firewall", firewall)
+	return nil
+}
+
+// runFirewall runs script, recording its output, and fails on a non-zero
+// exit.
+func (n *NetworkCutter) runFirewall(ctx context.Context, user, host, port string, params map[string]string, step, script string) error {
+	output, exitCode, err := n.run(ctx, user, host, port, params, script)
+	if err != nil {
+		return err
+	}
+	RecordOutput(ctx, truncateOutput(output, outputMaxBytes(params)))
+	RecordDetail(ctx, "exit_code", exitCode)
+	if exitCode != 0 {
+		return stepError(ctx, step, fmt.Errorf("firewall %s exited %d: %s", step, exitCode, strings.TrimSpace(output)))
+	}
+	return nil
+}
+
+// countIsolationRules lists the firewall's rules and counts those marked
+// as Atropos's.
+func (n *NetworkCutter) countIsolationRules(ctx context.Context, user, host, port string, params map[string]string, firewall string) (int, error) {
+	list := "nft list ruleset"
+	if firewall == FirewallIptables {
+		list = "iptables -S; ip6tables -S"
+	}
+	output, exitCode, err := n.run(ctx, user, host, port, params, list)
+	if err != nil {
+		return 0, err
+	}
+	if exitCode != 0 {
+		return 0, stepError(ctx, "verify", fmt.Errorf("%s exited %d: %s", list, exitCode, strings.TrimSpace(output)))
+	}
+	return strings.Count(output, isolationMarker), nil
+}
+
+func isolationFirewall(params map[string]string) (string, error) {
+	switch fw := params["firewall"]; fw {
+	case "", FirewallNftables:
+		return FirewallNftables, nil
+	case FirewallIptables:
+		return fw, nil
+	default:
+		return "", fmt.Errorf("firewall must be %q or %q", FirewallNftables, FirewallIptables)
+	}
+}
+
+// parseManagementCIDRs splits a comma-separated allowlist into IPv4 and
+// IPv6 networks. A bare address stands for itself alone.
+func parseManagementCIDRs(s string) (v4, v6 []*net.IPNet, err error) {
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			if ip := net.ParseIP(entry); ip != nil && ip.To4() != nil {
+				entry += "/32"
+			} else {
+				entry += "/128"
+			}
+		}
+		_, cidr, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, nil, fmt.Errorf("management_cidrs: %w", err)
+		}
+		if cidr.IP.To4() != nil {
+			v4 = append(v4, cidr)
+		} else {
+			v6 = append(v6, cidr)
+		}
+	}
+	if len(v4)+len(v6) == 0 {
+		return nil, nil, fmt.Errorf("net_isolate requires management_cidrs, or Atropos would lock itself out")
+	}
+	return v4, v6, nil
+}
+
+// checkManagementSource reads the client address from $SSH_CLIENT, as the
+// host sees it past any NAT, and makes sure the allowlist covers it.
+func checkManagementSource(sshClient string, cidrs []*net.IPNet) error {
+	fields := strings.Fields(sshClient)
+	if len(fields) == 0 {
+		return fmt.Errorf("host did not report the SSH client address; refusing to isolate it")
+	}
+	ip := net.ParseIP(fields[0])
+	if ip == nil {
+		return fmt.Errorf("host reported SSH client address %q; refusing to isolate it", fields[0])
+	}
+	for _, cidr := range cidrs {
+		if cidr.Contains(ip) {
+			return nil
+		}
+	}
+	return fmt.Errorf("management_cidrs does not cover %s, the address Atropos connects from; isolating would lock it out", ip)
+}
+
+func cidrList(cidrs []*net.IPNet) string {
+	parts := make([]string, len(cidrs))
+	for i, c := range cidrs {
+		parts[i] = c.String()
+	}
+	return strings.Join(parts, ", ")
+}
+
+// nftIsolateScript replaces the isolation table in one transaction, so a
+// second net_isolate updates the allowlist instead of failing.
+func nftIsolateScript(v4, v6 []*net.IPNet) string {
+	comment := fmt.Sprintf(" comment %q", isolationMarker)
+	var b strings.Builder
+	b.WriteString("nft -f - <<'ATROPOS'\n")
+	b.WriteString("table " + isolationTable + " {}\n")
+	b.WriteString("delete table " + isolationTable + "\n")
+	b.WriteString("table " + isolationTable + " {\n")
+	for _, chain := range []struct{ name, hook, iface, addr string }{
+		{"input", "input", "iif", "saddr"},
+		{"output", "output", "oif", "daddr"},
+	} {
+		b.WriteString("  chain " + chain.name + " {\n")
+		b.WriteString("    type filter hook " + chain.hook + " priority -10; policy drop;\n")
+		b.WriteString("    " + chain.iface + ` "lo" accept` + comment + "\n")
+		if len(v4) > 0 {
+			b.WriteString("    ip " + chain.addr + " { " + cidrList(v4) + " } accept" + comment + "\n")
+		}
+		if len(v6) > 0 {
+			b.WriteString("    ip6 " + chain.addr + " { " + cidrList(v6) + " } accept" + comment + "\n")
+		}
+		b.WriteString("  }\n")
+	}
+	b.WriteString("  chain forward {\n")
+	b.WriteString("    type filter hook forward priority -10; policy drop;\n")
+	b.WriteString("    counter drop" + comment + "\n")
+	b.WriteString("  }\n")
+	b.WriteString("}\n")
+	b.WriteString("ATROPOS\n")
+	return b.String()
+}
+
+func nftUnisolateScript() string {
+	return "nft -f - <<'ATROPOS'\n" +
+		"table " + isolationTable + " {}\n" +
+		"delete table " + isolationTable + "\n" +
+		"ATROPOS\n"
+}
+
+// iptablesIsolateScript fills Atropos's chains before jumping to them, so
+// the allowlist is in place by the time anything is dropped.
+func iptablesIsolateScript(v4, v6 []*net.IPNet) string {
+	mark := "-m comment --comment " + isolationMarker
+	var b strings.Builder
+	b.WriteString("set -e\n")
+	b.WriteString(iptablesUnisolateScript())
+	for _, fw := range []struct {
+		bin   string
+		cidrs []*net.IPNet
+	}{{"iptables", v4}, {"ip6tables", v6}} {
+		for _, chain := range []struct{ name, iface, addr string }{
+			{isolationChainIn, "-i", "-s"},
+			{isolationChainOut, "-o", "-d"},
+		} {
+			fmt.Fprintf(&b, "%s -N %s\n", fw.bin, chain.name)
+			fmt.Fprintf(&b, "%s -A %s %s lo %s -j RETURN\n", fw.bin, chain.name, chain.iface, mark)
+			for _, cidr := range fw.cidrs {
+				fmt.Fprintf(&b, "%s -A %s %s %s %s -j RETURN\n", fw.bin, chain.name, chain.addr, cidr, mark)
+			}
+			fmt.Fprintf(&b, "%s -A %s %s -j DROP\n", fw.bin, chain.name, mark)
+		}
+		fmt.Fprintf(&b, "%s -I INPUT 1 %s -j %s\n", fw.bin, mark, isolationChainIn)
+		fmt.Fprintf(&b, "%s -I OUTPUT 1 %s -j %s\n", fw.bin, mark, isolationChainOut)
+		fmt.Fprintf(&b, "%s -I FORWARD 1 %s -j DROP\n", fw.bin, mark)
+	}
+	return b.String()
+}
+
+// iptablesUnisolateScript deletes the marked jumps and Atropos's own
+// chains, leaving every other rule alone. It succeeds when there is
+// nothing to remove.
+func iptablesUnisolateScript() string {
+	mark := "-m comment --comment " + isolationMarker
+	var b strings.Builder
+	for _, bin := range []string{"iptables", "ip6tables"} {
+		fmt.Fprintf(&b, "while %s -D INPUT %s -j %s 2>/dev/null; do :; done\n", bin, mark, isolationChainIn)
+		fmt.Fprintf(&b, "while %s -D OUTPUT %s -j %s 2>/dev/null; do :; done\n", bin, mark, isolationChainOut)
+		fmt.Fprintf(&b, "while %s -D FORWARD %s -j DROP 2>/dev/null; do :; done\n", bin, mark)
+		for _, chain := range []string{isolationChainIn, isolationChainOut} {
+			fmt.Fprintf(&b, "if %s -L %s -n >/dev/null 2>&1; then %s -F %s; %s -X %s; fi\n", bin, chain, bin, chain, bin, chain)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,194 @@
+package cutter
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"atropos/internal/logger"
+)
+
+const (
+	LBHAProxy = "haproxy"
+	LBNginx   = "nginx"
+
+	lbDrainPolling = time.Second
+	// lbDrainReserve is kept back from the cut's deadline when waiting for
+	// sessions, to report how many are left.
+	lbDrainReserve = time.Second
+)
+
+// LBCutter takes a node out of its load balancer's rotation and puts it
+// back: through the HAProxy runtime API, or by marking the node's server
+// down in an nginx upstream file over SSH.
+type LBCutter struct {
+	network *NetworkCutter
+}
+
+// NewLBCutter reaches nginx hosts through network's SSH connections.
+func NewLBCutter(network *NetworkCutter) *LBCutter {
+	return &LBCutter{network: network}
+}
+
+func (l *LBCutter) Name() string {
+	return "lb"
+}
+
+func (l *LBCutter) CanHandle(action string) bool {
+	return action == "lb_drain" || action == "lb_enable"
+}
+
+func (l *LBCutter) SupportedActions() []string {
+	return []string{"lb_drain", "lb_enable"}
+}
+
+func (l *LBCutter) Execute(ctx context.Context, target string, params map[string]string) error {
+	action := params["action"]
+	if !l.CanHandle(action) {
+		return fmt.Errorf("unsupported action: %s", action)
+	}
+	server := params["lb_server"]
+	if server == "" {
+		server = target
+	}
+
+	lbType := params["lb_type"]
+	if lbType == "" {
+		lbType = LBHAProxy
+	}
+	logger.Get().Info("lb_cut",
+		zap.String("target", target),
+		zap.String("action", action),
+		zap.String("lb_type", lbType),
+		zap.String("lb_server", server),
+	)
+
+	switch lbType {
+	case LBHAProxy:
+		return l.haproxy(ctx, action, server, params)
+	case LBNginx:
+		return l.nginx(ctx, action, server, params)
+	default:
+		return fmt.Errorf("lb_type must be %q or %q", LBHAProxy, LBNginx)
+	}
+}
+
+func (l *LBCutter) haproxy(ctx context.Context, action, server string, params map[string]string) error {
+	backend := params["lb_backend"]
+	if backend == "" {
+		return fmt.Errorf("haproxy requires lb_backend")
+	}
+	rt, err := newHAProxyRuntime(params["haproxy_socket"])
+	if err != nil {
+		return err
+	}
+	RecordDetail(ctx, "lb_server", backend+"/"+server)
+
+	if action == "lb_enable" {
+		if err := rt.setServerState(ctx, backend, server, "ready"); err != nil {
+			return stepError(ctx, "enable", fmt.Errorf("haproxy %s: %w", rt, err))
+		}
+		return nil
+	}
+
+	if err := rt.setServerState(ctx, backend, server, "drain"); err != nil {
+		return stepError(ctx, "drain", fmt.Errorf("haproxy %s: %w", rt, err))
+	}
+	wait, err := lbDrainWait(ctx, params)
+	if err != nil || wait <= 0 {
+		return err
+	}
+	start := time.Now()
+	left, err := rt.waitDrained(ctx, backend, server, wait)
+	RecordDetail(ctx, "drain_wait_ms", time.Since(start).Milliseconds())
+	if err != nil {
+		return stepError(ctx, "wait", fmt.Errorf("haproxy %s: %w", rt, err))
+	}
+	RecordDetail(ctx, "active_sessions", left)
+	if left > 0 {
+		return fmt.Errorf("%s/%s still has %d sessions after %s", backend, server, left, time.Since(start).Round(time.Second))
+	}
+	return nil
+}
+
+// lbDrainWait is lb_drain_wait_seconds, cut short to fit the deadline.
+func lbDrainWait(ctx context.Context, params map[string]string) (time.Duration, error) {
+	s := params["lb_drain_wait_seconds"]
+	if s == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("lb_drain_wait_seconds must be a whole number >= 0")
+	}
+	wait := time.Duration(n) * time.Second
+	if deadline, ok := ctx.Deadline(); ok {
+		if left := time.Until(deadline) - lbDrainReserve; left < wait {
+			wait = left
+		}
+	}
+	return wait, nil
+}
+
+// nginx marks server down in the upstream file on lb_host, or takes the
+// mark off, and reloads nginx. A config nginx -t rejects is put back as it
+// was.
+func (l *LBCutter) nginx(ctx context.Context, action, server string, params map[string]string) error {
+	host := params["lb_host"]
+	if host == "" {
+		return fmt.Errorf("nginx requires lb_host")
+	}
+	file := params["nginx_upstream_file"]
+	if file == "" {
+		return fmt.Errorf("nginx requires nginx_upstream_file")
+	}
+	user := params["lb_user"]
+	if user == "" {
+		user = "root"
+	}
+	port := params["lb_port"]
+	if port == "" {
+		port = "22"
+	}
+	RecordDetail(ctx, "lb_server", server)
+
+	script := nginxToggleScript(file, server, action == "lb_drain")
+	output, exitCode, err := l.network.run(ctx, user, host, port, params, script)
+	if err != nil {
+		return err
+	}
+	RecordOutput(ctx, truncateOutput(output, outputMaxBytes(params)))
+	RecordDetail(ctx, "exit_code", exitCode)
+	if exitCode != 0 {
+		return fmt.Errorf("nginx on %s exited %d: %s", host, exitCode, strings.TrimSpace(output))
+	}
+	return nil
+}
+
+// nginxToggleScript edits the server line for addr, e.g. 10.0.1.15:8080,
+// in file. Draining adds down before the line's semicolon; enabling takes
+// it away. Both leave a line already as wanted alone.
+func nginxToggleScript(file, addr string, drain bool) string {
+	quoted := strings.ReplaceAll(regexp.QuoteMeta(addr), "/", `\/`)
+	line := `^[[:space:]]*server[[:space:]]+` + quoted + `([[:space:]]|;)`
+	var edit string
+	if drain {
+		edit = `/` + line + `/{/[[:space:]]down([[:space:]]|;)/!s/;/ down;/;}`
+	} else {
+		edit = `/` + line + `/s/[[:space:]]+down([[:space:]]*;)/\1/`
+	}
+	f := shellQuote(file)
+	backup := shellQuote(file + ".atropos.bak")
+	return strings.Join([]string{
+		`grep -Eq ` + shellQuote(line) + ` ` + f + ` || { echo ` + shellQuote("server "+addr+" not found in "+file) + ` >&2; exit 3; }`,
+		`cp ` + f + ` ` + backup,
+		`sed -E -i ` + shellQuote(edit) + ` ` + f,
+		`if ! nginx -t; then cp ` + backup + ` ` + f + `; exit 1; fi`,
+		`nginx -s reload`,
+	}, "\n")
+}
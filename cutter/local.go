@@ -0,0 +1,178 @@
+package cutter
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"atropos/internal/logger"
+)
+
+// localOutputLimit caps how much of each of stdout and stderr is kept.
+const localOutputLimit = 64 << 10
+
+// LocalCutter runs local_exec commands on the Atropos host. The command is
+// split into arguments the way a shell splits words, quotes included, but
+// runs without a shell: no pipes, redirects, globs or variables.
+type LocalCutter struct{}
+
+func NewLocalCutter() *LocalCutter {
+	return &LocalCutter{}
+}
+
+func (l *LocalCutter) Name() string {
+	return "local"
+}
+
+func (l *LocalCutter) CanHandle(action string) bool {
+	return action == "local_exec"
+}
+
+func (l *LocalCutter) SupportedActions() []string {
+	return []string{"local_exec"}
+}
+
+// Execute runs local_exec only. A strategy's cutter field can route any
+// action here, so the action is checked again rather than trusted.
+func (l *LocalCutter) Execute(ctx context.Context, target string, params map[string]string) error {
+	if action := params["action"]; !l.CanHandle(action) {
+		return fmt.Errorf("local cutter only runs local_exec, not %q", action)
+	}
+	command := params["command"]
+	if command == "" {
+		return fmt.Errorf("local cutter requires command")
+	}
+	argv, err := splitCommand(command)
+	if err != nil {
+		return fmt.Errorf("command: %w", err)
+	}
+
+	logger.Get().Info("local_cut",
+		zap.String("target", target),
+		zap.String("command", command),
+	)
+
+	stdout := &cappedBuffer{limit: localOutputLimit}
+	stderr := &cappedBuffer{limit: localOutputLimit}
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	// A child that outlives the killed command would hold the pipes open.
+	cmd.WaitDelay = time.Second
+
+	err = cmd.Run()
+	RecordDetail(ctx, "stdout", stdout.String())
+	RecordDetail(ctx, "stderr", stderr.String())
+	if ctx.Err() != nil {
+		return stepError(ctx, "command", ctx.Err())
+	}
+	exitCode := 0
+	if err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			return fmt.Errorf("command failed: %w", err)
+		}
+		exitCode = exitErr.ExitCode()
+	}
+
+	RecordDetail(ctx, "exit_code", exitCode)
+	output := stdout.String() + stderr.String()
+	rule, evalErr := evaluateCommand(params, exitCode, output)
+	if rule != "" {
+		RecordDetail(ctx, "matched_rule", rule)
+	}
+	if evalErr != nil {
+		return fmt.Errorf("command failed: %w, output: %s", evalErr, output)
+	}
+	return nil
+}
+
+// splitCommand splits s into words on unquoted whitespace. Single quotes
+// keep everything literal; within double quotes and outside quotes a
+// backslash escapes the next character.
+func splitCommand(s string) ([]string, error) {
+	var (
+		args    []string
+		word    strings.Builder
+		inWord  bool
+		quote   rune
+		escaped bool
+	)
+	for _, r := range s {
+		switch {
+		case escaped:
+			word.WriteRune(r)
+			escaped = false
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '\\':
+			escaped, inWord = true, true
+		case quote == '"':
+			if r == '"' {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote, inWord = r, true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inWord {
+				args = append(args, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if escaped {
+		return nil, fmt.Errorf("trailing backslash")
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote", quote)
+	}
+	if inWord {
+		args = append(args, word.String())
+	}
+	if len(args) == 0 {
+		return nil, fmt.Errorf("empty command")
+	}
+	return args, nil
+}
+
+// cappedBuffer keeps the first limit bytes written and drops the rest, so a
+// chatty command can't grow a history record without bound.
+type cappedBuffer struct {
+	buf     bytes.Buffer
+	limit   int
+	dropped int
+}
+
+func (c *cappedBuffer) Write(p []byte) (int, error) {
+	room := c.limit - c.buf.Len()
+	if room < 0 {
+		room = 0
+	}
+	if len(p) > room {
+		c.buf.Write(p[:room])
+		c.dropped += len(p) - room
+	} else {
+		c.buf.Write(p)
+	}
+	return len(p), nil
+}
+
+func (c *cappedBuffer) String() string {
+	return c.buf.String()
+}
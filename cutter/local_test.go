@@ -0,0 +1,47 @@
+package cutter
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLocalCutterRefusesOtherActions(t *testing.T) {
+	marker := filepath.Join(t.TempDir(), "ran")
+	for _, action := range []string{"ssh_command", "docker_stop_all", ""} {
+		err := NewLocalCutter().Execute(context.Background(), "edge", map[string]string{
+			"action":  action,
+			"command": "touch " + marker,
+		})
+		if err == nil {
+			t.Errorf("Execute(%q) succeeded, want an error", action)
+		}
+		if _, statErr := os.Stat(marker); statErr == nil {
+			t.Fatalf("Execute(%q) ran the command", action)
+		}
+	}
+}
+
+func TestLocalCutterRunsLocalExec(t *testing.T) {
+	marker := filepath.Join(t.TempDir(), "ran")
+	err := NewLocalCutter().Execute(context.Background(), "edge", map[string]string{
+		"action":  "local_exec",
+		"command": "touch " + marker,
+	})
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if _, err := os.Stat(marker); err != nil {
+		t.Fatalf("command did not run: %v", err)
+	}
+}
+
+func TestDefaultRegistryHasNoLocalCutter(t *testing.T) {
+	if _, ok := NewRegistry().Get("local"); ok {
+		t.Fatal("default registry has the local cutter")
+	}
+	if _, ok := NewRegistry("docker", "local").Get("local"); !ok {
+		t.Fatal("registry with local enabled has no local cutter")
+	}
+}
@@ -2,11 +2,14 @@ package cutter
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net"
 	"os"
+	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"go.uber.org/zap"
 	"golang.org/x/crypto/ssh"
@@ -15,10 +18,12 @@ import (
 	"atropos/internal/logger"
 )
 
-type NetworkCutter struct{}
+type NetworkCutter struct {
+	pool *sshPool
+}
 
 func NewNetworkCutter() *NetworkCutter {
-	return &NetworkCutter{}
+	return &NetworkCutter{pool: newSSHPool()}
 }
 
 func (n *NetworkCutter) Name() string {
@@ -26,7 +31,11 @@ func (n *NetworkCutter) Name() string {
 }
 
 func (n *NetworkCutter) CanHandle(action string) bool {
-	return strings.HasPrefix(action, "ssh_")
+	return strings.HasPrefix(action, "ssh_") || action == "net_isolate" || action == "net_unisolate"
+}
+
+func (n *NetworkCutter) SupportedActions() []string {
+	return []string{"ssh_*", "net_isolate", "net_unisolate"}
 }
 
 func (n *NetworkCutter) Execute(ctx context.Context, target string, params map[string]string) error {
@@ -39,11 +48,18 @@ func (n *NetworkCutter) Execute(ctx context.Context, target string, params map[s
 	if port == "" {
 		port = "22"
 	}
-	command := params["command"]
-
 	if host == "" {
 		return fmt.Errorf("network cutter requires host for target %s", target)
 	}
+
+	switch params["action"] {
+	case "net_isolate":
+		return n.isolate(ctx, target, user, host, port, params)
+	case "net_unisolate":
+		return n.unisolate(ctx, target, user, host, port, params)
+	}
+
+	command := params["command"]
 	if command == "" {
 		return fmt.Errorf("network cutter requires command")
 	}
@@ -54,54 +70,239 @@ func (n *NetworkCutter) Execute(ctx context.Context, target string, params map[s
 		zap.String("command", command),
 	)
 
-	client, err := n.connect(user, host, port)
+	output, exitCode, err := n.run(ctx, user, host, port, params, command)
 	if err != nil {
-		return fmt.Errorf("ssh connect: %w", err)
+		return err
 	}
-	defer client.Close()
+	RecordOutput(ctx, truncateOutput(output, outputMaxBytes(params)))
+	RecordDetail(ctx, "exit_code", exitCode)
+	rule, evalErr := evaluateCommand(params, exitCode, output)
+	if rule != "" {
+		RecordDetail(ctx, "matched_rule", rule)
+	}
+	if evalErr != nil {
+		return fmt.Errorf("command failed: %w, output: %s", evalErr, output)
+	}
+	return nil
+}
 
-	session, err := client.NewSession()
+// HealthCheck checks ssh-agent is reachable and holds a key. Nodes with
+// ssh_key_file don't need it.
+func (n *NetworkCutter) HealthCheck(ctx context.Context) error {
+	sock := os.Getenv("SSH_AUTH_SOCK")
+	if sock == "" {
+		return fmt.Errorf("SSH_AUTH_SOCK is not set; no ssh-agent")
+	}
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "unix", sock)
 	if err != nil {
-		return fmt.Errorf("ssh session: %w", err)
+		return fmt.Errorf("ssh-agent: %w", err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
 	}
-	defer session.Close()
+	keys, err := agent.NewClient(conn).List()
+	if err != nil {
+		return fmt.Errorf("ssh-agent: %w", err)
+	}
+	if len(keys) == 0 {
+		return fmt.Errorf("ssh-agent holds no keys")
+	}
+	return nil
+}
 
-	doneCh := make(chan error, 1)
+// Plan checks the host can be reached and logged in to, host key included,
+// by opening a session without running anything.
+func (n *NetworkCutter) Plan(ctx context.Context, target string, params map[string]string) (PlanResult, error) {
+	host, user, port := params["host"], params["user"], params["port"]
+	if user == "" {
+		user = "root"
+	}
+	if port == "" {
+		port = "22"
+	}
+	if host == "" {
+		return PlanResult{}, fmt.Errorf("network cutter requires host for target %s", target)
+	}
+	action := params["action"]
+	if strings.HasPrefix(action, "ssh_") && params["command"] == "" {
+		return PlanResult{}, fmt.Errorf("network cutter requires command")
+	}
+
+	session, release, err := n.session(ctx, user, host, port, params)
+	if err != nil {
+		return PlanResult{}, err
+	}
+	session.Close()
+	release(false)
+
+	addr := user + "@" + net.JoinHostPort(host, port)
+	details := map[string]interface{}{"ssh": addr}
+	if command := params["command"]; command != "" && strings.HasPrefix(action, "ssh_") {
+		details["command"] = command
+	}
+	return PlanResult{
+		Summary: fmt.Sprintf("%s over SSH to %s", action, addr),
+		Details: details,
+	}, nil
+}
+
+// run runs command on host and returns its combined output and exit code.
+// The error is for a command that couldn't run to the end, not for a
+// non-zero exit.
+func (n *NetworkCutter) run(ctx context.Context, user, host, port string, params map[string]string, command string) (string, int, error) {
+	session, release, err := n.session(ctx, user, host, port, params)
+	if err != nil {
+		return "", 0, err
+	}
+
+	type result struct {
+		output   string
+		exitCode int
+		err      error
+		// broken marks a command that failed for want of a working
+		// connection.
+		broken bool
+	}
+	doneCh := make(chan result, 1)
 	go func() {
 		output, err := session.CombinedOutput(command)
 		if err != nil {
-			doneCh <- fmt.Errorf("command failed: %w, output: %s", err, string(output))
+			var exitErr *ssh.ExitError
+			if !errors.As(err, &exitErr) {
+				doneCh <- result{err: fmt.Errorf("command failed: %w, output: %s", err, string(output)), broken: true}
+				return
+			}
+			doneCh <- result{output: string(output), exitCode: exitErr.ExitStatus()}
 			return
 		}
-		doneCh <- nil
+		doneCh <- result{output: string(output)}
 	}()
 
 	select {
 	case <-ctx.Done():
-		return ctx.Err()
-	case err := <-doneCh:
-		return err
+		_ = session.Signal(ssh.SIGKILL)
+		session.Close()
+		// A connection that can't even close the session is dropped,
+		// which ends the command.
+		select {
+		case <-doneCh:
+			release(false)
+		case <-time.After(sshKeepaliveTimeout):
+			release(true)
+			<-doneCh
+		}
+		return "", 0, stepError(ctx, "command", ctx.Err())
+	case res := <-doneCh:
+		session.Close()
+		release(res.broken)
+		return res.output, res.exitCode, res.err
+	}
+}
+
+// session opens a session on a pooled client. A pooled client that can't
+// open one is dropped and the session tried once more on a new connection.
+func (n *NetworkCutter) session(ctx context.Context, user, host, port string, params map[string]string) (*ssh.Session, func(bool), error) {
+	for {
+		client, release, reused, err := n.pool.acquire(ctx, user, host, port, params)
+		if err != nil {
+			return nil, nil, stepError(ctx, "connect", fmt.Errorf("ssh connect: %w", err))
+		}
+		session, err := client.NewSession()
+		if err == nil {
+			RecordDetail(ctx, "ssh_connection_reused", reused)
+			return session, release, nil
+		}
+		release(true)
+		if !reused {
+			return nil, nil, stepError(ctx, "session", fmt.Errorf("ssh session: %w", err))
+		}
+		logger.Get().Warn("ssh_pooled_client_dead",
+			zap.String("host", host),
+			zap.Error(err),
+		)
+	}
+}
+
+const defaultOutputMaxBytes = 16 << 10
+
+// outputMaxBytes is the output_max_bytes param, default 16 KiB.
+func outputMaxBytes(params map[string]string) int {
+	if n, err := strconv.Atoi(params["output_max_bytes"]); err == nil && n > 0 {
+		return n
+	}
+	return defaultOutputMaxBytes
+}
+
+// truncateOutput keeps the first max bytes of output, cut back to a whole
+// UTF-8 character, and says how much was dropped.
+func truncateOutput(output string, max int) string {
+	if len(output) <= max {
+		return output
 	}
+	cut := max
+	for cut > 0 && !utf8.RuneStart(output[cut]) {
+		cut--
+	}
+	return output[:cut] + fmt.Sprintf("\n[truncated %d bytes]", len(output)-cut)
 }
 
-func (n *NetworkCutter) connect(user, host, port string) (*ssh.Client, error) {
+// dialSSH connects with ssh_key_file, if set, and the keys in ssh-agent,
+// checking the host key as params ask (see hostKeyCallback).
+func dialSSH(ctx context.Context, user, host, port string, params map[string]string) (*ssh.Client, error) {
+	hostKey, err := hostKeyCallback(params)
+	if err != nil {
+		return nil, err
+	}
+
 	authMethods := []ssh.AuthMethod{}
 
+	if path := params["ssh_key_file"]; path != "" {
+		pem, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("ssh_key_file: %w", err)
+		}
+		signer, err := ssh.ParsePrivateKey(pem)
+		if err != nil {
+			return nil, fmt.Errorf("ssh_key_file %s: %w", path, err)
+		}
+		authMethods = append(authMethods, ssh.PublicKeys(signer))
+	}
+
 	if agentConn, err := net.Dial("unix", os.Getenv("SSH_AUTH_SOCK")); err == nil {
 		agentClient := agent.NewClient(agentConn)
 		authMethods = append(authMethods, ssh.PublicKeysCallback(agentClient.Signers))
 	}
 
 	if len(authMethods) == 0 {
-		return nil, fmt.Errorf("no SSH auth available; start ssh-agent")
+		return nil, fmt.Errorf("no SSH auth available; set ssh_key_file or start ssh-agent")
 	}
 
 	config := &ssh.ClientConfig{
 		User:            user,
 		Auth:            authMethods,
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		HostKeyCallback: hostKey,
 		Timeout:         10 * time.Second,
 	}
 
-	return ssh.Dial("tcp", net.JoinHostPort(host, port), config)
+	addr := net.JoinHostPort(host, port)
+	dialer := &net.Dialer{Timeout: config.Timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	sshConn, chans, reqs, err := ssh.NewClientConn(conn, addr, config)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	conn.SetDeadline(time.Time{})
+
+	return ssh.NewClient(sshConn, chans, reqs), nil
 }
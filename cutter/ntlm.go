@@ -0,0 +1,172 @@
+package cutter
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"strings"
+	"time"
+	"unicode/utf16"
+
+	"golang.org/x/crypto/md4"
+)
+
+// The WinRM cutter authenticates with NTLMv2 (MS-NLMP), the scheme Windows
+// accepts from hosts outside the domain. Only authentication is needed:
+// HTTPS protects the messages, so no session keys are derived.
+
+const (
+	ntlmNegotiateUnicode          = 0x00000001
+	ntlmRequestTarget             = 0x00000004
+	ntlmNegotiateNTLM             = 0x00000200
+	ntlmNegotiateAlwaysSign       = 0x00008000
+	ntlmNegotiateExtendedSecurity = 0x00080000
+	ntlmNegotiate128              = 0x20000000
+	ntlmNegotiate56               = 0x80000000
+
+	ntlmFlags = ntlmNegotiateUnicode | ntlmRequestTarget | ntlmNegotiateNTLM |
+		ntlmNegotiateAlwaysSign | ntlmNegotiateExtendedSecurity | ntlmNegotiate128 | ntlmNegotiate56
+
+	ntlmAvEOL       = 0
+	ntlmAvTimestamp = 7
+)
+
+var ntlmSignature = []byte("NTLMSSP\x00")
+
+// ntlmNegotiate is the first message, offering ntlmFlags.
+func ntlmNegotiate() []byte {
+	msg := make([]byte, 32)
+	copy(msg, ntlmSignature)
+	binary.LittleEndian.PutUint32(msg[8:], 1)
+	binary.LittleEndian.PutUint32(msg[12:], ntlmFlags)
+	return msg
+}
+
+type ntlmChallenge struct {
+	flags      uint32
+	challenge  []byte
+	targetInfo []byte
+}
+
+func parseNTLMChallenge(msg []byte) (*ntlmChallenge, error) {
+	if len(msg) < 48 || !bytes.Equal(msg[:8], ntlmSignature) || binary.LittleEndian.Uint32(msg[8:]) != 2 {
+		return nil, errors.New("not an NTLM challenge")
+	}
+	info, err := ntlmField(msg, 40)
+	if err != nil {
+		return nil, err
+	}
+	return &ntlmChallenge{
+		flags:      binary.LittleEndian.Uint32(msg[20:]),
+		challenge:  msg[24:32],
+		targetInfo: info,
+	}, nil
+}
+
+// ntlmField reads the payload a security buffer at off points to.
+func ntlmField(msg []byte, off int) ([]byte, error) {
+	n := int(binary.LittleEndian.Uint16(msg[off:]))
+	start := int(binary.LittleEndian.Uint32(msg[off+4:]))
+	if start+n > len(msg) {
+		return nil, errors.New("NTLM challenge field out of range")
+	}
+	return msg[start : start+n], nil
+}
+
+// ntlmAuthenticate answers c for user, given as DOMAIN\user, user@domain
+// or a bare local user name.
+func ntlmAuthenticate(c *ntlmChallenge, user, password string) ([]byte, error) {
+	domain := ""
+	if i := strings.IndexByte(user, '\\'); i >= 0 {
+		domain, user = user[:i], user[i+1:]
+	}
+
+	clientChallenge := make([]byte, 8)
+	if _, err := rand.Read(clientChallenge); err != nil {
+		return nil, err
+	}
+	ntResponse, lmResponse := ntlmv2Responses(ntowfv2(user, domain, password), c.challenge, clientChallenge, ntlmTimestamp(c.targetInfo), c.targetInfo)
+
+	fields := [][]byte{lmResponse, ntResponse, utf16LE(domain), utf16LE(user), nil, nil}
+	const header = 64
+	msg := make([]byte, header)
+	copy(msg, ntlmSignature)
+	binary.LittleEndian.PutUint32(msg[8:], 3)
+	offset := header
+	for i, f := range fields {
+		at := 12 + 8*i
+		binary.LittleEndian.PutUint16(msg[at:], uint16(len(f)))
+		binary.LittleEndian.PutUint16(msg[at+2:], uint16(len(f)))
+		binary.LittleEndian.PutUint32(msg[at+4:], uint32(offset))
+		offset += len(f)
+	}
+	binary.LittleEndian.PutUint32(msg[60:], c.flags&ntlmFlags)
+	for _, f := range fields {
+		msg = append(msg, f...)
+	}
+	return msg, nil
+}
+
+// ntowfv2 is the NTLMv2 key of user in domain.
+func ntowfv2(user, domain, password string) []byte {
+	md := md4.New()
+	md.Write(utf16LE(password))
+	return hmacMD5(md.Sum(nil), utf16LE(strings.ToUpper(user)+domain))
+}
+
+// ntlmv2Responses are the NT and LM responses to the server's challenge.
+func ntlmv2Responses(ntowf, serverChallenge, clientChallenge, timestamp, targetInfo []byte) (nt, lm []byte) {
+	// The NTLMv2 client challenge blob.
+	var blob bytes.Buffer
+	blob.Write([]byte{1, 1, 0, 0, 0, 0, 0, 0})
+	blob.Write(timestamp)
+	blob.Write(clientChallenge)
+	blob.Write([]byte{0, 0, 0, 0})
+	blob.Write(targetInfo)
+	blob.Write([]byte{0, 0, 0, 0})
+
+	proof := hmacMD5(ntowf, serverChallenge, blob.Bytes())
+	nt = append(proof, blob.Bytes()...)
+	lm = append(hmacMD5(ntowf, serverChallenge, clientChallenge), clientChallenge...)
+	return nt, lm
+}
+
+// ntlmTimestamp is the server's time from its target info, or the local
+// time as a Windows FILETIME when the server sent none.
+func ntlmTimestamp(info []byte) []byte {
+	for len(info) >= 4 {
+		id := binary.LittleEndian.Uint16(info)
+		n := int(binary.LittleEndian.Uint16(info[2:]))
+		if id == ntlmAvEOL || 4+n > len(info) {
+			break
+		}
+		if id == ntlmAvTimestamp && n == 8 {
+			return info[4:12]
+		}
+		info = info[4+n:]
+	}
+	ts := make([]byte, 8)
+	// 100ns intervals since 1601-01-01.
+	binary.LittleEndian.PutUint64(ts, uint64(time.Now().UnixNano()/100+116444736000000000))
+	return ts
+}
+
+func hmacMD5(key []byte, data ...[]byte) []byte {
+	h := hmac.New(md5.New, key)
+	for _, d := range data {
+		h.Write(d)
+	}
+	return h.Sum(nil)
+}
+
+func utf16LE(s string) []byte {
+	units := utf16.Encode([]rune(s))
+	b := make([]byte, 2*len(units))
+	for i, u := range units {
+		binary.LittleEndian.PutUint16(b[2*i:], u)
+	}
+	return b
+}
@@ -0,0 +1,129 @@
+package cutter
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+// The values below are the NTLMv2 example of MS-NLMP section 4.2.4: user
+// "User" in domain "Domain" with password "Password", talking to "Server".
+
+func unhex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(strings.ReplaceAll(s, " ", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// specChallenge is the CHALLENGE_MESSAGE of section 4.2.4.3.
+const specChallenge = "4e544c4d53535000 02000000 0c000c00 38000000 33828ae2 0123456789abcdef 0000000000000000 24002400 44000000 060070170000000f" +
+	"530065007200760065007200" +
+	"02000c0044006f006d00610069006e00 01000c00530065007200760065007200 00000000"
+
+func TestNTOWFv2(t *testing.T) {
+	want := unhex(t, "0c868a403bfd7a93a3001ef22ef02e3f")
+	if got := ntowfv2("User", "Domain", "Password"); !bytes.Equal(got, want) {
+		t.Fatalf("NTOWFv2 = %x, want %x", got, want)
+	}
+}
+
+func TestParseNTLMChallenge(t *testing.T) {
+	c, err := parseNTLMChallenge(unhex(t, specChallenge))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.flags != 0xe28a8233 {
+		t.Errorf("flags = %#x", c.flags)
+	}
+	if want := unhex(t, "0123456789abcdef"); !bytes.Equal(c.challenge, want) {
+		t.Errorf("challenge = %x", c.challenge)
+	}
+	wantInfo := unhex(t, "02000c0044006f006d00610069006e00 01000c00530065007200760065007200 00000000")
+	if !bytes.Equal(c.targetInfo, wantInfo) {
+		t.Errorf("target info = %x, want %x", c.targetInfo, wantInfo)
+	}
+}
+
+func TestNTLMv2Responses(t *testing.T) {
+	c, err := parseNTLMChallenge(unhex(t, specChallenge))
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientChallenge := unhex(t, "aaaaaaaaaaaaaaaa")
+	nt, lm := ntlmv2Responses(ntowfv2("User", "Domain", "Password"), c.challenge, clientChallenge, make([]byte, 8), c.targetInfo)
+
+	if want := unhex(t, "86c35097ac9cec102554764a57cccc19 aaaaaaaaaaaaaaaa"); !bytes.Equal(lm, want) {
+		t.Errorf("LMv2 response = %x, want %x", lm, want)
+	}
+	if want := unhex(t, "68cd0ab851e51c96aabc927bebef6a1c"); !bytes.Equal(nt[:16], want) {
+		t.Errorf("NTProofStr = %x, want %x", nt[:16], want)
+	}
+	blob := unhex(t, "0101000000000000 0000000000000000 aaaaaaaaaaaaaaaa 00000000")
+	if !bytes.HasPrefix(nt[16:], blob) || !bytes.HasSuffix(nt, append(c.targetInfo, 0, 0, 0, 0)) {
+		t.Errorf("NTLMv2 blob = %x", nt[16:])
+	}
+}
+
+func TestNTLMAuthenticate(t *testing.T) {
+	c, err := parseNTLMChallenge(unhex(t, specChallenge))
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, err := ntlmAuthenticate(c, `Domain\User`, "Password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(msg[:8], ntlmSignature) || binary.LittleEndian.Uint32(msg[8:]) != 3 {
+		t.Fatalf("not an AUTHENTICATE_MESSAGE: %x", msg[:12])
+	}
+	field := func(i int) []byte {
+		b, err := ntlmField(msg, 12+8*i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+	if got := field(2); !bytes.Equal(got, utf16LE("Domain")) {
+		t.Errorf("domain = %x", got)
+	}
+	if got := field(3); !bytes.Equal(got, utf16LE("User")) {
+		t.Errorf("user = %x", got)
+	}
+
+	// The server checks the proof against the blob the client sent.
+	nt := field(1)
+	proof := hmacMD5(ntowfv2("User", "Domain", "Password"), c.challenge, nt[16:])
+	if !bytes.Equal(nt[:16], proof) {
+		t.Errorf("NTProofStr %x does not match its blob", nt[:16])
+	}
+	if got, want := binary.LittleEndian.Uint32(msg[60:]), uint32(0xe28a8233&ntlmFlags); got != want {
+		t.Errorf("flags = %#x, want %#x", got, want)
+	}
+}
+
+func TestNTLMTimestamp(t *testing.T) {
+	info := unhex(t, "07000800 0102030405060708 00000000")
+	if got := ntlmTimestamp(info); !bytes.Equal(got, unhex(t, "0102030405060708")) {
+		t.Fatalf("timestamp = %x", got)
+	}
+	// Without one the local time is used, which is well past 2020.
+	got := binary.LittleEndian.Uint64(ntlmTimestamp(unhex(t, "00000000")))
+	if got < 132223104000000000 {
+		t.Fatalf("timestamp %d is before 2020", got)
+	}
+}
+
+func TestParseNTLMChallengeRejectsGarbage(t *testing.T) {
+	msg := unhex(t, specChallenge)
+	binary.LittleEndian.PutUint16(msg[40:], 0xffff)
+	for _, bad := range [][]byte{nil, ntlmNegotiate(), msg} {
+		if _, err := parseNTLMChallenge(bad); err == nil {
+			t.Errorf("%x parsed", bad)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package cutter
+
+import (
+	"crypto/tls"
+	"net/http"
+	"sync"
+
+	"atropos/internal/httpclient"
+)
+
+// outboundKey is what a shared client is picked by: the proxy it goes
+// through and whether it skips the server's certificate check.
+type outboundKey struct {
+	proxyURL string
+	insecure bool
+}
+
+// outboundClients holds one client per outboundKey, so cuts against the same
+// endpoint reuse its connections instead of dialing, and tunneling through
+// the proxy, every time.
+var outboundClients sync.Map
+
+// outboundClient returns the HTTP client a cutter calls out with, shared by
+// every cut with the same proxy_url and insecure setting. It goes through
+// params' proxy_url, or the proxy environment variables without one.
+// Callers must not modify it.
+func outboundClient(params map[string]string, insecure bool) (*http.Client, error) {
+	key := outboundKey{proxyURL: params["proxy_url"], insecure: insecure}
+	if client, ok := outboundClients.Load(key); ok {
+		return client.(*http.Client), nil
+	}
+	var tlsConfig *tls.Config
+	if insecure {
+		tlsConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+	client, _, err := newOutboundClient(params, tlsConfig)
+	if err != nil {
+		return nil, err
+	}
+	shared, _ := outboundClients.LoadOrStore(key, client)
+	return shared.(*http.Client), nil
+}
+
+// newOutboundClient builds a client of the caller's own, for connections
+// that can't be shared. tlsConfig, when set, replaces the transport's.
+func newOutboundClient(params map[string]string, tlsConfig *tls.Config) (*http.Client, *http.Transport, error) {
+	client, err := httpclient.New(0, params["proxy_url"])
+	if err != nil {
+		return nil, nil, err
+	}
+	transport := client.Transport.(*http.Transport)
+	if tlsConfig != nil {
+		transport.TLSClientConfig = tlsConfig
+	}
+	return client, transport, nil
+}
@@ -0,0 +1,188 @@
+package cutter
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"atropos/notifications"
+)
+
+// connectProxy tunnels CONNECT requests and records where they went.
+type connectProxy struct {
+	*httptest.Server
+	mu      sync.Mutex
+	tunnels []string
+}
+
+func newConnectProxy(t *testing.T) *connectProxy {
+	p := &connectProxy{}
+	p.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect {
+			http.Error(w, "CONNECT only", http.StatusMethodNotAllowed)
+			return
+		}
+		p.mu.Lock()
+		p.tunnels = append(p.tunnels, r.Host)
+		p.mu.Unlock()
+
+		upstream, err := net.Dial("tcp", r.Host)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			upstream.Close()
+			return
+		}
+		io.WriteString(conn, "HTTP/1.1 200 Connection established\r\n\r\n")
+		go func() {
+			io.Copy(upstream, conn)
+			upstream.Close()
+		}()
+		io.Copy(conn, upstream)
+		conn.Close()
+	}))
+	t.Cleanup(p.Close)
+	return p
+}
+
+func (p *connectProxy) tunneled(host string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, tunnel := range p.tunnels {
+		if tunnel == host {
+			return true
+		}
+	}
+	return false
+}
+
+func TestCuttersUseProxyURL(t *testing.T) {
+	upstream := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer upstream.Close()
+	upstreamURL, _ := url.Parse(upstream.URL)
+	host, port, _ := net.SplitHostPort(upstreamURL.Host)
+
+	tests := []struct {
+		name   string
+		cutter Cutter
+		params map[string]string
+	}{
+		{"webhook", NewWebhookCutter(), map[string]string{
+			"action": "http_call", "http_url": upstream.URL, "http_insecure": "true",
+		}},
+		{"ssm", NewSSMCutter(), map[string]string{
+			"action": "ssm_run_command", "command": "uptime", "ssm_instance_id": "i-0123456789abcdef0",
+			"aws_region": "us-east-1", "aws_access_key_id": "AKID", "aws_secret_access_key": "secret",
+			"ssm_endpoint": upstream.URL + "/",
+		}},
+		{"cloudflare", NewDNSCutter(), map[string]string{
+			"action": "dns_remove_record", "dns_zone": "example.com", "dns_provider": DNSProviderCloudflare,
+			"cloudflare_api_url": upstream.URL, "cloudflare_api_token": "token", "cloudflare_zone_id": "zone",
+		}},
+		{"winrm", NewWinRMCutter(), map[string]string{
+			"action": "winrm_shutdown", "host": host, "winrm_port": port,
+			"winrm_username": "admin", "winrm_password": "password", "winrm_insecure": "true",
+		}},
+		{"vmware", NewVMwareCutter(), map[string]string{
+			"action": "vmw_poweroff", "vmware_url": upstream.URL, "vmware_insecure": "true",
+			"vmware_username": "admin", "vmware_password": "password",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proxy := newConnectProxy(t)
+			tt.params["proxy_url"] = proxy.URL
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			// The upstream answers nothing useful; only the route matters.
+			tt.cutter.Execute(ctx, "web-01", tt.params)
+			if !proxy.tunneled(upstreamURL.Host) {
+				t.Fatalf("no CONNECT to %s through proxy_url", upstreamURL.Host)
+			}
+		})
+	}
+}
+
+func TestNotifiersUseProxyURL(t *testing.T) {
+	upstream := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer upstream.Close()
+	upstreamURL, _ := url.Parse(upstream.URL)
+
+	tests := []struct {
+		name   string
+		notify func(webhook *notifications.WebhookConfig) error
+	}{
+		{"webhook", func(webhook *notifications.WebhookConfig) error {
+			return notifications.NewWebhookNotifier(webhook).Notify(&notifications.CutEvent{Node: "web-01"})
+		}},
+		{"override", func(webhook *notifications.WebhookConfig) error {
+			nm := notifications.NewNotificationManager(&notifications.NotificationConfig{Enabled: true})
+			return nm.NotifyCutWith(&notifications.CutEvent{Node: "web-01"}, &notifications.Override{Webhook: webhook})
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proxy := newConnectProxy(t)
+			// The upstream's certificate isn't trusted; only the route matters.
+			tt.notify(&notifications.WebhookConfig{URL: upstream.URL, Retries: 1, ProxyURL: proxy.URL})
+			if !proxy.tunneled(upstreamURL.Host) {
+				t.Fatalf("no CONNECT to %s through proxy_url", upstreamURL.Host)
+			}
+		})
+	}
+}
+
+// Cuts through the same proxy_url share a client, and with it the tunnel.
+func TestOutboundClientShared(t *testing.T) {
+	upstream := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer upstream.Close()
+	proxy := newConnectProxy(t)
+
+	params := map[string]string{
+		"action": "http_call", "http_url": upstream.URL, "http_insecure": "true", "proxy_url": proxy.URL,
+	}
+	for i := 0; i < 3; i++ {
+		if err := NewWebhookCutter().Execute(context.Background(), "web-01", params); err != nil {
+			t.Fatalf("cut %d: %v", i+1, err)
+		}
+	}
+	proxy.mu.Lock()
+	tunnels := len(proxy.tunnels)
+	proxy.mu.Unlock()
+	if tunnels != 1 {
+		t.Fatalf("%d tunnels for 3 cuts, want the first reused", tunnels)
+	}
+
+	shared, _ := outboundClient(params, true)
+	if verified, _ := outboundClient(params, false); verified == shared {
+		t.Fatal("insecure and verifying cuts share a client")
+	}
+	if other, _ := outboundClient(map[string]string{}, true); other == shared {
+		t.Fatal("cuts through different proxies share a client")
+	}
+}
+
+func TestBadProxyURL(t *testing.T) {
+	err := NewWebhookCutter().Execute(context.Background(), "web-01", map[string]string{
+		"action": "http_call", "http_url": "https://example.invalid/", "proxy_url": "proxy.internal",
+	})
+	if err == nil || !strings.Contains(err.Error(), "proxy_url") {
+		t.Fatalf("err = %v, want a proxy_url error", err)
+	}
+}
@@ -0,0 +1,16 @@
+package cutter
+
+import "context"
+
+// Planner is implemented by cutters that can check, without changing
+// anything, whether a cut would work: the snapshot exists, containers
+// match, the host answers. Plan returns an error when the cut would fail.
+type Planner interface {
+	Plan(ctx context.Context, target string, params map[string]string) (PlanResult, error)
+}
+
+// PlanResult is what a cut would act on.
+type PlanResult struct {
+	Summary string                 `json:"summary"`
+	Details map[string]interface{} `json:"details,omitempty"`
+}
@@ -0,0 +1,132 @@
+package cutter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os/exec"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"atropos/internal/logger"
+)
+
+const (
+	// pluginStdoutLimit caps the result a plugin may print; more is a
+	// failure rather than a truncated result.
+	pluginStdoutLimit = 1 << 20
+	pluginStderrLimit = 64 << 10
+)
+
+// PluginRequest is what a plugin reads on stdin.
+type PluginRequest struct {
+	Target         string            `json:"target"`
+	Action         string            `json:"action"`
+	Params         map[string]string `json:"params"`
+	TimeoutSeconds int               `json:"timeout_seconds"`
+}
+
+// PluginResult is what a plugin prints on stdout before exiting 0.
+type PluginResult struct {
+	Success bool                   `json:"success"`
+	Error   string                 `json:"error,omitempty"`
+	Output  string                 `json:"output,omitempty"`
+	Details map[string]interface{} `json:"details,omitempty"`
+}
+
+// PluginCutter runs an external executable for every action starting with
+// its prefix. The executable gets a PluginRequest on stdin and answers with
+// a PluginResult on stdout; it is killed when the cut's deadline passes.
+type PluginCutter struct {
+	name   string
+	path   string
+	prefix string
+}
+
+func NewPluginCutter(name, path, prefix string) *PluginCutter {
+	return &PluginCutter{name: name, path: path, prefix: prefix}
+}
+
+func (p *PluginCutter) Name() string {
+	return p.name
+}
+
+func (p *PluginCutter) CanHandle(action string) bool {
+	return strings.HasPrefix(action, p.prefix)
+}
+
+func (p *PluginCutter) SupportedActions() []string {
+	return []string{p.prefix + "*"}
+}
+
+func (p *PluginCutter) Execute(ctx context.Context, target string, params map[string]string) error {
+	req := PluginRequest{
+		Target: target,
+		Action: params["action"],
+		Params: params,
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		req.TimeoutSeconds = int(math.Ceil(time.Until(deadline).Seconds()))
+	}
+	payload, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	logger.Get().Info("plugin_cut",
+		zap.String("plugin", p.name),
+		zap.String("target", target),
+		zap.String("action", req.Action),
+	)
+
+	stdout := &cappedBuffer{limit: pluginStdoutLimit}
+	stderr := &cappedBuffer{limit: pluginStderrLimit}
+	cmd := exec.CommandContext(ctx, p.path)
+	cmd.Stdin = bytes.NewReader(payload)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	cmd.WaitDelay = time.Second
+
+	err = cmd.Run()
+	if stderr.buf.Len() > 0 {
+		RecordDetail(ctx, "plugin_stderr", stderr.String())
+	}
+	if ctx.Err() != nil {
+		return stepError(ctx, "plugin", ctx.Err())
+	}
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return fmt.Errorf("plugin %s exited with %s: %s", p.name, exitErr.ProcessState, strings.TrimSpace(stderr.String()))
+		}
+		return fmt.Errorf("plugin %s: %w", p.name, err)
+	}
+	if stdout.dropped > 0 {
+		return fmt.Errorf("plugin %s printed more than %d bytes", p.name, pluginStdoutLimit)
+	}
+
+	var result PluginResult
+	dec := json.NewDecoder(bytes.NewReader(stdout.buf.Bytes()))
+	dec.UseNumber()
+	if err := dec.Decode(&result); err != nil {
+		return fmt.Errorf("plugin %s returned malformed output: %w", p.name, err)
+	}
+	for key, value := range result.Details {
+		RecordDetail(ctx, key, value)
+	}
+	if result.Output != "" {
+		RecordOutput(ctx, truncateOutput(result.Output, outputMaxBytes(params)))
+	}
+	if !result.Success {
+		if result.Error == "" {
+			result.Error = "reported failure"
+		}
+		return fmt.Errorf("plugin %s: %s", p.name, result.Error)
+	}
+	return nil
+}
@@ -0,0 +1,245 @@
+package cutter
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/crypto/ssh"
+
+	"atropos/internal/logger"
+)
+
+const (
+	defaultSSHPoolSize    = 2
+	defaultSSHMaxSessions = 10
+	defaultSSHIdleTimeout = 5 * time.Minute
+	sshKeepaliveInterval  = 30 * time.Second
+	sshKeepaliveTimeout   = 10 * time.Second
+)
+
+// sshPool keeps SSH clients open between cuts, up to ssh_pool_size per
+// host and ssh_max_sessions sessions on each. Clients are pinged every
+// sshKeepaliveInterval; one that doesn't answer, or has been idle past
+// ssh_idle_timeout_seconds, is closed.
+type sshPool struct {
+	mu    sync.Mutex
+	hosts map[string]*sshHostPool
+}
+
+type sshHostPool struct {
+	clients []*pooledSSH
+	// dialing counts connections being opened, so they count toward the
+	// pool size before they exist.
+	dialing int
+	// freed is closed, and replaced, whenever a session is released.
+	freed chan struct{}
+}
+
+type pooledSSH struct {
+	client      *ssh.Client
+	sessions    int
+	lastUsed    time.Time
+	idleTimeout time.Duration
+	dead        bool
+	// closed is closed when the client is dropped, which ends its
+	// keepalive.
+	closed chan struct{}
+}
+
+type sshPoolOptions struct {
+	size        int
+	maxSessions int
+	idleTimeout time.Duration
+}
+
+func sshPoolOptionsFromParams(params map[string]string) sshPoolOptions {
+	opts := sshPoolOptions{
+		size:        defaultSSHPoolSize,
+		maxSessions: defaultSSHMaxSessions,
+		idleTimeout: defaultSSHIdleTimeout,
+	}
+	if n, err := strconv.Atoi(params["ssh_pool_size"]); err == nil && n > 0 {
+		opts.size = n
+	}
+	if n, err := strconv.Atoi(params["ssh_max_sessions"]); err == nil && n > 0 {
+		opts.maxSessions = n
+	}
+	if n, err := strconv.Atoi(params["ssh_idle_timeout_seconds"]); err == nil && n > 0 {
+		opts.idleTimeout = time.Duration(n) * time.Second
+	}
+	return opts
+}
+
+func newSSHPool() *sshPool {
+	return &sshPool{hosts: make(map[string]*sshHostPool)}
+}
+
+// sshPoolKey tells hosts apart; the same host with other host key settings
+// gets its own clients.
+func sshPoolKey(user, host, port string, params map[string]string) string {
+	return strings.Join([]string{user, net.JoinHostPort(host, port), params["known_hosts"], params["host_key_fingerprint"], params["host_key_checking"], params["ssh_key_file"]}, "|")
+}
+
+// acquire returns a client with a session slot reserved for the caller,
+// reusing a pooled one when it can. release must be called once the
+// session is closed; broken closes the client instead of pooling it.
+func (p *sshPool) acquire(ctx context.Context, user, host, port string, params map[string]string) (*ssh.Client, func(broken bool), bool, error) {
+	key := sshPoolKey(user, host, port, params)
+	opts := sshPoolOptionsFromParams(params)
+
+	for {
+		p.mu.Lock()
+		hp := p.hosts[key]
+		if hp == nil {
+			hp = &sshHostPool{freed: make(chan struct{})}
+			p.hosts[key] = hp
+		}
+
+		var best *pooledSSH
+		for _, pc := range hp.clients {
+			if pc.dead || pc.sessions >= opts.maxSessions {
+				continue
+			}
+			if best == nil || pc.sessions < best.sessions {
+				best = pc
+			}
+		}
+		if best != nil {
+			best.sessions++
+			p.mu.Unlock()
+			return best.client, p.releaser(key, best), true, nil
+		}
+
+		if len(hp.clients)+hp.dialing < opts.size {
+			hp.dialing++
+			p.mu.Unlock()
+
+			client, err := dialSSH(ctx, user, host, port, params)
+
+			p.mu.Lock()
+			hp.dialing--
+			if err != nil {
+				p.wake(hp)
+				p.mu.Unlock()
+				return nil, nil, false, err
+			}
+			pc := &pooledSSH{client: client, sessions: 1, idleTimeout: opts.idleTimeout, closed: make(chan struct{})}
+			hp.clients = append(hp.clients, pc)
+			p.mu.Unlock()
+			go p.keepalive(key, pc)
+			return client, p.releaser(key, pc), false, nil
+		}
+
+		// Every client is at ssh_max_sessions; wait for a session to end.
+		freed := hp.freed
+		p.mu.Unlock()
+		select {
+		case <-ctx.Done():
+			return nil, nil, false, ctx.Err()
+		case <-freed:
+		}
+	}
+}
+
+func (p *sshPool) releaser(key string, pc *pooledSSH) func(bool) {
+	var once sync.Once
+	return func(broken bool) {
+		once.Do(func() {
+			p.mu.Lock()
+			defer p.mu.Unlock()
+			pc.sessions--
+			pc.lastUsed = time.Now()
+			if broken {
+				p.drop(key, pc)
+			}
+			if hp := p.hosts[key]; hp != nil {
+				p.wake(hp)
+			}
+		})
+	}
+}
+
+// keepalive pings pc until it dies or idles out.
+func (p *sshPool) keepalive(key string, pc *pooledSSH) {
+	ticker := time.NewTicker(sshKeepaliveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-pc.closed:
+			return
+		case <-ticker.C:
+		}
+		p.mu.Lock()
+		if pc.dead {
+			p.mu.Unlock()
+			return
+		}
+		if pc.sessions == 0 && time.Since(pc.lastUsed) > pc.idleTimeout {
+			p.drop(key, pc)
+			p.mu.Unlock()
+			return
+		}
+		p.mu.Unlock()
+
+		if err := pingSSH(pc.client); err != nil {
+			logger.Get().Warn("ssh_keepalive_failed",
+				zap.String("host", pc.client.RemoteAddr().String()),
+				zap.Error(err),
+			)
+			p.mu.Lock()
+			p.drop(key, pc)
+			p.mu.Unlock()
+			return
+		}
+	}
+}
+
+// drop closes pc and takes it out of the pool. The caller holds p.mu.
+func (p *sshPool) drop(key string, pc *pooledSSH) {
+	if !pc.dead {
+		pc.dead = true
+		pc.client.Close()
+		close(pc.closed)
+	}
+	hp := p.hosts[key]
+	if hp == nil {
+		return
+	}
+	for i, c := range hp.clients {
+		if c == pc {
+			hp.clients = append(hp.clients[:i], hp.clients[i+1:]...)
+			break
+		}
+	}
+	p.wake(hp)
+	if len(hp.clients) == 0 && hp.dialing == 0 {
+		delete(p.hosts, key)
+	}
+}
+
+// wake lets acquire calls waiting on hp look again. The caller holds p.mu.
+func (p *sshPool) wake(hp *sshHostPool) {
+	close(hp.freed)
+	hp.freed = make(chan struct{})
+}
+
+// pingSSH sends an OpenSSH keepalive, giving up after sshKeepaliveTimeout.
+func pingSSH(client *ssh.Client) error {
+	done := make(chan error, 1)
+	go func() {
+		_, _, err := client.SendRequest("keepalive@openssh.com", true, nil)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(sshKeepaliveTimeout):
+		return errors.New("keepalive timed out")
+	}
+}
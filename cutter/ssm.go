@@ -0,0 +1,259 @@
+package cutter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"atropos/internal/logger"
+)
+
+const ssmPolling = time.Second
+
+// SSMCutter runs shell commands on EC2 instances through AWS Systems
+// Manager Run Command, for nodes without SSH access.
+type SSMCutter struct{}
+
+func NewSSMCutter() *SSMCutter {
+	return &SSMCutter{}
+}
+
+func (s *SSMCutter) Name() string {
+	return "ssm"
+}
+
+func (s *SSMCutter) CanHandle(action string) bool {
+	return action == "ssm_run_command"
+}
+
+func (s *SSMCutter) SupportedActions() []string {
+	return []string{"ssm_run_command"}
+}
+
+func (s *SSMCutter) Execute(ctx context.Context, target string, params map[string]string) error {
+	if action := params["action"]; !s.CanHandle(action) {
+		return fmt.Errorf("unsupported action: %s", action)
+	}
+	command := params["command"]
+	if command == "" {
+		return fmt.Errorf("ssm_run_command requires command")
+	}
+	api, err := s.client(params)
+	if err != nil {
+		return err
+	}
+
+	instance, err := s.resolveInstance(ctx, api, target, params)
+	if err != nil {
+		return stepError(ctx, "resolve", err)
+	}
+	RecordDetail(ctx, "instance_id", instance)
+
+	logger.Get().Info("ssm_cut",
+		zap.String("target", target),
+		zap.String("instance_id", instance),
+		zap.String("command", command),
+	)
+
+	var sent struct {
+		Command struct {
+			CommandID string `json:"CommandId"`
+		} `json:"Command"`
+	}
+	err = api.call(ctx, "SendCommand", map[string]interface{}{
+		"DocumentName": "AWS-RunShellScript",
+		"InstanceIds":  []string{instance},
+		"Parameters":   map[string][]string{"commands": {command}},
+		"Comment":      "atropos cut on " + target,
+	}, &sent)
+	if err != nil {
+		return stepError(ctx, "send", fmt.Errorf("ssm SendCommand: %w", err))
+	}
+	commandID := sent.Command.CommandID
+	RecordDetail(ctx, "command_id", commandID)
+
+	inv, err := s.waitInvocation(ctx, api, commandID, instance)
+	if err != nil {
+		if ctx.Err() != nil {
+			s.cancel(api, commandID, instance)
+		}
+		return stepError(ctx, "wait", err)
+	}
+
+	output := inv.StandardOutputContent + inv.StandardErrorContent
+	RecordOutput(ctx, truncateOutput(output, outputMaxBytes(params)))
+	RecordDetail(ctx, "ssm_status", inv.Status)
+	if inv.Status != "Success" && inv.Status != "Failed" {
+		// Cancelled or TimedOut on the instance: there is no exit code
+		// to judge.
+		return fmt.Errorf("ssm command %s ended %s: %s", commandID, inv.Status, inv.StatusDetails)
+	}
+
+	RecordDetail(ctx, "exit_code", inv.ResponseCode)
+	rule, evalErr := evaluateCommand(params, inv.ResponseCode, output)
+	if rule != "" {
+		RecordDetail(ctx, "matched_rule", rule)
+	}
+	if evalErr != nil {
+		return fmt.Errorf("command failed: %w, output: %s", evalErr, output)
+	}
+	return nil
+}
+
+// Plan finds the instance the command would run on.
+func (s *SSMCutter) Plan(ctx context.Context, target string, params map[string]string) (PlanResult, error) {
+	if params["command"] == "" {
+		return PlanResult{}, fmt.Errorf("ssm_run_command requires command")
+	}
+	api, err := s.client(params)
+	if err != nil {
+		return PlanResult{}, err
+	}
+	instance, err := s.resolveInstance(ctx, api, target, params)
+	if err != nil {
+		return PlanResult{}, stepError(ctx, "resolve", err)
+	}
+	return PlanResult{
+		Summary: "ssm_run_command on " + instance,
+		Details: map[string]interface{}{"instance_id": instance},
+	}, nil
+}
+
+// client connects to SSM in the region and with the credentials params give.
+func (s *SSMCutter) client(params map[string]string) (*awsJSONClient, error) {
+	region := paramOrEnv(params, "aws_region", "AWS_REGION")
+	if region == "" {
+		region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	if region == "" {
+		return nil, fmt.Errorf("ssm cutter requires aws_region or AWS_REGION")
+	}
+	creds, err := awsCredentialsFromParams(params)
+	if err != nil {
+		return nil, err
+	}
+	endpoint := params["ssm_endpoint"]
+	if endpoint == "" {
+		endpoint = "https://ssm." + region + ".amazonaws.com/"
+	}
+	client, err := outboundClient(params, false)
+	if err != nil {
+		return nil, err
+	}
+	return &awsJSONClient{endpoint: endpoint, region: region, service: "ssm", target: "AmazonSSM", creds: creds, http: client}, nil
+}
+
+// resolveInstance takes ssm_instance_id as given, or finds the one managed
+// instance carrying ssm_instance_tag (Key=Value, default Name=<node>).
+func (s *SSMCutter) resolveInstance(ctx context.Context, api *awsJSONClient, target string, params map[string]string) (string, error) {
+	if id := params["ssm_instance_id"]; id != "" {
+		return id, nil
+	}
+	tag := params["ssm_instance_tag"]
+	if tag == "" {
+		tag = "Name=" + target
+	}
+	key, value, ok := strings.Cut(tag, "=")
+	if !ok || key == "" {
+		return "", fmt.Errorf("ssm_instance_tag must be Key=Value")
+	}
+
+	var ids []string
+	token := ""
+	for {
+		req := map[string]interface{}{
+			"Filters": []map[string]interface{}{{"Key": "tag:" + key, "Values": []string{value}}},
+		}
+		if token != "" {
+			req["NextToken"] = token
+		}
+		var res struct {
+			InstanceInformationList []struct {
+				InstanceID string `json:"InstanceId"`
+				PingStatus string `json:"PingStatus"`
+			} `json:"InstanceInformationList"`
+			NextToken string `json:"NextToken"`
+		}
+		if err := api.call(ctx, "DescribeInstanceInformation", req, &res); err != nil {
+			return "", fmt.Errorf("find instance tagged %s: %w", tag, err)
+		}
+		for _, info := range res.InstanceInformationList {
+			if info.PingStatus == "Online" {
+				ids = append(ids, info.InstanceID)
+			}
+		}
+		if token = res.NextToken; token == "" {
+			break
+		}
+	}
+	switch len(ids) {
+	case 0:
+		return "", fmt.Errorf("no online SSM-managed instance tagged %s", tag)
+	case 1:
+		return ids[0], nil
+	default:
+		return "", fmt.Errorf("%d instances tagged %s (%s); set ssm_instance_id", len(ids), tag, strings.Join(ids, ", "))
+	}
+}
+
+type ssmInvocation struct {
+	Status                string `json:"Status"`
+	StatusDetails         string `json:"StatusDetails"`
+	ResponseCode          int    `json:"ResponseCode"`
+	StandardOutputContent string `json:"StandardOutputContent"`
+	StandardErrorContent  string `json:"StandardErrorContent"`
+}
+
+// waitInvocation polls GetCommandInvocation until the command reaches a
+// terminal status.
+func (s *SSMCutter) waitInvocation(ctx context.Context, api *awsJSONClient, commandID, instance string) (*ssmInvocation, error) {
+	ticker := time.NewTicker(ssmPolling)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("ssm command %s: %w", commandID, ctx.Err())
+		case <-ticker.C:
+		}
+
+		var inv ssmInvocation
+		err := api.call(ctx, "GetCommandInvocation", map[string]string{
+			"CommandId":  commandID,
+			"InstanceId": instance,
+		}, &inv)
+		var apiErr *awsError
+		if errors.As(err, &apiErr) && apiErr.Type == "InvocationDoesNotExist" {
+			// Not visible yet right after SendCommand.
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("ssm GetCommandInvocation: %w", err)
+		}
+		switch inv.Status {
+		case "Success", "Failed", "Cancelled", "TimedOut":
+			return &inv, nil
+		}
+	}
+}
+
+// cancel stops a command the cut gave up on. It runs after the cut's
+// deadline, so it gets its own.
+func (s *SSMCutter) cancel(api *awsJSONClient, commandID, instance string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := api.call(ctx, "CancelCommand", map[string]interface{}{
+		"CommandId":   commandID,
+		"InstanceIds": []string{instance},
+	}, nil)
+	if err != nil {
+		logger.Get().Warn("ssm_cancel_failed",
+			zap.String("command_id", commandID),
+			zap.Error(err),
+		)
+	}
+}
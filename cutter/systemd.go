@@ -0,0 +1,357 @@
+package cutter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"atropos/internal/logger"
+)
+
+const (
+	systemdPolling = 500 * time.Millisecond
+
+	systemdBusName  = "org.freedesktop.systemd1"
+	systemdBusPath  = "/org/freedesktop/systemd1"
+	systemdManager  = "org.freedesktop.systemd1.Manager"
+	systemdUnitIf   = "org.freedesktop.systemd1.Unit"
+	dbusProperties  = "org.freedesktop.DBus.Properties"
+	dbusUnknownName = "org.freedesktop.DBus.Error.Unknown"
+)
+
+// systemctlExits names systemctl's exit codes, which follow the LSB init
+// script codes.
+var systemctlExits = map[int]string{
+	1: "failed",
+	2: "invalid argument",
+	3: "not implemented",
+	4: "insufficient privileges",
+	5: "unit not installed",
+	6: "unit not configured",
+	7: "unit not running",
+}
+
+func systemctlExitReason(code int) string {
+	if reason := systemctlExits[code]; reason != "" {
+		return reason
+	}
+	return "failed"
+}
+
+// SystemdCutter restarts, stops or masks the systemd unit named by the unit
+// param: through systemd's D-Bus API when the node is this machine, and
+// with systemctl over the network cutter's SSH connections otherwise. The
+// cut succeeds when the unit ends up in the state asked for.
+type SystemdCutter struct {
+	network *NetworkCutter
+}
+
+// NewSystemdCutter reaches other hosts through network's SSH connections.
+func NewSystemdCutter(network *NetworkCutter) *SystemdCutter {
+	return &SystemdCutter{network: network}
+}
+
+func (s *SystemdCutter) Name() string {
+	return "systemd"
+}
+
+func (s *SystemdCutter) CanHandle(action string) bool {
+	return action == "systemd_restart" || action == "systemd_stop" || action == "systemd_mask"
+}
+
+func (s *SystemdCutter) SupportedActions() []string {
+	return []string{"systemd_restart", "systemd_stop", "systemd_mask"}
+}
+
+// unitState is the unit's state read back after the action.
+type unitState struct {
+	LoadState     string
+	ActiveState   string
+	SubState      string
+	UnitFileState string
+}
+
+// settled reports whether the unit has stopped changing.
+func (u unitState) settled() bool {
+	switch u.ActiveState {
+	case "activating", "deactivating", "reloading", "refreshing":
+		return false
+	}
+	return true
+}
+
+func (s *SystemdCutter) Execute(ctx context.Context, target string, params map[string]string) error {
+	action := params["action"]
+	if !s.CanHandle(action) {
+		return fmt.Errorf("unsupported action: %s", action)
+	}
+	unit, err := systemdUnit(params["unit"])
+	if err != nil {
+		return err
+	}
+	host := params["host"]
+	local := systemdLocal(target, host)
+	if !local && host == "" {
+		return fmt.Errorf("systemd cutter requires host for target %s, which is not this machine", target)
+	}
+	via := "ssh"
+	if local {
+		via = "dbus"
+	}
+	RecordDetail(ctx, "unit", unit)
+	RecordDetail(ctx, "via", via)
+
+	logger.Get().Info("systemd_cut",
+		zap.String("target", target),
+		zap.String("action", action),
+		zap.String("unit", unit),
+		zap.String("via", via),
+	)
+
+	var state unitState
+	if local {
+		state, err = s.local(ctx, action, unit)
+	} else {
+		state, err = s.remote(ctx, action, unit, host, params)
+	}
+	if err != nil {
+		return err
+	}
+	for key, value := range map[string]string{
+		"load_state":      state.LoadState,
+		"active_state":    state.ActiveState,
+		"sub_state":       state.SubState,
+		"unit_file_state": state.UnitFileState,
+	} {
+		if value != "" {
+			RecordDetail(ctx, key, value)
+		}
+	}
+	return checkUnitState(action, unit, state)
+}
+
+// systemdUnit checks the unit param and adds .service to a bare name, as
+// systemctl does.
+func systemdUnit(unit string) (string, error) {
+	unit = strings.TrimSpace(unit)
+	if unit == "" {
+		return "", fmt.Errorf("systemd actions require unit")
+	}
+	if strings.HasPrefix(unit, "-") || strings.ContainsAny(unit, "/ \t\n") {
+		return "", fmt.Errorf("unit %q is not a unit name", unit)
+	}
+	if !strings.Contains(unit, ".") {
+		unit += ".service"
+	}
+	return unit, nil
+}
+
+// systemdLocal reports whether the node's host, or its name when it has
+// none, is this machine.
+func systemdLocal(target, host string) bool {
+	name := host
+	if name == "" {
+		name = target
+	}
+	if strings.EqualFold(name, "localhost") {
+		return true
+	}
+	if ip := net.ParseIP(name); ip != nil {
+		return ip.IsLoopback()
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		return false
+	}
+	short, _, _ := strings.Cut(hostname, ".")
+	return strings.EqualFold(name, hostname) || strings.EqualFold(name, short)
+}
+
+func checkUnitState(action, unit string, state unitState) error {
+	if state.LoadState == "not-found" {
+		return fmt.Errorf("unit %s not found", unit)
+	}
+	stopped := state.ActiveState == "inactive" || state.ActiveState == "failed"
+	switch action {
+	case "systemd_restart":
+		if state.ActiveState != "active" {
+			return fmt.Errorf("unit %s is %s (%s) after restart", unit, state.ActiveState, state.SubState)
+		}
+	case "systemd_stop":
+		if !stopped {
+			return fmt.Errorf("unit %s is still %s (%s) after stop", unit, state.ActiveState, state.SubState)
+		}
+	case "systemd_mask":
+		if state.UnitFileState != "masked" {
+			return fmt.Errorf("unit %s is %s, not masked", unit, state.UnitFileState)
+		}
+		if !stopped {
+			return fmt.Errorf("unit %s is masked but still %s (%s)", unit, state.ActiveState, state.SubState)
+		}
+	}
+	return nil
+}
+
+// local acts through systemd's D-Bus API and waits for the job it queued.
+// Masking also stops the unit, like systemctl mask --now.
+func (s *SystemdCutter) local(ctx context.Context, action, unit string) (unitState, error) {
+	bus, err := dialSystemBus(ctx)
+	if err != nil {
+		return unitState{}, stepError(ctx, "connect", err)
+	}
+	defer bus.Close()
+
+	manager := func(member, sig string, args ...interface{}) ([]interface{}, error) {
+		reply, err := bus.call(systemdBusName, systemdBusPath, systemdManager, member, sig, args...)
+		if err != nil {
+			return nil, stepError(ctx, "dbus", fmt.Errorf("%s %s: %w", member, unit, err))
+		}
+		return reply, nil
+	}
+
+	if action == "systemd_mask" {
+		if _, err := manager("MaskUnitFiles", "asbb", []interface{}{unit}, false, true); err != nil {
+			return unitState{}, err
+		}
+		if _, err := manager("Reload", ""); err != nil {
+			return unitState{}, err
+		}
+	}
+	member := "StopUnit"
+	if action == "systemd_restart" {
+		member = "RestartUnit"
+	}
+	reply, err := manager(member, "ss", unit, "replace")
+	if err != nil {
+		return unitState{}, err
+	}
+	job, _ := reply[0].(string)
+	if err := s.waitJob(ctx, bus, job); err != nil {
+		return unitState{}, stepError(ctx, "wait", fmt.Errorf("%s %s: %w", member, unit, err))
+	}
+
+	reply, err = manager("LoadUnit", "s", unit)
+	if err != nil {
+		return unitState{}, err
+	}
+	path, _ := reply[0].(string)
+	return waitUnitSettled(ctx, func() (unitState, error) {
+		var state unitState
+		for name, value := range map[string]*string{
+			"LoadState":     &state.LoadState,
+			"ActiveState":   &state.ActiveState,
+			"SubState":      &state.SubState,
+			"UnitFileState": &state.UnitFileState,
+		} {
+			reply, err := bus.call(systemdBusName, path, dbusProperties, "Get", "ss", systemdUnitIf, name)
+			if err != nil {
+				return state, stepError(ctx, "state", fmt.Errorf("read %s of %s: %w", name, unit, err))
+			}
+			*value, _ = reply[0].(string)
+		}
+		return state, nil
+	})
+}
+
+// waitJob polls the job until systemd drops it, which it does once the job
+// is done.
+func (s *SystemdCutter) waitJob(ctx context.Context, bus *dbusConn, job string) error {
+	for {
+		_, err := bus.call(systemdBusName, job, dbusProperties, "Get", "ss", "org.freedesktop.systemd1.Job", "State")
+		var callErr *dbusCallError
+		if errors.As(err, &callErr) && strings.HasPrefix(callErr.Name, dbusUnknownName) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("job %s: %w", job, ctx.Err())
+		case <-time.After(systemdPolling):
+		}
+	}
+}
+
+// remote runs systemctl on host. systemctl waits for the job itself.
+func (s *SystemdCutter) remote(ctx context.Context, action, unit, host string, params map[string]string) (unitState, error) {
+	user := params["user"]
+	if user == "" {
+		user = "root"
+	}
+	port := params["port"]
+	if port == "" {
+		port = "22"
+	}
+
+	command := map[string]string{
+		"systemd_restart": "systemctl restart ",
+		"systemd_stop":    "systemctl stop ",
+		"systemd_mask":    "systemctl mask --now ",
+	}[action] + shellQuote(unit)
+	output, exitCode, err := s.network.run(ctx, user, host, port, params, command)
+	if err != nil {
+		return unitState{}, err
+	}
+	RecordOutput(ctx, truncateOutput(output, outputMaxBytes(params)))
+	RecordDetail(ctx, "exit_code", exitCode)
+	if exitCode != 0 {
+		return unitState{}, fmt.Errorf("%s on %s exited %d (%s): %s", command, host, exitCode, systemctlExitReason(exitCode), strings.TrimSpace(output))
+	}
+
+	show := "systemctl show -p LoadState -p ActiveState -p SubState -p UnitFileState " + shellQuote(unit)
+	return waitUnitSettled(ctx, func() (unitState, error) {
+		output, exitCode, err := s.network.run(ctx, user, host, port, params, show)
+		if err != nil {
+			return unitState{}, err
+		}
+		if exitCode != 0 {
+			return unitState{}, fmt.Errorf("systemctl show %s on %s exited %d: %s", unit, host, exitCode, strings.TrimSpace(output))
+		}
+		return parseSystemctlShow(output), nil
+	})
+}
+
+// parseSystemctlShow reads systemctl show's Key=value lines.
+func parseSystemctlShow(output string) unitState {
+	var state unitState
+	for _, line := range strings.Split(output, "\n") {
+		key, value, _ := strings.Cut(strings.TrimSpace(line), "=")
+		switch key {
+		case "LoadState":
+			state.LoadState = value
+		case "ActiveState":
+			state.ActiveState = value
+		case "SubState":
+			state.SubState = value
+		case "UnitFileState":
+			state.UnitFileState = value
+		}
+	}
+	return state
+}
+
+// waitUnitSettled reads the unit's state until it stops changing or the
+// cut's deadline is near, and returns the last state read.
+func waitUnitSettled(ctx context.Context, read func() (unitState, error)) (unitState, error) {
+	for {
+		state, err := read()
+		if err != nil || state.settled() {
+			return state, err
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < 2*systemdPolling {
+			return state, nil
+		}
+		select {
+		case <-ctx.Done():
+			return state, nil
+		case <-time.After(systemdPolling):
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package cutter
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestCheckUnitState(t *testing.T) {
+	tests := []struct {
+		action string
+		state  unitState
+		err    string
+	}{
+		{"systemd_restart", unitState{LoadState: "loaded", ActiveState: "active", SubState: "running"}, ""},
+		{"systemd_restart", unitState{LoadState: "loaded", ActiveState: "failed", SubState: "failed"}, "is failed (failed) after restart"},
+		{"systemd_restart", unitState{LoadState: "not-found", ActiveState: "inactive"}, "not found"},
+		{"systemd_stop", unitState{LoadState: "loaded", ActiveState: "inactive", SubState: "dead"}, ""},
+		{"systemd_stop", unitState{LoadState: "loaded", ActiveState: "failed", SubState: "failed"}, ""},
+		{"systemd_stop", unitState{LoadState: "loaded", ActiveState: "active", SubState: "running"}, "still active (running) after stop"},
+		{"systemd_mask", unitState{LoadState: "masked", ActiveState: "inactive", UnitFileState: "masked"}, ""},
+		{"systemd_mask", unitState{LoadState: "loaded", ActiveState: "inactive", UnitFileState: "enabled"}, "is enabled, not masked"},
+		{"systemd_mask", unitState{LoadState: "masked", ActiveState: "active", SubState: "running", UnitFileState: "masked"}, "masked but still active"},
+	}
+	for _, tt := range tests {
+		err := checkUnitState(tt.action, "app.service", tt.state)
+		if tt.err == "" && err != nil {
+			t.Errorf("%s %+v: %v", tt.action, tt.state, err)
+		}
+		if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+			t.Errorf("%s %+v: err = %v, want %q", tt.action, tt.state, err, tt.err)
+		}
+	}
+}
+
+func TestSystemctlExitReason(t *testing.T) {
+	tests := map[int]string{
+		1:   "failed",
+		3:   "not implemented",
+		4:   "insufficient privileges",
+		5:   "unit not installed",
+		7:   "unit not running",
+		255: "failed",
+	}
+	for code, want := range tests {
+		if got := systemctlExitReason(code); got != want {
+			t.Errorf("exit %d: got %q, want %q", code, got, want)
+		}
+	}
+}
+
+func TestParseSystemctlShow(t *testing.T) {
+	got := parseSystemctlShow("LoadState=loaded\nActiveState=active\nSubState=running\nUnitFileState=enabled\n")
+	want := unitState{LoadState: "loaded", ActiveState: "active", SubState: "running", UnitFileState: "enabled"}
+	if got != want {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestSystemdUnit(t *testing.T) {
+	for in, want := range map[string]string{"sshd": "sshd.service", " nginx.service ": "nginx.service", "backup.timer": "backup.timer"} {
+		if got, err := systemdUnit(in); err != nil || got != want {
+			t.Errorf("%q: got %q, %v; want %q", in, got, err, want)
+		}
+	}
+	for _, bad := range []string{"", "--now", "a b", "../x"} {
+		if _, err := systemdUnit(bad); err == nil {
+			t.Errorf("%q accepted", bad)
+		}
+	}
+}
+
+func TestSystemdLocalRestart(t *testing.T) {
+	var mu sync.Mutex
+	var members []string
+	addr := fakeBus(t, func(call dbusCall) dbusReply {
+		mu.Lock()
+		members = append(members, call.member)
+		mu.Unlock()
+		switch call.member {
+		case "Hello":
+			return dbusReply{sig: "s", body: []interface{}{":1.1"}}
+		case "RestartUnit":
+			if !reflect.DeepEqual(call.args, []interface{}{"app.service", "replace"}) {
+				return dbusReply{name: "org.freedesktop.DBus.Error.InvalidArgs"}
+			}
+			return dbusReply{sig: "o", body: []interface{}{"/org/freedesktop/systemd1/job/7"}}
+		case "LoadUnit":
+			return dbusReply{sig: "o", body: []interface{}{"/org/freedesktop/systemd1/unit/app_2eservice"}}
+		case "Get":
+			if call.path == "/org/freedesktop/systemd1/job/7" {
+				return dbusReply{name: "org.freedesktop.DBus.Error.UnknownObject"}
+			}
+			value := map[string]string{
+				"LoadState": "loaded", "ActiveState": "active", "SubState": "running", "UnitFileState": "enabled",
+			}[call.args[1].(string)]
+			return dbusReply{sig: "v", body: []interface{}{dbusVariant{"s", value}}}
+		}
+		return dbusReply{name: "org.freedesktop.DBus.Error.UnknownMethod"}
+	})
+	t.Setenv("DBUS_SYSTEM_BUS_ADDRESS", addr)
+
+	err := NewSystemdCutter(nil).Execute(context.Background(), "localhost", map[string]string{
+		"action": "systemd_restart", "unit": "app",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if members[1] != "RestartUnit" || members[3] != "LoadUnit" {
+		t.Fatalf("calls = %v", members)
+	}
+}
@@ -3,14 +3,23 @@ package cutter
 import (
 	"context"
 	"fmt"
-	"os/exec"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"go.uber.org/zap"
 
 	"atropos/internal/logger"
 )
 
+const (
+	defaultACPIGrace = 20 * time.Second
+	// acpiPoweroffReserve is kept back from the grace period for the hard
+	// poweroff that follows it.
+	acpiPoweroffReserve = 5 * time.Second
+)
+
 type VBoxCutter struct{}
 
 func NewVBoxCutter() *VBoxCutter {
@@ -25,6 +34,10 @@ func (v *VBoxCutter) CanHandle(action string) bool {
 	return strings.HasPrefix(action, "vbox_")
 }
 
+func (v *VBoxCutter) SupportedActions() []string {
+	return []string{"vbox_revert_snapshot", "vbox_take_snapshot", "vbox_poweroff", "vbox_reset", "vbox_shutdown_acpi", "vbox_savestate", "vbox_pause", "vbox_resume"}
+}
+
 func (v *VBoxCutter) Execute(ctx context.Context, target string, params map[string]string) error {
 	action := params["action"]
 	vmName := params["vm_name"]
@@ -38,51 +51,313 @@ func (v *VBoxCutter) Execute(ctx context.Context, target string, params map[stri
 		zap.String("action", action),
 	)
 
+	vbm, err := newVBoxManage(params)
+	if err != nil {
+		return err
+	}
+
 	switch action {
 	case "vbox_revert_snapshot":
 		snapshotName := params["snapshot_name"]
 		if snapshotName == "" {
 			return fmt.Errorf("vbox_revert_snapshot requires snapshot_name")
 		}
-		return v.revertSnapshot(ctx, vmName, snapshotName)
+		// Check before touching the VM: a typo shouldn't leave it powered off.
+		snapshots, err := listSnapshots(ctx, vbm, vmName)
+		if err != nil {
+			return stepError(ctx, "snapshot list", err)
+		}
+		if !slices.Contains(snapshots, snapshotName) {
+			return fmt.Errorf("snapshot %q not found for VM %s, available: %s", snapshotName, vmName, snapshotList(snapshots))
+		}
+		if params["preserve_state"] == "true" {
+			if err := v.preserveState(ctx, vbm, target, vmName, params); err != nil {
+				return err
+			}
+		}
+		return v.revertSnapshot(ctx, vbm, vmName, snapshotName)
+	case "vbox_take_snapshot":
+		name := precutSnapshotName(params, target)
+		if err := v.takeSnapshot(ctx, vbm, vmName, name); err != nil {
+			return err
+		}
+		RecordDetail(ctx, "snapshot_taken", name)
+		return nil
 	case "vbox_poweroff":
-		return v.powerOff(ctx, vmName)
+		return v.powerOff(ctx, vbm, vmName)
 	case "vbox_reset":
-		return v.reset(ctx, vmName)
+		return v.reset(ctx, vbm, vmName)
+	case "vbox_shutdown_acpi":
+		grace := defaultACPIGrace
+		if s := params["acpi_grace_seconds"]; s != "" {
+			seconds, err := strconv.Atoi(s)
+			if err != nil || seconds <= 0 {
+				return fmt.Errorf("acpi_grace_seconds must be a whole number > 0")
+			}
+			grace = time.Duration(seconds) * time.Second
+		}
+		return v.shutdownACPI(ctx, vbm, vmName, grace)
+	case "vbox_savestate":
+		return v.control(ctx, vbm, vmName, "savestate", vmStopped)
+	case "vbox_pause":
+		return v.control(ctx, vbm, vmName, "pause", func(state string) bool {
+			return state == "paused" || vmStopped(state)
+		})
+	case "vbox_resume":
+		return v.resume(ctx, vbm, vmName)
 	default:
 		return fmt.Errorf("unsupported action: %s", action)
 	}
 }
 
-func (v *VBoxCutter) revertSnapshot(ctx context.Context, vmName, snapshotName string) error {
-	_ = v.powerOff(ctx, vmName)
+// HealthCheck runs VBoxManage --version on this machine. Nodes with
+// vbox_remote_host don't need it.
+func (v *VBoxCutter) HealthCheck(ctx context.Context) error {
+	bin, err := findVBoxManage(nil)
+	if err != nil {
+		return err
+	}
+	if output, err := (localVBoxManage{bin: bin}).command(ctx, "--version").CombinedOutput(); err != nil {
+		return fmt.Errorf("%s --version: %w, output: %s", bin, err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// Plan checks the VM exists and, for vbox_revert_snapshot, that it has the
+// snapshot.
+func (v *VBoxCutter) Plan(ctx context.Context, target string, params map[string]string) (PlanResult, error) {
+	action := params["action"]
+	vmName := params["vm_name"]
+	if vmName == "" {
+		vmName = target
+	}
+	vbm, err := newVBoxManage(params)
+	if err != nil {
+		return PlanResult{}, err
+	}
+	state, err := vmState(ctx, vbm, vmName)
+	if err != nil {
+		return PlanResult{}, stepError(ctx, "showvminfo", err)
+	}
+	details := map[string]interface{}{"vm": vmName, "vm_state": state}
+	if action == "vbox_revert_snapshot" {
+		snapshotName := params["snapshot_name"]
+		if snapshotName == "" {
+			return PlanResult{}, fmt.Errorf("vbox_revert_snapshot requires snapshot_name")
+		}
+		snapshots, err := listSnapshots(ctx, vbm, vmName)
+		if err != nil {
+			return PlanResult{}, stepError(ctx, "snapshot list", err)
+		}
+		if !slices.Contains(snapshots, snapshotName) {
+			return PlanResult{Details: details}, fmt.Errorf("snapshot %q not found for VM %s, available: %s", snapshotName, vmName, snapshotList(snapshots))
+		}
+		details["snapshot"] = snapshotName
+	}
+	return PlanResult{
+		Summary: fmt.Sprintf("%s on VM %s (%s)", action, vmName, state),
+		Details: details,
+	}, nil
+}
+
+func (v *VBoxCutter) revertSnapshot(ctx context.Context, vbm vboxManage, vmName, snapshotName string) error {
+	powerCtx, cancel := StepContext(ctx, 3)
+	err := v.powerOff(powerCtx, vbm, vmName)
+	cancel()
+	if ctx.Err() != nil {
+		return stepError(ctx, "poweroff", err)
+	}
 
-	cmd := exec.CommandContext(ctx, "VBoxManage", "snapshot", vmName, "restore", snapshotName)
+	restoreCtx, cancel := StepContext(ctx, 2)
+	defer cancel()
+	cmd := vbm.command(restoreCtx, "snapshot", vmName, "restore", snapshotName)
 	if output, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("restore snapshot %q: %w, output: %s", snapshotName, err, string(output))
+		return stepError(restoreCtx, "restore", fmt.Errorf("restore snapshot %q: %w, output: %s", snapshotName, err, string(output)))
 	}
 
-	startCmd := exec.CommandContext(ctx, "VBoxManage", "startvm", vmName, "--type", "headless")
+	startCmd := vbm.command(ctx, "startvm", vmName, "--type", "headless")
 	if output, err := startCmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("start VM: %w, output: %s", err, string(output))
+		return stepError(ctx, "startvm", fmt.Errorf("start VM: %w, output: %s", err, string(output)))
 	}
 
 	return nil
 }
 
-func (v *VBoxCutter) powerOff(ctx context.Context, vmName string) error {
-	cmd := exec.CommandContext(ctx, "VBoxManage", "controlvm", vmName, "poweroff")
+func (v *VBoxCutter) powerOff(ctx context.Context, vbm vboxManage, vmName string) error {
+	cmd := vbm.command(ctx, "controlvm", vmName, "poweroff")
 	output, err := cmd.CombinedOutput()
-	if err != nil && !strings.Contains(string(output), "not currently running") {
-		return fmt.Errorf("poweroff: %w, output: %s", err, string(output))
+	if err == nil {
+		return nil
+	}
+	// A VM that is already off is fine. The error text is localized, so ask
+	// for the state instead of matching it.
+	if state, stateErr := vmState(ctx, vbm, vmName); stateErr == nil && vmStopped(state) {
+		return nil
+	}
+	return stepError(ctx, "poweroff", fmt.Errorf("poweroff: %w, output: %s", err, string(output)))
+}
+
+func (v *VBoxCutter) reset(ctx context.Context, vbm vboxManage, vmName string) error {
+	cmd := vbm.command(ctx, "controlvm", vmName, "reset")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return stepError(ctx, "reset", fmt.Errorf("reset: %w, output: %s", err, string(output)))
 	}
 	return nil
 }
 
-func (v *VBoxCutter) reset(ctx context.Context, vmName string) error {
-	cmd := exec.CommandContext(ctx, "VBoxManage", "controlvm", vmName, "reset")
+// control runs controlvm command, which is fine to have failed when the VM
+// turns out to be in a state done reports as already there.
+func (v *VBoxCutter) control(ctx context.Context, vbm vboxManage, vmName, command string, done func(state string) bool) error {
+	cmd := vbm.command(ctx, "controlvm", vmName, command)
+	output, err := cmd.CombinedOutput()
+	if err == nil {
+		return nil
+	}
+	if state, stateErr := vmState(ctx, vbm, vmName); stateErr == nil && done(state) {
+		return nil
+	}
+	return stepError(ctx, command, fmt.Errorf("%s: %w, output: %s", command, err, string(output)))
+}
+
+// resume brings back a VM vbox_pause or vbox_savestate stopped: a paused VM
+// is resumed, a saved one started from its saved state.
+func (v *VBoxCutter) resume(ctx context.Context, vbm vboxManage, vmName string) error {
+	state, err := vmState(ctx, vbm, vmName)
+	if err != nil {
+		return stepError(ctx, "showvminfo", err)
+	}
+	switch state {
+	case "running":
+		return nil
+	case "saved", "aborted-saved":
+		cmd := vbm.command(ctx, "startvm", vmName, "--type", "headless")
+		if output, err := cmd.CombinedOutput(); err != nil {
+			return stepError(ctx, "startvm", fmt.Errorf("start VM: %w, output: %s", err, string(output)))
+		}
+		return nil
+	default:
+		return v.control(ctx, vbm, vmName, "resume", func(state string) bool { return state == "running" })
+	}
+}
+
+// shutdownACPI presses the VM's power button and waits up to grace for the
+// guest to power off, then pulls the plug. The shutdown detail records
+// which it was.
+func (v *VBoxCutter) shutdownACPI(ctx context.Context, vbm vboxManage, vmName string, grace time.Duration) error {
+	if deadline, ok := ctx.Deadline(); ok {
+		if left := time.Until(deadline) - acpiPoweroffReserve; left < grace {
+			grace = left
+		}
+	}
+
+	cmd := vbm.command(ctx, "controlvm", vmName, "acpipowerbutton")
 	if output, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("reset: %w, output: %s", err, string(output))
+		if state, stateErr := vmState(ctx, vbm, vmName); stateErr == nil && vmStopped(state) {
+			RecordDetail(ctx, "shutdown", "already_off")
+			return nil
+		}
+		return stepError(ctx, "acpipowerbutton", fmt.Errorf("acpipowerbutton: %w, output: %s", err, string(output)))
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, grace)
+	stopped := waitStopped(waitCtx, vbm, vmName)
+	cancel()
+	if stopped {
+		RecordDetail(ctx, "shutdown", "graceful")
+		return nil
+	}
+	if ctx.Err() != nil {
+		return stepError(ctx, "acpi_wait", ctx.Err())
+	}
+
+	logger.Get().Warn("vbox_acpi_ignored",
+		zap.String("vm", vmName),
+		zap.Duration("grace", grace),
+	)
+	RecordDetail(ctx, "shutdown", "forced")
+	return v.powerOff(ctx, vbm, vmName)
+}
+
+// waitStopped polls the VM's state every second until it is stopped or ctx
+// is done.
+func waitStopped(ctx context.Context, vbm vboxManage, vmName string) bool {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		if state, err := vmState(ctx, vbm, vmName); err == nil && vmStopped(state) {
+			return true
+		}
+		select {
+		case <-ctx.Done():
+			return false
+		case <-ticker.C:
+		}
+	}
+}
+
+// preserveState snapshots the VM as it is before a revert throws that
+// state away. A failed snapshot stops the revert unless
+// preserve_state_failure is continue.
+func (v *VBoxCutter) preserveState(ctx context.Context, vbm vboxManage, target, vmName string, params map[string]string) error {
+	name := precutSnapshotName(params, target)
+	snapCtx, cancel := StepContext(ctx, 2)
+	err := v.takeSnapshot(snapCtx, vbm, vmName, name)
+	cancel()
+	if err == nil {
+		RecordDetail(ctx, "snapshot_taken", name)
+		return nil
 	}
+	if params["preserve_state_failure"] != "continue" || ctx.Err() != nil {
+		return fmt.Errorf("preserve state: %w", err)
+	}
+	logger.Get().Warn("vbox_preserve_state_failed",
+		zap.String("vm", vmName),
+		zap.String("snapshot", name),
+		zap.Error(err),
+	)
+	RecordDetail(ctx, "snapshot_error", err.Error())
 	return nil
 }
+
+func (v *VBoxCutter) takeSnapshot(ctx context.Context, vbm vboxManage, vmName, name string) error {
+	cmd := vbm.command(ctx, "snapshot", vmName, "take", name)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return stepError(ctx, "snapshot", fmt.Errorf("take snapshot %q: %w, output: %s", name, err, string(output)))
+	}
+	return nil
+}
+
+// precutSnapshotName fills in the precut_snapshot_name template, default
+// atropos-precut-{timestamp}. {timestamp} is UTC, e.g. 20260102T150405Z;
+// {node} is the target.
+func precutSnapshotName(params map[string]string, target string) string {
+	name := params["precut_snapshot_name"]
+	if name == "" {
+		name = "atropos-precut-{timestamp}"
+	}
+	return strings.NewReplacer(
+		"{timestamp}", time.Now().UTC().Format("20060102T150405Z"),
+		"{node}", target,
+	).Replace(name)
+}
+
+// ListVBoxSnapshots returns the snapshot names of the VM a vbox_ action for
+// target would act on, in tree order.
+func ListVBoxSnapshots(ctx context.Context, target string, params map[string]string) ([]string, error) {
+	vbm, err := newVBoxManage(params)
+	if err != nil {
+		return nil, err
+	}
+	vmName := params["vm_name"]
+	if vmName == "" {
+		vmName = target
+	}
+	return listSnapshots(ctx, vbm, vmName)
+}
+
+func snapshotList(names []string) string {
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,115 @@
+package cutter
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+var ErrVBoxManageNotFound = errors.New("VBoxManage not found")
+
+// vboxManage builds VBoxManage commands, run on this machine or, with
+// vbox_remote_host set, on the VirtualBox host over SSH.
+type vboxManage interface {
+	command(ctx context.Context, args ...string) vboxCommand
+}
+
+// vboxCommand is the part of *exec.Cmd the VirtualBox cutter uses.
+type vboxCommand interface {
+	Output() ([]byte, error)
+	CombinedOutput() ([]byte, error)
+}
+
+type localVBoxManage struct {
+	bin string
+}
+
+func (l localVBoxManage) command(ctx context.Context, args ...string) vboxCommand {
+	return exec.CommandContext(ctx, l.bin, args...)
+}
+
+func newVBoxManage(params map[string]string) (vboxManage, error) {
+	if params["vbox_remote_host"] != "" {
+		return newRemoteVBoxManage(params), nil
+	}
+	bin, err := findVBoxManage(params)
+	if err != nil {
+		return nil, err
+	}
+	return localVBoxManage{bin: bin}, nil
+}
+
+// findVBoxManage resolves the VBoxManage binary: the vboxmanage_path param
+// when set, otherwise PATH and then the platform's install locations.
+func findVBoxManage(params map[string]string) (string, error) {
+	if path := params["vboxmanage_path"]; path != "" {
+		if _, err := exec.LookPath(path); err != nil {
+			return "", fmt.Errorf("%w, looked in: %s", ErrVBoxManageNotFound, path)
+		}
+		return path, nil
+	}
+
+	if path, err := exec.LookPath("VBoxManage"); err == nil {
+		return path, nil
+	}
+	looked := []string{"PATH"}
+	for _, path := range vboxManageCandidates() {
+		if _, err := exec.LookPath(path); err == nil {
+			return path, nil
+		}
+		looked = append(looked, path)
+	}
+	return "", fmt.Errorf("%w, looked in: %s", ErrVBoxManageNotFound, strings.Join(looked, ", "))
+}
+
+// vmState returns the VMState field of showvminfo's machine-readable
+// output, e.g. "running" or "poweroff". Unlike VBoxManage's error messages
+// it is not translated.
+func vmState(ctx context.Context, vbm vboxManage, vmName string) (string, error) {
+	output, err := vbm.command(ctx, "showvminfo", vmName, "--machinereadable").Output()
+	if err != nil {
+		return "", fmt.Errorf("showvminfo: %w", err)
+	}
+	scanner := bufio.NewScanner(strings.NewReader(string(output)))
+	for scanner.Scan() {
+		if value, ok := strings.CutPrefix(strings.TrimSpace(scanner.Text()), "VMState="); ok {
+			return strings.Trim(value, `"`), nil
+		}
+	}
+	return "", fmt.Errorf("showvminfo: no VMState for %s", vmName)
+}
+
+// vmStopped reports whether state is one controlvm refuses as "not
+// currently running".
+func vmStopped(state string) bool {
+	switch state {
+	case "poweroff", "aborted", "saved", "aborted-saved":
+		return true
+	}
+	return false
+}
+
+// listSnapshots reads the SnapshotName, SnapshotName-1, ... fields of
+// snapshot list's machine-readable output. VBoxManage fails the listing of
+// a VM without snapshots, so a VM that exists gets an empty list instead.
+func listSnapshots(ctx context.Context, vbm vboxManage, vmName string) ([]string, error) {
+	output, err := vbm.command(ctx, "snapshot", vmName, "list", "--machinereadable").Output()
+	if err != nil {
+		if _, stateErr := vmState(ctx, vbm, vmName); stateErr != nil {
+			return nil, fmt.Errorf("snapshot list: %w", err)
+		}
+		return nil, nil
+	}
+	var names []string
+	scanner := bufio.NewScanner(strings.NewReader(string(output)))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(strings.TrimSpace(scanner.Text()), "=")
+		if ok && (key == "SnapshotName" || strings.HasPrefix(key, "SnapshotName-")) {
+			names = append(names, strings.Trim(value, `"`))
+		}
+	}
+	return names, nil
+}
@@ -0,0 +1,9 @@
+package cutter
+
+func vboxManageCandidates() []string {
+	return []string{
+		"/usr/local/bin/VBoxManage",
+		"/opt/homebrew/bin/VBoxManage",
+		"/Applications/VirtualBox.app/Contents/MacOS/VBoxManage",
+	}
+}
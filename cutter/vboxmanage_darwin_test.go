@@ -0,0 +1,15 @@
+package cutter
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestVBoxManageCandidates(t *testing.T) {
+	got := vboxManageCandidates()
+	for _, path := range []string{"/usr/local/bin/VBoxManage", "/Applications/VirtualBox.app/Contents/MacOS/VBoxManage"} {
+		if !slices.Contains(got, path) {
+			t.Errorf("candidates %q lack %s", got, path)
+		}
+	}
+}
@@ -0,0 +1,11 @@
+//go:build !windows && !darwin
+
+package cutter
+
+func vboxManageCandidates() []string {
+	return []string{
+		"/usr/bin/VBoxManage",
+		"/usr/local/bin/VBoxManage",
+		"/usr/lib/virtualbox/VBoxManage",
+	}
+}
@@ -0,0 +1,15 @@
+//go:build !windows && !darwin
+
+package cutter
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestVBoxManageCandidates(t *testing.T) {
+	want := []string{"/usr/bin/VBoxManage", "/usr/local/bin/VBoxManage", "/usr/lib/virtualbox/VBoxManage"}
+	if got := vboxManageCandidates(); !slices.Equal(got, want) {
+		t.Fatalf("candidates = %q, want %q", got, want)
+	}
+}
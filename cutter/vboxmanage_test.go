@@ -0,0 +1,369 @@
+package cutter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// fakeVBoxManageEnv, set by the shim script, makes the test binary act as
+// VBoxManage with its state in the named directory.
+const fakeVBoxManageEnv = "ATROPOS_FAKE_VBOXMANAGE"
+
+func TestMain(m *testing.M) {
+	if dir := os.Getenv(fakeVBoxManageEnv); dir != "" {
+		os.Exit(fakeVBoxManage(dir, os.Args[1:]))
+	}
+	os.Exit(m.Run())
+}
+
+// fakeVBoxManage keeps the VM's state, snapshots and the calls made in
+// files under dir. Its errors are in German, as VBoxManage's are on a
+// German system. Commands listed in dir/fail fail without changing
+// anything.
+func fakeVBoxManage(dir string, args []string) int {
+	file := func(name string) string { return filepath.Join(dir, name) }
+	read := func(name string) string {
+		data, _ := os.ReadFile(file(name))
+		return strings.TrimSpace(string(data))
+	}
+	write := func(name, value string) {
+		os.WriteFile(file(name), []byte(value+"\n"), 0o600)
+	}
+	fail := func(format string, a ...interface{}) int {
+		fmt.Fprintf(os.Stderr, "VBoxManage: Fehler: "+format+"\n", a...)
+		return 1
+	}
+
+	calls, err := os.OpenFile(file("calls"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err == nil {
+		fmt.Fprintln(calls, strings.Join(args, " "))
+		calls.Close()
+	}
+	if len(args) == 1 && args[0] == "--version" {
+		fmt.Println("7.0.14r161095")
+		return 0
+	}
+	if len(args) < 3 {
+		return fail("Syntaxfehler: %s", strings.Join(args, " "))
+	}
+
+	vm, state := args[1], read("state")
+	if vm != read("vm") {
+		return fail("Die Maschine '%s' ist nicht registriert", vm)
+	}
+	command := args[0]
+	if len(args) > 2 {
+		command += " " + args[2]
+	}
+	if slices.Contains(strings.Split(read("fail"), "\n"), command) {
+		return fail("%s ist fehlgeschlagen", command)
+	}
+	var snapshots []string
+	if s := read("snapshots"); s != "" {
+		snapshots = strings.Split(s, "\n")
+	}
+
+	switch command {
+	case "showvminfo --machinereadable":
+		fmt.Printf("name=%q\nVMState=%q\n", vm, state)
+	case "controlvm poweroff", "controlvm acpipowerbutton", "controlvm savestate", "controlvm pause", "controlvm reset":
+		if state != "running" {
+			return fail("Die Maschine '%s' läuft derzeit nicht", vm)
+		}
+		write("state", map[string]string{
+			"poweroff":        "poweroff",
+			"acpipowerbutton": "poweroff",
+			"savestate":       "saved",
+			"pause":           "paused",
+			"reset":           "running",
+		}[args[2]])
+	case "controlvm resume":
+		if state != "paused" {
+			return fail("Die Maschine '%s' ist nicht angehalten", vm)
+		}
+		write("state", "running")
+	case "startvm --type":
+		if state == "running" || state == "paused" {
+			return fail("Die Maschine '%s' ist bereits gestartet", vm)
+		}
+		write("state", "running")
+	case "snapshot list":
+		if len(snapshots) == 0 {
+			return fail("Diese Maschine hat keine Sicherungspunkte")
+		}
+		for i, name := range snapshots {
+			key := "SnapshotName"
+			if i > 0 {
+				key += fmt.Sprintf("-%d", i)
+			}
+			fmt.Printf("%s=%q\n", key, name)
+		}
+	case "snapshot restore":
+		if state == "running" {
+			return fail("Die Maschine '%s' ist gesperrt", vm)
+		}
+		if len(args) < 4 || !slices.Contains(snapshots, args[3]) {
+			return fail("Sicherungspunkt nicht gefunden")
+		}
+	case "snapshot take":
+		write("snapshots", strings.Join(append(snapshots, args[3]), "\n"))
+	default:
+		return fail("Unbekannter Befehl: %s", strings.Join(args, " "))
+	}
+	return 0
+}
+
+// vboxShim is a VBoxManage shim script in bin that runs the test binary as
+// fakeVBoxManage with its state in dir.
+type vboxShim struct {
+	dir, bin, path string
+}
+
+func newVBoxShim(t *testing.T, vmState string, snapshots ...string) *vboxShim {
+	t.Helper()
+	root := t.TempDir()
+	s := &vboxShim{dir: filepath.Join(root, "state"), bin: filepath.Join(root, "bin")}
+	for _, dir := range []string{s.dir, s.bin} {
+		if err := os.Mkdir(dir, 0o700); err != nil {
+			t.Fatal(err)
+		}
+	}
+	testBinary, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.path = filepath.Join(s.bin, vboxShimName)
+	if err := os.WriteFile(s.path, []byte(vboxShimScript(testBinary, s.dir)), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	s.write(t, "vm", "devbox")
+	s.write(t, "state", vmState)
+	s.write(t, "snapshots", strings.Join(snapshots, "\n"))
+	return s
+}
+
+func (s *vboxShim) write(t *testing.T, name, value string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(s.dir, name), []byte(value+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func (s *vboxShim) read(t *testing.T, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(s.dir, name))
+	if err != nil && !os.IsNotExist(err) {
+		t.Fatal(err)
+	}
+	return strings.TrimSpace(string(data))
+}
+
+func (s *vboxShim) state(t *testing.T) string {
+	return s.read(t, "state")
+}
+
+// calls returns the VBoxManage command lines run so far, clearing them.
+func (s *vboxShim) calls(t *testing.T) []string {
+	t.Helper()
+	calls := s.read(t, "calls")
+	os.Remove(filepath.Join(s.dir, "calls"))
+	if calls == "" {
+		return nil
+	}
+	return strings.Split(calls, "\n")
+}
+
+func (s *vboxShim) execute(t *testing.T, action string, params map[string]string) (map[string]interface{}, error) {
+	t.Helper()
+	if params == nil {
+		params = map[string]string{}
+	}
+	params["action"] = action
+	if _, ok := params["vboxmanage_path"]; !ok {
+		params["vboxmanage_path"] = s.path
+	}
+	ctx, details := WithDetails(context.Background())
+	err := NewVBoxCutter().Execute(ctx, "devbox", params)
+	return details.Map(), err
+}
+
+func TestFindVBoxManage(t *testing.T) {
+	s := newVBoxShim(t, "running")
+
+	path, err := findVBoxManage(map[string]string{"vboxmanage_path": s.path})
+	if err != nil || path != s.path {
+		t.Fatalf("with vboxmanage_path: %q, %v; want %q", path, err, s.path)
+	}
+	missing := filepath.Join(s.bin, "nowhere", vboxShimName)
+	_, err = findVBoxManage(map[string]string{"vboxmanage_path": missing})
+	if !errors.Is(err, ErrVBoxManageNotFound) || !strings.Contains(err.Error(), "looked in: "+missing) {
+		t.Fatalf("with a missing vboxmanage_path: %v", err)
+	}
+
+	t.Setenv("PATH", s.bin)
+	if path, err := findVBoxManage(nil); err != nil || filepath.Dir(path) != s.bin {
+		t.Fatalf("from PATH: %q, %v; want the shim in %s", path, err, s.bin)
+	}
+	if err := NewVBoxCutter().HealthCheck(context.Background()); err != nil {
+		t.Fatalf("HealthCheck with VBoxManage on PATH: %v", err)
+	}
+
+	t.Setenv("PATH", t.TempDir())
+	for _, candidate := range vboxManageCandidates() {
+		if _, err := os.Stat(candidate); err == nil {
+			t.Skipf("VirtualBox is installed at %s", candidate)
+		}
+	}
+	_, err = findVBoxManage(nil)
+	if !errors.Is(err, ErrVBoxManageNotFound) {
+		t.Fatalf("with nothing installed: %v, want ErrVBoxManageNotFound", err)
+	}
+	want := "VBoxManage not found, looked in: " + strings.Join(append([]string{"PATH"}, vboxManageCandidates()...), ", ")
+	if err.Error() != want {
+		t.Fatalf("error %q, want %q", err, want)
+	}
+	if err := NewVBoxCutter().HealthCheck(context.Background()); !errors.Is(err, ErrVBoxManageNotFound) {
+		t.Fatalf("HealthCheck with nothing installed: %v", err)
+	}
+	_, err = s.execute(t, "vbox_poweroff", map[string]string{"vboxmanage_path": ""})
+	if err == nil || err.Error() != want {
+		t.Fatalf("cut with nothing installed: %v, want %q", err, want)
+	}
+}
+
+// VBoxManage's messages follow the host's locale, so the cutter must judge
+// a refused command by the VM's state, not by its output.
+func TestVBoxRefusalsAreLocaleIndependent(t *testing.T) {
+	s := newVBoxShim(t, "running")
+	if _, err := s.execute(t, "vbox_poweroff", nil); err != nil {
+		t.Fatalf("poweroff of a running VM: %v", err)
+	}
+	if state := s.state(t); state != "poweroff" {
+		t.Fatalf("state after poweroff = %s", state)
+	}
+	s.calls(t)
+
+	if _, err := s.execute(t, "vbox_poweroff", nil); err != nil {
+		t.Fatalf("poweroff of a VM that is already off: %v", err)
+	}
+	want := []string{"controlvm devbox poweroff", "showvminfo devbox --machinereadable"}
+	if calls := s.calls(t); !slices.Equal(calls, want) {
+		t.Fatalf("calls = %q, want %q", calls, want)
+	}
+	for _, action := range []string{"vbox_savestate", "vbox_pause"} {
+		if _, err := s.execute(t, action, nil); err != nil {
+			t.Fatalf("%s of a VM that is off: %v", action, err)
+		}
+	}
+
+	details, err := s.execute(t, "vbox_shutdown_acpi", nil)
+	if err != nil || details["shutdown"] != "already_off" {
+		t.Fatalf("ACPI shutdown of a VM that is off: %v, details %v", err, details)
+	}
+
+	// A refusal while the VM is still running is a real failure, reported
+	// with what VBoxManage said.
+	s.write(t, "state", "running")
+	s.write(t, "fail", "controlvm poweroff")
+	_, err = s.execute(t, "vbox_poweroff", nil)
+	if err == nil || !strings.Contains(err.Error(), "controlvm poweroff ist fehlgeschlagen") {
+		t.Fatalf("failed poweroff of a running VM: %v", err)
+	}
+	if state := s.state(t); state != "running" {
+		t.Fatalf("state after a failed poweroff = %s", state)
+	}
+}
+
+func TestVBoxPauseAndResume(t *testing.T) {
+	s := newVBoxShim(t, "running")
+	for _, step := range []struct {
+		action, want string
+	}{
+		{"vbox_pause", "paused"},
+		{"vbox_resume", "running"},
+		{"vbox_resume", "running"},
+		{"vbox_savestate", "saved"},
+		{"vbox_resume", "running"},
+		{"vbox_reset", "running"},
+	} {
+		if _, err := s.execute(t, step.action, nil); err != nil {
+			t.Fatalf("%s: %v", step.action, err)
+		}
+		if state := s.state(t); state != step.want {
+			t.Fatalf("state after %s = %s, want %s", step.action, state, step.want)
+		}
+	}
+}
+
+func TestVBoxRevertSnapshot(t *testing.T) {
+	s := newVBoxShim(t, "running", "base", "patched")
+
+	_, err := s.execute(t, "vbox_revert_snapshot", map[string]string{"snapshot_name": "pristine"})
+	if err == nil || !strings.Contains(err.Error(), `snapshot "pristine" not found for VM devbox, available: base, patched`) {
+		t.Fatalf("revert to a missing snapshot: %v", err)
+	}
+	if state := s.state(t); state != "running" {
+		t.Fatalf("a revert to a missing snapshot left the VM %s", state)
+	}
+	s.calls(t)
+
+	details, err := s.execute(t, "vbox_revert_snapshot", map[string]string{
+		"snapshot_name":        "base",
+		"preserve_state":       "true",
+		"precut_snapshot_name": "before-{node}",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"snapshot devbox list --machinereadable",
+		"snapshot devbox take before-devbox",
+		"controlvm devbox poweroff",
+		"snapshot devbox restore base",
+		"startvm devbox --type headless",
+	}
+	if calls := s.calls(t); !slices.Equal(calls, want) {
+		t.Fatalf("calls = %q, want %q", calls, want)
+	}
+	if state := s.state(t); state != "running" || details["snapshot_taken"] != "before-devbox" {
+		t.Fatalf("after the revert: state %s, details %v", state, details)
+	}
+
+	snapshots, err := ListVBoxSnapshots(context.Background(), "devbox", map[string]string{"vboxmanage_path": s.path})
+	if err != nil || !slices.Equal(snapshots, []string{"base", "patched", "before-devbox"}) {
+		t.Fatalf("snapshots = %q, %v", snapshots, err)
+	}
+}
+
+// VBoxManage fails snapshot list for a VM without snapshots; that is an
+// empty list only if the VM exists.
+func TestVBoxSnapshotsOfBareVM(t *testing.T) {
+	s := newVBoxShim(t, "poweroff")
+	params := map[string]string{"vboxmanage_path": s.path}
+
+	snapshots, err := ListVBoxSnapshots(context.Background(), "devbox", params)
+	if err != nil || len(snapshots) != 0 {
+		t.Fatalf("snapshots of a VM without any = %q, %v", snapshots, err)
+	}
+	if _, err := ListVBoxSnapshots(context.Background(), "other", params); err == nil {
+		t.Fatal("listed the snapshots of an unregistered VM")
+	}
+	_, err = s.execute(t, "vbox_revert_snapshot", map[string]string{"snapshot_name": "base"})
+	if err == nil || !strings.Contains(err.Error(), "available: none") {
+		t.Fatalf("revert on a VM without snapshots: %v", err)
+	}
+
+	plan, err := NewVBoxCutter().Plan(context.Background(), "devbox", map[string]string{
+		"action":          "vbox_poweroff",
+		"vboxmanage_path": s.path,
+	})
+	if err != nil || plan.Details["vm_state"] != "poweroff" {
+		t.Fatalf("plan = %+v, %v", plan, err)
+	}
+}
@@ -0,0 +1,11 @@
+//go:build !windows
+
+package cutter
+
+import "fmt"
+
+const vboxShimName = "VBoxManage"
+
+func vboxShimScript(testBinary, stateDir string) string {
+	return fmt.Sprintf("#!/bin/sh\n%s='%s' exec '%s' \"$@\"\n", fakeVBoxManageEnv, stateDir, testBinary)
+}
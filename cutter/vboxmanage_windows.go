@@ -0,0 +1,40 @@
+package cutter
+
+import (
+	"os"
+	"path/filepath"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+func vboxManageCandidates() []string {
+	var dirs []string
+	if key, err := registry.OpenKey(registry.LOCAL_MACHINE, `SOFTWARE\Oracle\VirtualBox`, registry.QUERY_VALUE); err == nil {
+		if dir, _, err := key.GetStringValue("InstallDir"); err == nil && dir != "" {
+			dirs = append(dirs, dir)
+		}
+		key.Close()
+	}
+	// Set by the VirtualBox installer.
+	for _, env := range []string{"VBOX_MSI_INSTALL_PATH", "VBOX_INSTALL_PATH"} {
+		if dir := os.Getenv(env); dir != "" {
+			dirs = append(dirs, dir)
+		}
+	}
+	for _, env := range []string{"ProgramFiles", "ProgramFiles(x86)"} {
+		if dir := os.Getenv(env); dir != "" {
+			dirs = append(dirs, filepath.Join(dir, "Oracle", "VirtualBox"))
+		}
+	}
+
+	paths := make([]string, 0, len(dirs))
+	seen := make(map[string]bool)
+	for _, dir := range dirs {
+		path := filepath.Join(dir, "VBoxManage.exe")
+		if !seen[path] {
+			seen[path] = true
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
@@ -0,0 +1,33 @@
+package cutter
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+const vboxShimName = "VBoxManage.cmd"
+
+func vboxShimScript(testBinary, stateDir string) string {
+	return fmt.Sprintf("@echo off\r\nset %s=%s\r\n\"%s\" %%*\r\nexit /b %%errorlevel%%\r\n", fakeVBoxManageEnv, stateDir, testBinary)
+}
+
+func TestVBoxManageCandidates(t *testing.T) {
+	installer := t.TempDir()
+	programFiles := t.TempDir()
+	t.Setenv("VBOX_MSI_INSTALL_PATH", installer)
+	t.Setenv("VBOX_INSTALL_PATH", installer)
+	t.Setenv("ProgramFiles", programFiles)
+	t.Setenv("ProgramFiles(x86)", programFiles)
+
+	got := vboxManageCandidates()
+	for _, path := range []string{
+		filepath.Join(installer, "VBoxManage.exe"),
+		filepath.Join(programFiles, "Oracle", "VirtualBox", "VBoxManage.exe"),
+	} {
+		if n := len(slices.DeleteFunc(slices.Clone(got), func(c string) bool { return c != path })); n != 1 {
+			t.Errorf("candidates %q list %s %d times, want once", got, path, n)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package cutter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// remoteVBoxManage runs VBoxManage on another machine over SSH, with the
+// keys in ssh-agent like the ssh_ actions. The remote shell must be POSIX.
+type remoteVBoxManage struct {
+	host string
+	user string
+	port string
+	bin  string
+	// params carry the host key settings.
+	params map[string]string
+}
+
+func newRemoteVBoxManage(params map[string]string) *remoteVBoxManage {
+	r := &remoteVBoxManage{
+		host:   params["vbox_remote_host"],
+		user:   params["vbox_remote_user"],
+		port:   params["vbox_remote_port"],
+		bin:    params["vboxmanage_path"],
+		params: params,
+	}
+	if r.user == "" {
+		r.user = "root"
+	}
+	if r.port == "" {
+		r.port = "22"
+	}
+	if r.bin == "" {
+		r.bin = "VBoxManage"
+	}
+	return r
+}
+
+func (r *remoteVBoxManage) command(ctx context.Context, args ...string) vboxCommand {
+	return &remoteVBoxCommand{ctx: ctx, vbm: r, args: args}
+}
+
+type remoteVBoxCommand struct {
+	ctx  context.Context
+	vbm  *remoteVBoxManage
+	args []string
+}
+
+func (c *remoteVBoxCommand) Output() ([]byte, error) {
+	return c.run(func(s *ssh.Session, cmd string) ([]byte, error) { return s.Output(cmd) })
+}
+
+func (c *remoteVBoxCommand) CombinedOutput() ([]byte, error) {
+	return c.run(func(s *ssh.Session, cmd string) ([]byte, error) { return s.CombinedOutput(cmd) })
+}
+
+// run reports a non-zero exit like exec does, so callers can't tell the
+// two apart, and kills the command when the context ends.
+func (c *remoteVBoxCommand) run(fn func(*ssh.Session, string) ([]byte, error)) ([]byte, error) {
+	client, err := dialSSH(c.ctx, c.vbm.user, c.vbm.host, c.vbm.port, c.vbm.params)
+	if err != nil {
+		return nil, fmt.Errorf("ssh connect %s: %w", c.vbm.host, err)
+	}
+	defer client.Close()
+	session, err := client.NewSession()
+	if err != nil {
+		return nil, fmt.Errorf("ssh session %s: %w", c.vbm.host, err)
+	}
+	defer session.Close()
+
+	words := make([]string, 0, len(c.args)+1)
+	for _, word := range append([]string{c.vbm.bin}, c.args...) {
+		words = append(words, shellQuote(word))
+	}
+
+	type result struct {
+		output []byte
+		err    error
+	}
+	done := make(chan result, 1)
+	go func() {
+		output, err := fn(session, strings.Join(words, " "))
+		done <- result{output, err}
+	}()
+
+	select {
+	case <-c.ctx.Done():
+		_ = session.Signal(ssh.SIGKILL)
+		session.Close()
+		client.Close()
+		<-done
+		return nil, c.ctx.Err()
+	case res := <-done:
+		var exitErr *ssh.ExitError
+		if errors.As(res.err, &exitErr) {
+			return res.output, remoteExitError(exitErr.ExitStatus())
+		}
+		return res.output, res.err
+	}
+}
+
+// remoteExitError reads like *exec.ExitError's message.
+type remoteExitError int
+
+func (e remoteExitError) Error() string {
+	return fmt.Sprintf("exit status %d", int(e))
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
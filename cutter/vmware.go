@@ -0,0 +1,196 @@
+package cutter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"atropos/internal/logger"
+)
+
+// VMwareCutter acts on vSphere VMs through vCenter or a standalone ESXi
+// host.
+type VMwareCutter struct{}
+
+func NewVMwareCutter() *VMwareCutter {
+	return &VMwareCutter{}
+}
+
+func (v *VMwareCutter) Name() string {
+	return "vmware"
+}
+
+func (v *VMwareCutter) CanHandle(action string) bool {
+	return strings.HasPrefix(action, "vmw_")
+}
+
+func (v *VMwareCutter) SupportedActions() []string {
+	return []string{"vmw_poweroff", "vmw_reset", "vmw_suspend", "vmw_revert_snapshot"}
+}
+
+func (v *VMwareCutter) Execute(ctx context.Context, target string, params map[string]string) error {
+	action := params["action"]
+	vmName := params["vm_name"]
+	if vmName == "" {
+		vmName = target
+	}
+
+	switch action {
+	case "vmw_poweroff", "vmw_reset", "vmw_suspend":
+	case "vmw_revert_snapshot":
+		if params["snapshot_name"] == "" {
+			return fmt.Errorf("vmw_revert_snapshot requires snapshot_name")
+		}
+	default:
+		return fmt.Errorf("unsupported action: %s", action)
+	}
+
+	sdkURL, err := vmwareSDKURL(paramOrEnv(params, "vmware_url", "VMWARE_URL"))
+	if err != nil {
+		return err
+	}
+	insecure, _ := strconv.ParseBool(paramOrEnv(params, "vmware_insecure", "VMWARE_INSECURE"))
+
+	logger.Get().Info("vmware_cut",
+		zap.String("target", target),
+		zap.String("vm", vmName),
+		zap.String("action", action),
+		zap.String("vmware_url", sdkURL),
+	)
+
+	c, err := newVimClient(ctx, sdkURL,
+		paramOrEnv(params, "vmware_username", "VMWARE_USERNAME"),
+		paramOrEnv(params, "vmware_password", "VMWARE_PASSWORD"),
+		insecure, params)
+	if err != nil {
+		return stepError(ctx, "connect", fmt.Errorf("vmware %s: %w", sdkURL, err))
+	}
+	defer c.logout()
+
+	container := c.content.RootFolder
+	if dc := params["vmware_datacenter"]; dc != "" {
+		path := dc + "/vm"
+		if folder := strings.Trim(params["vmware_folder"], "/"); folder != "" {
+			path += "/" + folder
+		}
+		folder, ok, err := c.findByInventoryPath(ctx, path)
+		if err != nil {
+			return stepError(ctx, "find", fmt.Errorf("find %s: %w", path, err))
+		}
+		if !ok {
+			return fmt.Errorf("inventory path %s not found", path)
+		}
+		container = folder
+	}
+	vm, err := c.findVM(ctx, container, vmName)
+	if err != nil {
+		return stepError(ctx, "find", err)
+	}
+	RecordDetail(ctx, "vm", vm.Value)
+
+	vmRef := vm.xml("_this")
+	switch action {
+	case "vmw_poweroff":
+		err = c.runTask(ctx, `<PowerOffVM_Task xmlns="urn:vim25">`+vmRef+`</PowerOffVM_Task>`)
+		err = ignoreInvalidPowerState(err)
+	case "vmw_suspend":
+		err = c.runTask(ctx, `<SuspendVM_Task xmlns="urn:vim25">`+vmRef+`</SuspendVM_Task>`)
+		err = ignoreInvalidPowerState(err)
+	case "vmw_reset":
+		err = c.runTask(ctx, `<ResetVM_Task xmlns="urn:vim25">`+vmRef+`</ResetVM_Task>`)
+	case "vmw_revert_snapshot":
+		err = v.revertSnapshot(ctx, c, vm, params["snapshot_name"])
+	}
+	if err != nil {
+		return stepError(ctx, action, fmt.Errorf("%s %s: %w", action, vmName, err))
+	}
+	return nil
+}
+
+// revertSnapshot reverts vm to the snapshot called name, wherever it is in
+// the snapshot tree.
+func (v *VMwareCutter) revertSnapshot(ctx context.Context, c *vimClient, vm moref, name string) error {
+	objects, err := c.retrieve(ctx, vm, "VirtualMachine", "", "snapshot")
+	if err != nil {
+		return err
+	}
+	var info struct {
+		Roots []vmwareSnapshot `xml:"rootSnapshotList"`
+	}
+	if len(objects) == 1 && len(objects[0].PropSet) == 1 {
+		if err := objects[0].PropSet[0].decode(&info); err != nil {
+			return fmt.Errorf("snapshot tree: %w", err)
+		}
+	}
+
+	var names []string
+	var found moref
+	matches := 0
+	var walk func([]vmwareSnapshot)
+	walk = func(tree []vmwareSnapshot) {
+		for _, s := range tree {
+			names = append(names, s.Name)
+			if s.Name == name {
+				matches++
+				found = s.Snapshot
+			}
+			walk(s.Children)
+		}
+	}
+	walk(info.Roots)
+	switch matches {
+	case 0:
+		return fmt.Errorf("snapshot %q not found, available: %s", name, snapshotList(names))
+	case 1:
+	default:
+		return fmt.Errorf("snapshot name %q matches %d snapshots", name, matches)
+	}
+	return c.runTask(ctx, `<RevertToSnapshot_Task xmlns="urn:vim25">`+found.xml("_this")+`</RevertToSnapshot_Task>`)
+}
+
+type vmwareSnapshot struct {
+	Snapshot moref            `xml:"snapshot"`
+	Name     string           `xml:"name"`
+	Children []vmwareSnapshot `xml:"childSnapshotList"`
+}
+
+// ignoreInvalidPowerState treats a VM already in the wanted power state,
+// e.g. powering off one that is off, as done.
+func ignoreInvalidPowerState(err error) error {
+	var fault *vmwareFault
+	if errors.As(err, &fault) && fault.Type == "InvalidPowerState" {
+		return nil
+	}
+	return err
+}
+
+func paramOrEnv(params map[string]string, key, env string) string {
+	if v := params[key]; v != "" {
+		return v
+	}
+	return os.Getenv(env)
+}
+
+// vmwareSDKURL accepts a bare host or a URL and points it at /sdk.
+func vmwareSDKURL(raw string) (string, error) {
+	if raw == "" {
+		return "", fmt.Errorf("vmware cutter requires vmware_url or VMWARE_URL")
+	}
+	if !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", fmt.Errorf("vmware_url: %w", err)
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = "/sdk"
+	}
+	return u.String(), nil
+}
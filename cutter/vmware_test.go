@@ -0,0 +1,283 @@
+package cutter
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeVCenter answers the vim25 calls the VMware cutter makes for an
+// inventory of two VMs, web-01 (vm-1) and db-01 (vm-2).
+type fakeVCenter struct {
+	*httptest.Server
+
+	mu sync.Mutex
+	// calls lists each operation with its _this, e.g. "PowerOffVM_Task vm-2".
+	calls []string
+	// faults maps an operation to the fault type it answers with.
+	faults map[string]string
+	// taskError, when set, fails tasks with this fault type.
+	taskError string
+	polls     int
+}
+
+type vimRequest struct {
+	Body struct {
+		Op struct {
+			XMLName  xml.Name
+			This     string   `xml:"_this"`
+			UserName string   `xml:"userName"`
+			Path     string   `xml:"inventoryPath"`
+			Props    []string `xml:"specSet>propSet>pathSet"`
+			Obj      string   `xml:"specSet>objectSet>obj"`
+			Token    string   `xml:"token"`
+			Parent   string   `xml:"container"`
+		} `xml:",any"`
+	} `xml:"Body"`
+}
+
+func newFakeVCenter(t *testing.T) *fakeVCenter {
+	f := &fakeVCenter{faults: map[string]string{}}
+	f.Server = httptest.NewTLSServer(http.HandlerFunc(f.serve))
+	t.Cleanup(f.Close)
+	return f
+}
+
+func (f *fakeVCenter) params(action string) map[string]string {
+	return map[string]string{
+		"action":          action,
+		"vmware_url":      f.URL,
+		"vmware_insecure": "true",
+		"vmware_username": "admin",
+		"vmware_password": "password",
+	}
+}
+
+func (f *fakeVCenter) Calls() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.calls...)
+}
+
+func (f *fakeVCenter) serve(w http.ResponseWriter, r *http.Request) {
+	var req vimRequest
+	if err := xml.NewDecoder(r.Body).Decode(&req); err != nil || r.Header.Get("SOAPAction") != vimSOAPAction {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	op := req.Body.Op
+	name := op.XMLName.Local
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	call := name
+	if op.This != "" {
+		call += " " + op.This
+	}
+	switch name {
+	case "RetrievePropertiesEx":
+		call += " " + op.Obj + " " + strings.Join(op.Props, ",")
+	case "CreateContainerView":
+		call += " " + op.Parent
+	case "FindByInventoryPath":
+		call += " " + op.Path
+	}
+	f.calls = append(f.calls, call)
+
+	if name != "RetrieveServiceContent" && name != "Login" {
+		if c, err := r.Cookie("vmware_soap_session"); err != nil || c.Value != "session-1" {
+			vimFault(w, "NotAuthenticated", "not logged in")
+			return
+		}
+	}
+	if fault := f.faults[name]; fault != "" {
+		vimFault(w, fault, "The attempted operation cannot be performed in the current state.")
+		return
+	}
+
+	var inner string
+	switch name {
+	case "RetrieveServiceContent":
+		inner = `<returnval><rootFolder type="Folder">group-d1</rootFolder><propertyCollector type="PropertyCollector">propertyCollector</propertyCollector>` +
+			`<viewManager type="ViewManager">ViewManager</viewManager><searchIndex type="SearchIndex">SearchIndex</searchIndex>` +
+			`<sessionManager type="SessionManager">SessionManager</sessionManager></returnval>`
+	case "Login":
+		if op.UserName != "admin" {
+			vimFault(w, "InvalidLogin", "Cannot complete login due to an incorrect user name or password.")
+			return
+		}
+		http.SetCookie(w, &http.Cookie{Name: "vmware_soap_session", Value: "session-1"})
+		inner = `<returnval><key>session-1</key></returnval>`
+	case "FindByInventoryPath":
+		if op.Path == "dc1/vm/team" {
+			inner = `<returnval type="Folder">group-v7</returnval>`
+		}
+	case "CreateContainerView":
+		inner = `<returnval type="ContainerView">session[1]view-1</returnval>`
+	case "RetrievePropertiesEx", "ContinueRetrievePropertiesEx":
+		inner = f.properties(op.Obj, op.Props, op.Token)
+	case "PowerOffVM_Task", "SuspendVM_Task", "ResetVM_Task", "RevertToSnapshot_Task":
+		inner = `<returnval type="Task">task-1</returnval>`
+	}
+	fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?><soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/" `+
+		`xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance"><soapenv:Body><%sResponse xmlns="urn:vim25">%s</%sResponse></soapenv:Body></soapenv:Envelope>`,
+		name, inner, name)
+}
+
+// properties pages the VM list, one VM per page, to exercise the
+// continuation token.
+func (f *fakeVCenter) properties(obj string, props []string, token string) string {
+	if token == "page-2" {
+		return `<returnval><objects><obj type="VirtualMachine">vm-2</obj><propSet><name>name</name><val xsi:type="xsd:string">db-01</val></propSet></objects></returnval>`
+	}
+	switch strings.Join(props, ",") {
+	case "name":
+		return `<returnval><token>page-2</token><objects><obj type="VirtualMachine">vm-1</obj><propSet><name>name</name><val xsi:type="xsd:string">web-01</val></propSet></objects></returnval>`
+	case "info":
+		// The task runs for one poll.
+		f.polls++
+		state := "running"
+		var taskErr string
+		if f.polls > 1 {
+			state = "success"
+			if f.taskError != "" {
+				state = "error"
+				taskErr = `<error><fault xsi:type="` + f.taskError + `"></fault><localizedMessage>The operation is not supported on the object.</localizedMessage></error>`
+			}
+		}
+		return `<returnval><objects><obj type="Task">task-1</obj><propSet><name>info</name><val xsi:type="TaskInfo"><key>task-1</key><state>` +
+			state + `</state>` + taskErr + `</val></propSet></objects></returnval>`
+	case "snapshot":
+		return `<returnval><objects><obj type="VirtualMachine">` + obj + `</obj><propSet><name>snapshot</name><val xsi:type="VirtualMachineSnapshotInfo">` +
+			`<rootSnapshotList><snapshot type="VirtualMachineSnapshot">snapshot-1</snapshot><name>base</name>` +
+			`<childSnapshotList><snapshot type="VirtualMachineSnapshot">snapshot-2</snapshot><name>clean</name></childSnapshotList>` +
+			`</rootSnapshotList></val></propSet></objects></returnval>`
+	}
+	return `<returnval></returnval>`
+}
+
+func vimFault(w http.ResponseWriter, typ, message string) {
+	w.WriteHeader(http.StatusInternalServerError)
+	fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?><soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/" `+
+		`xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance"><soapenv:Body><soapenv:Fault><faultcode>ServerFaultCode</faultcode>`+
+		`<faultstring>%s</faultstring><detail><%sFault xmlns="urn:vim25" xsi:type="%s"></%sFault></detail></soapenv:Fault></soapenv:Body></soapenv:Envelope>`,
+		message, typ, typ, typ)
+}
+
+func TestVMwarePowerOff(t *testing.T) {
+	f := newFakeVCenter(t)
+	if err := NewVMwareCutter().Execute(context.Background(), "db-01", f.params("vmw_poweroff")); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"RetrieveServiceContent ServiceInstance",
+		"Login SessionManager",
+		"CreateContainerView ViewManager group-d1",
+		"RetrievePropertiesEx propertyCollector session[1]view-1 name",
+		"ContinueRetrievePropertiesEx propertyCollector",
+		"DestroyView session[1]view-1",
+		"PowerOffVM_Task vm-2",
+		"RetrievePropertiesEx propertyCollector task-1 info",
+		"RetrievePropertiesEx propertyCollector task-1 info",
+		"Logout SessionManager",
+	}
+	if got := f.Calls(); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("calls:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestVMwarePowerOffAlreadyOff(t *testing.T) {
+	f := newFakeVCenter(t)
+	f.faults["PowerOffVM_Task"] = "InvalidPowerState"
+	if err := NewVMwareCutter().Execute(context.Background(), "web-01", f.params("vmw_poweroff")); err != nil {
+		t.Fatalf("powering off a VM that is off: %v", err)
+	}
+}
+
+func TestVMwareTaskFault(t *testing.T) {
+	f := newFakeVCenter(t)
+	f.taskError = "NotSupported"
+	err := NewVMwareCutter().Execute(context.Background(), "web-01", f.params("vmw_reset"))
+	if err == nil || !strings.Contains(err.Error(), "NotSupported: The operation is not supported on the object.") {
+		t.Fatalf("err = %v, want the task's fault", err)
+	}
+}
+
+func TestVMwareLoginFault(t *testing.T) {
+	f := newFakeVCenter(t)
+	params := f.params("vmw_poweroff")
+	params["vmware_username"] = "nobody"
+	err := NewVMwareCutter().Execute(context.Background(), "web-01", params)
+	if err == nil || !strings.Contains(err.Error(), "login: InvalidLogin: Cannot complete login") {
+		t.Fatalf("err = %v, want InvalidLogin", err)
+	}
+}
+
+func TestVMwareRevertSnapshot(t *testing.T) {
+	f := newFakeVCenter(t)
+	params := f.params("vmw_revert_snapshot")
+	params["snapshot_name"] = "clean"
+	if err := NewVMwareCutter().Execute(context.Background(), "web-01", params); err != nil {
+		t.Fatal(err)
+	}
+	calls := f.Calls()
+	found := false
+	for _, call := range calls {
+		found = found || call == "RevertToSnapshot_Task snapshot-2"
+	}
+	if !found {
+		t.Fatalf("no revert to snapshot-2 in %v", calls)
+	}
+
+	params["snapshot_name"] = "missing"
+	err := NewVMwareCutter().Execute(context.Background(), "web-01", params)
+	if err == nil || !strings.Contains(err.Error(), `snapshot "missing" not found, available: base, clean`) {
+		t.Fatalf("err = %v, want the available snapshots", err)
+	}
+}
+
+func TestVMwareInventoryPath(t *testing.T) {
+	f := newFakeVCenter(t)
+	params := f.params("vmw_suspend")
+	params["vmware_datacenter"] = "dc1"
+	params["vmware_folder"] = "/team/"
+	if err := NewVMwareCutter().Execute(context.Background(), "web-01", params); err != nil {
+		t.Fatal(err)
+	}
+	calls := strings.Join(f.Calls(), "\n")
+	if !strings.Contains(calls, "FindByInventoryPath SearchIndex dc1/vm/team") || !strings.Contains(calls, "CreateContainerView ViewManager group-v7") {
+		t.Fatalf("VM not looked up under the folder:\n%s", calls)
+	}
+
+	params["vmware_folder"] = "other"
+	err := NewVMwareCutter().Execute(context.Background(), "web-01", params)
+	if err == nil || !strings.Contains(err.Error(), "inventory path dc1/vm/other not found") {
+		t.Fatalf("err = %v, want the path not found", err)
+	}
+}
+
+func TestVMwareVMNotFound(t *testing.T) {
+	f := newFakeVCenter(t)
+	err := NewVMwareCutter().Execute(context.Background(), "app-01", f.params("vmw_poweroff"))
+	if err == nil || !strings.Contains(err.Error(), `VM "app-01" not found`) {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestVMwareSDKURL(t *testing.T) {
+	for in, want := range map[string]string{
+		"vcenter.lab":                    "https://vcenter.lab/sdk",
+		"https://vcenter.lab/":           "https://vcenter.lab/sdk",
+		"https://esxi-01.lab:8443/sdk":   "https://esxi-01.lab:8443/sdk",
+		"http://vcenter.lab/custom/path": "http://vcenter.lab/custom/path",
+	} {
+		if got, err := vmwareSDKURL(in); err != nil || got != want {
+			t.Errorf("%s: got %q, %v; want %q", in, got, err, want)
+		}
+	}
+}
@@ -0,0 +1,310 @@
+package cutter
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"strings"
+	"time"
+)
+
+// The VMware cutter speaks the vSphere web services (vim25) SOAP API
+// directly; it needs only a handful of calls.
+
+const (
+	vimSOAPAction  = "urn:vim25/6.5"
+	xsiNamespace   = "http://www.w3.org/2001/XMLSchema-instance"
+	vimTaskPolling = 500 * time.Millisecond
+)
+
+// moref is a vSphere managed object reference, e.g. VirtualMachine vm-42.
+type moref struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+func (m moref) xml(name string) string {
+	return fmt.Sprintf(`<%s type="%s">%s</%s>`, name, xmlText(m.Type), xmlText(m.Value), name)
+}
+
+// vmwareFault is a SOAP or task fault, e.g. InvalidLogin or
+// InvalidPowerState, with vSphere's message for it.
+type vmwareFault struct {
+	Type    string
+	Message string
+}
+
+func (f *vmwareFault) Error() string {
+	if f.Type == "" {
+		return f.Message
+	}
+	return f.Type + ": " + f.Message
+}
+
+// serviceContent holds the service's singletons the cutter calls.
+type serviceContent struct {
+	RootFolder        moref `xml:"rootFolder"`
+	PropertyCollector moref `xml:"propertyCollector"`
+	ViewManager       moref `xml:"viewManager"`
+	SearchIndex       moref `xml:"searchIndex"`
+	SessionManager    moref `xml:"sessionManager"`
+}
+
+type vimClient struct {
+	url     string
+	http    *http.Client
+	content serviceContent
+}
+
+// newVimClient logs in to the SDK endpoint at url, through params'
+// proxy_url when it is set.
+func newVimClient(ctx context.Context, url, user, password string, insecure bool, params map[string]string) (*vimClient, error) {
+	shared, err := outboundClient(params, insecure)
+	if err != nil {
+		return nil, err
+	}
+	// The session cookie is this login's; the connections are shared.
+	jar, _ := cookiejar.New(nil)
+	client := &http.Client{Transport: shared.Transport, Jar: jar}
+	c := &vimClient{url: url, http: client}
+
+	var sc struct {
+		Returnval *serviceContent `xml:"returnval"`
+	}
+	body := `<RetrieveServiceContent xmlns="urn:vim25"><_this type="ServiceInstance">ServiceInstance</_this></RetrieveServiceContent>`
+	if err := c.call(ctx, body, &sc); err != nil {
+		return nil, err
+	}
+	if sc.Returnval == nil {
+		return nil, fmt.Errorf("no service content from %s", url)
+	}
+	c.content = *sc.Returnval
+
+	login := fmt.Sprintf(`<Login xmlns="urn:vim25">%s<userName>%s</userName><password>%s</password></Login>`,
+		c.content.SessionManager.xml("_this"), xmlText(user), xmlText(password))
+	if err := c.call(ctx, login, nil); err != nil {
+		return nil, fmt.Errorf("login: %w", err)
+	}
+	return c, nil
+}
+
+func (c *vimClient) logout() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_ = c.call(ctx, fmt.Sprintf(`<Logout xmlns="urn:vim25">%s</Logout>`, c.content.SessionManager.xml("_this")), nil)
+}
+
+// call posts body, one vim25 request element, and decodes the response
+// element into out.
+func (c *vimClient) call(ctx context.Context, body string, out interface{}) error {
+	envelope := `<?xml version="1.0" encoding="UTF-8"?>` +
+		`<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/" xmlns:xsi="` + xsiNamespace + `">` +
+		`<soapenv:Body>` + body + `</soapenv:Body></soapenv:Envelope>`
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, strings.NewReader(envelope))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/xml; charset=utf-8")
+	req.Header.Set("SOAPAction", vimSOAPAction)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 16<<20))
+	if err != nil {
+		return err
+	}
+
+	var env struct {
+		Body struct {
+			Fault *struct {
+				String string `xml:"faultstring"`
+				Detail struct {
+					Fault struct {
+						Type string `xml:"http://www.w3.org/2001/XMLSchema-instance type,attr"`
+					} `xml:",any"`
+				} `xml:"detail"`
+			} `xml:"Fault"`
+			Inner []byte `xml:",innerxml"`
+		} `xml:"Body"`
+	}
+	if err := xml.Unmarshal(data, &env); err != nil {
+		return fmt.Errorf("HTTP %d: unreadable response: %w", resp.StatusCode, err)
+	}
+	if f := env.Body.Fault; f != nil {
+		return &vmwareFault{Type: f.Detail.Fault.Type, Message: f.String}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	if out == nil {
+		return nil
+	}
+	return xml.Unmarshal(bytes.TrimSpace(env.Body.Inner), out)
+}
+
+// propValue is one property as RetrievePropertiesEx returns it; Inner is
+// decoded by whoever asked for the property.
+type propValue struct {
+	Name string `xml:"name"`
+	Val  struct {
+		Text  string `xml:",chardata"`
+		Inner string `xml:",innerxml"`
+	} `xml:"val"`
+}
+
+// decode reads a structured value. The xsi prefix is declared again since
+// the value is taken out of the envelope that declared it.
+func (p propValue) decode(out interface{}) error {
+	return xml.Unmarshal([]byte(`<val xmlns:xsi="`+xsiNamespace+`">`+p.Val.Inner+`</val>`), out)
+}
+
+type objectContent struct {
+	Obj     moref       `xml:"obj"`
+	PropSet []propValue `xml:"propSet"`
+}
+
+// retrieve reads props of objType objects reachable from obj: obj itself,
+// or with a traversal path the objects along it.
+func (c *vimClient) retrieve(ctx context.Context, obj moref, objType, traversePath string, props ...string) ([]objectContent, error) {
+	var spec strings.Builder
+	spec.WriteString(`<specSet><propSet><type>` + xmlText(objType) + `</type>`)
+	for _, p := range props {
+		spec.WriteString(`<pathSet>` + xmlText(p) + `</pathSet>`)
+	}
+	spec.WriteString(`</propSet><objectSet>` + obj.xml("obj"))
+	if traversePath != "" {
+		spec.WriteString(`<skip>true</skip><selectSet xsi:type="TraversalSpec"><type>` + xmlText(obj.Type) + `</type><path>` + xmlText(traversePath) + `</path><skip>false</skip></selectSet>`)
+	}
+	spec.WriteString(`</objectSet></specSet>`)
+
+	var objects []objectContent
+	body := `<RetrievePropertiesEx xmlns="urn:vim25">` + c.content.PropertyCollector.xml("_this") + spec.String() + `<options/></RetrievePropertiesEx>`
+	for {
+		var res struct {
+			Returnval struct {
+				Token   string          `xml:"token"`
+				Objects []objectContent `xml:"objects"`
+			} `xml:"returnval"`
+		}
+		if err := c.call(ctx, body, &res); err != nil {
+			return nil, err
+		}
+		objects = append(objects, res.Returnval.Objects...)
+		if res.Returnval.Token == "" {
+			return objects, nil
+		}
+		body = `<ContinueRetrievePropertiesEx xmlns="urn:vim25">` + c.content.PropertyCollector.xml("_this") + `<token>` + xmlText(res.Returnval.Token) + `</token></ContinueRetrievePropertiesEx>`
+	}
+}
+
+// findByInventoryPath resolves a path like dc1/vm/team to its object.
+func (c *vimClient) findByInventoryPath(ctx context.Context, path string) (moref, bool, error) {
+	var res struct {
+		Returnval *moref `xml:"returnval"`
+	}
+	body := fmt.Sprintf(`<FindByInventoryPath xmlns="urn:vim25">%s<inventoryPath>%s</inventoryPath></FindByInventoryPath>`,
+		c.content.SearchIndex.xml("_this"), xmlText(path))
+	if err := c.call(ctx, body, &res); err != nil {
+		return moref{}, false, err
+	}
+	if res.Returnval == nil {
+		return moref{}, false, nil
+	}
+	return *res.Returnval, true, nil
+}
+
+// findVM looks for the virtual machine called name anywhere under container.
+func (c *vimClient) findVM(ctx context.Context, container moref, name string) (moref, error) {
+	var view struct {
+		Returnval moref `xml:"returnval"`
+	}
+	body := fmt.Sprintf(`<CreateContainerView xmlns="urn:vim25">%s%s<type>VirtualMachine</type><recursive>true</recursive></CreateContainerView>`,
+		c.content.ViewManager.xml("_this"), container.xml("container"))
+	if err := c.call(ctx, body, &view); err != nil {
+		return moref{}, fmt.Errorf("create view: %w", err)
+	}
+	defer c.call(ctx, fmt.Sprintf(`<DestroyView xmlns="urn:vim25">%s</DestroyView>`, view.Returnval.xml("_this")), nil)
+
+	objects, err := c.retrieve(ctx, view.Returnval, "VirtualMachine", "view", "name")
+	if err != nil {
+		return moref{}, err
+	}
+	var found []moref
+	for _, obj := range objects {
+		for _, p := range obj.PropSet {
+			if p.Name == "name" && p.Val.Text == name {
+				found = append(found, obj.Obj)
+			}
+		}
+	}
+	switch len(found) {
+	case 0:
+		return moref{}, fmt.Errorf("VM %q not found", name)
+	case 1:
+		return found[0], nil
+	default:
+		return moref{}, fmt.Errorf("VM name %q matches %d VMs; narrow it with vmware_datacenter or vmware_folder", name, len(found))
+	}
+}
+
+// runTask calls a method returning a Task and waits for the task to end.
+func (c *vimClient) runTask(ctx context.Context, body string) error {
+	var res struct {
+		Returnval moref `xml:"returnval"`
+	}
+	if err := c.call(ctx, body, &res); err != nil {
+		return err
+	}
+	task := res.Returnval
+
+	ticker := time.NewTicker(vimTaskPolling)
+	defer ticker.Stop()
+	for {
+		objects, err := c.retrieve(ctx, task, "Task", "", "info")
+		if err != nil {
+			return fmt.Errorf("task %s: %w", task.Value, err)
+		}
+		var info struct {
+			State string `xml:"state"`
+			Error *struct {
+				Fault struct {
+					Type string `xml:"http://www.w3.org/2001/XMLSchema-instance type,attr"`
+				} `xml:"fault"`
+				Message string `xml:"localizedMessage"`
+			} `xml:"error"`
+		}
+		if len(objects) == 1 && len(objects[0].PropSet) == 1 {
+			if err := objects[0].PropSet[0].decode(&info); err != nil {
+				return fmt.Errorf("task %s: %w", task.Value, err)
+			}
+		}
+		switch info.State {
+		case "success":
+			return nil
+		case "error":
+			if info.Error == nil {
+				return fmt.Errorf("task %s failed", task.Value)
+			}
+			return &vmwareFault{Type: info.Error.Fault.Type, Message: info.Error.Message}
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("task %s still %s: %w", task.Value, info.State, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+func xmlText(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
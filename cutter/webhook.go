@@ -0,0 +1,169 @@
+package cutter
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"atropos/internal/logger"
+)
+
+const defaultHTTPCallTimeout = 10 * time.Second
+
+// defaultHTTPCallBody is sent when the strategy gives no http_body.
+const defaultHTTPCallBody = `{"node": "{{node}}", "entropy": {{entropy}}, "action": "{{action}}", "cut_id": "{{cut_id}}"}`
+
+// WebhookCutter hands a cut to another system by calling its HTTP API.
+type WebhookCutter struct{}
+
+func NewWebhookCutter() *WebhookCutter {
+	return &WebhookCutter{}
+}
+
+func (w *WebhookCutter) Name() string {
+	return "webhook"
+}
+
+func (w *WebhookCutter) CanHandle(action string) bool {
+	return action == "http_call"
+}
+
+func (w *WebhookCutter) SupportedActions() []string {
+	return []string{"http_call"}
+}
+
+func (w *WebhookCutter) Execute(ctx context.Context, target string, params map[string]string) error {
+	if action := params["action"]; !w.CanHandle(action) {
+		return fmt.Errorf("unsupported action: %s", action)
+	}
+	url := params["http_url"]
+	if url == "" {
+		return fmt.Errorf("http_call requires http_url")
+	}
+	method := strings.ToUpper(params["http_method"])
+	if method == "" {
+		method = http.MethodPost
+	}
+	timeout := defaultHTTPCallTimeout
+	if raw := params["http_timeout_seconds"]; raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			return fmt.Errorf("http_timeout_seconds must be a positive integer")
+		}
+		timeout = time.Duration(n) * time.Second
+	}
+	insecure, _ := strconv.ParseBool(params["http_insecure"])
+
+	body, err := httpCallBody(target, params)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("http_url: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "atropos")
+	if id := params["cut_id"]; id != "" {
+		req.Header.Set("X-Atropos-Cut-Id", id)
+	}
+	if err := setHTTPCallHeaders(req, params["http_headers"]); err != nil {
+		return err
+	}
+	if token := params["http_bearer_token"]; token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	if secret := params["http_hmac_secret"]; secret != "" {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(body)
+		req.Header.Set("X-Atropos-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	logger.Get().Info("http_call_cut",
+		zap.String("target", target),
+		zap.String("method", method),
+		zap.String("url", url),
+	)
+
+	client, err := outboundClient(params, insecure)
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return stepError(ctx, "request", fmt.Errorf("%s %s: %w", method, url, err))
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, int64(outputMaxBytes(params))+1))
+	output := truncateOutput(string(data), outputMaxBytes(params))
+	RecordDetail(ctx, "status_code", resp.StatusCode)
+	if output != "" {
+		RecordOutput(ctx, output)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s %s: HTTP %d: %s", method, url, resp.StatusCode, output)
+	}
+	return nil
+}
+
+// httpCallBody fills the http_body template's {{node}}, {{entropy}},
+// {{action}} and {{cut_id}} placeholders, escaped for use inside JSON
+// strings, and checks the result is JSON.
+func httpCallBody(target string, params map[string]string) ([]byte, error) {
+	tmpl := params["http_body"]
+	if tmpl == "" {
+		tmpl = defaultHTTPCallBody
+	}
+	entropy := params["entropy"]
+	if entropy == "" {
+		entropy = "null"
+	}
+	body := strings.NewReplacer(
+		"{{node}}", jsonEscape(target),
+		"{{entropy}}", entropy,
+		"{{action}}", jsonEscape(params["action"]),
+		"{{cut_id}}", jsonEscape(params["cut_id"]),
+	).Replace(tmpl)
+	if !json.Valid([]byte(body)) {
+		return nil, fmt.Errorf("http_body is not valid JSON once filled in: %s", body)
+	}
+	return []byte(body), nil
+}
+
+// jsonEscape returns s as it would appear between the quotes of a JSON
+// string.
+func jsonEscape(s string) string {
+	quoted, _ := json.Marshal(s)
+	return string(quoted[1 : len(quoted)-1])
+}
+
+// setHTTPCallHeaders adds http_headers, one "Name: value" per line.
+func setHTTPCallHeaders(req *http.Request, raw string) error {
+	for _, line := range strings.Split(raw, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(line, ":")
+		if !ok || strings.TrimSpace(name) == "" {
+			return fmt.Errorf("http_headers: %q is not \"Name: value\"", line)
+		}
+		req.Header.Set(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+	return nil
+}
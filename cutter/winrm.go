@@ -0,0 +1,110 @@
+package cutter
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"atropos/internal/logger"
+)
+
+const defaultWinRMPort = "5986"
+
+// WinRMCutter runs PowerShell on Windows nodes over HTTPS WinRM, signing in
+// with NTLM.
+type WinRMCutter struct{}
+
+func NewWinRMCutter() *WinRMCutter {
+	return &WinRMCutter{}
+}
+
+func (w *WinRMCutter) Name() string {
+	return "winrm"
+}
+
+func (w *WinRMCutter) CanHandle(action string) bool {
+	return strings.HasPrefix(action, "winrm_")
+}
+
+func (w *WinRMCutter) SupportedActions() []string {
+	return []string{"winrm_exec", "winrm_restart_service", "winrm_shutdown"}
+}
+
+func (w *WinRMCutter) Execute(ctx context.Context, target string, params map[string]string) error {
+	action := params["action"]
+	host := params["host"]
+	if host == "" {
+		return fmt.Errorf("winrm cutter requires host for target %s", target)
+	}
+
+	var script string
+	switch action {
+	case "winrm_exec":
+		script = params["command"]
+		if script == "" {
+			return fmt.Errorf("winrm_exec requires command")
+		}
+	case "winrm_restart_service":
+		service := params["service"]
+		if service == "" {
+			return fmt.Errorf("winrm_restart_service requires the service param")
+		}
+		script = fmt.Sprintf("Restart-Service -Name %s -Force -ErrorAction Stop", powerShellQuote(service))
+	case "winrm_shutdown":
+		// A short delay lets the command report back before the
+		// connection goes away.
+		script = "shutdown.exe /s /f /t 5 /d p:0:0 /c 'Atropos cut'\nexit $LASTEXITCODE"
+	default:
+		return fmt.Errorf("unsupported action: %s", action)
+	}
+
+	user := paramOrEnv(params, "winrm_username", "WINRM_USERNAME")
+	if user == "" {
+		return fmt.Errorf("winrm cutter requires winrm_username or WINRM_USERNAME")
+	}
+	port := params["winrm_port"]
+	if port == "" {
+		port = defaultWinRMPort
+	}
+	url := "https://" + net.JoinHostPort(host, port) + "/wsman"
+	insecure, _ := strconv.ParseBool(paramOrEnv(params, "winrm_insecure", "WINRM_INSECURE"))
+
+	logger.Get().Info("winrm_cut",
+		zap.String("target", target),
+		zap.String("host", host),
+		zap.String("action", action),
+	)
+
+	c, err := newWinRMClient(url, user, paramOrEnv(params, "winrm_password", "WINRM_PASSWORD"), params["winrm_ca_file"], insecure, params)
+	if err != nil {
+		return err
+	}
+	defer c.close()
+
+	output, exitCode, err := c.runPowerShell(ctx, script)
+	if output != "" {
+		RecordOutput(ctx, truncateOutput(output, outputMaxBytes(params)))
+	}
+	if err != nil {
+		return stepError(ctx, "command", fmt.Errorf("winrm %s: %w", host, err))
+	}
+
+	RecordDetail(ctx, "exit_code", exitCode)
+	rule, evalErr := evaluateCommand(params, exitCode, output)
+	if rule != "" {
+		RecordDetail(ctx, "matched_rule", rule)
+	}
+	if evalErr != nil {
+		return fmt.Errorf("command failed: %w, output: %s", evalErr, output)
+	}
+	return nil
+}
+
+// powerShellQuote makes s a single-quoted PowerShell string.
+func powerShellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
@@ -0,0 +1,285 @@
+package cutter
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The WinRM cutter speaks WS-Management to the Windows Remote Shell: open
+// a shell, run one command in it, read its output until it ends, and
+// delete the shell.
+
+const (
+	wsmanShellURI       = "http://schemas.microsoft.com/wbem/wsman/1/windows/shell/cmd"
+	wsmanShellNamespace = "http://schemas.microsoft.com/wbem/wsman/1/windows/shell"
+	wsmanCommandDone    = wsmanShellNamespace + "/CommandState/Done"
+	wsmanSignalKill     = wsmanShellNamespace + "/signal/terminate"
+	// A Receive with no output before the operation timeout ends in this
+	// fault, and is simply asked again.
+	wsmanTimedOutCode = "2150858793"
+)
+
+// winrmFault is a WS-Management fault, with Windows' code and message.
+type winrmFault struct {
+	Code    string
+	Message string
+}
+
+func (f *winrmFault) Error() string {
+	if f.Code == "" {
+		return f.Message
+	}
+	return fmt.Sprintf("%s (WSManFault %s)", f.Message, f.Code)
+}
+
+type winrmClient struct {
+	url      string
+	user     string
+	password string
+	http     *http.Client
+}
+
+// newWinRMClient talks to url as user. caFile, when set, replaces the
+// system roots for the host's certificate; insecure skips the check.
+// params' proxy_url, when set, is the proxy to go through.
+func newWinRMClient(url, user, password, caFile string, insecure bool, params map[string]string) (*winrmClient, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: insecure}
+	if caFile != "" && !insecure {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("winrm_ca_file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("winrm_ca_file: no certificates in %s", caFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	// NTLM authenticates the connection, so both legs of the handshake
+	// must use the same one, and no other login may share it: the client
+	// is this one's alone.
+	client, transport, err := newOutboundClient(params, tlsConfig)
+	if err != nil {
+		return nil, err
+	}
+	transport.MaxConnsPerHost = 1
+	transport.MaxIdleConnsPerHost = 1
+	return &winrmClient{url: url, user: user, password: password, http: client}, nil
+}
+
+func (c *winrmClient) close() {
+	c.http.CloseIdleConnections()
+}
+
+// post authenticates with NTLM and sends body, a WS-Management envelope,
+// decoding the response's SOAP body into out.
+func (c *winrmClient) post(ctx context.Context, envelope string, out interface{}) error {
+	resp, err := c.send(ctx, "", "Negotiate "+base64.StdEncoding.EncodeToString(ntlmNegotiate()))
+	if err != nil {
+		return err
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		return fmt.Errorf("HTTP %d to NTLM negotiate", resp.StatusCode)
+	}
+	token := ""
+	for _, h := range resp.Header.Values("WWW-Authenticate") {
+		if t, ok := strings.CutPrefix(h, "Negotiate "); ok {
+			token = t
+		}
+	}
+	if token == "" {
+		return errors.New("server did not offer Negotiate authentication")
+	}
+	raw, err := base64.StdEncoding.DecodeString(token)
+	if err != nil {
+		return fmt.Errorf("NTLM challenge: %w", err)
+	}
+	challenge, err := parseNTLMChallenge(raw)
+	if err != nil {
+		return err
+	}
+	auth, err := ntlmAuthenticate(challenge, c.user, c.password)
+	if err != nil {
+		return err
+	}
+
+	resp, err = c.send(ctx, envelope, "Negotiate "+base64.StdEncoding.EncodeToString(auth))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusUnauthorized {
+		return fmt.Errorf("authentication failed for %s", c.user)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 16<<20))
+	if err != nil {
+		return err
+	}
+
+	var env struct {
+		Body struct {
+			Fault *struct {
+				Reason string `xml:"Reason>Text"`
+				Detail struct {
+					WSManFault struct {
+						Code    string `xml:"Code,attr"`
+						Message string `xml:"Message"`
+					} `xml:"WSManFault"`
+				} `xml:"Detail"`
+			} `xml:"Fault"`
+			Inner []byte `xml:",innerxml"`
+		} `xml:"Body"`
+	}
+	if err := xml.Unmarshal(data, &env); err != nil {
+		return fmt.Errorf("HTTP %d: unreadable response: %w", resp.StatusCode, err)
+	}
+	if f := env.Body.Fault; f != nil {
+		msg := strings.TrimSpace(f.Detail.WSManFault.Message)
+		if msg == "" {
+			msg = strings.TrimSpace(f.Reason)
+		}
+		return &winrmFault{Code: f.Detail.WSManFault.Code, Message: msg}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	if out == nil {
+		return nil
+	}
+	return xml.Unmarshal(bytes.TrimSpace(env.Body.Inner), out)
+}
+
+func (c *winrmClient) send(ctx context.Context, body, auth string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, strings.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/soap+xml;charset=UTF-8")
+	req.Header.Set("Authorization", auth)
+	return c.http.Do(req)
+}
+
+// envelope wraps body in a WS-Management request for action on the cmd
+// shell, or on shellID when it is set.
+func (c *winrmClient) envelope(action, shellID, options, body string) string {
+	id := make([]byte, 16)
+	rand.Read(id)
+	id[6] = id[6]&0x0f | 0x40
+	id[8] = id[8]&0x3f | 0x80
+	messageID := fmt.Sprintf("uuid:%x-%x-%x-%x-%x", id[0:4], id[4:6], id[6:8], id[8:10], id[10:])
+
+	var b strings.Builder
+	b.WriteString(`<s:Envelope xmlns:s="http://www.w3.org/2003/05/soap-envelope" xmlns:a="http://schemas.xmlsoap.org/ws/2004/08/addressing" xmlns:w="http://schemas.dmtf.org/wbem/wsman/1/wsman.xsd" xmlns:rsp="` + wsmanShellNamespace + `">`)
+	b.WriteString(`<s:Header>`)
+	b.WriteString(`<a:To>` + xmlText(c.url) + `</a:To>`)
+	b.WriteString(`<w:ResourceURI s:mustUnderstand="true">` + wsmanShellURI + `</w:ResourceURI>`)
+	b.WriteString(`<a:ReplyTo><a:Address s:mustUnderstand="true">http://schemas.xmlsoap.org/ws/2004/08/addressing/role/anonymous</a:Address></a:ReplyTo>`)
+	b.WriteString(`<a:Action s:mustUnderstand="true">` + action + `</a:Action>`)
+	b.WriteString(`<w:MaxEnvelopeSize s:mustUnderstand="true">153600</w:MaxEnvelopeSize>`)
+	b.WriteString(`<a:MessageID>` + messageID + `</a:MessageID>`)
+	b.WriteString(`<w:Locale xml:lang="en-US" s:mustUnderstand="false"/>`)
+	b.WriteString(`<w:OperationTimeout>PT20S</w:OperationTimeout>`)
+	if shellID != "" {
+		b.WriteString(`<w:SelectorSet><w:Selector Name="ShellId">` + xmlText(shellID) + `</w:Selector></w:SelectorSet>`)
+	}
+	b.WriteString(options)
+	b.WriteString(`</s:Header><s:Body>` + body + `</s:Body></s:Envelope>`)
+	return b.String()
+}
+
+// runPowerShell runs script in a new shell and returns its combined
+// output and exit code. The command is terminated if ctx ends first.
+func (c *winrmClient) runPowerShell(ctx context.Context, script string) (string, int, error) {
+	var shell struct {
+		ShellID string `xml:"ShellId"`
+	}
+	options := `<w:OptionSet><w:Option Name="WINRS_NOPROFILE">TRUE</w:Option><w:Option Name="WINRS_CODEPAGE">65001</w:Option></w:OptionSet>`
+	create := c.envelope("http://schemas.xmlsoap.org/ws/2004/09/transfer/Create", "", options,
+		`<rsp:Shell><rsp:InputStreams>stdin</rsp:InputStreams><rsp:OutputStreams>stdout stderr</rsp:OutputStreams></rsp:Shell>`)
+	if err := c.post(ctx, create, &shell); err != nil {
+		return "", 0, fmt.Errorf("create shell: %w", err)
+	}
+	defer c.deleteShell(shell.ShellID)
+
+	encoded := base64.StdEncoding.EncodeToString(utf16LE("$ProgressPreference = 'SilentlyContinue'\n" + script))
+	var cmd struct {
+		CommandID string `xml:"CommandId"`
+	}
+	command := c.envelope(wsmanShellNamespace+"/Command", shell.ShellID,
+		`<w:OptionSet><w:Option Name="WINRS_CONSOLEMODE_STDIN">TRUE</w:Option></w:OptionSet>`,
+		`<rsp:CommandLine><rsp:Command>powershell.exe</rsp:Command><rsp:Arguments>-NoProfile -NonInteractive -EncodedCommand `+encoded+`</rsp:Arguments></rsp:CommandLine>`)
+	if err := c.post(ctx, command, &cmd); err != nil {
+		return "", 0, fmt.Errorf("start command: %w", err)
+	}
+
+	var output bytes.Buffer
+	receive := c.envelope(wsmanShellNamespace+"/Receive", shell.ShellID, "",
+		`<rsp:Receive><rsp:DesiredStream CommandId="`+xmlText(cmd.CommandID)+`">stdout stderr</rsp:DesiredStream></rsp:Receive>`)
+	for {
+		var res struct {
+			Streams []struct {
+				Name string `xml:"Name,attr"`
+				Data string `xml:",chardata"`
+			} `xml:"Stream"`
+			State struct {
+				State    string `xml:"State,attr"`
+				ExitCode string `xml:"ExitCode"`
+			} `xml:"CommandState"`
+		}
+		err := c.post(ctx, receive, &res)
+		var fault *winrmFault
+		if errors.As(err, &fault) && fault.Code == wsmanTimedOutCode {
+			continue
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				c.terminate(shell.ShellID, cmd.CommandID)
+			}
+			return output.String(), 0, fmt.Errorf("receive output: %w", err)
+		}
+		for _, s := range res.Streams {
+			data, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s.Data))
+			if err != nil {
+				return output.String(), 0, fmt.Errorf("%s stream: %w", s.Name, err)
+			}
+			output.Write(data)
+		}
+		if res.State.State == wsmanCommandDone {
+			code, err := strconv.Atoi(strings.TrimSpace(res.State.ExitCode))
+			if err != nil {
+				return output.String(), 0, fmt.Errorf("exit code %q: %w", res.State.ExitCode, err)
+			}
+			return output.String(), code, nil
+		}
+	}
+}
+
+// terminate and deleteShell clean up after the cut, so they get their own
+// short deadline rather than the cut's.
+func (c *winrmClient) terminate(shellID, commandID string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_ = c.post(ctx, c.envelope(wsmanShellNamespace+"/Signal", shellID, "",
+		`<rsp:Signal CommandId="`+xmlText(commandID)+`"><rsp:Code>`+wsmanSignalKill+`</rsp:Code></rsp:Signal>`), nil)
+}
+
+func (c *winrmClient) deleteShell(shellID string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_ = c.post(ctx, c.envelope("http://schemas.xmlsoap.org/ws/2004/09/transfer/Delete", shellID, "", ""), nil)
+}
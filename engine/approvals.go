@@ -0,0 +1,314 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"atropos/cutter"
+	"atropos/history"
+	"atropos/internal/logger"
+	"atropos/internal/timefmt"
+	"atropos/policy"
+)
+
+func (e *Executor) requestApproval(node string, entropy float64, strategy *policy.Strategy, opts CutOptions) *cutter.CutResult {
+	now := time.Now().UTC()
+	approvalID := fmt.Sprintf("apr_%d_%s", now.UnixNano(), node)
+
+	result := &cutter.CutResult{
+		Target:  node,
+		Action:  strategy.Action,
+		Success: true,
+		Outcome: history.OutcomePendingApproval,
+	}
+
+	record := e.newRecord(node, entropy, strategy, result)
+	record.Approval = &history.Approval{
+		ID:     approvalID,
+		Status: history.ApprovalPending,
+	}
+	opts.apply(record)
+
+	pending := &history.PendingCut{
+		ID:        approvalID,
+		Node:      node,
+		Entropy:   entropy,
+		Action:    strategy.Action,
+		Threshold: strategy.Threshold,
+		CutID:     record.ID,
+		Tags:      opts.Tags,
+		Source:    opts.Source,
+		Reason:    opts.Reason,
+		CreatedAt: now,
+		ExpiresAt: now.Add(e.approvalTimeout()),
+	}
+
+	if e.history == nil {
+		result.Success = false
+		result.Outcome = history.OutcomeFailed
+		result.Error = fmt.Errorf("approval required but no history store configured")
+		return result
+	}
+
+	if err := e.history.Approvals().Enqueue(pending); err != nil {
+		result.Success = false
+		result.Outcome = history.OutcomeFailed
+		result.Error = fmt.Errorf("enqueue approval: %w", err)
+		record = e.newRecord(node, entropy, strategy, result)
+		opts.apply(record)
+	}
+
+	logger.Get().Info("cut_pending_approval",
+		zap.String("node", node),
+		zap.String("action", strategy.Action),
+		zap.String("approval_id", approvalID),
+		zap.Time("expires_at", pending.ExpiresAt),
+	)
+
+	e.recordCut(record, result)
+	return result
+}
+
+func pendingOptions(pending *history.PendingCut) CutOptions {
+	return CutOptions{Tags: pending.Tags, Source: pending.Source, Reason: pending.Reason}
+}
+
+func (e *Executor) approvalTimeout() time.Duration {
+	if pol := e.currentPolicy(); pol != nil {
+		return pol.GetApprovalTimeout()
+	}
+	return time.Hour
+}
+
+func (e *Executor) PendingApprovals() []*history.PendingCut {
+	e.expireApprovals()
+	return e.history.Approvals().List()
+}
+
+// Approve runs an approved cut. The node's lock and a cut slot are taken
+// before the approval is, so a cut that can't get a slot stays pending.
+func (e *Executor) Approve(ctx context.Context, id, by, reason string) (*cutter.CutResult, error) {
+	e.active.Add(1)
+	defer e.active.Add(-1)
+
+	if e.closing.Load() {
+		return nil, ErrShuttingDown
+	}
+	e.expireApprovals()
+
+	queued, err := e.history.Approvals().Get(id)
+	if err != nil {
+		return nil, err
+	}
+	unlock := e.nodeLocks.lock(queued.Node)
+	defer unlock()
+	release, err := e.slots.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	if e.closing.Load() {
+		return nil, ErrShuttingDown
+	}
+	pending, err := e.history.Approvals().Take(id)
+	if err != nil {
+		return nil, err
+	}
+
+	approval := &history.Approval{
+		ID:        pending.ID,
+		Status:    history.ApprovalApproved,
+		DecidedBy: by,
+		DecidedAt: time.Now().UTC(),
+		Reason:    reason,
+	}
+
+	logger.Get().Info("cut_approved",
+		zap.String("node", pending.Node),
+		zap.String("action", pending.Action),
+		zap.String("approval_id", pending.ID),
+		zap.String("approved_by", by),
+	)
+
+	attempt, result := e.admitApproved(pending, approval)
+	if attempt == nil {
+		return result, nil
+	}
+	return e.runSlotted(ctx, attempt), nil
+}
+
+// admitApproved runs an approved cut's guards under e.mu, as admitCut does.
+// Everything but the triggers is checked again: the node may have changed
+// while the cut waited. A dependency block refuses the cut whatever the
+// node's dependency_mode, since deferring would drop the approval.
+func (e *Executor) admitApproved(pending *history.PendingCut, approval *history.Approval) (*cutAttempt, *cutter.CutResult) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	pol := e.currentPolicy()
+	nodePolicy, ok := e.lookupNode(pol, pending.Node)
+	if !ok {
+		return nil, e.failApproved(pending, approval, fmt.Errorf("unknown node: %s", pending.Node))
+	}
+	strategy, ok := nodePolicy.SelectStrategyByAction(pending.Action)
+	if !ok {
+		return nil, e.failApproved(pending, approval, fmt.Errorf("strategy %s no longer in policy for node %s", pending.Action, pending.Node))
+	}
+	// The windows and blackouts that applied when the cut was requested may
+	// not apply now.
+	if err := e.checkTimeWindows(nodePolicy); err != nil {
+		return nil, e.skipApproved(pending, approval, history.OutcomeSkippedTimeWindow, err, nil)
+	}
+	if blackout, ok := pol.ActiveBlackout(nodePolicy, time.Now()); ok {
+		return nil, e.skipApproved(pending, approval, history.OutcomeSkippedBlackout,
+			fmt.Errorf("blackout period in effect until %s: %s", timefmt.RFC3339(blackout.End), blackout.Description),
+			map[string]interface{}{
+				"blackout":     blackout.Description,
+				"blackout_end": timefmt.RFC3339(blackout.End),
+			})
+	}
+	if until, open := e.breakers.open(pending.Node, time.Now()); open {
+		return nil, e.skipApproved(pending, approval, history.OutcomeCircuitOpen,
+			fmt.Errorf("circuit open for node %s until %s", pending.Node, timefmt.RFC3339(until)), nil)
+	}
+	if block := e.CheckDependencies(nodePolicy); block != nil {
+		logger.Get().Warn("cut_blocked_by_dependency",
+			zap.String("node", pending.Node),
+			zap.String("action", strategy.Action),
+			zap.String("dependency", block.Node),
+			zap.String("reason", block.Reason),
+			zap.String("approval_id", pending.ID),
+		)
+		return nil, e.skipApproved(pending, approval, history.OutcomeBlocked,
+			fmt.Errorf("blocked by dependency %s: %s", block.Node, block.Reason),
+			map[string]interface{}{
+				"blocked_by":   block.Node,
+				"block_reason": block.Reason,
+			})
+	}
+	var rateLimit rateLimitCharge
+	if !policy.ObserveOnly(strategy.Action) {
+		var refused *cutter.CutResult
+		if rateLimit, refused = e.admitRateLimit(pending.Node, nodePolicy, strategy); refused != nil {
+			return nil, e.skipApproved(pending, approval, refused.Outcome, refused.Error, refused.Details)
+		}
+	}
+
+	logger.CutInitiated(pending.Node, strategy.Action, pending.Entropy)
+
+	return e.admitted(&cutAttempt{
+		policy:     pol,
+		node:       pending.Node,
+		entropy:    pending.Entropy,
+		nodePolicy: nodePolicy,
+		strategy:   strategy,
+		approval:   approval,
+		opts:       pendingOptions(pending),
+		rateLimit:  rateLimit,
+	}), nil
+}
+
+func (e *Executor) Reject(id, by, reason string) error {
+	pending, err := e.history.Approvals().Take(id)
+	if err != nil {
+		return err
+	}
+
+	logger.Get().Info("cut_rejected",
+		zap.String("node", pending.Node),
+		zap.String("action", pending.Action),
+		zap.String("approval_id", pending.ID),
+		zap.String("rejected_by", by),
+	)
+
+	e.recordDecision(pending, &history.Approval{
+		ID:        pending.ID,
+		Status:    history.ApprovalRejected,
+		DecidedBy: by,
+		DecidedAt: time.Now().UTC(),
+		Reason:    reason,
+	})
+	return nil
+}
+
+// StartApprovalSweeper expires pending approvals on a timer, so their
+// records and notifications don't wait for someone to list or approve.
+func (e *Executor) StartApprovalSweeper(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			e.expireApprovals()
+		}
+	}()
+}
+
+func (e *Executor) expireApprovals() {
+	now := time.Now()
+	expired, err := e.history.Approvals().TakeExpired(now)
+	if err != nil {
+		logger.Get().Error("failed_to_expire_approvals", zap.Error(err))
+		return
+	}
+
+	for _, pending := range expired {
+		logger.Get().Warn("cut_approval_expired",
+			zap.String("node", pending.Node),
+			zap.String("action", pending.Action),
+			zap.String("approval_id", pending.ID),
+		)
+		e.recordDecision(pending, &history.Approval{
+			ID:        pending.ID,
+			Status:    history.ApprovalExpired,
+			DecidedBy: "system",
+			DecidedAt: pending.ExpiresAt.UTC(),
+			Reason:    "approval window expired",
+		})
+	}
+}
+
+// recordDecision records a rejected or expired approval. Nothing ran, so it
+// is not a success.
+func (e *Executor) recordDecision(pending *history.PendingCut, approval *history.Approval) {
+	result := &cutter.CutResult{
+		Target:  pending.Node,
+		Action:  pending.Action,
+		Success: false,
+		Outcome: history.OutcomeRejected,
+	}
+	strategy := &policy.Strategy{Action: pending.Action, Threshold: pending.Threshold, ApprovalRequired: true}
+
+	record := e.newRecord(pending.Node, pending.Entropy, strategy, result)
+	record.Approval = approval
+	pendingOptions(pending).apply(record)
+	e.recordCut(record, result)
+}
+
+func (e *Executor) failApproved(pending *history.PendingCut, approval *history.Approval, err error) *cutter.CutResult {
+	return e.skipApproved(pending, approval, "", err, nil)
+}
+
+// skipApproved records an approved cut that did not run, with outcome
+// failed, or skipped_* when a guard refused it at approval time.
+func (e *Executor) skipApproved(pending *history.PendingCut, approval *history.Approval, outcome string, err error, details map[string]interface{}) *cutter.CutResult {
+	logger.CutFailed(pending.Node, pending.Action, err)
+	result := &cutter.CutResult{
+		Target:  pending.Node,
+		Action:  pending.Action,
+		Success: false,
+		Outcome: outcome,
+		Error:   err,
+		Details: details,
+	}
+	strategy := &policy.Strategy{Action: pending.Action, Threshold: pending.Threshold, ApprovalRequired: true}
+
+	record := e.newRecord(pending.Node, pending.Entropy, strategy, result)
+	record.Approval = approval
+	pendingOptions(pending).apply(record)
+	e.recordCut(record, result)
+	return result
+}
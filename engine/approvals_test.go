@@ -0,0 +1,242 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"atropos/history"
+)
+
+const approvalPolicy = `
+nodes:
+  db:
+    strategies:
+      - threshold: 0.5
+        action: test_failover
+        approval_required: true
+`
+
+func TestApproveRechecksBlackout(t *testing.T) {
+	e, c := newTestExecutor(t, approvalPolicy)
+	pending := e.ExecuteCut(context.Background(), "db", 0.9)
+	if pending.Outcome != history.OutcomePendingApproval {
+		t.Fatalf("outcome = %q, want pending_approval", pending.Outcome)
+	}
+	approvals := e.PendingApprovals()
+	if len(approvals) != 1 {
+		t.Fatalf("%d pending approvals, want 1", len(approvals))
+	}
+
+	now := time.Now().UTC()
+	e.SetPolicy(loadTestPolicy(t, approvalPolicy+`
+blackout_periods:
+  - start: "`+now.Add(-time.Hour).Format(time.RFC3339)+`"
+    end: "`+now.Add(time.Hour).Format(time.RFC3339)+`"
+    description: change freeze
+`))
+
+	result, err := e.Approve(context.Background(), approvals[0].ID, "alice", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Success || result.Outcome != history.OutcomeSkippedBlackout {
+		t.Fatalf("approved during blackout: success %v, outcome %q", result.Success, result.Outcome)
+	}
+	if calls := c.Calls(); len(calls) != 0 {
+		t.Fatalf("cutter ran during the blackout: %v", calls)
+	}
+	record, err := e.GetHistory().LoadCut(result.CutID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.Approval == nil || record.Approval.DecidedBy != "alice" {
+		t.Fatalf("record approval = %+v, want alice's", record.Approval)
+	}
+}
+
+func TestApprovalSweeperExpires(t *testing.T) {
+	e, _ := newTestExecutor(t, approvalPolicy)
+	expired := time.Now().Add(-time.Minute).UTC()
+	err := e.GetHistory().Approvals().Enqueue(&history.PendingCut{
+		ID:        "apr_expired_db",
+		Node:      "db",
+		Entropy:   0.9,
+		Action:    "test_failover",
+		Threshold: 0.5,
+		CreatedAt: expired.Add(-time.Hour),
+		ExpiresAt: expired,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e.StartApprovalSweeper(10 * time.Millisecond)
+	var records []*history.CutRecord
+	waitFor(t, "the sweeper to expire the approval", func() bool {
+		records, _ = e.GetHistory().ListCutsByNode("db", 0)
+		return len(records) == 1
+	})
+	if len(e.GetHistory().Approvals().List()) != 0 {
+		t.Fatal("expired approval still pending")
+	}
+	record := records[0]
+	if record.Success || record.Outcome != history.OutcomeRejected || record.Approval.Status != history.ApprovalExpired {
+		t.Fatalf("expired record: success %v, outcome %q, approval %+v", record.Success, record.Outcome, record.Approval)
+	}
+}
+
+func TestRejectedApprovalIsNotSuccess(t *testing.T) {
+	e, _ := newTestExecutor(t, approvalPolicy)
+	e.ExecuteCut(context.Background(), "db", 0.9)
+	approvals := e.PendingApprovals()
+	if len(approvals) != 1 {
+		t.Fatalf("%d pending approvals, want 1", len(approvals))
+	}
+	if err := e.Reject(approvals[0].ID, "bob", "not now"); err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err := e.GetHistory().GetStats(history.Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.SuccessCuts != 0 {
+		t.Fatalf("rejected approval counted as %d successful cuts", stats.SuccessCuts)
+	}
+	records, _ := e.GetHistory().ListCutsByNode("db", 0)
+	for _, record := range records {
+		if record.Outcome == history.OutcomeRejected && record.Success {
+			t.Fatalf("rejected record stored as a success: %+v", record)
+		}
+	}
+}
+
+const approvalGuardPolicy = `
+server:
+  dedup_window_seconds: 0
+nodes:
+  cache:
+    strategies:
+      - threshold: 0.5
+        action: test_restart
+  db:
+    depends_on: [cache]
+    rate_limit:
+      max_cuts: 1
+      window_minutes: 60
+    circuit_breaker:
+      failure_threshold: 1
+    strategies:
+      - threshold: 0.3
+        action: test_restart
+      - threshold: 0.5
+        action: test_failover
+        approval_required: true
+`
+
+// requestApproval queues a cut on db for approval and returns its ID.
+func requestApproval(t *testing.T, e *Executor) string {
+	t.Helper()
+	before := len(e.PendingApprovals())
+	if result := e.ExecuteCut(context.Background(), "db", 0.9); result.Outcome != history.OutcomePendingApproval {
+		t.Fatalf("outcome = %q (%v), want pending_approval", result.Outcome, result.Error)
+	}
+	approvals := e.PendingApprovals()
+	if len(approvals) != before+1 {
+		t.Fatalf("%d pending approvals, want %d", len(approvals), before+1)
+	}
+	return approvals[len(approvals)-1].ID
+}
+
+// Only an approved cut that runs counts against the rate limit.
+func TestApprovalChargesRateLimitWhenRun(t *testing.T) {
+	e, c := newTestExecutor(t, approvalGuardPolicy)
+	if err := e.Reject(requestApproval(t, e), "bob", ""); err != nil {
+		t.Fatal(err)
+	}
+	expired, err := e.GetHistory().Approvals().Get(requestApproval(t, e))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expired.ExpiresAt = time.Now().Add(-time.Second)
+	e.expireApprovals()
+
+	id := requestApproval(t, e)
+	if status, _ := e.RateLimitStatus("db"); status.Count != 0 {
+		t.Fatalf("rate limit count %d with only a pending approval, want 0", status.Count)
+	}
+	result, err := e.Approve(context.Background(), id, "alice", "")
+	if err != nil || !result.Success {
+		t.Fatalf("approved cut: %+v, %v", result, err)
+	}
+	if result := e.ExecuteCut(context.Background(), "db", 0.9); result.Outcome != history.OutcomeSkippedRateLimited {
+		t.Fatalf("cut after the approved one: outcome %q, want skipped_rate_limited", result.Outcome)
+	}
+	if calls := len(c.Calls()); calls != 1 {
+		t.Fatalf("cutter ran %d times, want the approved cut only", calls)
+	}
+}
+
+// Approving runs the breaker, dependency and rate-limit guards again.
+func TestApproveRechecksGuards(t *testing.T) {
+	cases := []struct {
+		name    string
+		setup   func(t *testing.T, e *Executor, c *testCutter)
+		outcome string
+	}{
+		{
+			name: "circuit open",
+			setup: func(t *testing.T, e *Executor, c *testCutter) {
+				c.fail["test_restart"] = errors.New("boom")
+				if result := e.ExecuteCut(context.Background(), "db", 0.4); result.Success {
+					t.Fatal("failing cut succeeded")
+				}
+			},
+			outcome: history.OutcomeCircuitOpen,
+		},
+		{
+			name: "dependency in flight",
+			setup: func(t *testing.T, e *Executor, c *testCutter) {
+				e.beginFlight("cache")
+			},
+			outcome: history.OutcomeBlocked,
+		},
+		{
+			name: "rate limit",
+			setup: func(t *testing.T, e *Executor, c *testCutter) {
+				if result := e.ExecuteCut(context.Background(), "db", 0.4); !result.Success {
+					t.Fatalf("cut: %v", result.Error)
+				}
+			},
+			outcome: history.OutcomeSkippedRateLimited,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			e, c := newTestExecutor(t, approvalGuardPolicy)
+			id := requestApproval(t, e)
+			tc.setup(t, e, c)
+			calls := len(c.Calls())
+
+			result, err := e.Approve(context.Background(), id, "alice", "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Success || result.Outcome != tc.outcome {
+				t.Fatalf("approved cut: success %v, outcome %q (%v); want %s", result.Success, result.Outcome, result.Error, tc.outcome)
+			}
+			if len(c.Calls()) != calls {
+				t.Fatalf("cutter ran for the refused approval: %v", c.Calls())
+			}
+			record, err := e.GetHistory().LoadCut(result.CutID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if record.Outcome != tc.outcome || record.Approval == nil || record.Approval.DecidedBy != "alice" {
+				t.Fatalf("record: outcome %q, approval %+v", record.Outcome, record.Approval)
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package engine
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	"atropos/cutter"
+	"atropos/internal/logger"
+	"atropos/policy"
+)
+
+type BatchCut struct {
+	Node    string
+	Entropy float64
+	Opts    CutOptions
+}
+
+type BatchResult struct {
+	Step   policy.OrderStep
+	Result *cutter.CutResult
+}
+
+// ExecuteBatch runs the cuts one after another in dependency order, so each
+// dependency check sees the outcome of the cuts placed before it. Each cut
+// gets the policy's cut deadline, as a single cut's caller waits that long.
+func (e *Executor) ExecuteBatch(ctx context.Context, cuts []BatchCut) []BatchResult {
+	byNode := make(map[string]BatchCut, len(cuts))
+	nodes := make([]string, 0, len(cuts))
+	for _, cut := range cuts {
+		byNode[cut.Node] = cut
+		nodes = append(nodes, cut.Node)
+	}
+
+	var (
+		steps    []policy.OrderStep
+		deadline time.Duration
+	)
+	if pol := e.currentPolicy(); pol != nil {
+		steps = pol.OrderBatch(nodes)
+		deadline = pol.GetCutDeadline()
+	} else {
+		for i, node := range nodes {
+			steps = append(steps, policy.OrderStep{Node: node, Position: i, Reason: "request order"})
+		}
+	}
+
+	results := make([]BatchResult, 0, len(steps))
+	for _, step := range steps {
+		logger.Get().Info("batch_cut_ordered",
+			zap.String("node", step.Node),
+			zap.Int("position", step.Position),
+			zap.Strings("after", step.After),
+			zap.String("reason", step.Reason),
+		)
+		cut := byNode[step.Node]
+		cutCtx, cancel := ctx, context.CancelFunc(func() {})
+		if deadline > 0 {
+			cutCtx, cancel = context.WithTimeout(ctx, deadline)
+		}
+		results = append(results, BatchResult{
+			Step:   step,
+			Result: e.ExecuteCutWith(cutCtx, cut.Node, cut.Entropy, cut.Opts),
+		})
+		cancel()
+	}
+	return results
+}
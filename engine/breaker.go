@@ -0,0 +1,155 @@
+package engine
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"atropos/cutter"
+	"atropos/internal/logger"
+	"atropos/policy"
+)
+
+type CircuitState struct {
+	Open                bool       `json:"open"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	OpenedAt            *time.Time `json:"opened_at,omitempty"`
+	OpenUntil           *time.Time `json:"open_until,omitempty"`
+	LastError           string     `json:"last_error,omitempty"`
+}
+
+type circuit struct {
+	failures  []time.Time
+	openedAt  time.Time
+	openUntil time.Time
+	lastError string
+	window    time.Duration
+}
+
+type circuitBreakers struct {
+	nodes map[string]*circuit
+	mu    sync.Mutex
+}
+
+func newCircuitBreakers() *circuitBreakers {
+	return &circuitBreakers{nodes: make(map[string]*circuit)}
+}
+
+// open reports whether cuts for the node are currently short-circuited.
+func (b *circuitBreakers) open(node string, now time.Time) (time.Time, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c, ok := b.nodes[node]
+	if !ok || c.openUntil.IsZero() {
+		return time.Time{}, false
+	}
+	if now.Before(c.openUntil) {
+		return c.openUntil, true
+	}
+	// Cool-off is over: let the next cut through and start counting afresh.
+	delete(b.nodes, node)
+	return time.Time{}, false
+}
+
+// observe records a finished cut and returns true when this failure opened
+// the breaker.
+func (b *circuitBreakers) observe(node string, cfg policy.CircuitBreaker, result *cutter.CutResult, now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if result.Success {
+		delete(b.nodes, node)
+		return false
+	}
+
+	c, ok := b.nodes[node]
+	if !ok {
+		c = &circuit{}
+		b.nodes[node] = c
+	}
+
+	c.window = time.Duration(cfg.WindowMinutes) * time.Minute
+	cutoff := now.Add(-c.window)
+	kept := c.failures[:0]
+	for _, t := range c.failures {
+		if t.After(cutoff) {
+			kept = append(kept, t)
+		}
+	}
+	c.failures = append(kept, now)
+	if result.Error != nil {
+		c.lastError = result.Error.Error()
+	}
+
+	if len(c.failures) < cfg.FailureThreshold || !c.openUntil.IsZero() {
+		return false
+	}
+	c.openedAt = now
+	c.openUntil = now.Add(time.Duration(cfg.CooloffMinutes) * time.Minute)
+	return true
+}
+
+func (b *circuitBreakers) reset(node string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	_, ok := b.nodes[node]
+	delete(b.nodes, node)
+	return ok
+}
+
+func (b *circuitBreakers) state(node string, now time.Time) CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c, ok := b.nodes[node]
+	if !ok {
+		return CircuitState{}
+	}
+	state := CircuitState{ConsecutiveFailures: len(c.failures), LastError: c.lastError}
+	if !c.openUntil.IsZero() && now.Before(c.openUntil) {
+		openedAt, openUntil := c.openedAt, c.openUntil
+		state.Open = true
+		state.OpenedAt = &openedAt
+		state.OpenUntil = &openUntil
+	}
+	return state
+}
+
+// observeCircuit feeds a finished cut into the node's breaker. Refusals that
+// never reached a cutter don't count either way.
+func (e *Executor) observeCircuit(attempt *cutAttempt, result *cutter.CutResult) {
+	cfg := attempt.nodePolicy.GetCircuitBreaker()
+	if cfg.Disabled || result.DryRun || policy.ObserveOnly(result.Action) || errors.Is(result.Error, ErrExecutorSaturated) {
+		return
+	}
+
+	if !e.breakers.observe(attempt.node, cfg, result, time.Now()) {
+		return
+	}
+
+	state := e.breakers.state(attempt.node, time.Now())
+	logger.Get().Error("circuit_opened",
+		zap.String("node", attempt.node),
+		zap.Int("failures", state.ConsecutiveFailures),
+		zap.Timep("open_until", state.OpenUntil),
+		zap.String("last_error", state.LastError),
+	)
+	e.events.publish(Event{
+		Type:    EventCircuitOpened,
+		Node:    attempt.node,
+		Action:  result.Action,
+		Circuit: &state,
+	})
+}
+
+// ResetCircuit closes the node's breaker and clears its failure count.
+func (e *Executor) ResetCircuit(node string) bool {
+	reset := e.breakers.reset(node)
+	if reset {
+		logger.Get().Info("circuit_reset", zap.String("node", node))
+	}
+	return reset
+}
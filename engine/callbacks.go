@@ -0,0 +1,177 @@
+package engine
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+
+	"atropos/history"
+	"atropos/internal/httpclient"
+	"atropos/internal/logger"
+	"atropos/notifications"
+	"atropos/policy"
+)
+
+const callbackBuffer = 1024
+
+// CallbackPayload is the outcome report POSTed back to the caller after an
+// executed cut. It is signed with callbacks.secret in X-Atropos-Signature.
+type CallbackPayload struct {
+	CutID      string    `json:"cut_id"`
+	Node       string    `json:"node"`
+	Action     string    `json:"action"`
+	Success    bool      `json:"success"`
+	DryRun     bool      `json:"dry_run,omitempty"`
+	ReasonCode string    `json:"reason_code"`
+	Error      string    `json:"error,omitempty"`
+	LatencyMs  int64     `json:"latency_ms"`
+	Timestamp  time.Time `json:"timestamp"`
+	KeyID      string    `json:"key_id,omitempty"`
+}
+
+type CallbackStats struct {
+	Delivered     int64      `json:"delivered"`
+	Failed        int64      `json:"failed"`
+	Retries       int64      `json:"retries"`
+	LastError     string     `json:"last_error,omitempty"`
+	LastFailureAt *time.Time `json:"last_failure_at,omitempty"`
+}
+
+type callbackStats struct {
+	delivered   atomic.Int64
+	failed      atomic.Int64
+	retries     atomic.Int64
+	lastError   string
+	lastFailure time.Time
+	mu          sync.Mutex
+}
+
+func (e *Executor) CallbackStats() CallbackStats {
+	s := e.callbacks
+	stats := CallbackStats{
+		Delivered: s.delivered.Load(),
+		Failed:    s.failed.Load(),
+		Retries:   s.retries.Load(),
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.lastFailure.IsZero() {
+		at := s.lastFailure
+		stats.LastError = s.lastError
+		stats.LastFailureAt = &at
+	}
+	return stats
+}
+
+// runCallbacks reports executed cuts back to their callers, one at a time and
+// in publish order, like the notification subscriber.
+func (e *Executor) runCallbacks(events <-chan Event) {
+	var (
+		client   *http.Client
+		clientOf policy.CallbackConfig
+	)
+	for event := range events {
+		record := event.Record
+		if record == nil || !record.Executed() || policy.ObserveOnly(record.Action) {
+			continue
+		}
+		pol := e.currentPolicy()
+		target := pol.CallbackURLFor(record.CallbackURL, record.KeyID)
+		if target == "" {
+			continue
+		}
+
+		cfg := pol.Callbacks
+		if client == nil || cfg.ProxyURL != clientOf.ProxyURL || cfg.TimeoutSeconds != clientOf.TimeoutSeconds {
+			c, err := httpclient.New(cfg.GetTimeout(), cfg.ProxyURL)
+			if err != nil {
+				e.callbackFailed(record, target, err)
+				continue
+			}
+			client, clientOf = c, *cfg
+		}
+		e.deliverCallback(client, cfg, target, record)
+	}
+}
+
+func (e *Executor) deliverCallback(client *http.Client, cfg *policy.CallbackConfig, target string, record *history.CutRecord) {
+	payload, err := json.Marshal(newCallbackPayload(record))
+	if err != nil {
+		e.callbackFailed(record, target, err)
+		return
+	}
+
+	mac := hmac.New(sha256.New, []byte(cfg.SecretValue()))
+	mac.Write(payload)
+	headers := map[string]string{
+		"X-Atropos-Signature": "sha256=" + hex.EncodeToString(mac.Sum(nil)),
+		"X-Atropos-Cut-Id":    record.ID,
+	}
+
+	attempts, err := notifications.PostJSON(client, target, payload, headers, cfg.Retries)
+	if attempts > 1 {
+		e.callbacks.retries.Add(int64(attempts - 1))
+	}
+	if err != nil {
+		e.callbackFailed(record, target, err)
+		return
+	}
+	e.callbacks.delivered.Add(1)
+	logger.Get().Debug("callback_delivered",
+		zap.String("cut_id", record.ID),
+		zap.String("node", record.Node),
+		zap.Int("attempts", attempts),
+	)
+}
+
+func (e *Executor) callbackFailed(record *history.CutRecord, target string, err error) {
+	s := e.callbacks
+	s.failed.Add(1)
+	s.mu.Lock()
+	s.lastError = err.Error()
+	s.lastFailure = time.Now().UTC()
+	s.mu.Unlock()
+
+	logger.Get().Error("callback_failed",
+		zap.Error(err),
+		zap.String("cut_id", record.ID),
+		zap.String("node", record.Node),
+		zap.String("url", target),
+	)
+}
+
+func newCallbackPayload(record *history.CutRecord) CallbackPayload {
+	return CallbackPayload{
+		CutID:      record.ID,
+		Node:       record.Node,
+		Action:     record.Action,
+		Success:    record.Success,
+		DryRun:     record.DryRun,
+		ReasonCode: callbackReasonCode(record),
+		Error:      record.Error,
+		LatencyMs:  record.LatencyMs,
+		Timestamp:  record.Timestamp,
+		KeyID:      record.KeyID,
+	}
+}
+
+// callbackReasonCode condenses a record into a stable code: the outcome when
+// it says more than success, otherwise dry_run, success or failed.
+func callbackReasonCode(record *history.CutRecord) string {
+	switch {
+	case !history.PlainOutcome(record.Outcome):
+		return record.Outcome
+	case record.DryRun:
+		return "dry_run"
+	case record.Success:
+		return "success"
+	}
+	return "failed"
+}
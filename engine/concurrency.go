@@ -0,0 +1,115 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var ErrExecutorSaturated = errors.New("executor saturated")
+
+type cutSlots struct {
+	sem      chan struct{}
+	timeout  time.Duration
+	inFlight atomic.Int64
+	queued   atomic.Int64
+}
+
+func newCutSlots(max int, timeout time.Duration) *cutSlots {
+	s := &cutSlots{timeout: timeout}
+	if max > 0 {
+		s.sem = make(chan struct{}, max)
+	}
+	return s
+}
+
+func (s *cutSlots) acquire(ctx context.Context) (func(), error) {
+	release := func() {
+		s.inFlight.Add(-1)
+		if s.sem != nil {
+			<-s.sem
+		}
+	}
+
+	if s.sem == nil {
+		s.inFlight.Add(1)
+		return release, nil
+	}
+
+	select {
+	case s.sem <- struct{}{}:
+		s.inFlight.Add(1)
+		return release, nil
+	default:
+	}
+
+	s.queued.Add(1)
+	defer s.queued.Add(-1)
+
+	timer := time.NewTimer(s.timeout)
+	defer timer.Stop()
+
+	select {
+	case s.sem <- struct{}{}:
+		s.inFlight.Add(1)
+		return release, nil
+	case <-timer.C:
+		return nil, fmt.Errorf("%w: no slot free after %s (max_concurrent_cuts=%d)", ErrExecutorSaturated, s.timeout, cap(s.sem))
+	case <-ctx.Done():
+		return nil, fmt.Errorf("waiting for cut slot: %w", ctx.Err())
+	}
+}
+
+// nodeLocks serializes cuts on the same node and leaves different nodes to
+// run side by side. A node's entry is dropped once nobody holds or waits for
+// it.
+type nodeLocks struct {
+	mu    sync.Mutex
+	nodes map[string]*nodeLock
+}
+
+type nodeLock struct {
+	mu   sync.Mutex
+	refs int
+}
+
+func (l *nodeLocks) lock(node string) func() {
+	l.mu.Lock()
+	if l.nodes == nil {
+		l.nodes = make(map[string]*nodeLock)
+	}
+	nl := l.nodes[node]
+	if nl == nil {
+		nl = &nodeLock{}
+		l.nodes[node] = nl
+	}
+	nl.refs++
+	l.mu.Unlock()
+
+	nl.mu.Lock()
+	return func() {
+		nl.mu.Unlock()
+		l.mu.Lock()
+		if nl.refs--; nl.refs == 0 {
+			delete(l.nodes, node)
+		}
+		l.mu.Unlock()
+	}
+}
+
+type ConcurrencyStatus struct {
+	InFlight      int64 `json:"in_flight"`
+	Queued        int64 `json:"queued"`
+	MaxConcurrent int   `json:"max_concurrent,omitempty"`
+}
+
+func (e *Executor) Concurrency() ConcurrencyStatus {
+	return ConcurrencyStatus{
+		InFlight:      e.slots.inFlight.Load(),
+		Queued:        e.slots.queued.Load(),
+		MaxConcurrent: cap(e.slots.sem),
+	}
+}
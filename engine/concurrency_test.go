@@ -0,0 +1,119 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"atropos/cutter"
+)
+
+const concurrencyPolicy = `
+server:
+  dedup_window_seconds: 0
+  max_concurrent_cuts: 2
+  cut_queue_timeout_seconds: 5
+nodes:
+  athena:
+    strategies:
+      - threshold: 0.5
+        action: test_restart
+  borg:
+    strategies:
+      - threshold: 0.5
+        action: test_restart
+  clotho:
+    strategies:
+      - threshold: 0.5
+        action: test_restart
+`
+
+// Cuts on different nodes run side by side up to max_concurrent_cuts; the
+// next waits for a slot, not for the others to finish deciding.
+func TestCutsOnDifferentNodesRunConcurrently(t *testing.T) {
+	e, c := newTestExecutor(t, concurrencyPolicy)
+	block := make(chan struct{})
+	c.block = block
+
+	athena := startCut(e, "athena")
+	borg := startCut(e, "borg")
+	waitFor(t, "both cuts to reach the cutter", func() bool { return len(c.Calls()) == 2 })
+	if status := e.Concurrency(); status.InFlight != 2 || status.Queued != 0 {
+		t.Fatalf("concurrency = %+v, want 2 in flight", status)
+	}
+
+	clotho := startCut(e, "clotho")
+	waitFor(t, "the third cut to queue", func() bool { return e.Concurrency().Queued == 1 })
+	if calls := len(c.Calls()); calls != 2 {
+		t.Fatalf("cutter ran %d times with both slots taken, want 2", calls)
+	}
+
+	close(block)
+	for _, done := range []<-chan *cutter.CutResult{athena, borg, clotho} {
+		if result := <-done; !result.Success {
+			t.Fatalf("%s: %v", result.Target, result.Error)
+		}
+	}
+	calls := c.Calls()
+	sort.Strings(calls)
+	if len(calls) != 3 || calls[2] != "clotho: test_restart" {
+		t.Fatalf("cutter calls %q, want one per node", calls)
+	}
+	if status := e.Concurrency(); status.InFlight != 0 || status.Queued != 0 {
+		t.Fatalf("concurrency after the cuts = %+v, want idle", status)
+	}
+}
+
+// A node's second cut waits for its first, without holding a slot.
+func TestCutsOnOneNodeRunInTurn(t *testing.T) {
+	e, c := newTestExecutor(t, concurrencyPolicy)
+	block := make(chan struct{})
+	c.block = block
+
+	first := startCut(e, "athena")
+	waitFor(t, "the first cut to reach the cutter", func() bool { return len(c.Calls()) == 1 })
+	second := startCut(e, "athena")
+	borg := startCut(e, "borg")
+	waitFor(t, "borg's cut to reach the cutter", func() bool { return len(c.Calls()) == 2 })
+	if status := e.Concurrency(); status.InFlight != 2 || status.Queued != 0 {
+		t.Fatalf("concurrency = %+v, want athena's first cut and borg's in flight", status)
+	}
+
+	close(block)
+	for _, done := range []<-chan *cutter.CutResult{first, second, borg} {
+		if result := <-done; !result.Success {
+			t.Fatalf("%s: %v", result.Target, result.Error)
+		}
+	}
+	if calls := len(c.Calls()); calls != 3 {
+		t.Fatalf("cutter ran %d times, want 3", calls)
+	}
+}
+
+// A cut that finds no free slot is refused after the queue timeout and
+// leaves the node free for the next.
+func TestCutSaturatedReleasesNode(t *testing.T) {
+	e, c := newTestExecutor(t, concurrencyPolicy)
+	e.slots = newCutSlots(1, 50*time.Millisecond)
+	block := make(chan struct{})
+	c.block = block
+
+	athena := startCut(e, "athena")
+	waitFor(t, "the first cut to reach the cutter", func() bool { return len(c.Calls()) == 1 })
+	if result := e.ExecuteCut(context.Background(), "borg", 0.9); !errors.Is(result.Error, ErrExecutorSaturated) {
+		t.Fatalf("borg's cut: %v, want ErrExecutorSaturated", result.Error)
+	}
+	if e.inFlight("borg") {
+		t.Fatal("borg still in flight after its cut was refused")
+	}
+
+	close(block)
+	if result := <-athena; !result.Success {
+		t.Fatalf("athena: %v", result.Error)
+	}
+	if result := e.ExecuteCut(context.Background(), "borg", 0.9); !result.Success {
+		t.Fatalf("borg's retry: %v", result.Error)
+	}
+}
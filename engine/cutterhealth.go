@@ -0,0 +1,146 @@
+package engine
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"atropos/cutter"
+	"atropos/policy"
+)
+
+const cutterHealthTimeout = 5 * time.Second
+
+const (
+	CutterHealthy   = "healthy"
+	CutterUnhealthy = "unhealthy"
+	// CutterUnchecked is the status of cutters without a health check.
+	CutterUnchecked = "unchecked"
+)
+
+// CutterHealth is the result of one cutter's health check. Actions lists
+// the policy's actions the cutter runs, as "node: action".
+type CutterHealth struct {
+	Cutter    string    `json:"cutter"`
+	Status    string    `json:"status"`
+	Error     string    `json:"error,omitempty"`
+	Actions   []string  `json:"actions,omitempty"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// CheckCutters runs the health check of every registered cutter that has
+// one, all at once, and keeps the results for CutterHealth.
+func (e *Executor) CheckCutters(ctx context.Context) []CutterHealth {
+	cutters := e.registry.Cutters()
+	actions := e.policyActions()
+	results := make([]CutterHealth, len(cutters))
+
+	var wg sync.WaitGroup
+	for i, c := range cutters {
+		results[i] = CutterHealth{Cutter: c.Name(), Status: CutterUnchecked, Actions: actions[c.Name()]}
+		checker, ok := c.(cutter.HealthChecker)
+		if !ok {
+			results[i].CheckedAt = time.Now().UTC()
+			continue
+		}
+		wg.Add(1)
+		go func(result *CutterHealth) {
+			defer wg.Done()
+			checkCtx, cancel := context.WithTimeout(ctx, cutterHealthTimeout)
+			defer cancel()
+			result.Status = CutterHealthy
+			if err := checker.HealthCheck(checkCtx); err != nil {
+				result.Status = CutterUnhealthy
+				result.Error = err.Error()
+			}
+			result.CheckedAt = time.Now().UTC()
+		}(&results[i])
+	}
+	wg.Wait()
+
+	e.health.mu.Lock()
+	e.health.cutters = results
+	e.health.mu.Unlock()
+	return results
+}
+
+// CutterHealth returns the results of the last CheckCutters.
+func (e *Executor) CutterHealth() []CutterHealth {
+	e.health.mu.RLock()
+	defer e.health.mu.RUnlock()
+	return append([]CutterHealth(nil), e.health.cutters...)
+}
+
+// CutterComponent is degraded when a cutter the policy uses fails its
+// check. Cutters nothing uses don't count.
+func CutterComponent(results []CutterHealth) ComponentHealth {
+	now := time.Now().UTC()
+	var failing []string
+	for _, r := range results {
+		if r.Status == CutterUnhealthy && len(r.Actions) > 0 {
+			failing = append(failing, r.Cutter+": "+r.Error)
+		}
+	}
+	if len(failing) > 0 {
+		return ComponentHealth{Status: HealthDegraded, Message: strings.Join(failing, "; "), CheckedAt: now}
+	}
+	return ComponentHealth{Status: HealthOperational, CheckedAt: now}
+}
+
+// policyActions maps each cutter's name to the policy actions it would
+// run: strategies and their steps, hooks, SSH verification and recovery.
+func (e *Executor) policyActions() map[string][]string {
+	seen := make(map[string]map[string]bool)
+	add := func(c cutter.Cutter, node, action string) {
+		if seen[c.Name()] == nil {
+			seen[c.Name()] = make(map[string]bool)
+		}
+		seen[c.Name()][node+": "+action] = true
+	}
+	addStrategy := func(node string, s *policy.Strategy) {
+		if policy.ObserveOnly(s.Action) {
+			return
+		}
+		if c, err := e.findCutter(s); err == nil {
+			add(c, node, s.Action)
+		}
+	}
+
+	for name, node := range e.currentPolicy().Nodes {
+		for i := range node.Strategies {
+			strat := &node.Strategies[i]
+			if len(strat.Actions) == 0 {
+				addStrategy(name, strat)
+			}
+			for j := range strat.Actions {
+				addStrategy(name, strat.Actions[j].Strategy(strat))
+			}
+			for _, hooks := range [][]policy.Hook{strat.PreHooks, strat.PostHooks} {
+				for _, hook := range hooks {
+					if c, ok := e.registry.FindCutter(hook.Action); ok {
+						add(c, name, hook.Action)
+					}
+				}
+			}
+			if v := strat.Verify; v != nil && v.Type == policy.VerifySSH {
+				if c, ok := e.registry.FindCutter("ssh_verify"); ok {
+					add(c, name, "ssh_verify")
+				}
+			}
+		}
+		if node.Recovery != nil {
+			addStrategy(name, node.Recovery.Strategy())
+		}
+	}
+
+	actions := make(map[string][]string, len(seen))
+	for name, set := range seen {
+		for action := range set {
+			actions[name] = append(actions[name], action)
+		}
+		sort.Strings(actions[name])
+	}
+	return actions
+}
@@ -0,0 +1,123 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"atropos/cutter"
+	"atropos/history"
+	"atropos/internal/logger"
+	"atropos/policy"
+)
+
+// dedupKey is a node and its reading rounded to three decimals; two requests
+// with the same key inside the dedup window are one request sent twice.
+type dedupKey struct {
+	node    string
+	entropy int64
+}
+
+func newDedupKey(node string, entropy float64) dedupKey {
+	return dedupKey{node: node, entropy: int64(math.Round(entropy * 1000))}
+}
+
+type dedupEntry struct {
+	result     *cutter.CutResult
+	receivedAt time.Time
+}
+
+type dedupCache struct {
+	entries map[dedupKey]dedupEntry
+	mu      sync.Mutex
+}
+
+func newDedupCache() *dedupCache {
+	return &dedupCache{entries: make(map[dedupKey]dedupEntry)}
+}
+
+// lookup returns the result of the first request with key if it was
+// received within window before receivedAt.
+func (d *dedupCache) lookup(key dedupKey, window time.Duration, receivedAt time.Time) (*cutter.CutResult, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	entry, ok := d.entries[key]
+	if !ok || receivedAt.Sub(entry.receivedAt) > window {
+		return nil, false
+	}
+	return entry.result, true
+}
+
+func (d *dedupCache) remember(key dedupKey, result *cutter.CutResult, receivedAt time.Time) {
+	d.mu.Lock()
+	d.entries[key] = dedupEntry{result: result, receivedAt: receivedAt}
+	d.mu.Unlock()
+}
+
+// collect drops entries older than window.
+func (d *dedupCache) collect(now time.Time, window time.Duration) int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	evicted := 0
+	for key, entry := range d.entries {
+		if now.Sub(entry.receivedAt) > window {
+			delete(d.entries, key)
+			evicted++
+		}
+	}
+	return evicted
+}
+
+func (d *dedupCache) size() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.entries)
+}
+
+// executeDeduped runs the cut unless an identical request for the node was
+// received within the dedup window, in which case it answers with that
+// request's result. Callers hold the node's lock.
+func (e *Executor) executeDeduped(ctx context.Context, node string, entropy float64, opts CutOptions) *cutter.CutResult {
+	window := e.currentPolicy().GetDedupWindow()
+	if opts.Strategy != nil || window <= 0 {
+		return e.executeCut(ctx, node, entropy, opts)
+	}
+
+	key := newDedupKey(node, entropy)
+	if first, ok := e.dedup.lookup(key, window, opts.ReceivedAt); ok {
+		return e.suppressDuplicate(node, entropy, first, opts)
+	}
+	result := e.executeCut(ctx, node, entropy, opts)
+	// A cut turned away for lack of capacity never ran; a retry may.
+	if !errors.Is(result.Error, ErrExecutorSaturated) {
+		e.dedup.remember(key, result, opts.ReceivedAt)
+	}
+	return result
+}
+
+func (e *Executor) suppressDuplicate(node string, entropy float64, first *cutter.CutResult, opts CutOptions) *cutter.CutResult {
+	logger.Get().Info("duplicate_suppressed",
+		zap.String("node", node),
+		zap.Float64("entropy", entropy),
+		zap.String("first_cut_id", first.CutID),
+	)
+
+	record := e.newRecord(node, entropy, &policy.Strategy{Action: first.Action}, &cutter.CutResult{
+		Target:  node,
+		Action:  first.Action,
+		Success: true,
+		Outcome: history.OutcomeDuplicateSuppressed,
+	})
+	record.DuplicateOf = first.CutID
+	opts.apply(record)
+	e.saveRecord(record)
+
+	dup := *first
+	dup.Duplicate = true
+	return &dup
+}
@@ -0,0 +1,127 @@
+package engine
+
+import (
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"atropos/cutter"
+	"atropos/history"
+	"atropos/internal/logger"
+	"atropos/policy"
+)
+
+type DependencyBlock struct {
+	Node   string `json:"node"`
+	Reason string `json:"reason"`
+}
+
+func (e *Executor) beginFlight(node string) {
+	e.flightMu.Lock()
+	e.flights[node]++
+	e.flightMu.Unlock()
+}
+
+func (e *Executor) endFlight(node string) {
+	e.flightMu.Lock()
+	if e.flights[node]--; e.flights[node] <= 0 {
+		delete(e.flights, node)
+	}
+	e.flightMu.Unlock()
+}
+
+func (e *Executor) inFlight(node string) bool {
+	e.flightMu.Lock()
+	defer e.flightMu.Unlock()
+	return e.flights[node] > 0
+}
+
+// CheckDependencies reports the first dependency of the node that currently
+// has a cut running, whose latest cut within the window failed, or, with a
+// grace period set, was cut successfully within it.
+func (e *Executor) CheckDependencies(nodePolicy *policy.NodePolicy) *DependencyBlock {
+	window := nodePolicy.GetDependencyWindow()
+	grace := nodePolicy.GetDependencyGrace()
+	now := time.Now()
+	cutoff := now.Add(-max(window, grace))
+
+	for _, dep := range nodePolicy.DependsOn {
+		if e.inFlight(dep) {
+			return &DependencyBlock{Node: dep, Reason: "cut in flight"}
+		}
+
+		if e.history == nil {
+			continue
+		}
+		cuts, err := e.history.ListCutsByNode(dep, 0)
+		if err != nil {
+			continue
+		}
+		for _, cut := range cuts {
+			if cut.Timestamp.Before(cutoff) {
+				break
+			}
+			if !cut.Executed() || cut.DryRun || cut.Action == "none" || policy.ObserveOnly(cut.Action) {
+				continue
+			}
+			if !cut.Success && cut.Timestamp.After(now.Add(-window)) {
+				return &DependencyBlock{
+					Node:   dep,
+					Reason: fmt.Sprintf("cut %s failed within the last %s", cut.ID, window),
+				}
+			}
+			if cut.Success && grace > 0 && cut.Timestamp.After(now.Add(-grace)) {
+				return &DependencyBlock{
+					Node:   dep,
+					Reason: fmt.Sprintf("cut %s succeeded within the %s grace period", cut.ID, grace),
+				}
+			}
+			break
+		}
+	}
+
+	return nil
+}
+
+func (e *Executor) blockOnDependency(node string, entropy float64, nodePolicy *policy.NodePolicy, strategy *policy.Strategy, block *DependencyBlock, opts CutOptions) *cutter.CutResult {
+	mode := nodePolicy.GetDependencyMode()
+	logger.Get().Warn("cut_blocked_by_dependency",
+		zap.String("node", node),
+		zap.String("action", strategy.Action),
+		zap.String("dependency", block.Node),
+		zap.String("reason", block.Reason),
+		zap.String("mode", mode),
+	)
+
+	details := map[string]interface{}{
+		"blocked_by":   block.Node,
+		"block_reason": block.Reason,
+	}
+
+	if mode == policy.DependencyModeDefer {
+		result := &cutter.CutResult{
+			Target:  node,
+			Action:  strategy.Action,
+			Success: true,
+			Outcome: history.OutcomeDeferred,
+			Details: details,
+		}
+		record := e.newRecord(node, entropy, strategy, result)
+		opts.apply(record)
+		e.saveRecord(record)
+		result.CutID = record.ID
+		return result
+	}
+
+	result := &cutter.CutResult{
+		Target:  node,
+		Action:  strategy.Action,
+		Success: false,
+		Outcome: history.OutcomeBlocked,
+		Error:   fmt.Errorf("blocked by dependency %s: %s", block.Node, block.Reason),
+		Details: details,
+	}
+	e.logCut(node, entropy, strategy, result, opts)
+	return result
+}
@@ -0,0 +1,99 @@
+package engine
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"atropos/cutter"
+)
+
+// maxDetailsBytes caps a record's details once encoded. Keys that don't fit
+// are dropped and listed under details_dropped.
+const maxDetailsBytes = 16 << 10
+
+// cutDetails returns what the cutter recorded, with the values of
+// sensitive params redacted wherever they were echoed and the details
+// capped in size.
+func cutDetails(d *cutter.Details, params map[string]string) (map[string]interface{}, string) {
+	redact := secretReplacer(params)
+	details := d.Map()
+	for key, value := range details {
+		if sensitiveParam(key) {
+			details[key] = redactedParam
+			continue
+		}
+		details[key] = redactValue(value, redact)
+	}
+	return capDetails(details), redact.Replace(d.Output())
+}
+
+// secretReplacer replaces the values of sensitive params. Values shorter
+// than four characters would match too much to be worth hiding.
+func secretReplacer(params map[string]string) *strings.Replacer {
+	var pairs []string
+	for key, value := range params {
+		if len(value) >= 4 && sensitiveParam(key) {
+			pairs = append(pairs, value, redactedParam)
+		}
+	}
+	return strings.NewReplacer(pairs...)
+}
+
+func redactValue(value interface{}, redact *strings.Replacer) interface{} {
+	switch v := value.(type) {
+	case string:
+		return redact.Replace(v)
+	case []string:
+		out := make([]string, len(v))
+		for i, s := range v {
+			out[i] = redact.Replace(s)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			out[i] = redactValue(item, redact)
+		}
+		return out
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			if sensitiveParam(key) {
+				out[key] = redactedParam
+				continue
+			}
+			out[key] = redactValue(item, redact)
+		}
+		return out
+	}
+	return value
+}
+
+// capDetails keeps keys, in name order, while the encoded details stay
+// under maxDetailsBytes.
+func capDetails(details map[string]interface{}) map[string]interface{} {
+	if data, err := json.Marshal(details); err == nil && len(data) <= maxDetailsBytes {
+		return details
+	}
+	keys := make([]string, 0, len(details))
+	for key := range details {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	kept := make(map[string]interface{}, len(details))
+	var dropped []string
+	size := 2
+	for _, key := range keys {
+		data, err := json.Marshal(map[string]interface{}{key: details[key]})
+		if err != nil || size+len(data) > maxDetailsBytes {
+			dropped = append(dropped, key)
+			continue
+		}
+		kept[key] = details[key]
+		size += len(data) - 1
+	}
+	kept["details_dropped"] = dropped
+	return kept
+}
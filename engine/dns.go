@@ -0,0 +1,47 @@
+package engine
+
+import (
+	"encoding/json"
+
+	"go.uber.org/zap"
+
+	"atropos/internal/logger"
+)
+
+// addRemovedDNSRecords hands dns_restore_record the records the node's
+// latest successful dns_remove_record took out, so it puts back exactly
+// those. A dns_records param set in the policy wins.
+func (e *Executor) addRemovedDNSRecords(node string, params map[string]string) {
+	if params["action"] != "dns_restore_record" || params["dns_records"] != "" || e.history == nil {
+		return
+	}
+	cuts, err := e.history.ListCutsByNode(node, 0)
+	if err != nil {
+		logger.Get().Warn("dns_removed_records_unavailable",
+			zap.String("node", node),
+			zap.Error(err),
+		)
+		return
+	}
+	for _, cut := range cuts {
+		removed := []interface{}{}
+		if cut.Success && cut.Details["dns_removed"] != nil {
+			removed = append(removed, cut.Details["dns_removed"])
+		}
+		for _, step := range cut.Steps {
+			if step.Success && step.Details["dns_removed"] != nil {
+				removed = append(removed, step.Details["dns_removed"])
+			}
+		}
+		if len(removed) == 0 {
+			continue
+		}
+		// The sequence's last removal is the one to undo.
+		data, err := json.Marshal(removed[len(removed)-1])
+		if err != nil {
+			return
+		}
+		params["dns_records"] = string(data)
+		return
+	}
+}
@@ -0,0 +1,115 @@
+package engine
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"atropos/history"
+	"atropos/internal/logger"
+)
+
+type EventType string
+
+// Lifecycle events, in the order a single cut produces them. A cut that never
+// reaches a cutter (refused, deferred, noop, dry run) skips cut_started.
+const (
+	EventCutRequested         EventType = "cut_requested"
+	EventCutStarted           EventType = "cut_started"
+	EventCutRecorded          EventType = "cut_recorded"
+	EventHistoryQuotaExceeded EventType = "history_quota_exceeded"
+	EventCircuitOpened        EventType = "circuit_opened"
+	EventAll                  EventType = "*"
+)
+
+const defaultSubscriptionBuffer = 64
+
+type Event struct {
+	Type    EventType
+	Node    string
+	Action  string
+	Entropy float64
+	Time    time.Time
+
+	// Record is set on cut_recorded. Notify is false for records that are
+	// stored without notifying (deferred readings, quiet noop tiers).
+	Record *history.CutRecord
+	Notify bool
+
+	// Quota is set on history_quota_exceeded.
+	Quota *history.QuotaStatus
+
+	// Circuit is set on circuit_opened.
+	Circuit *CircuitState
+}
+
+type subscription struct {
+	eventType EventType
+	ch        chan Event
+	dropped   int
+}
+
+// EventBus fans executor events out to subscribers. Delivery never blocks the
+// executor: a subscriber whose buffer is full misses the event. Events are
+// delivered in publish order, so a subscriber sees each node's events in the
+// order they happened.
+type EventBus struct {
+	subs []*subscription
+	mu   sync.Mutex
+}
+
+func NewEventBus() *EventBus {
+	return &EventBus{}
+}
+
+func (b *EventBus) Subscribe(eventType EventType) <-chan Event {
+	return b.subscribe(eventType, defaultSubscriptionBuffer)
+}
+
+func (b *EventBus) subscribe(eventType EventType, buffer int) <-chan Event {
+	sub := &subscription{eventType: eventType, ch: make(chan Event, buffer)}
+	b.mu.Lock()
+	b.subs = append(b.subs, sub)
+	b.mu.Unlock()
+	return sub.ch
+}
+
+// Unsubscribe closes the channel returned by Subscribe.
+func (b *EventBus) Unsubscribe(ch <-chan Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for i, sub := range b.subs {
+		if sub.ch == ch {
+			close(sub.ch)
+			b.subs = append(b.subs[:i], b.subs[i+1:]...)
+			return
+		}
+	}
+}
+
+func (b *EventBus) publish(event Event) {
+	if event.Time.IsZero() {
+		event.Time = time.Now().UTC()
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, sub := range b.subs {
+		if sub.eventType != EventAll && sub.eventType != event.Type {
+			continue
+		}
+		select {
+		case sub.ch <- event:
+		default:
+			sub.dropped++
+			if sub.dropped == 1 || sub.dropped%1000 == 0 {
+				logger.Get().Warn("event_dropped",
+					zap.String("subscription", string(sub.eventType)),
+					zap.String("event", string(event.Type)),
+					zap.Int("dropped_total", sub.dropped),
+				)
+			}
+		}
+	}
+}
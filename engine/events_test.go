@@ -0,0 +1,195 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// collect drains ch into a slice until it is closed.
+func collect(ch <-chan Event) func() []Event {
+	var events []Event
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for event := range ch {
+			events = append(events, event)
+		}
+	}()
+	return func() []Event {
+		<-done
+		return events
+	}
+}
+
+// checkNodeOrder fails unless each node's events carry increasing sequence
+// numbers in Entropy.
+func checkNodeOrder(t *testing.T, name string, events []Event) {
+	t.Helper()
+	last := make(map[string]float64)
+	for _, event := range events {
+		if prev, ok := last[event.Node]; ok && event.Entropy <= prev {
+			t.Fatalf("%s: %s's event %v came after %v", name, event.Node, event.Entropy, prev)
+		}
+		last[event.Node] = event.Entropy
+	}
+}
+
+func TestEventBusStress(t *testing.T) {
+	const nodes, perNode = 8, 2000
+	bus := NewEventBus()
+	all := bus.subscribe(EventAll, nodes*perNode)
+	recorded := bus.subscribe(EventCutRecorded, nodes*perNode)
+	stuck := bus.Subscribe(EventAll)
+	allEvents, recordedEvents := collect(all), collect(recorded)
+
+	// The stuck subscriber reads nothing until publishing is over.
+	published := make(chan struct{})
+	stuckDone := make(chan []Event)
+	go func() {
+		<-published
+		var events []Event
+		for event := range stuck {
+			events = append(events, event)
+		}
+		stuckDone <- events
+	}()
+
+	var wg sync.WaitGroup
+	// Subscribers coming and going while events are published.
+	stop := make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			ch := bus.Subscribe(EventCutStarted)
+			bus.Unsubscribe(ch)
+		}
+	}()
+	var publishers sync.WaitGroup
+	for n := 0; n < nodes; n++ {
+		publishers.Add(1)
+		go func(node string) {
+			defer publishers.Done()
+			for i := 1; i <= perNode; i++ {
+				eventType := EventCutRequested
+				if i%2 == 0 {
+					eventType = EventCutRecorded
+				}
+				bus.publish(Event{Type: eventType, Node: node, Entropy: float64(i)})
+			}
+		}(fmt.Sprintf("node-%d", n))
+	}
+	publishers.Wait()
+	close(published)
+	close(stop)
+	wg.Wait()
+	for _, ch := range []<-chan Event{all, recorded, stuck} {
+		bus.Unsubscribe(ch)
+	}
+
+	events := allEvents()
+	if len(events) != nodes*perNode {
+		t.Fatalf("EventAll subscriber got %d of %d events", len(events), nodes*perNode)
+	}
+	checkNodeOrder(t, "EventAll", events)
+
+	events = recordedEvents()
+	if len(events) != nodes*perNode/2 {
+		t.Fatalf("cut_recorded subscriber got %d of %d events", len(events), nodes*perNode/2)
+	}
+	for _, event := range events {
+		if event.Type != EventCutRecorded {
+			t.Fatalf("cut_recorded subscriber got %s", event.Type)
+		}
+	}
+	checkNodeOrder(t, "cut_recorded", events)
+
+	// The stuck subscriber loses what didn't fit its buffer instead of
+	// holding up the others, and what it does get is still in order.
+	events = <-stuckDone
+	if len(events) != defaultSubscriptionBuffer {
+		t.Fatalf("stuck subscriber got %d events, want its buffer's %d", len(events), defaultSubscriptionBuffer)
+	}
+	checkNodeOrder(t, "stuck", events)
+
+	bus.mu.Lock()
+	defer bus.mu.Unlock()
+	if len(bus.subs) != 0 {
+		t.Fatalf("%d subscriptions left after unsubscribing", len(bus.subs))
+	}
+}
+
+// Each cut's events reach every subscriber as requested, started, recorded,
+// with no other event for the node in between, however many cuts run at
+// once.
+func TestEventsUnderConcurrentCuts(t *testing.T) {
+	const nodes, perNode = 6, 25
+	policyYAML := "server:\n  dedup_window_seconds: 0\n  max_concurrent_cuts: 4\nnodes:\n"
+	for n := 0; n < nodes; n++ {
+		policyYAML += fmt.Sprintf(`  node-%d:
+    rate_limit:
+      max_cuts: 1000
+      window_minutes: 60
+    strategies:
+      - threshold: 0.5
+        action: test_restart
+`, n)
+	}
+	e, c := newTestExecutor(t, policyYAML)
+
+	subs := make([]func() []Event, 3)
+	chans := make([]<-chan Event, len(subs))
+	for i := range subs {
+		chans[i] = e.events.subscribe(EventAll, nodes*perNode*3)
+		subs[i] = collect(chans[i])
+	}
+
+	var wg sync.WaitGroup
+	for n := 0; n < nodes; n++ {
+		wg.Add(1)
+		go func(node string) {
+			defer wg.Done()
+			for i := 0; i < perNode; i++ {
+				if result := e.ExecuteCut(context.Background(), node, 0.9); !result.Success {
+					t.Errorf("%s cut %d: %+v", node, i, result)
+					return
+				}
+			}
+		}(fmt.Sprintf("node-%d", n))
+	}
+	wg.Wait()
+	if calls := len(c.Calls()); calls != nodes*perNode {
+		t.Fatalf("cutter ran %d times, want %d", calls, nodes*perNode)
+	}
+	for _, ch := range chans {
+		e.events.Unsubscribe(ch)
+	}
+
+	want := []EventType{EventCutRequested, EventCutStarted, EventCutRecorded}
+	for i, sub := range subs {
+		byNode := make(map[string][]EventType)
+		for _, event := range sub() {
+			byNode[event.Node] = append(byNode[event.Node], event.Type)
+		}
+		if len(byNode) != nodes {
+			t.Fatalf("subscriber %d saw %d nodes, want %d", i, len(byNode), nodes)
+		}
+		for node, types := range byNode {
+			if len(types) != perNode*len(want) {
+				t.Fatalf("subscriber %d: %s has %d events, want %d", i, node, len(types), perNode*len(want))
+			}
+			for j, eventType := range types {
+				if eventType != want[j%len(want)] {
+					t.Fatalf("subscriber %d: %s's event %d is %s, want %s", i, node, j, eventType, want[j%len(want)])
+				}
+			}
+		}
+	}
+}
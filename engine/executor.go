@@ -2,8 +2,11 @@ package engine
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"go.uber.org/zap"
@@ -11,17 +14,40 @@ import (
 	"atropos/cutter"
 	"atropos/history"
 	"atropos/internal/logger"
+	"atropos/internal/timefmt"
 	"atropos/notifications"
 	"atropos/policy"
 )
 
 type Executor struct {
-	policy        *policy.RemediationPolicy
+	policy        atomic.Pointer[policy.RemediationPolicy]
 	registry      *cutter.Registry
 	history       *history.HistoryManager
 	rateLimiter   *RateLimiter
-	notifications *notifications.NotificationManager
-	mu            sync.Mutex
+	triggers      *TriggerCounter
+	notifications atomic.Pointer[notifications.NotificationManager]
+	events        *EventBus
+	breakers      *circuitBreakers
+	callbacks     *callbackStats
+	pool          *workerPool
+	gc            gcState
+	reloader      func() (*policy.RemediationPolicy, error)
+	reloadMu      sync.Mutex
+	slots         *cutSlots
+	health        healthState
+	flights       map[string]int
+	flightMu      sync.Mutex
+	run           *runStats
+	dedup         *dedupCache
+	inProgress    *inProgressCuts
+	closing       atomic.Bool
+	active        atomic.Int64
+	nodeLocks     nodeLocks
+	notifyQueue   <-chan Event
+	callbackQueue <-chan Event
+	// mu is held while a cut's guards run, so one node's dependency check
+	// and another's flight claim don't interleave. Cuts run outside it.
+	mu sync.Mutex
 }
 
 type RateLimiter struct {
@@ -29,24 +55,65 @@ type RateLimiter struct {
 	mu         sync.Mutex
 }
 
+// rateLimitEntry holds the times of a node's cuts within the trailing
+// window, oldest first.
 type rateLimitEntry struct {
-	count       int
-	windowStart time.Time
-	limit       *policy.RateLimit
+	cuts  []time.Time
+	limit *policy.RateLimit
+}
+
+// prune drops cuts that have left the window ending at now.
+func (entry *rateLimitEntry) prune(now time.Time, window time.Duration) {
+	i := 0
+	for i < len(entry.cuts) && now.Sub(entry.cuts[i]) > window {
+		i++
+	}
+	entry.cuts = entry.cuts[i:]
 }
 
 func NewExecutor(pol *policy.RemediationPolicy, history *history.HistoryManager, notif *notifications.NotificationManager) *Executor {
-	return &Executor{
-		policy:        pol,
-		registry:      cutter.NewRegistry(),
-		history:       history,
-		notifications: notif,
+	e := &Executor{
+		registry: cutter.NewRegistry(pol.EnabledCutters()...),
+		history:  history,
 		rateLimiter: &RateLimiter{
 			nodeCounts: make(map[string]rateLimitEntry),
 		},
+		triggers:   NewTriggerCounter(),
+		events:     NewEventBus(),
+		breakers:   newCircuitBreakers(),
+		callbacks:  &callbackStats{},
+		flights:    make(map[string]int),
+		slots:      newCutSlots(pol.Server.MaxConcurrentCuts, pol.GetCutQueueTimeout()),
+		run:        newRunStats(),
+		dedup:      newDedupCache(),
+		inProgress: newInProgressCuts(),
+	}
+	// The cutters section and plugins are only read here, so changing them
+	// takes a restart. Plugins come after the built-in cutters.
+	for _, plugin := range pol.Plugins {
+		e.registry.Register(cutter.NewPluginCutter(plugin.Name, plugin.Path, plugin.ActionPrefix))
 	}
+	e.policy.Store(pol)
+	e.notifications.Store(notif)
+	e.notifyQueue = e.events.subscribe(EventAll, notificationBuffer)
+	e.callbackQueue = e.events.subscribe(EventCutRecorded, callbackBuffer)
+	go e.runNotifications(e.notifyQueue)
+	go e.runCallbacks(e.callbackQueue)
+	if pool, ok := pol.Server.GetWorkerPool(); ok {
+		e.pool = newWorkerPool(e, pool.Workers, pool.QueueSize)
+	}
+	e.registerStateMetrics()
+	return e
+}
+
+// Events exposes the lifecycle event bus for extensions.
+func (e *Executor) Events() *EventBus {
+	return e.events
 }
 
+// checkRateLimit reports whether the limit has room for another cut, and if
+// not how long until it does. It doesn't count the cut; record does that
+// once the cut runs.
 func (rl *RateLimiter) checkRateLimit(node string, rateLimit *policy.RateLimit) (bool, time.Duration, error) {
 	if rateLimit == nil || rateLimit.MaxCuts == 0 {
 		return true, 0, nil
@@ -56,26 +123,33 @@ func (rl *RateLimiter) checkRateLimit(node string, rateLimit *policy.RateLimit)
 	defer rl.mu.Unlock()
 
 	now := time.Now()
-	entry, exists := rl.nodeCounts[node]
+	entry := rl.nodeCounts[node]
 	windowDuration := time.Duration(rateLimit.Window) * time.Minute
+	entry.prune(now, windowDuration)
+	entry.limit = rateLimit
+	rl.nodeCounts[node] = entry
 
-	if !exists || now.Sub(entry.windowStart) > windowDuration {
-		rl.nodeCounts[node] = rateLimitEntry{
-			count:       1,
-			windowStart: now,
-			limit:       rateLimit,
-		}
-		return true, windowDuration, nil
+	if len(entry.cuts) >= rateLimit.MaxCuts {
+		timeUntilReset := entry.cuts[0].Add(windowDuration).Sub(now)
+		return false, timeUntilReset, fmt.Errorf("rate limit exceeded: %d cuts per %d minutes", rateLimit.MaxCuts, rateLimit.Window)
 	}
+	return true, windowDuration, nil
+}
 
-	if entry.count >= rateLimit.MaxCuts {
-		timeUntilReset := entry.windowStart.Add(windowDuration).Sub(now)
-		return false, timeUntilReset, fmt.Errorf("rate limit exceeded: %d cuts per %d minutes", rateLimit.MaxCuts, rateLimit.Window)
+// record counts a cut against the limit, so later cuts see it.
+func (rl *RateLimiter) record(key string, rateLimit *policy.RateLimit) {
+	if rateLimit == nil || rateLimit.MaxCuts == 0 {
+		return
 	}
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
 
-	entry.count++
-	rl.nodeCounts[node] = entry
-	return true, windowDuration, nil
+	now := time.Now()
+	entry := rl.nodeCounts[key]
+	entry.prune(now, time.Duration(rateLimit.Window)*time.Minute)
+	entry.limit = rateLimit
+	entry.cuts = append(entry.cuts, now)
+	rl.nodeCounts[key] = entry
 }
 
 func (e *Executor) GetHistory() *history.HistoryManager {
@@ -83,7 +157,47 @@ func (e *Executor) GetHistory() *history.HistoryManager {
 }
 
 func (e *Executor) GetPolicy() *policy.RemediationPolicy {
-	return e.policy
+	return e.currentPolicy()
+}
+
+// SetPolicy swaps the active policy. Cuts already in flight finish with the
+// snapshot they started with.
+func (e *Executor) SetPolicy(pol *policy.RemediationPolicy) {
+	e.policy.Store(pol)
+}
+
+func (e *Executor) currentPolicy() *policy.RemediationPolicy {
+	return e.policy.Load()
+}
+
+// SetNotifications swaps the notification manager, e.g. after a reload
+// changed the notifications section.
+func (e *Executor) SetNotifications(nm *notifications.NotificationManager) {
+	e.notifications.Store(nm)
+}
+
+func (e *Executor) lookupNode(pol *policy.RemediationPolicy, node string) (*policy.NodePolicy, bool) {
+	if pol == nil {
+		return nil, false
+	}
+	return pol.GetNode(node)
+}
+
+// RegisterCutter adds a cutter after the built-in ones, so it only handles
+// actions none of them claim.
+func (e *Executor) RegisterCutter(c cutter.Cutter) {
+	e.registry.Register(c)
+}
+
+// HasCutter reports whether a registered cutter runs action, checked
+// against the actions cutters list.
+func (e *Executor) HasCutter(action string) bool {
+	return e.registry.Supports(action)
+}
+
+// Cutters describes the registered cutters and their actions.
+func (e *Executor) Cutters() []cutter.Info {
+	return e.registry.Describe()
 }
 
 func (e *Executor) checkTimeWindows(nodePolicy *policy.NodePolicy) error {
@@ -104,85 +218,468 @@ func (e *Executor) checkTimeWindows(nodePolicy *policy.NodePolicy) error {
 }
 
 func (e *Executor) ExecuteCut(ctx context.Context, node string, entropy float64) *cutter.CutResult {
+	return e.ExecuteCutWith(ctx, node, entropy, CutOptions{})
+}
+
+func (e *Executor) ExecuteCutWith(ctx context.Context, node string, entropy float64, opts CutOptions) *cutter.CutResult {
+	if opts.ReceivedAt.IsZero() {
+		opts.ReceivedAt = time.Now()
+	}
+	// Registered before waiting for the node, which may be most of the wait.
+	if opts.CutID != "" {
+		e.trackInProgress(node, entropy, opts)
+	}
+	return e.executeTracked(ctx, node, entropy, opts)
+}
+
+// executeTracked runs a cut whose placeholder, if it has one, is already
+// tracked.
+func (e *Executor) executeTracked(ctx context.Context, node string, entropy float64, opts CutOptions) *cutter.CutResult {
+	cutsInFlight.Add(1)
+	defer cutsInFlight.Add(-1)
+	result := e.executeLocked(ctx, node, entropy, opts)
+	if opts.CutID != "" {
+		e.finishInProgress(opts.CutID, result)
+	}
+	return result
+}
+
+// executeLocked holds the node's lock for the whole cut, so cuts on one node
+// run one at a time while other nodes' cuts proceed.
+func (e *Executor) executeLocked(ctx context.Context, node string, entropy float64, opts CutOptions) *cutter.CutResult {
+	e.active.Add(1)
+	defer e.active.Add(-1)
+	unlock := e.nodeLocks.lock(node)
+	defer unlock()
+
+	if e.closing.Load() {
+		return &cutter.CutResult{Target: node, Error: ErrShuttingDown}
+	}
+	return e.executeDeduped(ctx, node, entropy, opts)
+}
+
+func (e *Executor) executeCut(ctx context.Context, node string, entropy float64, opts CutOptions) *cutter.CutResult {
+	attempt, result := e.admitCut(node, entropy, opts)
+	if attempt == nil {
+		return result
+	}
+	return e.runStrategy(ctx, attempt)
+}
+
+// admitCut runs the cut's guards under e.mu. It returns the attempt to run,
+// or the result when a guard settled the cut.
+func (e *Executor) admitCut(node string, entropy float64, opts CutOptions) (*cutAttempt, *cutter.CutResult) {
 	e.mu.Lock()
 	defer e.mu.Unlock()
 
-	nodePolicy, ok := e.policy.GetNode(node)
+	e.events.publish(Event{Type: EventCutRequested, Node: node, Entropy: entropy})
+
+	pol := e.currentPolicy()
+	nodePolicy, ok := e.lookupNode(pol, node)
 	if !ok {
 		result := &cutter.CutResult{
 			Target:  node,
 			Success: false,
 			Error:   fmt.Errorf("unknown node: %s", node),
 		}
-		e.logCut(node, entropy, &policy.Strategy{}, result, 0)
-		return result
+		e.logCut(node, entropy, &policy.Strategy{}, result, opts)
+		return nil, result
 	}
 
 	if err := e.checkTimeWindows(nodePolicy); err != nil {
 		result := &cutter.CutResult{
 			Target:  node,
 			Success: false,
+			Outcome: history.OutcomeSkippedTimeWindow,
 			Error:   err,
 		}
-		e.logCut(node, entropy, &policy.Strategy{}, result, 0)
-		return result
+		e.logCut(node, entropy, &policy.Strategy{}, result, opts)
+		return nil, result
 	}
 
-	strategy, ok := nodePolicy.SelectStrategy(entropy)
-	if !ok {
+	if blackout, ok := pol.ActiveBlackout(nodePolicy, time.Now()); ok {
+		result := &cutter.CutResult{
+			Target:  node,
+			Success: false,
+			Outcome: history.OutcomeSkippedBlackout,
+			Error:   fmt.Errorf("blackout period in effect until %s: %s", timefmt.RFC3339(blackout.End), blackout.Description),
+			Details: map[string]interface{}{
+				"blackout":     blackout.Description,
+				"blackout_end": timefmt.RFC3339(blackout.End),
+			},
+		}
+		e.logCut(node, entropy, &policy.Strategy{}, result, opts)
+		return nil, result
+	}
+
+	var (
+		strategy, pending *policy.Strategy
+		count             int
+	)
+	if opts.Strategy != nil {
+		strategy = opts.Strategy
+	} else {
+		strategy, pending, count = e.triggers.observe(nodePolicy, entropy)
+	}
+	if strategy == nil && pending != nil {
+		result := &cutter.CutResult{
+			Target:  node,
+			Action:  pending.Action,
+			Success: true,
+			Outcome: history.OutcomeDeferred,
+		}
+		logger.Get().Info("cut_deferred",
+			zap.String("node", node),
+			zap.String("action", pending.Action),
+			zap.Int("count", count),
+			zap.Int("required", pending.RequiredTriggers()),
+		)
+		record := e.newRecord(node, entropy, pending, result)
+		record.TriggerCount = count
+		opts.apply(record)
+		e.saveRecord(record)
+		return nil, result
+	}
+	if strategy == nil {
+		if contained := e.containedBy(nodePolicy, entropy); contained != nil {
+			return e.admitRecovery(pol, nodePolicy, entropy, contained, opts)
+		}
 		result := &cutter.CutResult{
 			Target:  node,
 			Action:  "none",
 			Success: true,
+			Outcome: history.OutcomeSkippedBelowThreshold,
 		}
-		e.logCut(node, entropy, &policy.Strategy{Action: "none", Threshold: 0}, result, 0)
-		return result
+		e.logCut(node, entropy, &policy.Strategy{Action: "none", Threshold: 0}, result, opts)
+		return nil, result
 	}
 
-	if allowed, _, err := e.rateLimiter.checkRateLimit(node, nodePolicy.RateLimit); !allowed {
+	if until, open := e.breakers.open(node, time.Now()); open {
 		result := &cutter.CutResult{
 			Target:  node,
+			Action:  strategy.Action,
+			Success: false,
+			Outcome: history.OutcomeCircuitOpen,
+			Error:   fmt.Errorf("circuit open for node %s until %s", node, timefmt.RFC3339(until)),
+		}
+		record := e.newRecord(node, entropy, strategy, result)
+		opts.apply(record)
+		e.saveRecord(record)
+		result.CutID = record.ID
+		return nil, result
+	}
+
+	if block := e.CheckDependencies(nodePolicy); block != nil {
+		return nil, e.blockOnDependency(node, entropy, nodePolicy, strategy, block, opts)
+	}
+
+	// Observing doesn't touch the node, so noop and notify_only neither
+	// consume nor are refused by the rate limit. A cut waiting for approval
+	// is checked now and charged only if it runs.
+	var rateLimit rateLimitCharge
+	if !policy.ObserveOnly(strategy.Action) {
+		var refused *cutter.CutResult
+		if rateLimit, refused = e.admitRateLimit(node, nodePolicy, strategy); refused != nil {
+			e.logCut(node, entropy, strategy, refused, opts)
+			return nil, refused
+		}
+	}
+
+	if strategy.ApprovalRequired {
+		return nil, e.requestApproval(node, entropy, strategy, opts)
+	}
+
+	logger.CutInitiated(node, strategy.Action, entropy)
+
+	return e.admitted(&cutAttempt{
+		policy:     pol,
+		node:       node,
+		entropy:    entropy,
+		nodePolicy: nodePolicy,
+		strategy:   strategy,
+		opts:       opts,
+		rateLimit:  rateLimit,
+	}), nil
+}
+
+// admitted marks the attempt's guards done and, unless it only observes or
+// simulates, claims the node's flight before e.mu is released, so a
+// dependent node's guards see it from here on.
+func (e *Executor) admitted(attempt *cutAttempt) *cutAttempt {
+	attempt.guardsDone = time.Now()
+	if attempt.opts.ReceivedAt.IsZero() {
+		attempt.opts.ReceivedAt = attempt.guardsDone
+	}
+	if !attempt.nodePolicy.DryRun && !policy.ObserveOnly(attempt.strategy.Action) {
+		e.beginFlight(attempt.node)
+		attempt.flight = true
+	}
+	return attempt
+}
+
+// runStrategy runs an admitted attempt in a cut slot, so max_concurrent_cuts
+// bounds how many strategies run at once across all nodes.
+func (e *Executor) runStrategy(ctx context.Context, attempt *cutAttempt) *cutter.CutResult {
+	release, err := e.slots.acquire(ctx)
+	if err != nil {
+		if attempt.flight {
+			e.endFlight(attempt.node)
+		}
+		logger.CutFailed(attempt.node, attempt.strategy.Action, err)
+		result := &cutter.CutResult{
+			Target:  attempt.node,
+			Action:  attempt.strategy.Action,
 			Success: false,
 			Error:   err,
 		}
-		e.logCut(node, entropy, strategy, result, 0)
+		e.logAttempt(attempt, result)
 		return result
 	}
+	defer release()
+	if attempt.opts.slotted != nil {
+		attempt.opts.slotted()
+	}
+	return e.runSlotted(ctx, attempt)
+}
 
-	logger.CutInitiated(node, strategy.Action, entropy)
+// runSlotted runs an admitted attempt whose caller holds a cut slot. The
+// cut counts against its rate limit from here.
+func (e *Executor) runSlotted(ctx context.Context, attempt *cutAttempt) *cutter.CutResult {
+	if attempt.flight {
+		defer e.endFlight(attempt.node)
+	}
+	if charge := attempt.rateLimit; charge.key != "" {
+		e.rateLimiter.record(charge.key, charge.limit)
+	}
+	result := e.runStrategyChain(ctx, attempt)
+	e.observeCircuit(attempt, result)
+	return result
+}
 
-	result := e.executeStrategy(ctx, node, nodePolicy, strategy)
-
-	if !result.Success {
-		if strategy.OnFailure != "" {
-			fallbackStrategy, ok := nodePolicy.SelectStrategyByAction(strategy.OnFailure)
-			if ok {
-				logger.Get().Warn("fallback_strategy",
-					zap.String("node", node),
-					zap.String("original_action", strategy.Action),
-					zap.String("fallback_action", fallbackStrategy.Action),
-				)
-				return e.executeStrategy(ctx, node, nodePolicy, fallbackStrategy)
-			}
+// runStrategyChain runs the strategy and then, while attempts fail, its
+// on_failure fallback or escalation, up to max_chain_depth extra steps. Every
+// attempt is recorded; later ones link back to the first via chain_id.
+func (e *Executor) runStrategyChain(ctx context.Context, attempt *cutAttempt) *cutter.CutResult {
+	maxDepth := 3
+	if attempt.policy != nil {
+		maxDepth = attempt.policy.GetMaxChainDepth()
+	}
+
+	var chain []cutter.ChainLink
+	tried := make(map[string]bool)
+	via := ""
+	selected := attempt.strategy
+	finish := func(result *cutter.CutResult) *cutter.CutResult {
+		result.Chain = chain
+		result.Escalation = summarizeChain(selected, chain, result)
+		return result
+	}
+	for {
+		result := e.executeStrategy(ctx, attempt)
+		link := cutter.ChainLink{
+			CutID:     result.CutID,
+			Action:    result.Action,
+			Via:       via,
+			Success:   result.Success,
+			LatencyMs: result.LatencyMs,
+		}
+		if result.Error != nil {
+			link.Error = result.Error.Error()
+		}
+		chain = append(chain, link)
+		tried[attempt.strategy.Action] = true
+
+		next, nextVia := e.nextInChain(attempt, result)
+		if next == nil {
+			return finish(result)
+		}
+		if len(chain) > maxDepth {
+			logger.Get().Warn("chain_depth_exceeded",
+				zap.String("node", attempt.node),
+				zap.String("action", attempt.strategy.Action),
+				zap.String("next_action", next.strategy.Action),
+				zap.Int("max_chain_depth", maxDepth),
+			)
+			return finish(result)
+		}
+		if tried[next.strategy.Action] {
+			logger.Get().Warn("chain_loop_stopped",
+				zap.String("node", attempt.node),
+				zap.String("action", attempt.strategy.Action),
+				zap.String("next_action", next.strategy.Action),
+			)
+			return finish(result)
 		}
 
-		if strategy.Critical {
-			if escalated, ok := nodePolicy.GetEscalationStrategy(strategy.Threshold); ok {
-				logger.Escalation(node, strategy.Action, escalated.Action, result.Error.Error())
-				return e.executeStrategy(ctx, node, nodePolicy, escalated)
+		next.chainID = attempt.chainID
+		if next.chainID == "" {
+			next.chainID = result.CutID
+		}
+		next.chainStep = len(chain)
+		next.parentCutID = result.CutID
+		next.trigger = history.TriggerEscalation
+		next.reason = fmt.Sprintf("escalation from %s", attempt.strategy.Action)
+		if nextVia == "on_failure" {
+			next.trigger = history.TriggerFallback
+			next.reason = fmt.Sprintf("fallback after %s failed", attempt.strategy.Action)
+		}
+		escalations.Inc(attempt.node, next.trigger)
+		if result.Error != nil {
+			next.reason += ": " + result.Error.Error()
+		}
+		next.guardsDone = time.Now()
+		next.opts.CutID = ""
+		attempt, via = next, nextVia
+	}
+}
+
+// summarizeChain describes a chain of more than one step for the caller.
+func summarizeChain(selected *policy.Strategy, chain []cutter.ChainLink, result *cutter.CutResult) *cutter.Escalation {
+	if len(chain) < 2 {
+		return nil
+	}
+	esc := &cutter.Escalation{
+		Type:              "escalation",
+		OriginalAction:    selected.Action,
+		OriginalThreshold: selected.Threshold,
+		OriginalCritical:  selected.Critical,
+		OriginalCutID:     chain[0].CutID,
+		OriginalError:     chain[0].Error,
+		FinalAction:       result.Action,
+		Steps:             len(chain),
+	}
+	if chain[1].Via == "on_failure" {
+		esc.Type = "fallback"
+	}
+	if result.Error != nil {
+		esc.FinalError = result.Error.Error()
+	}
+	return esc
+}
+
+// nextInChain picks what to try after a failed attempt: the on_failure
+// fallback, else an escalation for critical strategies. It returns nil when
+// the chain ends here.
+func (e *Executor) nextInChain(attempt *cutAttempt, result *cutter.CutResult) (*cutAttempt, string) {
+	if result.Success || errors.Is(result.Error, ErrExecutorSaturated) {
+		return nil, ""
+	}
+	node, nodePolicy, strategy := attempt.node, attempt.nodePolicy, attempt.strategy
+
+	if strategy.OnFailure != "" {
+		if fallbackStrategy, ok := nodePolicy.SelectStrategyByAction(strategy.OnFailure); ok {
+			logger.Get().Warn("fallback_strategy",
+				zap.String("node", node),
+				zap.String("original_action", strategy.Action),
+				zap.String("fallback_action", fallbackStrategy.Action),
+			)
+			return &cutAttempt{
+				policy:     attempt.policy,
+				node:       node,
+				entropy:    attempt.entropy,
+				nodePolicy: nodePolicy,
+				strategy:   fallbackStrategy,
+				opts:       attempt.opts,
+			}, "on_failure"
+		}
+	}
+
+	if strategy.Critical {
+		if escalated, ok := nodePolicy.GetEscalationStrategy(strategy); ok {
+			reason := ""
+			if result.Error != nil {
+				reason = result.Error.Error()
+			}
+			logger.Escalation(node, strategy.Action, escalated.Action, reason)
+			via := "threshold"
+			if strategy.EscalateTo != "" {
+				via = "escalate_to"
 			}
+			return &cutAttempt{
+				policy:     attempt.policy,
+				node:       node,
+				entropy:    attempt.entropy,
+				nodePolicy: nodePolicy,
+				strategy:   escalated,
+				opts:       attempt.opts,
+				escalation: &history.Escalation{
+					From:   strategy.Action,
+					To:     escalated.Action,
+					Via:    via,
+					Reason: reason,
+				},
+			}, via
 		}
 	}
 
-	return result
+	return nil, ""
+}
+
+type cutAttempt struct {
+	policy       *policy.RemediationPolicy
+	node         string
+	entropy      float64
+	nodePolicy   *policy.NodePolicy
+	strategy     *policy.Strategy
+	escalation   *history.Escalation
+	approval     *history.Approval
+	opts         CutOptions
+	params       map[string]string
+	chainID      string
+	chainStep    int
+	parentCutID  string
+	trigger      string
+	reason       string
+	hooks        []history.HookResult
+	steps        []history.StepResult
+	verification *history.Verification
+	// rateLimit is the limit the cut was admitted under, charged when it
+	// runs.
+	rateLimit rateLimitCharge
+	// flight is set while the node's flight is claimed for the attempt.
+	flight bool
+
+	guardsDone  time.Time
+	cutterStart time.Time
+	cutterEnd   time.Time
 }
 
-func (e *Executor) executeStrategy(ctx context.Context, node string, nodePolicy *policy.NodePolicy, strategy *policy.Strategy) *cutter.CutResult {
+func (e *Executor) executeStrategy(ctx context.Context, attempt *cutAttempt) *cutter.CutResult {
+	node, nodePolicy, strategy := attempt.node, attempt.nodePolicy, attempt.strategy
 	start := time.Now()
 
-	c, ok := e.registry.FindCutter(strategy.Action)
-	if !ok {
-		err := fmt.Errorf("no cutter for action: %s", strategy.Action)
+	if strategy.Action == policy.ActionNoop {
+		logger.Get().Info("cut_observed",
+			zap.String("node", node),
+			zap.Float64("entropy", attempt.entropy),
+		)
+		result := &cutter.CutResult{
+			Target:  node,
+			Action:  policy.ActionNoop,
+			Success: true,
+			Details: map[string]interface{}{"observe_only": true},
+		}
+		e.logAttempt(attempt, result)
+		return result
+	}
+	if strategy.Action == policy.ActionNotifyOnly {
+		logger.Get().Info("cut_notified",
+			zap.String("node", node),
+			zap.Float64("entropy", attempt.entropy),
+		)
+		result := &cutter.CutResult{
+			Target:  node,
+			Action:  policy.ActionNotifyOnly,
+			Success: true,
+			DryRun:  nodePolicy.DryRun,
+			Outcome: history.OutcomeNotified,
+		}
+		e.logAttempt(attempt, result)
+		return result
+	}
+
+	steps, err := e.resolveSteps(node, nodePolicy, strategy)
+	if err != nil {
 		logger.CutFailed(node, strategy.Action, err)
 		result := &cutter.CutResult{
 			Target:  node,
@@ -190,26 +687,79 @@ func (e *Executor) executeStrategy(ctx context.Context, node string, nodePolicy
 			Success: false,
 			Error:   err,
 		}
-		e.logCut(node, 0, strategy, result, 0)
+		e.logAttempt(attempt, result)
 		return result
 	}
 
-	params := map[string]string{
-		"action":        strategy.Action,
-		"command":       strategy.Command,
-		"snapshot_name": strategy.SnapshotName,
-		"host":          nodePolicy.Host,
-		"user":          nodePolicy.User,
+	addCutContext(attempt, steps)
+	if len(strategy.Actions) == 0 {
+		attempt.params = steps[0].params
 	}
-	if nodePolicy.Port > 0 {
-		params["port"] = fmt.Sprintf("%d", nodePolicy.Port)
+
+	if nodePolicy.DryRun {
+		logger.Get().Info("cut_simulated",
+			zap.String("node", node),
+			zap.String("action", strategy.Action),
+			zap.String("cutter", stepCutters(steps)),
+		)
+		result := &cutter.CutResult{
+			Target:  node,
+			Action:  strategy.Action,
+			Success: true,
+			DryRun:  true,
+		}
+		e.logAttempt(attempt, result)
+		return result
 	}
 
-	cutCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
-	defer cancel()
+	e.events.publish(Event{Type: EventCutStarted, Node: node, Action: strategy.Action, Entropy: attempt.entropy})
 
-	err := c.Execute(cutCtx, node, params)
-	latency := time.Since(start).Milliseconds()
+	// Hooks have their own timeouts and are left out of the action's latency.
+	hookStart := time.Now()
+	if err := e.runHooks(ctx, attempt, "pre", strategy.PreHooks); err != nil && strategy.AbortOnPreHookFailure() {
+		logger.CutFailed(node, strategy.Action, err)
+		result := &cutter.CutResult{
+			Target:  node,
+			Action:  strategy.Action,
+			Success: false,
+			Outcome: history.OutcomeHookFailed,
+			Error:   err,
+		}
+		e.logAttempt(attempt, result)
+		return result
+	}
+	hookTime := time.Since(hookStart)
+
+	// A sequence reports its steps' details and latencies separately, and
+	// its latency is their sum.
+	var (
+		details map[string]interface{}
+		output  string
+		latency int64
+	)
+	attempt.cutterStart = time.Now()
+	cutCtx, cancel := context.WithTimeout(ctx, strategy.GetTimeout())
+	if len(strategy.Actions) > 0 {
+		latency, err = e.runSteps(cutCtx, attempt, steps)
+		attempt.cutterEnd = time.Now()
+	} else {
+		stepCtx, d := cutter.WithDetails(cutCtx)
+		err = e.executeWithRetries(stepCtx, steps[0].cutter, node, strategy, steps[0].params)
+		attempt.cutterEnd = time.Now()
+		latency = (time.Since(start) - hookTime).Milliseconds()
+		details, output = cutDetails(d, steps[0].params)
+	}
+	cancel()
+	cutDuration.Observe(attempt.cutterEnd.Sub(attempt.cutterStart).Seconds(), strategy.Action)
+	outcome := ""
+	if err == nil && strategy.Verify != nil {
+		if err = e.verifyCut(ctx, attempt); err != nil {
+			outcome = history.OutcomeVerifyFailed
+		}
+	}
+	if err == nil {
+		e.runHooks(ctx, attempt, "post", strategy.PostHooks)
+	}
 
 	var result *cutter.CutResult
 	if err != nil {
@@ -218,8 +768,11 @@ func (e *Executor) executeStrategy(ctx context.Context, node string, nodePolicy
 			Target:    node,
 			Action:    strategy.Action,
 			Success:   false,
+			Outcome:   outcome,
 			Error:     err,
 			LatencyMs: latency,
+			Details:   details,
+			Output:    output,
 		}
 	} else {
 		logger.CutExecuted(node, strategy.Action, latency)
@@ -228,85 +781,224 @@ func (e *Executor) executeStrategy(ctx context.Context, node string, nodePolicy
 			Action:    strategy.Action,
 			Success:   true,
 			LatencyMs: latency,
+			Details:   details,
+			Output:    output,
 		}
 	}
 
-	e.logCut(node, strategy.Threshold, strategy, result, latency)
+	e.logAttempt(attempt, result)
 	return result
 }
 
-func (e *Executor) logCut(node string, entropy float64, strategy *policy.Strategy, result *cutter.CutResult, latency int64) {
-	if e.history == nil {
+func (e *Executor) logAttempt(attempt *cutAttempt, result *cutter.CutResult) {
+	record := e.newRecord(attempt.node, attempt.entropy, attempt.strategy, result)
+	if attempt.policy != nil {
+		record.PolicyVersion = attempt.policy.Meta.Version
+		record.PolicyHash = attempt.policy.Hash()
+	}
+	if len(attempt.params) > 0 {
+		record.Strategy.Params = redactParams(attempt.params)
+		record.Error = secretReplacer(attempt.params).Replace(record.Error)
+	}
+	record.Escalation = attempt.escalation
+	record.Approval = attempt.approval
+	record.ChainID = attempt.chainID
+	record.ChainStep = attempt.chainStep
+	record.ParentCutID = attempt.parentCutID
+	record.Trigger = attempt.trigger
+	if attempt.reason != "" {
+		record.Source = history.SourceAtropos
+		record.Reason = attempt.reason
+	}
+	record.Hooks = attempt.hooks
+	record.Steps = attempt.steps
+	record.Verification = attempt.verification
+	if attempt.rateLimit.scope != "" && attempt.parentCutID == "" {
+		if record.Details == nil {
+			record.Details = make(map[string]interface{})
+		}
+		record.Details["rate_limit"] = attempt.rateLimit.scope
+	}
+	attempt.opts.apply(record)
+	if record.Trigger == "" {
+		record.Trigger = history.TriggerInitial
+	}
+	if record.Timings != nil {
+		record.Timings.GuardsDoneAt = utcPtr(attempt.guardsDone)
+		record.Timings.CutterStartAt = utcPtr(attempt.cutterStart)
+		record.Timings.CutterEndAt = utcPtr(attempt.cutterEnd)
+	}
+
+	// Observe-only tiers stay quiet unless the strategy asks to notify.
+	if attempt.strategy.Action == policy.ActionNoop && !attempt.strategy.Notify {
+		result.CutID = record.ID
+		e.saveRecord(record)
 		return
 	}
+	e.recordCut(record, result)
+}
+
+func utcPtr(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	t = t.UTC()
+	return &t
+}
 
-	policyVer := ""
-	if e.policy != nil && e.policy.Meta.Version != "" {
-		policyVer = e.policy.Meta.Version
+func (e *Executor) newRecord(node string, entropy float64, strategy *policy.Strategy, result *cutter.CutResult) *history.CutRecord {
+	policyVer, policyHash := "", ""
+	if pol := e.currentPolicy(); pol != nil {
+		policyVer = pol.Meta.Version
+		policyHash = pol.Hash()
 	}
 
 	timestamp := time.Now().UTC()
 	record := &history.CutRecord{
-		ID:            fmt.Sprintf("cut_%d_%s", timestamp.Unix(), node),
+		ID:            history.NewCutID(node, timestamp),
 		Node:          node,
 		Entropy:       entropy,
 		Timestamp:     timestamp,
 		PolicyVersion: policyVer,
+		PolicyHash:    policyHash,
 		Strategy: history.StrategyInfo{
-			Threshold:    strategy.Threshold,
-			Action:       strategy.Action,
-			Critical:     strategy.Critical,
-			SnapshotName: strategy.SnapshotName,
-			Command:      strategy.Command,
+			Threshold:           strategy.Threshold,
+			Action:              strategy.Action,
+			Critical:            strategy.Critical,
+			SnapshotName:        strategy.SnapshotName,
+			Command:             strategy.Command,
+			ConsecutiveTriggers: strategy.RequiredTriggers(),
+			Description:         strategy.Description,
+			RunbookURL:          strategy.RunbookURL,
+			Labels:              strategy.Labels,
 		},
 	}
 
 	if result != nil {
+		if result.Outcome == "" {
+			result.Outcome = history.RanOutcome(result.Success)
+		}
 		record.Action = result.Action
 		record.Success = result.Success
+		record.DryRun = result.DryRun
+		record.Outcome = result.Outcome
 		record.LatencyMs = result.LatencyMs
+		record.Details = result.Details
+		record.Output = result.Output
 		if result.Error != nil {
 			record.Error = result.Error.Error()
 		}
 	}
 
+	return record
+}
+
+func (e *Executor) saveRecord(record *history.CutRecord) {
+	e.storeRecord(record, false)
+}
+
+func (e *Executor) logCut(node string, entropy float64, strategy *policy.Strategy, result *cutter.CutResult, opts CutOptions) {
+	record := e.newRecord(node, entropy, strategy, result)
+	opts.apply(record)
+	e.recordCut(record, result)
+}
+
+func (e *Executor) recordCut(record *history.CutRecord, result *cutter.CutResult) {
+	if e.history == nil {
+		return
+	}
+
+	if result != nil {
+		result.CutID = record.ID
+	}
+	e.storeRecord(record, true)
+}
+
+// storeRecord persists synchronously, so a record is durable before anyone
+// hears about it, then publishes cut_recorded.
+func (e *Executor) storeRecord(record *history.CutRecord, notify bool) {
+	observeRecord(record)
+	if e.history == nil {
+		return
+	}
+	e.inProgress.markSaved(record.ID)
+
 	if err := e.history.SaveCut(record); err != nil {
+		historyWriteErrors.Inc()
 		logger.Get().Error("failed_to_save_cut_history",
 			zap.Error(err),
-			zap.String("node", node),
+			zap.String("node", record.Node),
 			zap.String("action", record.Action),
 		)
 	}
+	e.run.add(record)
+
+	e.events.publish(Event{
+		Type:    EventCutRecorded,
+		Node:    record.Node,
+		Action:  record.Action,
+		Entropy: record.Entropy,
+		Record:  record,
+		Notify:  notify,
+	})
+}
 
-	if e.notifications != nil {
-		event := &notifications.CutEvent{
-			ID:        record.ID,
-			Node:      node,
-			Action:    record.Action,
-			Success:   record.Success,
-			Entropy:   entropy,
-			LatencyMs: record.LatencyMs,
-			Timestamp: record.Timestamp,
-		}
-		if result != nil && result.Error != nil {
-			event.Error = result.Error.Error()
-		}
+func (e *Executor) HistoryQuotaExceeded(status history.QuotaStatus) {
+	logger.Get().Warn("history_quota_exceeded",
+		zap.String("node", status.Node),
+		zap.Int("records", status.Records),
+		zap.Int("limit", status.Limit),
+	)
+
+	e.events.publish(Event{
+		Type:  EventHistoryQuotaExceeded,
+		Node:  status.Node,
+		Quota: &status,
+	})
+}
 
-		if err := e.notifications.NotifyCut(event); err != nil {
-			logger.Get().Error("failed_to_send_notification",
-				zap.Error(err),
-				zap.String("node", node),
-				zap.String("action", record.Action),
-			)
+type NodeStatus struct {
+	Node     string         `json:"node"`
+	Triggers []TriggerState `json:"triggers"`
+	Circuit  CircuitState   `json:"circuit"`
+}
+
+// NodeStatuses reports every node in the policy, sorted by name.
+func (e *Executor) NodeStatuses() []*NodeStatus {
+	pol := e.currentPolicy()
+	names := make([]string, 0, len(pol.Nodes))
+	for name := range pol.Nodes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	statuses := make([]*NodeStatus, 0, len(names))
+	for _, name := range names {
+		if status, ok := e.NodeStatus(name); ok {
+			statuses = append(statuses, status)
 		}
 	}
+	return statuses
+}
+
+func (e *Executor) NodeStatus(node string) (*NodeStatus, bool) {
+	nodePolicy, ok := e.lookupNode(e.currentPolicy(), node)
+	if !ok {
+		return nil, false
+	}
+
+	return &NodeStatus{
+		Node:     node,
+		Triggers: e.triggers.State(nodePolicy),
+		Circuit:  e.breakers.state(node, time.Now()),
+	}, true
 }
 
-func (e *Executor) ExecuteCutAsync(ctx context.Context, node string, entropy float64) <-chan *cutter.CutResult {
+func (e *Executor) ExecuteCutAsync(ctx context.Context, node string, entropy float64, opts CutOptions) <-chan *cutter.CutResult {
 	ch := make(chan *cutter.CutResult, 1)
 	go func() {
 		defer close(ch)
-		ch <- e.ExecuteCut(ctx, node, entropy)
+		ch <- e.ExecuteCutWith(ctx, node, entropy, opts)
 	}()
 	return ch
 }
@@ -0,0 +1,90 @@
+package engine
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"atropos/history"
+	"atropos/notifications"
+	"atropos/policy"
+)
+
+// testCutter handles test_ actions. While block is set, each call waits on
+// it; actions in fail return their error.
+type testCutter struct {
+	mu    sync.Mutex
+	calls []string
+	block chan struct{}
+	fail  map[string]error
+}
+
+func (c *testCutter) Name() string { return "test" }
+
+func (c *testCutter) CanHandle(action string) bool {
+	return strings.HasPrefix(action, "test_")
+}
+
+func (c *testCutter) Execute(ctx context.Context, target string, params map[string]string) error {
+	c.mu.Lock()
+	c.calls = append(c.calls, target+": "+params["action"])
+	block := c.block
+	err := c.fail[params["action"]]
+	c.mu.Unlock()
+	if block != nil {
+		select {
+		case <-block:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return err
+}
+
+func (c *testCutter) Calls() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.calls...)
+}
+
+func loadTestPolicy(t *testing.T, policyYAML string) *policy.RemediationPolicy {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "policy.yaml")
+	if err := os.WriteFile(path, []byte(policyYAML), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	pol, err := policy.LoadPolicy(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pol
+}
+
+// newTestExecutor runs policyYAML against a fresh history directory, with a
+// testCutter registered and notifications off.
+func newTestExecutor(t *testing.T, policyYAML string) (*Executor, *testCutter) {
+	t.Helper()
+	historyDir := filepath.Join(t.TempDir(), "history")
+	notif := notifications.NewNotificationManager(&notifications.NotificationConfig{
+		StateFile: filepath.Join(historyDir, "notification_state.json"),
+	})
+	e := NewExecutor(loadTestPolicy(t, policyYAML), history.NewHistoryManager(historyDir), notif)
+	c := &testCutter{fail: make(map[string]error)}
+	e.RegisterCutter(c)
+	return e, c
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
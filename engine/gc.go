@@ -0,0 +1,161 @@
+package engine
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"atropos/internal/logger"
+)
+
+// StateSummary counts the per-node entries the executor keeps in memory.
+type StateSummary struct {
+	RateLimits   int        `json:"rate_limits"`
+	Triggers     int        `json:"triggers"`
+	Circuits     int        `json:"circuits"`
+	Flights      int        `json:"flights"`
+	Dedup        int        `json:"dedup"`
+	IdleMinutes  float64    `json:"idle_minutes"`
+	LastGC       *time.Time `json:"last_gc,omitempty"`
+	LastEvicted  int        `json:"last_evicted"`
+	TotalEvicted int64      `json:"total_evicted"`
+}
+
+type gcState struct {
+	last         time.Time
+	lastEvicted  int
+	totalEvicted int64
+	mu           sync.Mutex
+}
+
+// StartStateGC periodically drops runtime state for nodes that have gone
+// quiet, so ephemeral node names don't accumulate forever.
+func (e *Executor) StartStateGC(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			e.collectState(time.Now())
+		}
+	}()
+}
+
+func (e *Executor) collectState(now time.Time) int {
+	pol := e.currentPolicy()
+	idle := pol.GetStateIdle()
+	evicted := e.rateLimiter.collect(now, idle) +
+		e.triggers.collect(now, idle) +
+		e.breakers.collect(now, idle) +
+		e.dedup.collect(now, pol.GetDedupWindow())
+
+	e.gc.mu.Lock()
+	e.gc.last = now.UTC()
+	e.gc.lastEvicted = evicted
+	e.gc.totalEvicted += int64(evicted)
+	e.gc.mu.Unlock()
+
+	if evicted > 0 {
+		summary := e.StateSummary()
+		logger.Get().Info("state_gc",
+			zap.Int("evicted", evicted),
+			zap.Int("rate_limits", summary.RateLimits),
+			zap.Int("triggers", summary.Triggers),
+			zap.Int("circuits", summary.Circuits),
+		)
+	}
+	return evicted
+}
+
+func (e *Executor) StateSummary() StateSummary {
+	summary := StateSummary{
+		IdleMinutes: e.currentPolicy().GetStateIdle().Minutes(),
+	}
+
+	e.rateLimiter.mu.Lock()
+	summary.RateLimits = len(e.rateLimiter.nodeCounts)
+	e.rateLimiter.mu.Unlock()
+
+	e.triggers.mu.Lock()
+	summary.Triggers = len(e.triggers.nodeCounts)
+	e.triggers.mu.Unlock()
+
+	e.breakers.mu.Lock()
+	summary.Circuits = len(e.breakers.nodes)
+	e.breakers.mu.Unlock()
+
+	e.flightMu.Lock()
+	summary.Flights = len(e.flights)
+	e.flightMu.Unlock()
+
+	summary.Dedup = e.dedup.size()
+
+	e.gc.mu.Lock()
+	if !e.gc.last.IsZero() {
+		last := e.gc.last
+		summary.LastGC = &last
+	}
+	summary.LastEvicted = e.gc.lastEvicted
+	summary.TotalEvicted = e.gc.totalEvicted
+	e.gc.mu.Unlock()
+	return summary
+}
+
+// collect drops entries whose last cut has left the window and is older than
+// idle.
+func (rl *RateLimiter) collect(now time.Time, idle time.Duration) int {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	evicted := 0
+	for node, entry := range rl.nodeCounts {
+		window := time.Duration(entry.limit.Window) * time.Minute
+		if n := len(entry.cuts); n == 0 || now.Sub(entry.cuts[n-1]) > max(window, idle) {
+			delete(rl.nodeCounts, node)
+			evicted++
+		}
+	}
+	return evicted
+}
+
+// collect forgets consecutive-trigger counts for nodes with no reading within
+// idle; a streak that long interrupted starts over.
+func (tc *TriggerCounter) collect(now time.Time, idle time.Duration) int {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+
+	evicted := 0
+	for node := range tc.nodeCounts {
+		if now.Sub(tc.lastSeen[node]) > idle {
+			delete(tc.nodeCounts, node)
+			delete(tc.lastSeen, node)
+			evicted++
+		}
+	}
+	return evicted
+}
+
+// collect drops closed breakers whose last failure has left both the failure
+// window and the idle period.
+func (b *circuitBreakers) collect(now time.Time, idle time.Duration) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	evicted := 0
+	for node, c := range b.nodes {
+		if !c.openUntil.IsZero() {
+			if now.Before(c.openUntil) {
+				continue
+			}
+			delete(b.nodes, node)
+			evicted++
+			continue
+		}
+		keep := max(c.window, idle)
+		if len(c.failures) == 0 || now.Sub(c.failures[len(c.failures)-1]) > keep {
+			delete(b.nodes, node)
+			evicted++
+		}
+	}
+	return evicted
+}
@@ -0,0 +1,128 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// oneShotPolicy has n nodes like a CI fleet's, each cut once and never seen
+// again. Odd nodes fail, so they leave a circuit entry behind as well.
+func oneShotPolicy(n int) string {
+	var b strings.Builder
+	b.WriteString("server:\n  state_idle_minutes: 10\n  dedup_window_seconds: 60\nnodes:\n")
+	for i := 0; i < n; i++ {
+		action := "test_restart"
+		if i%2 == 1 {
+			action = "test_fail"
+		}
+		fmt.Fprintf(&b, `  runner-%05d:
+    rate_limit:
+      max_cuts: 3
+      window_minutes: 30
+    strategies:
+      - threshold: 0.5
+        action: %s
+`, i, action)
+	}
+	return b.String()
+}
+
+func TestStateGCOneShotNodes(t *testing.T) {
+	const nodes = 2000
+	e, c := newTestExecutor(t, oneShotPolicy(nodes))
+	c.fail["test_fail"] = errors.New("runner gone")
+
+	// Collections run throughout, as the ticker would; with every node
+	// fresh they must not evict anything.
+	stop := make(chan struct{})
+	gcDone := make(chan int)
+	go func() {
+		evicted := 0
+		for {
+			select {
+			case <-stop:
+				gcDone <- evicted
+				return
+			case <-time.After(10 * time.Millisecond):
+				evicted += e.collectState(time.Now())
+				_ = e.StateSummary()
+			}
+		}
+	}()
+
+	start := time.Now()
+	jobs := make(chan int)
+	var workers sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for i := range jobs {
+				e.ExecuteCut(context.Background(), fmt.Sprintf("runner-%05d", i), 0.9)
+			}
+		}()
+	}
+	for i := 0; i < nodes; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	workers.Wait()
+	close(stop)
+	if evicted := <-gcDone; evicted != 0 {
+		t.Fatalf("collections during the cuts evicted %d entries, want 0", evicted)
+	}
+	if calls := len(c.Calls()); calls != nodes {
+		t.Fatalf("cutter ran %d times, want %d", calls, nodes)
+	}
+
+	summary := e.StateSummary()
+	if summary.RateLimits != nodes || summary.Triggers != nodes || summary.Circuits != nodes/2 || summary.Dedup != nodes {
+		t.Fatalf("state after the cuts = %+v, want %d rate limits, triggers and dedup entries and %d circuits", summary, nodes, nodes/2)
+	}
+
+	// Past the dedup window but within the idle period and rate limit
+	// window, only dedup entries go.
+	if evicted := e.collectState(start.Add(5 * time.Minute)); evicted != nodes {
+		t.Fatalf("collection at +5m evicted %d, want the %d dedup entries", evicted, nodes)
+	}
+	summary = e.StateSummary()
+	if summary.RateLimits != nodes || summary.Triggers != nodes || summary.Circuits != nodes/2 || summary.Dedup != 0 {
+		t.Fatalf("state at +5m = %+v", summary)
+	}
+
+	// Past the idle period, the triggers go; rate limits and circuits are
+	// kept for their 30 and 60 minute windows.
+	if evicted := e.collectState(start.Add(15 * time.Minute)); evicted != nodes {
+		t.Fatalf("collection at +15m evicted %d, want the %d trigger entries", evicted, nodes)
+	}
+	if evicted := e.collectState(start.Add(45 * time.Minute)); evicted != nodes {
+		t.Fatalf("collection at +45m evicted %d, want the %d rate limit entries", evicted, nodes)
+	}
+	if evicted := e.collectState(start.Add(90 * time.Minute)); evicted != nodes/2 {
+		t.Fatalf("collection at +90m evicted %d, want the %d circuits", evicted, nodes/2)
+	}
+
+	summary = e.StateSummary()
+	if summary.RateLimits != 0 || summary.Triggers != 0 || summary.Circuits != 0 || summary.Dedup != 0 {
+		t.Fatalf("state after the last collection = %+v, want empty", summary)
+	}
+	if summary.LastEvicted != nodes/2 || summary.TotalEvicted != int64(3*nodes+nodes/2) {
+		t.Fatalf("evicted last %d, total %d; want %d and %d", summary.LastEvicted, summary.TotalEvicted, nodes/2, 3*nodes+nodes/2)
+	}
+
+	// An evicted node starts over with a fresh rate limit.
+	before := len(c.Calls())
+	for i := 0; i < 3; i++ {
+		if result := e.ExecuteCut(context.Background(), "runner-00000", 0.9+float64(i)/100); !result.Success {
+			t.Fatalf("cut %d after eviction: %v", i, result.Error)
+		}
+	}
+	if calls := len(c.Calls()) - before; calls != 3 {
+		t.Fatalf("cutter ran %d times after eviction, want 3", calls)
+	}
+}
@@ -0,0 +1,121 @@
+package engine
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"atropos/internal/logger"
+)
+
+const (
+	HealthOperational = "operational"
+	HealthDegraded    = "degraded"
+	HealthUnhealthy   = "unhealthy"
+)
+
+func HealthRank(level string) int {
+	switch level {
+	case HealthDegraded:
+		return 1
+	case HealthUnhealthy:
+		return 2
+	}
+	return 0
+}
+
+type ComponentHealth struct {
+	Status    string    `json:"status"`
+	Message   string    `json:"message,omitempty"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+type HealthReport struct {
+	Status     string                     `json:"status"`
+	Components map[string]ComponentHealth `json:"components"`
+}
+
+type healthState struct {
+	components map[string]ComponentHealth
+	cutters    []CutterHealth
+	mu         sync.RWMutex
+}
+
+func (e *Executor) Health() HealthReport {
+	e.health.mu.RLock()
+	defer e.health.mu.RUnlock()
+
+	report := HealthReport{
+		Status:     HealthOperational,
+		Components: make(map[string]ComponentHealth, len(e.health.components)),
+	}
+	for name, component := range e.health.components {
+		report.Components[name] = component
+		if HealthRank(component.Status) > HealthRank(report.Status) {
+			report.Status = component.Status
+		}
+	}
+	return report
+}
+
+// StartHealthChecks probes components in the background so the health
+// endpoint only ever reads cached state.
+func (e *Executor) StartHealthChecks(interval time.Duration) {
+	e.checkHealth()
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			e.checkHealth()
+		}
+	}()
+}
+
+func (e *Executor) checkHealth() {
+	now := time.Now().UTC()
+	components := make(map[string]ComponentHealth)
+
+	if e.history != nil {
+		history := ComponentHealth{Status: HealthOperational, CheckedAt: now}
+		if err := e.history.CheckWritable(); err != nil {
+			history = ComponentHealth{Status: HealthUnhealthy, Message: err.Error(), CheckedAt: now}
+		}
+		components["history"] = history
+
+		quota := ComponentHealth{Status: HealthOperational, CheckedAt: now}
+		if exceeded := e.history.QuotaExceeded(); len(exceeded) > 0 {
+			quota = ComponentHealth{Status: HealthDegraded, Message: "nodes over history quota", CheckedAt: now}
+		}
+		components["history_quota"] = quota
+	}
+
+	if notifier := e.notifications.Load(); notifier.Enabled() {
+		notif := ComponentHealth{Status: HealthOperational, CheckedAt: now}
+		if err := notifier.LastError(); err != nil {
+			notif = ComponentHealth{Status: HealthDegraded, Message: err.Error(), CheckedAt: now}
+		}
+		components["notifications"] = notif
+	}
+
+	components["cutters"] = CutterComponent(e.CheckCutters(context.Background()))
+
+	e.health.mu.Lock()
+	previous := e.health.components
+	e.health.components = components
+	e.health.mu.Unlock()
+
+	for name, component := range components {
+		if prev, ok := previous[name]; ok && prev.Status == component.Status {
+			continue
+		}
+		if component.Status != HealthOperational {
+			logger.Get().Warn("component_health_changed",
+				zap.String("component", name),
+				zap.String("status", component.Status),
+				zap.String("message", component.Message),
+			)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"atropos/history"
+	"atropos/internal/logger"
+	"atropos/policy"
+)
+
+// runHooks runs hooks in order, each under its own timeout, and records the
+// results on the attempt. Every hook runs even if an earlier one failed; the
+// first failure is returned.
+func (e *Executor) runHooks(ctx context.Context, attempt *cutAttempt, phase string, hooks []policy.Hook) error {
+	var firstErr error
+	for i := range hooks {
+		hook := &hooks[i]
+		start := time.Now()
+		err := e.runHook(ctx, attempt, hook)
+		result := history.HookResult{
+			Phase:     phase,
+			Action:    hook.Action,
+			Success:   err == nil,
+			LatencyMs: time.Since(start).Milliseconds(),
+		}
+		if err != nil {
+			result.Error = err.Error()
+			logger.Get().Warn("hook_failed",
+				zap.String("node", attempt.node),
+				zap.String("phase", phase),
+				zap.String("action", hook.Action),
+				zap.Error(err),
+			)
+			if firstErr == nil {
+				firstErr = fmt.Errorf("%s hook %s: %w", phase, hook.Action, err)
+			}
+		}
+		attempt.hooks = append(attempt.hooks, result)
+	}
+	return firstErr
+}
+
+func (e *Executor) runHook(ctx context.Context, attempt *cutAttempt, hook *policy.Hook) error {
+	c, ok := e.registry.FindCutter(hook.Action)
+	if !ok {
+		return fmt.Errorf("no cutter for action: %s", hook.Action)
+	}
+	hookCtx, cancel := context.WithTimeout(ctx, hook.GetTimeout())
+	defer cancel()
+	return c.Execute(hookCtx, attempt.node, e.cutterParams(c, buildParams(attempt.node, attempt.nodePolicy, hook.Strategy())))
+}
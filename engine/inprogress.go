@@ -0,0 +1,155 @@
+package engine
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"atropos/cutter"
+	"atropos/history"
+	"atropos/internal/logger"
+)
+
+// inProgressCuts holds a placeholder record for each cut whose ID was handed
+// out up front (CutOptions.CutID), from the request until the cut's last
+// record is saved. A caller told to poll the cut finds it here meanwhile.
+// Once a caller is actually told to poll, the placeholder is also saved to
+// history, so the ID resolves even if Atropos restarts; the cut's own first
+// record replaces it there.
+type inProgressCuts struct {
+	records map[string]*inProgressCut
+	mu      sync.Mutex
+}
+
+type inProgressCut struct {
+	record *history.CutRecord
+	// saved is set once a record of the cut replaced the placeholder in
+	// history.
+	saved bool
+	// persisted is set once the placeholder itself is in history.
+	persisted bool
+}
+
+func newInProgressCuts() *inProgressCuts {
+	return &inProgressCuts{records: make(map[string]*inProgressCut)}
+}
+
+func (p *inProgressCuts) add(record *history.CutRecord) {
+	p.mu.Lock()
+	p.records[record.ID] = &inProgressCut{record: record}
+	p.mu.Unlock()
+}
+
+// markSaved waits out a placeholder being persisted, so the cut's record
+// always lands after it.
+func (p *inProgressCuts) markSaved(id string) {
+	p.mu.Lock()
+	if cut, ok := p.records[id]; ok {
+		cut.saved = true
+	}
+	p.mu.Unlock()
+}
+
+func (p *inProgressCuts) remove(id string) *inProgressCut {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	cut := p.records[id]
+	delete(p.records, id)
+	return cut
+}
+
+// trackInProgress registers the cut's placeholder. It stays in memory until
+// PersistInProgress.
+func (e *Executor) trackInProgress(node string, entropy float64, opts CutOptions) {
+	record := &history.CutRecord{
+		ID:        opts.CutID,
+		Node:      node,
+		Entropy:   entropy,
+		Outcome:   history.OutcomeInProgress,
+		Timestamp: opts.ReceivedAt.UTC(),
+		Trigger:   history.TriggerInitial,
+	}
+	opts.apply(record)
+	e.inProgress.add(record)
+}
+
+// PersistInProgress saves the placeholder of cut id to history, for a
+// caller about to be told to poll it. It does nothing once the cut has saved
+// a record of its own, or has finished.
+func (e *Executor) PersistInProgress(id string) {
+	if e.history == nil {
+		return
+	}
+	e.inProgress.mu.Lock()
+	defer e.inProgress.mu.Unlock()
+	cut, ok := e.inProgress.records[id]
+	if !ok || cut.saved || cut.persisted {
+		return
+	}
+	cp := *cut.record
+	if err := e.history.SaveCut(&cp); err != nil {
+		historyWriteErrors.Inc()
+		logger.Get().Error("failed_to_save_cut_history",
+			zap.Error(err),
+			zap.String("node", cp.Node),
+			zap.String("cut_id", id),
+		)
+		return
+	}
+	cut.persisted = true
+}
+
+// finishInProgress drops the placeholder. A cut that ended without saving a
+// record under its ID, e.g. one refused during shutdown, has a persisted
+// placeholder marked failed so pollers don't wait forever.
+func (e *Executor) finishInProgress(id string, result *cutter.CutResult) {
+	cut := e.inProgress.remove(id)
+	if cut == nil || cut.saved || !cut.persisted || e.history == nil {
+		return
+	}
+	record := *cut.record
+	record.Outcome = history.OutcomeFailed
+	record.Action = result.Action
+	if result.Error != nil {
+		record.Error = result.Error.Error()
+	}
+	if err := e.history.SaveCut(&record); err != nil {
+		historyWriteErrors.Inc()
+		logger.Get().Error("failed_to_save_cut_history",
+			zap.Error(err),
+			zap.String("node", record.Node),
+			zap.String("cut_id", id),
+		)
+	}
+}
+
+// InProgressCut returns a copy of the placeholder for a cut that has not
+// finished yet.
+func (e *Executor) InProgressCut(id string) (*history.CutRecord, bool) {
+	e.inProgress.mu.Lock()
+	defer e.inProgress.mu.Unlock()
+	cut, ok := e.inProgress.records[id]
+	if !ok {
+		return nil, false
+	}
+	cp := *cut.record
+	return &cp, true
+}
+
+// StartCut saves the cut's in_progress record and runs the cut in the
+// background. It returns the cut's ID, opts.CutID or a new one; the first
+// record of the cut replaces the placeholder under that ID.
+func (e *Executor) StartCut(ctx context.Context, node string, entropy float64, opts CutOptions) string {
+	if opts.ReceivedAt.IsZero() {
+		opts.ReceivedAt = time.Now()
+	}
+	if opts.CutID == "" {
+		opts.CutID = history.NewCutID(node, opts.ReceivedAt)
+	}
+	e.trackInProgress(node, entropy, opts)
+	e.PersistInProgress(opts.CutID)
+	go e.executeTracked(ctx, node, entropy, opts)
+	return opts.CutID
+}
@@ -0,0 +1,62 @@
+package engine
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"atropos/cutter"
+	"atropos/history"
+)
+
+// A cut handed its ID up front keeps its placeholder in memory until a
+// caller is told to poll it; only then does it reach history.
+func TestInProgressPersistedOnlyWhenPolled(t *testing.T) {
+	e, c := newTestExecutor(t, shutdownPolicy)
+	c.block = make(chan struct{})
+	id := history.NewCutID("athena", time.Now())
+
+	done := make(chan *cutter.CutResult, 1)
+	go func() { done <- e.ExecuteCutWith(context.Background(), "athena", 0.9, CutOptions{CutID: id}) }()
+	waitFor(t, "the cut to reach the cutter", func() bool { return len(c.Calls()) == 1 })
+
+	if cut, ok := e.InProgressCut(id); !ok || cut.Outcome != history.OutcomeInProgress {
+		t.Fatalf("placeholder %+v, %v; want one in memory", cut, ok)
+	}
+	if _, err := e.history.LoadCut(id); err == nil {
+		t.Fatal("placeholder saved to history before anyone polled")
+	}
+
+	e.PersistInProgress(id)
+	if cut, err := e.history.LoadCut(id); err != nil || cut.Outcome != history.OutcomeInProgress {
+		t.Fatalf("history after persisting: %+v, %v; want the placeholder", cut, err)
+	}
+
+	close(c.block)
+	if result := <-done; !result.Success {
+		t.Fatalf("cut: %v", result.Error)
+	}
+	// Too late to persist: the cut's record stays.
+	e.PersistInProgress(id)
+	if cut, err := e.history.LoadCut(id); err != nil || cut.Outcome != history.OutcomeExecuted {
+		t.Fatalf("history after the cut: %+v, %v; want the executed record", cut, err)
+	}
+}
+
+// A cut answered in time never writes a placeholder, so there is nothing to
+// mark failed when it is refused.
+func TestInProgressRefusedWithoutPlaceholder(t *testing.T) {
+	e, _ := newTestExecutor(t, shutdownPolicy)
+	e.closing.Store(true)
+	id := history.NewCutID("athena", time.Now())
+
+	if result := e.ExecuteCutWith(context.Background(), "athena", 0.9, CutOptions{CutID: id}); result.Error == nil {
+		t.Fatal("cut ran while shutting down")
+	}
+	if _, err := e.history.LoadCut(id); err == nil {
+		t.Fatal("refused cut left a record behind")
+	}
+	if _, ok := e.InProgressCut(id); ok {
+		t.Fatal("refused cut still in progress")
+	}
+}
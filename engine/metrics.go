@@ -0,0 +1,78 @@
+package engine
+
+import (
+	"time"
+
+	"atropos/history"
+	"atropos/internal/metrics"
+)
+
+var (
+	cutsTotal = metrics.NewCounter("atropos_cuts_total",
+		"Cut records saved, by node, action and outcome.", "node", "action", "outcome")
+	cutDuration = metrics.NewHistogram("atropos_cut_duration_seconds",
+		"Time the cutter took, retries included, by action.", metrics.DefaultBuckets, "action")
+	cutPhase = metrics.NewHistogram("atropos_cut_phase_seconds",
+		"Time spent in each phase of a cut: decision, wait and cutter.", metrics.DefaultBuckets, "phase")
+	cutsInFlight = metrics.NewGauge("atropos_cuts_in_flight",
+		"Cut requests being decided or run, including those waiting their turn.")
+	rateLimitRejections = metrics.NewCounter("atropos_rate_limit_rejections_total",
+		"Cuts refused by a rate limit, by node and limit (node or action).", "node", "limit")
+	rateLimitBypasses = metrics.NewCounter("atropos_rate_limit_bypasses_total",
+		"Critical cuts that skipped their rate limit, by node.", "node")
+	escalations = metrics.NewCounter("atropos_escalations_total",
+		"Chain steps taken after a failed strategy, by node and type (fallback or escalation).", "node", "type")
+	historyWriteErrors = metrics.NewCounter("atropos_history_write_errors_total",
+		"Cut records that could not be saved.")
+)
+
+func observeRecord(record *history.CutRecord) {
+	cutsTotal.Inc(record.Node, record.Action, record.Outcome)
+	if t := record.Timings; t != nil {
+		if ms, ok := t.DecisionMs(); ok {
+			cutPhase.Observe(msSeconds(ms), "decision")
+		}
+		if ms, ok := t.WaitMs(); ok {
+			cutPhase.Observe(msSeconds(ms), "wait")
+		}
+		if ms, ok := t.CutterMs(); ok {
+			cutPhase.Observe(msSeconds(ms), "cutter")
+		}
+	}
+}
+
+func msSeconds(ms int64) float64 {
+	return (time.Duration(ms) * time.Millisecond).Seconds()
+}
+
+// registerStateMetrics exposes the executor's in-memory state and callback
+// counters, read at scrape time. A later executor replaces an earlier one.
+func (e *Executor) registerStateMetrics() {
+	metrics.GaugeFunc("atropos_state_entries",
+		"Per-node runtime state entries kept in memory, by map.", "map",
+		func() map[string]float64 {
+			s := e.StateSummary()
+			return map[string]float64{
+				"rate_limits": float64(s.RateLimits),
+				"triggers":    float64(s.Triggers),
+				"circuits":    float64(s.Circuits),
+				"flights":     float64(s.Flights),
+				"dedup":       float64(s.Dedup),
+			}
+		})
+	metrics.CounterFunc("atropos_state_evicted_total",
+		"Runtime state entries dropped by garbage collection.", "",
+		func() map[string]float64 {
+			return map[string]float64{"": float64(e.StateSummary().TotalEvicted)}
+		})
+	metrics.CounterFunc("atropos_callbacks_total",
+		"Outcome callbacks, by result (delivered, failed or retried).", "result",
+		func() map[string]float64 {
+			s := e.CallbackStats()
+			return map[string]float64{
+				"delivered": float64(s.Delivered),
+				"failed":    float64(s.Failed),
+				"retried":   float64(s.Retries),
+			}
+		})
+}
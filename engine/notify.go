@@ -0,0 +1,99 @@
+package engine
+
+import (
+	"fmt"
+
+	"go.uber.org/zap"
+
+	"atropos/history"
+	"atropos/internal/logger"
+	"atropos/internal/timefmt"
+	"atropos/notifications"
+)
+
+const notificationBuffer = 1024
+
+// runNotifications is the notification subscriber: it turns recorded cuts
+// and quota alerts into notifier calls, one at a time and in publish order.
+func (e *Executor) runNotifications(events <-chan Event) {
+	for event := range events {
+		switch event.Type {
+		case EventCutRecorded:
+			if event.Notify {
+				e.notifyRecord(event.Record)
+			}
+		case EventHistoryQuotaExceeded:
+			e.notifyQuota(event)
+		case EventCircuitOpened:
+			e.notifyCircuit(event)
+		}
+	}
+}
+
+func (e *Executor) notifyRecord(record *history.CutRecord) {
+	event := &notifications.CutEvent{
+		ID:          record.ID,
+		Node:        record.Node,
+		Action:      record.Action,
+		Success:     record.Success,
+		Outcome:     record.Outcome,
+		Trigger:     record.Trigger,
+		Source:      record.Source,
+		Reason:      record.Reason,
+		DryRun:      record.DryRun,
+		Entropy:     record.Entropy,
+		LatencyMs:   record.LatencyMs,
+		Error:       record.Error,
+		Timestamp:   record.Timestamp,
+		Description: record.Strategy.Description,
+		RunbookURL:  record.Strategy.RunbookURL,
+		Tags:        record.Tags,
+	}
+	e.sendNotification(event)
+}
+
+func (e *Executor) notifyQuota(event Event) {
+	status := event.Quota
+	e.sendNotification(&notifications.CutEvent{
+		Node:      status.Node,
+		Action:    "history_quota",
+		Outcome:   "quota_exceeded",
+		Error:     fmt.Sprintf("history quota exceeded: %d records (limit %d)", status.Records, status.Limit),
+		Timestamp: event.Time,
+	})
+}
+
+func (e *Executor) notifyCircuit(event Event) {
+	state := event.Circuit
+	e.sendNotification(&notifications.CutEvent{
+		Node:      event.Node,
+		Action:    event.Action,
+		Outcome:   history.OutcomeCircuitOpen,
+		Error:     fmt.Sprintf("circuit opened after %d consecutive failures: %s", state.ConsecutiveFailures, state.LastError),
+		Timestamp: event.Time,
+		Metadata: map[string]interface{}{
+			"severity":   "critical",
+			"open_until": timefmt.RFC3339(*state.OpenUntil),
+		},
+	})
+}
+
+func (e *Executor) sendNotification(event *notifications.CutEvent) {
+	notifier := e.notifications.Load()
+	if notifier == nil {
+		return
+	}
+
+	var override *notifications.Override
+	if nodePolicy, ok := e.lookupNode(e.currentPolicy(), event.Node); ok {
+		override = nodePolicy.Notifications
+	}
+
+	if err := notifier.NotifyCutWith(event, override); err != nil {
+		logger.Get().Error("failed_to_send_notification",
+			zap.Error(err),
+			zap.String("node", event.Node),
+			zap.String("action", event.Action),
+		)
+	}
+}
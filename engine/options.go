@@ -0,0 +1,68 @@
+package engine
+
+import (
+	"time"
+
+	"atropos/history"
+	"atropos/policy"
+)
+
+type CutOptions struct {
+	Tags  map[string]string
+	KeyID string
+	// CallbackURL is a per-request outcome target, already checked against
+	// callbacks.allowed_hosts by the caller.
+	CallbackURL string
+	// ReceivedAt is when the request reached Atropos; it starts the record's
+	// timings.
+	ReceivedAt time.Time
+	// EntropyOriginal is the out-of-range reading the caller clamped.
+	EntropyOriginal *float64
+	// Strategy, when set, is run instead of the one the entropy selects and
+	// skips consecutive-trigger counting; schedules use it.
+	Strategy *policy.Strategy
+	// Trigger marks the first record of the cut; later chain steps keep
+	// their fallback or escalation trigger.
+	Trigger string
+	// Source names who asked for the cut and Reason why. Chain steps set
+	// their own.
+	Source string
+	Reason string
+	// CutID, when set, is the ID of the cut's first record, handed to the
+	// caller before the cut finishes. Later chain steps get their own.
+	CutID string
+
+	// slotted, when set, is called once the cut has a slot and its strategy
+	// starts; the pool splits its workers' waiting from running with it.
+	slotted func()
+}
+
+func (o CutOptions) apply(record *history.CutRecord) {
+	if o.CutID != "" {
+		record.ID = o.CutID
+	}
+	if len(o.Tags) > 0 {
+		record.Tags = o.Tags
+	}
+	if o.KeyID != "" {
+		record.KeyID = o.KeyID
+	}
+	if o.CallbackURL != "" {
+		record.CallbackURL = o.CallbackURL
+	}
+	if o.Trigger != "" && record.Trigger == "" {
+		record.Trigger = o.Trigger
+	}
+	if o.Source != "" && record.Source == "" {
+		record.Source = o.Source
+	}
+	if o.Reason != "" && record.Reason == "" {
+		record.Reason = o.Reason
+	}
+	if o.EntropyOriginal != nil {
+		record.EntropyOriginal = o.EntropyOriginal
+	}
+	if !o.ReceivedAt.IsZero() {
+		record.Timings = &history.Timings{ReceivedAt: o.ReceivedAt.UTC()}
+	}
+}
@@ -0,0 +1,90 @@
+package engine
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"atropos/internal/logger"
+	"atropos/policy"
+)
+
+const redactedParam = "[redacted]"
+
+var sensitiveParamWords = []string{"secret", "password", "token"}
+
+// buildParams assembles the map handed to Cutter.Execute. Strategy params and
+// then node params are merged in, but never replace a key that has a value.
+func buildParams(node string, nodePolicy *policy.NodePolicy, strategy *policy.Strategy) map[string]string {
+	params := map[string]string{
+		"action":        strategy.Action,
+		"command":       strategy.Command,
+		"snapshot_name": strategy.SnapshotName,
+		"host":          nodePolicy.Host,
+		"user":          nodePolicy.User,
+	}
+	if nodePolicy.Port > 0 {
+		params["port"] = fmt.Sprintf("%d", nodePolicy.Port)
+	}
+	if len(strategy.SuccessExitCodes) > 0 {
+		codes := make([]string, len(strategy.SuccessExitCodes))
+		for i, code := range strategy.SuccessExitCodes {
+			codes[i] = strconv.Itoa(code)
+		}
+		params["success_exit_codes"] = strings.Join(codes, ",")
+	}
+	if strategy.SuccessOutputRegex != "" {
+		params["success_output_regex"] = strategy.SuccessOutputRegex
+	}
+	if strategy.FailureOutputRegex != "" {
+		params["failure_output_regex"] = strategy.FailureOutputRegex
+	}
+	if strategy.PreserveState {
+		params["preserve_state"] = "true"
+		params["preserve_state_failure"] = strategy.PreserveStateFailure
+	}
+
+	for key, value := range strategy.Params {
+		if existing := params[key]; existing != "" {
+			logger.Get().Warn("strategy_param_ignored",
+				zap.String("node", node),
+				zap.String("action", strategy.Action),
+				zap.String("param", key),
+			)
+			continue
+		}
+		params[key] = value
+	}
+	for key, value := range nodePolicy.Params {
+		if params[key] == "" {
+			params[key] = value
+		}
+	}
+	return params
+}
+
+func redactParams(params map[string]string) map[string]string {
+	out := make(map[string]string, len(params))
+	for key, value := range params {
+		if value == "" {
+			continue
+		}
+		if sensitiveParam(key) {
+			value = redactedParam
+		}
+		out[key] = value
+	}
+	return out
+}
+
+func sensitiveParam(key string) bool {
+	key = strings.ToLower(key)
+	for _, word := range sensitiveParamWords {
+		if strings.Contains(key, word) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,57 @@
+package engine
+
+import (
+	"context"
+	"time"
+
+	"atropos/cutter"
+	"atropos/policy"
+)
+
+const planTimeout = 10 * time.Second
+
+// StepPlan is a cutter's read-only check of one of a strategy's actions.
+type StepPlan struct {
+	Action    string                 `json:"action"`
+	Cutter    string                 `json:"cutter,omitempty"`
+	Supported bool                   `json:"supported"`
+	OK        bool                   `json:"ok"`
+	Summary   string                 `json:"summary,omitempty"`
+	Error     string                 `json:"error,omitempty"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+}
+
+// Plan asks the cutters of each of strategy's actions whether it would work,
+// without running it. Cutters that can't tell say so rather than OK.
+func (e *Executor) Plan(ctx context.Context, node string, nodePolicy *policy.NodePolicy, strategy *policy.Strategy) []StepPlan {
+	if policy.ObserveOnly(strategy.Action) {
+		return nil
+	}
+	steps, err := e.resolveSteps(node, nodePolicy, strategy)
+	if err != nil {
+		return []StepPlan{{Action: strategy.Action, Supported: true, Error: err.Error()}}
+	}
+
+	plans := make([]StepPlan, len(steps))
+	for i, step := range steps {
+		plan := StepPlan{Action: step.strategy.Action, Cutter: step.cutter.Name()}
+		planner, ok := step.cutter.(cutter.Planner)
+		if !ok {
+			plan.Summary = "plan not supported"
+			plans[i] = plan
+			continue
+		}
+		plan.Supported = true
+		stepCtx, cancel := context.WithTimeout(ctx, planTimeout)
+		result, err := planner.Plan(stepCtx, node, step.params)
+		cancel()
+		plan.Summary, plan.Details = result.Summary, result.Details
+		if err != nil {
+			plan.Error = err.Error()
+		} else {
+			plan.OK = true
+		}
+		plans[i] = plan
+	}
+	return plans
+}
@@ -0,0 +1,246 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"atropos/cutter"
+)
+
+var (
+	ErrQueueFull    = errors.New("cut queue full")
+	ErrPoolDisabled = errors.New("worker pool not configured")
+)
+
+const (
+	JobQueued  = "queued"
+	JobRunning = "running"
+	JobDone    = "done"
+)
+
+// maxRetainedJobs bounds how many finished jobs stay pollable.
+const maxRetainedJobs = 1000
+
+type CutJob struct {
+	ID          string     `json:"id"`
+	Node        string     `json:"node"`
+	Entropy     float64    `json:"entropy"`
+	Status      string     `json:"status"`
+	SubmittedAt time.Time  `json:"submitted_at"`
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty"`
+	CutID       string     `json:"cut_id,omitempty"`
+	Action      string     `json:"action,omitempty"`
+	Success     bool       `json:"success"`
+	Outcome     string     `json:"outcome,omitempty"`
+	Error       string     `json:"error,omitempty"`
+	// Escalation is set as in the synchronous cut response.
+	Escalation *cutter.Escalation `json:"escalation,omitempty"`
+}
+
+type poolJob struct {
+	job  *CutJob
+	opts CutOptions
+}
+
+// worker splits its busy time into waiting, for the node's earlier cut or a
+// free cut slot, and running the cut once it has one.
+type worker struct {
+	busy         atomic.Bool
+	completed    atomic.Int64
+	waitingNanos atomic.Int64
+	runningNanos atomic.Int64
+}
+
+type workerPool struct {
+	queue   chan *poolJob
+	workers []*worker
+	started time.Time
+	seq     atomic.Uint64
+
+	jobs     map[string]*CutJob
+	finished []string
+	mu       sync.Mutex
+}
+
+func newWorkerPool(e *Executor, workers, queueSize int) *workerPool {
+	p := &workerPool{
+		queue:   make(chan *poolJob, queueSize),
+		workers: make([]*worker, workers),
+		started: time.Now(),
+		jobs:    make(map[string]*CutJob),
+	}
+	for i := range p.workers {
+		p.workers[i] = &worker{}
+		go p.run(e, p.workers[i])
+	}
+	return p
+}
+
+func (p *workerPool) run(e *Executor, w *worker) {
+	for pj := range p.queue {
+		w.busy.Store(true)
+		start := time.Now().UTC()
+		p.update(pj.job.ID, func(job *CutJob) {
+			job.Status = JobRunning
+			job.StartedAt = &start
+		})
+
+		// Cuts settled by a guard never get a slot, and wait throughout.
+		var running time.Time
+		opts := pj.opts
+		opts.slotted = func() { running = time.Now().UTC() }
+
+		// The submitting request is long gone, so the cut gets its own context.
+		result := e.ExecuteCutWith(context.Background(), pj.job.Node, pj.job.Entropy, opts)
+
+		end := time.Now().UTC()
+		p.update(pj.job.ID, func(job *CutJob) {
+			job.Status = JobDone
+			job.FinishedAt = &end
+			job.CutID = result.CutID
+			job.Action = result.Action
+			job.Success = result.Success
+			job.Outcome = result.Outcome
+			job.Escalation = result.Escalation
+			if result.Error != nil {
+				job.Error = result.Error.Error()
+			}
+		})
+		p.retire(pj.job.ID)
+
+		if running.IsZero() {
+			running = end
+		}
+		w.waitingNanos.Add(int64(running.Sub(start)))
+		w.runningNanos.Add(int64(end.Sub(running)))
+		w.completed.Add(1)
+		w.busy.Store(false)
+	}
+}
+
+func (p *workerPool) submit(node string, entropy float64, opts CutOptions) (*CutJob, error) {
+	job := &CutJob{
+		ID:          fmt.Sprintf("job_%d_%d", time.Now().UnixNano(), p.seq.Add(1)),
+		Node:        node,
+		Entropy:     entropy,
+		Status:      JobQueued,
+		SubmittedAt: time.Now().UTC(),
+	}
+
+	p.mu.Lock()
+	p.jobs[job.ID] = job
+	p.mu.Unlock()
+
+	// Copied before a worker can pick the job up and update it.
+	snapshot := *job
+	select {
+	case p.queue <- &poolJob{job: job, opts: opts}:
+		return &snapshot, nil
+	default:
+		p.mu.Lock()
+		delete(p.jobs, job.ID)
+		p.mu.Unlock()
+		return nil, fmt.Errorf("%w: %d jobs waiting", ErrQueueFull, cap(p.queue))
+	}
+}
+
+func (p *workerPool) update(id string, fn func(*CutJob)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if job, ok := p.jobs[id]; ok {
+		fn(job)
+	}
+}
+
+// retire marks a job finished and forgets the oldest finished jobs beyond
+// maxRetainedJobs.
+func (p *workerPool) retire(id string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.finished = append(p.finished, id)
+	for len(p.finished) > maxRetainedJobs {
+		delete(p.jobs, p.finished[0])
+		p.finished = p.finished[1:]
+	}
+}
+
+func (p *workerPool) get(id string) (CutJob, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	job, ok := p.jobs[id]
+	if !ok {
+		return CutJob{}, false
+	}
+	return *job, true
+}
+
+type WorkerStatus struct {
+	ID          int     `json:"id"`
+	Busy        bool    `json:"busy"`
+	Completed   int64   `json:"completed"`
+	Utilization float64 `json:"utilization"`
+	Waiting     float64 `json:"waiting"`
+}
+
+type PoolStatus struct {
+	Workers       int            `json:"workers"`
+	Busy          int            `json:"busy"`
+	QueueDepth    int            `json:"queue_depth"`
+	QueueCapacity int            `json:"queue_capacity"`
+	PerWorker     []WorkerStatus `json:"per_worker"`
+}
+
+// SubmitCut queues a cut for the worker pool and returns its job at once.
+func (e *Executor) SubmitCut(node string, entropy float64, opts CutOptions) (*CutJob, error) {
+	if e.pool == nil {
+		return nil, ErrPoolDisabled
+	}
+	return e.pool.submit(node, entropy, opts)
+}
+
+func (e *Executor) Job(id string) (CutJob, bool) {
+	if e.pool == nil {
+		return CutJob{}, false
+	}
+	return e.pool.get(id)
+}
+
+func (e *Executor) PoolEnabled() bool {
+	return e.pool != nil
+}
+
+// Pool reports queue depth and, per worker, the shares of wall time since
+// startup spent running cuts and waiting to. It returns nil without a worker
+// pool.
+func (e *Executor) Pool() *PoolStatus {
+	p := e.pool
+	if p == nil {
+		return nil
+	}
+	uptime := time.Since(p.started)
+	status := &PoolStatus{
+		Workers:       len(p.workers),
+		QueueDepth:    len(p.queue),
+		QueueCapacity: cap(p.queue),
+		PerWorker:     make([]WorkerStatus, len(p.workers)),
+	}
+	for i, w := range p.workers {
+		busy := w.busy.Load()
+		if busy {
+			status.Busy++
+		}
+		status.PerWorker[i] = WorkerStatus{
+			ID:          i,
+			Busy:        busy,
+			Completed:   w.completed.Load(),
+			Utilization: float64(w.runningNanos.Load()) / float64(uptime),
+			Waiting:     float64(w.waitingNanos.Load()) / float64(uptime),
+		}
+	}
+	return status
+}
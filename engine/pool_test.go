@@ -0,0 +1,95 @@
+package engine
+
+import (
+	"testing"
+	"time"
+)
+
+const poolPolicy = `
+server:
+  dedup_window_seconds: 0
+  worker_pool:
+    workers: 2
+    queue_size: 8
+nodes:
+  athena:
+    strategies:
+      - threshold: 0.5
+        action: test_restart
+  borg:
+    strategies:
+      - threshold: 0.5
+        action: test_restart
+`
+
+func waitJob(t *testing.T, e *Executor, id string) CutJob {
+	t.Helper()
+	var job CutJob
+	waitFor(t, "job "+id, func() bool {
+		job, _ = e.Job(id)
+		return job.Status == JobDone
+	})
+	return job
+}
+
+// Two workers run cuts on two nodes at once; a second cut on the same node
+// waits for the first, and the wait is reported apart from running.
+func TestPoolRunsNodesConcurrently(t *testing.T) {
+	e, c := newTestExecutor(t, poolPolicy)
+	block := make(chan struct{})
+	c.block = block
+
+	var ids []string
+	for _, node := range []string{"athena", "borg"} {
+		job, err := e.SubmitCut(node, 0.9, CutOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, job.ID)
+	}
+	waitFor(t, "both jobs to reach the cutter", func() bool { return len(c.Calls()) == 2 })
+	if status := e.Pool(); status.Busy != 2 {
+		t.Fatalf("pool = %+v, want both workers busy", status)
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(block)
+	for _, id := range ids {
+		if job := waitJob(t, e, id); !job.Success {
+			t.Fatalf("job %s: %s", id, job.Error)
+		}
+	}
+
+	block = make(chan struct{})
+	c.mu.Lock()
+	c.block = block
+	c.mu.Unlock()
+	first, err := e.SubmitCut("athena", 0.9, CutOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "the first athena job to reach the cutter", func() bool { return len(c.Calls()) == 3 })
+	second, err := e.SubmitCut("athena", 0.9, CutOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "the second athena job to start", func() bool {
+		job, _ := e.Job(second.ID)
+		return job.Status == JobRunning
+	})
+	time.Sleep(50 * time.Millisecond)
+	close(block)
+	for _, id := range []string{first.ID, second.ID} {
+		if job := waitJob(t, e, id); !job.Success {
+			t.Fatalf("job %s: %s", id, job.Error)
+		}
+	}
+
+	var running, waiting float64
+	for _, w := range e.Pool().PerWorker {
+		running += w.Utilization
+		waiting += w.Waiting
+	}
+	if running <= 0 || waiting <= 0 {
+		t.Fatalf("pool utilization %g, waiting %g; want both reported", running, waiting)
+	}
+}
@@ -0,0 +1,75 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"atropos/history"
+	"atropos/notifications"
+)
+
+func TestHistoryQuotaNotifiesOnce(t *testing.T) {
+	var mu sync.Mutex
+	var quotaEvents []notifications.CutEvent
+	sink := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		var event notifications.CutEvent
+		if json.Unmarshal(data, &event) == nil && event.Action == "history_quota" {
+			mu.Lock()
+			quotaEvents = append(quotaEvents, event)
+			mu.Unlock()
+		}
+	}))
+	defer sink.Close()
+	received := func() []notifications.CutEvent {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]notifications.CutEvent(nil), quotaEvents...)
+	}
+
+	historyDir := filepath.Join(t.TempDir(), "history")
+	notif := notifications.NewNotificationManager(&notifications.NotificationConfig{
+		Enabled: true,
+		Webhook: &notifications.WebhookConfig{URL: sink.URL, Retries: 1},
+	})
+	h := history.NewHistoryManager(historyDir)
+	e := NewExecutor(loadTestPolicy(t, `
+server:
+  dedup_window_seconds: 0
+nodes:
+  athena:
+    strategies:
+      - threshold: 0.5
+        action: test_restart
+`), h, notif)
+	e.RegisterCutter(&testCutter{})
+	if err := h.SetQuota(history.Quota{MaxRecordsPerNode: 2}, e.HistoryQuotaExceeded); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 4; i++ {
+		e.ExecuteCut(context.Background(), "athena", 0.8)
+	}
+	waitFor(t, "the quota notification", func() bool { return len(received()) > 0 })
+	if _, err := h.TrimToQuota(); err != nil {
+		t.Fatal(err)
+	}
+	e.ExecuteCut(context.Background(), "athena", 0.8)
+	waitFor(t, "the second quota notification", func() bool { return len(received()) > 1 })
+
+	events := received()
+	if len(events) != 2 {
+		t.Fatalf("%d quota notifications, want one per crossing", len(events))
+	}
+	for _, event := range events {
+		if event.Node != "athena" || event.Outcome != "quota_exceeded" || event.Error != "history quota exceeded: 3 records (limit 2)" {
+			t.Fatalf("quota notification = %+v", event)
+		}
+	}
+}
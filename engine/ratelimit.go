@@ -0,0 +1,248 @@
+package engine
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"atropos/cutter"
+	"atropos/history"
+	"atropos/internal/logger"
+	"atropos/policy"
+)
+
+// Which limit a cut was admitted or refused under.
+const (
+	rateLimitNode     = "node"
+	rateLimitAction   = "action"
+	rateLimitBypassed = "bypassed"
+)
+
+func actionRateLimitKey(node, action string) string {
+	return node + "|" + action
+}
+
+// rateLimitCharge is the limit a cut was admitted under. scope is "" when
+// none is configured.
+type rateLimitCharge struct {
+	scope string
+	key   string
+	limit *policy.RateLimit
+}
+
+// admitRateLimit checks the limit the strategy's cuts count against: its
+// own rate_limit per node and action when it has one, otherwise the node's.
+// It returns the limit that admitted the cut, to charge when it runs, or the
+// refusal to record.
+func (e *Executor) admitRateLimit(node string, nodePolicy *policy.NodePolicy, strategy *policy.Strategy) (rateLimitCharge, *cutter.CutResult) {
+	key, scope, limit := node, rateLimitNode, nodePolicy.RateLimit
+	if strategy.RateLimit != nil {
+		key, scope, limit = actionRateLimitKey(node, strategy.Action), rateLimitAction, strategy.RateLimit
+	}
+	if limit == nil || limit.MaxCuts == 0 {
+		return rateLimitCharge{}, nil
+	}
+
+	if strategy.BypassRateLimit {
+		rateLimitBypasses.Inc(node)
+		logger.Get().Info("rate_limit_bypassed",
+			zap.String("node", node),
+			zap.String("action", strategy.Action),
+			zap.String("limit", scope),
+		)
+		return rateLimitCharge{scope: rateLimitBypassed, key: key, limit: limit}, nil
+	}
+
+	allowed, wait, err := e.rateLimiter.checkRateLimit(key, limit)
+	if allowed {
+		return rateLimitCharge{scope: scope, key: key, limit: limit}, nil
+	}
+	if scope == rateLimitAction {
+		err = fmt.Errorf("rate limit exceeded for %s: %d cuts per %d minutes", strategy.Action, limit.MaxCuts, limit.Window)
+	}
+	rateLimitRejections.Inc(node, scope)
+	logger.Get().Info("rate_limited",
+		zap.String("node", node),
+		zap.String("action", strategy.Action),
+		zap.String("limit", scope),
+		zap.Duration("reset_in", wait),
+	)
+	return rateLimitCharge{}, &cutter.CutResult{
+		Target:  node,
+		Action:  strategy.Action,
+		Success: false,
+		Outcome: history.OutcomeSkippedRateLimited,
+		Error:   err,
+		Details: map[string]interface{}{
+			"rate_limit":               scope,
+			"rate_limit_reset_seconds": wait.Seconds(),
+		},
+	}
+}
+
+// RateLimitStatus counts a node's cuts within the trailing rate-limit window.
+// WindowStart is the oldest of them and ResetAt when it leaves the window,
+// freeing a slot.
+type RateLimitStatus struct {
+	Node           string     `json:"node"`
+	Action         string     `json:"action,omitempty"`
+	Configured     bool       `json:"configured"`
+	MaxCuts        int        `json:"max_cuts,omitempty"`
+	WindowMinutes  int        `json:"window_minutes,omitempty"`
+	Count          int        `json:"count"`
+	Remaining      int        `json:"remaining"`
+	Exhausted      bool       `json:"exhausted"`
+	WindowStart    *time.Time `json:"window_start,omitempty"`
+	ResetAt        *time.Time `json:"reset_at,omitempty"`
+	ResetInSeconds float64    `json:"reset_in_seconds"`
+	// Actions are the node's strategies with their own rate_limit.
+	Actions []RateLimitStatus `json:"actions,omitempty"`
+}
+
+func (rl *RateLimiter) nodeStatus(node string, nodePolicy *policy.NodePolicy, now time.Time) RateLimitStatus {
+	status := rl.status(node, node, nodePolicy.RateLimit, now)
+	for _, strategy := range nodePolicy.Strategies {
+		if strategy.RateLimit == nil {
+			continue
+		}
+		action := rl.status(node, actionRateLimitKey(node, strategy.Action), strategy.RateLimit, now)
+		action.Action = strategy.Action
+		status.Actions = append(status.Actions, action)
+	}
+	return status
+}
+
+func (rl *RateLimiter) status(node, key string, rateLimit *policy.RateLimit, now time.Time) RateLimitStatus {
+	status := RateLimitStatus{Node: node}
+	if rateLimit == nil || rateLimit.MaxCuts == 0 {
+		return status
+	}
+	status.Configured = true
+	status.MaxCuts = rateLimit.MaxCuts
+	status.WindowMinutes = rateLimit.Window
+	status.Remaining = rateLimit.MaxCuts
+
+	window := time.Duration(rateLimit.Window) * time.Minute
+	rl.mu.Lock()
+	entry := rl.nodeCounts[key]
+	entry.cuts = append([]time.Time(nil), entry.cuts...)
+	rl.mu.Unlock()
+
+	entry.prune(now, window)
+	if len(entry.cuts) == 0 {
+		return status
+	}
+
+	start := entry.cuts[0].UTC()
+	reset := start.Add(window)
+	status.Count = len(entry.cuts)
+	status.Remaining = max(rateLimit.MaxCuts-status.Count, 0)
+	status.Exhausted = status.Remaining == 0
+	status.WindowStart = &start
+	status.ResetAt = &reset
+	status.ResetInSeconds = reset.Sub(now).Seconds()
+	return status
+}
+
+// reset drops the node's entry and those of its per-action limits. It
+// returns how many were dropped.
+func (rl *RateLimiter) reset(node string) int {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	cleared := 0
+	for key := range rl.nodeCounts {
+		if key == node || strings.HasPrefix(key, node+"|") {
+			delete(rl.nodeCounts, key)
+			cleared++
+		}
+	}
+	return cleared
+}
+
+// RateLimitReset says who cleared a node's rate limit and why.
+type RateLimitReset struct {
+	KeyID    string
+	Source   string
+	Reason   string
+	RemoteIP string
+}
+
+// ResetRateLimit clears the node's rate-limit state, per-action limits
+// included, so its next cut is admitted at once. It returns the state before
+// the reset. The reset is logged and saved to history as a record with
+// outcome rate_limit_reset.
+func (e *Executor) ResetRateLimit(node string, by RateLimitReset) (*RateLimitStatus, bool) {
+	nodePolicy, ok := e.lookupNode(e.currentPolicy(), node)
+	if !ok {
+		return nil, false
+	}
+	now := time.Now()
+	previous := e.rateLimiter.nodeStatus(node, nodePolicy, now)
+	cleared := e.rateLimiter.reset(node)
+
+	logger.Get().Info("rate_limit_reset",
+		zap.String("node", node),
+		zap.String("key_id", by.KeyID),
+		zap.String("source", by.Source),
+		zap.String("remote_ip", by.RemoteIP),
+		zap.String("reason", by.Reason),
+		zap.Int("previous_count", previous.Count),
+		zap.Int("cleared", cleared),
+	)
+
+	record := &history.CutRecord{
+		ID:        history.NewCutID(node, now),
+		Node:      node,
+		Entropy:   history.ScheduledEntropy,
+		Timestamp: now.UTC(),
+		Action:    "rate_limit_reset",
+		Success:   true,
+		Outcome:   history.OutcomeRateLimitReset,
+		KeyID:     by.KeyID,
+		Source:    by.Source,
+		Reason:    by.Reason,
+		Details: map[string]interface{}{
+			"previous_count": previous.Count,
+			"remote_ip":      by.RemoteIP,
+		},
+	}
+	if pol := e.currentPolicy(); pol != nil {
+		record.PolicyVersion = pol.Meta.Version
+		record.PolicyHash = pol.Hash()
+	}
+	e.saveRecord(record)
+	return &previous, true
+}
+
+func (e *Executor) RateLimitStatus(node string) (*RateLimitStatus, bool) {
+	nodePolicy, ok := e.lookupNode(e.currentPolicy(), node)
+	if !ok {
+		return nil, false
+	}
+	status := e.rateLimiter.nodeStatus(node, nodePolicy, time.Now())
+	return &status, true
+}
+
+// RateLimitStatuses reports every node in the policy, sorted by name.
+func (e *Executor) RateLimitStatuses() []*RateLimitStatus {
+	pol := e.currentPolicy()
+	names := make([]string, 0, len(pol.Nodes))
+	for name := range pol.Nodes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	now := time.Now()
+	statuses := make([]*RateLimitStatus, 0, len(names))
+	for _, name := range names {
+		if nodePolicy, ok := e.lookupNode(pol, name); ok {
+			status := e.rateLimiter.nodeStatus(name, nodePolicy, now)
+			statuses = append(statuses, &status)
+		}
+	}
+	return statuses
+}
@@ -0,0 +1,168 @@
+package engine
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"atropos/history"
+	"atropos/policy"
+)
+
+const rateLimitPolicy = `
+server:
+  dedup_window_seconds: 0
+nodes:
+  athena:
+    rate_limit:
+      max_cuts: 3
+      window_minutes: 60
+    strategies:
+      - threshold: 0.5
+        action: test_restart
+`
+
+// ageCuts moves the cuts recorded against key d into the past, as if they
+// had been made that much earlier.
+func ageCuts(e *Executor, key string, d time.Duration) {
+	e.rateLimiter.mu.Lock()
+	defer e.rateLimiter.mu.Unlock()
+	entry := e.rateLimiter.nodeCounts[key]
+	for i := range entry.cuts {
+		entry.cuts[i] = entry.cuts[i].Add(-d)
+	}
+	e.rateLimiter.nodeCounts[key] = entry
+}
+
+// cutAdmitted runs a cut on athena and reports whether the rate limit let it
+// through, with the wait it gave if not.
+func cutAdmitted(t *testing.T, e *Executor) (bool, time.Duration) {
+	t.Helper()
+	result := e.ExecuteCut(context.Background(), "athena", 0.9)
+	if result.Outcome != history.OutcomeSkippedRateLimited {
+		if !result.Success {
+			t.Fatalf("cut failed: %v", result.Error)
+		}
+		return true, 0
+	}
+	seconds, _ := result.Details["rate_limit_reset_seconds"].(float64)
+	return false, time.Duration(seconds * float64(time.Second))
+}
+
+// A fixed window would let 3 cuts through just before its end and 3 more
+// just after. The trailing window still counts the first three.
+func TestRateLimitNoBurstAcrossWindowBoundary(t *testing.T) {
+	e, c := newTestExecutor(t, rateLimitPolicy)
+	for i := 0; i < 3; i++ {
+		if ok, _ := cutAdmitted(t, e); !ok {
+			t.Fatalf("cut %d refused", i+1)
+		}
+	}
+
+	// The three are now from 59:50 into the hour; this cut is at 60:00.
+	ageCuts(e, "athena", time.Hour-10*time.Second)
+	ok, wait := cutAdmitted(t, e)
+	if ok {
+		t.Fatal("cut just past a fixed window's end admitted")
+	}
+	if wait <= 0 || wait > 10*time.Second {
+		t.Fatalf("refused with a wait of %s, want at most the 10s until the first cut leaves", wait)
+	}
+	status := e.rateLimiter.status("athena", "athena", &policy.RateLimit{MaxCuts: 3, Window: 60}, time.Now())
+	if status.Count != 3 || !status.Exhausted {
+		t.Fatalf("status = %+v, want 3 of 3", status)
+	}
+
+	// Once they are more than an hour old, all three slots are free again,
+	// and no more.
+	ageCuts(e, "athena", 11*time.Second)
+	for i := 0; i < 3; i++ {
+		if ok, _ := cutAdmitted(t, e); !ok {
+			t.Fatalf("cut %d after the window refused", i+1)
+		}
+	}
+	if ok, _ := cutAdmitted(t, e); ok {
+		t.Fatal("fourth cut in the new window admitted")
+	}
+	if calls := len(c.Calls()); calls != 6 {
+		t.Fatalf("cutter ran %d times, want 6", calls)
+	}
+}
+
+// Slots free up one at a time as each cut leaves the trailing window.
+func TestRateLimitSlidesPerCut(t *testing.T) {
+	e, _ := newTestExecutor(t, rateLimitPolicy)
+	for _, gap := range []time.Duration{40 * time.Minute, 15 * time.Minute, 0} {
+		if ok, _ := cutAdmitted(t, e); !ok {
+			t.Fatal("cut refused under the limit")
+		}
+		ageCuts(e, "athena", gap)
+	}
+
+	// The cuts are 55m, 15m and 0m old: the oldest leaves in 5m.
+	ok, wait := cutAdmitted(t, e)
+	if ok || wait <= 5*time.Minute-time.Second || wait > 5*time.Minute {
+		t.Fatalf("admitted %v, wait %s; want refused for 5m", ok, wait)
+	}
+	ageCuts(e, "athena", 5*time.Minute+time.Second)
+	if ok, _ := cutAdmitted(t, e); !ok {
+		t.Fatal("cut refused after the oldest left the window")
+	}
+	// Now 20m, 5m and 0m old: the next slot is 40m away.
+	ok, wait = cutAdmitted(t, e)
+	if ok || wait <= 40*time.Minute-2*time.Second || wait > 40*time.Minute {
+		t.Fatalf("admitted %v, wait %s; want refused for about 40m", ok, wait)
+	}
+}
+
+// A cut exactly a window old still counts; a nanosecond later it doesn't.
+func TestRateLimitWindowEdge(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	window := time.Hour
+	entry := rateLimitEntry{cuts: []time.Time{
+		now.Add(-window - time.Nanosecond),
+		now.Add(-window),
+		now.Add(-time.Minute),
+	}}
+	entry.prune(now, window)
+	if len(entry.cuts) != 2 || !entry.cuts[0].Equal(now.Add(-window)) {
+		t.Fatalf("after pruning: %v, want the cut exactly a window old and the newer one", entry.cuts)
+	}
+
+	rl := &RateLimiter{nodeCounts: map[string]rateLimitEntry{"athena": {cuts: []time.Time{now.Add(-window), now.Add(-time.Minute)}}}}
+	limit := &policy.RateLimit{MaxCuts: 2, Window: 60}
+	status := rl.status("athena", "athena", limit, now)
+	if status.Count != 2 || !status.Exhausted || status.ResetInSeconds != 0 {
+		t.Fatalf("at the edge: %+v, want 2 of 2 resetting now", status)
+	}
+	status = rl.status("athena", "athena", limit, now.Add(time.Nanosecond))
+	if status.Count != 1 || status.Exhausted || status.ResetInSeconds != (59*time.Minute-time.Nanosecond).Seconds() {
+		t.Fatalf("just past the edge: %+v, want 1 of 2", status)
+	}
+}
+
+// Cuts that left the window are dropped as new ones are checked and
+// counted, so a busy
+// node's entry stays at most max_cuts long.
+func TestRateLimitPrunesOldCuts(t *testing.T) {
+	rl := &RateLimiter{nodeCounts: make(map[string]rateLimitEntry)}
+	limit := &policy.RateLimit{MaxCuts: 5, Window: 1}
+	old := make([]time.Time, 1000)
+	for i := range old {
+		old[i] = time.Now().Add(-2*time.Minute - time.Duration(i)*time.Second)
+	}
+	rl.nodeCounts["athena"] = rateLimitEntry{cuts: old}
+
+	for i := 0; i < 10; i++ {
+		allowed, _, _ := rl.checkRateLimit("athena", limit)
+		if allowed {
+			rl.record("athena", limit)
+		}
+		if allowed != (i < 5) {
+			t.Fatalf("check %d: allowed %v", i+1, allowed)
+		}
+		if n := len(rl.nodeCounts["athena"].cuts); n > limit.MaxCuts {
+			t.Fatalf("check %d: entry holds %d cuts", i+1, n)
+		}
+	}
+}
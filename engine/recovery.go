@@ -0,0 +1,74 @@
+package engine
+
+import (
+	"fmt"
+
+	"go.uber.org/zap"
+
+	"atropos/cutter"
+	"atropos/history"
+	"atropos/internal/logger"
+	"atropos/policy"
+)
+
+// containedBy returns the containment cut a reading under the node's
+// recovery floor should undo: the node's last successful cut, when it was
+// containment rather than an earlier recovery. Noop observations are passed
+// over.
+func (e *Executor) containedBy(nodePolicy *policy.NodePolicy, entropy float64) *history.CutRecord {
+	recovery := nodePolicy.Recovery
+	if recovery == nil || entropy >= recovery.BelowThreshold || e.history == nil {
+		return nil
+	}
+
+	cuts, err := e.history.ListCutsByNode(nodePolicy.Name, 0)
+	if err != nil {
+		logger.Get().Warn("recovery_history_unavailable",
+			zap.String("node", nodePolicy.Name),
+			zap.Error(err),
+		)
+		return nil
+	}
+	for _, cut := range cuts {
+		if !cut.Executed() || !cut.Success || policy.ObserveOnly(cut.Action) {
+			continue
+		}
+		if cut.Trigger != history.TriggerRecovery && recovery.Follows(cut.Action) {
+			return cut
+		}
+		return nil
+	}
+	return nil
+}
+
+// admitRecovery admits the node's recovery action. It counts against the
+// rate limit like any other cut.
+func (e *Executor) admitRecovery(pol *policy.RemediationPolicy, nodePolicy *policy.NodePolicy, entropy float64, contained *history.CutRecord, opts CutOptions) (*cutAttempt, *cutter.CutResult) {
+	node := nodePolicy.Name
+	strategy := nodePolicy.Recovery.Strategy()
+	opts.Trigger = history.TriggerRecovery
+	opts.Source = history.SourceAtropos
+	opts.Reason = fmt.Sprintf("entropy %.2f below recovery floor %.2f after %s (%s)", entropy, strategy.Threshold, contained.Action, contained.ID)
+
+	rateLimit, refused := e.admitRateLimit(node, nodePolicy, strategy)
+	if refused != nil {
+		e.logCut(node, entropy, strategy, refused, opts)
+		return nil, refused
+	}
+
+	logger.Get().Info("recovery_initiated",
+		zap.String("node", node),
+		zap.String("action", strategy.Action),
+		zap.Float64("entropy", entropy),
+		zap.String("contained_cut_id", contained.ID),
+	)
+	return e.admitted(&cutAttempt{
+		policy:     pol,
+		node:       node,
+		entropy:    entropy,
+		nodePolicy: nodePolicy,
+		strategy:   strategy,
+		opts:       opts,
+		rateLimit:  rateLimit,
+	}), nil
+}
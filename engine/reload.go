@@ -0,0 +1,26 @@
+package engine
+
+import (
+	"errors"
+
+	"atropos/policy"
+)
+
+var ErrReloadUnsupported = errors.New("policy reload not configured")
+
+// SetReloader installs the function that re-reads and applies the policy,
+// shared by SIGHUP and the reload endpoint. Call it before serving.
+func (e *Executor) SetReloader(fn func() (*policy.RemediationPolicy, error)) {
+	e.reloader = fn
+}
+
+// ReloadPolicy runs the installed reloader; concurrent reloads run one at a
+// time.
+func (e *Executor) ReloadPolicy() (*policy.RemediationPolicy, error) {
+	if e.reloader == nil {
+		return nil, ErrReloadUnsupported
+	}
+	e.reloadMu.Lock()
+	defer e.reloadMu.Unlock()
+	return e.reloader()
+}
@@ -0,0 +1,142 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"atropos/policy"
+)
+
+func reloadPolicy(action string) string {
+	return fmt.Sprintf(`
+server:
+  dedup_window_seconds: 0
+nodes:
+  web:
+    rate_limit: {max_cuts: 1000, window_minutes: 60}
+    strategies:
+      - threshold: 0.5
+        action: %[1]s
+  db:
+    rate_limit: {max_cuts: 1000, window_minutes: 60}
+    strategies:
+      - threshold: 0.5
+        action: %[1]s
+`, action)
+}
+
+// Run with -race: cuts, reads and reloads all touch the policy at once.
+func TestReloadDuringCuts(t *testing.T) {
+	e, c := newTestExecutor(t, reloadPolicy("test_a"))
+	policies := []*policy.RemediationPolicy{
+		loadTestPolicy(t, reloadPolicy("test_a")),
+		loadTestPolicy(t, reloadPolicy("test_b")),
+	}
+	var reloads int
+	e.SetReloader(func() (*policy.RemediationPolicy, error) {
+		reloads++
+		pol := policies[reloads%2]
+		e.SetPolicy(pol)
+		return pol, nil
+	})
+
+	done := make(chan struct{})
+	var readers sync.WaitGroup
+	readers.Add(2)
+	go func() {
+		defer readers.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			if _, err := e.ReloadPolicy(); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+	go func() {
+		defer readers.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			_ = e.GetPolicy().Nodes["web"].Strategies[0].Action
+			_ = e.NodeStatuses()
+			_ = e.policyActions()
+		}
+	}()
+
+	var cuts sync.WaitGroup
+	for _, node := range []string{"web", "db", "web", "db"} {
+		cuts.Add(1)
+		go func() {
+			defer cuts.Done()
+			for i := 0; i < 50; i++ {
+				if result := e.ExecuteCut(context.Background(), node, 0.9); !result.Success {
+					t.Errorf("cut on %s failed: %s", node, result.Error)
+					return
+				}
+			}
+		}()
+	}
+	cuts.Wait()
+	close(done)
+	readers.Wait()
+
+	calls := c.Calls()
+	if len(calls) != 200 {
+		t.Fatalf("%d cutter calls, want 200", len(calls))
+	}
+	for _, call := range calls {
+		if !strings.HasSuffix(call, ": test_a") && !strings.HasSuffix(call, ": test_b") {
+			t.Fatalf("unexpected call %q", call)
+		}
+	}
+}
+
+func TestInFlightCutKeepsItsPolicy(t *testing.T) {
+	sequence := func(a, b string) string {
+		return `
+nodes:
+  web:
+    strategies:
+      - threshold: 0.5
+        actions:
+          - action: ` + a + `
+          - action: ` + b + `
+`
+	}
+	e, c := newTestExecutor(t, sequence("test_old_1", "test_old_2"))
+	c.block = make(chan struct{})
+
+	resultCh := make(chan bool, 1)
+	go func() {
+		resultCh <- e.ExecuteCut(context.Background(), "web", 0.9).Success
+	}()
+	waitFor(t, "the first step", func() bool { return len(c.Calls()) == 1 })
+
+	e.SetPolicy(loadTestPolicy(t, sequence("test_new_1", "test_new_2")))
+	c.mu.Lock()
+	close(c.block)
+	c.block = nil
+	c.mu.Unlock()
+	if !<-resultCh {
+		t.Fatal("cut failed")
+	}
+
+	if calls := c.Calls(); !reflect.DeepEqual(calls, []string{"web: test_old_1", "web: test_old_2"}) {
+		t.Fatalf("calls = %v, want both steps of the policy the cut started with", calls)
+	}
+	if action := e.GetPolicy().Nodes["web"].Strategies[0].Actions[0].Action; action != "test_new_1" {
+		t.Fatalf("policy after the cut has %s, want the reloaded one", action)
+	}
+}
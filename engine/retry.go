@@ -0,0 +1,62 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"atropos/cutter"
+	"atropos/internal/logger"
+	"atropos/policy"
+)
+
+// executeWithRetries runs the cutter up to strategy.Retries extra times with
+// exponential backoff. All attempts share ctx, so the cut deadline bounds the
+// retries too.
+func (e *Executor) executeWithRetries(ctx context.Context, c cutter.Cutter, node string, strategy *policy.Strategy, params map[string]string) error {
+	var errs []string
+	var lastErr error
+	attempts := strategy.Retries + 1
+
+	for i := 1; i <= attempts; i++ {
+		err := c.Execute(ctx, node, params)
+		if err == nil {
+			if i > 1 {
+				cutter.RecordDetail(ctx, "attempts", i)
+				cutter.RecordDetail(ctx, "attempt_errors", errs)
+			}
+			return nil
+		}
+		errs = append(errs, err.Error())
+		lastErr = err
+
+		if i == attempts {
+			break
+		}
+		backoff := strategy.RetryBackoff(i)
+		logger.Get().Warn("cut_retry",
+			zap.String("node", node),
+			zap.String("action", strategy.Action),
+			zap.Int("attempt", i),
+			zap.Duration("backoff", backoff),
+			zap.Error(err),
+		)
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			cutter.RecordDetail(ctx, "attempts", i)
+			cutter.RecordDetail(ctx, "attempt_errors", errs)
+			return fmt.Errorf("gave up after %d attempts (%v): %w", i, ctx.Err(), err)
+		}
+	}
+
+	if attempts == 1 {
+		return lastErr
+	}
+	cutter.RecordDetail(ctx, "attempts", attempts)
+	cutter.RecordDetail(ctx, "attempt_errors", errs)
+	return fmt.Errorf("failed after %d attempts: %w", attempts, lastErr)
+}
@@ -0,0 +1,119 @@
+package engine
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+
+	"atropos/history"
+	"atropos/internal/logger"
+	"atropos/policy"
+)
+
+// maxScheduleCatchUp bounds how far back the scheduler fires minutes it
+// slept through, e.g. after the host was suspended.
+const maxScheduleCatchUp = 5 * time.Minute
+
+// ScheduleInfo describes one node schedule and its upcoming fire times.
+type ScheduleInfo struct {
+	Node     string      `json:"node"`
+	Name     string      `json:"name,omitempty"`
+	Cron     string      `json:"cron"`
+	Timezone string      `json:"timezone,omitempty"`
+	Action   string      `json:"action"`
+	Next     []time.Time `json:"next"`
+}
+
+// StartScheduler fires node schedules at the top of each matching minute.
+// Schedules are read from the current policy every minute, so reloads take
+// effect without a restart.
+func (e *Executor) StartScheduler() {
+	go func() {
+		last := time.Now().Truncate(time.Minute)
+		for {
+			time.Sleep(time.Until(last.Add(time.Minute)))
+			now := time.Now()
+			for m := last.Add(time.Minute); !m.After(now); m = m.Add(time.Minute) {
+				if now.Sub(m) <= maxScheduleCatchUp {
+					e.fireDue(m)
+				}
+				last = m
+			}
+		}
+	}()
+}
+
+func (e *Executor) fireDue(minute time.Time) {
+	if e.closing.Load() {
+		return
+	}
+	pol := e.currentPolicy()
+	for name, node := range pol.Nodes {
+		for i := range node.Schedules {
+			if sched := &node.Schedules[i]; sched.Due(minute) {
+				go e.runSchedule(name, sched)
+			}
+		}
+	}
+}
+
+// runSchedule fires a scheduled cut. It passes through the same guards as a
+// webhook cut except consecutive triggers.
+func (e *Executor) runSchedule(node string, sched *policy.Schedule) {
+	logger.Get().Info("schedule_fired",
+		zap.String("node", node),
+		zap.String("schedule", sched.Label()),
+		zap.String("action", sched.Action),
+	)
+	result := e.ExecuteCutWith(context.Background(), node, history.ScheduledEntropy, CutOptions{
+		Tags:     map[string]string{"schedule": sched.Label()},
+		Strategy: sched.Strategy(),
+		Trigger:  history.TriggerScheduled,
+		Source:   history.SourceAtropos,
+		Reason:   "schedule " + sched.Label(),
+	})
+	if !result.Success {
+		logger.Get().Warn("scheduled_cut_failed",
+			zap.String("node", node),
+			zap.String("schedule", sched.Label()),
+			zap.String("cut_id", result.CutID),
+			zap.Error(result.Error),
+		)
+	}
+}
+
+// Schedules lists every node schedule in the policy with its next count fire
+// times, sorted by node.
+func (e *Executor) Schedules(now time.Time, count int) []ScheduleInfo {
+	pol := e.currentPolicy()
+	names := make([]string, 0, len(pol.Nodes))
+	for name := range pol.Nodes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	infos := []ScheduleInfo{}
+	for _, name := range names {
+		for i := range pol.Nodes[name].Schedules {
+			sched := &pol.Nodes[name].Schedules[i]
+			info := ScheduleInfo{
+				Node:     name,
+				Name:     sched.Name,
+				Cron:     sched.Cron,
+				Timezone: sched.Timezone,
+				Action:   sched.Action,
+				Next:     []time.Time{},
+			}
+			for t := now; len(info.Next) < count; {
+				if t = sched.Next(t); t.IsZero() {
+					break
+				}
+				info.Next = append(info.Next, t)
+			}
+			infos = append(infos, info)
+		}
+	}
+	return infos
+}
@@ -0,0 +1,157 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"atropos/history"
+	"atropos/internal/logger"
+	"atropos/internal/timefmt"
+	"atropos/notifications"
+)
+
+var ErrShuttingDown = errors.New("shutting down")
+
+// ShutdownReport summarises the run that is ending.
+type ShutdownReport struct {
+	Reason             string         `json:"reason"`
+	StartedAt          time.Time      `json:"started_at"`
+	StoppedAt          time.Time      `json:"stopped_at"`
+	UptimeSeconds      float64        `json:"uptime_seconds"`
+	UptimeHuman        string         `json:"uptime_human"`
+	Cuts               int            `json:"cuts"`
+	CutsByOutcome      map[string]int `json:"cuts_by_outcome"`
+	ApprovalsAbandoned int            `json:"approvals_abandoned"`
+	JobsAbandoned      int            `json:"jobs_abandoned,omitempty"`
+	NotificationQueue  int            `json:"notification_queue"`
+	CallbackQueue      int            `json:"callback_queue"`
+	// Drained is false when cuts were still running at the deadline.
+	Drained    bool   `json:"drained"`
+	FlushError string `json:"flush_error,omitempty"`
+}
+
+// runStats counts the records stored since startup.
+type runStats struct {
+	started  time.Time
+	outcomes map[string]int
+	total    int
+	mu       sync.Mutex
+}
+
+func newRunStats() *runStats {
+	return &runStats{started: time.Now().UTC(), outcomes: make(map[string]int)}
+}
+
+func (s *runStats) add(record *history.CutRecord) {
+	outcome := record.Outcome
+	if outcome == "" {
+		outcome = history.RanOutcome(record.Success)
+	}
+	s.mu.Lock()
+	s.outcomes[outcome]++
+	s.total++
+	s.mu.Unlock()
+}
+
+func (s *runStats) snapshot() (int, map[string]int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	outcomes := make(map[string]int, len(s.outcomes))
+	for k, v := range s.outcomes {
+		outcomes[k] = v
+	}
+	return s.total, outcomes
+}
+
+// Shutdown stops taking cuts, waits until ctx is done for the running cuts
+// and queued notifications and callbacks, flushes history to disk, and
+// reports on the run. Cuts requested afterwards fail with ErrShuttingDown.
+func (e *Executor) Shutdown(ctx context.Context, reason string) *ShutdownReport {
+	e.closing.Store(true)
+
+	jobs := 0
+	if e.pool != nil {
+		jobs = len(e.pool.queue)
+	}
+	drained := e.waitIdle(ctx)
+	e.waitQueues(ctx)
+
+	var flushErr error
+	if e.history != nil {
+		flushErr = e.history.Sync()
+	}
+
+	now := time.Now().UTC()
+	total, outcomes := e.run.snapshot()
+	report := &ShutdownReport{
+		Reason:            reason,
+		StartedAt:         e.run.started,
+		StoppedAt:         now,
+		UptimeSeconds:     now.Sub(e.run.started).Seconds(),
+		UptimeHuman:       timefmt.Human(now.Sub(e.run.started)),
+		Cuts:              total,
+		CutsByOutcome:     outcomes,
+		JobsAbandoned:     jobs,
+		NotificationQueue: len(e.notifyQueue),
+		CallbackQueue:     len(e.callbackQueue),
+		Drained:           drained,
+	}
+	if e.history != nil {
+		report.ApprovalsAbandoned = len(e.history.Approvals().List())
+	}
+	if flushErr != nil {
+		report.FlushError = flushErr.Error()
+		logger.Get().Error("history_flush_failed", zap.Error(flushErr))
+	}
+
+	if notifier := e.notifications.Load(); notifier.NotifyOnShutdown() {
+		e.sendNotification(&notifications.CutEvent{
+			Action:    "shutdown",
+			Outcome:   "shutdown",
+			Success:   drained && flushErr == nil,
+			Error:     reason,
+			Timestamp: now,
+			Metadata: map[string]interface{}{
+				"uptime_human":        report.UptimeHuman,
+				"cuts":                report.Cuts,
+				"cuts_by_outcome":     report.CutsByOutcome,
+				"approvals_abandoned": report.ApprovalsAbandoned,
+				"notification_queue":  report.NotificationQueue,
+				"drained":             report.Drained,
+			},
+		})
+	}
+	return report
+}
+
+// waitIdle waits for the running cuts and approvals to finish, and for those
+// waiting on a node to see the executor closing. Once none is active,
+// nothing is half-recorded.
+func (e *Executor) waitIdle(ctx context.Context) bool {
+	ticker := time.NewTicker(20 * time.Millisecond)
+	defer ticker.Stop()
+	for e.active.Load() > 0 {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return false
+		}
+	}
+	return true
+}
+
+func (e *Executor) waitQueues(ctx context.Context) {
+	ticker := time.NewTicker(20 * time.Millisecond)
+	defer ticker.Stop()
+	for len(e.notifyQueue) > 0 || len(e.callbackQueue) > 0 {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
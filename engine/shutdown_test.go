@@ -0,0 +1,140 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"atropos/cutter"
+	"atropos/history"
+	"atropos/notifications"
+)
+
+const shutdownPolicy = `
+server:
+  dedup_window_seconds: 0
+nodes:
+  athena:
+    strategies:
+      - threshold: 0.5
+        action: test_restart
+  db:
+    strategies:
+      - threshold: 0.5
+        action: test_failover
+        approval_required: true
+`
+
+// newShutdownExecutor is newTestExecutor with the history directory
+// returned, so the test can read back what reached the disk.
+func newShutdownExecutor(t *testing.T) (*Executor, *testCutter, string) {
+	t.Helper()
+	historyDir := filepath.Join(t.TempDir(), "history")
+	notif := notifications.NewNotificationManager(&notifications.NotificationConfig{
+		StateFile: filepath.Join(historyDir, "notification_state.json"),
+	})
+	e := NewExecutor(loadTestPolicy(t, shutdownPolicy), history.NewHistoryManager(historyDir), notif)
+	c := &testCutter{fail: make(map[string]error), block: make(chan struct{})}
+	e.RegisterCutter(c)
+	return e, c, historyDir
+}
+
+// startCut runs a cut in the background and returns its result channel.
+func startCut(e *Executor, node string) <-chan *cutter.CutResult {
+	done := make(chan *cutter.CutResult, 1)
+	go func() { done <- e.ExecuteCut(context.Background(), node, 0.9) }()
+	return done
+}
+
+func TestShutdownDuringCut(t *testing.T) {
+	e, c, historyDir := newShutdownExecutor(t)
+	if result := e.ExecuteCut(context.Background(), "db", 0.9); result.Outcome != history.OutcomePendingApproval {
+		t.Fatalf("db outcome = %q, want pending_approval", result.Outcome)
+	}
+
+	running := startCut(e, "athena")
+	waitFor(t, "the cut to reach the cutter", func() bool { return len(c.Calls()) == 1 })
+	// Requested before the shutdown, but still waiting for the running cut.
+	queued := startCut(e, "athena")
+
+	reports := make(chan *ShutdownReport, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		reports <- e.Shutdown(ctx, "SIGTERM")
+	}()
+	select {
+	case report := <-reports:
+		t.Fatalf("Shutdown returned while a cut was running: %+v", report)
+	case <-time.After(100 * time.Millisecond):
+	}
+	late := startCut(e, "athena")
+
+	close(c.block)
+	if result := <-running; !result.Success {
+		t.Fatalf("running cut: %v", result.Error)
+	}
+	for name, done := range map[string]<-chan *cutter.CutResult{"queued": queued, "late": late} {
+		if result := <-done; !errors.Is(result.Error, ErrShuttingDown) {
+			t.Fatalf("%s cut: %v, want ErrShuttingDown", name, result.Error)
+		}
+	}
+	report := <-reports
+
+	if !report.Drained || report.Reason != "SIGTERM" || report.FlushError != "" {
+		t.Fatalf("report = %+v, want drained after SIGTERM", report)
+	}
+	if report.Cuts != 2 || report.CutsByOutcome[history.OutcomeExecuted] != 1 || report.CutsByOutcome[history.OutcomePendingApproval] != 1 {
+		t.Fatalf("report counts %d cuts, %v; want the executed cut and the pending one", report.Cuts, report.CutsByOutcome)
+	}
+	if report.ApprovalsAbandoned != 1 {
+		t.Fatalf("%d approvals abandoned, want 1", report.ApprovalsAbandoned)
+	}
+	if report.StoppedAt.Before(report.StartedAt) || report.UptimeSeconds <= 0 {
+		t.Fatalf("report runs from %s to %s", report.StartedAt, report.StoppedAt)
+	}
+	if calls := c.Calls(); len(calls) != 1 {
+		t.Fatalf("cutter calls %q, want only the cut that was running", calls)
+	}
+
+	// Everything counted reached the disk.
+	cuts, err := history.NewHistoryManager(historyDir).ListCuts(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	outcomes := make(map[string]int)
+	for _, cut := range cuts {
+		outcomes[cut.Outcome]++
+	}
+	if len(cuts) != report.Cuts || outcomes[history.OutcomeExecuted] != 1 || outcomes[history.OutcomePendingApproval] != 1 {
+		t.Fatalf("history on disk has %d cuts, %v; want what the report counted", len(cuts), outcomes)
+	}
+}
+
+func TestShutdownDeadlineWithCutRunning(t *testing.T) {
+	e, c, historyDir := newShutdownExecutor(t)
+	running := startCut(e, "athena")
+	waitFor(t, "the cut to reach the cutter", func() bool { return len(c.Calls()) == 1 })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	report := e.Shutdown(ctx, "deadline")
+	if report.Drained || report.Cuts != 0 {
+		t.Fatalf("report = %+v, want undrained with nothing recorded", report)
+	}
+
+	// The cut that outlived the deadline is still recorded when it ends.
+	close(c.block)
+	if result := <-running; !result.Success {
+		t.Fatalf("running cut: %v", result.Error)
+	}
+	cuts, err := history.NewHistoryManager(historyDir).ListCuts(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cuts) != 1 || cuts[0].Outcome != history.OutcomeExecuted {
+		t.Fatalf("history on disk = %d cuts, want the late one", len(cuts))
+	}
+}
@@ -0,0 +1,28 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"atropos/cutter"
+	"atropos/policy"
+)
+
+// Snapshots lists the VirtualBox snapshots of the node's VM. It uses the
+// params of the node's first vbox_ strategy, so vm_name and
+// vboxmanage_path resolve as they would for a cut.
+func (e *Executor) Snapshots(ctx context.Context, node string) ([]string, error) {
+	nodePolicy, ok := e.currentPolicy().GetNode(node)
+	if !ok {
+		return nil, fmt.Errorf("unknown node: %s", node)
+	}
+	strategy := &policy.Strategy{}
+	for i := range nodePolicy.Strategies {
+		if strings.HasPrefix(nodePolicy.Strategies[i].Action, "vbox_") {
+			strategy = &nodePolicy.Strategies[i]
+			break
+		}
+	}
+	return cutter.ListVBoxSnapshots(ctx, node, buildParams(node, nodePolicy, strategy))
+}
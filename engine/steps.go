@@ -0,0 +1,140 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"atropos/cutter"
+	"atropos/history"
+	"atropos/internal/logger"
+	"atropos/policy"
+)
+
+// actionStep is one action of a strategy with the cutter that runs it.
+type actionStep struct {
+	strategy *policy.Strategy
+	cutter   cutter.Cutter
+	params   map[string]string
+}
+
+// resolveSteps finds a cutter for every action the strategy runs, so a
+// sequence with an unknown action fails before its first step.
+func (e *Executor) resolveSteps(node string, nodePolicy *policy.NodePolicy, strategy *policy.Strategy) ([]actionStep, error) {
+	strategies := []*policy.Strategy{strategy}
+	if len(strategy.Actions) > 0 {
+		strategies = make([]*policy.Strategy, len(strategy.Actions))
+		for i := range strategy.Actions {
+			strategies[i] = strategy.Actions[i].Strategy(strategy)
+		}
+	}
+
+	steps := make([]actionStep, len(strategies))
+	for i, s := range strategies {
+		c, err := e.findCutter(s)
+		if err != nil {
+			return nil, err
+		}
+		steps[i] = actionStep{strategy: s, cutter: c, params: e.cutterParams(c, buildParams(node, nodePolicy, s))}
+		e.addRemovedDNSRecords(node, steps[i].params)
+	}
+	return steps, nil
+}
+
+// addCutContext gives http_call steps the cut's ID and entropy for their
+// request body. The ID is fixed now, so the record takes the same one.
+func addCutContext(attempt *cutAttempt, steps []actionStep) {
+	for _, step := range steps {
+		if step.strategy.Action != policy.ActionHTTPCall {
+			continue
+		}
+		if attempt.opts.CutID == "" {
+			attempt.opts.CutID = history.NewCutID(attempt.node, time.Now().UTC())
+		}
+		step.params["cut_id"] = attempt.opts.CutID
+		step.params["entropy"] = strconv.FormatFloat(attempt.entropy, 'f', -1, 64)
+	}
+}
+
+// findCutter returns the cutter a strategy names, or else the first that
+// handles its action.
+func (e *Executor) findCutter(s *policy.Strategy) (cutter.Cutter, error) {
+	if s.Cutter != "" {
+		c, ok := e.registry.Get(s.Cutter)
+		if !ok {
+			return nil, fmt.Errorf("cutter %s is not registered; restart to apply the cutters section", s.Cutter)
+		}
+		return c, nil
+	}
+	c, ok := e.registry.FindCutter(s.Action)
+	if !ok {
+		return nil, fmt.Errorf("no cutter for action: %s", s.Action)
+	}
+	return c, nil
+}
+
+// cutterParams fills in the cutter's defaults from the cutters section
+// where params leave them unset.
+func (e *Executor) cutterParams(c cutter.Cutter, params map[string]string) map[string]string {
+	for key, value := range e.currentPolicy().CutterParams(c.Name()) {
+		if params[key] == "" {
+			params[key] = value
+		}
+	}
+	return params
+}
+
+func stepCutters(steps []actionStep) string {
+	names := make([]string, len(steps))
+	for i, step := range steps {
+		names[i] = step.cutter.Name()
+	}
+	return strings.Join(names, "+")
+}
+
+// runSteps runs a strategy's actions in order, each with an even share of
+// what is left of ctx's deadline, and records each on the attempt. It stops at the first failure unless the
+// strategy has continue_on_error, and returns the summed step latency and
+// the first failure.
+func (e *Executor) runSteps(ctx context.Context, attempt *cutAttempt, steps []actionStep) (int64, error) {
+	var (
+		latency  int64
+		firstErr error
+	)
+	for i, step := range steps {
+		stepCtx, cancel := cutter.StepContext(ctx, len(steps)-i)
+		stepCtx, details := cutter.WithDetails(stepCtx)
+		start := time.Now()
+		err := e.executeWithRetries(stepCtx, step.cutter, attempt.node, step.strategy, step.params)
+		cancel()
+
+		result := history.StepResult{
+			Action:    step.strategy.Action,
+			Success:   err == nil,
+			LatencyMs: time.Since(start).Milliseconds(),
+		}
+		result.Details, result.Output = cutDetails(details, step.params)
+		latency += result.LatencyMs
+		if err != nil {
+			result.Error = secretReplacer(step.params).Replace(err.Error())
+			logger.Get().Warn("step_failed",
+				zap.String("node", attempt.node),
+				zap.String("action", step.strategy.Action),
+				zap.Int("step", i+1),
+				zap.Error(err),
+			)
+			if firstErr == nil {
+				firstErr = fmt.Errorf("step %d %s: %w", i+1, step.strategy.Action, err)
+			}
+		}
+		attempt.steps = append(attempt.steps, result)
+		if err != nil && !attempt.strategy.ContinueOnError {
+			break
+		}
+	}
+	return latency, firstErr
+}
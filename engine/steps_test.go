@@ -0,0 +1,74 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+const timeoutPolicy = `
+server:
+  dedup_window_seconds: 0
+nodes:
+  athena:
+    strategies:
+      - threshold: 0.5
+        action: test_restart
+        timeout_seconds: 1
+        retries: 3
+        retry_backoff_seconds: 0.01
+  borg:
+    strategies:
+      - threshold: 0.5
+        timeout_seconds: 1
+        continue_on_error: true
+        actions:
+          - action: test_stop
+          - action: test_start
+`
+
+// Retries share the strategy's timeout rather than each getting their own.
+func TestStrategyTimeoutBoundsRetries(t *testing.T) {
+	e, c := newTestExecutor(t, timeoutPolicy)
+	c.block = make(chan struct{})
+
+	start := time.Now()
+	result := e.ExecuteCut(context.Background(), "athena", 0.9)
+	if elapsed := time.Since(start); elapsed > 1500*time.Millisecond {
+		t.Fatalf("cut took %s with a 1s timeout", elapsed)
+	}
+	if result.Success || !errors.Is(result.Error, context.DeadlineExceeded) {
+		t.Fatalf("result error %v, want the deadline", result.Error)
+	}
+}
+
+// A sequence's steps split its timeout, so the whole sequence ends within it.
+func TestSequenceSplitsStrategyTimeout(t *testing.T) {
+	e, c := newTestExecutor(t, timeoutPolicy)
+	c.block = make(chan struct{})
+
+	start := time.Now()
+	result := e.ExecuteCut(context.Background(), "borg", 0.9)
+	elapsed := time.Since(start)
+	if elapsed > 1500*time.Millisecond {
+		t.Fatalf("sequence took %s with a 1s timeout", elapsed)
+	}
+	if result.Success || len(c.Calls()) != 2 {
+		t.Fatalf("success %v after %d steps, want both steps to time out", result.Success, len(c.Calls()))
+	}
+	cuts, err := e.history.ListCuts(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	steps := cuts[0].Steps
+	if len(steps) != 2 {
+		t.Fatalf("recorded %d steps, want 2", len(steps))
+	}
+	// The first step gets half the timeout, the second what is left.
+	for i, step := range steps {
+		if step.LatencyMs < 400 || step.LatencyMs > 700 {
+			t.Fatalf("step %d ran %dms, want about half of 1s", i+1, step.LatencyMs)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package engine
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"atropos/policy"
+)
+
+type TriggerCounter struct {
+	nodeCounts map[string]map[string]int
+	lastSeen   map[string]time.Time
+	mu         sync.Mutex
+}
+
+type TriggerState struct {
+	Action    string  `json:"action"`
+	Threshold float64 `json:"threshold"`
+	Count     int     `json:"count"`
+	Required  int     `json:"required"`
+}
+
+func NewTriggerCounter() *TriggerCounter {
+	return &TriggerCounter{
+		nodeCounts: make(map[string]map[string]int),
+		lastSeen:   make(map[string]time.Time),
+	}
+}
+
+func triggerKey(strategy *policy.Strategy) string {
+	return fmt.Sprintf("%s@%g", strategy.Action, strategy.Threshold)
+}
+
+// observe records a reading for every strategy of the node and returns the
+// highest strategy whose consecutive count has reached its requirement. When
+// the reading crosses a threshold but no strategy is ready yet, the highest
+// crossed strategy is returned as pending together with its current count.
+func (tc *TriggerCounter) observe(nodePolicy *policy.NodePolicy, entropy float64) (ready *policy.Strategy, pending *policy.Strategy, count int) {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+
+	counts, ok := tc.nodeCounts[nodePolicy.Name]
+	if !ok {
+		counts = make(map[string]int)
+		tc.nodeCounts[nodePolicy.Name] = counts
+	}
+	tc.lastSeen[nodePolicy.Name] = time.Now()
+
+	for i := range nodePolicy.Strategies {
+		strategy := &nodePolicy.Strategies[i]
+		key := triggerKey(strategy)
+		if entropy >= strategy.Threshold {
+			counts[key]++
+		} else {
+			delete(counts, key)
+		}
+	}
+
+	for i := range nodePolicy.Strategies {
+		strategy := &nodePolicy.Strategies[i]
+		if entropy < strategy.Threshold {
+			continue
+		}
+		key := triggerKey(strategy)
+		if counts[key] >= strategy.RequiredTriggers() {
+			delete(counts, key)
+			return strategy, nil, 0
+		}
+		if pending == nil {
+			pending = strategy
+			count = counts[key]
+		}
+	}
+
+	if len(counts) == 0 {
+		delete(tc.nodeCounts, nodePolicy.Name)
+		delete(tc.lastSeen, nodePolicy.Name)
+	}
+
+	return nil, pending, count
+}
+
+func (tc *TriggerCounter) State(nodePolicy *policy.NodePolicy) []TriggerState {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+
+	counts := tc.nodeCounts[nodePolicy.Name]
+	states := make([]TriggerState, 0, len(nodePolicy.Strategies))
+	for i := range nodePolicy.Strategies {
+		strategy := &nodePolicy.Strategies[i]
+		states = append(states, TriggerState{
+			Action:    strategy.Action,
+			Threshold: strategy.Threshold,
+			Count:     counts[triggerKey(strategy)],
+			Required:  strategy.RequiredTriggers(),
+		})
+	}
+	return states
+}
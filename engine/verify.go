@@ -0,0 +1,120 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+
+	"atropos/history"
+	"atropos/internal/httpclient"
+	"atropos/internal/logger"
+	"atropos/policy"
+)
+
+var ErrVerificationFailed = errors.New("verification failed")
+
+// verifyCut waits delay_seconds, then probes until one attempt passes or the
+// attempts run out. The result is stored on the attempt; a failure comes back
+// wrapping ErrVerificationFailed.
+func (e *Executor) verifyCut(ctx context.Context, attempt *cutAttempt) error {
+	v := attempt.strategy.Verify
+	start := time.Now()
+	result := &history.Verification{Type: v.Type}
+	attempt.verification = result
+
+	var err error
+	wait := time.Duration(v.DelaySeconds) * time.Second
+	for result.Attempts < v.GetAttempts() {
+		if wait > 0 {
+			select {
+			case <-time.After(wait):
+			case <-ctx.Done():
+			}
+			if err = ctx.Err(); err != nil {
+				break
+			}
+		}
+		result.Attempts++
+		if err = e.probe(ctx, attempt, v); err == nil {
+			break
+		}
+		wait = v.GetInterval()
+	}
+
+	result.DurationMs = time.Since(start).Milliseconds()
+	if err != nil {
+		result.Error = err.Error()
+		logger.Get().Warn("verification_failed",
+			zap.String("node", attempt.node),
+			zap.String("action", attempt.strategy.Action),
+			zap.String("type", v.Type),
+			zap.Int("attempts", result.Attempts),
+			zap.Error(err),
+		)
+		return fmt.Errorf("%w: %s probe: %v", ErrVerificationFailed, v.Type, err)
+	}
+	result.Success = true
+	logger.Get().Info("verification_passed",
+		zap.String("node", attempt.node),
+		zap.String("type", v.Type),
+		zap.Int("attempts", result.Attempts),
+		zap.Int64("duration_ms", result.DurationMs),
+	)
+	return nil
+}
+
+func (e *Executor) probe(ctx context.Context, attempt *cutAttempt, v *policy.Verify) error {
+	probeCtx, cancel := context.WithTimeout(ctx, v.GetTimeout())
+	defer cancel()
+
+	switch v.Type {
+	case policy.VerifyTCP:
+		addr := v.Address
+		if addr == "" {
+			if attempt.nodePolicy.Host == "" {
+				return fmt.Errorf("tcp probe needs address or a node host")
+			}
+			addr = net.JoinHostPort(attempt.nodePolicy.Host, strconv.Itoa(v.Port))
+		}
+		var d net.Dialer
+		conn, err := d.DialContext(probeCtx, "tcp", addr)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+
+	case policy.VerifyHTTP:
+		client, err := httpclient.New(v.GetTimeout(), "")
+		if err != nil {
+			return err
+		}
+		req, err := http.NewRequestWithContext(probeCtx, http.MethodGet, v.URL, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode != v.GetExpectStatus() {
+			return fmt.Errorf("got status %d, want %d", resp.StatusCode, v.GetExpectStatus())
+		}
+		return nil
+
+	case policy.VerifySSH:
+		strategy := &policy.Strategy{Action: "ssh_verify", Command: v.Command}
+		c, ok := e.registry.FindCutter(strategy.Action)
+		if !ok {
+			return fmt.Errorf("no cutter for action: %s", strategy.Action)
+		}
+		return c.Execute(probeCtx, attempt.node, e.cutterParams(c, buildParams(attempt.node, attempt.nodePolicy, strategy)))
+	}
+	return fmt.Errorf("unknown verify type %q", v.Type)
+}
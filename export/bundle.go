@@ -0,0 +1,138 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+const (
+	BundleFormat    = "atropos-export-v1"
+	SignatureMethod = "hmac-sha256"
+
+	manifestFile  = "manifest.json"
+	signatureFile = "manifest.sig"
+)
+
+type Manifest struct {
+	Format        string            `json:"format"`
+	DataFile      string            `json:"data_file"`
+	DataSHA256    string            `json:"data_sha256"`
+	RecordCount   int               `json:"record_count"`
+	Filters       map[string]string `json:"filters,omitempty"`
+	ExportedAt    time.Time         `json:"exported_at"`
+	PolicyVersion string            `json:"policy_version,omitempty"`
+	PolicyHash    string            `json:"policy_hash,omitempty"`
+	Signature     string            `json:"signature_method"`
+}
+
+// WriteBundle zips the data file with a manifest and an HMAC over the
+// manifest. The manifest carries the data digest, so the signature covers both.
+func WriteBundle(w io.Writer, data []byte, manifest Manifest, key []byte) error {
+	if len(key) == 0 {
+		return fmt.Errorf("signing key is empty")
+	}
+
+	sum := sha256.Sum256(data)
+	manifest.Format = BundleFormat
+	manifest.DataSHA256 = hex.EncodeToString(sum[:])
+	manifest.Signature = SignatureMethod
+	manifest.ExportedAt = manifest.ExportedAt.UTC()
+
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode manifest: %w", err)
+	}
+
+	zw := zip.NewWriter(w)
+	files := []struct {
+		name string
+		body []byte
+	}{
+		{manifest.DataFile, data},
+		{manifestFile, manifestData},
+		{signatureFile, []byte(sign(manifestData, key) + "\n")},
+	}
+	for _, f := range files {
+		fw, err := zw.CreateHeader(&zip.FileHeader{
+			Name:     f.name,
+			Method:   zip.Deflate,
+			Modified: manifest.ExportedAt,
+		})
+		if err != nil {
+			return fmt.Errorf("add %s: %w", f.name, err)
+		}
+		if _, err := fw.Write(f.body); err != nil {
+			return fmt.Errorf("write %s: %w", f.name, err)
+		}
+	}
+	return zw.Close()
+}
+
+// VerifyBundle checks the manifest signature and the data digest and returns
+// the manifest when both hold.
+func VerifyBundle(r io.ReaderAt, size int64, key []byte) (*Manifest, error) {
+	if len(key) == 0 {
+		return nil, fmt.Errorf("signing key is empty")
+	}
+
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("open bundle: %w", err)
+	}
+
+	manifestData, err := readEntry(zr, manifestFile)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := readEntry(zr, signatureFile)
+	if err != nil {
+		return nil, err
+	}
+	if !hmac.Equal([]byte(sign(manifestData, key)), bytes.TrimSpace(sig)) {
+		return nil, fmt.Errorf("manifest signature does not match")
+	}
+
+	var manifest Manifest
+	if err := json.Unmarshal(manifestData, &manifest); err != nil {
+		return nil, fmt.Errorf("decode manifest: %w", err)
+	}
+	if manifest.Format != BundleFormat {
+		return nil, fmt.Errorf("unsupported bundle format %q", manifest.Format)
+	}
+
+	data, err := readEntry(zr, manifest.DataFile)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	if hex.EncodeToString(sum[:]) != manifest.DataSHA256 {
+		return nil, fmt.Errorf("%s does not match manifest digest", manifest.DataFile)
+	}
+	return &manifest, nil
+}
+
+func sign(data, key []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func readEntry(zr *zip.Reader, name string) ([]byte, error) {
+	f, err := zr.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("bundle is missing %s", name)
+	}
+	defer f.Close()
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", name, err)
+	}
+	return data, nil
+}
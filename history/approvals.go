@@ -0,0 +1,170 @@
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	ApprovalPending  = "pending"
+	ApprovalApproved = "approved"
+	ApprovalRejected = "rejected"
+	ApprovalExpired  = "expired"
+)
+
+type PendingCut struct {
+	ID        string            `json:"id"`
+	Node      string            `json:"node"`
+	Entropy   float64           `json:"entropy"`
+	Action    string            `json:"action"`
+	Threshold float64           `json:"threshold"`
+	CutID     string            `json:"cut_id"`
+	Tags      map[string]string `json:"tags,omitempty"`
+	Source    string            `json:"source,omitempty"`
+	Reason    string            `json:"reason,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+	ExpiresAt time.Time         `json:"expires_at"`
+}
+
+type Approval struct {
+	ID        string    `json:"id"`
+	Status    string    `json:"status"`
+	DecidedBy string    `json:"decided_by,omitempty"`
+	DecidedAt time.Time `json:"decided_at,omitempty"`
+	Reason    string    `json:"reason,omitempty"`
+}
+
+type ApprovalStore struct {
+	path    string
+	pending map[string]*PendingCut
+	mu      sync.Mutex
+}
+
+func NewApprovalStore(dir string) (*ApprovalStore, error) {
+	store := &ApprovalStore{
+		path:    filepath.Join(dir, "approvals.json"),
+		pending: make(map[string]*PendingCut),
+	}
+
+	data, err := os.ReadFile(store.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return store, nil
+		}
+		return nil, fmt.Errorf("read approvals: %w", err)
+	}
+
+	var pending []*PendingCut
+	if err := json.Unmarshal(data, &pending); err != nil {
+		return nil, fmt.Errorf("parse approvals: %w", err)
+	}
+	for _, p := range pending {
+		store.pending[p.ID] = p
+	}
+
+	return store, nil
+}
+
+func (s *ApprovalStore) Enqueue(cut *PendingCut) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.pending[cut.ID]; exists {
+		return fmt.Errorf("approval %s already queued", cut.ID)
+	}
+	s.pending[cut.ID] = cut
+	return s.persist()
+}
+
+// Take removes a pending cut from the queue so exactly one caller can act on
+// it, whether that is an operator decision or the expiry sweep.
+func (s *ApprovalStore) Take(id string) (*PendingCut, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cut, ok := s.pending[id]
+	if !ok {
+		return nil, fmt.Errorf("approval %s not found", id)
+	}
+	delete(s.pending, id)
+	if err := s.persist(); err != nil {
+		s.pending[id] = cut
+		return nil, err
+	}
+	return cut, nil
+}
+
+// Get returns a pending cut without taking it.
+func (s *ApprovalStore) Get(id string) (*PendingCut, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cut, ok := s.pending[id]
+	if !ok {
+		return nil, fmt.Errorf("approval %s not found", id)
+	}
+	return cut, nil
+}
+
+func (s *ApprovalStore) TakeExpired(now time.Time) ([]*PendingCut, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var expired []*PendingCut
+	for id, cut := range s.pending {
+		if !now.Before(cut.ExpiresAt) {
+			expired = append(expired, cut)
+			delete(s.pending, id)
+		}
+	}
+	if len(expired) == 0 {
+		return nil, nil
+	}
+
+	if err := s.persist(); err != nil {
+		for _, cut := range expired {
+			s.pending[cut.ID] = cut
+		}
+		return nil, err
+	}
+	return expired, nil
+}
+
+func (s *ApprovalStore) List() []*PendingCut {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pending := make([]*PendingCut, 0, len(s.pending))
+	for _, cut := range s.pending {
+		pending = append(pending, cut)
+	}
+
+	sort.Slice(pending, func(i, j int) bool {
+		return pending[i].CreatedAt.Before(pending[j].CreatedAt)
+	})
+
+	return pending
+}
+
+func (s *ApprovalStore) persist() error {
+	pending := make([]*PendingCut, 0, len(s.pending))
+	for _, cut := range s.pending {
+		pending = append(pending, cut)
+	}
+
+	data, err := json.MarshalIndent(pending, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode approvals: %w", err)
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("write approvals: %w", err)
+	}
+	return os.Rename(tmp, s.path)
+}
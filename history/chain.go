@@ -0,0 +1,31 @@
+package history
+
+import "sort"
+
+// LoadChain returns every record of the fallback/escalation chain the cut
+// belongs to, in execution order. A cut outside any chain comes back alone.
+func (h *HistoryManager) LoadChain(id string) ([]*CutRecord, error) {
+	cut, err := h.LoadCut(id)
+	if err != nil {
+		return nil, err
+	}
+	root := cut.ChainID
+	if root == "" {
+		root = cut.ID
+	}
+
+	cuts, err := h.ListCutsByNode(cut.Node, 0)
+	if err != nil {
+		return nil, err
+	}
+	var chain []*CutRecord
+	for _, c := range cuts {
+		if c.ID == root || c.ChainID == root {
+			chain = append(chain, c)
+		}
+	}
+	sort.SliceStable(chain, func(i, j int) bool {
+		return chain[i].ChainStep < chain[j].ChainStep
+	})
+	return chain, nil
+}
@@ -0,0 +1,84 @@
+package history
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	actionNoop       = "noop"
+	actionNotifyOnly = "notify_only"
+)
+
+type Filter struct {
+	IncludeImported bool
+	IncludeDryRun   bool
+	Labels          map[string]string
+	Tags            map[string]string
+	Since           time.Time
+	ExcludeNoop     bool
+	// IncludeSkipped keeps cuts refused before they ran (skipped_* outcomes).
+	IncludeSkipped bool
+	// Outcome, when set, keeps only records with exactly this outcome.
+	Outcome string
+}
+
+func (f Filter) Match(record *CutRecord) bool {
+	if !f.IncludeImported && record.Trigger == TriggerImported {
+		return false
+	}
+	if !f.IncludeDryRun && record.DryRun {
+		return false
+	}
+	if f.ExcludeNoop && (record.Action == actionNoop || record.Action == actionNotifyOnly) {
+		return false
+	}
+	if !f.IncludeSkipped && record.Skipped() {
+		return false
+	}
+	if f.Outcome != "" && record.Outcome != f.Outcome {
+		return false
+	}
+	if !f.Since.IsZero() && record.Timestamp.Before(f.Since) {
+		return false
+	}
+	for key, value := range f.Labels {
+		if v, ok := record.Strategy.Labels[key]; !ok || v != value {
+			return false
+		}
+	}
+	for key, value := range f.Tags {
+		if v, ok := record.Tags[key]; !ok || v != value {
+			return false
+		}
+	}
+	return true
+}
+
+func FilterCuts(cuts []*CutRecord, filter Filter, limit int) []*CutRecord {
+	var matched []*CutRecord
+	for _, cut := range cuts {
+		if !filter.Match(cut) {
+			continue
+		}
+		matched = append(matched, cut)
+		if limit > 0 && len(matched) == limit {
+			break
+		}
+	}
+	return matched
+}
+
+func ParseLabelSelector(s string) (key, value string, err error) {
+	key, value, ok := strings.Cut(s, "=")
+	key = strings.TrimSpace(key)
+	if !ok || key == "" {
+		return "", "", fmt.Errorf("label selector %q: expected key=value", s)
+	}
+	return key, strings.TrimSpace(value), nil
+}
+
+func LabelKey(key, value string) string {
+	return key + "=" + value
+}
@@ -10,53 +10,349 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"atropos/internal/timefmt"
+)
+
+// Trigger values say why a record exists. Fallback and escalation records
+// point at the attempt that failed before them via ParentCutID.
+const (
+	TriggerInitial    = "initial"
+	TriggerFallback   = "fallback"
+	TriggerEscalation = "escalation"
+	TriggerImported   = "imported"
+	TriggerScheduled  = "scheduled"
+	TriggerRecovery   = "recovery"
+)
+
+// Source values for cuts without a caller-supplied source. Chain steps and
+// schedules are started by Atropos itself; Reason then says why.
+const (
+	SourceWebhook = "webhook"
+	SourceAtropos = "atropos"
 )
 
+// ScheduledEntropy is recorded for scheduled cuts, which have no reading, and
+// for operator actions such as rate-limit resets.
+const ScheduledEntropy = -1.0
+
+// Every record carries an outcome. Executed and failed are cuts that ran;
+// the skipped_* outcomes were refused before anything touched the node and
+// never count toward success rates.
+const (
+	OutcomeExecuted        = "executed"
+	OutcomeFailed          = "failed"
+	OutcomeDeferred        = "deferred"
+	OutcomePendingApproval = "pending_approval"
+	OutcomeRejected        = "rejected"
+	OutcomeBlocked         = "blocked"
+	OutcomeCircuitOpen     = "circuit_open"
+	OutcomeHookFailed      = "hook_failed"
+	OutcomeVerifyFailed    = "verification_failed"
+	// OutcomeDuplicateSuppressed is a repeat of a request inside the dedup
+	// window; DuplicateOf names the cut that answered it.
+	OutcomeDuplicateSuppressed = "duplicate_suppressed"
+	// OutcomeInProgress marks the placeholder saved for a cut still running.
+	// The cut's first record replaces it.
+	OutcomeInProgress = "in_progress"
+	// OutcomeRateLimitReset records an operator clearing a node's rate
+	// limit; nothing was cut.
+	OutcomeRateLimitReset = "rate_limit_reset"
+	// OutcomeNotified is a notify_only strategy: a notification was sent
+	// and the node left alone.
+	OutcomeNotified = "notified"
+
+	OutcomeSkippedBelowThreshold = "skipped_below_threshold"
+	OutcomeSkippedRateLimited    = "skipped_rate_limited"
+	OutcomeSkippedTimeWindow     = "skipped_time_window"
+	OutcomeSkippedBlackout       = "skipped_blackout"
+)
+
+// RanOutcome is the outcome of a cut that ran, before anything more specific
+// (hook or verification failure) applies.
+func RanOutcome(success bool) string {
+	if success {
+		return OutcomeExecuted
+	}
+	return OutcomeFailed
+}
+
+// IsSkipped reports whether outcome means the cut was refused before it ran.
+func IsSkipped(outcome string) bool {
+	return strings.HasPrefix(outcome, "skipped_")
+}
+
+// PlainOutcome reports whether outcome says no more than Success does.
+func PlainOutcome(outcome string) bool {
+	return outcome == "" || outcome == OutcomeExecuted || outcome == OutcomeFailed
+}
+
 type CutRecord struct {
-	ID            string       `json:"id"`
-	Node          string       `json:"node"`
-	Entropy       float64      `json:"entropy"`
-	Action        string       `json:"action"`
-	Success       bool         `json:"success"`
-	Error         string       `json:"error,omitempty"`
-	LatencyMs     int64        `json:"latency_ms"`
-	Timestamp     time.Time    `json:"timestamp"`
-	PolicyVersion string       `json:"policy_version"`
-	Strategy      StrategyInfo `json:"strategy"`
+	ID      string  `json:"id"`
+	Node    string  `json:"node"`
+	Entropy float64 `json:"entropy"`
+	// EntropyOriginal is the reading as sent, when it was clamped into range.
+	EntropyOriginal *float64               `json:"entropy_original,omitempty"`
+	Action          string                 `json:"action"`
+	Success         bool                   `json:"success"`
+	DryRun          bool                   `json:"dry_run,omitempty"`
+	Outcome         string                 `json:"outcome,omitempty"`
+	Error           string                 `json:"error,omitempty"`
+	LatencyMs       int64                  `json:"latency_ms"`
+	Timestamp       time.Time              `json:"timestamp"`
+	PolicyVersion   string                 `json:"policy_version"`
+	PolicyHash      string                 `json:"policy_hash,omitempty"`
+	Strategy        StrategyInfo           `json:"strategy"`
+	TriggerCount    int                    `json:"trigger_count,omitempty"`
+	Escalation      *Escalation            `json:"escalation,omitempty"`
+	Approval        *Approval              `json:"approval,omitempty"`
+	Details         map[string]interface{} `json:"details,omitempty"`
+	Output          string                 `json:"output,omitempty"`
+	Tags            map[string]string      `json:"tags,omitempty"`
+	KeyID           string                 `json:"key_id,omitempty"`
+	Trigger         string                 `json:"trigger,omitempty"`
+	Source          string                 `json:"source,omitempty"`
+	Reason          string                 `json:"reason,omitempty"`
+	CallbackURL     string                 `json:"callback_url,omitempty"`
+	ParentCutID     string                 `json:"parent_cut_id,omitempty"`
+	DuplicateOf     string                 `json:"duplicate_of,omitempty"`
+	ChainID         string                 `json:"chain_id,omitempty"`
+	ChainStep       int                    `json:"chain_step,omitempty"`
+	Timings         *Timings               `json:"timings,omitempty"`
+	Hooks           []HookResult           `json:"hooks,omitempty"`
+	Steps           []StepResult           `json:"steps,omitempty"`
+	Verification    *Verification          `json:"verification,omitempty"`
+}
+
+// Verification is the outcome of a strategy's post-cut probe.
+type Verification struct {
+	Type       string `json:"type"`
+	Success    bool   `json:"success"`
+	Attempts   int    `json:"attempts"`
+	DurationMs int64  `json:"duration_ms"`
+	Error      string `json:"error,omitempty"`
+}
+
+// HookResult is one pre- or post-hook run around the cut's action.
+type HookResult struct {
+	Phase     string `json:"phase"`
+	Action    string `json:"action"`
+	Success   bool   `json:"success"`
+	Error     string `json:"error,omitempty"`
+	LatencyMs int64  `json:"latency_ms"`
+}
+
+// StepResult is one action of a strategy that runs several in order. Steps
+// after a failure that stopped the strategy are not listed.
+type StepResult struct {
+	Action    string                 `json:"action"`
+	Success   bool                   `json:"success"`
+	Error     string                 `json:"error,omitempty"`
+	LatencyMs int64                  `json:"latency_ms"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+	Output    string                 `json:"output,omitempty"`
+}
+
+// Timings marks when Atropos itself reached each phase of a cut, so time
+// spent before the cutter ran is visible next to LatencyMs. Later steps of a
+// chain count their guards as done when the previous step failed.
+type Timings struct {
+	ReceivedAt    time.Time  `json:"received_at"`
+	GuardsDoneAt  *time.Time `json:"guards_done_at,omitempty"`
+	CutterStartAt *time.Time `json:"cutter_start_at,omitempty"`
+	CutterEndAt   *time.Time `json:"cutter_end_at,omitempty"`
+}
+
+// DecisionMs is the time from webhook receipt to the end of guard evaluation.
+func (t *Timings) DecisionMs() (int64, bool) {
+	return spanMs(&t.ReceivedAt, t.GuardsDoneAt)
+}
+
+// WaitMs is the time from the end of guard evaluation to the cutter starting,
+// e.g. waiting for a concurrency slot.
+func (t *Timings) WaitMs() (int64, bool) {
+	return spanMs(t.GuardsDoneAt, t.CutterStartAt)
+}
+
+func (t *Timings) CutterMs() (int64, bool) {
+	return spanMs(t.CutterStartAt, t.CutterEndAt)
+}
+
+func spanMs(from, to *time.Time) (int64, bool) {
+	if from == nil || to == nil || from.IsZero() {
+		return 0, false
+	}
+	return to.Sub(*from).Milliseconds(), true
+}
+
+type Escalation struct {
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Via    string `json:"via"`
+	Reason string `json:"reason,omitempty"`
+}
+
+func (r *CutRecord) Deferred() bool {
+	return r.Outcome == OutcomeDeferred
+}
+
+func (r *CutRecord) Skipped() bool {
+	return IsSkipped(r.Outcome)
+}
+
+func (r *CutRecord) Executed() bool {
+	switch r.Outcome {
+	case OutcomeDeferred, OutcomePendingApproval, OutcomeRejected, OutcomeBlocked, OutcomeCircuitOpen, OutcomeDuplicateSuppressed, OutcomeInProgress, OutcomeRateLimitReset:
+		return false
+	}
+	return !r.Skipped()
+}
+
+// normalizeOutcome fills in the outcome of records written before every
+// record had one, from what those records did say.
+func (r *CutRecord) normalizeOutcome() {
+	switch {
+	case r.Outcome == "skipped", r.Outcome == "" && r.Action == "none":
+		r.Outcome = OutcomeSkippedBelowThreshold
+	case r.Outcome != "":
+	case strings.HasPrefix(r.Error, "rate limit exceeded"):
+		r.Outcome = OutcomeSkippedRateLimited
+	case strings.HasPrefix(r.Error, "outside allowed time windows"):
+		r.Outcome = OutcomeSkippedTimeWindow
+	case strings.HasPrefix(r.Error, "blackout period in effect"):
+		r.Outcome = OutcomeSkippedBlackout
+	default:
+		r.Outcome = RanOutcome(r.Success)
+	}
 }
 
 type StrategyInfo struct {
-	Threshold    float64 `json:"threshold"`
-	Action       string  `json:"action"`
-	Critical     bool    `json:"critical"`
-	SnapshotName string  `json:"snapshot_name,omitempty"`
-	Command      string  `json:"command,omitempty"`
+	Threshold           float64           `json:"threshold"`
+	Action              string            `json:"action"`
+	Critical            bool              `json:"critical"`
+	SnapshotName        string            `json:"snapshot_name,omitempty"`
+	Command             string            `json:"command,omitempty"`
+	ConsecutiveTriggers int               `json:"consecutive_triggers,omitempty"`
+	Description         string            `json:"description,omitempty"`
+	RunbookURL          string            `json:"runbook_url,omitempty"`
+	Labels              map[string]string `json:"labels,omitempty"`
+	Params              map[string]string `json:"params,omitempty"`
 }
 
 type HistoryManager struct {
-	historyDir string
-	mu         sync.RWMutex
+	historyDir      string
+	silences        *SilenceStore
+	approvals       *ApprovalStore
+	quota           *Quota
+	counts          map[string]int
+	overQuota       map[string]bool
+	onQuotaExceeded func(QuotaStatus)
+	version         Version
+	unsynced        map[string]bool
+	mu              sync.RWMutex
 }
 
 func NewHistoryManager(historyDir string) *HistoryManager {
 	if err := os.MkdirAll(historyDir, 0755); err != nil {
 		panic(fmt.Sprintf("failed to create history directory: %v", err))
 	}
+	silences, err := NewSilenceStore(historyDir)
+	if err != nil {
+		panic(fmt.Sprintf("failed to load silences: %v", err))
+	}
+	approvals, err := NewApprovalStore(historyDir)
+	if err != nil {
+		panic(fmt.Sprintf("failed to load approvals: %v", err))
+	}
+	now := time.Now().UTC()
 	return &HistoryManager{
 		historyDir: historyDir,
+		silences:   silences,
+		approvals:  approvals,
+		version:    Version{Modified: now, epoch: now.UnixNano()},
 	}
 }
 
+func (h *HistoryManager) Approvals() *ApprovalStore {
+	return h.approvals
+}
+
+func (h *HistoryManager) Silences() *SilenceStore {
+	return h.silences
+}
+
 func (h *HistoryManager) SaveCut(record *CutRecord) error {
 	h.mu.Lock()
-	defer h.mu.Unlock()
+	h.normalize(record)
+	path, err := h.recordPath(record.ID)
+	if err != nil {
+		h.mu.Unlock()
+		return err
+	}
+	_, statErr := os.Stat(path)
+	err = h.writeLocked(record)
+	var alert func()
+	if err == nil {
+		h.bumpLocked()
+		if os.IsNotExist(statErr) {
+			alert = h.countNewLocked(record.Node)
+		}
+	}
+	h.mu.Unlock()
+
+	if alert != nil {
+		alert()
+	}
+	return err
+}
+
+// SaveNewCut stores a record without ever replacing an existing one; when the
+// ID is already taken a numeric suffix is appended until it is unique.
+func (h *HistoryManager) SaveNewCut(record *CutRecord) error {
+	h.mu.Lock()
+
+	h.normalize(record)
+	if _, err := h.recordPath(record.ID); err != nil {
+		h.mu.Unlock()
+		return err
+	}
+	base := record.ID
+	for i := 1; ; i++ {
+		if _, err := os.Stat(h.joinPath(record.ID + ".json.gz")); os.IsNotExist(err) {
+			break
+		}
+		record.ID = fmt.Sprintf("%s_%d", base, i)
+	}
+	err := h.writeLocked(record)
+	var alert func()
+	if err == nil {
+		h.bumpLocked()
+		alert = h.countNewLocked(record.Node)
+	}
+	h.mu.Unlock()
+
+	if alert != nil {
+		alert()
+	}
+	return err
+}
+
+func (h *HistoryManager) normalize(record *CutRecord) {
+	if record.Timestamp.IsZero() {
+		record.Timestamp = time.Now()
+	}
+	record.Timestamp = record.Timestamp.UTC()
 
 	if record.ID == "" {
-		record.ID = fmt.Sprintf("cut_%d_%s", time.Now().Unix(), record.Node)
+		record.ID = NewCutID(record.Node, record.Timestamp)
 	}
+}
 
-	filename := fmt.Sprintf("%s.json.gz", record.ID)
-	filepath := h.joinPath(filename)
+func (h *HistoryManager) writeLocked(record *CutRecord) error {
+	filepath, err := h.recordPath(record.ID)
+	if err != nil {
+		return err
+	}
 
 	file, err := os.Create(filepath)
 	if err != nil {
@@ -73,16 +369,30 @@ func (h *HistoryManager) SaveCut(record *CutRecord) error {
 		return fmt.Errorf("encode record: %w", err)
 	}
 
+	if h.unsynced == nil {
+		h.unsynced = make(map[string]bool)
+	}
+	h.unsynced[filepath] = true
 	return nil
 }
 
+func NewCutID(node string, t time.Time) string {
+	return fmt.Sprintf("cut_%d_%s", t.UnixNano(), node)
+}
+
 func (h *HistoryManager) LoadCut(id string) (*CutRecord, error) {
 	h.mu.RLock()
 	defer h.mu.RUnlock()
 
+	return h.loadLocked(id)
+}
+
+func (h *HistoryManager) loadLocked(id string) (*CutRecord, error) {
 	id = strings.TrimSuffix(id, ".json.gz")
-	filename := fmt.Sprintf("%s.json.gz", id)
-	filepath := h.joinPath(filename)
+	filepath, err := h.recordPath(id)
+	if err != nil {
+		return nil, err
+	}
 
 	file, err := os.Open(filepath)
 	if err != nil {
@@ -100,6 +410,8 @@ func (h *HistoryManager) LoadCut(id string) (*CutRecord, error) {
 	if err := json.NewDecoder(gz).Decode(&record); err != nil {
 		return nil, fmt.Errorf("decode record: %w", err)
 	}
+	record.Timestamp = record.Timestamp.UTC()
+	record.normalizeOutcome()
 
 	return &record, nil
 }
@@ -123,7 +435,7 @@ func (h *HistoryManager) ListCuts(limit int) ([]*CutRecord, error) {
 		}
 
 		id := strings.TrimSuffix(entry.Name(), ".json.gz")
-		record, err := h.LoadCut(id)
+		record, err := h.loadLocked(id)
 		if err != nil {
 			continue
 		}
@@ -174,14 +486,42 @@ func (h *HistoryManager) GetLatestCutByNode(node string) (*CutRecord, error) {
 	return cuts[0], nil
 }
 
-func (h *HistoryManager) PurgeOldCuts(retentionDays int) error {
+// FailInProgress marks records still in_progress as failed with reason. Only
+// a cut interrupted by a crash or restart leaves one behind, so call it at
+// startup, before any cut runs.
+func (h *HistoryManager) FailInProgress(reason string) (int, error) {
+	cuts, err := h.ListCuts(0)
+	if err != nil {
+		return 0, err
+	}
+
+	failed := 0
+	for _, cut := range cuts {
+		if cut.Outcome != OutcomeInProgress {
+			continue
+		}
+		cut.Outcome = OutcomeFailed
+		cut.Error = reason
+		if err := h.SaveCut(cut); err != nil {
+			return failed, err
+		}
+		failed++
+	}
+	return failed, nil
+}
+
+func (h *HistoryManager) PurgeOldCuts(retentionDays int) (int, error) {
+	if retentionDays <= 0 {
+		return 0, nil
+	}
+
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
 	cutoff := time.Now().AddDate(0, 0, -retentionDays)
 	entries, err := os.ReadDir(h.historyDir)
 	if err != nil {
-		return fmt.Errorf("read directory: %w", err)
+		return 0, fmt.Errorf("read directory: %w", err)
 	}
 
 	var purged int
@@ -193,12 +533,18 @@ func (h *HistoryManager) PurgeOldCuts(retentionDays int) error {
 			continue
 		}
 
-		info, err := entry.Info()
-		if err != nil {
+		// Imported and re-saved records can have a ModTime far newer than the
+		// event itself, so the record timestamp wins when it can be read.
+		var ts time.Time
+		if record, err := h.loadLocked(entry.Name()); err == nil {
+			ts = record.Timestamp
+		} else if info, err := entry.Info(); err == nil {
+			ts = info.ModTime()
+		} else {
 			continue
 		}
 
-		if info.ModTime().Before(cutoff) {
+		if ts.Before(cutoff) {
 			filepath := h.joinPath(entry.Name())
 			if err := os.Remove(filepath); err != nil {
 				continue
@@ -207,33 +553,106 @@ func (h *HistoryManager) PurgeOldCuts(retentionDays int) error {
 		}
 	}
 
-	return nil
+	if purged > 0 {
+		h.bumpLocked()
+		if err := h.recountLocked(); err != nil {
+			return purged, err
+		}
+	}
+	return purged, nil
 }
 
-func (h *HistoryManager) GetStats() (*HistoryStats, error) {
+func (h *HistoryManager) GetStats(filter Filter) (*HistoryStats, error) {
 	allCuts, err := h.ListCuts(0)
 	if err != nil {
 		return nil, err
 	}
 
 	stats := &HistoryStats{
-		TotalCuts:   len(allCuts),
-		SuccessCuts: 0,
-		FailedCuts:  0,
-		ByNode:      make(map[string]int),
-		ByAction:    make(map[string]int),
-		Nodes:       make(map[string]*NodeStats),
+		SuccessCuts:      0,
+		FailedCuts:       0,
+		ByNode:           make(map[string]int),
+		ByAction:         make(map[string]int),
+		ByOutcome:        make(map[string]int),
+		SkippedByOutcome: make(map[string]int),
+		ByLabel:          make(map[string]int),
+		Nodes:            make(map[string]*NodeStats),
+	}
+	hashes := make(map[string]*PolicyHashUsage)
+
+	// A fallback or escalation chain counts once, as a success if any step
+	// succeeded.
+	chainSucceeded := make(map[string]bool)
+	for _, cut := range allCuts {
+		if cut.ChainID != "" && cut.Success {
+			chainSucceeded[cut.ChainID] = true
+		}
 	}
 
+	// Dry runs and skipped cuts are counted whether or not the filter keeps
+	// them, but only among the records its other criteria match.
+	counted := filter
+	counted.IncludeDryRun, counted.IncludeSkipped = true, true
 	for _, cut := range allCuts {
-		if cut.Success {
+		if !counted.Match(cut) {
+			continue
+		}
+		if cut.DryRun {
+			stats.DryRunCuts++
+		}
+		if cut.Skipped() {
+			stats.SkippedCuts++
+			stats.SkippedByOutcome[cut.Outcome]++
+		}
+		if !filter.Match(cut) {
+			continue
+		}
+
+		if cut.PolicyHash != "" {
+			usage := hashes[cut.PolicyHash]
+			if usage == nil {
+				usage = &PolicyHashUsage{Hash: cut.PolicyHash, FirstSeen: cut.Timestamp}
+				hashes[cut.PolicyHash] = usage
+			}
+			usage.Cuts++
+			if cut.Timestamp.Before(usage.FirstSeen) {
+				usage.FirstSeen = cut.Timestamp
+			}
+			if cut.Timestamp.After(usage.LastSeen) {
+				usage.LastSeen = cut.Timestamp
+				usage.PolicyVersion = cut.PolicyVersion
+			}
+		}
+
+		if !cut.Executed() {
+			if cut.Deferred() {
+				stats.DeferredCuts++
+			}
+			if cut.Outcome == OutcomeDuplicateSuppressed {
+				stats.DuplicateCuts++
+			}
+			stats.ByOutcome[cut.Outcome]++
+			continue
+		}
+
+		stats.ByAction[cut.Action]++
+		if cut.ParentCutID != "" {
+			stats.ChainedCuts++
+			continue
+		}
+		success := cut.Success || chainSucceeded[cut.ID]
+
+		stats.TotalCuts++
+		if success {
 			stats.SuccessCuts++
 		} else {
 			stats.FailedCuts++
 		}
 
 		stats.ByNode[cut.Node]++
-		stats.ByAction[cut.Action]++
+		for key, value := range cut.Strategy.Labels {
+			stats.ByLabel[LabelKey(key, value)]++
+		}
 
 		if stats.Nodes[cut.Node] == nil {
 			stats.Nodes[cut.Node] = &NodeStats{
@@ -245,7 +664,7 @@ func (h *HistoryManager) GetStats() (*HistoryStats, error) {
 		}
 
 		stats.Nodes[cut.Node].TotalCuts++
-		if cut.Success {
+		if success {
 			stats.Nodes[cut.Node].Success++
 		} else {
 			stats.Nodes[cut.Node].Failed++
@@ -261,21 +680,49 @@ func (h *HistoryManager) GetStats() (*HistoryStats, error) {
 
 	if stats.FirstCut != nil && stats.LastCut != nil {
 		stats.TotalDuration = stats.LastCut.Sub(*stats.FirstCut)
+		stats.TotalDurationSeconds = stats.TotalDuration.Seconds()
+		stats.TotalDurationHuman = timefmt.Human(stats.TotalDuration)
 	}
 
+	for _, usage := range hashes {
+		stats.PolicyHashes = append(stats.PolicyHashes, *usage)
+	}
+	sort.Slice(stats.PolicyHashes, func(i, j int) bool {
+		return stats.PolicyHashes[i].FirstSeen.Before(stats.PolicyHashes[j].FirstSeen)
+	})
+
 	return stats, nil
 }
 
 type HistoryStats struct {
-	TotalCuts     int                   `json:"total_cuts"`
-	SuccessCuts   int                   `json:"success_cuts"`
-	FailedCuts    int                   `json:"failed_cuts"`
-	FirstCut      *time.Time            `json:"first_cut,omitempty"`
-	LastCut       *time.Time            `json:"last_cut,omitempty"`
-	TotalDuration time.Duration         `json:"total_duration"`
-	ByNode        map[string]int        `json:"by_node"`
-	ByAction      map[string]int        `json:"by_action"`
-	Nodes         map[string]*NodeStats `json:"nodes"`
+	TotalCuts            int                   `json:"total_cuts"`
+	SuccessCuts          int                   `json:"success_cuts"`
+	FailedCuts           int                   `json:"failed_cuts"`
+	DeferredCuts         int                   `json:"deferred_cuts"`
+	DryRunCuts           int                   `json:"dry_run_cuts"`
+	SkippedCuts          int                   `json:"skipped_cuts"`
+	DuplicateCuts        int                   `json:"duplicate_cuts"`
+	SkippedByOutcome     map[string]int        `json:"skipped_by_outcome"`
+	ChainedCuts          int                   `json:"chained_cuts"`
+	FirstCut             *time.Time            `json:"first_cut,omitempty"`
+	LastCut              *time.Time            `json:"last_cut,omitempty"`
+	TotalDuration        time.Duration         `json:"total_duration"`
+	TotalDurationSeconds float64               `json:"total_duration_seconds"`
+	TotalDurationHuman   string                `json:"total_duration_human"`
+	ByNode               map[string]int        `json:"by_node"`
+	ByAction             map[string]int        `json:"by_action"`
+	ByOutcome            map[string]int        `json:"by_outcome,omitempty"`
+	ByLabel              map[string]int        `json:"by_label,omitempty"`
+	PolicyHashes         []PolicyHashUsage     `json:"policy_hashes,omitempty"`
+	Nodes                map[string]*NodeStats `json:"nodes"`
+}
+
+type PolicyHashUsage struct {
+	Hash          string    `json:"hash"`
+	PolicyVersion string    `json:"policy_version,omitempty"`
+	Cuts          int       `json:"cuts"`
+	FirstSeen     time.Time `json:"first_seen"`
+	LastSeen      time.Time `json:"last_seen"`
 }
 
 type NodeStats struct {
@@ -288,3 +735,21 @@ type NodeStats struct {
 func (h *HistoryManager) joinPath(filename string) string {
 	return filepath.Join(h.historyDir, filename)
 }
+
+func (h *HistoryManager) CheckWritable() error {
+	f, err := os.CreateTemp(h.historyDir, ".healthcheck-*")
+	if err != nil {
+		return fmt.Errorf("history not writable: %w", err)
+	}
+	name := f.Name()
+	_, werr := f.Write([]byte("ok"))
+	cerr := f.Close()
+	os.Remove(name)
+	if werr != nil {
+		return fmt.Errorf("history not writable: %w", werr)
+	}
+	if cerr != nil {
+		return fmt.Errorf("history not writable: %w", cerr)
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package history
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func saveTestCut(t *testing.T, h *HistoryManager, record *CutRecord) {
+	t.Helper()
+	if record.ID == "" {
+		record.ID = NewCutID(record.Node, record.Timestamp)
+	}
+	if err := h.SaveNewCut(record); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGetStatsFilteredTotalsDiffer(t *testing.T) {
+	h := NewHistoryManager(t.TempDir())
+	now := time.Now().UTC()
+	old := now.AddDate(0, 0, -30)
+	web := StrategyInfo{Labels: map[string]string{"tier": "web"}}
+
+	saveTestCut(t, h, &CutRecord{Node: "athena", Action: "docker_restart", Success: true, Timestamp: old, Strategy: web})
+	saveTestCut(t, h, &CutRecord{Node: "athena", Action: "docker_restart", Success: false, Timestamp: old.Add(time.Minute)})
+	saveTestCut(t, h, &CutRecord{Node: "athena", Action: "docker_restart", Success: true, DryRun: true, Timestamp: old.Add(2 * time.Minute)})
+	saveTestCut(t, h, &CutRecord{Node: "athena", Action: "none", Outcome: OutcomeSkippedBlackout, Timestamp: old.Add(3 * time.Minute)})
+	saveTestCut(t, h, &CutRecord{Node: "borg", Action: "docker_restart", Success: true, Timestamp: now, Strategy: web})
+	saveTestCut(t, h, &CutRecord{Node: "borg", Action: "docker_restart", Success: true, DryRun: true, Timestamp: now.Add(time.Second)})
+	saveTestCut(t, h, &CutRecord{Node: "borg", Action: "restart_nginx", Success: true, Trigger: TriggerImported, Timestamp: now.Add(2 * time.Second)})
+
+	all, err := h.GetStats(Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if all.TotalCuts != 3 || all.FailedCuts != 1 || all.DryRunCuts != 2 || all.SkippedCuts != 1 {
+		t.Fatalf("unfiltered stats: total %d, failed %d, dry runs %d, skipped %d; want 3, 1, 2, 1",
+			all.TotalCuts, all.FailedCuts, all.DryRunCuts, all.SkippedCuts)
+	}
+
+	recent, err := h.GetStats(Filter{Since: now.AddDate(0, 0, -7)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if recent.TotalCuts != 1 || recent.FailedCuts != 0 || recent.DryRunCuts != 1 || recent.SkippedCuts != 0 {
+		t.Fatalf("last 7 days: total %d, failed %d, dry runs %d, skipped %d; want 1, 0, 1, 0",
+			recent.TotalCuts, recent.FailedCuts, recent.DryRunCuts, recent.SkippedCuts)
+	}
+	if len(recent.SkippedByOutcome) != 0 || recent.ByNode["athena"] != 0 {
+		t.Fatalf("last 7 days counted old records: skipped %v, by node %v", recent.SkippedByOutcome, recent.ByNode)
+	}
+
+	labelled, err := h.GetStats(Filter{Labels: map[string]string{"tier": "web"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if labelled.TotalCuts != 2 || labelled.DryRunCuts != 0 || labelled.SkippedCuts != 0 {
+		t.Fatalf("tier=web: total %d, dry runs %d, skipped %d; want 2, 0, 0",
+			labelled.TotalCuts, labelled.DryRunCuts, labelled.SkippedCuts)
+	}
+
+	imported, err := h.GetStats(Filter{IncludeImported: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imported.TotalCuts != all.TotalCuts+1 {
+		t.Fatalf("with imported: total %d, want %d", imported.TotalCuts, all.TotalCuts+1)
+	}
+}
+
+func TestSavedTimestampsAreUTC(t *testing.T) {
+	h := NewHistoryManager(t.TempDir())
+	pdt := time.FixedZone("PDT", -7*3600)
+	ts := time.Date(2025, 11, 1, 20, 14, 0, 0, pdt)
+
+	record := &CutRecord{Node: "athena", Action: "docker_restart", Success: true, Timestamp: ts}
+	saveTestCut(t, h, record)
+	if record.Timestamp.Location() != time.UTC || !record.Timestamp.Equal(ts) {
+		t.Fatalf("saved timestamp = %v, want %v in UTC", record.Timestamp, ts)
+	}
+	loaded, err := h.LoadCut(record.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Timestamp.Location() != time.UTC || !loaded.Timestamp.Equal(ts) {
+		t.Fatalf("loaded timestamp = %v, want %v in UTC", loaded.Timestamp, ts)
+	}
+
+	stats, err := h.GetStats(Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.FirstCut == nil || !stats.FirstCut.Equal(ts) {
+		t.Fatalf("first cut = %v, want %v", stats.FirstCut, ts)
+	}
+}
+
+func TestImportedTimestampsAreUTC(t *testing.T) {
+	records, errs := ParseExternalRecords(strings.NewReader(
+		`{"node":"athena","action":"restart_nginx","success":true,"timestamp":"2025-11-02T12:44:00+09:30"}` + "\n"))
+	if len(errs) != 0 {
+		t.Fatalf("import errors: %v", errs)
+	}
+	want := time.Date(2025, 11, 2, 3, 14, 0, 0, time.UTC)
+	if got := records[0].Timestamp; got.Location() != time.UTC || !got.Equal(want) {
+		t.Fatalf("imported timestamp = %v, want %v", got, want)
+	}
+}
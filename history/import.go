@@ -0,0 +1,118 @@
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+const maxImportLine = 1 << 20
+
+// ExternalRecord is one NDJSON line of an external history import.
+//
+//	{"node":"web-1","action":"restart_nginx","success":true,
+//	 "timestamp":"2025-11-02T03:14:00Z","latency_ms":1200,"source":"cron-remediate"}
+type ExternalRecord struct {
+	Node      string   `json:"node"`
+	Action    string   `json:"action"`
+	Success   *bool    `json:"success"`
+	Timestamp string   `json:"timestamp"`
+	LatencyMs int64    `json:"latency_ms"`
+	Source    string   `json:"source"`
+	Entropy   *float64 `json:"entropy,omitempty"`
+	Error     string   `json:"error,omitempty"`
+}
+
+type ImportError struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+func ParseExternalRecords(r io.Reader) ([]*CutRecord, []ImportError) {
+	var records []*CutRecord
+	var errs []ImportError
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxImportLine)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		var ext ExternalRecord
+		if err := json.Unmarshal([]byte(text), &ext); err != nil {
+			errs = append(errs, ImportError{Line: line, Error: fmt.Sprintf("invalid json: %v", err)})
+			continue
+		}
+
+		record, err := ext.toCutRecord()
+		if err != nil {
+			errs = append(errs, ImportError{Line: line, Error: err.Error()})
+			continue
+		}
+		records = append(records, record)
+	}
+
+	if err := scanner.Err(); err != nil {
+		errs = append(errs, ImportError{Line: line + 1, Error: fmt.Sprintf("read: %v", err)})
+	}
+
+	return records, errs
+}
+
+func (ext *ExternalRecord) toCutRecord() (*CutRecord, error) {
+	if ext.Node == "" {
+		return nil, fmt.Errorf("node is required")
+	}
+	if err := ValidateNodeName(ext.Node); err != nil {
+		return nil, err
+	}
+	if ext.Action == "" {
+		return nil, fmt.Errorf("action is required")
+	}
+	if ext.Success == nil {
+		return nil, fmt.Errorf("success is required")
+	}
+	if ext.LatencyMs < 0 {
+		return nil, fmt.Errorf("latency_ms must be >= 0")
+	}
+
+	ts, err := time.Parse(time.RFC3339, ext.Timestamp)
+	if err != nil {
+		return nil, fmt.Errorf("timestamp must be RFC3339: %v", err)
+	}
+	if ts.After(time.Now().Add(time.Minute)) {
+		return nil, fmt.Errorf("timestamp %s is in the future", ext.Timestamp)
+	}
+
+	source := ext.Source
+	if source == "" {
+		source = "external"
+	}
+
+	record := &CutRecord{
+		Node:      ext.Node,
+		Action:    ext.Action,
+		Success:   *ext.Success,
+		Error:     ext.Error,
+		LatencyMs: ext.LatencyMs,
+		Timestamp: ts.UTC(),
+		Trigger:   TriggerImported,
+		Source:    source,
+		Strategy: StrategyInfo{
+			Action: ext.Action,
+		},
+	}
+	if ext.Entropy != nil {
+		record.Entropy = *ext.Entropy
+	}
+
+	return record, nil
+}
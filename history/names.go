@@ -0,0 +1,39 @@
+package history
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+)
+
+const MaxNodeNameLength = 128
+
+var (
+	ErrInvalidNodeName = errors.New("invalid node name")
+	ErrInvalidCutID    = errors.New("invalid cut id")
+
+	safeNamePattern = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+)
+
+// ValidateNodeName accepts only names that are safe to embed in a file name:
+// ASCII letters, digits, '.', '_' and '-', at most MaxNodeNameLength long.
+func ValidateNodeName(name string) error {
+	if len(name) == 0 || len(name) > MaxNodeNameLength || !safeNamePattern.MatchString(name) || name == "." || name == ".." {
+		return fmt.Errorf("%w %q: use 1-%d characters of A-Z, a-z, 0-9, '.', '_', '-'", ErrInvalidNodeName, name, MaxNodeNameLength)
+	}
+	return nil
+}
+
+// recordPath maps a cut ID to its file, refusing anything that could resolve
+// outside the history directory.
+func (h *HistoryManager) recordPath(id string) (string, error) {
+	if !safeNamePattern.MatchString(id) || id == "." || id == ".." {
+		return "", fmt.Errorf("%w %q", ErrInvalidCutID, id)
+	}
+	path := h.joinPath(id + ".json.gz")
+	if filepath.Dir(path) != filepath.Clean(h.historyDir) {
+		return "", fmt.Errorf("%w %q", ErrInvalidCutID, id)
+	}
+	return path, nil
+}
@@ -0,0 +1,91 @@
+package history
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateNodeName(t *testing.T) {
+	for _, name := range []string{
+		"athena",
+		"web-01",
+		"db_primary.eu",
+		"...",
+		".hidden",
+		strings.Repeat("a", MaxNodeNameLength),
+	} {
+		if err := ValidateNodeName(name); err != nil {
+			t.Errorf("ValidateNodeName(%q) = %v, want ok", name, err)
+		}
+	}
+
+	for _, name := range []string{
+		"",
+		".",
+		"..",
+		"../etc",
+		"../../etc/passwd",
+		"a/../b",
+		"athena/..",
+		`..\windows`,
+		`a\b`,
+		"/etc/passwd",
+		"/athena",
+		`C:\athena`,
+		"C:athena",
+		"~athena",
+		"athena\x00.json",
+		"athena\n",
+		"at hena",
+		"athéna",
+		"ατροπος",
+		"athena\u200b",
+		"ａｔｈｅｎａ",
+		"athena\u2215etc",
+		"athena%2f..",
+		strings.Repeat("a", MaxNodeNameLength+1),
+	} {
+		err := ValidateNodeName(name)
+		if !errors.Is(err, ErrInvalidNodeName) {
+			t.Errorf("ValidateNodeName(%q) = %v, want ErrInvalidNodeName", name, err)
+		}
+	}
+}
+
+func TestRecordPathStaysInHistoryDir(t *testing.T) {
+	dir := t.TempDir()
+	h := NewHistoryManager(filepath.Join(dir, "history"))
+	outside := filepath.Join(dir, "secret.json.gz")
+	if err := os.WriteFile(outside, []byte("not a record"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, id := range []string{
+		"",
+		".",
+		"..",
+		"../secret",
+		"history/../../secret",
+		outside,
+		strings.TrimSuffix(outside, ".json.gz"),
+		`..\secret`,
+		"athéna-1",
+	} {
+		if _, err := h.LoadCut(id); !errors.Is(err, ErrInvalidCutID) {
+			t.Errorf("LoadCut(%q) = %v, want ErrInvalidCutID", id, err)
+		}
+		// SaveCut gives an empty ID a fresh one.
+		if id == "" {
+			continue
+		}
+		if err := h.SaveCut(&CutRecord{ID: id, Node: "athena"}); !errors.Is(err, ErrInvalidCutID) {
+			t.Errorf("SaveCut with ID %q = %v, want ErrInvalidCutID", id, err)
+		}
+	}
+	if data, err := os.ReadFile(outside); err != nil || string(data) != "not a record" {
+		t.Fatalf("file outside the history directory changed: %q, %v", data, err)
+	}
+}
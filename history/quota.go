@@ -0,0 +1,183 @@
+package history
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+type Quota struct {
+	MaxRecordsPerNode int
+	Nodes             map[string]int
+}
+
+func (q *Quota) limit(node string) int {
+	if q == nil {
+		return 0
+	}
+	if n, ok := q.Nodes[node]; ok {
+		return n
+	}
+	return q.MaxRecordsPerNode
+}
+
+func (q *Quota) enabled() bool {
+	if q == nil {
+		return false
+	}
+	if q.MaxRecordsPerNode > 0 {
+		return true
+	}
+	for _, n := range q.Nodes {
+		if n > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+type QuotaStatus struct {
+	Node    string `json:"node"`
+	Records int    `json:"records"`
+	Limit   int    `json:"limit"`
+}
+
+// SetQuota enables per-node record quotas. Saves are never refused; a node
+// that goes over its quota is flagged until the records are trimmed, and
+// onExceeded is called once each time a node crosses its limit.
+func (h *HistoryManager) SetQuota(q Quota, onExceeded func(QuotaStatus)) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !q.enabled() {
+		h.quota = nil
+		h.counts = nil
+		h.overQuota = nil
+		return nil
+	}
+
+	h.quota = &q
+	h.onQuotaExceeded = onExceeded
+	return h.recountLocked()
+}
+
+func (h *HistoryManager) QuotaExceeded() []QuotaStatus {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	var exceeded []QuotaStatus
+	for node := range h.overQuota {
+		exceeded = append(exceeded, QuotaStatus{
+			Node:    node,
+			Records: h.counts[node],
+			Limit:   h.quota.limit(node),
+		})
+	}
+	sort.Slice(exceeded, func(i, j int) bool {
+		return exceeded[i].Node < exceeded[j].Node
+	})
+	return exceeded
+}
+
+// TrimToQuota deletes the oldest records of every node that is over its quota.
+func (h *HistoryManager) TrimToQuota() (int, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !h.quota.enabled() {
+		return 0, nil
+	}
+
+	byNode, err := h.recordsByNodeLocked()
+	if err != nil {
+		return 0, err
+	}
+
+	var removed int
+	for node, records := range byNode {
+		limit := h.quota.limit(node)
+		if limit <= 0 || len(records) <= limit {
+			continue
+		}
+
+		sort.Slice(records, func(i, j int) bool {
+			return records[i].Timestamp.Before(records[j].Timestamp)
+		})
+		for _, record := range records[:len(records)-limit] {
+			if err := os.Remove(h.joinPath(record.ID + ".json.gz")); err != nil {
+				continue
+			}
+			removed++
+		}
+	}
+
+	if removed > 0 {
+		h.bumpLocked()
+	}
+	return removed, h.recountLocked()
+}
+
+func (h *HistoryManager) recordsByNodeLocked() (map[string][]*CutRecord, error) {
+	entries, err := os.ReadDir(h.historyDir)
+	if err != nil {
+		return nil, fmt.Errorf("read directory: %w", err)
+	}
+
+	byNode := make(map[string][]*CutRecord)
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json.gz") {
+			continue
+		}
+		record, err := h.loadLocked(entry.Name())
+		if err != nil {
+			continue
+		}
+		byNode[record.Node] = append(byNode[record.Node], record)
+	}
+	return byNode, nil
+}
+
+func (h *HistoryManager) recountLocked() error {
+	if h.quota == nil {
+		return nil
+	}
+
+	byNode, err := h.recordsByNodeLocked()
+	if err != nil {
+		return err
+	}
+
+	h.counts = make(map[string]int, len(byNode))
+	h.overQuota = make(map[string]bool)
+	for node, records := range byNode {
+		h.counts[node] = len(records)
+		if limit := h.quota.limit(node); limit > 0 && len(records) > limit {
+			h.overQuota[node] = true
+		}
+	}
+	return nil
+}
+
+// countNewLocked tracks a freshly written record. When it pushes the node over
+// its quota for the first time it returns the alert to run once the lock is
+// released.
+func (h *HistoryManager) countNewLocked(node string) func() {
+	if h.quota == nil {
+		return nil
+	}
+
+	h.counts[node]++
+	limit := h.quota.limit(node)
+	if limit <= 0 || h.counts[node] <= limit || h.overQuota[node] {
+		return nil
+	}
+
+	h.overQuota[node] = true
+	cb := h.onQuotaExceeded
+	if cb == nil {
+		return nil
+	}
+	status := QuotaStatus{Node: node, Records: h.counts[node], Limit: limit}
+	return func() { cb(status) }
+}
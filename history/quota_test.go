@@ -0,0 +1,169 @@
+package history
+
+import (
+	"os"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestTrimToQuotaRemovesOldestFirst(t *testing.T) {
+	h := NewHistoryManager(t.TempDir())
+	if err := h.SetQuota(Quota{MaxRecordsPerNode: 3, Nodes: map[string]int{"borg": 1, "cassandra": 0}}, nil); err != nil {
+		t.Fatal(err)
+	}
+	base := time.Date(2025, 11, 2, 3, 0, 0, 0, time.UTC)
+	// IDs sort the other way round from the timestamps, so a trim that went
+	// by file name would keep the wrong records.
+	for i, id := range []string{"z", "y", "x", "w", "v"} {
+		saveTestCut(t, h, &CutRecord{ID: "athena_" + id, Node: "athena", Action: "docker_restart", Timestamp: base.Add(time.Duration(i) * time.Minute)})
+	}
+	for i, id := range []string{"b", "a"} {
+		saveTestCut(t, h, &CutRecord{ID: "borg_" + id, Node: "borg", Action: "docker_restart", Timestamp: base.Add(time.Duration(i) * time.Minute)})
+	}
+	for i := 0; i < 5; i++ {
+		saveTestCut(t, h, &CutRecord{Node: "cassandra", Action: "docker_restart", Timestamp: base.Add(time.Duration(i) * time.Minute)})
+	}
+
+	removed, err := h.TrimToQuota()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 3 {
+		t.Fatalf("removed %d records, want 2 of athena's and 1 of borg's", removed)
+	}
+	for node, want := range map[string][]string{
+		"athena": {"athena_v", "athena_w", "athena_x"},
+		"borg":   {"borg_a"},
+	} {
+		if got := nodeIDs(t, h, node); !equalStrings(got, want) {
+			t.Errorf("%s kept %v, want the newest %v", node, got, want)
+		}
+	}
+	if got := len(nodeIDs(t, h, "cassandra")); got != 5 {
+		t.Errorf("cassandra, with no limit, kept %d of 5 records", got)
+	}
+
+	if removed, err := h.TrimToQuota(); err != nil || removed != 0 {
+		t.Fatalf("second trim removed %d, %v; want nothing", removed, err)
+	}
+}
+
+func nodeIDs(t *testing.T, h *HistoryManager, node string) []string {
+	t.Helper()
+	cuts, err := h.ListCutsByNode(node, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := make([]string, 0, len(cuts))
+	for _, cut := range cuts {
+		ids = append(ids, cut.ID)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestQuotaSignalLifecycle(t *testing.T) {
+	h := NewHistoryManager(t.TempDir())
+	var alerts []QuotaStatus
+	if err := h.SetQuota(Quota{MaxRecordsPerNode: 2}, func(s QuotaStatus) { alerts = append(alerts, s) }); err != nil {
+		t.Fatal(err)
+	}
+	base := time.Now().UTC().Add(-time.Hour)
+	save := func(i int) *CutRecord {
+		record := &CutRecord{Node: "athena", Action: "docker_restart", Timestamp: base.Add(time.Duration(i) * time.Second)}
+		saveTestCut(t, h, record)
+		return record
+	}
+
+	save(0)
+	last := save(1)
+	if len(alerts) != 0 || len(h.QuotaExceeded()) != 0 {
+		t.Fatalf("at the limit: alerts %v, exceeded %v; want neither", alerts, h.QuotaExceeded())
+	}
+
+	// Saving over the limit still persists the record.
+	over := save(2)
+	if _, err := h.LoadCut(over.ID); err != nil {
+		t.Fatalf("record over the quota was not saved: %v", err)
+	}
+	want := QuotaStatus{Node: "athena", Records: 3, Limit: 2}
+	if len(alerts) != 1 || alerts[0] != want {
+		t.Fatalf("alerts = %v, want one %v", alerts, want)
+	}
+	if exceeded := h.QuotaExceeded(); len(exceeded) != 1 || exceeded[0] != want {
+		t.Fatalf("exceeded = %v, want %v", exceeded, want)
+	}
+
+	// Rewriting a record, and further saves while flagged, don't alert again.
+	last.Outcome = "updated"
+	if err := h.SaveCut(last); err != nil {
+		t.Fatal(err)
+	}
+	save(3)
+	if len(alerts) != 1 {
+		t.Fatalf("alerted %d times while over the quota, want once", len(alerts))
+	}
+	if exceeded := h.QuotaExceeded(); exceeded[0].Records != 4 {
+		t.Fatalf("exceeded = %v, want 4 records counted", exceeded)
+	}
+
+	if removed, err := h.TrimToQuota(); err != nil || removed != 2 {
+		t.Fatalf("trim removed %d, %v; want 2", removed, err)
+	}
+	if exceeded := h.QuotaExceeded(); len(exceeded) != 0 {
+		t.Fatalf("still flagged after the trim: %v", exceeded)
+	}
+
+	// Crossing the limit again alerts again.
+	save(4)
+	if len(alerts) != 2 || alerts[1] != want {
+		t.Fatalf("alerts after crossing again = %v, want a second %v", alerts, want)
+	}
+
+	if err := h.SetQuota(Quota{}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if exceeded := h.QuotaExceeded(); len(exceeded) != 0 {
+		t.Fatalf("still flagged with quotas off: %v", exceeded)
+	}
+}
+
+// A node already over its quota when quotas are set is flagged from the
+// start, without an alert for records that were saved before.
+func TestQuotaCountsExistingRecords(t *testing.T) {
+	dir := t.TempDir()
+	h := NewHistoryManager(dir)
+	for i := 0; i < 3; i++ {
+		saveTestCut(t, h, &CutRecord{Node: "athena", Action: "docker_restart", Timestamp: time.Now().UTC().Add(time.Duration(i) * time.Second)})
+	}
+	// Not a record; the count skips it.
+	if err := os.WriteFile(h.joinPath("notes.txt"), []byte("x"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	restarted := NewHistoryManager(dir)
+	alerted := false
+	if err := restarted.SetQuota(Quota{MaxRecordsPerNode: 2}, func(QuotaStatus) { alerted = true }); err != nil {
+		t.Fatal(err)
+	}
+	want := QuotaStatus{Node: "athena", Records: 3, Limit: 2}
+	if exceeded := restarted.QuotaExceeded(); len(exceeded) != 1 || exceeded[0] != want {
+		t.Fatalf("exceeded = %v, want %v", exceeded, want)
+	}
+	if alerted {
+		t.Fatal("alerted for records saved before the quota was set")
+	}
+}
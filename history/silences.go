@@ -0,0 +1,140 @@
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+type Silence struct {
+	Node      string    `json:"node"`
+	Reason    string    `json:"reason"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type SilenceStore struct {
+	path     string
+	silences map[string]*Silence
+	mu       sync.Mutex
+}
+
+func NewSilenceStore(dir string) (*SilenceStore, error) {
+	store := &SilenceStore{
+		path:     filepath.Join(dir, "silences.json"),
+		silences: make(map[string]*Silence),
+	}
+
+	data, err := os.ReadFile(store.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return store, nil
+		}
+		return nil, fmt.Errorf("read silences: %w", err)
+	}
+
+	var silences []*Silence
+	if err := json.Unmarshal(data, &silences); err != nil {
+		return nil, fmt.Errorf("parse silences: %w", err)
+	}
+	for _, s := range silences {
+		store.silences[s.Node] = s
+	}
+
+	return store, nil
+}
+
+func (s *SilenceStore) Silence(node string, duration time.Duration, reason string) (*Silence, error) {
+	if duration <= 0 {
+		return nil, fmt.Errorf("silence duration must be positive")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now().UTC()
+	silence := &Silence{
+		Node:      node,
+		Reason:    reason,
+		CreatedAt: now,
+		ExpiresAt: now.Add(duration),
+	}
+	s.silences[node] = silence
+
+	if err := s.persist(); err != nil {
+		return nil, err
+	}
+	return silence, nil
+}
+
+func (s *SilenceStore) Unsilence(node string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.silences[node]; !ok {
+		return false, nil
+	}
+	delete(s.silences, node)
+	return true, s.persist()
+}
+
+func (s *SilenceStore) IsSilenced(node string) bool {
+	if s == nil {
+		return false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	silence, ok := s.silences[node]
+	return ok && time.Now().Before(silence.ExpiresAt)
+}
+
+func (s *SilenceStore) Active() []*Silence {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	expired := false
+	active := make([]*Silence, 0, len(s.silences))
+	for node, silence := range s.silences {
+		if !now.Before(silence.ExpiresAt) {
+			delete(s.silences, node)
+			expired = true
+			continue
+		}
+		active = append(active, silence)
+	}
+
+	if expired {
+		_ = s.persist()
+	}
+
+	sort.Slice(active, func(i, j int) bool {
+		return active[i].ExpiresAt.Before(active[j].ExpiresAt)
+	})
+
+	return active
+}
+
+func (s *SilenceStore) persist() error {
+	silences := make([]*Silence, 0, len(s.silences))
+	for _, silence := range s.silences {
+		silences = append(silences, silence)
+	}
+
+	data, err := json.MarshalIndent(silences, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode silences: %w", err)
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("write silences: %w", err)
+	}
+	return os.Rename(tmp, s.path)
+}
@@ -0,0 +1,64 @@
+package history
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// checkGolden compares v's JSON with testdata/name.golden.
+func checkGolden(t *testing.T, name string, v interface{}) {
+	t.Helper()
+	got, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got = append(got, '\n')
+	path := filepath.Join("testdata", name+".golden")
+	if *update {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("%s differs from %s:\n%s", name, path, got)
+	}
+}
+
+func TestStatsJSON(t *testing.T) {
+	h := NewHistoryManager(t.TempDir())
+	base := time.Date(2025, 11, 2, 3, 0, 0, 0, time.UTC)
+	saveTestCut(t, h, &CutRecord{Node: "athena", Action: "docker_restart", Success: true, Timestamp: base})
+	saveTestCut(t, h, &CutRecord{Node: "athena", Action: "docker_restart", Success: false, Timestamp: base.Add(45 * time.Minute)})
+	saveTestCut(t, h, &CutRecord{Node: "borg", Action: "restart_nginx", Success: true, Timestamp: base.Add(90*time.Minute + 500*time.Millisecond)})
+
+	stats, err := h.GetStats(Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.TotalDurationSeconds != 5400.5 || stats.TotalDurationHuman != "1h30m1s" {
+		t.Fatalf("duration = %v seconds, %q; want 5400.5 and 1h30m1s", stats.TotalDurationSeconds, stats.TotalDurationHuman)
+	}
+	checkGolden(t, "stats", stats)
+}
+
+func TestStatsJSONSingleCut(t *testing.T) {
+	h := NewHistoryManager(t.TempDir())
+	saveTestCut(t, h, &CutRecord{Node: "athena", Action: "docker_restart", Success: true, Timestamp: time.Date(2025, 11, 2, 3, 0, 0, 0, time.UTC)})
+
+	stats, err := h.GetStats(Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, "stats_single_cut", stats)
+}
@@ -0,0 +1,47 @@
+package history
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Sync forces the records written since the last Sync, the state files
+// (approvals, silences, notification state) and the directory itself to
+// disk.
+func (h *HistoryManager) Sync() error {
+	h.mu.Lock()
+	paths := make([]string, 0, len(h.unsynced))
+	for path := range h.unsynced {
+		paths = append(paths, path)
+	}
+	h.unsynced = nil
+	h.mu.Unlock()
+
+	state, err := filepath.Glob(h.joinPath("*.json"))
+	if err != nil {
+		return err
+	}
+	paths = append(paths, state...)
+
+	var errs []error
+	for _, path := range append(paths, h.historyDir) {
+		if err := syncPath(path); err != nil && !os.IsNotExist(err) {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func syncPath(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := f.Sync(); err != nil {
+		return fmt.Errorf("sync %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package history
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	MaxTags        = 16
+	MaxTagBuckets  = 100
+	OtherTagBucket = "_other"
+)
+
+var (
+	tagKeyPattern   = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,64}$`)
+	tagValuePattern = regexp.MustCompile(`^[A-Za-z0-9_.:/@-]{1,128}$`)
+)
+
+func ValidateTags(tags map[string]string) error {
+	if len(tags) > MaxTags {
+		return fmt.Errorf("too many tags: %d (max %d)", len(tags), MaxTags)
+	}
+	for key, value := range tags {
+		if !tagKeyPattern.MatchString(key) {
+			return fmt.Errorf("tag key %q: must be 1-64 characters of letters, digits, '_', '.', '-'", key)
+		}
+		if !tagValuePattern.MatchString(value) {
+			return fmt.Errorf("tag %q value %q: must be 1-128 characters of letters, digits, '_', '.', ':', '/', '@', '-'", key, value)
+		}
+	}
+	return nil
+}
+
+func ParseTagSelector(s string) (key, value string, err error) {
+	key, value, ok := strings.Cut(s, ":")
+	if !ok || key == "" {
+		return "", "", fmt.Errorf("tag selector %q: expected key:value", s)
+	}
+	return key, value, nil
+}
+
+func TagKey(key, value string) string {
+	return key + ":" + value
+}
+
+// CountTag adds one to the bucket for a tag, folding new values into a single
+// overflow bucket once MaxTagBuckets distinct tags are tracked.
+func CountTag(counts map[string]int, key, value string) {
+	bucket := TagKey(key, value)
+	if _, ok := counts[bucket]; !ok && len(counts) >= MaxTagBuckets {
+		bucket = OtherTagBucket
+	}
+	counts[bucket]++
+}
@@ -0,0 +1,29 @@
+package history
+
+import (
+	"fmt"
+	"time"
+)
+
+// Version identifies the contents of the store. The counter restarts with the
+// process, so the ETag also carries the time the manager was created.
+type Version struct {
+	Counter  uint64
+	Modified time.Time
+	epoch    int64
+}
+
+func (v Version) ETag() string {
+	return fmt.Sprintf(`W/"%x-%d"`, v.epoch, v.Counter)
+}
+
+func (h *HistoryManager) Version() Version {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.version
+}
+
+func (h *HistoryManager) bumpLocked() {
+	h.version.Counter++
+	h.version.Modified = time.Now().UTC()
+}
@@ -0,0 +1,80 @@
+package history
+
+import (
+	"testing"
+	"time"
+)
+
+func TestVersionBumpsOnEveryChange(t *testing.T) {
+	dir := t.TempDir()
+	h := NewHistoryManager(dir)
+	if err := h.SetQuota(Quota{MaxRecordsPerNode: 1}, nil); err != nil {
+		t.Fatal(err)
+	}
+	last := h.Version()
+	changed := func(what string) {
+		t.Helper()
+		v := h.Version()
+		if v.Counter <= last.Counter || v.ETag() == last.ETag() || v.Modified.Before(last.Modified) {
+			t.Fatalf("%s: version %+v did not move on from %+v", what, v, last)
+		}
+		last = v
+	}
+	unchanged := func(what string) {
+		t.Helper()
+		if v := h.Version(); v != last {
+			t.Fatalf("%s: version moved to %+v", what, v)
+		}
+	}
+
+	old := &CutRecord{Node: "athena", Action: "docker_restart", Timestamp: time.Now().UTC().AddDate(0, 0, -90)}
+	saveTestCut(t, h, old)
+	changed("SaveNewCut")
+	old.Outcome = "updated"
+	if err := h.SaveCut(old); err != nil {
+		t.Fatal(err)
+	}
+	changed("SaveCut")
+
+	if _, err := h.ListCuts(0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := h.GetStats(Filter{}); err != nil {
+		t.Fatal(err)
+	}
+	unchanged("reads")
+
+	saveTestCut(t, h, &CutRecord{Node: "athena", Action: "docker_restart", Timestamp: time.Now().UTC()})
+	changed("second save")
+	if removed, err := h.TrimToQuota(); err != nil || removed != 1 {
+		t.Fatalf("trim removed %d, %v", removed, err)
+	}
+	changed("TrimToQuota")
+	if removed, _ := h.TrimToQuota(); removed != 0 {
+		t.Fatalf("second trim removed %d", removed)
+	}
+	unchanged("a trim that removed nothing")
+
+	saveTestCut(t, h, &CutRecord{Node: "borg", Action: "docker_restart", Timestamp: time.Now().UTC().AddDate(0, 0, -90)})
+	changed("third save")
+	if purged, err := h.PurgeOldCuts(30); err != nil || purged != 1 {
+		t.Fatalf("purge removed %d, %v", purged, err)
+	}
+	changed("PurgeOldCuts")
+	if purged, _ := h.PurgeOldCuts(30); purged != 0 {
+		t.Fatalf("second purge removed %d", purged)
+	}
+	unchanged("a purge that removed nothing")
+}
+
+// The counter restarts with the process, so a restarted store must not
+// hand out an ETag a client cached before the restart.
+func TestVersionDiffersAcrossRestarts(t *testing.T) {
+	dir := t.TempDir()
+	first := NewHistoryManager(dir)
+	time.Sleep(time.Millisecond)
+	second := NewHistoryManager(dir)
+	if first.Version().ETag() == second.Version().ETag() {
+		t.Fatalf("restarted store reuses ETag %s", first.Version().ETag())
+	}
+}
@@ -0,0 +1,279 @@
+// Package metrics keeps Atropos's Prometheus metrics in one process-wide
+// registry and writes them in the text exposition format. Packages declare
+// their metrics as package variables and update them directly, the way they
+// call logger.Get().
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets suit cut and phase durations in seconds.
+var DefaultBuckets = []float64{0.005, 0.025, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+type metric interface {
+	name() string
+	write(w io.Writer)
+}
+
+var registry = struct {
+	metrics map[string]metric
+	mu      sync.Mutex
+}{metrics: make(map[string]metric)}
+
+// register adds m, replacing any metric of the same name, so a func metric
+// bound to a new executor takes over from the old one.
+func register(m metric) {
+	registry.mu.Lock()
+	registry.metrics[m.name()] = m
+	registry.mu.Unlock()
+}
+
+// Write renders every metric, sorted by name.
+func Write(w io.Writer) {
+	registry.mu.Lock()
+	all := make([]metric, 0, len(registry.metrics))
+	for _, m := range registry.metrics {
+		all = append(all, m)
+	}
+	registry.mu.Unlock()
+
+	sort.Slice(all, func(i, j int) bool { return all[i].name() < all[j].name() })
+	for _, m := range all {
+		m.write(w)
+	}
+}
+
+// series is a label-keyed set of values shared by counters and gauges.
+type series struct {
+	metricName string
+	help       string
+	kind       string
+	labels     []string
+	values     map[string]float64
+	mu         sync.Mutex
+}
+
+func newSeries(name, help, kind string, labels []string) *series {
+	s := &series{metricName: name, help: help, kind: kind, labels: labels, values: make(map[string]float64)}
+	// A metric without labels has exactly one series; show it from the start.
+	if len(labels) == 0 {
+		s.values[""] = 0
+	}
+	return s
+}
+
+func (s *series) name() string { return s.metricName }
+
+func (s *series) add(v float64, values []string) {
+	key := seriesKey(s.labels, values)
+	s.mu.Lock()
+	s.values[key] += v
+	s.mu.Unlock()
+}
+
+func (s *series) set(v float64, values []string) {
+	key := seriesKey(s.labels, values)
+	s.mu.Lock()
+	s.values[key] = v
+	s.mu.Unlock()
+}
+
+func (s *series) write(w io.Writer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	writeHeader(w, s.metricName, s.help, s.kind)
+	for _, key := range sortedKeys(s.values) {
+		fmt.Fprintf(w, "%s%s %s\n", s.metricName, key, formatValue(s.values[key]))
+	}
+}
+
+type Counter struct{ s *series }
+
+// NewCounter registers a counter with the given label names.
+func NewCounter(name, help string, labels ...string) *Counter {
+	c := &Counter{s: newSeries(name, help, "counter", labels)}
+	register(c.s)
+	return c
+}
+
+// Inc adds one to the series with the given label values, in label order.
+func (c *Counter) Inc(values ...string) { c.s.add(1, values) }
+
+type Gauge struct{ s *series }
+
+func NewGauge(name, help string, labels ...string) *Gauge {
+	g := &Gauge{s: newSeries(name, help, "gauge", labels)}
+	register(g.s)
+	return g
+}
+
+func (g *Gauge) Set(v float64, values ...string) { g.s.set(v, values) }
+func (g *Gauge) Add(v float64, values ...string) { g.s.add(v, values) }
+
+type Histogram struct {
+	metricName string
+	help       string
+	labels     []string
+	buckets    []float64
+	series     map[string]*histogramSeries
+	mu         sync.Mutex
+}
+
+type histogramSeries struct {
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// NewHistogram registers a histogram with the given upper bucket bounds,
+// which must be sorted; +Inf is implied.
+func NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	h := &Histogram{metricName: name, help: help, labels: labels, buckets: buckets, series: make(map[string]*histogramSeries)}
+	register(h)
+	return h
+}
+
+func (h *Histogram) name() string { return h.metricName }
+
+func (h *Histogram) Observe(v float64, values ...string) {
+	key := seriesKey(h.labels, values)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s := h.series[key]
+	if s == nil {
+		s = &histogramSeries{counts: make([]uint64, len(h.buckets))}
+		h.series[key] = s
+	}
+	for i, bound := range h.buckets {
+		if v <= bound {
+			s.counts[i]++
+		}
+	}
+	s.sum += v
+	s.count++
+}
+
+func (h *Histogram) write(w io.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	writeHeader(w, h.metricName, h.help, "histogram")
+	keys := make([]string, 0, len(h.series))
+	for key := range h.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		s := h.series[key]
+		for i, bound := range h.buckets {
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.metricName, withLabel(key, "le", formatValue(bound)), s.counts[i])
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.metricName, withLabel(key, "le", "+Inf"), s.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.metricName, key, formatValue(s.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.metricName, key, s.count)
+	}
+}
+
+// funcMetric reads its values at scrape time, keyed by the value of its one
+// label ("" when it has none).
+type funcMetric struct {
+	metricName string
+	help       string
+	kind       string
+	label      string
+	fn         func() map[string]float64
+}
+
+func (f *funcMetric) name() string { return f.metricName }
+
+func (f *funcMetric) write(w io.Writer) {
+	values := f.fn()
+	writeHeader(w, f.metricName, f.help, f.kind)
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		labels := ""
+		if f.label != "" {
+			labels = seriesKey([]string{f.label}, []string{key})
+		}
+		fmt.Fprintf(w, "%s%s %s\n", f.metricName, labels, formatValue(values[key]))
+	}
+}
+
+// GaugeFunc registers a gauge read from fn at scrape time. With a label, fn
+// returns a value per label value; without one, under the key "".
+func GaugeFunc(name, help, label string, fn func() map[string]float64) {
+	register(&funcMetric{metricName: name, help: help, kind: "gauge", label: label, fn: fn})
+}
+
+// CounterFunc is GaugeFunc for values that only grow.
+func CounterFunc(name, help, label string, fn func() map[string]float64) {
+	register(&funcMetric{metricName: name, help: help, kind: "counter", label: label, fn: fn})
+}
+
+func writeHeader(w io.Writer, name, help, kind string) {
+	fmt.Fprintf(w, "# HELP %s %s\n", name, strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", name, kind)
+}
+
+// seriesKey renders label pairs as they appear in the output, e.g.
+// {node="web",action="noop"}. Missing values are empty.
+func seriesKey(labels, values []string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, label := range labels {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		value := ""
+		if i < len(values) {
+			value = values[i]
+		}
+		b.WriteString(label)
+		b.WriteString(`="`)
+		b.WriteString(escapeLabel(value))
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+func withLabel(key, label, value string) string {
+	pair := label + `="` + value + `"`
+	if key == "" {
+		return "{" + pair + "}"
+	}
+	return key[:len(key)-1] + "," + pair + "}"
+}
+
+func escapeLabel(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
+
+func formatValue(v float64) string {
+	if math.IsInf(v, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func sortedKeys(m map[string]float64) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,18 @@
+package timefmt
+
+import "time"
+
+func RFC3339(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+func Now() string {
+	return RFC3339(time.Now())
+}
+
+func Human(d time.Duration) string {
+	if d < time.Second {
+		return d.Round(time.Millisecond).String()
+	}
+	return d.Round(time.Second).String()
+}
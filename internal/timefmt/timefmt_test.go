@@ -0,0 +1,66 @@
+package timefmt
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// The tests run with a local zone half an hour off a whole-hour offset, so
+// a local time formatted as UTC can't pass by accident.
+func TestMain(m *testing.M) {
+	os.Setenv("TZ", "Australia/Adelaide")
+	time.Local = time.FixedZone("ACST", 9*3600+30*60)
+	os.Exit(m.Run())
+}
+
+func TestRFC3339(t *testing.T) {
+	for _, tc := range []struct {
+		in   time.Time
+		want string
+	}{
+		{time.Date(2025, 11, 2, 3, 14, 0, 0, time.UTC), "2025-11-02T03:14:00Z"},
+		{time.Date(2025, 11, 2, 12, 44, 0, 0, time.Local), "2025-11-02T03:14:00Z"},
+		{time.Date(2025, 11, 1, 20, 14, 0, 0, time.FixedZone("PDT", -7*3600)), "2025-11-02T03:14:00Z"},
+		// Sub-second precision is dropped, not rounded.
+		{time.Date(2025, 11, 2, 3, 14, 0, 999_000_000, time.UTC), "2025-11-02T03:14:00Z"},
+	} {
+		if got := RFC3339(tc.in); got != tc.want {
+			t.Errorf("RFC3339(%v) = %s, want %s", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestNow(t *testing.T) {
+	before := time.Now().Truncate(time.Second)
+	got := Now()
+	after := time.Now()
+	if !strings.HasSuffix(got, "Z") {
+		t.Fatalf("Now() = %s, want UTC", got)
+	}
+	ts, err := time.Parse(time.RFC3339, got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ts.Before(before) || ts.After(after) {
+		t.Fatalf("Now() = %s, want between %s and %s", got, before.UTC(), after.UTC())
+	}
+}
+
+func TestHuman(t *testing.T) {
+	for _, tc := range []struct {
+		in   time.Duration
+		want string
+	}{
+		{0, "0s"},
+		{1500 * time.Microsecond, "2ms"},
+		{999 * time.Millisecond, "999ms"},
+		{1499 * time.Millisecond, "1s"},
+		{90*time.Minute + 30*time.Second + 600*time.Millisecond, "1h30m31s"},
+	} {
+		if got := Human(tc.in); got != tc.want {
+			t.Errorf("Human(%v) = %s, want %s", tc.in, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+//go:build integration
+
+package lab
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"atropos/notifications"
+)
+
+// FakeCutter handles every action starting with "fake_". Actions listed in
+// Fail return an error; every call is recorded.
+type FakeCutter struct {
+	Fail  map[string]error
+	Delay time.Duration
+
+	calls []FakeCall
+	mu    sync.Mutex
+}
+
+type FakeCall struct {
+	Target string
+	Action string
+	Params map[string]string
+}
+
+func NewFakeCutter() *FakeCutter {
+	return &FakeCutter{Fail: make(map[string]error)}
+}
+
+func (f *FakeCutter) Name() string { return "fake" }
+
+func (f *FakeCutter) CanHandle(action string) bool {
+	return strings.HasPrefix(action, "fake_")
+}
+
+func (f *FakeCutter) Execute(ctx context.Context, target string, params map[string]string) error {
+	action := params["action"]
+	f.mu.Lock()
+	f.calls = append(f.calls, FakeCall{Target: target, Action: action, Params: params})
+	err := f.Fail[action]
+	delay := f.Delay
+	f.mu.Unlock()
+
+	if delay > 0 {
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return err
+}
+
+// SetFailure makes action fail with err, or succeed again when err is nil.
+func (f *FakeCutter) SetFailure(action string, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err == nil {
+		delete(f.Fail, action)
+		return
+	}
+	f.Fail[action] = err
+}
+
+func (f *FakeCutter) Calls() []FakeCall {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]FakeCall(nil), f.calls...)
+}
+
+var ErrFake = errors.New("fake cutter failure")
+
+// Receiver collects notification webhooks.
+type Receiver struct {
+	URL string
+
+	server *httptest.Server
+	events []notifications.CutEvent
+	mu     sync.Mutex
+}
+
+func NewReceiver() *Receiver {
+	r := &Receiver{}
+	r.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		var event notifications.CutEvent
+		if err := json.Unmarshal(body, &event); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		r.mu.Lock()
+		r.events = append(r.events, event)
+		r.mu.Unlock()
+	}))
+	r.URL = r.server.URL
+	return r
+}
+
+func (r *Receiver) Close() {
+	r.server.Close()
+}
+
+func (r *Receiver) Events() []notifications.CutEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]notifications.CutEvent(nil), r.events...)
+}
+
+// WaitForEvents polls until at least n notifications arrived.
+func (r *Receiver) WaitForEvents(n int, timeout time.Duration) []notifications.CutEvent {
+	deadline := time.Now().Add(timeout)
+	for {
+		events := r.Events()
+		if len(events) >= n || time.Now().After(deadline) {
+			return events
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
@@ -0,0 +1,167 @@
+//go:build integration
+
+// Package lab runs a complete Atropos in-process for end-to-end tests: real
+// policy loading, HMAC webhook, executor, history store, and notifications,
+// with a fake cutter in place of Docker, VirtualBox, and SSH. Build with
+// -tags integration.
+package lab
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"time"
+
+	"atropos/api"
+	"atropos/client"
+	"atropos/engine"
+	"atropos/history"
+	"atropos/notifications"
+	"atropos/policy"
+)
+
+// Secret is the HMAC secret Start writes into the policy when it has none.
+const Secret = "lab-secret"
+
+type Lab struct {
+	URL      string
+	Dir      string
+	Policy   *policy.RemediationPolicy
+	Executor *engine.Executor
+	Cutter   *FakeCutter
+
+	server *httptest.Server
+}
+
+// Start loads policyYAML into a fresh temporary directory and serves it. Use
+// {{NOTIFY_URL}} in the YAML to point a notification webhook at receiver.
+func Start(policyYAML string, receiver *Receiver) (*Lab, error) {
+	dir, err := os.MkdirTemp("", "atropos-lab-")
+	if err != nil {
+		return nil, fmt.Errorf("lab dir: %w", err)
+	}
+	if receiver != nil {
+		policyYAML = string(bytes.ReplaceAll([]byte(policyYAML), []byte("{{NOTIFY_URL}}"), []byte(receiver.URL)))
+	}
+
+	path := filepath.Join(dir, "policy.yaml")
+	if err := os.WriteFile(path, []byte(policyYAML), 0o600); err != nil {
+		return nil, fmt.Errorf("write policy: %w", err)
+	}
+	pol, err := policy.LoadPolicy(path)
+	if err != nil {
+		return nil, err
+	}
+	if pol.Server.HMACSecret == "" && pol.Server.HMACSecretFile == "" && len(pol.Server.HMACKeys) == 0 {
+		pol.Server.HMACSecret = Secret
+	}
+
+	historyDir := filepath.Join(dir, "history")
+	notifConfig := &notifications.NotificationConfig{Enabled: false}
+	if pol.Notifications != nil {
+		cfg := *pol.Notifications
+		notifConfig = &cfg
+	}
+	notifConfig.StateFile = filepath.Join(historyDir, "notification_state.json")
+
+	exec := engine.NewExecutor(pol, history.NewHistoryManager(historyDir), notifications.NewNotificationManager(notifConfig))
+	fake := NewFakeCutter()
+	exec.RegisterCutter(fake)
+
+	server := httptest.NewServer(api.NewServer(exec))
+	return &Lab{
+		URL:      server.URL,
+		Dir:      dir,
+		Policy:   pol,
+		Executor: exec,
+		Cutter:   fake,
+		server:   server,
+	}, nil
+}
+
+func (l *Lab) Close() {
+	l.server.Close()
+	os.RemoveAll(l.Dir)
+}
+
+// SignRequest returns the X-Lachesis-Signature value for body.
+func SignRequest(secret string, body []byte) string {
+	return client.Sign(secret, body)
+}
+
+// Post sends a signed JSON request and decodes the response into out, if
+// given. It returns the status code.
+func (l *Lab) Post(path string, body interface{}, out interface{}) (int, error) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequest(http.MethodPost, l.URL+path, bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Lachesis-Signature", SignRequest(l.Policy.GetHMACSecret(), payload))
+	return l.do(req, out)
+}
+
+func (l *Lab) Get(path string, out interface{}) (int, error) {
+	req, err := http.NewRequest(http.MethodGet, l.URL+path, nil)
+	if err != nil {
+		return 0, err
+	}
+	return l.do(req, out)
+}
+
+func (l *Lab) do(req *http.Request, out interface{}) (int, error) {
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp.StatusCode, err
+	}
+	if out == nil {
+		return resp.StatusCode, nil
+	}
+	if raw, ok := out.(*[]byte); ok {
+		*raw = data
+		return resp.StatusCode, nil
+	}
+	return resp.StatusCode, json.Unmarshal(data, out)
+}
+
+// Cut posts a signed cut request.
+func (l *Lab) Cut(node string, entropy float64) (api.CutResponse, int, error) {
+	var resp api.CutResponse
+	status, err := l.Post("/api/v1/cut", api.CutRequest{Node: node, Entropy: entropy}, &resp)
+	return resp, status, err
+}
+
+// WaitForCut polls the history store until a record for node matching match
+// appears, or the timeout passes. A nil match accepts any record.
+func (l *Lab) WaitForCut(node string, timeout time.Duration, match func(*history.CutRecord) bool) (*history.CutRecord, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		cuts, err := l.Executor.GetHistory().ListCutsByNode(node, 0)
+		if err != nil {
+			return nil, err
+		}
+		for _, cut := range cuts {
+			if match == nil || match(cut) {
+				return cut, nil
+			}
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("no matching cut for %s after %s", node, timeout)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
@@ -0,0 +1,187 @@
+//go:build integration
+
+package lab
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"atropos/api"
+	"atropos/history"
+	"atropos/trends"
+)
+
+const labPolicy = `
+notifications:
+  enabled: true
+  webhook:
+    url: "{{NOTIFY_URL}}"
+nodes:
+  web:
+    strategies:
+      - threshold: 0.5
+        action: fake_restart
+  db:
+    strategies:
+      - threshold: 0.5
+        action: fake_failover
+`
+
+func startLab(t *testing.T, policyYAML string) (*Lab, *Receiver) {
+	t.Helper()
+	rcv := NewReceiver()
+	t.Cleanup(rcv.Close)
+	l, err := Start(policyYAML, rcv)
+	if err != nil {
+		t.Fatalf("start lab: %v", err)
+	}
+	t.Cleanup(l.Close)
+	return l, rcv
+}
+
+func TestSignedCutEndToEnd(t *testing.T) {
+	l, rcv := startLab(t, labPolicy)
+
+	resp, status, err := l.Cut("web", 0.8)
+	if err != nil || status != http.StatusOK {
+		t.Fatalf("cut: status %d, err %v", status, err)
+	}
+	if !resp.Success || resp.Action != "fake_restart" {
+		t.Fatalf("cut response = %+v", resp)
+	}
+
+	rec, err := l.WaitForCut("web", 2*time.Second, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.ID != resp.CutID || !rec.Success || rec.Entropy != 0.8 {
+		t.Fatalf("history record = %+v, want cut %s", rec, resp.CutID)
+	}
+	if calls := l.Cutter.Calls(); len(calls) != 1 || calls[0].Target != "web" {
+		t.Fatalf("fake cutter calls = %+v", calls)
+	}
+
+	var trend trends.GlobalTrend
+	if status, err := l.Get("/api/v1/trends?days=1", &trend); err != nil || status != http.StatusOK {
+		t.Fatalf("trends: status %d, err %v", status, err)
+	}
+	if trend.TotalCuts != 1 || trend.ByNode["web"] != 1 || trend.ByAction["fake_restart"] != 1 {
+		t.Fatalf("trends = %+v", trend)
+	}
+
+	var export struct {
+		TotalCuts int                  `json:"total_cuts"`
+		Cuts      []*history.CutRecord `json:"cuts"`
+	}
+	if status, err := l.Get("/api/v1/export/history.json", &export); err != nil || status != http.StatusOK {
+		t.Fatalf("export: status %d, err %v", status, err)
+	}
+	if export.TotalCuts != 1 || export.Cuts[0].ID != resp.CutID {
+		t.Fatalf("export = %+v", export)
+	}
+
+	events := rcv.WaitForEvents(1, 2*time.Second)
+	if len(events) != 1 {
+		t.Fatalf("got %d notifications, want 1", len(events))
+	}
+	if events[0].ID != resp.CutID || events[0].Node != "web" || !events[0].Success {
+		t.Fatalf("notification = %+v", events[0])
+	}
+}
+
+func TestFailedCutEndToEnd(t *testing.T) {
+	l, rcv := startLab(t, labPolicy)
+	l.Cutter.SetFailure("fake_failover", ErrFake)
+
+	resp, _, err := l.Cut("db", 0.9)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Success || !strings.Contains(resp.Error, ErrFake.Error()) {
+		t.Fatalf("cut response = %+v, want the fake failure", resp)
+	}
+
+	rec, err := l.WaitForCut("db", 2*time.Second, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.Success {
+		t.Fatalf("history record = %+v, want a failure", rec)
+	}
+
+	var stats api.StatsResponse
+	if status, err := l.Get("/api/v1/stats", &stats); err != nil || status != http.StatusOK {
+		t.Fatalf("stats: status %d, err %v", status, err)
+	}
+	if stats.TotalCuts != 1 || stats.FailedCuts != 1 {
+		t.Fatalf("stats = %+v", stats)
+	}
+
+	events := rcv.WaitForEvents(1, 2*time.Second)
+	if len(events) != 1 || events[0].Success || events[0].Error == "" {
+		t.Fatalf("notifications = %+v, want one failure", events)
+	}
+}
+
+func TestUnsignedCutRejected(t *testing.T) {
+	l, rcv := startLab(t, labPolicy)
+
+	body, _ := json.Marshal(api.CutRequest{Node: "web", Entropy: 0.8})
+	for name, sig := range map[string]string{
+		"unsigned":     "",
+		"wrong secret": SignRequest("not-the-secret", body),
+	} {
+		req, err := http.NewRequest(http.MethodPost, l.URL+"/api/v1/cut", bytes.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sig != "" {
+			req.Header.Set("X-Lachesis-Signature", sig)
+		}
+		status, err := l.do(req, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if status != http.StatusUnauthorized && status != http.StatusForbidden {
+			t.Errorf("%s: status %d, want 401 or 403", name, status)
+		}
+	}
+
+	if calls := l.Cutter.Calls(); len(calls) != 0 {
+		t.Fatalf("rejected requests reached the cutter: %+v", calls)
+	}
+	if cuts, _ := l.Executor.GetHistory().ListCuts(0); len(cuts) != 0 {
+		t.Fatalf("rejected requests were recorded: %d cuts", len(cuts))
+	}
+	if events := rcv.WaitForEvents(1, 200*time.Millisecond); len(events) != 0 {
+		t.Fatalf("rejected requests notified: %+v", events)
+	}
+}
+
+// TestDockerVictim kills the lab-victim container of docker-compose.yml.
+// It runs only when DOCKER_HOST points at the lab's daemon.
+func TestDockerVictim(t *testing.T) {
+	if os.Getenv("DOCKER_HOST") != "tcp://127.0.0.1:23750" {
+		t.Skip("DOCKER_HOST is not the lab daemon; see lab/docker-compose.yml")
+	}
+	l, _ := startLab(t, `
+nodes:
+  lab-victim:
+    strategies:
+      - threshold: 0.5
+        action: docker_kill_all
+`)
+
+	resp, _, err := l.Cut("lab-victim", 0.9)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.Success {
+		t.Fatalf("cut response = %+v", resp)
+	}
+}
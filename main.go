@@ -1,18 +1,26 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
+	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"slices"
 	"syscall"
+	"time"
 
 	"go.uber.org/zap"
 
 	"atropos/api"
+	"atropos/cutter"
 	"atropos/engine"
+	"atropos/export"
 	"atropos/history"
 	"atropos/internal/logger"
+	"atropos/internal/timefmt"
 	"atropos/notifications"
 	"atropos/policy"
 )
@@ -20,8 +28,18 @@ import (
 func main() {
 	policyPath := flag.String("policy", "atropos_policy.yaml", "Path to policy file")
 	historyDir := flag.String("history-dir", "cut_history", "Directory for cut history")
+	lint := flag.Bool("lint", false, "Lint the policy file and exit")
+	verifyBundle := flag.String("verify-export", "", "Verify a signed export bundle against the policy's signing key and exit")
 	flag.Parse()
 
+	if *lint {
+		os.Exit(lintPolicy(*policyPath))
+	}
+
+	if *verifyBundle != "" {
+		os.Exit(verifyExport(*policyPath, *verifyBundle))
+	}
+
 	log := logger.Get()
 	log.Info("ATROPOS_INIT", zap.String("policy_file", *policyPath))
 
@@ -34,38 +52,287 @@ func main() {
 
 	historyMgr := history.NewHistoryManager(*historyDir)
 	log.Info("HISTORY_MANAGER_INIT", zap.String("history_dir", *historyDir))
+	if failed, err := historyMgr.FailInProgress("interrupted by restart"); err != nil {
+		log.Warn("HISTORY_IN_PROGRESS_CHECK_FAILED", zap.Error(err))
+	} else if failed > 0 {
+		log.Warn("IN_PROGRESS_CUTS_FAILED", zap.Int("count", failed))
+	}
 
-	var notifConfig *notifications.NotificationConfig
-	if notifPath := os.Getenv("ATROPOS_NOTIFICATIONS_CONFIG"); notifPath != "" {
-		var err error
-		notifConfig, err = notifications.LoadNotificationConfig(notifPath)
-		if err != nil {
-			log.Warn("NOTIFICATION_CONFIG_LOAD_FAILED", zap.Error(err))
-			notifConfig = &notifications.NotificationConfig{Enabled: false}
+	notifMgr := buildNotifications(pol, *historyDir)
+
+	exec := engine.NewExecutor(pol, historyMgr, notifMgr)
+	for _, plugin := range pol.Plugins {
+		log.Info("PLUGIN_REGISTERED",
+			zap.String("plugin", plugin.Name),
+			zap.String("path", plugin.Path),
+			zap.String("action_prefix", plugin.ActionPrefix),
+		)
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			log.Error("ATROPOS_PANIC", zap.Any("panic", r), zap.Stack("stack"))
+			shutdown(context.Background(), exec, fmt.Sprintf("panic: %v", r))
+			os.Exit(2)
 		}
-	} else {
-		notifConfig = &notifications.NotificationConfig{Enabled: false}
+	}()
+
+	quota := historyQuota(pol)
+	if err := historyMgr.SetQuota(quota, exec.HistoryQuotaExceeded); err != nil {
+		log.Warn("HISTORY_QUOTA_INIT_FAILED", zap.Error(err))
 	}
-	notifMgr := notifications.NewNotificationManager(notifConfig)
-	log.Info("NOTIFICATION_MANAGER_INIT", zap.Bool("enabled", notifConfig.Enabled))
 
-	exec := engine.NewExecutor(pol, historyMgr, notifMgr)
-	server := api.NewServer(exec, pol.GetHMACSecret())
+	exec.StartHealthChecks(30 * time.Second)
+	for _, h := range exec.CutterHealth() {
+		if h.Status == engine.CutterUnhealthy && len(h.Actions) > 0 {
+			log.Warn("CUTTER_UNHEALTHY",
+				zap.String("cutter", h.Cutter),
+				zap.String("error", h.Error),
+				zap.Strings("affected_actions", h.Actions),
+			)
+		}
+	}
+	exec.StartStateGC(5 * time.Minute)
+	exec.StartApprovalSweeper(time.Minute)
+	exec.StartScheduler()
 
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	if days := pol.Server.HistoryRetentionDays; days > 0 || quota.MaxRecordsPerNode > 0 || len(quota.Nodes) > 0 {
+		go purgeHistory(historyMgr, days)
+	}
+	server := api.NewServer(exec)
+
+	exec.SetReloader(func() (*policy.RemediationPolicy, error) {
+		newPol, err := policy.LoadPolicy(*policyPath)
+		if err != nil {
+			log.Error("POLICY_RELOAD_FAILED", zap.Error(err))
+			return nil, err
+		}
+		if !slices.Equal(newPol.EnabledCutters(), pol.EnabledCutters()) || !slices.Equal(newPol.Plugins, pol.Plugins) {
+			log.Warn("CUTTERS_CHANGED_RESTART_REQUIRED")
+		}
+		exec.SetPolicy(newPol)
+		exec.SetNotifications(buildNotifications(newPol, *historyDir))
+		log.Info("POLICY_RELOADED",
+			zap.Int("node_count", len(newPol.Nodes)),
+			zap.String("policy_hash", newPol.Hash()),
+		)
+		return newPol, nil
+	})
 
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
 	go func() {
-		<-quit
-		log.Info("ATROPOS_SHUTDOWN")
-		os.Exit(0)
+		for range reload {
+			exec.ReloadPolicy()
+		}
 	}()
 
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+
 	addr := pol.GetListenAddr()
-	log.Info("ATROPOS_ONLINE", zap.String("listen_addr", addr))
+	srv := &http.Server{Addr: addr, Handler: server}
+	serveErr := make(chan error, 1)
+
+	if pol.Server.TLS != nil {
+		tlsConfig, err := api.BuildTLSConfig(pol.Server.TLS)
+		if err != nil {
+			log.Error("TLS_CONFIG_FAILED", zap.Error(err))
+			shutdown(context.Background(), exec, "tls config: "+err.Error())
+			os.Exit(1)
+		}
+		srv.TLSConfig = tlsConfig
+
+		log.Info("ATROPOS_ONLINE",
+			zap.String("listen_addr", addr),
+			zap.Bool("tls", true),
+			zap.Bool("client_auth", tlsConfig.ClientCAs != nil),
+		)
+		go func() { serveErr <- srv.ListenAndServeTLS("", "") }()
+	} else {
+		log.Info("ATROPOS_ONLINE", zap.String("listen_addr", addr))
+		go func() { serveErr <- srv.ListenAndServe() }()
+	}
 
-	if err := server.Run(addr); err != nil {
+	var reason string
+	code := 0
+	select {
+	case sig := <-quit:
+		reason = "signal: " + sig.String()
+	case err := <-serveErr:
 		fmt.Fprintf(os.Stderr, "server error: %v\n", err)
-		os.Exit(1)
+		reason, code = "server error: "+err.Error(), 1
+	}
+
+	// Stop taking requests before draining, so nothing new starts. Both
+	// steps share the shutdown timeout.
+	ctx, cancel := context.WithTimeout(context.Background(), exec.GetPolicy().GetShutdownTimeout())
+	if err := srv.Shutdown(ctx); err != nil {
+		log.Warn("HTTP_SHUTDOWN_INCOMPLETE", zap.Error(err))
+	}
+	shutdown(ctx, exec, reason)
+	cancel()
+	os.Exit(code)
+}
+
+// shutdown drains the executor, flushes history, and writes the run summary
+// to the audit log. Without a deadline on ctx it waits up to
+// shutdown_timeout_seconds.
+func shutdown(ctx context.Context, exec *engine.Executor, reason string) {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, exec.GetPolicy().GetShutdownTimeout())
+		defer cancel()
+	}
+	report := exec.Shutdown(ctx, reason)
+
+	logger.Get().Info("ATROPOS_SHUTDOWN",
+		zap.String("reason", report.Reason),
+		zap.String("uptime", report.UptimeHuman),
+		zap.Float64("uptime_seconds", report.UptimeSeconds),
+		zap.Int("cuts", report.Cuts),
+		zap.Any("cuts_by_outcome", report.CutsByOutcome),
+		zap.Int("approvals_abandoned", report.ApprovalsAbandoned),
+		zap.Int("jobs_abandoned", report.JobsAbandoned),
+		zap.Int("notification_queue", report.NotificationQueue),
+		zap.Int("callback_queue", report.CallbackQueue),
+		zap.Bool("drained", report.Drained),
+		zap.String("flush_error", report.FlushError),
+	)
+	logger.Get().Sync()
+}
+
+func purgeHistory(historyMgr *history.HistoryManager, retentionDays int) {
+	log := logger.Get()
+	ticker := time.NewTicker(24 * time.Hour)
+	defer ticker.Stop()
+
+	for {
+		if retentionDays > 0 {
+			purged, err := historyMgr.PurgeOldCuts(retentionDays)
+			if err != nil {
+				log.Error("HISTORY_PURGE_FAILED", zap.Error(err))
+			} else {
+				log.Info("HISTORY_PURGED",
+					zap.Int("removed", purged),
+					zap.Int("retention_days", retentionDays),
+				)
+			}
+		}
+
+		trimmed, err := historyMgr.TrimToQuota()
+		if err != nil {
+			log.Error("HISTORY_TRIM_FAILED", zap.Error(err))
+		} else if trimmed > 0 {
+			log.Info("HISTORY_TRIMMED", zap.Int("removed", trimmed))
+		}
+
+		<-ticker.C
+	}
+}
+
+func historyQuota(pol *policy.RemediationPolicy) history.Quota {
+	var quota history.Quota
+	if pol.Server.HistoryQuota != nil {
+		quota.MaxRecordsPerNode = pol.Server.HistoryQuota.MaxRecordsPerNode
+	}
+	for name, node := range pol.Nodes {
+		if node.MaxHistoryRecords > 0 {
+			if quota.Nodes == nil {
+				quota.Nodes = make(map[string]int)
+			}
+			quota.Nodes[name] = node.MaxHistoryRecords
+		}
+	}
+	return quota
+}
+
+// buildNotifications prefers the policy's notifications section and falls
+// back to the file named by ATROPOS_NOTIFICATIONS_CONFIG.
+func buildNotifications(pol *policy.RemediationPolicy, historyDir string) *notifications.NotificationManager {
+	log := logger.Get()
+
+	notifConfig := &notifications.NotificationConfig{Enabled: false}
+	source := "none"
+	if pol.Notifications != nil {
+		cfg := *pol.Notifications
+		notifConfig = &cfg
+		source = "policy"
+	} else if notifPath := os.Getenv("ATROPOS_NOTIFICATIONS_CONFIG"); notifPath != "" {
+		cfg, err := notifications.LoadNotificationConfig(notifPath)
+		if err != nil {
+			log.Warn("NOTIFICATION_CONFIG_LOAD_FAILED", zap.Error(err))
+		} else {
+			notifConfig = cfg
+			source = notifPath
+		}
+	}
+	if notifConfig.StateFile == "" {
+		notifConfig.StateFile = filepath.Join(historyDir, "notification_state.json")
+	}
+
+	log.Info("NOTIFICATION_MANAGER_INIT",
+		zap.Bool("enabled", notifConfig.Enabled),
+		zap.String("source", source),
+	)
+	return notifications.NewNotificationManager(notifConfig)
+}
+
+func lintPolicy(path string) int {
+	pol, err := policy.LoadPolicy(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+		return 2
+	}
+
+	registry := cutter.NewRegistry(pol.EnabledCutters()...)
+	for _, plugin := range pol.Plugins {
+		registry.Register(cutter.NewPluginCutter(plugin.Name, plugin.Path, plugin.ActionPrefix))
+	}
+	warnings := pol.Lint(registry.Supports)
+
+	for _, w := range warnings {
+		fmt.Println(w.String())
+	}
+	if len(warnings) > 0 {
+		return 1
+	}
+
+	fmt.Printf("%s: no issues found\n", path)
+	return 0
+}
+
+func verifyExport(policyPath, bundlePath string) int {
+	pol, err := policy.LoadPolicy(policyPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", policyPath, err)
+		return 2
+	}
+	key := pol.GetExportSigningKey()
+	if key == "" {
+		fmt.Fprintf(os.Stderr, "%s: no export signing key configured\n", policyPath)
+		return 2
+	}
+
+	f, err := os.Open(bundlePath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 2
 	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 2
+	}
+
+	manifest, err := export.VerifyBundle(f, info.Size(), []byte(key))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: INVALID: %v\n", bundlePath, err)
+		return 1
+	}
+
+	fmt.Printf("%s: OK (%s, %d records, exported %s, policy %s)\n",
+		bundlePath, manifest.DataFile, manifest.RecordCount,
+		timefmt.RFC3339(manifest.ExportedAt), manifest.PolicyHash)
+	return 0
 }
@@ -0,0 +1,111 @@
+package notifications
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"atropos/history"
+)
+
+type suppressionState struct {
+	path    string
+	window  time.Duration
+	entries map[string]time.Time
+	mu      sync.Mutex
+}
+
+func newSuppressionState(path string, window time.Duration) *suppressionState {
+	state := &suppressionState{
+		path:    path,
+		window:  window,
+		entries: make(map[string]time.Time),
+	}
+
+	if path == "" {
+		return state
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return state
+	}
+
+	var entries map[string]time.Time
+	if err := json.Unmarshal(data, &entries); err == nil && entries != nil {
+		state.entries = entries
+	}
+	return state
+}
+
+func suppressionKey(event *CutEvent) string {
+	outcome := event.Outcome
+	if history.PlainOutcome(outcome) {
+		outcome = map[bool]string{true: "success", false: "failed"}[event.Success]
+	}
+	return event.Node + "|" + event.Action + "|" + outcome
+}
+
+// claim reports whether the event may be sent and, if so, records it before
+// delivery so a crash mid-send does not produce a duplicate after restart.
+// A failed delivery gives the claim back with release.
+func (s *suppressionState) claim(event *CutEvent, now time.Time) (bool, error) {
+	if s.window <= 0 {
+		return true, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := suppressionKey(event)
+	if last, ok := s.entries[key]; ok && now.Sub(last) < s.window {
+		return false, nil
+	}
+	s.entries[key] = now
+
+	for k, t := range s.entries {
+		if now.Sub(t) >= s.window {
+			delete(s.entries, k)
+		}
+	}
+
+	return true, s.persist()
+}
+
+// release gives back a claim made at claimedAt whose delivery failed, so the
+// next attempt at the event is sent rather than suppressed. A later claim of
+// the same key is left alone.
+func (s *suppressionState) release(event *CutEvent, claimedAt time.Time) error {
+	if s.window <= 0 {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := suppressionKey(event)
+	if last, ok := s.entries[key]; !ok || !last.Equal(claimedAt) {
+		return nil
+	}
+	delete(s.entries, key)
+	return s.persist()
+}
+
+func (s *suppressionState) persist() error {
+	if s.path == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(s.entries)
+	if err != nil {
+		return fmt.Errorf("encode notification state: %w", err)
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("write notification state: %w", err)
+	}
+	return os.Rename(tmp, s.path)
+}
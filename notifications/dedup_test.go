@@ -0,0 +1,188 @@
+package notifications
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func dedupConfig(sink *webhookSink, stateFile string) *NotificationConfig {
+	return &NotificationConfig{
+		Enabled:            true,
+		Webhook:            sink.config(),
+		DedupWindowSeconds: 3600,
+		MaxEventAgeSeconds: 600,
+		StateFile:          stateFile,
+	}
+}
+
+func TestSuppressionSurvivesRestart(t *testing.T) {
+	sink := newWebhookSink(t)
+	stateFile := filepath.Join(t.TempDir(), "notification_state.json")
+
+	first := NewNotificationManager(dedupConfig(sink, stateFile))
+	for i := 0; i < 2; i++ {
+		if err := first.NotifyCut(testEvent("athena", false)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := len(sink.received()); got != 1 {
+		t.Fatalf("first run posted %d events, want 1", got)
+	}
+	if _, err := os.Stat(stateFile); err != nil {
+		t.Fatalf("state file not written: %v", err)
+	}
+
+	// A crash loop restarts the process; the same failure stays quiet.
+	for restart := 0; restart < 3; restart++ {
+		nm := NewNotificationManager(dedupConfig(sink, stateFile))
+		if err := nm.NotifyCut(testEvent("athena", false)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := len(sink.received()); got != 1 {
+		t.Fatalf("after restarts %d events were posted, want still 1", got)
+	}
+
+	// Another outcome or node is a new event.
+	nm := NewNotificationManager(dedupConfig(sink, stateFile))
+	for _, event := range []*CutEvent{testEvent("athena", true), testEvent("borg", false)} {
+		if err := nm.NotifyCut(event); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := len(sink.received()); got != 3 {
+		t.Fatalf("posted %d events, want 3 once the recovery and borg's failure went out", got)
+	}
+}
+
+// A failed send doesn't claim the window: the retry goes out, in this run
+// or the next.
+func TestFailedSendNotSuppressed(t *testing.T) {
+	sink := newWebhookSink(t)
+	stateFile := filepath.Join(t.TempDir(), "notification_state.json")
+	sink.failing = 1
+
+	nm := NewNotificationManager(dedupConfig(sink, stateFile))
+	if err := nm.NotifyCut(testEvent("athena", false)); err == nil {
+		t.Fatal("first send succeeded against a failing webhook")
+	}
+	if err := nm.NotifyCut(testEvent("athena", false)); err != nil {
+		t.Fatalf("retry: %v", err)
+	}
+	if got := len(sink.received()); got != 1 {
+		t.Fatalf("posted %d events, want the retry", got)
+	}
+	if err := nm.NotifyCut(testEvent("athena", false)); err != nil || len(sink.received()) != 1 {
+		t.Fatalf("delivered event not suppressed: %v, %d posted", err, len(sink.received()))
+	}
+
+	sink.mu.Lock()
+	sink.failing = 1
+	sink.mu.Unlock()
+	if err := nm.NotifyCut(testEvent("borg", false)); err == nil {
+		t.Fatal("borg's send succeeded against a failing webhook")
+	}
+	restarted := NewNotificationManager(dedupConfig(sink, stateFile))
+	if err := restarted.NotifyCut(testEvent("borg", false)); err != nil {
+		t.Fatalf("retry after restart: %v", err)
+	}
+	if got := len(sink.received()); got != 2 {
+		t.Fatalf("posted %d events, want borg's retry after the restart", got)
+	}
+}
+
+func TestOldEventsSkippedAfterRestart(t *testing.T) {
+	sink := newWebhookSink(t)
+	nm := NewNotificationManager(dedupConfig(sink, filepath.Join(t.TempDir(), "state.json")))
+
+	stale := testEvent("athena", false)
+	stale.Timestamp = time.Now().Add(-time.Hour)
+	if err := nm.NotifyCut(stale); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(sink.received()); got != 0 {
+		t.Fatalf("%d events older than max_event_age_seconds were posted", got)
+	}
+
+	recent := testEvent("athena", false)
+	recent.Timestamp = time.Now().Add(-time.Minute)
+	if err := nm.NotifyCut(recent); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(sink.received()); got != 1 {
+		t.Fatalf("posted %d events, want the recent one", got)
+	}
+}
+
+func TestSuppressionWindowExpires(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), "state.json")
+	now := time.Date(2025, 11, 2, 3, 0, 0, 0, time.UTC)
+	s := newSuppressionState(stateFile, 10*time.Minute)
+
+	claim := func(event *CutEvent, at time.Time) bool {
+		t.Helper()
+		send, err := s.claim(event, at)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return send
+	}
+	if !claim(testEvent("athena", false), now) || !claim(testEvent("borg", false), now.Add(5*time.Minute)) {
+		t.Fatal("first events were suppressed")
+	}
+	if claim(testEvent("athena", false), now.Add(10*time.Minute-time.Second)) {
+		t.Fatal("athena's failure was sent again inside the window")
+	}
+	if !claim(testEvent("athena", false), now.Add(10*time.Minute)) {
+		t.Fatal("athena's failure was still suppressed once the window passed")
+	}
+
+	// Entries are pruned as they expire, so the file doesn't grow.
+	claim(testEvent("cassandra", false), now.Add(16*time.Minute))
+	data, err := os.ReadFile(stateFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entries map[string]time.Time
+	if err := json.Unmarshal(data, &entries); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := entries["borg|docker_restart|failed"]; ok || len(entries) != 2 {
+		t.Fatalf("state = %v, want athena and cassandra only", entries)
+	}
+
+	restarted := newSuppressionState(stateFile, 10*time.Minute)
+	if send, _ := restarted.claim(testEvent("cassandra", false), now.Add(17*time.Minute)); send {
+		t.Fatal("cassandra's failure was sent again after a restart")
+	}
+}
+
+func TestUnreadableStateStartsEmpty(t *testing.T) {
+	dir := t.TempDir()
+	corrupt := filepath.Join(dir, "corrupt.json")
+	if err := os.WriteFile(corrupt, []byte("{not json"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{corrupt, filepath.Join(dir, "missing.json")} {
+		s := newSuppressionState(path, time.Hour)
+		if send, err := s.claim(testEvent("athena", false), time.Now()); !send || err != nil {
+			t.Fatalf("%s: claim = %v, %v; want a fresh state", path, send, err)
+		}
+	}
+}
+
+func TestNoWindowNeverSuppresses(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), "state.json")
+	s := newSuppressionState(stateFile, 0)
+	for i := 0; i < 3; i++ {
+		if send, err := s.claim(testEvent("athena", false), time.Now()); !send || err != nil {
+			t.Fatalf("claim %d = %v, %v", i, send, err)
+		}
+	}
+	if _, err := os.Stat(stateFile); !os.IsNotExist(err) {
+		t.Fatalf("state file written without a window: %v", err)
+	}
+}
@@ -0,0 +1,6 @@
+package notifications
+
+import "atropos/internal/metrics"
+
+var notificationFailures = metrics.NewCounter("atropos_notification_failures_total",
+	"Cut notifications that could not be delivered.")
@@ -3,34 +3,117 @@ package notifications
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/smtp"
+	"net/url"
 	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	"gopkg.in/yaml.v3"
-	"time"
+
+	"atropos/history"
+	"atropos/internal/httpclient"
+	"atropos/internal/secretfile"
+	"atropos/internal/timefmt"
 )
 
 type NotificationConfig struct {
-	Enabled bool           `json:"enabled"`
-	Webhook *WebhookConfig `json:"webhook,omitempty"`
-	Email   *EmailConfig   `json:"email,omitempty"`
+	Enabled            bool           `json:"enabled" yaml:"enabled"`
+	Webhook            *WebhookConfig `json:"webhook,omitempty" yaml:"webhook,omitempty"`
+	Email              *EmailConfig   `json:"email,omitempty" yaml:"email,omitempty"`
+	DedupWindowSeconds int            `json:"dedup_window_seconds,omitempty" yaml:"dedup_window_seconds,omitempty"`
+	MaxEventAgeSeconds int            `json:"max_event_age_seconds,omitempty" yaml:"max_event_age_seconds,omitempty"`
+	StateFile          string         `json:"state_file,omitempty" yaml:"state_file,omitempty"`
+	NotifyOnShutdown   bool           `json:"notify_on_shutdown,omitempty" yaml:"notify_on_shutdown,omitempty"`
 }
 
 type WebhookConfig struct {
-	URL     string            `json:"url"`
-	Headers map[string]string `json:"headers"`
-	Retries int               `json:"retries"`
+	URL      string            `json:"url" yaml:"url"`
+	Headers  map[string]string `json:"headers" yaml:"headers"`
+	Retries  int               `json:"retries" yaml:"retries"`
+	ProxyURL string            `json:"proxy_url,omitempty" yaml:"proxy_url,omitempty"`
+}
+
+func (c *NotificationConfig) Validate() error {
+	if c == nil {
+		return nil
+	}
+	if c.DedupWindowSeconds < 0 || c.MaxEventAgeSeconds < 0 {
+		return fmt.Errorf("dedup_window_seconds and max_event_age_seconds must be >= 0")
+	}
+	if c.Webhook != nil {
+		u, err := url.Parse(c.Webhook.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("webhook: url must be an absolute http(s) URL")
+		}
+		if c.Webhook.Retries < 0 {
+			return fmt.Errorf("webhook: retries must be >= 0")
+		}
+		if err := c.Webhook.Validate(); err != nil {
+			return err
+		}
+	}
+	if c.Email != nil {
+		if c.Email.SMTPHost == "" {
+			return fmt.Errorf("email: smtp_host is required")
+		}
+		if c.Email.SMTPPort < 1 || c.Email.SMTPPort > 65535 {
+			return fmt.Errorf("email: smtp_port must be between 1 and 65535")
+		}
+		if c.Email.From == "" {
+			return fmt.Errorf("email: from is required")
+		}
+		if len(c.Email.To) == 0 {
+			return fmt.Errorf("email: at least one recipient is required")
+		}
+	}
+	return nil
+}
+
+func (c *WebhookConfig) Validate() error {
+	if c == nil || c.ProxyURL == "" {
+		return nil
+	}
+	if _, err := httpclient.ParseProxyURL(c.ProxyURL); err != nil {
+		return fmt.Errorf("webhook: %w", err)
+	}
+	return nil
 }
 
 type EmailConfig struct {
-	SMTPHost     string   `json:"smtp_host"`
-	SMTPPort     int      `json:"smtp_port"`
-	SMTPUser     string   `json:"smtp_user"`
-	SMTPPassword string   `json:"smtp_password"`
-	From         string   `json:"from"`
-	To           []string `json:"to"`
+	SMTPHost         string   `json:"smtp_host" yaml:"smtp_host"`
+	SMTPPort         int      `json:"smtp_port" yaml:"smtp_port"`
+	SMTPUser         string   `json:"smtp_user" yaml:"smtp_user"`
+	SMTPPassword     string   `json:"smtp_password" yaml:"smtp_password"`
+	SMTPPasswordFile string   `json:"smtp_password_file,omitempty" yaml:"smtp_password_file,omitempty"`
+	From             string   `json:"from" yaml:"from"`
+	To               []string `json:"to" yaml:"to"`
+
+	passwordFromFile string
+}
+
+func (c *EmailConfig) ResolveSecrets() error {
+	if c == nil || c.SMTPPasswordFile == "" {
+		return nil
+	}
+	password, err := secretfile.Read(c.SMTPPasswordFile)
+	if err != nil {
+		return fmt.Errorf("email: smtp_password_file: %w", err)
+	}
+	c.passwordFromFile = password
+	return nil
+}
+
+func (c *EmailConfig) password() string {
+	if c.passwordFromFile != "" {
+		return c.passwordFromFile
+	}
+	return c.SMTPPassword
 }
 
 type Notifier interface {
@@ -38,26 +121,57 @@ type Notifier interface {
 }
 
 type CutEvent struct {
-	ID        string                 `json:"id"`
-	Node      string                 `json:"node"`
-	Action    string                 `json:"action"`
-	Success   bool                   `json:"success"`
-	Entropy   float64                `json:"entropy"`
-	LatencyMs int64                  `json:"latency_ms"`
-	Error     string                 `json:"error,omitempty"`
-	Timestamp time.Time              `json:"timestamp"`
-	Metadata  map[string]interface{} `json:"metadata,omitempty"`
+	ID          string                 `json:"id"`
+	Node        string                 `json:"node"`
+	Action      string                 `json:"action"`
+	Success     bool                   `json:"success"`
+	Outcome     string                 `json:"outcome,omitempty"`
+	Trigger     string                 `json:"trigger,omitempty"`
+	Source      string                 `json:"source,omitempty"`
+	Reason      string                 `json:"reason,omitempty"`
+	DryRun      bool                   `json:"dry_run,omitempty"`
+	Entropy     float64                `json:"entropy"`
+	LatencyMs   int64                  `json:"latency_ms"`
+	Error       string                 `json:"error,omitempty"`
+	Timestamp   time.Time              `json:"timestamp"`
+	Description string                 `json:"description,omitempty"`
+	RunbookURL  string                 `json:"runbook_url,omitempty"`
+	Tags        map[string]string      `json:"tags,omitempty"`
+	Metadata    map[string]interface{} `json:"metadata,omitempty"`
 }
 
 type WebhookNotifier struct {
 	config *WebhookConfig
 	client *http.Client
+	err    error
+}
+
+// webhookClients holds one client per proxy_url, shared by every webhook
+// that goes through it.
+var webhookClients sync.Map
+
+func webhookClient(proxyURL string) (*http.Client, error) {
+	if client, ok := webhookClients.Load(proxyURL); ok {
+		return client.(*http.Client), nil
+	}
+	client, err := httpclient.New(30*time.Second, proxyURL)
+	if err != nil {
+		return nil, err
+	}
+	shared, _ := webhookClients.LoadOrStore(proxyURL, client)
+	return shared.(*http.Client), nil
 }
 
 func NewWebhookNotifier(config *WebhookConfig) *WebhookNotifier {
+	var proxyURL string
+	if config != nil {
+		proxyURL = config.ProxyURL
+	}
+	client, err := webhookClient(proxyURL)
 	return &WebhookNotifier{
 		config: config,
-		client: &http.Client{Timeout: 30 * time.Second},
+		client: client,
+		err:    err,
 	}
 }
 
@@ -65,46 +179,58 @@ func (wn *WebhookNotifier) Notify(event *CutEvent) error {
 	if wn.config == nil || wn.config.URL == "" {
 		return nil
 	}
+	if wn.err != nil {
+		return fmt.Errorf("webhook client: %w", wn.err)
+	}
 
 	payload, err := json.Marshal(event)
 	if err != nil {
 		return fmt.Errorf("marshal event: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", wn.config.URL, bytes.NewBuffer(payload))
+	attempts, err := PostJSON(wn.client, wn.config.URL, payload, wn.config.Headers, wn.config.Retries)
 	if err != nil {
-		return fmt.Errorf("create request: %w", err)
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	for key, value := range wn.config.Headers {
-		req.Header.Set(key, value)
+		return fmt.Errorf("webhook failed after %d retries: %w", attempts, err)
 	}
+	return nil
+}
 
-	retries := wn.config.Retries
+// PostJSON POSTs payload until a 2xx response, making up to retries attempts
+// (3 when zero) with a linearly growing pause between them. It returns the
+// number of attempts made.
+func PostJSON(client *http.Client, url string, payload []byte, headers map[string]string, retries int) (int, error) {
 	if retries == 0 {
 		retries = 3
 	}
 
 	var lastErr error
 	for i := 0; i < retries; i++ {
-		resp, err := wn.client.Do(req)
+		if i > 0 {
+			time.Sleep(time.Duration(i) * time.Second)
+		}
+
+		req, err := http.NewRequest("POST", url, bytes.NewReader(payload))
+		if err != nil {
+			return i + 1, fmt.Errorf("create request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		for key, value := range headers {
+			req.Header.Set(key, value)
+		}
+
+		resp, err := client.Do(req)
 		if err != nil {
 			lastErr = err
-			time.Sleep(time.Duration(i+1) * time.Second)
 			continue
 		}
 		resp.Body.Close()
 
 		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
-			return nil
+			return i + 1, nil
 		}
-
-		lastErr = fmt.Errorf("webhook returned status %d", resp.StatusCode)
-		time.Sleep(time.Duration(i+1) * time.Second)
+		lastErr = fmt.Errorf("returned status %d", resp.StatusCode)
 	}
-
-	return fmt.Errorf("webhook failed after %d retries: %w", retries, lastErr)
+	return retries, lastErr
 }
 
 type EmailNotifier struct {
@@ -122,9 +248,15 @@ func (en *EmailNotifier) Notify(event *CutEvent) error {
 		return nil
 	}
 
-	subject := fmt.Sprintf("[Atropos] Cut %s - %s",
-		map[bool]string{true: "Success", false: "Failed"}[event.Success],
-		event.Node)
+	status := map[bool]string{true: "SUCCESS", false: "FAILED"}[event.Success]
+	if !history.PlainOutcome(event.Outcome) {
+		status = strings.ToUpper(event.Outcome)
+	}
+	if event.DryRun {
+		status += " (DRY RUN)"
+	}
+
+	subject := fmt.Sprintf("[Atropos] Cut %s - %s", status, event.Node)
 
 	body := fmt.Sprintf(`
 Atropos Cut Notification
@@ -135,16 +267,38 @@ Status: %s
 Entropy: %.4f
 Latency: %dms
 Timestamp: %s
-`, event.Node, event.Action,
-		map[bool]string{true: "SUCCESS", false: "FAILED"}[event.Success],
+`, event.Node, event.Action, status,
 		event.Entropy, event.LatencyMs,
-		event.Timestamp.Format(time.RFC3339))
+		timefmt.RFC3339(event.Timestamp))
 
+	if event.Trigger != "" {
+		body += fmt.Sprintf("Trigger: %s\n", event.Trigger)
+	}
+	if event.Source != "" {
+		body += fmt.Sprintf("Source: %s\n", event.Source)
+	}
+	if event.Reason != "" {
+		body += fmt.Sprintf("Reason: %s\n", event.Reason)
+	}
 	if !event.Success && event.Error != "" {
 		body += fmt.Sprintf("\nError: %s\n", event.Error)
 	}
+	if event.Description != "" {
+		body += fmt.Sprintf("\nDescription: %s\n", event.Description)
+	}
+	if event.RunbookURL != "" {
+		body += fmt.Sprintf("Runbook: %s\n", event.RunbookURL)
+	}
+	if len(event.Tags) > 0 {
+		tags := make([]string, 0, len(event.Tags))
+		for key, value := range event.Tags {
+			tags = append(tags, key+":"+value)
+		}
+		sort.Strings(tags)
+		body += fmt.Sprintf("Tags: %s\n", strings.Join(tags, ", "))
+	}
 
-	auth := smtp.PlainAuth("", en.config.SMTPUser, en.config.SMTPPassword, "")
+	auth := smtp.PlainAuth("", en.config.SMTPUser, en.config.password(), "")
 
 	msg := fmt.Sprintf("From: %s\r\nSubject: %s\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\n%s",
 		en.config.From, subject, body)
@@ -189,8 +343,11 @@ func (cn *CompositeNotifier) Notify(event *CutEvent) error {
 }
 
 type NotificationManager struct {
-	config   *NotificationConfig
-	notifier Notifier
+	config      *NotificationConfig
+	notifier    Notifier
+	suppression *suppressionState
+	lastErr     error
+	mu          sync.Mutex
 }
 
 func LoadNotificationConfig(path string) (*NotificationConfig, error) {
@@ -203,14 +360,31 @@ func LoadNotificationConfig(path string) (*NotificationConfig, error) {
 	if err := yaml.Unmarshal(data, &config); err != nil {
 		return nil, fmt.Errorf("parse config: %w", err)
 	}
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	if err := config.Email.ResolveSecrets(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
 
 	return &config, nil
 }
 
+type noopNotifier struct{}
+
+func (noopNotifier) Notify(event *CutEvent) error {
+	return nil
+}
+
 func NewNotificationManager(config *NotificationConfig) *NotificationManager {
+	if config == nil {
+		config = &NotificationConfig{Enabled: false}
+	}
+
 	if !config.Enabled {
 		return &NotificationManager{
-			config: config,
+			config:   config,
+			notifier: noopNotifier{},
 		}
 	}
 
@@ -227,17 +401,85 @@ func NewNotificationManager(config *NotificationConfig) *NotificationManager {
 	return &NotificationManager{
 		config:   config,
 		notifier: NewCompositeNotifier(notifiers),
+		suppression: newSuppressionState(config.StateFile,
+			time.Duration(config.DedupWindowSeconds)*time.Second),
+	}
+}
+
+func (nm *NotificationManager) maxEventAge() int {
+	if nm.config == nil {
+		return 0
 	}
+	return nm.config.MaxEventAgeSeconds
+}
+
+func (nm *NotificationManager) Enabled() bool {
+	return nm != nil && nm.config != nil && nm.config.Enabled
+}
+
+func (nm *NotificationManager) NotifyOnShutdown() bool {
+	return nm.Enabled() && nm.config.NotifyOnShutdown
 }
 
 func (nm *NotificationManager) NotifyCut(event *CutEvent) error {
-	if !nm.config.Enabled {
+	return nm.NotifyCutWith(event, nil)
+}
+
+func (nm *NotificationManager) NotifyCutWith(event *CutEvent, override *Override) error {
+	if nm == nil || event == nil {
 		return nil
 	}
 
-	event.Metadata = map[string]interface{}{
-		"source": "atropos",
+	notifier := nm.notifierFor(override)
+	if notifier == nil {
+		return nil
+	}
+
+	now := time.Now()
+	if maxAge := time.Duration(nm.maxEventAge()) * time.Second; maxAge > 0 && !event.Timestamp.IsZero() && now.Sub(event.Timestamp) > maxAge {
+		return nil
+	}
+
+	var stateErr error
+	if nm.suppression != nil {
+		var send bool
+		send, stateErr = nm.suppression.claim(event, now)
+		if !send {
+			return nil
+		}
 	}
 
-	return nm.notifier.Notify(event)
+	if event.Metadata == nil {
+		event.Metadata = make(map[string]interface{})
+	}
+	if _, ok := event.Metadata["source"]; !ok {
+		event.Metadata["source"] = "atropos"
+	}
+
+	err := notifier.Notify(event)
+	if err != nil {
+		notificationFailures.Inc()
+		// Failed deliveries don't count against the window.
+		if nm.suppression != nil {
+			err = errors.Join(err, nm.suppression.release(event, now))
+		}
+	}
+	nm.mu.Lock()
+	nm.lastErr = err
+	nm.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return stateErr
+}
+
+// LastError returns the delivery error of the most recent notification, or nil
+// if it went through.
+func (nm *NotificationManager) LastError() error {
+	if nm == nil {
+		return nil
+	}
+	nm.mu.Lock()
+	defer nm.mu.Unlock()
+	return nm.lastErr
 }
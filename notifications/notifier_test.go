@@ -0,0 +1,189 @@
+package notifications
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// webhookSink records the events posted to it. While failing is above zero,
+// each post is refused with a 503 and counts it down.
+type webhookSink struct {
+	*httptest.Server
+	mu      sync.Mutex
+	events  []CutEvent
+	failing int
+}
+
+func newWebhookSink(t *testing.T) *webhookSink {
+	t.Helper()
+	s := &webhookSink{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		var event CutEvent
+		if err := json.Unmarshal(data, &event); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.failing > 0 {
+			s.failing--
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		s.events = append(s.events, event)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *webhookSink) received() []CutEvent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]CutEvent(nil), s.events...)
+}
+
+func (s *webhookSink) config() *WebhookConfig {
+	return &WebhookConfig{URL: s.URL, Retries: 1}
+}
+
+func testEvent(node string, success bool) *CutEvent {
+	return &CutEvent{Node: node, Action: "docker_restart", Success: success, Timestamp: time.Now().UTC()}
+}
+
+func TestDisabledManagerIsNoop(t *testing.T) {
+	sink := newWebhookSink(t)
+	var nilManager *NotificationManager
+	for name, nm := range map[string]*NotificationManager{
+		"nil config": NewNotificationManager(nil),
+		"disabled":   NewNotificationManager(&NotificationConfig{Enabled: false, Webhook: sink.config()}),
+		"nil":        nilManager,
+	} {
+		if nm.Enabled() || nm.NotifyOnShutdown() {
+			t.Errorf("%s: manager reports itself enabled", name)
+		}
+		if nm != nil && nm.notifier == nil {
+			t.Errorf("%s: manager has no notifier to fall back on", name)
+		}
+		if err := nm.NotifyCut(testEvent("athena", false)); err != nil {
+			t.Errorf("%s: NotifyCut: %v", name, err)
+		}
+		if err := nm.NotifyCut(nil); err != nil {
+			t.Errorf("%s: NotifyCut(nil): %v", name, err)
+		}
+		if err := nm.LastError(); err != nil {
+			t.Errorf("%s: LastError: %v", name, err)
+		}
+	}
+	if got := len(sink.received()); got != 0 {
+		t.Fatalf("disabled managers posted %d events", got)
+	}
+}
+
+func TestDisabledManagerConcurrentCalls(t *testing.T) {
+	nm := NewNotificationManager(&NotificationConfig{Enabled: false})
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if err := nm.NotifyCut(testEvent("athena", j%2 == 0)); err != nil {
+					t.Error(err)
+					return
+				}
+				nm.Enabled()
+				nm.LastError()
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// An override can turn notifications on for a node even when they are off
+// globally.
+func TestOverrideEnablesDisabledManager(t *testing.T) {
+	sink := newWebhookSink(t)
+	nm := NewNotificationManager(&NotificationConfig{Enabled: false})
+	enabled := true
+	if err := nm.NotifyCutWith(testEvent("athena", false), &Override{Enabled: &enabled, Webhook: sink.config()}); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(sink.received()); got != 1 {
+		t.Fatalf("override posted %d events, want 1", got)
+	}
+	if err := nm.NotifyCutWith(testEvent("athena", false), &Override{Enabled: &enabled}); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(sink.received()); got != 1 {
+		t.Fatalf("override without channels on a disabled manager posted %d events, want still 1", got)
+	}
+}
+
+// An override's notifiers are built once, and webhooks through the same
+// proxy share a client.
+func TestOverrideReusesNotifiers(t *testing.T) {
+	sink := newWebhookSink(t)
+	override := &Override{Webhook: sink.config()}
+	first := override.channels()
+	if second := override.channels(); len(second) != 1 || second[0] != first[0] {
+		t.Fatalf("channels rebuilt: %v then %v", first, second)
+	}
+	other := NewWebhookNotifier(sink.config())
+	if first[0].(*WebhookNotifier).client != other.client {
+		t.Fatal("webhooks through the same proxy have their own clients")
+	}
+}
+
+func TestMetadataPreserved(t *testing.T) {
+	sink := newWebhookSink(t)
+	nm := NewNotificationManager(&NotificationConfig{Enabled: true, Webhook: sink.config()})
+
+	events := []*CutEvent{
+		testEvent("none", false),
+		testEvent("extra", false),
+		testEvent("source", false),
+	}
+	events[1].Metadata = map[string]interface{}{"ticket": "INC-1", "attempt": 2}
+	events[2].Metadata = map[string]interface{}{"source": "lachesis", "ticket": "INC-2"}
+	for _, event := range events {
+		if err := nm.NotifyCut(event); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := map[string]map[string]interface{}{
+		"none":   {"source": "atropos"},
+		"extra":  {"source": "atropos", "ticket": "INC-1", "attempt": float64(2)},
+		"source": {"source": "lachesis", "ticket": "INC-2"},
+	}
+	received := sink.received()
+	if len(received) != len(want) {
+		t.Fatalf("posted %d events, want %d", len(received), len(want))
+	}
+	for _, event := range received {
+		if got, want := event.Metadata, want[event.Node]; !equalMetadata(got, want) {
+			t.Errorf("%s: metadata = %v, want %v", event.Node, got, want)
+		}
+	}
+	if events[2].Metadata["source"] != "lachesis" {
+		t.Fatalf("caller's metadata was changed to %v", events[2].Metadata)
+	}
+}
+
+func equalMetadata(a, b map[string]interface{}) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if b[k] != v {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,62 @@
+package notifications
+
+import "sync"
+
+const (
+	OverrideModeReplace = "replace"
+	OverrideModeExtend  = "extend"
+)
+
+type Override struct {
+	Enabled *bool          `json:"enabled,omitempty" yaml:"enabled,omitempty"`
+	Mode    string         `json:"mode,omitempty" yaml:"mode,omitempty"`
+	Webhook *WebhookConfig `json:"webhook,omitempty" yaml:"webhook,omitempty"`
+	Email   *EmailConfig   `json:"email,omitempty" yaml:"email,omitempty"`
+
+	once      sync.Once
+	notifiers []Notifier
+}
+
+// channels builds the override's notifiers on first use and returns the
+// same ones after.
+func (o *Override) channels() []Notifier {
+	o.once.Do(func() {
+		if o.Webhook != nil {
+			o.notifiers = append(o.notifiers, NewWebhookNotifier(o.Webhook))
+		}
+		if o.Email != nil {
+			o.notifiers = append(o.notifiers, NewEmailNotifier(o.Email))
+		}
+	})
+	return o.notifiers
+}
+
+func (nm *NotificationManager) notifierFor(override *Override) Notifier {
+	if override == nil {
+		if !nm.Enabled() {
+			return nil
+		}
+		return nm.notifier
+	}
+
+	enabled := nm.Enabled()
+	if override.Enabled != nil {
+		enabled = *override.Enabled
+	}
+	if !enabled {
+		return nil
+	}
+
+	channels := override.channels()
+	if len(channels) == 0 {
+		if !nm.Enabled() {
+			return nil
+		}
+		return nm.notifier
+	}
+
+	if override.Mode == OverrideModeExtend && nm.Enabled() {
+		channels = append([]Notifier{nm.notifier}, channels...)
+	}
+	return NewCompositeNotifier(channels)
+}
@@ -0,0 +1,43 @@
+package policy
+
+import (
+	"fmt"
+	"time"
+)
+
+type BlackoutPeriod struct {
+	Start       time.Time `yaml:"start"`
+	End         time.Time `yaml:"end"`
+	Description string    `yaml:"description,omitempty"`
+}
+
+func (b BlackoutPeriod) Active(now time.Time) bool {
+	return !now.Before(b.Start) && now.Before(b.End)
+}
+
+func (b BlackoutPeriod) validate() error {
+	if b.Start.IsZero() || b.End.IsZero() {
+		return fmt.Errorf("blackout period needs start and end")
+	}
+	if !b.End.After(b.Start) {
+		return fmt.Errorf("blackout period %q ends before it starts", b.Description)
+	}
+	return nil
+}
+
+// ActiveBlackout returns the first policy-wide or node blackout covering now.
+func (p *RemediationPolicy) ActiveBlackout(node *NodePolicy, now time.Time) (*BlackoutPeriod, bool) {
+	for i := range p.BlackoutPeriods {
+		if p.BlackoutPeriods[i].Active(now) {
+			return &p.BlackoutPeriods[i], true
+		}
+	}
+	if node != nil {
+		for i := range node.BlackoutPeriods {
+			if node.BlackoutPeriods[i].Active(now) {
+				return &node.BlackoutPeriods[i], true
+			}
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,121 @@
+package policy
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"atropos/internal/httpclient"
+	"atropos/internal/secretfile"
+)
+
+// CallbackConfig describes where cut outcomes are reported back to the
+// caller. URL is the global target; hmac_keys entries may name their own,
+// and a request may name one whose host is in AllowedHosts.
+type CallbackConfig struct {
+	URL            string   `yaml:"url,omitempty"`
+	Secret         string   `yaml:"secret,omitempty"`
+	SecretFile     string   `yaml:"secret_file,omitempty"`
+	Retries        int      `yaml:"retries,omitempty"`
+	TimeoutSeconds int      `yaml:"timeout_seconds,omitempty"`
+	ProxyURL       string   `yaml:"proxy_url,omitempty"`
+	AllowedHosts   []string `yaml:"allowed_hosts,omitempty"`
+
+	secretFromFile string
+}
+
+func (c *CallbackConfig) SecretValue() string {
+	if c.secretFromFile != "" {
+		return c.secretFromFile
+	}
+	return c.Secret
+}
+
+func (c *CallbackConfig) GetTimeout() time.Duration {
+	if c.TimeoutSeconds > 0 {
+		return time.Duration(c.TimeoutSeconds) * time.Second
+	}
+	return 10 * time.Second
+}
+
+// AllowRequestURL reports whether a callback URL supplied with a cut request
+// may be used: it must be an absolute http(s) URL on an allowlisted host.
+func (c *CallbackConfig) AllowRequestURL(raw string) error {
+	if c == nil || len(c.AllowedHosts) == 0 {
+		return fmt.Errorf("per-request callback_url is not enabled")
+	}
+	u, err := parseCallbackURL(raw)
+	if err != nil {
+		return err
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, allowed := range c.AllowedHosts {
+		if strings.ToLower(allowed) == host {
+			return nil
+		}
+	}
+	return fmt.Errorf("callback_url host %q is not in callbacks.allowed_hosts", u.Hostname())
+}
+
+// CallbackURLFor picks the callback target for a cut: the URL the request
+// carried, then the signing key's callback_url, then the global url.
+func (p *RemediationPolicy) CallbackURLFor(requested, keyID string) string {
+	if p.Callbacks == nil {
+		return ""
+	}
+	if requested != "" {
+		return requested
+	}
+	for _, key := range p.Server.HMACKeys {
+		if key.KeyID == keyID && key.CallbackURL != "" {
+			return key.CallbackURL
+		}
+	}
+	return p.Callbacks.URL
+}
+
+func (c *CallbackConfig) resolveSecret() error {
+	if c.SecretFile == "" {
+		return nil
+	}
+	secret, err := secretfile.Read(c.SecretFile)
+	if err != nil {
+		return fmt.Errorf("callbacks: secret_file: %w", err)
+	}
+	c.secretFromFile = secret
+	return nil
+}
+
+func (c *CallbackConfig) validate() error {
+	if c.URL != "" {
+		if _, err := parseCallbackURL(c.URL); err != nil {
+			return fmt.Errorf("callbacks: %w", err)
+		}
+	}
+	if c.Retries < 0 || c.Retries > 10 {
+		return fmt.Errorf("callbacks: retries must be between 0 and 10")
+	}
+	if c.TimeoutSeconds < 0 {
+		return fmt.Errorf("callbacks: timeout_seconds must be >= 0")
+	}
+	if c.ProxyURL != "" {
+		if _, err := httpclient.ParseProxyURL(c.ProxyURL); err != nil {
+			return fmt.Errorf("callbacks: %w", err)
+		}
+	}
+	for i, host := range c.AllowedHosts {
+		if host == "" || strings.ContainsAny(host, ":/") {
+			return fmt.Errorf("callbacks: allowed_hosts[%d]: must be a bare hostname", i)
+		}
+	}
+	return nil
+}
+
+func parseCallbackURL(raw string) (*url.URL, error) {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("callback_url must be an absolute http(s) URL")
+	}
+	return u, nil
+}
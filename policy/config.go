@@ -2,20 +2,112 @@ package policy
 
 import (
 	"fmt"
+	"net/url"
 	"os"
+	"regexp"
 	"sort"
+	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
+
+	"atropos/history"
+	"atropos/internal/secretfile"
+	"atropos/notifications"
 )
 
+// ActionNoop is handled by the executor itself: it records that the threshold
+// was crossed without touching the node.
+const ActionNoop = "noop"
+
+// ActionNotifyOnly is like noop, but always notifies, with outcome notified.
+const ActionNotifyOnly = "notify_only"
+
+// ObserveOnly reports whether action is handled by the executor without
+// touching the node.
+func ObserveOnly(action string) bool {
+	return action == ActionNoop || action == ActionNotifyOnly
+}
+
 type Strategy struct {
-	Threshold    float64 `yaml:"threshold"`
-	Action       string  `yaml:"action"`
-	Command      string  `yaml:"command,omitempty"`
-	Critical     bool    `yaml:"critical,omitempty"`
-	SnapshotName string  `yaml:"snapshot_name,omitempty"`
-	EscalateTo   string  `yaml:"escalate_to,omitempty"`
-	OnFailure    string  `yaml:"on_failure,omitempty"`
+	Threshold            float64           `yaml:"threshold"`
+	Action               string            `yaml:"action"`
+	Cutter               string            `yaml:"cutter,omitempty"`
+	Command              string            `yaml:"command,omitempty"`
+	Critical             bool              `yaml:"critical,omitempty"`
+	SnapshotName         string            `yaml:"snapshot_name,omitempty"`
+	EscalateTo           string            `yaml:"escalate_to,omitempty"`
+	OnFailure            string            `yaml:"on_failure,omitempty"`
+	ConsecutiveTriggers  int               `yaml:"consecutive_triggers,omitempty"`
+	ApprovalRequired     bool              `yaml:"approval_required,omitempty"`
+	SuccessExitCodes     []int             `yaml:"success_exit_codes,omitempty"`
+	SuccessOutputRegex   string            `yaml:"success_output_regex,omitempty"`
+	FailureOutputRegex   string            `yaml:"failure_output_regex,omitempty"`
+	Description          string            `yaml:"description,omitempty"`
+	RunbookURL           string            `yaml:"runbook_url,omitempty"`
+	Labels               map[string]string `yaml:"labels,omitempty"`
+	Params               map[string]string `yaml:"params,omitempty"`
+	Notify               bool              `yaml:"notify,omitempty"`
+	TimeoutSeconds       int               `yaml:"timeout_seconds,omitempty"`
+	Retries              int               `yaml:"retries,omitempty"`
+	RetryBackoffSeconds  float64           `yaml:"retry_backoff_seconds,omitempty"`
+	PreHooks             []Hook            `yaml:"pre_hooks,omitempty"`
+	PostHooks            []Hook            `yaml:"post_hooks,omitempty"`
+	PreHookFailure       string            `yaml:"pre_hook_failure,omitempty"`
+	Verify               *Verify           `yaml:"verify,omitempty"`
+	RateLimit            *RateLimit        `yaml:"rate_limit,omitempty"`
+	BypassRateLimit      bool              `yaml:"bypass_rate_limit,omitempty"`
+	Actions              []ActionStep      `yaml:"actions,omitempty"`
+	ContinueOnError      bool              `yaml:"continue_on_error,omitempty"`
+	PreserveState        bool              `yaml:"preserve_state,omitempty"`
+	PreserveStateFailure string            `yaml:"preserve_state_failure,omitempty"`
+	Index                int               `yaml:"-"`
+}
+
+// GetTimeout bounds the strategy's action with its retries, or all of its
+// steps together. It defaults to 30s.
+func (s *Strategy) GetTimeout() time.Duration {
+	if s.TimeoutSeconds <= 0 {
+		return 30 * time.Second
+	}
+	return time.Duration(s.TimeoutSeconds) * time.Second
+}
+
+// RetryBackoff is the wait before the given retry (1-based), doubling each
+// time from retry_backoff_seconds (default 1s).
+func (s *Strategy) RetryBackoff(retry int) time.Duration {
+	base := time.Second
+	if s.RetryBackoffSeconds > 0 {
+		base = time.Duration(s.RetryBackoffSeconds * float64(time.Second))
+	}
+	return base << (retry - 1)
+}
+
+type CircuitBreaker struct {
+	Disabled         bool `yaml:"disabled,omitempty"`
+	FailureThreshold int  `yaml:"failure_threshold,omitempty"`
+	WindowMinutes    int  `yaml:"window_minutes,omitempty"`
+	CooloffMinutes   int  `yaml:"cooloff_minutes,omitempty"`
+}
+
+// GetCircuitBreaker fills in defaults: open after 5 consecutive failures
+// within 60 minutes, stay open for 30.
+func (n *NodePolicy) GetCircuitBreaker() CircuitBreaker {
+	cb := CircuitBreaker{FailureThreshold: 5, WindowMinutes: 60, CooloffMinutes: 30}
+	if n.CircuitBreaker == nil {
+		return cb
+	}
+	cb.Disabled = n.CircuitBreaker.Disabled
+	if n.CircuitBreaker.FailureThreshold > 0 {
+		cb.FailureThreshold = n.CircuitBreaker.FailureThreshold
+	}
+	if n.CircuitBreaker.WindowMinutes > 0 {
+		cb.WindowMinutes = n.CircuitBreaker.WindowMinutes
+	}
+	if n.CircuitBreaker.CooloffMinutes > 0 {
+		cb.CooloffMinutes = n.CircuitBreaker.CooloffMinutes
+	}
+	return cb
 }
 
 type TimeWindow struct {
@@ -24,14 +116,27 @@ type TimeWindow struct {
 }
 
 type NodePolicy struct {
-	Host        string       `yaml:"host,omitempty"`
-	Port        int          `yaml:"port,omitempty"`
-	User        string       `yaml:"user,omitempty"`
-	Description string       `yaml:"description,omitempty"`
-	Strategies  []Strategy   `yaml:"strategies"`
-	TimeWindows []TimeWindow `yaml:"time_windows,omitempty"`
-	RateLimit   *RateLimit   `yaml:"rate_limit,omitempty"`
-	Name        string       `yaml:"-"`
+	Host                    string                  `yaml:"host,omitempty"`
+	Port                    int                     `yaml:"port,omitempty"`
+	User                    string                  `yaml:"user,omitempty"`
+	Description             string                  `yaml:"description,omitempty"`
+	Strategies              []Strategy              `yaml:"strategies"`
+	TimeWindows             []TimeWindow            `yaml:"time_windows,omitempty"`
+	RateLimit               *RateLimit              `yaml:"rate_limit,omitempty"`
+	DryRun                  bool                    `yaml:"dry_run,omitempty"`
+	Notifications           *notifications.Override `yaml:"notifications,omitempty"`
+	MaxHistoryRecords       int                     `yaml:"max_history_records,omitempty"`
+	DependsOn               []string                `yaml:"depends_on,omitempty"`
+	DependencyMode          string                  `yaml:"dependency_mode,omitempty"`
+	DependencyWindowMinutes int                     `yaml:"dependency_failure_window_minutes,omitempty"`
+	DependencyOrder         map[string]string       `yaml:"dependency_order,omitempty"`
+	DependencyGraceMinutes  int                     `yaml:"dependency_grace_minutes,omitempty"`
+	CircuitBreaker          *CircuitBreaker         `yaml:"circuit_breaker,omitempty"`
+	BlackoutPeriods         []BlackoutPeriod        `yaml:"blackout_periods,omitempty"`
+	Schedules               []Schedule              `yaml:"schedules,omitempty"`
+	Recovery                *Recovery               `yaml:"recovery,omitempty"`
+	Params                  map[string]string       `yaml:"params,omitempty"`
+	Name                    string                  `yaml:"-"`
 }
 
 type RateLimit struct {
@@ -40,8 +145,84 @@ type RateLimit struct {
 }
 
 type ServerConfig struct {
-	ListenAddr string `yaml:"listen_addr"`
-	HMACSecret string `yaml:"hmac_secret"`
+	ListenAddr             string        `yaml:"listen_addr"`
+	HMACSecret             string        `yaml:"hmac_secret"`
+	HMACSecretFile         string        `yaml:"hmac_secret_file,omitempty"`
+	HMACKeys               []HMACKey     `yaml:"hmac_keys,omitempty"`
+	ApprovalTimeoutMinutes int           `yaml:"approval_timeout_minutes,omitempty"`
+	HistoryRetentionDays   int           `yaml:"history_retention_days,omitempty"`
+	TLS                    *TLSConfig    `yaml:"tls,omitempty"`
+	HistoryQuota           *HistoryQuota `yaml:"history_quota,omitempty"`
+	MaxConcurrentCuts      int           `yaml:"max_concurrent_cuts,omitempty"`
+	WorkerPool             *WorkerPool   `yaml:"worker_pool,omitempty"`
+	StateIdleMinutes       int           `yaml:"state_idle_minutes,omitempty"`
+	MaxChainDepth          *int          `yaml:"max_chain_depth,omitempty"`
+	EntropyOutOfRange      string        `yaml:"entropy_out_of_range,omitempty"`
+	EntropyEpsilon         float64       `yaml:"entropy_epsilon,omitempty"`
+	CutQueueTimeoutSeconds int           `yaml:"cut_queue_timeout_seconds,omitempty"`
+	HealthFailLevel        string        `yaml:"health_fail_level,omitempty"`
+	ExportSigningKey       string        `yaml:"export_signing_key,omitempty"`
+	ExportSigningKeyFile   string        `yaml:"export_signing_key_file,omitempty"`
+	ShutdownTimeoutSeconds int           `yaml:"shutdown_timeout_seconds,omitempty"`
+	DedupWindowSeconds     *int          `yaml:"dedup_window_seconds,omitempty"`
+	CutDeadlineSeconds     int           `yaml:"cut_deadline_seconds,omitempty"`
+	DisableMetrics         bool          `yaml:"disable_metrics,omitempty"`
+	EnableLocalCutter      bool          `yaml:"enable_local_cutter,omitempty"`
+}
+
+// WorkerPool switches POST /cut to queued execution: a fixed set of workers
+// drains a bounded queue, and a full queue rejects new cuts.
+type WorkerPool struct {
+	Workers   int `yaml:"workers,omitempty"`
+	QueueSize int `yaml:"queue_size,omitempty"`
+}
+
+// GetWorkerPool fills in defaults: 8 workers and a queue of 64. The second
+// result is false when no pool is configured.
+func (s *ServerConfig) GetWorkerPool() (WorkerPool, bool) {
+	if s.WorkerPool == nil {
+		return WorkerPool{}, false
+	}
+	pool := WorkerPool{Workers: 8, QueueSize: 64}
+	if s.WorkerPool.Workers > 0 {
+		pool.Workers = s.WorkerPool.Workers
+	}
+	if s.WorkerPool.QueueSize > 0 {
+		pool.QueueSize = s.WorkerPool.QueueSize
+	}
+	return pool, true
+}
+
+type HistoryQuota struct {
+	MaxRecordsPerNode int `yaml:"max_records_per_node"`
+}
+
+type HMACKey struct {
+	KeyID      string     `yaml:"key_id,omitempty"`
+	Secret     string     `yaml:"secret,omitempty"`
+	SecretFile string     `yaml:"secret_file,omitempty"`
+	ExpiresAt  *time.Time `yaml:"expires_at,omitempty"`
+	// CallbackURL overrides callbacks.url for cuts signed with this key.
+	CallbackURL string `yaml:"callback_url,omitempty"`
+
+	secretFromFile string
+}
+
+func (k HMACKey) SecretValue() string {
+	if k.secretFromFile != "" {
+		return k.secretFromFile
+	}
+	return k.Secret
+}
+
+func (k HMACKey) Expired(now time.Time) bool {
+	return k.ExpiresAt != nil && now.After(*k.ExpiresAt)
+}
+
+type TLSConfig struct {
+	CertFile     string `yaml:"cert_file"`
+	KeyFile      string `yaml:"key_file"`
+	ClientCAFile string `yaml:"client_ca_file,omitempty"`
 }
 
 type Meta struct {
@@ -50,10 +231,19 @@ type Meta struct {
 }
 
 type RemediationPolicy struct {
-	Meta      Meta                   `yaml:"meta"`
-	Server    ServerConfig           `yaml:"server"`
-	Nodes     map[string]*NodePolicy `yaml:"nodes"`
-	nodeIndex map[string]*NodePolicy
+	Meta            Meta                              `yaml:"meta"`
+	Server          ServerConfig                      `yaml:"server"`
+	Nodes           map[string]*NodePolicy            `yaml:"nodes"`
+	BlackoutPeriods []BlackoutPeriod                  `yaml:"blackout_periods,omitempty"`
+	Notifications   *notifications.NotificationConfig `yaml:"notifications,omitempty"`
+	Callbacks       *CallbackConfig                   `yaml:"callbacks,omitempty"`
+	Plugins         []Plugin                          `yaml:"plugins,omitempty"`
+	Cutters         []CutterConfig                    `yaml:"cutters,omitempty"`
+	nodeIndex       map[string]*NodePolicy
+
+	hmacSecretFromFile string
+	exportKeyFromFile  string
+	hash               string
 }
 
 func LoadPolicy(path string) (*RemediationPolicy, error) {
@@ -71,6 +261,14 @@ func LoadPolicy(path string) (*RemediationPolicy, error) {
 		return nil, err
 	}
 
+	if err := policy.resolveSecrets(); err != nil {
+		return nil, err
+	}
+
+	if err := policy.computeHash(); err != nil {
+		return nil, err
+	}
+
 	policy.buildIndex()
 	return &policy, nil
 }
@@ -80,10 +278,146 @@ func (p *RemediationPolicy) validate() error {
 		return fmt.Errorf("policy must define at least one node")
 	}
 
+	if p.Server.ApprovalTimeoutMinutes < 0 {
+		return fmt.Errorf("server: approval_timeout_minutes must be >= 0")
+	}
+
+	if p.Server.HistoryRetentionDays < 0 {
+		return fmt.Errorf("server: history_retention_days must be >= 0")
+	}
+
+	switch p.Server.HealthFailLevel {
+	case "", "degraded", "unhealthy":
+	default:
+		return fmt.Errorf("server: health_fail_level must be \"degraded\" or \"unhealthy\"")
+	}
+
+	keyIDs := make(map[string]bool)
+	for i, key := range p.Server.HMACKeys {
+		if key.KeyID == "" {
+			continue
+		}
+		if key.KeyID == "default" || keyIDs[key.KeyID] {
+			return fmt.Errorf("server: hmac_keys[%d]: key_id %q is reserved or duplicated", i, key.KeyID)
+		}
+		keyIDs[key.KeyID] = true
+	}
+
+	if p.Callbacks != nil {
+		if err := p.Callbacks.validate(); err != nil {
+			return err
+		}
+	}
+	for i, key := range p.Server.HMACKeys {
+		if key.CallbackURL == "" {
+			continue
+		}
+		if p.Callbacks == nil {
+			return fmt.Errorf("server: hmac_keys[%d]: callback_url requires a callbacks section", i)
+		}
+		if _, err := parseCallbackURL(key.CallbackURL); err != nil {
+			return fmt.Errorf("server: hmac_keys[%d]: %w", i, err)
+		}
+	}
+
+	if p.Server.MaxConcurrentCuts < 0 || p.Server.CutQueueTimeoutSeconds < 0 {
+		return fmt.Errorf("server: max_concurrent_cuts and cut_queue_timeout_seconds must be >= 0")
+	}
+
+	switch p.Server.EntropyOutOfRange {
+	case "", EntropyReject, EntropyClamp:
+	default:
+		return fmt.Errorf("server: entropy_out_of_range must be \"reject\" or \"clamp\"")
+	}
+	if p.Server.EntropyEpsilon < 0 || p.Server.EntropyEpsilon > 0.1 {
+		return fmt.Errorf("server: entropy_epsilon must be between 0 and 0.1")
+	}
+
+	if d := p.Server.MaxChainDepth; d != nil && *d < 0 {
+		return fmt.Errorf("server: max_chain_depth must be >= 0")
+	}
+
+	if p.Server.CutDeadlineSeconds < 0 {
+		return fmt.Errorf("server: cut_deadline_seconds must be >= 0")
+	}
+
+	if d := p.Server.DedupWindowSeconds; d != nil && *d < 0 {
+		return fmt.Errorf("server: dedup_window_seconds must be >= 0")
+	}
+
+	if p.Server.StateIdleMinutes < 0 {
+		return fmt.Errorf("server: state_idle_minutes must be >= 0")
+	}
+
+	if w := p.Server.WorkerPool; w != nil && (w.Workers < 0 || w.QueueSize < 0) {
+		return fmt.Errorf("server: worker_pool.workers and worker_pool.queue_size must be >= 0")
+	}
+
+	if q := p.Server.HistoryQuota; q != nil && q.MaxRecordsPerNode < 0 {
+		return fmt.Errorf("server: history_quota.max_records_per_node must be >= 0")
+	}
+
+	if t := p.Server.TLS; t != nil && (t.CertFile == "" || t.KeyFile == "") {
+		return fmt.Errorf("server: tls requires both cert_file and key_file")
+	}
+
+	if err := p.validateDependencies(); err != nil {
+		return err
+	}
+
+	for i, b := range p.BlackoutPeriods {
+		if err := b.validate(); err != nil {
+			return fmt.Errorf("blackout_periods[%d]: %w", i, err)
+		}
+	}
+
+	if err := p.Notifications.Validate(); err != nil {
+		return fmt.Errorf("notifications: %w", err)
+	}
+
 	for name, node := range p.Nodes {
+		if err := history.ValidateNodeName(name); err != nil {
+			return fmt.Errorf("node %q: %w", name, err)
+		}
 		if len(node.Strategies) == 0 {
 			return fmt.Errorf("node %q: needs at least one strategy", name)
 		}
+		for j := range node.Strategies {
+			if err := node.Strategies[j].nameSteps(); err != nil {
+				return fmt.Errorf("node %q strategy %d: %w", name, j, err)
+			}
+		}
+		for i, b := range node.BlackoutPeriods {
+			if err := b.validate(); err != nil {
+				return fmt.Errorf("node %q blackout_periods[%d]: %w", name, i, err)
+			}
+		}
+		for i := range node.Schedules {
+			if err := node.Schedules[i].compile(); err != nil {
+				return fmt.Errorf("node %q schedules[%d]: %w", name, i, err)
+			}
+		}
+		if node.Recovery != nil {
+			if err := node.Recovery.validate(node); err != nil {
+				return fmt.Errorf("node %q recovery: %w", name, err)
+			}
+		}
+		if cb := node.CircuitBreaker; cb != nil && (cb.FailureThreshold < 0 || cb.WindowMinutes < 0 || cb.CooloffMinutes < 0) {
+			return fmt.Errorf("node %q: circuit_breaker values must be >= 0", name)
+		}
+		if node.MaxHistoryRecords < 0 {
+			return fmt.Errorf("node %q: max_history_records must be >= 0", name)
+		}
+		if n := node.Notifications; n != nil {
+			switch n.Mode {
+			case "", notifications.OverrideModeReplace, notifications.OverrideModeExtend:
+			default:
+				return fmt.Errorf("node %q: notifications mode must be %q or %q", name, notifications.OverrideModeReplace, notifications.OverrideModeExtend)
+			}
+			if err := n.Webhook.Validate(); err != nil {
+				return fmt.Errorf("node %q: notifications %w", name, err)
+			}
+		}
 		for j, strat := range node.Strategies {
 			if strat.Threshold < 0 || strat.Threshold > 1 {
 				return fmt.Errorf("node %q strategy %d: threshold must be 0-1", name, j)
@@ -91,17 +425,99 @@ func (p *RemediationPolicy) validate() error {
 			if strat.Action == "" {
 				return fmt.Errorf("node %q strategy %d: action required", name, j)
 			}
+			if strat.ConsecutiveTriggers < 0 {
+				return fmt.Errorf("node %q strategy %d: consecutive_triggers must be >= 0", name, j)
+			}
+			if strat.TimeoutSeconds < 0 {
+				return fmt.Errorf("node %q strategy %d: timeout_seconds must be >= 0", name, j)
+			}
+			if strat.Retries < 0 || strat.Retries > 10 {
+				return fmt.Errorf("node %q strategy %d: retries must be between 0 and 10", name, j)
+			}
+			if strat.RetryBackoffSeconds < 0 {
+				return fmt.Errorf("node %q strategy %d: retry_backoff_seconds must be >= 0", name, j)
+			}
+			if rl := strat.RateLimit; rl != nil && (rl.MaxCuts <= 0 || rl.Window <= 0) {
+				return fmt.Errorf("node %q strategy %d: rate_limit max_cuts and window_minutes must be > 0", name, j)
+			}
+			if strat.ContinueOnError && len(strat.Actions) == 0 {
+				return fmt.Errorf("node %q strategy %d: continue_on_error requires actions", name, j)
+			}
+			if strat.BypassRateLimit && !strat.Critical {
+				return fmt.Errorf("node %q strategy %d: bypass_rate_limit requires critical: true", name, j)
+			}
+			if err := strat.validateHooks(); err != nil {
+				return fmt.Errorf("node %q strategy %d: %w", name, j, err)
+			}
+			if err := strat.validatePreserveState(); err != nil {
+				return fmt.Errorf("node %q strategy %d: %w", name, j, err)
+			}
+			if err := strat.validateIsolation(node, p.CutterParams(strat.cutterOr("network"))); err != nil {
+				return fmt.Errorf("node %q strategy %d: %w", name, j, err)
+			}
+			if err := strat.validateHTTPCall(node, p.CutterParams(strat.cutterOr("webhook"))); err != nil {
+				return fmt.Errorf("node %q strategy %d: %w", name, j, err)
+			}
+			if err := strat.validateSystemd(node, p.CutterParams(strat.cutterOr("systemd"))); err != nil {
+				return fmt.Errorf("node %q strategy %d: %w", name, j, err)
+			}
+			if strat.Verify != nil {
+				if err := strat.Verify.validate(); err != nil {
+					return fmt.Errorf("node %q strategy %d: %w", name, j, err)
+				}
+			}
+			if strat.SuccessOutputRegex != "" {
+				if _, err := regexp.Compile(strat.SuccessOutputRegex); err != nil {
+					return fmt.Errorf("node %q strategy %d: success_output_regex: %w", name, j, err)
+				}
+			}
+			if strat.FailureOutputRegex != "" {
+				if _, err := regexp.Compile(strat.FailureOutputRegex); err != nil {
+					return fmt.Errorf("node %q strategy %d: failure_output_regex: %w", name, j, err)
+				}
+			}
+			for key := range strat.Labels {
+				if strings.TrimSpace(key) == "" {
+					return fmt.Errorf("node %q strategy %d: label keys must not be empty", name, j)
+				}
+				if strings.Contains(key, "=") {
+					return fmt.Errorf("node %q strategy %d: label key %q must not contain '='", name, j, key)
+				}
+			}
+			if strat.RunbookURL != "" {
+				u, err := url.Parse(strat.RunbookURL)
+				if err != nil {
+					return fmt.Errorf("node %q strategy %d: runbook_url: %w", name, j, err)
+				}
+				if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+					return fmt.Errorf("node %q strategy %d: runbook_url must be an absolute http(s) URL", name, j)
+				}
+			}
+			if strat.EscalateTo != "" {
+				if _, ok := node.SelectStrategyByAction(strat.EscalateTo); !ok {
+					return fmt.Errorf("node %q strategy %d: escalate_to %q does not match any strategy action", name, j, strat.EscalateTo)
+				}
+			}
 		}
 	}
 
-	return nil
+	if err := p.validatePlugins(); err != nil {
+		return err
+	}
+	if err := p.validateLocalExec(); err != nil {
+		return err
+	}
+	return p.validateCutters()
 }
 
 func (p *RemediationPolicy) buildIndex() {
 	p.nodeIndex = make(map[string]*NodePolicy, len(p.Nodes))
 	for name, node := range p.Nodes {
 		node.Name = name
-		sort.Slice(node.Strategies, func(a, b int) bool {
+		for i := range node.Strategies {
+			node.Strategies[i].Index = i
+		}
+		sort.SliceStable(node.Strategies, func(a, b int) bool {
 			return node.Strategies[a].Threshold > node.Strategies[b].Threshold
 		})
 		p.nodeIndex[name] = node
@@ -122,6 +538,13 @@ func (n *NodePolicy) SelectStrategy(entropy float64) (*Strategy, bool) {
 	return nil, false
 }
 
+func (s *Strategy) RequiredTriggers() int {
+	if s.ConsecutiveTriggers < 1 {
+		return 1
+	}
+	return s.ConsecutiveTriggers
+}
+
 func (n *NodePolicy) SelectStrategyByAction(action string) (*Strategy, bool) {
 	for i := range n.Strategies {
 		if n.Strategies[i].Action == action {
@@ -131,9 +554,13 @@ func (n *NodePolicy) SelectStrategyByAction(action string) (*Strategy, bool) {
 	return nil, false
 }
 
-func (n *NodePolicy) GetEscalationStrategy(currentThreshold float64) (*Strategy, bool) {
+func (n *NodePolicy) GetEscalationStrategy(current *Strategy) (*Strategy, bool) {
+	if current.EscalateTo != "" {
+		return n.SelectStrategyByAction(current.EscalateTo)
+	}
+
 	for i := range n.Strategies {
-		if n.Strategies[i].Threshold > currentThreshold {
+		if n.Strategies[i].Threshold > current.Threshold {
 			return &n.Strategies[i], true
 		}
 	}
@@ -151,5 +578,150 @@ func (p *RemediationPolicy) GetHMACSecret() string {
 	if secret := os.Getenv("ATROPOS_HMAC_SECRET"); secret != "" {
 		return secret
 	}
+	if p.hmacSecretFromFile != "" {
+		return p.hmacSecretFromFile
+	}
 	return p.Server.HMACSecret
 }
+
+func (p *RemediationPolicy) GetExportSigningKey() string {
+	if key := os.Getenv("ATROPOS_EXPORT_SIGNING_KEY"); key != "" {
+		return key
+	}
+	if p.exportKeyFromFile != "" {
+		return p.exportKeyFromFile
+	}
+	return p.Server.ExportSigningKey
+}
+
+// GetHMACKeys returns every secret a webhook signature may match: the single
+// legacy secret first (key ID "default"), then the hmac_keys list.
+func (p *RemediationPolicy) GetHMACKeys() []HMACKey {
+	var keys []HMACKey
+	if secret := p.GetHMACSecret(); secret != "" {
+		keys = append(keys, HMACKey{KeyID: "default", secretFromFile: secret})
+	}
+	return append(keys, p.Server.HMACKeys...)
+}
+
+func (p *RemediationPolicy) resolveSecrets() error {
+	if p.Server.HMACSecretFile != "" {
+		secret, err := secretfile.Read(p.Server.HMACSecretFile)
+		if err != nil {
+			return fmt.Errorf("server: hmac_secret_file: %w", err)
+		}
+		p.hmacSecretFromFile = secret
+	}
+
+	if p.Server.ExportSigningKeyFile != "" {
+		key, err := secretfile.Read(p.Server.ExportSigningKeyFile)
+		if err != nil {
+			return fmt.Errorf("server: export_signing_key_file: %w", err)
+		}
+		p.exportKeyFromFile = key
+	}
+
+	for i, key := range p.Server.HMACKeys {
+		if key.SecretFile != "" {
+			secret, err := secretfile.Read(key.SecretFile)
+			if err != nil {
+				return fmt.Errorf("server: hmac_keys[%d]: secret_file: %w", i, err)
+			}
+			p.Server.HMACKeys[i].secretFromFile = secret
+		}
+		if p.Server.HMACKeys[i].SecretValue() == "" {
+			return fmt.Errorf("server: hmac_keys[%d]: secret or secret_file required", i)
+		}
+	}
+
+	if p.Callbacks != nil {
+		if err := p.Callbacks.resolveSecret(); err != nil {
+			return err
+		}
+		if p.Callbacks.SecretValue() == "" {
+			return fmt.Errorf("callbacks: secret or secret_file required")
+		}
+	}
+
+	if p.Notifications != nil {
+		if err := p.Notifications.Email.ResolveSecrets(); err != nil {
+			return fmt.Errorf("notifications: %w", err)
+		}
+	}
+
+	for name, node := range p.Nodes {
+		if node.Notifications == nil {
+			continue
+		}
+		if err := node.Notifications.Email.ResolveSecrets(); err != nil {
+			return fmt.Errorf("node %q: notifications %w", name, err)
+		}
+	}
+	return nil
+}
+
+func (p *RemediationPolicy) GetHealthFailLevel() string {
+	if p.Server.HealthFailLevel != "" {
+		return p.Server.HealthFailLevel
+	}
+	return "unhealthy"
+}
+
+func (p *RemediationPolicy) GetCutQueueTimeout() time.Duration {
+	if p.Server.CutQueueTimeoutSeconds > 0 {
+		return time.Duration(p.Server.CutQueueTimeoutSeconds) * time.Second
+	}
+	return 10 * time.Second
+}
+
+// GetShutdownTimeout bounds how long shutdown waits for running cuts and
+// queued notifications.
+func (p *RemediationPolicy) GetShutdownTimeout() time.Duration {
+	if p.Server.ShutdownTimeoutSeconds > 0 {
+		return time.Duration(p.Server.ShutdownTimeoutSeconds) * time.Second
+	}
+	return 30 * time.Second
+}
+
+// GetCutDeadline is how long POST /cut waits for a result before answering
+// 202 with the cut's ID to poll.
+func (p *RemediationPolicy) GetCutDeadline() time.Duration {
+	if p.Server.CutDeadlineSeconds > 0 {
+		return time.Duration(p.Server.CutDeadlineSeconds) * time.Second
+	}
+	return 35 * time.Second
+}
+
+// GetDedupWindow is how long an identical node and entropy pair is answered
+// with the first request's result instead of cutting again. 0 disables it.
+func (p *RemediationPolicy) GetDedupWindow() time.Duration {
+	if p.Server.DedupWindowSeconds != nil {
+		return time.Duration(*p.Server.DedupWindowSeconds) * time.Second
+	}
+	return 10 * time.Second
+}
+
+// GetMaxChainDepth bounds how many fallback or escalation steps may follow
+// the first strategy of a cut. 0 disables them.
+func (p *RemediationPolicy) GetMaxChainDepth() int {
+	if p.Server.MaxChainDepth != nil {
+		return *p.Server.MaxChainDepth
+	}
+	return 3
+}
+
+// GetStateIdle is how long per-node runtime state may go untouched before
+// garbage collection drops it.
+func (p *RemediationPolicy) GetStateIdle() time.Duration {
+	if p.Server.StateIdleMinutes > 0 {
+		return time.Duration(p.Server.StateIdleMinutes) * time.Minute
+	}
+	return time.Hour
+}
+
+func (p *RemediationPolicy) GetApprovalTimeout() time.Duration {
+	if p.Server.ApprovalTimeoutMinutes > 0 {
+		return time.Duration(p.Server.ApprovalTimeoutMinutes) * time.Minute
+	}
+	return time.Hour
+}
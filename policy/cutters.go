@@ -0,0 +1,114 @@
+package policy
+
+import (
+	"fmt"
+	"slices"
+)
+
+// BuiltinCutters names the cutters Atropos ships, as the cutters section
+// and a strategy's cutter field refer to them.
+var BuiltinCutters = []string{"docker", "network", "lb", "systemd", "vbox", "vmware", "winrm", "dns", "ssm", "webhook", "local"}
+
+// CutterConfig enables a built-in cutter and gives it default params, used
+// when neither the strategy nor the node sets them.
+type CutterConfig struct {
+	Name   string            `yaml:"name"`
+	Params map[string]string `yaml:"params,omitempty"`
+}
+
+// EnabledCutters lists the built-in cutters to register: those in the
+// cutters section, or all of them when there is none. local is left out
+// unless server.enable_local_cutter is set.
+func (p *RemediationPolicy) EnabledCutters() []string {
+	var names []string
+	if len(p.Cutters) == 0 {
+		names = append(names, BuiltinCutters...)
+	}
+	for _, c := range p.Cutters {
+		names = append(names, c.Name)
+	}
+	if !p.Server.EnableLocalCutter {
+		names = slices.DeleteFunc(names, func(name string) bool { return name == LocalCutter })
+	}
+	return names
+}
+
+// CutterEnabled reports whether a strategy may select the named cutter: an
+// enabled built-in or a plugin.
+func (p *RemediationPolicy) CutterEnabled(name string) bool {
+	for _, enabled := range p.EnabledCutters() {
+		if enabled == name {
+			return true
+		}
+	}
+	for _, plugin := range p.Plugins {
+		if plugin.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// CutterParams returns the named cutter's defaults from the cutters section.
+func (p *RemediationPolicy) CutterParams(name string) map[string]string {
+	for _, c := range p.Cutters {
+		if c.Name == name {
+			return c.Params
+		}
+	}
+	return nil
+}
+
+// cutterOr is the cutter the strategy names, or fallback, the one its
+// action is routed to otherwise.
+func (s *Strategy) cutterOr(fallback string) string {
+	if s.Cutter != "" {
+		return s.Cutter
+	}
+	return fallback
+}
+
+func builtinCutter(name string) bool {
+	for _, builtin := range BuiltinCutters {
+		if builtin == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *RemediationPolicy) validateCutters() error {
+	seen := make(map[string]bool, len(p.Cutters))
+	for i, c := range p.Cutters {
+		if !builtinCutter(c.Name) {
+			return fmt.Errorf("cutters[%d]: unknown cutter %q", i, c.Name)
+		}
+		if seen[c.Name] {
+			return fmt.Errorf("cutters[%d]: duplicate cutter %q", i, c.Name)
+		}
+		seen[c.Name] = true
+	}
+	for _, plugin := range p.Plugins {
+		if builtinCutter(plugin.Name) {
+			return fmt.Errorf("plugin %q: name is taken by a built-in cutter", plugin.Name)
+		}
+	}
+
+	for name, node := range p.Nodes {
+		for j, strat := range node.Strategies {
+			if strat.Cutter != "" && len(strat.Actions) > 0 {
+				return fmt.Errorf("node %q strategy %d: cutter applies to one action; set it on each of actions", name, j)
+			}
+			selected := []string{strat.Cutter}
+			for _, step := range strat.Actions {
+				selected = append(selected, step.Cutter)
+			}
+			for _, c := range selected {
+				if c != "" && !p.CutterEnabled(c) {
+					return fmt.Errorf("node %q strategy %d: cutter %q is unknown or not enabled", name, j, c)
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,238 @@
+package policy
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+const (
+	DependencyModeRefuse = "refuse"
+	DependencyModeDefer  = "defer"
+
+	DependencyOrderLast  = "last"
+	DependencyOrderFirst = "first"
+)
+
+const (
+	unvisited = iota
+	visiting
+	done
+)
+
+func (n *NodePolicy) GetDependencyMode() string {
+	if n.DependencyMode == "" {
+		return DependencyModeRefuse
+	}
+	return n.DependencyMode
+}
+
+func (n *NodePolicy) GetDependencyWindow() time.Duration {
+	if n.DependencyWindowMinutes > 0 {
+		return time.Duration(n.DependencyWindowMinutes) * time.Minute
+	}
+	return 15 * time.Minute
+}
+
+func (n *NodePolicy) GetDependencyGrace() time.Duration {
+	return time.Duration(n.DependencyGraceMinutes) * time.Minute
+}
+
+// GetDependencyOrder says whether dep is cut after (last, the default) or
+// before (first) this node when both are in the same batch.
+func (n *NodePolicy) GetDependencyOrder(dep string) string {
+	if order := n.DependencyOrder[dep]; order != "" {
+		return order
+	}
+	return DependencyOrderLast
+}
+
+type OrderStep struct {
+	Node     string   `json:"node"`
+	Position int      `json:"position"`
+	After    []string `json:"after,omitempty"`
+	Reason   string   `json:"reason"`
+}
+
+// OrderBatch sorts nodes so every depends_on edge between them is respected
+// in its configured direction; unrelated nodes keep their request order.
+func (p *RemediationPolicy) OrderBatch(nodes []string) []OrderStep {
+	edges := p.orderEdges(nodes)
+	before := make(map[string][]string)
+	for _, e := range edges {
+		before[e[1]] = append(before[e[1]], e[0])
+	}
+
+	placed := make(map[string]bool, len(nodes))
+	steps := make([]OrderStep, 0, len(nodes))
+	for len(steps) < len(nodes) {
+		progressed := false
+		for _, node := range nodes {
+			if placed[node] {
+				continue
+			}
+			ready := true
+			for _, prev := range before[node] {
+				if !placed[prev] {
+					ready = false
+					break
+				}
+			}
+			if !ready {
+				continue
+			}
+
+			step := OrderStep{Node: node, Position: len(steps), After: before[node], Reason: "request order"}
+			if len(step.After) > 0 {
+				step.Reason = "dependency ordering"
+			}
+			steps = append(steps, step)
+			placed[node] = true
+			progressed = true
+			break
+		}
+		if !progressed {
+			// Only reachable for a policy that skipped validation; fall back
+			// to request order for whatever is left.
+			for _, node := range nodes {
+				if !placed[node] {
+					steps = append(steps, OrderStep{Node: node, Position: len(steps), Reason: "ordering cycle"})
+					placed[node] = true
+				}
+			}
+		}
+	}
+	return steps
+}
+
+// orderEdges returns [first, second] pairs for every dependency edge among
+// the given nodes.
+func (p *RemediationPolicy) orderEdges(nodes []string) [][2]string {
+	in := make(map[string]bool, len(nodes))
+	for _, node := range nodes {
+		in[node] = true
+	}
+
+	var edges [][2]string
+	for _, node := range nodes {
+		np, ok := p.Nodes[node]
+		if !ok {
+			continue
+		}
+		for _, dep := range np.DependsOn {
+			if !in[dep] {
+				continue
+			}
+			if np.GetDependencyOrder(dep) == DependencyOrderFirst {
+				edges = append(edges, [2]string{dep, node})
+			} else {
+				edges = append(edges, [2]string{node, dep})
+			}
+		}
+	}
+	return edges
+}
+
+func (p *RemediationPolicy) validateDependencies() error {
+	names := make([]string, 0, len(p.Nodes))
+	for name, node := range p.Nodes {
+		names = append(names, name)
+		switch node.DependencyMode {
+		case "", DependencyModeRefuse, DependencyModeDefer:
+		default:
+			return fmt.Errorf("node %q: dependency_mode must be %q or %q", name, DependencyModeRefuse, DependencyModeDefer)
+		}
+		if node.DependencyWindowMinutes < 0 {
+			return fmt.Errorf("node %q: dependency_failure_window_minutes must be >= 0", name)
+		}
+		if node.DependencyGraceMinutes < 0 {
+			return fmt.Errorf("node %q: dependency_grace_minutes must be >= 0", name)
+		}
+		for dep, order := range node.DependencyOrder {
+			if !containsString(node.DependsOn, dep) {
+				return fmt.Errorf("node %q: dependency_order %q is not in depends_on", name, dep)
+			}
+			if order != DependencyOrderLast && order != DependencyOrderFirst {
+				return fmt.Errorf("node %q: dependency_order %q must be %q or %q", name, dep, DependencyOrderLast, DependencyOrderFirst)
+			}
+		}
+		for _, dep := range node.DependsOn {
+			if _, ok := p.Nodes[dep]; !ok {
+				return fmt.Errorf("node %q: depends_on %q is not a node in the policy", name, dep)
+			}
+		}
+	}
+	sort.Strings(names)
+
+	state := make(map[string]int, len(p.Nodes))
+
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		switch state[name] {
+		case visiting:
+			return fmt.Errorf("depends_on cycle: %v", append(path, name))
+		case done:
+			return nil
+		}
+		state[name] = visiting
+		for _, dep := range p.Nodes[name].DependsOn {
+			if err := visit(dep, append(path, name)); err != nil {
+				return err
+			}
+		}
+		state[name] = done
+		return nil
+	}
+
+	for _, name := range names {
+		if err := visit(name, nil); err != nil {
+			return err
+		}
+	}
+
+	return p.validateOrdering(names)
+}
+
+// validateOrdering rejects dependency_order settings that, although the
+// depends_on graph is acyclic, would make batch ordering impossible.
+func (p *RemediationPolicy) validateOrdering(names []string) error {
+	after := make(map[string][]string)
+	for _, e := range p.orderEdges(names) {
+		after[e[0]] = append(after[e[0]], e[1])
+	}
+
+	state := make(map[string]int, len(names))
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		switch state[name] {
+		case visiting:
+			return fmt.Errorf("dependency_order cycle: %v", append(path, name))
+		case done:
+			return nil
+		}
+		state[name] = visiting
+		for _, next := range after[name] {
+			if err := visit(next, append(path, name)); err != nil {
+				return err
+			}
+		}
+		state[name] = done
+		return nil
+	}
+
+	for _, name := range names {
+		if err := visit(name, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
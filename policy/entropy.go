@@ -0,0 +1,40 @@
+package policy
+
+import (
+	"fmt"
+	"math"
+)
+
+const (
+	EntropyReject = "reject"
+	EntropyClamp  = "clamp"
+)
+
+func (p *RemediationPolicy) GetEntropyEpsilon() float64 {
+	if p.Server.EntropyEpsilon > 0 {
+		return p.Server.EntropyEpsilon
+	}
+	return 1e-6
+}
+
+// NormalizeEntropy checks a reading against [0, 1]. In clamp mode a value
+// within entropy_epsilon of the range is pulled onto the nearest bound and
+// clamped is true; anything further out is an error in either mode.
+func (p *RemediationPolicy) NormalizeEntropy(v float64) (float64, bool, error) {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0, false, fmt.Errorf("entropy must be a finite number")
+	}
+	if v >= 0 && v <= 1 {
+		return v, false, nil
+	}
+	if p.Server.EntropyOutOfRange == EntropyClamp {
+		eps := p.GetEntropyEpsilon()
+		if v < 0 && v >= -eps {
+			return 0, true, nil
+		}
+		if v > 1 && v <= 1+eps {
+			return 1, true, nil
+		}
+	}
+	return 0, false, fmt.Errorf("entropy %g outside [0, 1]", v)
+}
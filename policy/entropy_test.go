@@ -0,0 +1,125 @@
+package policy
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+const entropyNodes = `
+nodes:
+  athena:
+    strategies:
+      - threshold: 0.5
+        action: docker_restart
+`
+
+func TestNormalizeEntropy(t *testing.T) {
+	type want struct {
+		v       float64
+		clamped bool
+		err     bool
+	}
+	inRange := func(v float64) want { return want{v: v} }
+	rejected := want{err: true}
+
+	cases := []struct {
+		name   string
+		server string
+		in     []float64
+		want   []want
+	}{
+		{
+			name: "reject by default",
+			in:   []float64{0, 0.5, 1, -1e-9, 1 + 1e-9, -0.5, 1.5},
+			want: []want{inRange(0), inRange(0.5), inRange(1), rejected, rejected, rejected, rejected},
+		},
+		{
+			name:   "reject",
+			server: "  entropy_out_of_range: reject\n  entropy_epsilon: 0.05\n",
+			in:     []float64{0, 1, -0.01, 1.01},
+			want:   []want{inRange(0), inRange(1), rejected, rejected},
+		},
+		{
+			name:   "clamp with the default epsilon",
+			server: "  entropy_out_of_range: clamp\n",
+			in: []float64{
+				0.25,
+				-1e-9,
+				1 + 1e-9,
+				-1e-6,
+				1 + 1e-6,
+				math.Nextafter(-1e-6, math.Inf(-1)),
+				math.Nextafter(1+1e-6, math.Inf(1)),
+				-0.5,
+				2,
+			},
+			want: []want{
+				inRange(0.25),
+				{v: 0, clamped: true},
+				{v: 1, clamped: true},
+				{v: 0, clamped: true},
+				{v: 1, clamped: true},
+				rejected,
+				rejected,
+				rejected,
+				rejected,
+			},
+		},
+		{
+			name:   "clamp with a configured epsilon",
+			server: "  entropy_out_of_range: clamp\n  entropy_epsilon: 0.01\n",
+			in:     []float64{-0.01, 1.01, -0.0101, 1.0101},
+			want:   []want{{v: 0, clamped: true}, {v: 1, clamped: true}, rejected, rejected},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			data := entropyNodes
+			if tc.server != "" {
+				data = "server:\n" + tc.server + entropyNodes
+			}
+			p, err := loadTestPolicy(t, data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// Non-finite readings are refused whatever the mode.
+			tc.in = append(tc.in, math.NaN(), math.Inf(1), math.Inf(-1))
+			tc.want = append(tc.want, rejected, rejected, rejected)
+			for i, in := range tc.in {
+				v, clamped, err := p.NormalizeEntropy(in)
+				want := tc.want[i]
+				if want.err {
+					if err == nil {
+						t.Errorf("NormalizeEntropy(%g) = %g, %v; want an error", in, v, clamped)
+					}
+					continue
+				}
+				if err != nil || v != want.v || clamped != want.clamped {
+					t.Errorf("NormalizeEntropy(%g) = %g, %v, %v; want %g, %v", in, v, clamped, err, want.v, want.clamped)
+				}
+			}
+		})
+	}
+}
+
+func TestEntropyConfigValidation(t *testing.T) {
+	for server, wantErr := range map[string]string{
+		"entropy_out_of_range: wrap": "entropy_out_of_range",
+		"entropy_epsilon: -0.001":    "entropy_epsilon",
+		"entropy_epsilon: 0.2":       "entropy_epsilon",
+		"entropy_epsilon: 0.1":       "",
+	} {
+		_, err := loadTestPolicy(t, "server:\n  "+server+"\n"+entropyNodes)
+		if wantErr == "" {
+			if err != nil {
+				t.Errorf("%s: %v", server, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), wantErr) {
+			t.Errorf("%s: error %v, want one naming %s", server, err, wantErr)
+		}
+	}
+}
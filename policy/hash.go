@@ -0,0 +1,63 @@
+package policy
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+
+	"atropos/notifications"
+)
+
+func (p *RemediationPolicy) Hash() string {
+	return p.hash
+}
+
+// computeHash digests the parsed policy re-encoded with sorted keys, so
+// comments and formatting don't count as changes. Inline secrets are blanked.
+func (p *RemediationPolicy) computeHash() error {
+	canon := *p
+	canon.Server.HMACSecret = ""
+	canon.Server.ExportSigningKey = ""
+	if p.Notifications != nil && p.Notifications.Email != nil {
+		notif, email := *p.Notifications, *p.Notifications.Email
+		email.SMTPPassword = ""
+		notif.Email = &email
+		canon.Notifications = &notif
+	}
+	if p.Callbacks != nil {
+		callbacks := *p.Callbacks
+		callbacks.Secret = ""
+		canon.Callbacks = &callbacks
+	}
+	canon.Server.HMACKeys = make([]HMACKey, len(p.Server.HMACKeys))
+	for i, key := range p.Server.HMACKeys {
+		key.Secret = ""
+		canon.Server.HMACKeys[i] = key
+	}
+	canon.Nodes = make(map[string]*NodePolicy, len(p.Nodes))
+	for name, node := range p.Nodes {
+		if node.Notifications != nil && node.Notifications.Email != nil {
+			n, email := *node, *node.Notifications.Email
+			email.SMTPPassword = ""
+			// Field by field: an Override holds the notifiers it built.
+			n.Notifications = &notifications.Override{
+				Enabled: node.Notifications.Enabled,
+				Mode:    node.Notifications.Mode,
+				Webhook: node.Notifications.Webhook,
+				Email:   &email,
+			}
+			node = &n
+		}
+		canon.Nodes[name] = node
+	}
+
+	data, err := yaml.Marshal(&canon)
+	if err != nil {
+		return fmt.Errorf("hash policy: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	p.hash = hex.EncodeToString(sum[:])
+	return nil
+}
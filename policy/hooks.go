@@ -0,0 +1,64 @@
+package policy
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	PreHookAbort    = "abort"
+	PreHookContinue = "continue"
+)
+
+// Hook runs an action through the cutter registry before or after a
+// strategy's own action, e.g. to capture evidence first.
+type Hook struct {
+	Action         string            `yaml:"action"`
+	Command        string            `yaml:"command,omitempty"`
+	SnapshotName   string            `yaml:"snapshot_name,omitempty"`
+	Params         map[string]string `yaml:"params,omitempty"`
+	TimeoutSeconds int               `yaml:"timeout_seconds,omitempty"`
+}
+
+// GetTimeout defaults to 30s, the same as a strategy's.
+func (h *Hook) GetTimeout() time.Duration {
+	if h.TimeoutSeconds <= 0 {
+		return 30 * time.Second
+	}
+	return time.Duration(h.TimeoutSeconds) * time.Second
+}
+
+// Strategy wraps the hook so it gets the same cutter params as a strategy.
+func (h *Hook) Strategy() *Strategy {
+	return &Strategy{
+		Action:       h.Action,
+		Command:      h.Command,
+		SnapshotName: h.SnapshotName,
+		Params:       h.Params,
+	}
+}
+
+// AbortOnPreHookFailure reports whether a failed pre-hook stops the cut
+// (the default) rather than letting it go ahead.
+func (s *Strategy) AbortOnPreHookFailure() bool {
+	return s.PreHookFailure != PreHookContinue
+}
+
+func (s *Strategy) validateHooks() error {
+	switch s.PreHookFailure {
+	case "", PreHookAbort, PreHookContinue:
+	default:
+		return fmt.Errorf("pre_hook_failure must be %q or %q", PreHookAbort, PreHookContinue)
+	}
+	for phase, hooks := range map[string][]Hook{"pre_hooks": s.PreHooks, "post_hooks": s.PostHooks} {
+		for i, hook := range hooks {
+			if hook.Action == "" || ObserveOnly(hook.Action) {
+				return fmt.Errorf("%s[%d]: action required", phase, i)
+			}
+			if hook.TimeoutSeconds < 0 {
+				return fmt.Errorf("%s[%d]: timeout_seconds must be >= 0", phase, i)
+			}
+		}
+	}
+	return nil
+}
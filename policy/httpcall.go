@@ -0,0 +1,47 @@
+package policy
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+)
+
+const ActionHTTPCall = "http_call"
+
+// validateHTTPCall checks an http_call strategy has a usable http_url, from
+// its own params, the node's or the cutter's defaults.
+func (s *Strategy) validateHTTPCall(node *NodePolicy, defaults map[string]string) error {
+	uses := false
+	for _, action := range s.StepActions() {
+		if action == ActionHTTPCall {
+			uses = true
+		}
+	}
+	if !uses {
+		return nil
+	}
+
+	param := func(key string) string {
+		if v := s.Params[key]; v != "" {
+			return v
+		}
+		if v := node.Params[key]; v != "" {
+			return v
+		}
+		return defaults[key]
+	}
+	raw := param("http_url")
+	if raw == "" {
+		return fmt.Errorf("%s requires http_url in params", ActionHTTPCall)
+	}
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("http_url %q must be an http or https URL", raw)
+	}
+	if t := param("http_timeout_seconds"); t != "" {
+		if n, err := strconv.Atoi(t); err != nil || n <= 0 {
+			return fmt.Errorf("http_timeout_seconds must be a positive integer, got %q", t)
+		}
+	}
+	return nil
+}
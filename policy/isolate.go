@@ -0,0 +1,63 @@
+package policy
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+const (
+	ActionNetIsolate   = "net_isolate"
+	ActionNetUnisolate = "net_unisolate"
+)
+
+// validateIsolation checks a strategy that isolates a node has a usable
+// management_cidrs allowlist, from its own params, the node's or the
+// cutter's defaults, so a typo can't lock Atropos out of the host.
+func (s *Strategy) validateIsolation(node *NodePolicy, defaults map[string]string) error {
+	uses := false
+	for _, action := range s.StepActions() {
+		if action == ActionNetIsolate || action == ActionNetUnisolate {
+			uses = true
+		}
+	}
+	if !uses {
+		return nil
+	}
+
+	param := func(key string) string {
+		if v := s.Params[key]; v != "" {
+			return v
+		}
+		if v := node.Params[key]; v != "" {
+			return v
+		}
+		return defaults[key]
+	}
+	switch fw := param("firewall"); fw {
+	case "", "nftables", "iptables":
+	default:
+		return fmt.Errorf("firewall must be \"nftables\" or \"iptables\", got %q", fw)
+	}
+
+	for _, action := range s.StepActions() {
+		if action != ActionNetIsolate {
+			continue
+		}
+		cidrs := 0
+		for _, entry := range strings.Split(param("management_cidrs"), ",") {
+			entry = strings.TrimSpace(entry)
+			if entry == "" {
+				continue
+			}
+			if _, _, err := net.ParseCIDR(entry); err != nil && net.ParseIP(entry) == nil {
+				return fmt.Errorf("management_cidrs: %q is not a CIDR or IP address", entry)
+			}
+			cidrs++
+		}
+		if cidrs == 0 {
+			return fmt.Errorf("%s requires management_cidrs in params", ActionNetIsolate)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package policy
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	LintUnreachableStrategy = "unreachable_strategy"
+	LintDuplicateThreshold  = "duplicate_threshold"
+	LintDanglingOnFailure   = "dangling_on_failure"
+	LintDanglingEscalateTo  = "dangling_escalate_to"
+	LintNoCutter            = "no_cutter"
+	LintMissingHost         = "missing_host"
+	LintMissingSnapshotName = "missing_snapshot_name"
+	LintExpiredBlackout     = "expired_blackout"
+)
+
+type LintWarning struct {
+	Node          string `json:"node"`
+	StrategyIndex int    `json:"strategy_index"`
+	Action        string `json:"action"`
+	Code          string `json:"code"`
+	Message       string `json:"message"`
+}
+
+func (w LintWarning) String() string {
+	if w.StrategyIndex < 0 {
+		node := w.Node
+		if node == "" {
+			node = "policy"
+		}
+		return fmt.Sprintf("%s: %s: %s", node, w.Code, w.Message)
+	}
+	return fmt.Sprintf("%s strategy %d (%s): %s: %s", w.Node, w.StrategyIndex, w.Action, w.Code, w.Message)
+}
+
+// Lint reports configuration that loads fine but will misbehave at cut time.
+// Strategy indexes refer to the order in the policy file, not the sorted order.
+func (p *RemediationPolicy) Lint(hasCutter func(action string) bool) []LintWarning {
+	var warnings []LintWarning
+
+	now := time.Now()
+	expired := func(node string, periods []BlackoutPeriod) {
+		for i, b := range periods {
+			if !b.End.After(now) {
+				warnings = append(warnings, LintWarning{
+					Node:          node,
+					StrategyIndex: -1,
+					Code:          LintExpiredBlackout,
+					Message:       fmt.Sprintf("blackout_periods[%d] %q ended %s", i, b.Description, b.End.UTC().Format(time.RFC3339)),
+				})
+			}
+		}
+	}
+	expired("", p.BlackoutPeriods)
+
+	for name, node := range p.Nodes {
+		expired(name, node.BlackoutPeriods)
+
+		referenced := make(map[string]bool)
+		for _, strat := range node.Strategies {
+			if strat.OnFailure != "" {
+				referenced[strat.OnFailure] = true
+			}
+			if strat.EscalateTo != "" {
+				referenced[strat.EscalateTo] = true
+			}
+		}
+
+		warn := func(strat *Strategy, code, format string, args ...interface{}) {
+			warnings = append(warnings, LintWarning{
+				Node:          name,
+				StrategyIndex: strat.Index,
+				Action:        strat.Action,
+				Code:          code,
+				Message:       fmt.Sprintf(format, args...),
+			})
+		}
+
+		for i := range node.Strategies {
+			strat := &node.Strategies[i]
+
+			for j := 0; j < i; j++ {
+				prev := &node.Strategies[j]
+				if prev.Threshold != strat.Threshold {
+					continue
+				}
+				warn(strat, LintDuplicateThreshold, "threshold %.2f is also used by strategy %d (%s)", strat.Threshold, prev.Index, prev.Action)
+				if !referenced[strat.Action] {
+					warn(strat, LintUnreachableStrategy, "shadowed by strategy %d (%s) and not referenced by on_failure or escalate_to", prev.Index, prev.Action)
+				}
+				break
+			}
+
+			if strat.OnFailure != "" {
+				if _, ok := node.SelectStrategyByAction(strat.OnFailure); !ok {
+					warn(strat, LintDanglingOnFailure, "on_failure %q does not match any strategy action", strat.OnFailure)
+				}
+			}
+			if strat.EscalateTo != "" {
+				if _, ok := node.SelectStrategyByAction(strat.EscalateTo); !ok {
+					warn(strat, LintDanglingEscalateTo, "escalate_to %q does not match any strategy action", strat.EscalateTo)
+				}
+			}
+
+			for _, step := range strat.selectedSteps() {
+				// A step that names its cutter was checked when the policy loaded.
+				if step.Cutter == "" && hasCutter != nil && !ObserveOnly(step.Action) && !hasCutter(step.Action) {
+					warn(strat, LintNoCutter, "no registered cutter handles action %q", step.Action)
+				}
+			}
+			for _, hooks := range [][]Hook{strat.PreHooks, strat.PostHooks} {
+				for _, hook := range hooks {
+					if hasCutter != nil && !hasCutter(hook.Action) {
+						warn(strat, LintNoCutter, "no registered cutter handles hook action %q", hook.Action)
+					}
+				}
+			}
+			steps := strat.Actions
+			if len(steps) == 0 {
+				steps = []ActionStep{{Action: strat.Action, SnapshotName: strat.SnapshotName}}
+			}
+			for _, step := range steps {
+				if (strings.HasPrefix(step.Action, "ssh_") || step.Action == ActionNetIsolate || step.Action == ActionNetUnisolate) && node.Host == "" {
+					warn(strat, LintMissingHost, "ssh action requires host on the node")
+				}
+				if strings.HasPrefix(step.Action, "winrm_") && node.Host == "" {
+					warn(strat, LintMissingHost, "winrm action requires host on the node")
+				}
+				if (step.Action == "vbox_revert_snapshot" || step.Action == "vmw_revert_snapshot") && step.SnapshotName == "" {
+					warn(strat, LintMissingSnapshotName, "%s requires snapshot_name", step.Action)
+				}
+			}
+		}
+
+		if r := node.Recovery; r != nil && hasCutter != nil && !hasCutter(r.Action) {
+			warnings = append(warnings, LintWarning{
+				Node:          name,
+				StrategyIndex: -1,
+				Action:        r.Action,
+				Code:          LintNoCutter,
+				Message:       fmt.Sprintf("no registered cutter handles recovery action %q", r.Action),
+			})
+		}
+	}
+
+	sort.Slice(warnings, func(i, j int) bool {
+		if warnings[i].Node != warnings[j].Node {
+			return warnings[i].Node < warnings[j].Node
+		}
+		if warnings[i].StrategyIndex != warnings[j].StrategyIndex {
+			return warnings[i].StrategyIndex < warnings[j].StrategyIndex
+		}
+		return warnings[i].Code < warnings[j].Code
+	})
+
+	return warnings
+}
@@ -0,0 +1,57 @@
+package policy
+
+import "fmt"
+
+// ActionLocalExec runs a command on the Atropos host itself. Only a policy
+// with server.enable_local_cutter may use it.
+const ActionLocalExec = "local_exec"
+
+// LocalCutter is the cutter that runs ActionLocalExec.
+const LocalCutter = "local"
+
+// validateLocalExec refuses local_exec, and the local cutter by name,
+// anywhere in the policy unless the server opted in.
+func (p *RemediationPolicy) validateLocalExec() error {
+	if p.Server.EnableLocalCutter {
+		return nil
+	}
+	for i, c := range p.Cutters {
+		if c.Name == LocalCutter {
+			return fmt.Errorf("cutters[%d]: %s requires server.enable_local_cutter", i, LocalCutter)
+		}
+	}
+	for name, node := range p.Nodes {
+		for j, strat := range node.Strategies {
+			actions := strat.StepActions()
+			cutters := []string{strat.Cutter}
+			for _, step := range strat.Actions {
+				cutters = append(cutters, step.Cutter)
+			}
+			for _, hook := range strat.PreHooks {
+				actions = append(actions, hook.Action)
+			}
+			for _, hook := range strat.PostHooks {
+				actions = append(actions, hook.Action)
+			}
+			for _, action := range actions {
+				if action == ActionLocalExec {
+					return fmt.Errorf("node %q strategy %d: %s requires server.enable_local_cutter", name, j, ActionLocalExec)
+				}
+			}
+			for _, c := range cutters {
+				if c == LocalCutter {
+					return fmt.Errorf("node %q strategy %d: cutter %s requires server.enable_local_cutter", name, j, LocalCutter)
+				}
+			}
+		}
+		for i, s := range node.Schedules {
+			if s.Action == ActionLocalExec {
+				return fmt.Errorf("node %q schedules[%d]: %s requires server.enable_local_cutter", name, i, ActionLocalExec)
+			}
+		}
+		if r := node.Recovery; r != nil && r.Action == ActionLocalExec {
+			return fmt.Errorf("node %q recovery: %s requires server.enable_local_cutter", name, ActionLocalExec)
+		}
+	}
+	return nil
+}
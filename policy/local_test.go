@@ -0,0 +1,98 @@
+package policy
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func loadTestPolicy(t *testing.T, data string) (*RemediationPolicy, error) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "policy.yaml")
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return LoadPolicy(path)
+}
+
+func TestLocalCutterRequiresOptIn(t *testing.T) {
+	cases := map[string]string{
+		"strategy cutter": `
+nodes:
+  edge:
+    host: edge.local
+    strategies:
+      - threshold: 0.9
+        action: ssh_command
+        cutter: local
+        command: "touch /tmp/pwned"
+`,
+		"step cutter": `
+nodes:
+  edge:
+    host: edge.local
+    strategies:
+      - threshold: 0.9
+        actions:
+          - action: ssh_command
+            cutter: local
+            command: "touch /tmp/pwned"
+`,
+		"local_exec hook": `
+nodes:
+  edge:
+    host: edge.local
+    strategies:
+      - threshold: 0.9
+        action: docker_stop_all
+        pre_hooks:
+          - action: local_exec
+            command: "touch /tmp/pwned"
+`,
+		"cutters section": `
+cutters:
+  - name: docker
+  - name: local
+nodes:
+  edge:
+    strategies:
+      - threshold: 0.9
+        action: docker_stop_all
+`,
+	}
+	for name, data := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := loadTestPolicy(t, "server:\n  hmac_secret: test\n"+data)
+			if err == nil || !strings.Contains(err.Error(), "enable_local_cutter") {
+				t.Fatalf("LoadPolicy error = %v, want enable_local_cutter", err)
+			}
+		})
+	}
+}
+
+func TestEnabledCuttersLeavesOutLocal(t *testing.T) {
+	const nodes = `
+nodes:
+  edge:
+    strategies:
+      - threshold: 0.9
+        action: docker_stop_all
+`
+	p, err := loadTestPolicy(t, "server:\n  hmac_secret: test\n"+nodes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if slices.Contains(p.EnabledCutters(), LocalCutter) || p.CutterEnabled(LocalCutter) {
+		t.Fatalf("EnabledCutters() = %v, want no %s without enable_local_cutter", p.EnabledCutters(), LocalCutter)
+	}
+
+	p, err = loadTestPolicy(t, "server:\n  hmac_secret: test\n  enable_local_cutter: true\n"+nodes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Contains(p.EnabledCutters(), LocalCutter) {
+		t.Fatalf("EnabledCutters() = %v, want %s with enable_local_cutter", p.EnabledCutters(), LocalCutter)
+	}
+}
@@ -0,0 +1,50 @@
+package policy
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Plugin is an external cutter: an executable that handles every action
+// starting with ActionPrefix. Plugins are registered at startup.
+type Plugin struct {
+	Name         string `yaml:"name"`
+	Path         string `yaml:"path"`
+	ActionPrefix string `yaml:"action_prefix"`
+}
+
+func (p *RemediationPolicy) validatePlugins() error {
+	names := make(map[string]bool, len(p.Plugins))
+	for i, plugin := range p.Plugins {
+		if plugin.Name == "" {
+			return fmt.Errorf("plugins[%d]: name required", i)
+		}
+		if names[plugin.Name] {
+			return fmt.Errorf("plugins[%d]: duplicate name %q", i, plugin.Name)
+		}
+		names[plugin.Name] = true
+
+		if plugin.ActionPrefix == "" {
+			return fmt.Errorf("plugin %q: action_prefix required", plugin.Name)
+		}
+		for _, other := range p.Plugins[:i] {
+			if strings.HasPrefix(plugin.ActionPrefix, other.ActionPrefix) || strings.HasPrefix(other.ActionPrefix, plugin.ActionPrefix) {
+				return fmt.Errorf("plugin %q: action_prefix %q overlaps plugin %q's %q", plugin.Name, plugin.ActionPrefix, other.Name, other.ActionPrefix)
+			}
+		}
+
+		if !filepath.IsAbs(plugin.Path) {
+			return fmt.Errorf("plugin %q: path must be absolute", plugin.Name)
+		}
+		info, err := os.Stat(plugin.Path)
+		if err != nil {
+			return fmt.Errorf("plugin %q: %w", plugin.Name, err)
+		}
+		if info.IsDir() || info.Mode()&0o111 == 0 {
+			return fmt.Errorf("plugin %q: %s is not executable", plugin.Name, plugin.Path)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package policy
+
+import "fmt"
+
+const (
+	PreserveStateAbort    = "abort"
+	PreserveStateContinue = "continue"
+)
+
+// validatePreserveState checks preserve_state is set on a strategy that
+// reverts a VirtualBox snapshot, the only action that honours it.
+func (s *Strategy) validatePreserveState() error {
+	switch s.PreserveStateFailure {
+	case "", PreserveStateAbort, PreserveStateContinue:
+	default:
+		return fmt.Errorf("preserve_state_failure must be %q or %q", PreserveStateAbort, PreserveStateContinue)
+	}
+	if !s.PreserveState {
+		if s.PreserveStateFailure != "" {
+			return fmt.Errorf("preserve_state_failure requires preserve_state: true")
+		}
+		return nil
+	}
+	for _, action := range s.StepActions() {
+		if action == "vbox_revert_snapshot" {
+			return nil
+		}
+	}
+	return fmt.Errorf("preserve_state requires a vbox_revert_snapshot action")
+}
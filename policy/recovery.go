@@ -0,0 +1,68 @@
+package policy
+
+import "fmt"
+
+// Recovery undoes containment once a node settles: the first reading under
+// BelowThreshold after a containment cut runs Action, once, until the node
+// is contained again. The fields after Action are passed to the cutter as a
+// strategy would.
+type Recovery struct {
+	BelowThreshold float64           `yaml:"below_threshold"`
+	Action         string            `yaml:"action"`
+	SnapshotName   string            `yaml:"snapshot_name,omitempty"`
+	Command        string            `yaml:"command,omitempty"`
+	Params         map[string]string `yaml:"params,omitempty"`
+	Description    string            `yaml:"description,omitempty"`
+	// AfterActions limits which strategy actions count as containment;
+	// empty means any but noop.
+	AfterActions []string `yaml:"after_actions,omitempty"`
+}
+
+// Strategy is the strategy a recovery runs. Its threshold is the floor.
+func (r *Recovery) Strategy() *Strategy {
+	return &Strategy{
+		Threshold:    r.BelowThreshold,
+		Action:       r.Action,
+		SnapshotName: r.SnapshotName,
+		Command:      r.Command,
+		Params:       r.Params,
+		Description:  r.Description,
+	}
+}
+
+// Follows reports whether a cut running action is containment the recovery
+// undoes.
+func (r *Recovery) Follows(action string) bool {
+	if ObserveOnly(action) || action == r.Action {
+		return false
+	}
+	if len(r.AfterActions) == 0 {
+		return true
+	}
+	for _, a := range r.AfterActions {
+		if a == action {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Recovery) validate(node *NodePolicy) error {
+	if r.Action == "" || ObserveOnly(r.Action) {
+		return fmt.Errorf("action required")
+	}
+	if r.BelowThreshold <= 0 || r.BelowThreshold > 1 {
+		return fmt.Errorf("below_threshold must be greater than 0 and at most 1")
+	}
+	for _, strat := range node.Strategies {
+		if r.BelowThreshold > strat.Threshold {
+			return fmt.Errorf("below_threshold %.2f is above strategy %q's threshold %.2f", r.BelowThreshold, strat.Action, strat.Threshold)
+		}
+	}
+	for _, action := range r.AfterActions {
+		if _, ok := node.SelectStrategyByAction(action); !ok {
+			return fmt.Errorf("after_actions %q does not match any strategy action", action)
+		}
+	}
+	return nil
+}
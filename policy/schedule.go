@@ -0,0 +1,248 @@
+package policy
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule fires an action on a node at cron times, regardless of entropy.
+// The fields after Action are passed to the cutter as a strategy would.
+type Schedule struct {
+	Name         string            `yaml:"name,omitempty"`
+	Cron         string            `yaml:"cron"`
+	Timezone     string            `yaml:"timezone,omitempty"`
+	Action       string            `yaml:"action"`
+	SnapshotName string            `yaml:"snapshot_name,omitempty"`
+	Command      string            `yaml:"command,omitempty"`
+	Params       map[string]string `yaml:"params,omitempty"`
+	Description  string            `yaml:"description,omitempty"`
+
+	spec *CronSpec
+	loc  *time.Location
+}
+
+func (s *Schedule) compile() error {
+	if s.Action == "" {
+		return fmt.Errorf("action required")
+	}
+	spec, err := ParseCron(s.Cron)
+	if err != nil {
+		return err
+	}
+	loc := time.Local
+	if s.Timezone != "" {
+		if loc, err = time.LoadLocation(s.Timezone); err != nil {
+			return fmt.Errorf("timezone: %w", err)
+		}
+	}
+	s.spec, s.loc = spec, loc
+	return nil
+}
+
+// Label names the schedule in records and logs: its name, or the cron
+// expression when it has none.
+func (s *Schedule) Label() string {
+	if s.Name != "" {
+		return s.Name
+	}
+	return s.Cron
+}
+
+// Due reports whether the schedule fires in the minute containing t.
+func (s *Schedule) Due(t time.Time) bool {
+	return s.spec != nil && s.spec.Matches(t.In(s.loc))
+}
+
+// Next returns the first fire time after t, or the zero time if there is
+// none within five years.
+func (s *Schedule) Next(t time.Time) time.Time {
+	if s.spec == nil {
+		return time.Time{}
+	}
+	return s.spec.Next(t.In(s.loc))
+}
+
+// Strategy is the strategy a scheduled cut runs.
+func (s *Schedule) Strategy() *Strategy {
+	return &Strategy{
+		Action:       s.Action,
+		SnapshotName: s.SnapshotName,
+		Command:      s.Command,
+		Params:       s.Params,
+		Description:  s.Description,
+	}
+}
+
+// CronSpec is a parsed five-field cron expression: minute, hour, day of
+// month, month, day of week.
+type CronSpec struct {
+	minute, hour, dom, month, dow uint64
+	// Like cron, when both day fields are restricted a day matching
+	// either one fires.
+	domStar, dowStar bool
+}
+
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+type cronField struct {
+	name     string
+	min, max int
+	names    []string
+}
+
+var cronFields = [5]cronField{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12, names: []string{"", "jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}},
+	{name: "day of week", min: 0, max: 7, names: []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}},
+}
+
+func ParseCron(expr string) (*CronSpec, error) {
+	expr = strings.TrimSpace(expr)
+	if macro, ok := cronMacros[strings.ToLower(expr)]; ok {
+		expr = macro
+	}
+	parts := strings.Fields(expr)
+	if len(parts) != 5 {
+		return nil, fmt.Errorf("cron %q: need 5 fields (minute hour day month weekday)", expr)
+	}
+
+	var bits [5]uint64
+	for i, part := range parts {
+		b, err := cronFields[i].parse(part)
+		if err != nil {
+			return nil, fmt.Errorf("cron %q: %w", expr, err)
+		}
+		bits[i] = b
+	}
+	// 7 is Sunday too.
+	if bits[4]&(1<<7) != 0 {
+		bits[4] = bits[4]&^(1<<7) | 1
+	}
+	return &CronSpec{
+		minute:  bits[0],
+		hour:    bits[1],
+		dom:     bits[2],
+		month:   bits[3],
+		dow:     bits[4],
+		domStar: parts[2] == "*" || parts[2] == "?",
+		dowStar: parts[4] == "*" || parts[4] == "?",
+	}, nil
+}
+
+func (f cronField) parse(field string) (uint64, error) {
+	var bits uint64
+	for _, item := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(item, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepPart)
+			if err != nil || n < 1 {
+				return 0, fmt.Errorf("%s: invalid step %q", f.name, stepPart)
+			}
+			step = n
+		}
+
+		lo, hi := f.min, f.max
+		switch {
+		case rangePart == "*" || rangePart == "?":
+		case strings.Contains(rangePart, "-"):
+			a, b, _ := strings.Cut(rangePart, "-")
+			var err error
+			if lo, err = f.value(a); err != nil {
+				return 0, err
+			}
+			if hi, err = f.value(b); err != nil {
+				return 0, err
+			}
+			if lo > hi {
+				return 0, fmt.Errorf("%s: range %q is backwards", f.name, rangePart)
+			}
+		default:
+			v, err := f.value(rangePart)
+			if err != nil {
+				return 0, err
+			}
+			lo = v
+			if !hasStep {
+				hi = v
+			}
+		}
+
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+func (f cronField) value(s string) (int, error) {
+	for i, name := range f.names {
+		if name != "" && strings.EqualFold(s, name) {
+			return i, nil
+		}
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < f.min || v > f.max {
+		return 0, fmt.Errorf("%s: %q is not between %d and %d", f.name, s, f.min, f.max)
+	}
+	return v, nil
+}
+
+func (c *CronSpec) dayMatches(t time.Time) bool {
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+	if c.domStar || c.dowStar {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// Matches reports whether the minute containing t is a fire time, in t's
+// location.
+func (c *CronSpec) Matches(t time.Time) bool {
+	return c.month&(1<<uint(t.Month())) != 0 &&
+		c.dayMatches(t) &&
+		c.hour&(1<<uint(t.Hour())) != 0 &&
+		c.minute&(1<<uint(t.Minute())) != 0
+}
+
+// Next returns the first fire time strictly after t, in t's location, or the
+// zero time if none falls within five years (e.g. "0 0 31 2 *").
+func (c *CronSpec) Next(t time.Time) time.Time {
+	loc := t.Location()
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.Year() + 5
+
+	for t.Year() <= limit {
+		if c.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if !c.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if c.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+			continue
+		}
+		if c.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
@@ -0,0 +1,75 @@
+package policy
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ActionStep is one action of a strategy that runs several in order. The
+// fields after Action are passed to the cutter as a strategy's would.
+type ActionStep struct {
+	Action       string            `yaml:"action"`
+	Cutter       string            `yaml:"cutter,omitempty"`
+	Command      string            `yaml:"command,omitempty"`
+	SnapshotName string            `yaml:"snapshot_name,omitempty"`
+	Params       map[string]string `yaml:"params,omitempty"`
+}
+
+// Strategy is the step as a strategy, keeping s's success criteria and
+// retries.
+func (step *ActionStep) Strategy(s *Strategy) *Strategy {
+	return &Strategy{
+		Threshold:            s.Threshold,
+		Action:               step.Action,
+		Cutter:               step.Cutter,
+		Command:              step.Command,
+		SnapshotName:         step.SnapshotName,
+		Params:               step.Params,
+		SuccessExitCodes:     s.SuccessExitCodes,
+		SuccessOutputRegex:   s.SuccessOutputRegex,
+		FailureOutputRegex:   s.FailureOutputRegex,
+		Retries:              s.Retries,
+		RetryBackoffSeconds:  s.RetryBackoffSeconds,
+		PreserveState:        s.PreserveState,
+		PreserveStateFailure: s.PreserveStateFailure,
+	}
+}
+
+// selectedSteps lists the strategy's steps, or its one action as a step.
+func (s *Strategy) selectedSteps() []ActionStep {
+	if len(s.Actions) > 0 {
+		return s.Actions
+	}
+	return []ActionStep{{Action: s.Action, Cutter: s.Cutter}}
+}
+
+// StepActions lists the actions the strategy runs: its steps, or its one
+// action.
+func (s *Strategy) StepActions() []string {
+	if len(s.Actions) == 0 {
+		return []string{s.Action}
+	}
+	actions := make([]string, len(s.Actions))
+	for i, step := range s.Actions {
+		actions[i] = step.Action
+	}
+	return actions
+}
+
+// nameSteps gives a strategy with actions the name its records, on_failure
+// and escalate_to use: the step actions joined with "+".
+func (s *Strategy) nameSteps() error {
+	if len(s.Actions) == 0 {
+		return nil
+	}
+	if s.Action != "" {
+		return fmt.Errorf("action and actions are mutually exclusive")
+	}
+	for i, step := range s.Actions {
+		if step.Action == "" || ObserveOnly(step.Action) {
+			return fmt.Errorf("actions[%d]: action required", i)
+		}
+	}
+	s.Action = strings.Join(s.StepActions(), "+")
+	return nil
+}
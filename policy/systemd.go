@@ -0,0 +1,35 @@
+package policy
+
+import (
+	"fmt"
+	"strings"
+)
+
+// validateSystemd checks a strategy with systemd_ actions names a unit, in
+// its own params, the node's or the cutter's defaults.
+func (s *Strategy) validateSystemd(node *NodePolicy, defaults map[string]string) error {
+	uses := false
+	for _, action := range s.StepActions() {
+		if strings.HasPrefix(action, "systemd_") {
+			uses = true
+		}
+	}
+	if !uses {
+		return nil
+	}
+
+	unit := s.Params["unit"]
+	if unit == "" {
+		unit = node.Params["unit"]
+	}
+	if unit == "" {
+		unit = defaults["unit"]
+	}
+	if strings.TrimSpace(unit) == "" {
+		return fmt.Errorf("systemd actions require unit in params")
+	}
+	if strings.HasPrefix(unit, "-") || strings.ContainsAny(strings.TrimSpace(unit), "/ \t\n") {
+		return fmt.Errorf("unit %q is not a unit name", unit)
+	}
+	return nil
+}
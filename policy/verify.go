@@ -0,0 +1,84 @@
+package policy
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+)
+
+const (
+	VerifyTCP  = "tcp"
+	VerifyHTTP = "http"
+	VerifySSH  = "ssh"
+)
+
+// Verify is a probe run after the action succeeds to check the node really
+// came back. Until it passes, or the attempts run out, the cut isn't
+// considered successful.
+type Verify struct {
+	Type string `yaml:"type"`
+	// Address is host:port for tcp; Port alone uses the node's host.
+	Address string `yaml:"address,omitempty"`
+	Port    int    `yaml:"port,omitempty"`
+	URL     string `yaml:"url,omitempty"`
+	// ExpectStatus is the HTTP status that passes; default 200.
+	ExpectStatus    int    `yaml:"expect_status,omitempty"`
+	Command         string `yaml:"command,omitempty"`
+	DelaySeconds    int    `yaml:"delay_seconds,omitempty"`
+	Attempts        int    `yaml:"attempts,omitempty"`
+	IntervalSeconds int    `yaml:"interval_seconds,omitempty"`
+	TimeoutSeconds  int    `yaml:"timeout_seconds,omitempty"`
+}
+
+func (v *Verify) GetAttempts() int {
+	if v.Attempts <= 0 {
+		return 3
+	}
+	return v.Attempts
+}
+
+func (v *Verify) GetInterval() time.Duration {
+	if v.IntervalSeconds <= 0 {
+		return 5 * time.Second
+	}
+	return time.Duration(v.IntervalSeconds) * time.Second
+}
+
+// GetTimeout bounds a single probe.
+func (v *Verify) GetTimeout() time.Duration {
+	if v.TimeoutSeconds <= 0 {
+		return 5 * time.Second
+	}
+	return time.Duration(v.TimeoutSeconds) * time.Second
+}
+
+func (v *Verify) GetExpectStatus() int {
+	if v.ExpectStatus == 0 {
+		return 200
+	}
+	return v.ExpectStatus
+}
+
+func (v *Verify) validate() error {
+	switch v.Type {
+	case VerifyTCP:
+		if v.Address == "" && v.Port == 0 {
+			return fmt.Errorf("verify: tcp needs address or port")
+		}
+	case VerifyHTTP:
+		u, err := url.Parse(v.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("verify: http needs an absolute http(s) url")
+		}
+	case VerifySSH:
+		if v.Command == "" {
+			return fmt.Errorf("verify: ssh needs command")
+		}
+	default:
+		return fmt.Errorf("verify: type must be %q, %q or %q", VerifyTCP, VerifyHTTP, VerifySSH)
+	}
+	if v.DelaySeconds < 0 || v.Attempts < 0 || v.IntervalSeconds < 0 || v.TimeoutSeconds < 0 {
+		return fmt.Errorf("verify: delay, attempts, interval and timeout must be >= 0")
+	}
+	return nil
+}
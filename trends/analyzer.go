@@ -4,11 +4,14 @@ import (
 	"sort"
 	"time"
 
+	"atropos/correlation"
 	"atropos/history"
+	"atropos/internal/timefmt"
 )
 
 type Analyzer struct {
-	history *history.HistoryManager
+	history  *history.HistoryManager
+	importer *correlation.ClothoImporter
 }
 
 func NewAnalyzer(historyMgr *history.HistoryManager) *Analyzer {
@@ -17,15 +20,26 @@ func NewAnalyzer(historyMgr *history.HistoryManager) *Analyzer {
 	}
 }
 
+// SetCorrelation lets problematic-node scoring use the Clotho reports
+// imported into importer. Without reports scoring only looks at cuts.
+func (a *Analyzer) SetCorrelation(importer *correlation.ClothoImporter) {
+	a.importer = importer
+}
+
 type NodeTrend struct {
-	Node         string         `json:"node"`
-	TotalCuts    int            `json:"total_cuts"`
-	SuccessRate  float64        `json:"success_rate"`
-	AvgLatencyMs int64          `json:"avg_latency_ms"`
-	ByAction     map[string]int `json:"by_action"`
-	MostCommon   string         `json:"most_common_action"`
-	LastCut      *time.Time     `json:"last_cut,omitempty"`
-	FirstCut     *time.Time     `json:"first_cut,omitempty"`
+	Node         string             `json:"node"`
+	TotalCuts    int                `json:"total_cuts"`
+	SuccessRate  float64            `json:"success_rate"`
+	AvgLatencyMs int64              `json:"avg_latency_ms"`
+	ByAction     map[string]int     `json:"by_action"`
+	MostCommon   string             `json:"most_common_action"`
+	LastCut      *time.Time         `json:"last_cut,omitempty"`
+	FirstCut     *time.Time         `json:"first_cut,omitempty"`
+	Timings      *TimingPercentiles `json:"timings,omitempty"`
+	// Set on problematic nodes when Clotho reports are loaded.
+	UnresolvedFindings *int     `json:"unresolved_findings,omitempty"`
+	ResolutionRate     *float64 `json:"resolution_rate,omitempty"`
+	ProblemScore       *int     `json:"problem_score,omitempty"`
 }
 
 type ActionStats struct {
@@ -40,16 +54,21 @@ type ActionStats struct {
 }
 
 type GlobalTrend struct {
-	PeriodDays       int             `json:"period_days"`
-	TotalCuts        int             `json:"total_cuts"`
-	SuccessRate      float64         `json:"success_rate"`
-	ByNode           map[string]int  `json:"by_node"`
-	ByAction         map[string]int  `json:"by_action"`
-	NodeTrends       []*NodeTrend    `json:"node_trends"`
-	ActionStats      []*ActionStats  `json:"action_stats"`
-	MTTR             *time.Duration  `json:"mttr,omitempty"`
-	ProblematicNodes []*NodeTrend    `json:"problematic_nodes"`
-	Timeline         []TimelineEntry `json:"timeline"`
+	PeriodDays       int                `json:"period_days"`
+	TotalCuts        int                `json:"total_cuts"`
+	SuccessRate      float64            `json:"success_rate"`
+	ByNode           map[string]int     `json:"by_node"`
+	ByAction         map[string]int     `json:"by_action"`
+	ByLabel          map[string]int     `json:"by_label"`
+	ByTag            map[string]int     `json:"by_tag"`
+	NodeTrends       []*NodeTrend       `json:"node_trends"`
+	ActionStats      []*ActionStats     `json:"action_stats"`
+	MTTR             *time.Duration     `json:"mttr,omitempty"`
+	MTTRSeconds      *float64           `json:"mttr_seconds,omitempty"`
+	MTTRHuman        string             `json:"mttr_human,omitempty"`
+	ProblematicNodes []*NodeTrend       `json:"problematic_nodes"`
+	Timeline         []TimelineEntry    `json:"timeline"`
+	Timings          *TimingPercentiles `json:"timings,omitempty"`
 }
 
 type TimelineEntry struct {
@@ -60,11 +79,12 @@ type TimelineEntry struct {
 	Entropy   float64   `json:"entropy"`
 }
 
-func (a *Analyzer) GetNodeTrends(node string) (*NodeTrend, error) {
+func (a *Analyzer) GetNodeTrends(node string, filter history.Filter) (*NodeTrend, error) {
 	cuts, err := a.history.ListCutsByNode(node, 0)
 	if err != nil {
 		return nil, err
 	}
+	cuts = selectCuts(cuts, filter)
 
 	if len(cuts) == 0 {
 		return &NodeTrend{
@@ -111,15 +131,17 @@ func (a *Analyzer) GetNodeTrends(node string) (*NodeTrend, error) {
 		trend.AvgLatencyMs = totalLatency / int64(trend.TotalCuts)
 	}
 	trend.MostCommon = mostCommon
+	trend.Timings = timingPercentiles(cuts)
 
 	return trend, nil
 }
 
-func (a *Analyzer) GetActionStats() ([]*ActionStats, error) {
+func (a *Analyzer) GetActionStats(filter history.Filter) ([]*ActionStats, error) {
 	allCuts, err := a.history.ListCuts(0)
 	if err != nil {
 		return nil, err
 	}
+	allCuts = selectCuts(allCuts, filter)
 
 	actions := make(map[string]*ActionStats)
 
@@ -175,11 +197,12 @@ func (a *Analyzer) GetActionStats() ([]*ActionStats, error) {
 	return result, nil
 }
 
-func (a *Analyzer) GetGlobalTrends(days int) (*GlobalTrend, error) {
+func (a *Analyzer) GetGlobalTrends(days int, filter history.Filter) (*GlobalTrend, error) {
 	allCuts, err := a.history.ListCuts(0)
 	if err != nil {
 		return nil, err
 	}
+	allCuts = selectCuts(allCuts, filter)
 
 	cutoff := time.Now().AddDate(0, 0, -days)
 	var recentCuts []*history.CutRecord
@@ -194,6 +217,8 @@ func (a *Analyzer) GetGlobalTrends(days int) (*GlobalTrend, error) {
 		TotalCuts:  len(recentCuts),
 		ByNode:     make(map[string]int),
 		ByAction:   make(map[string]int),
+		ByLabel:    make(map[string]int),
+		ByTag:      make(map[string]int),
 		Timeline:   []TimelineEntry{},
 	}
 
@@ -203,6 +228,12 @@ func (a *Analyzer) GetGlobalTrends(days int) (*GlobalTrend, error) {
 	for _, cut := range recentCuts {
 		trend.ByNode[cut.Node]++
 		trend.ByAction[cut.Action]++
+		for key, value := range cut.Strategy.Labels {
+			trend.ByLabel[history.LabelKey(key, value)]++
+		}
+		for key, value := range cut.Tags {
+			history.CountTag(trend.ByTag, key, value)
+		}
 
 		trend.Timeline = append(trend.Timeline, TimelineEntry{
 			Timestamp: cut.Timestamp,
@@ -221,16 +252,20 @@ func (a *Analyzer) GetGlobalTrends(days int) (*GlobalTrend, error) {
 		successRate = float64(totalSuccess) / float64(trend.TotalCuts) * 100
 	}
 	trend.SuccessRate = successRate
+	trend.Timings = timingPercentiles(recentCuts)
 
 	mttr := a.calculateMTTR(recentCuts)
 	if mttr != nil {
 		trend.MTTR = mttr
+		seconds := mttr.Seconds()
+		trend.MTTRSeconds = &seconds
+		trend.MTTRHuman = timefmt.Human(*mttr)
 	}
 
-	problematicNodes := a.identifyProblematicNodes(recentCuts)
+	problematicNodes := a.identifyProblematicNodes(recentCuts, filter, time.Duration(days)*24*time.Hour)
 	trend.ProblematicNodes = problematicNodes
 
-	actionStats, err := a.GetActionStats()
+	actionStats, err := a.GetActionStats(filter)
 	if err != nil {
 		return nil, err
 	}
@@ -252,7 +287,7 @@ func (a *Analyzer) GetGlobalTrends(days int) (*GlobalTrend, error) {
 	}
 
 	for node := range nodes {
-		nodeTrend, err := a.GetNodeTrends(node)
+		nodeTrend, err := a.GetNodeTrends(node, filter)
 		if err != nil {
 			continue
 		}
@@ -302,33 +337,74 @@ func (a *Analyzer) calculateMTTR(cuts []*history.CutRecord) *time.Duration {
 	return &avg
 }
 
-func (a *Analyzer) identifyProblematicNodes(cuts []*history.CutRecord) []*NodeTrend {
+// identifyProblematicNodes returns up to five nodes with at least three cuts
+// and a failure, busiest first. With Clotho reports loaded, nodes whose
+// findings went unresolved within window qualify too, and nodes are ranked
+// by failed cuts plus unresolved findings.
+func (a *Analyzer) identifyProblematicNodes(cuts []*history.CutRecord, filter history.Filter, window time.Duration) []*NodeTrend {
 	nodeCutCount := make(map[string]int)
 	nodeFailCount := make(map[string]int)
+	nodeRefs := make(map[string][]correlation.CutReference)
+	correlated := a.importer != nil && a.importer.HasReports()
 
 	for _, cut := range cuts {
 		nodeCutCount[cut.Node]++
 		if !cut.Success {
 			nodeFailCount[cut.Node]++
 		}
+		if correlated {
+			nodeRefs[cut.Node] = append(nodeRefs[cut.Node], correlation.CutReference{
+				ID:        cut.ID,
+				Timestamp: cut.Timestamp,
+				Action:    cut.Action,
+				Success:   cut.Success,
+			})
+		}
 	}
 
 	var problematic []*NodeTrend
 	for node := range nodeCutCount {
+		if a.history.Silences().IsSilenced(node) {
+			continue
+		}
+
 		totalCuts := nodeCutCount[node]
 		failedCuts := nodeFailCount[node]
 
-		if totalCuts >= 3 && failedCuts > 0 {
-			nodeTrend, err := a.GetNodeTrends(node)
+		var result *correlation.CorrelationResult
+		if correlated {
+			result, _ = correlation.NewCorrelator(a.importer, nodeRefs[node]).Correlate(node, window)
+		}
+		unresolved := 0
+		if result != nil {
+			unresolved = len(result.Unresolved)
+		}
+
+		if (totalCuts >= 3 && failedCuts > 0) || unresolved > 0 {
+			nodeTrend, err := a.GetNodeTrends(node, filter)
 			if err != nil {
 				continue
 			}
+			if result != nil {
+				rate := result.ResolutionRate()
+				score := failedCuts + unresolved
+				nodeTrend.UnresolvedFindings = &unresolved
+				nodeTrend.ResolutionRate = &rate
+				nodeTrend.ProblemScore = &score
+			}
 			problematic = append(problematic, nodeTrend)
 		}
 	}
 
 	sort.Slice(problematic, func(i, j int) bool {
-		return problematic[i].TotalCuts > problematic[j].TotalCuts
+		x, y := problematic[i], problematic[j]
+		if correlated && *x.ProblemScore != *y.ProblemScore {
+			return *x.ProblemScore > *y.ProblemScore
+		}
+		if correlated && *x.ResolutionRate != *y.ResolutionRate {
+			return *x.ResolutionRate < *y.ResolutionRate
+		}
+		return x.TotalCuts > y.TotalCuts
 	})
 
 	if len(problematic) > 5 {
@@ -337,3 +413,14 @@ func (a *Analyzer) identifyProblematicNodes(cuts []*history.CutRecord) []*NodeTr
 
 	return problematic
 }
+
+func selectCuts(cuts []*history.CutRecord, filter history.Filter) []*history.CutRecord {
+	filtered := make([]*history.CutRecord, 0, len(cuts))
+	for _, cut := range cuts {
+		if !cut.Executed() || !filter.Match(cut) {
+			continue
+		}
+		filtered = append(filtered, cut)
+	}
+	return filtered
+}
@@ -0,0 +1,122 @@
+package trends
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"atropos/history"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// checkGolden compares v's JSON with testdata/name.golden.
+func checkGolden(t *testing.T, name string, v interface{}) {
+	t.Helper()
+	got, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got = append(got, '\n')
+	path := filepath.Join("testdata", name+".golden")
+	if *update {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("%s differs from %s:\n%s", name, path, got)
+	}
+}
+
+// goldenHistory holds cuts at fixed times, so everything but the period's
+// cutoff is independent of the clock.
+func goldenHistory(t *testing.T, offsets ...time.Duration) *history.HistoryManager {
+	t.Helper()
+	h := history.NewHistoryManager(t.TempDir())
+	base := time.Date(2025, 11, 2, 3, 0, 0, 0, time.UTC)
+	for i, offset := range offsets {
+		ts := base.Add(offset)
+		err := h.SaveNewCut(&history.CutRecord{
+			ID:        history.NewCutID("athena", ts),
+			Node:      "athena",
+			Action:    "docker_restart",
+			Success:   true,
+			Entropy:   0.8,
+			LatencyMs: int64(100 * (i + 1)),
+			Timestamp: ts,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return h
+}
+
+// period reaches back far enough to include the fixed cuts.
+const period = 100 * 365
+
+func TestGlobalTrendJSON(t *testing.T) {
+	a := NewAnalyzer(goldenHistory(t, 0, 30*time.Minute, 75*time.Minute))
+	trend, err := a.GetGlobalTrends(period, history.Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if trend.MTTRSeconds == nil || *trend.MTTRSeconds != 2250 || trend.MTTRHuman != "37m30s" {
+		t.Fatalf("mttr = %v seconds, %q; want 2250 and 37m30s", trend.MTTRSeconds, trend.MTTRHuman)
+	}
+	checkGolden(t, "global_trend", trend)
+}
+
+func TestGlobalTrendJSONSubSecondMTTR(t *testing.T) {
+	a := NewAnalyzer(goldenHistory(t, 0, 750*time.Millisecond))
+	trend, err := a.GetGlobalTrends(period, history.Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, "global_trend_subsecond", map[string]interface{}{
+		"mttr":         trend.MTTR,
+		"mttr_seconds": trend.MTTRSeconds,
+		"mttr_human":   trend.MTTRHuman,
+	})
+}
+
+// Without two successful cuts on a node there is no MTTR, and all three
+// fields are left out.
+func TestGlobalTrendJSONWithoutMTTR(t *testing.T) {
+	a := NewAnalyzer(goldenHistory(t, 0))
+	trend, err := a.GetGlobalTrends(period, history.Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(trend)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"mttr", "mttr_seconds", "mttr_human"} {
+		if _, ok := fields[key]; ok {
+			t.Errorf("%s is present without an MTTR", key)
+		}
+	}
+}
+
+func TestNodeTrendJSON(t *testing.T) {
+	a := NewAnalyzer(goldenHistory(t, 0, 30*time.Minute, 75*time.Minute))
+	trend, err := a.GetNodeTrends("athena", history.Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, "node_trend", trend)
+}
@@ -0,0 +1,215 @@
+package trends
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"atropos/correlation"
+	"atropos/history"
+)
+
+// scoringFixture holds cuts within the last two days:
+//
+//	athena: 4 cuts, 1 failed; its finding was fixed by a later cut
+//	borg:   3 cuts, 2 failed; only a passed finding
+//	clotho: 2 cuts, none failed; 3 findings after its last cut
+//	delos:  5 cuts, 1 failed; its finding was followed by the failed cut
+//
+// and a report with those findings.
+type scoringFixture struct {
+	history *history.HistoryManager
+	report  correlation.ClothoReport
+}
+
+func newScoringFixture(t *testing.T) *scoringFixture {
+	t.Helper()
+	now := time.Now().UTC().Truncate(time.Second)
+	ago := func(hours int) time.Time { return now.Add(-time.Duration(hours) * time.Hour) }
+	f := &scoringFixture{history: history.NewHistoryManager(t.TempDir())}
+
+	cut := func(node string, hours int, success bool) {
+		ts := ago(hours)
+		err := f.history.SaveNewCut(&history.CutRecord{
+			ID:        history.NewCutID(node, ts),
+			Node:      node,
+			Action:    "docker_restart",
+			Success:   success,
+			Timestamp: ts,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	finding := func(node, control string, hours int, passed bool) {
+		f.report.Findings = append(f.report.Findings, correlation.ClothoFinding{
+			ControlID:     control,
+			CollectorType: "ssh",
+			Node:          node,
+			Passed:        passed,
+			Timestamp:     ago(hours).Format(time.RFC3339),
+		})
+	}
+
+	cut("athena", 40, true)
+	cut("athena", 39, true)
+	cut("athena", 38, false)
+	cut("athena", 9, true)
+	finding("athena", "CIS-1.1", 10, false)
+
+	cut("borg", 30, false)
+	cut("borg", 29, false)
+	cut("borg", 28, true)
+	finding("borg", "CIS-1.1", 27, true)
+
+	cut("clotho", 5, true)
+	cut("clotho", 2, true)
+	finding("clotho", "CIS-1.1", 1, false)
+	finding("clotho", "CIS-2.3", 1, false)
+	finding("clotho", "CIS-4.2", 1, false)
+
+	for hours := 30; hours > 26; hours-- {
+		cut("delos", hours, true)
+	}
+	cut("delos", 19, false)
+	finding("delos", "CIS-1.1", 20, false)
+
+	f.report.AuditID = "audit-1"
+	return f
+}
+
+func (f *scoringFixture) problematic(t *testing.T, importer *correlation.ClothoImporter) []*NodeTrend {
+	t.Helper()
+	a := NewAnalyzer(f.history)
+	if importer != nil {
+		a.SetCorrelation(importer)
+	}
+	trend, err := a.GetGlobalTrends(7, history.Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return trend.ProblematicNodes
+}
+
+func (f *scoringFixture) importer(t *testing.T) *correlation.ClothoImporter {
+	t.Helper()
+	data, err := json.Marshal(f.report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	importer := correlation.NewClothoImporter()
+	if _, err := importer.ImportReport(bytes.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+	return importer
+}
+
+func problematicNames(nodes []*NodeTrend) []string {
+	names := make([]string, len(nodes))
+	for i, node := range nodes {
+		names[i] = node.Node
+	}
+	return names
+}
+
+func TestProblematicNodesWithoutReports(t *testing.T) {
+	f := newScoringFixture(t)
+
+	// Without reports, or with an importer that has none, only nodes with
+	// three cuts and a failure count, busiest first.
+	for name, importer := range map[string]*correlation.ClothoImporter{
+		"no importer":    nil,
+		"empty importer": correlation.NewClothoImporter(),
+	} {
+		nodes := f.problematic(t, importer)
+		if got, want := fmt.Sprint(problematicNames(nodes)), "[delos athena borg]"; got != want {
+			t.Fatalf("%s: problematic nodes %s, want %s", name, got, want)
+		}
+		for _, node := range nodes {
+			if node.UnresolvedFindings != nil || node.ResolutionRate != nil || node.ProblemScore != nil {
+				t.Fatalf("%s: %s has correlation fields without reports", name, node.Node)
+			}
+			data, err := json.Marshal(node)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if bytes.Contains(data, []byte("problem_score")) || bytes.Contains(data, []byte("unresolved_findings")) {
+				t.Fatalf("%s: %s's JSON has correlation fields: %s", name, node.Node, data)
+			}
+		}
+	}
+}
+
+func TestProblematicNodesWithReports(t *testing.T) {
+	f := newScoringFixture(t)
+	nodes := f.problematic(t, f.importer(t))
+
+	// clotho's cuts all succeeded, but its findings are still open, which
+	// ranks it first. delos and athena tie on score; delos's finding was
+	// followed by a failed cut, so it ranks above athena's fixed one.
+	want := []struct {
+		node       string
+		unresolved int
+		rate       float64
+		score      int
+	}{
+		{"clotho", 3, 0, 3},
+		{"borg", 0, 0, 2},
+		{"delos", 0, 0, 1},
+		{"athena", 0, 100, 1},
+	}
+	if len(nodes) != len(want) {
+		t.Fatalf("problematic nodes %v, want %d", problematicNames(nodes), len(want))
+	}
+	for i, w := range want {
+		node := nodes[i]
+		if node.Node != w.node {
+			t.Fatalf("problematic nodes %v, want clotho, borg, delos, athena", problematicNames(nodes))
+		}
+		if node.UnresolvedFindings == nil || node.ResolutionRate == nil || node.ProblemScore == nil {
+			t.Fatalf("%s lacks correlation fields", node.Node)
+		}
+		if *node.UnresolvedFindings != w.unresolved || *node.ResolutionRate != w.rate || *node.ProblemScore != w.score {
+			t.Errorf("%s: unresolved %d, resolution rate %g, score %d; want %d, %g, %d", node.Node,
+				*node.UnresolvedFindings, *node.ResolutionRate, *node.ProblemScore, w.unresolved, w.rate, w.score)
+		}
+	}
+
+	// A silenced node is left out however bad its score.
+	if _, err := f.history.Silences().Silence("clotho", time.Hour, "known"); err != nil {
+		t.Fatal(err)
+	}
+	if got := fmt.Sprint(problematicNames(f.problematic(t, f.importer(t)))); got != "[borg delos athena]" {
+		t.Fatalf("with clotho silenced: %s", got)
+	}
+}
+
+func TestProblematicNodesKeepsFive(t *testing.T) {
+	h := history.NewHistoryManager(t.TempDir())
+	now := time.Now().UTC()
+	for n := 0; n < 7; n++ {
+		node := fmt.Sprintf("node-%d", n)
+		for i := 0; i < 3+n; i++ {
+			ts := now.Add(-time.Duration(n*10+i+1) * time.Minute)
+			err := h.SaveNewCut(&history.CutRecord{
+				ID:        history.NewCutID(node, ts),
+				Node:      node,
+				Action:    "docker_restart",
+				Success:   i > 0,
+				Timestamp: ts,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	trend, err := NewAnalyzer(h).GetGlobalTrends(7, history.Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fmt.Sprint(problematicNames(trend.ProblematicNodes)); got != "[node-6 node-5 node-4 node-3 node-2]" {
+		t.Fatalf("problematic nodes %s, want the five busiest", got)
+	}
+}
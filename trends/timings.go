@@ -0,0 +1,69 @@
+package trends
+
+import (
+	"sort"
+
+	"atropos/history"
+)
+
+type PhasePercentiles struct {
+	Samples int   `json:"samples"`
+	P50Ms   int64 `json:"p50_ms"`
+	P90Ms   int64 `json:"p90_ms"`
+	P99Ms   int64 `json:"p99_ms"`
+	MaxMs   int64 `json:"max_ms"`
+}
+
+// TimingPercentiles summarizes Atropos's own phases across cuts: decision is
+// webhook receipt to guards done, wait is guards done to cutter start.
+type TimingPercentiles struct {
+	Decision PhasePercentiles `json:"decision"`
+	Wait     PhasePercentiles `json:"wait"`
+	Cutter   PhasePercentiles `json:"cutter"`
+}
+
+func timingPercentiles(cuts []*history.CutRecord) *TimingPercentiles {
+	var decision, wait, cutter []int64
+	for _, cut := range cuts {
+		t := cut.Timings
+		if t == nil {
+			continue
+		}
+		if ms, ok := t.DecisionMs(); ok {
+			decision = append(decision, ms)
+		}
+		if ms, ok := t.WaitMs(); ok {
+			wait = append(wait, ms)
+		}
+		if ms, ok := t.CutterMs(); ok {
+			cutter = append(cutter, ms)
+		}
+	}
+	if len(decision) == 0 {
+		return nil
+	}
+	return &TimingPercentiles{
+		Decision: percentiles(decision),
+		Wait:     percentiles(wait),
+		Cutter:   percentiles(cutter),
+	}
+}
+
+// percentiles uses the nearest-rank method.
+func percentiles(samples []int64) PhasePercentiles {
+	if len(samples) == 0 {
+		return PhasePercentiles{}
+	}
+	sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+	rank := func(p int) int64 {
+		i := (p*len(samples) + 99) / 100
+		return samples[max(i-1, 0)]
+	}
+	return PhasePercentiles{
+		Samples: len(samples),
+		P50Ms:   rank(50),
+		P90Ms:   rank(90),
+		P99Ms:   rank(99),
+		MaxMs:   samples[len(samples)-1],
+	}
+}